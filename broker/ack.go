@@ -0,0 +1,96 @@
+package broker
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	// ErrAlreadyAcked is returned by the acks of an event acked before.
+	ErrAlreadyAcked = errors.New("event already acked")
+
+	// ErrAlreadyNacked is returned by the acks of an event the driver
+	// already nacked, rejected or requeued.
+	ErrAlreadyNacked = errors.New("event already nacked")
+)
+
+const (
+	ackPending int32 = iota
+	ackAcked
+	ackNacked
+)
+
+// AckState tracks the settlement of an event, embedded by the events of
+// the drivers so that the message is settled once whatever the number of
+// Ack calls. The zero value is pending.
+type AckState struct {
+	state atomic.Int32
+}
+
+// Ack run ack unless the event is settled, ErrAlreadyAcked or
+// ErrAlreadyNacked is returned then. A failed ack leaves the event pending.
+func (s *AckState) Ack(ack func() error) error {
+	return s.settle(ackAcked, ack)
+}
+
+// Nack run nack, the negative settlement of the driver, unless the event
+// is settled.
+func (s *AckState) Nack(nack func() error) error {
+	return s.settle(ackNacked, nack)
+}
+
+func (s *AckState) settle(state int32, fn func() error) error {
+	if !s.state.CompareAndSwap(ackPending, state) {
+		if s.state.Load() == ackAcked {
+			return ErrAlreadyAcked
+		}
+		return ErrAlreadyNacked
+	}
+	if fn == nil {
+		return nil
+	}
+	if err := fn(); err != nil {
+		s.state.Store(ackPending)
+		return err
+	}
+	return nil
+}
+
+// IsAcked report whether the event was acked.
+func (s *AckState) IsAcked() bool {
+	return s.state.Load() == ackAcked
+}
+
+// IsNacked report whether the event was nacked.
+func (s *AckState) IsNacked() bool {
+	return s.state.Load() == ackNacked
+}
+
+// AckTracker is implemented by the events tracking their settlement.
+type AckTracker interface {
+	IsAcked() bool
+	IsNacked() bool
+}
+
+// IsAcked report whether event was acked, false when its driver does not
+// track it.
+func IsAcked(event Event) bool {
+	t, ok := event.(AckTracker)
+	return ok && t.IsAcked()
+}
+
+// IsSettled report whether event was acked or nacked, false when its
+// driver does not track it.
+func IsSettled(event Event) bool {
+	t, ok := event.(AckTracker)
+	return ok && (t.IsAcked() || t.IsNacked())
+}
+
+// AutoAck ack event unless its handler settled it already, for the auto
+// acks of the drivers and of the consumers wrapping a handler.
+func AutoAck(event Event) error {
+	if IsSettled(event) {
+		return nil
+	}
+	return event.Ack()
+}
@@ -0,0 +1,57 @@
+package broker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ackEvent struct {
+	AckState
+	acks int
+}
+
+func (e *ackEvent) Topic() string           { return "orders" }
+func (e *ackEvent) Message() *Message       { return nil }
+func (e *ackEvent) RawMessage() interface{} { return nil }
+func (e *ackEvent) Error() error            { return nil }
+
+func (e *ackEvent) Ack() error {
+	return e.AckState.Ack(func() error {
+		e.acks++
+		return nil
+	})
+}
+
+func TestAckState(t *testing.T) {
+	e := &ackEvent{}
+	assert.False(t, IsAcked(e))
+	assert.False(t, IsSettled(e))
+
+	assert.Nil(t, e.Ack())
+	assert.ErrorIs(t, e.Ack(), ErrAlreadyAcked)
+	assert.Equal(t, 1, e.acks)
+	assert.True(t, IsAcked(e))
+	assert.True(t, IsSettled(e))
+	assert.ErrorIs(t, e.Nack(nil), ErrAlreadyAcked)
+
+	e = &ackEvent{}
+	assert.Nil(t, e.Nack(nil))
+	assert.ErrorIs(t, e.Ack(), ErrAlreadyNacked)
+	assert.Equal(t, 0, e.acks)
+	assert.False(t, IsAcked(e))
+	assert.True(t, IsSettled(e))
+}
+
+func TestAckState_Failure(t *testing.T) {
+	var s AckState
+
+	failure := errors.New("channel closed")
+	assert.ErrorIs(t, s.Ack(func() error { return failure }), failure)
+	assert.False(t, s.IsAcked())
+
+	// a failed ack may be retried.
+	assert.Nil(t, s.Ack(func() error { return nil }))
+	assert.True(t, s.IsAcked())
+}
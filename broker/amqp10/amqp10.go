@@ -0,0 +1,527 @@
+package amqp10
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/Azure/go-amqp"
+
+	"go.opentelemetry.io/otel/attribute"
+	semConv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/tracing"
+)
+
+const (
+	defaultAddr           = "amqp://127.0.0.1:5672"
+	defaultConnectTimeout = 30 * time.Second
+)
+
+type amqpBroker struct {
+	sync.RWMutex
+
+	options broker.Options
+
+	conn    *amqp.Conn
+	session *amqp.Session
+	senders map[string]*amqp.Sender
+
+	subscribers *broker.SubscriberSyncMap
+
+	producerTracer *tracing.Tracer
+	consumerTracer *tracing.Tracer
+
+	metrics *broker.Metrics
+}
+
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.NewOptionsAndApply(opts...)
+
+	b := &amqpBroker{
+		options:     options,
+		senders:     make(map[string]*amqp.Sender),
+		subscribers: broker.NewSubscriberSyncMap(),
+	}
+
+	return b
+}
+
+func (b *amqpBroker) Name() string {
+	return "amqp10"
+}
+
+func (b *amqpBroker) defaults() {
+	WithConnectTimeout(defaultConnectTimeout)(&b.options)
+}
+
+func (b *amqpBroker) Options() broker.Options {
+	if b.options.Context == nil {
+		b.options.Context = context.Background()
+	}
+	return b.options
+}
+
+func (b *amqpBroker) Address() string {
+	if len(b.options.Addrs) > 0 {
+		return b.options.Addrs[0]
+	}
+	return ""
+}
+
+func (b *amqpBroker) Init(opts ...broker.Option) error {
+	b.defaults()
+
+	b.options.Apply(opts...)
+
+	var cAddrs []string
+	for _, addr := range b.options.Addrs {
+		if len(addr) == 0 {
+			continue
+		}
+		addr = refitUrl(addr, b.options.Secure || b.options.TLSConfig != nil)
+		cAddrs = append(cAddrs, addr)
+	}
+	if len(cAddrs) == 0 {
+		cAddrs = []string{defaultAddr}
+	}
+	b.options.Addrs = cAddrs
+
+	if len(b.options.Tracings) > 0 {
+		b.producerTracer = tracing.NewTracer(trace.SpanKindProducer, "amqp10-producer", b.options.Tracings...)
+		b.consumerTracer = tracing.NewTracer(trace.SpanKindConsumer, "amqp10-consumer", b.options.Tracings...)
+	}
+
+	b.metrics = broker.NewMetrics("amqp10", b.options.MeterProvider)
+
+	return nil
+}
+
+func (b *amqpBroker) Connect() error {
+	uri, err := url.Parse(b.Address())
+	if err != nil {
+		return err
+	}
+	if !isSchema(uri.Scheme) {
+		return fmt.Errorf("expected amqp:// protocol but was %s", uri.Scheme)
+	}
+
+	// username and password of the address are used for SASL PLAIN.
+	c := ConfigFromOptions(b.options)
+
+	connOpts := &amqp.ConnOptions{
+		ContainerID: c.ContainerID,
+		IdleTimeout: c.IdleTimeout,
+		TLSConfig:   b.options.TLSConfig,
+	}
+	if c.Username != "" || c.Password != "" {
+		connOpts.SASLType = amqp.SASLTypePlain(c.Username, c.Password)
+	}
+	if c.Anonymous {
+		connOpts.SASLType = amqp.SASLTypeAnonymous()
+	}
+	if c.TLSConfig != nil {
+		connOpts.TLSConfig = c.TLSConfig
+	}
+
+	ctx := context.Background()
+	if c.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.ConnectTimeout)
+		defer cancel()
+	}
+
+	conn, err := amqp.Dial(ctx, b.Address(), connOpts)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", uri.Host, err)
+	}
+
+	session, err := conn.NewSession(ctx, nil)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+
+	b.Lock()
+	b.conn = conn
+	b.session = session
+	b.Unlock()
+
+	return nil
+}
+
+// Disconnect closes the connection without detaching the receiver links,
+// so that durable subscriptions are kept on the broker.
+func (b *amqpBroker) Disconnect() error {
+	b.Lock()
+	conn := b.conn
+	b.conn = nil
+	b.session = nil
+	b.senders = make(map[string]*amqp.Sender)
+	b.Unlock()
+
+	var err error
+	if conn != nil {
+		err = conn.Close()
+	}
+
+	b.subscribers.Clear()
+
+	return err
+}
+
+func (b *amqpBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	m, options, err := b.newMessage(ctx, topic, msg, opts)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = b.publish(ctx, topic, m, options)
+	b.metrics.RecordPublish(ctx, topic, start, err)
+
+	return err
+}
+
+// newMessage encode msg and run the publish interceptors, it returns the
+// message to send to topic with the options of the publish.
+func (b *amqpBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) (*amqp.Message, broker.PublishOptions, error) {
+	options := broker.PublishOptions{
+		Context: ctx,
+	}
+
+	buf, err := broker.Marshal(b.options.Codec, msg)
+	if err != nil {
+		return nil, options, err
+	}
+	buf, opts, err = b.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return nil, options, err
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	c := PublishConfigFromOptions(options)
+
+	m := amqp.NewMessage(buf)
+	m.Properties = &amqp.MessageProperties{}
+
+	if c.Headers != nil || options.Headers != nil {
+		m.ApplicationProperties = make(map[string]any, len(c.Headers)+len(options.Headers))
+		for k, v := range c.Headers {
+			m.ApplicationProperties[k] = v
+		}
+		for k, v := range options.Headers {
+			m.ApplicationProperties[k] = v
+		}
+	}
+	if c.MessageID != "" {
+		m.Properties.MessageID = c.MessageID
+	}
+	if c.Subject != "" {
+		m.Properties.Subject = &c.Subject
+	}
+	if c.ContentType != "" {
+		m.Properties.ContentType = &c.ContentType
+	}
+
+	m.Header = &amqp.MessageHeader{
+		Durable:  c.Durable,
+		Priority: c.Priority,
+		TTL:      c.TTL,
+	}
+
+	return m, options, nil
+}
+
+func (b *amqpBroker) publish(ctx context.Context, topic string, msg *amqp.Message, options broker.PublishOptions) error {
+	c := PublishConfigFromOptions(options)
+
+	sender, err := b.sender(ctx, topic, c.TargetCapabilities)
+	if err != nil {
+		return err
+	}
+
+	span := b.startProducerSpan(options.Context, topic, msg)
+
+	err = sender.Send(ctx, msg, nil)
+
+	b.finishProducerSpan(span, err)
+
+	return err
+}
+
+func (b *amqpBroker) sender(ctx context.Context, topic string, capabilities []string) (*amqp.Sender, error) {
+	key := topic + "|" + strings.Join(capabilities, ",")
+
+	b.RLock()
+	sender, ok := b.senders[key]
+	session := b.session
+	b.RUnlock()
+	if ok {
+		return sender, nil
+	}
+	if session == nil {
+		return nil, errors.New("not connected")
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if sender, ok = b.senders[key]; ok {
+		return sender, nil
+	}
+
+	sender, err := session.NewSender(ctx, topic, &amqp.SenderOptions{
+		TargetCapabilities: capabilities,
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.senders[key] = sender
+
+	return sender, nil
+}
+
+func (b *amqpBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	b.RLock()
+	session := b.session
+	b.RUnlock()
+	if session == nil {
+		return nil, errors.New("not connected")
+	}
+
+	options := broker.SubscribeOptions{
+		Context: context.Background(),
+		AutoAck: true,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	handler = options.TimeoutHandler(handler)
+	handler = b.metrics.Handler(topic, handler)
+
+	sc := SubscribeConfigFromOptions(options)
+
+	receiverOpts := &amqp.ReceiverOptions{
+		Credit:             sc.Credit,
+		SourceCapabilities: sc.SourceCapabilities,
+	}
+	if sc.DurableSubscription != "" {
+		receiverOpts.Name = sc.DurableSubscription
+		receiverOpts.Durability = amqp.DurabilityUnsettledState
+		receiverOpts.ExpiryPolicy = amqp.ExpiryPolicyNever
+	}
+	if sc.Selector != "" {
+		receiverOpts.Filters = append(receiverOpts.Filters, amqp.NewSelectorFilter(sc.Selector))
+	}
+
+	rejectOnError := sc.RejectOnError
+
+	receiver, err := session.NewReceiver(context.Background(), topic, receiverOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sub := &subscriber{
+		b:        b,
+		options:  options,
+		topic:    topic,
+		receiver: receiver,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(sub.done)
+
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		for {
+			msg, err := receiver.Receive(ctx, nil)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Errorf("[amqp10] receive from [%s] failed: %s", topic, err)
+					sub.options.ReportError(sub.options.Context, broker.ErrReceive, err, nil)
+				}
+				return
+			}
+
+			// at most credit messages are in flight, as the credit is only
+			// replenished once a message is settled.
+			wg.Add(1)
+			go func(msg *amqp.Message) {
+				defer wg.Done()
+				b.handleMessage(sub, receiver, msg, handler, binder, rejectOnError)
+			}(msg)
+		}
+	}()
+
+	b.subscribers.Add(topic, sub)
+
+	return sub, nil
+}
+
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (b *amqpBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
+
+func (b *amqpBroker) handleMessage(sub *subscriber, s settler, msg *amqp.Message, handler broker.Handler, binder broker.Binder, rejectOnError bool) {
+	m := &broker.Message{
+		Headers: messageHeaders(b.options.HeaderCodec, msg),
+	}
+
+	p := &publication{settler: s, msg: msg, m: m, topic: sub.topic}
+
+	ctx, span := b.startConsumerSpan(sub.options.Context, sub.topic, msg)
+
+	if binder != nil && !sub.options.RawBody {
+		m.Body = binder()
+	} else {
+		m.Body = msg.GetData()
+	}
+
+	if err := b.options.Decode(&sub.options, m.Headers, msg.GetData(), &m.Body); err != nil {
+		p.err = err
+		log.Errorf("[amqp10] unmarshal message failed: %s", err)
+		sub.options.ReportError(ctx, broker.ErrUnmarshal, err, p)
+
+		var settleErr error
+		action := sub.options.HandleUnmarshalFailure(ctx, sub.topic, m.Headers, msg.GetData(), err)
+		switch action {
+		case broker.UnmarshalActionAck:
+			settleErr = p.Ack()
+		case broker.UnmarshalActionRetry, broker.UnmarshalActionStop:
+			settleErr = p.Nack(func() error {
+				return s.ModifyMessage(context.Background(), msg, &amqp.ModifyMessageOptions{
+					DeliveryFailed: true,
+				})
+			})
+		default:
+			settleErr = p.Nack(func() error {
+				return s.RejectMessage(context.Background(), msg, &amqp.Error{
+					Condition:   amqp.ErrCondDecodeError,
+					Description: err.Error(),
+				})
+			})
+		}
+		if settleErr != nil {
+			sub.options.ReportError(ctx, broker.ErrAck, settleErr, p)
+		}
+		if action == broker.UnmarshalActionStop {
+			// unsubscribe outside of the receive loop, which it waits for.
+			go func() { _ = sub.Unsubscribe(true) }()
+		}
+		b.finishConsumerSpan(span, err)
+		return
+	}
+
+	var err error
+	if p.err = handler(ctx, p); p.err != nil {
+		if !broker.IsSettled(p) {
+			err = p.Nack(func() error {
+				if rejectOnError {
+					return s.RejectMessage(context.Background(), msg, &amqp.Error{
+						Condition:   amqp.ErrCondInternalError,
+						Description: p.err.Error(),
+					})
+				}
+				return s.ModifyMessage(context.Background(), msg, &amqp.ModifyMessageOptions{
+					DeliveryFailed: true,
+				})
+			})
+		}
+		if err != nil {
+			log.Errorf("[amqp10] settle message failed: %s", err)
+			sub.options.ReportError(ctx, broker.ErrAck, err, p)
+		}
+		b.finishConsumerSpan(span, p.err)
+		return
+	}
+
+	if sub.options.AutoAck {
+		if err = broker.AutoAck(p); err != nil {
+			log.Errorf("[amqp10] accept message failed: %s", err)
+			sub.options.ReportError(ctx, broker.ErrAck, err, p)
+		}
+	}
+
+	b.finishConsumerSpan(span, err)
+}
+
+func (b *amqpBroker) startProducerSpan(ctx context.Context, topic string, msg *amqp.Message) trace.Span {
+	if b.producerTracer == nil {
+		return nil
+	}
+
+	carrier := NewMessageCarrier(msg)
+
+	attrs := []attribute.KeyValue{
+		semConv.MessagingSystemKey.String("amqp10"),
+		semConv.MessagingProtocolKey.String("AMQP"),
+		semConv.MessagingProtocolVersionKey.String("1.0"),
+		semConv.MessagingDestinationKindTopic,
+		semConv.MessagingDestinationKey.String(topic),
+	}
+
+	var span trace.Span
+	_, span = b.producerTracer.Start(ctx, carrier, attrs...)
+
+	return span
+}
+
+func (b *amqpBroker) finishProducerSpan(span trace.Span, err error) {
+	if b.producerTracer == nil {
+		return
+	}
+
+	b.producerTracer.End(context.Background(), span, err)
+}
+
+func (b *amqpBroker) startConsumerSpan(ctx context.Context, topic string, msg *amqp.Message) (context.Context, trace.Span) {
+	if b.consumerTracer == nil {
+		return ctx, nil
+	}
+
+	carrier := NewMessageCarrier(msg)
+
+	attrs := []attribute.KeyValue{
+		semConv.MessagingSystemKey.String("amqp10"),
+		semConv.MessagingProtocolKey.String("AMQP"),
+		semConv.MessagingProtocolVersionKey.String("1.0"),
+		semConv.MessagingDestinationKindTopic,
+		semConv.MessagingDestinationKey.String(topic),
+		semConv.MessagingOperationReceive,
+	}
+	if msg.Properties != nil && msg.Properties.MessageID != nil {
+		attrs = append(attrs, semConv.MessagingMessageIDKey.String(fmt.Sprint(msg.Properties.MessageID)))
+	}
+
+	var span trace.Span
+	ctx, span = b.consumerTracer.Start(ctx, carrier, attrs...)
+
+	return ctx, span
+}
+
+func (b *amqpBroker) finishConsumerSpan(span trace.Span, err error) {
+	if b.consumerTracer == nil {
+		return
+	}
+
+	b.consumerTracer.End(context.Background(), span, err)
+}
@@ -0,0 +1,207 @@
+package amqp10
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed amqp10 configuration of a broker, the fields are the
+// ones set one by one with the broker options of the package.
+type Config struct {
+	Username  string
+	Password  string
+	Anonymous bool
+	// ContainerID must be stable across restarts for durable subscriptions.
+	ContainerID    string
+	ConnectTimeout time.Duration
+	IdleTimeout    time.Duration
+	// TLSConfig replaces the TLS config of the broker options when not nil.
+	TLSConfig *tls.Config
+}
+
+// DefaultConfig return the configuration of a broker without options.
+func DefaultConfig() Config {
+	return Config{
+		ConnectTimeout: defaultConnectTimeout,
+	}
+}
+
+// WithConfig set the whole configuration at once, c should start from
+// DefaultConfig, the zero fields are left unset. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		var opts []broker.Option
+		if c.Username != "" || c.Password != "" {
+			opts = append(opts, WithAuth(c.Username, c.Password))
+		}
+		if c.Anonymous {
+			opts = append(opts, WithAnonymous())
+		}
+		if c.ContainerID != "" {
+			opts = append(opts, WithContainerID(c.ContainerID))
+		}
+		if c.ConnectTimeout != 0 {
+			opts = append(opts, WithConnectTimeout(c.ConnectTimeout))
+		}
+		if c.IdleTimeout != 0 {
+			opts = append(opts, WithIdleTimeout(c.IdleTimeout))
+		}
+		if c.TLSConfig != nil {
+			opts = append(opts, WithTLSConfig(c.TLSConfig))
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	c := DefaultConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(authKey{}).(*authRecord); ok {
+		c.Username, c.Password = v.username, v.password
+	}
+	c.Anonymous, _ = opts.Context.Value(anonymousKey{}).(bool)
+	c.ContainerID, _ = opts.Context.Value(containerIdKey{}).(string)
+	if v, ok := opts.Context.Value(connectTimeoutKey{}).(time.Duration); ok {
+		c.ConnectTimeout = v
+	}
+	c.IdleTimeout, _ = opts.Context.Value(idleTimeoutKey{}).(time.Duration)
+	c.TLSConfig, _ = opts.Context.Value(tlsConfigKey{}).(*tls.Config)
+
+	return c
+}
+
+// SubscribeConfig is the typed amqp10 configuration of a subscription.
+type SubscribeConfig struct {
+	Credit int32
+	// DurableSubscription is the name of a durable receiver link, see
+	// WithDurableSubscription.
+	DurableSubscription string
+	Selector            string
+	SourceCapabilities  []string
+	RejectOnError       bool
+}
+
+// DefaultSubscribeConfig return the configuration of a subscription without
+// options.
+func DefaultSubscribeConfig() SubscribeConfig {
+	return SubscribeConfig{Credit: 1}
+}
+
+// WithSubscribeConfig set the whole subscription configuration at once, c
+// should start from DefaultSubscribeConfig.
+func WithSubscribeConfig(c SubscribeConfig) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		opts := []broker.SubscribeOption{
+			WithCredit(c.Credit),
+		}
+		if c.DurableSubscription != "" {
+			opts = append(opts, WithDurableSubscription(c.DurableSubscription))
+		}
+		if c.Selector != "" {
+			opts = append(opts, WithSelector(c.Selector))
+		}
+		if c.SourceCapabilities != nil {
+			opts = append(opts, WithSourceCapabilities(c.SourceCapabilities...))
+		}
+		if c.RejectOnError {
+			opts = append(opts, WithRejectOnError())
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// SubscribeConfigFromOptions return the subscription configuration set in
+// the context of opts, a credit not above zero keeps the default.
+func SubscribeConfigFromOptions(opts broker.SubscribeOptions) SubscribeConfig {
+	c := DefaultSubscribeConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(creditKey{}).(int32); ok && v > 0 {
+		c.Credit = v
+	}
+	c.DurableSubscription, _ = opts.Context.Value(durableSubscriptionKey{}).(string)
+	c.Selector, _ = opts.Context.Value(selectorKey{}).(string)
+	c.SourceCapabilities, _ = opts.Context.Value(sourceCapabilitiesKey{}).([]string)
+	c.RejectOnError, _ = opts.Context.Value(rejectOnErrorKey{}).(bool)
+
+	return c
+}
+
+// PublishConfig is the typed amqp10 configuration of a publishing, the zero
+// fields are left unset.
+type PublishConfig struct {
+	// Headers are set as application properties.
+	Headers            map[string]string
+	MessageID          string
+	Subject            string
+	ContentType        string
+	Durable            bool
+	Priority           uint8
+	TTL                time.Duration
+	TargetCapabilities []string
+}
+
+// WithPublishConfig set the whole publishing configuration at once.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		var opts []broker.PublishOption
+		if c.Headers != nil {
+			opts = append(opts, WithHeaders(c.Headers))
+		}
+		if c.MessageID != "" {
+			opts = append(opts, WithMessageID(c.MessageID))
+		}
+		if c.Subject != "" {
+			opts = append(opts, WithSubject(c.Subject))
+		}
+		if c.ContentType != "" {
+			opts = append(opts, WithContentType(c.ContentType))
+		}
+		if c.Durable {
+			opts = append(opts, WithDurableMessage())
+		}
+		if c.Priority != 0 {
+			opts = append(opts, WithPriority(c.Priority))
+		}
+		if c.TTL != 0 {
+			opts = append(opts, WithTTL(c.TTL))
+		}
+		if c.TargetCapabilities != nil {
+			opts = append(opts, WithTargetCapabilities(c.TargetCapabilities...))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	var c PublishConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.Headers, _ = opts.Context.Value(headerKey{}).(map[string]string)
+	c.MessageID, _ = opts.Context.Value(messageIdKey{}).(string)
+	c.Subject, _ = opts.Context.Value(subjectKey{}).(string)
+	c.ContentType, _ = opts.Context.Value(contentTypeKey{}).(string)
+	c.Durable, _ = opts.Context.Value(durableMessageKey{}).(bool)
+	c.Priority, _ = opts.Context.Value(priorityKey{}).(uint8)
+	c.TTL, _ = opts.Context.Value(ttlKey{}).(time.Duration)
+	c.TargetCapabilities, _ = opts.Context.Value(targetCapabilitiesKey{}).([]string)
+
+	return c
+}
@@ -0,0 +1,71 @@
+package amqp10
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultConfig(), ConfigFromOptions(broker.NewOptions()))
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithAuth("user", "password"),
+		WithContainerID("orders"),
+		WithIdleTimeout(time.Minute),
+	))
+
+	c := DefaultConfig()
+	c.Username = "user"
+	c.Password = "password"
+	c.ContainerID = "orders"
+	c.IdleTimeout = time.Minute
+
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c), WithContainerID("payments")))
+	assert.Equal(t, "payments", overridden.ContainerID)
+}
+
+func TestSubscribeConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultSubscribeConfig(), SubscribeConfigFromOptions(broker.NewSubscribeOptions()))
+
+	legacy := SubscribeConfigFromOptions(broker.NewSubscribeOptions(
+		WithCredit(10),
+		WithSourceCapabilities(CapabilityTopic),
+		WithRejectOnError(),
+	))
+
+	c := SubscribeConfig{
+		Credit:             10,
+		SourceCapabilities: []string{CapabilityTopic},
+		RejectOnError:      true,
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c))))
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	assert.Equal(t, PublishConfig{}, PublishConfigFromOptions(broker.PublishOptions{}))
+
+	legacy := PublishConfigFromOptions(broker.NewPublishOptions(
+		WithSubject("created"),
+		WithDurableMessage(),
+		WithTTL(time.Minute),
+		WithTargetCapabilities(CapabilityQueue),
+	))
+
+	c := PublishConfig{
+		Subject:            "created",
+		Durable:            true,
+		TTL:                time.Minute,
+		TargetCapabilities: []string{CapabilityQueue},
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+}
@@ -0,0 +1,64 @@
+package amqp10
+
+import (
+	"fmt"
+
+	amqp "github.com/Azure/go-amqp"
+
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var _ propagation.TextMapCarrier = (*MessageCarrier)(nil)
+
+// MessageCarrier injects and extracts trace context with the application
+// properties of a message.
+type MessageCarrier struct {
+	msg *amqp.Message
+}
+
+func NewMessageCarrier(msg *amqp.Message) MessageCarrier {
+	return MessageCarrier{msg: msg}
+}
+
+func (c MessageCarrier) Get(key string) string {
+	if v, ok := c.msg.ApplicationProperties[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func (c MessageCarrier) Set(key, val string) {
+	if c.msg.ApplicationProperties == nil {
+		c.msg.ApplicationProperties = make(map[string]any)
+	}
+	c.msg.ApplicationProperties[key] = val
+}
+
+func (c MessageCarrier) Keys() []string {
+	out := make([]string, 0, len(c.msg.ApplicationProperties))
+	for k := range c.msg.ApplicationProperties {
+		out = append(out, k)
+	}
+	return out
+}
+
+func messageHeaders(codec broker.HeaderCodec, msg *amqp.Message) map[string]string {
+	m := make(map[string]string, len(msg.ApplicationProperties)+3)
+	for k, v := range msg.ApplicationProperties {
+		m[k] = broker.EncodeHeader(codec, v)
+	}
+	if p := msg.Properties; p != nil {
+		if p.MessageID != nil {
+			m["message-id"] = fmt.Sprint(p.MessageID)
+		}
+		if p.Subject != nil {
+			m["subject"] = *p.Subject
+		}
+		if p.ContentType != nil {
+			m["content-type"] = *p.ContentType
+		}
+	}
+	return m
+}
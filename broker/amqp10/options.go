@@ -0,0 +1,145 @@
+package amqp10
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+const (
+	// CapabilityQueue routes to an anycast address, e.g. an Artemis queue.
+	CapabilityQueue = "queue"
+	// CapabilityTopic routes to a multicast address, e.g. an Artemis topic.
+	CapabilityTopic = "topic"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+
+type authKey struct{}
+type anonymousKey struct{}
+type containerIdKey struct{}
+type connectTimeoutKey struct{}
+type idleTimeoutKey struct{}
+type tlsConfigKey struct{}
+
+type authRecord struct {
+	username string
+	password string
+}
+
+// WithAuth authenticate with SASL PLAIN.
+func WithAuth(username string, password string) broker.Option {
+	return broker.OptionContextWithValue(authKey{}, &authRecord{
+		username: username,
+		password: password,
+	})
+}
+
+// WithAnonymous authenticate with SASL ANONYMOUS.
+func WithAnonymous() broker.Option {
+	return broker.OptionContextWithValue(anonymousKey{}, true)
+}
+
+// WithContainerID set the container id of the connection, durable
+// subscriptions are identified by the container id and the link name, so
+// it must be stable across restarts.
+func WithContainerID(id string) broker.Option {
+	return broker.OptionContextWithValue(containerIdKey{}, id)
+}
+
+func WithConnectTimeout(timeout time.Duration) broker.Option {
+	return broker.OptionContextWithValue(connectTimeoutKey{}, timeout)
+}
+
+func WithIdleTimeout(timeout time.Duration) broker.Option {
+	return broker.OptionContextWithValue(idleTimeoutKey{}, timeout)
+}
+
+func WithTLSConfig(config *tls.Config) broker.Option {
+	return broker.OptionContextWithValue(tlsConfigKey{}, config)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+type headerKey struct{}
+type messageIdKey struct{}
+type subjectKey struct{}
+type contentTypeKey struct{}
+type durableMessageKey struct{}
+type priorityKey struct{}
+type ttlKey struct{}
+type targetCapabilitiesKey struct{}
+
+// WithHeaders set the application properties of the message.
+func WithHeaders(h map[string]string) broker.PublishOption {
+	return broker.PublishContextWithValue(headerKey{}, h)
+}
+
+func WithMessageID(id string) broker.PublishOption {
+	return broker.PublishContextWithValue(messageIdKey{}, id)
+}
+
+func WithSubject(subject string) broker.PublishOption {
+	return broker.PublishContextWithValue(subjectKey{}, subject)
+}
+
+func WithContentType(contentType string) broker.PublishOption {
+	return broker.PublishContextWithValue(contentTypeKey{}, contentType)
+}
+
+// WithDurableMessage ask the broker to persist the message.
+func WithDurableMessage() broker.PublishOption {
+	return broker.PublishContextWithValue(durableMessageKey{}, true)
+}
+
+func WithPriority(priority uint8) broker.PublishOption {
+	return broker.PublishContextWithValue(priorityKey{}, priority)
+}
+
+func WithTTL(ttl time.Duration) broker.PublishOption {
+	return broker.PublishContextWithValue(ttlKey{}, ttl)
+}
+
+// WithTargetCapabilities set the capabilities of the sender target, such as
+// CapabilityTopic to publish to a multicast address.
+func WithTargetCapabilities(capabilities ...string) broker.PublishOption {
+	return broker.PublishContextWithValue(targetCapabilitiesKey{}, capabilities)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+type creditKey struct{}
+type durableSubscriptionKey struct{}
+type selectorKey struct{}
+type sourceCapabilitiesKey struct{}
+type rejectOnErrorKey struct{}
+
+// WithCredit set the link credit of the receiver, the broker delivers at
+// most credit unsettled messages, which are handled concurrently.
+func WithCredit(credit int32) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(creditKey{}, credit)
+}
+
+// WithDurableSubscription name the receiver link and keep its state on the
+// broker while disconnected, requires WithContainerID.
+func WithDurableSubscription(name string) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(durableSubscriptionKey{}, name)
+}
+
+// WithSelector filter the messages with a JMS selector expression.
+func WithSelector(selector string) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(selectorKey{}, selector)
+}
+
+// WithSourceCapabilities set the capabilities of the receiver source, such
+// as CapabilityTopic to subscribe to a multicast address.
+func WithSourceCapabilities(capabilities ...string) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(sourceCapabilitiesKey{}, capabilities)
+}
+
+// WithRejectOnError reject the message when the handler fails, so that it
+// is dead-lettered instead of redelivered.
+func WithRejectOnError() broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(rejectOnErrorKey{}, true)
+}
@@ -0,0 +1,48 @@
+package amqp10
+
+import (
+	"context"
+
+	amqp "github.com/Azure/go-amqp"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// settler settles the messages of a receiver link.
+type settler interface {
+	AcceptMessage(ctx context.Context, msg *amqp.Message) error
+	RejectMessage(ctx context.Context, msg *amqp.Message, e *amqp.Error) error
+	ModifyMessage(ctx context.Context, msg *amqp.Message, options *amqp.ModifyMessageOptions) error
+}
+
+type publication struct {
+	broker.AckState
+
+	settler settler
+	msg     *amqp.Message
+	m       *broker.Message
+	topic   string
+	err     error
+}
+
+func (p *publication) Ack() error {
+	return p.AckState.Ack(func() error {
+		return p.settler.AcceptMessage(context.Background(), p.msg)
+	})
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (p *publication) Topic() string {
+	return p.topic
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (p *publication) RawMessage() interface{} {
+	return p.msg
+}
@@ -0,0 +1,83 @@
+package amqp10
+
+import (
+	"context"
+	"testing"
+
+	amqp "github.com/Azure/go-amqp"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+// settleRecorder records how the messages are settled.
+type settleRecorder struct {
+	accepted, rejected, modified int
+}
+
+func (s *settleRecorder) AcceptMessage(context.Context, *amqp.Message) error {
+	s.accepted++
+	return nil
+}
+
+func (s *settleRecorder) RejectMessage(context.Context, *amqp.Message, *amqp.Error) error {
+	s.rejected++
+	return nil
+}
+
+func (s *settleRecorder) ModifyMessage(context.Context, *amqp.Message, *amqp.ModifyMessageOptions) error {
+	s.modified++
+	return nil
+}
+
+// newTestSubscriber return a subscriber handling messages without a server.
+func newTestSubscriber(opts []broker.Option, topic string, sopts ...broker.SubscribeOption) (*amqpBroker, *subscriber) {
+	b := NewBroker(opts...).(*amqpBroker)
+
+	sub := &subscriber{
+		b:       b,
+		options: broker.NewSubscribeOptions(sopts...),
+		topic:   topic,
+		cancel:  func() {},
+		done:    make(chan struct{}),
+	}
+	close(sub.done)
+
+	return b, sub
+}
+
+func TestSubscribePath(t *testing.T) {
+	mocks.TestSubscribePath(t, func(opts []broker.Option, topic string, headers broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) {
+		b, sub := newTestSubscriber(opts, topic, sopts...)
+
+		properties := make(map[string]any, len(headers))
+		for k, v := range headers {
+			properties[k] = v
+		}
+
+		b.handleMessage(sub, &settleRecorder{}, &amqp.Message{Data: [][]byte{body}, ApplicationProperties: properties}, handler, binder, false)
+	}, true)
+}
+
+func TestUnmarshalFailureSettlement(t *testing.T) {
+	binder := func() broker.Any { return new(string) }
+	handler := func(context.Context, broker.Event) error { return nil }
+
+	for _, tt := range []struct {
+		name                         string
+		sopts                        []broker.SubscribeOption
+		accepted, rejected, modified int
+	}{
+		{name: "default", rejected: 1},
+		{name: "skip", sopts: []broker.SubscribeOption{broker.WithUnmarshalSkip()}, accepted: 1},
+		{name: "fail", sopts: []broker.SubscribeOption{broker.WithUnmarshalFail()}, modified: 1},
+	} {
+		b, sub := newTestSubscriber([]broker.Option{broker.WithCodec("json")}, "orders", tt.sopts...)
+		s := &settleRecorder{}
+
+		b.handleMessage(sub, s, &amqp.Message{Data: [][]byte{[]byte(`{`)}}, handler, binder, false)
+		if s.accepted != tt.accepted || s.rejected != tt.rejected || s.modified != tt.modified {
+			t.Errorf("%s: settled %+v", tt.name, *s)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package amqp10
+
+import (
+	"context"
+	"sync"
+
+	amqp "github.com/Azure/go-amqp"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type subscriber struct {
+	sync.RWMutex
+
+	b *amqpBroker
+
+	options  broker.SubscribeOptions
+	topic    string
+	receiver *amqp.Receiver
+	cancel   context.CancelFunc
+	done     chan struct{}
+	closed   bool
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.options
+}
+
+func (s *subscriber) Topic() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.topic
+}
+
+// Unsubscribe closes the receiver link, which also removes a durable
+// subscription from the broker.
+func (s *subscriber) Unsubscribe(removeFromManager bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	s.cancel()
+	<-s.done
+
+	var err error
+	if s.receiver != nil {
+		err = s.receiver.Close(context.Background())
+	}
+
+	if s.b != nil && s.b.subscribers != nil && removeFromManager {
+		_ = s.b.subscribers.RemoveOnly(s.topic)
+	}
+
+	return err
+}
+
+func (s *subscriber) IsClosed() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.closed
+}
@@ -0,0 +1,26 @@
+package amqp10
+
+import (
+	"regexp"
+)
+
+var re = regexp.MustCompile("^amqps?://.*")
+
+func hasUrlPrefix(url string) bool {
+	return re.MatchString(url)
+}
+
+func isSchema(schema string) bool {
+	return schema == "amqp" || schema == "amqps"
+}
+
+func refitUrl(url string, enableTLS bool) string {
+	if !hasUrlPrefix(url) {
+		prefix := "amqp://"
+		if enableTLS {
+			prefix = "amqps://"
+		}
+		url = prefix + url
+	}
+	return url
+}
@@ -0,0 +1,319 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var (
+	ErrAlreadyRunning = errors.New("archive: already running")
+	ErrNotUploaded    = errors.New("archive: segments not uploaded")
+)
+
+// Archiver subscribes to topics and writes their messages to a store in
+// compressed segments, one per topic, sealed by size or by age:
+//
+//	{prefix}{topic}/2024/05/17/09/{start}-{seq}.jsonl.gz
+//
+// The messages are acknowledged once their segment is uploaded, a failed
+// upload is retried, and blocks the later segments, until Stop. The records
+// archived from a broker whose messages have no headers, see
+// broker.CarriesHeaders, have none.
+type Archiver struct {
+	sync.Mutex
+
+	b      broker.Broker
+	store  Store
+	topics []string
+
+	format        Format
+	compression   Compression
+	prefix        string
+	maxSize       int64
+	maxAge        time.Duration
+	subscribeOpts []broker.SubscribeOption
+	errorHandler  func(err error)
+	clock         broker.Clock
+
+	subs     []broker.Subscriber
+	segments map[string]*segment
+	sealed   []*segment
+	seq      uint64
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func New(b broker.Broker, store Store, topics []string, opts ...Option) *Archiver {
+	a := &Archiver{
+		b:      b,
+		store:  store,
+		topics: topics,
+
+		format:      JSONLines,
+		compression: CompressionGzip,
+		maxSize:     DefaultMaxSegmentSize,
+		maxAge:      DefaultMaxSegmentAge,
+		clock:       broker.SystemClock,
+
+		segments: make(map[string]*segment),
+	}
+
+	for _, o := range opts {
+		o(a)
+	}
+
+	return a
+}
+
+// Run subscribe to the topics and start archiving.
+func (a *Archiver) Run() error {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.quit != nil {
+		return ErrAlreadyRunning
+	}
+
+	opts := append([]broker.SubscribeOption{broker.DisableAutoAck()}, a.subscribeOpts...)
+	for _, topic := range a.topics {
+		sub, err := a.subscribe(topic, opts)
+		if err != nil {
+			_ = unsubscribe(a.subs)
+			a.subs = nil
+			return err
+		}
+		a.subs = append(a.subs, sub)
+	}
+
+	a.quit = make(chan struct{})
+	a.wg.Add(1)
+	go a.rotate(a.quit)
+
+	return nil
+}
+
+// Stop unsubscribe and upload the open segments, ErrNotUploaded is
+// returned when some could not be, their messages are left unacknowledged.
+func (a *Archiver) Stop() error {
+	a.Lock()
+	quit, subs := a.quit, a.subs
+	a.quit, a.subs = nil, nil
+	a.Unlock()
+
+	if quit == nil {
+		return nil
+	}
+
+	// unsubscribe unlocked, the drivers wait for the running handlers.
+	err := unsubscribe(subs)
+	close(quit)
+	a.wg.Wait()
+
+	a.Lock()
+	defer a.Unlock()
+
+	for topic, seg := range a.segments {
+		a.seal(seg)
+		delete(a.segments, topic)
+	}
+	a.upload()
+
+	if n := len(a.sealed); n > 0 {
+		a.sealed = nil
+		return errors.Join(err, fmt.Errorf("%w: %d", ErrNotUploaded, n))
+	}
+	return err
+}
+
+func (a *Archiver) subscribe(topic string, opts []broker.SubscribeOption) (broker.Subscriber, error) {
+	sub, err := broker.SubscribeRaw(a.b, topic, a.handle, opts...)
+	if errors.Is(err, broker.ErrRawUnsupported) {
+		opts = append(append([]broker.SubscribeOption{}, opts...), broker.WithRawBody())
+		return a.b.Subscribe(topic, broker.RawHandler(a.handle).Handler(), nil, opts...)
+	}
+	return sub, err
+}
+
+func unsubscribe(subs []broker.Subscriber) error {
+	var errs []error
+	for _, sub := range subs {
+		if err := sub.Unsubscribe(true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a *Archiver) handle(_ context.Context, evt broker.RawEvent) error {
+	a.Lock()
+	defer a.Unlock()
+
+	topic := evt.Topic()
+	seg, ok := a.segments[topic]
+	if !ok {
+		var err error
+		if seg, err = a.newSegment(topic); err != nil {
+			return err
+		}
+		a.segments[topic] = seg
+	}
+
+	if err := seg.records.Write(&Record{
+		Topic:   topic,
+		Headers: evt.Headers(),
+		Body:    evt.Body(),
+		Time:    a.clock.Now(),
+	}); err != nil {
+		return err
+	}
+	seg.events = append(seg.events, evt)
+
+	if a.maxSize > 0 && seg.size >= a.maxSize {
+		a.seal(seg)
+		delete(a.segments, topic)
+		a.upload()
+	}
+
+	return nil
+}
+
+// rotate seal the segments older than maxAge and retry the failed uploads.
+func (a *Archiver) rotate(quit chan struct{}) {
+	defer a.wg.Done()
+
+	interval := time.Second
+	if a.maxAge > 0 && a.maxAge < interval {
+		interval = a.maxAge
+	}
+	ticker := a.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case now := <-ticker.C():
+			a.Lock()
+			for topic, seg := range a.segments {
+				if a.maxAge > 0 && now.Sub(seg.start) >= a.maxAge {
+					a.seal(seg)
+					delete(a.segments, topic)
+				}
+			}
+			a.upload()
+			a.Unlock()
+		}
+	}
+}
+
+// seal close the writers of seg and queue it for upload.
+func (a *Archiver) seal(seg *segment) {
+	if err := seg.close(); err != nil {
+		a.reportError(fmt.Errorf("archive: seal segment of [%s] failed: %w", seg.topic, err))
+		return
+	}
+	a.sealed = append(a.sealed, seg)
+}
+
+// upload put the sealed segments in order and acknowledge their messages,
+// stopping at the first failure so that no later message is acknowledged.
+func (a *Archiver) upload() {
+	for len(a.sealed) > 0 {
+		seg := a.sealed[0]
+		data := seg.buf.Bytes()
+		if err := a.store.Put(context.Background(), seg.key, bytes.NewReader(data), int64(len(data))); err != nil {
+			a.reportError(fmt.Errorf("archive: upload [%s] failed: %w", seg.key, err))
+			return
+		}
+		a.sealed = a.sealed[1:]
+
+		for _, evt := range seg.events {
+			if err := evt.Ack(); err != nil {
+				a.reportError(fmt.Errorf("archive: ack message of [%s] failed: %w", seg.topic, err))
+			}
+		}
+	}
+}
+
+func (a *Archiver) reportError(err error) {
+	if a.errorHandler != nil {
+		a.errorHandler(err)
+		return
+	}
+	log.Errorf("[archive] %v", err)
+}
+
+func (a *Archiver) newSegment(topic string) (*segment, error) {
+	a.seq++
+	start := a.clock.Now().UTC()
+
+	seg := &segment{
+		topic: topic,
+		start: start,
+		key: fmt.Sprintf("%s%s/%s/%d-%d%s%s", a.prefix, topic, start.Format("2006/01/02/15"),
+			start.UnixNano(), a.seq, a.format.Extension(), a.compression.Extension()),
+	}
+
+	var err error
+	if seg.compressor, err = a.compression.newWriter(&seg.buf); err != nil {
+		return nil, err
+	}
+	seg.records = a.format.NewWriter(&countingWriter{w: seg.compressor, n: &seg.size})
+
+	return seg, nil
+}
+
+type segment struct {
+	topic string
+	start time.Time
+	key   string
+
+	buf        bytes.Buffer
+	compressor io.WriteCloser
+	records    RecordWriter
+	// size is the length of the records before compression.
+	size int64
+
+	events []broker.RawEvent
+}
+
+func (s *segment) close() error {
+	if err := s.records.Close(); err != nil {
+		return err
+	}
+	return s.compressor.Close()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// Replay publish the records of a segment back to their topics on b. A broker
+// whose messages have no headers, see broker.CarriesHeaders, gets the bodies
+// only, the archived headers are dropped.
+func Replay(ctx context.Context, b broker.Broker, r io.Reader, format Format, compression Compression, opts ...broker.PublishOption) error {
+	headers := broker.CarriesHeaders(b)
+	return ReadSegment(r, format, compression, func(rec *Record) error {
+		if !headers {
+			return b.Publish(ctx, rec.Topic, rec.Body, opts...)
+		}
+		return b.Publish(ctx, rec.Topic, rec.Body, append([]broker.PublishOption{broker.WithHeaders(rec.Headers)}, opts...)...)
+	})
+}
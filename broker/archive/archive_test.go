@@ -0,0 +1,233 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func newFakeBroker(t *testing.T) *mocks.FakeBroker {
+	b := mocks.NewFakeBroker()
+	assert.NoError(t, b.Connect())
+	return b
+}
+
+func deliver(t *testing.T, b *mocks.FakeBroker, topic, body string) *mocks.Event {
+	events, err := b.Deliver(context.Background(), topic, []byte(body), broker.Headers{"id": body})
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	return events[0]
+}
+
+type memoryStore struct {
+	sync.Mutex
+	fail     bool
+	segments map[string][]byte
+	keys     []string
+}
+
+func (s *memoryStore) Put(_ context.Context, key string, body io.Reader, size int64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.fail {
+		return errors.New("unavailable")
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != size {
+		return errors.New("size mismatch")
+	}
+	if s.segments == nil {
+		s.segments = map[string][]byte{}
+	}
+	s.segments[key] = data
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *memoryStore) setFail(fail bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.fail = fail
+}
+
+func (s *memoryStore) records(t *testing.T, key string) []*Record {
+	s.Lock()
+	defer s.Unlock()
+
+	var records []*Record
+	assert.NoError(t, ReadSegment(bytes.NewReader(s.segments[key]), JSONLines, CompressionGzip, func(r *Record) error {
+		records = append(records, r)
+		return nil
+	}))
+	return records
+}
+
+func TestArchiver_RotateBySize(t *testing.T) {
+	b := newFakeBroker(t)
+	store := &memoryStore{}
+
+	a := New(b, store, []string{"orders", "payments"},
+		WithPrefix("archive/"),
+		WithMaxSegmentSize(150),
+		WithMaxSegmentAge(0),
+	)
+	assert.NoError(t, a.Run())
+	assert.ErrorIs(t, a.Run(), ErrAlreadyRunning)
+
+	var events []*mocks.Event
+	for _, body := range []string{"o1", "o2", "o3"} {
+		events = append(events, deliver(t, b, "orders", body))
+	}
+	p1 := deliver(t, b, "payments", "p1")
+
+	// the first two records fill a segment, the others are still open.
+	assert.Len(t, store.keys, 1)
+	key := store.keys[0]
+	assert.True(t, strings.HasPrefix(key, "archive/orders/"), key)
+	assert.True(t, strings.HasSuffix(key, ".jsonl.gz"), key)
+	assert.True(t, events[0].IsAcked())
+	assert.True(t, events[1].IsAcked())
+	assert.False(t, events[2].IsAcked())
+
+	records := store.records(t, key)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "orders", records[0].Topic)
+	assert.Equal(t, []byte("o1"), records[0].Body)
+	assert.Equal(t, broker.Headers{"id": "o1"}, records[0].Headers)
+
+	assert.NoError(t, a.Stop())
+	assert.Len(t, store.keys, 3)
+	assert.True(t, events[2].IsAcked())
+	assert.True(t, p1.IsAcked())
+	events, _ = b.Deliver(context.Background(), "orders", []byte("o4"), nil)
+	assert.Empty(t, events, "unsubscribed")
+}
+
+func TestArchiver_RotateByAge(t *testing.T) {
+	b := newFakeBroker(t)
+	store := &memoryStore{}
+
+	a := New(b, store, []string{"orders"}, WithMaxSegmentAge(20*time.Millisecond))
+	assert.NoError(t, a.Run())
+	defer a.Stop()
+
+	evt := deliver(t, b, "orders", "o1")
+	assert.Eventually(t, evt.IsAcked, time.Second, 5*time.Millisecond)
+
+	store.Lock()
+	assert.Len(t, store.keys, 1)
+	store.Unlock()
+}
+
+func TestArchiver_RetryUpload(t *testing.T) {
+	b := newFakeBroker(t)
+	store := &memoryStore{fail: true}
+
+	var mtx sync.Mutex
+	var errs []error
+	a := New(b, store, []string{"orders"},
+		WithMaxSegmentSize(1),
+		WithMaxSegmentAge(0),
+		WithErrorHandler(func(err error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			errs = append(errs, err)
+		}),
+	)
+	assert.NoError(t, a.Run())
+
+	e1 := deliver(t, b, "orders", "o1")
+	e2 := deliver(t, b, "orders", "o2")
+	assert.False(t, e1.IsAcked())
+	assert.False(t, e2.IsAcked())
+
+	store.setFail(false)
+	assert.Eventually(t, e2.IsAcked, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, e1.IsAcked())
+
+	store.Lock()
+	assert.Len(t, store.keys, 2)
+	store.Unlock()
+	assert.Equal(t, "o1", string(store.records(t, store.keys[0])[0].Body))
+
+	mtx.Lock()
+	assert.NotEmpty(t, errs)
+	mtx.Unlock()
+
+	assert.NoError(t, a.Stop())
+}
+
+func TestArchiver_StopNotUploaded(t *testing.T) {
+	b := newFakeBroker(t)
+	store := &memoryStore{fail: true}
+
+	a := New(b, store, []string{"orders"}, WithErrorHandler(func(error) {}))
+	assert.NoError(t, a.Run())
+
+	evt := deliver(t, b, "orders", "o1")
+	assert.ErrorIs(t, a.Stop(), ErrNotUploaded)
+	assert.False(t, evt.IsAcked())
+}
+
+func TestFileStoreAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	b := newFakeBroker(t)
+
+	a := New(b, NewFileStore(dir), []string{"orders"}, WithCompression(CompressionNone))
+	assert.NoError(t, a.Run())
+	deliver(t, b, "orders", "o1")
+	deliver(t, b, "orders", "o2")
+	assert.NoError(t, a.Stop())
+
+	var files []string
+	assert.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	}))
+	assert.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0], ".jsonl"), files[0])
+
+	file, err := os.Open(files[0])
+	assert.NoError(t, err)
+	defer file.Close()
+
+	replay := newFakeBroker(t)
+	assert.NoError(t, Replay(context.Background(), replay, file, JSONLines, CompressionNone))
+	published := replay.PublishedTo("orders")
+	assert.Len(t, published, 2)
+	assert.Equal(t, []byte("o2"), published[1].Body)
+	assert.Equal(t, broker.Headers{"id": "o2"}, published[1].Headers)
+}
+
+func TestReplay_Headerless(t *testing.T) {
+	var segment bytes.Buffer
+	w := JSONLines.NewWriter(&segment)
+	assert.NoError(t, w.Write(&Record{Topic: "orders", Headers: broker.Headers{"id": "o1"}, Body: []byte("o1")}))
+	assert.NoError(t, w.Close())
+
+	replay := mocks.NewHeaderlessBroker()
+	assert.NoError(t, replay.Connect())
+	assert.NoError(t, Replay(context.Background(), replay, &segment, JSONLines, CompressionNone))
+	published := replay.PublishedTo("orders")
+	assert.Len(t, published, 1)
+	assert.Equal(t, []byte("o1"), published[0].Body)
+	assert.Empty(t, published[0].Headers)
+}
@@ -0,0 +1,142 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Record is an archived message, Body is base64 in the JSON lines.
+type Record struct {
+	Topic   string         `json:"topic"`
+	Headers broker.Headers `json:"headers,omitempty"`
+	Body    []byte         `json:"body"`
+	Time    time.Time      `json:"time"`
+}
+
+// RecordWriter appends records to a segment, Close is called before the
+// segment is uploaded.
+type RecordWriter interface {
+	Write(r *Record) error
+	Close() error
+}
+
+// RecordReader reads back the records of a segment, io.EOF after the last one.
+type RecordReader interface {
+	Read() (*Record, error)
+}
+
+// Format is the layout of the records in a segment, e.g. JSON lines or
+// Parquet.
+type Format interface {
+	Extension() string
+	NewWriter(w io.Writer) RecordWriter
+	NewReader(r io.Reader) RecordReader
+}
+
+// JSONLines writes a JSON object per line.
+var JSONLines Format = jsonLines{}
+
+type jsonLines struct{}
+
+func (jsonLines) Extension() string { return ".jsonl" }
+
+func (jsonLines) NewWriter(w io.Writer) RecordWriter {
+	return &jsonLinesWriter{enc: json.NewEncoder(w)}
+}
+
+func (jsonLines) NewReader(r io.Reader) RecordReader {
+	return &jsonLinesReader{dec: json.NewDecoder(bufio.NewReader(r))}
+}
+
+type jsonLinesWriter struct {
+	enc *json.Encoder
+}
+
+func (w *jsonLinesWriter) Write(r *Record) error { return w.enc.Encode(r) }
+
+func (w *jsonLinesWriter) Close() error { return nil }
+
+type jsonLinesReader struct {
+	dec *json.Decoder
+}
+
+func (r *jsonLinesReader) Read() (*Record, error) {
+	var rec Record
+	if err := r.dec.Decode(&rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+type Compression string
+
+const (
+	CompressionNone Compression = ""
+	CompressionGzip Compression = "gzip"
+)
+
+func (c Compression) Extension() string {
+	switch c {
+	case CompressionGzip:
+		return ".gz"
+	default:
+		return ""
+	}
+}
+
+func (c Compression) newWriter(w io.Writer) (io.WriteCloser, error) {
+	switch c {
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("archive: unknown compression %q", string(c))
+	}
+}
+
+func (c Compression) newReader(r io.Reader) (io.ReadCloser, error) {
+	switch c {
+	case CompressionNone:
+		return io.NopCloser(r), nil
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	default:
+		return nil, fmt.Errorf("archive: unknown compression %q", string(c))
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// ReadSegment call fn with every record of a segment written with format and compression.
+func ReadSegment(r io.Reader, format Format, compression Compression, fn func(r *Record) error) error {
+	rc, err := compression.newReader(r)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	rr := format.NewReader(rc)
+	for {
+		rec, err := rr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(rec); err != nil {
+			return err
+		}
+	}
+}
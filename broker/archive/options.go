@@ -0,0 +1,72 @@
+package archive
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+const (
+	DefaultMaxSegmentSize = 64 << 20
+	DefaultMaxSegmentAge  = 5 * time.Minute
+)
+
+type Option func(a *Archiver)
+
+// WithFormat set the layout of the segments, default is JSONLines.
+func WithFormat(format Format) Option {
+	return func(a *Archiver) {
+		a.format = format
+	}
+}
+
+// WithCompression set the compression of the segments, default is gzip.
+func WithCompression(c Compression) Option {
+	return func(a *Archiver) {
+		a.compression = c
+	}
+}
+
+// WithPrefix prepend prefix to the keys of the segments, e.g. "archive/".
+func WithPrefix(prefix string) Option {
+	return func(a *Archiver) {
+		a.prefix = prefix
+	}
+}
+
+// WithMaxSegmentSize seal a segment once its records take size bytes
+// before compression, default is 64MiB.
+func WithMaxSegmentSize(size int64) Option {
+	return func(a *Archiver) {
+		a.maxSize = size
+	}
+}
+
+// WithMaxSegmentAge seal a segment age after its first record, default is
+// 5 minutes. Zero only rotates by size.
+func WithMaxSegmentAge(age time.Duration) Option {
+	return func(a *Archiver) {
+		a.maxAge = age
+	}
+}
+
+// WithSubscribeOptions pass options to the subscriptions of the topics.
+func WithSubscribeOptions(opts ...broker.SubscribeOption) Option {
+	return func(a *Archiver) {
+		a.subscribeOpts = append(a.subscribeOpts, opts...)
+	}
+}
+
+// WithErrorHandler observe the failed uploads, they are retried until Stop.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(a *Archiver) {
+		a.errorHandler = fn
+	}
+}
+
+// WithClock set the clock of the segment rotation, broker.SystemClock by default.
+func WithClock(clock broker.Clock) Option {
+	return func(a *Archiver) {
+		a.clock = clock
+	}
+}
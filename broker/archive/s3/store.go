@@ -0,0 +1,88 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"github.com/tx7do/kratos-transport/broker/archive"
+)
+
+var _ archive.Store = (*Store)(nil)
+
+type Option func(s *Store)
+
+// WithStorageClass set the storage class of the segments, e.g. STANDARD_IA.
+func WithStorageClass(class string) Option {
+	return func(s *Store) {
+		s.storageClass = class
+	}
+}
+
+// WithPartSize set the part size of the multipart uploads, default is 5MiB.
+func WithPartSize(size int64) Option {
+	return func(s *Store) {
+		s.partSize = size
+	}
+}
+
+// Store uploads the segments to a bucket of S3, or of a compatible service
+// such as Aliyun OSS or MinIO.
+type Store struct {
+	uploader *s3manager.Uploader
+	bucket   string
+
+	storageClass string
+	partSize     int64
+}
+
+func NewStore(client s3iface.S3API, bucket string, opts ...Option) *Store {
+	s := &Store{
+		bucket:   bucket,
+		partSize: s3manager.DefaultUploadPartSize,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	s.uploader = s3manager.NewUploaderWithClient(client, func(u *s3manager.Uploader) {
+		u.PartSize = s.partSize
+	})
+
+	return s
+}
+
+func (s *Store) Put(ctx context.Context, key string, body io.Reader, _ int64) error {
+	input := &s3manager.UploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType(key)),
+	}
+	if strings.HasSuffix(key, ".gz") {
+		input.ContentEncoding = aws.String("gzip")
+	}
+	if s.storageClass != "" {
+		input.StorageClass = aws.String(s.storageClass)
+	}
+
+	_, err := s.uploader.UploadWithContext(ctx, input)
+	return err
+}
+
+func contentType(key string) string {
+	key = strings.TrimSuffix(key, ".gz")
+	switch {
+	case strings.HasSuffix(key, ".jsonl"):
+		return "application/x-ndjson"
+	case strings.HasSuffix(key, ".parquet"):
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/octet-stream"
+	}
+}
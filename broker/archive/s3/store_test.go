@@ -0,0 +1,47 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_Put(t *testing.T) {
+	var path, encoding, class string
+	var body []byte
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		path = r.URL.Path
+		encoding = r.Header.Get("Content-Encoding")
+		class = r.Header.Get("X-Amz-Storage-Class")
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer hs.Close()
+
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(hs.URL),
+		Region:           aws.String("us-east-1"),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	assert.NoError(t, err)
+
+	store := NewStore(s3.New(sess), "archive", WithStorageClass("STANDARD_IA"))
+	data := []byte("segment")
+	assert.NoError(t, store.Put(context.Background(), "orders/2024/05/17/09/1-1.jsonl.gz", bytes.NewReader(data), int64(len(data))))
+
+	assert.Equal(t, "/archive/orders/2024/05/17/09/1-1.jsonl.gz", path)
+	assert.Equal(t, "gzip", encoding)
+	assert.Equal(t, "STANDARD_IA", class)
+	assert.Equal(t, data, body)
+}
@@ -0,0 +1,54 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Store keeps the sealed segments, e.g. an S3 or OSS bucket.
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64) error
+}
+
+// StoreFunc adapts a function to Store.
+type StoreFunc func(ctx context.Context, key string, body io.Reader, size int64) error
+
+func (f StoreFunc) Put(ctx context.Context, key string, body io.Reader, size int64) error {
+	return f(ctx, key, body, size)
+}
+
+// FileStore writes the segments below a local directory, the key is the
+// relative path of the file.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (s *FileStore) Put(_ context.Context, key string, body io.Reader, _ int64) error {
+	name := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return err
+	}
+
+	// write aside and rename, a reader never sees a partial segment.
+	tmp := name + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, body); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err = file.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, name)
+}
@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// MessageIDHeader is the header the message id is read from.
+const MessageIDHeader = "x-message-id"
+
+type Direction string
+
+const (
+	DirectionPublish Direction = "publish"
+	DirectionConsume Direction = "consume"
+)
+
+// Record is the audit entry of one published or consumed message.
+type Record struct {
+	Time        time.Time      `json:"time"`
+	Direction   Direction      `json:"direction"`
+	Broker      string         `json:"broker"`
+	Topic       string         `json:"topic"`
+	MessageID   string         `json:"message_id,omitempty"`
+	Headers     broker.Headers `json:"headers,omitempty"`
+	Size        int            `json:"size"`
+	PayloadHash string         `json:"payload_hash,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
+
+type auditBroker struct {
+	broker.Broker
+
+	sink Sink
+
+	payloadHash bool
+	failOnError bool
+	onError     func(err error)
+}
+
+// NewBroker wraps b to write an audit record of every published and consumed message to sink.
+func NewBroker(b broker.Broker, sink Sink, opts ...Option) broker.Broker {
+	ab := &auditBroker{
+		Broker:  b,
+		sink:    sink,
+		onError: func(error) {},
+	}
+
+	for _, o := range opts {
+		o(ab)
+	}
+
+	return ab
+}
+
+func (b *auditBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	err := b.Broker.Publish(ctx, topic, msg, opts...)
+
+	options := broker.NewPublishOptions(opts...)
+	headers, _ := options.Context.Value(headersKey{}).(broker.Headers)
+
+	record := b.newRecord(DirectionPublish, topic, headers, msg, err)
+	record.MessageID = headers[MessageIDHeader]
+
+	if werr := b.write(ctx, record); werr != nil && err == nil {
+		return werr
+	}
+
+	return err
+}
+
+func (b *auditBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Broker.Subscribe(topic,
+		func(ctx context.Context, event broker.Event) error {
+			err := handler(ctx, event)
+
+			var (
+				headers broker.Headers
+				body    broker.Any
+			)
+			if msg := event.Message(); msg != nil {
+				headers = msg.Headers
+				body = msg.Body
+			}
+
+			record := b.newRecord(DirectionConsume, topic, headers, body, err)
+			if v, ok := event.(interface{ MessageID() string }); ok {
+				record.MessageID = v.MessageID()
+			} else {
+				record.MessageID = headers[MessageIDHeader]
+			}
+
+			if werr := b.write(ctx, record); werr != nil && err == nil {
+				return werr
+			}
+
+			return err
+		},
+		binder,
+		opts...,
+	)
+}
+
+func (b *auditBroker) newRecord(direction Direction, topic string, headers broker.Headers, body broker.Any, err error) *Record {
+	record := &Record{
+		Time:      time.Now(),
+		Direction: direction,
+		Broker:    b.Name(),
+		Topic:     topic,
+		Headers:   headers,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if body != nil {
+		if buf, merr := broker.Marshal(b.Options().Codec, body); merr == nil {
+			record.Size = len(buf)
+			if b.payloadHash {
+				sum := sha256.Sum256(buf)
+				record.PayloadHash = hex.EncodeToString(sum[:])
+			}
+		}
+	}
+
+	return record
+}
+
+// write reports sink failures, they fail the operation only with WithFailOnSinkError.
+func (b *auditBroker) write(ctx context.Context, record *Record) error {
+	err := b.sink.Write(ctx, record)
+	if err == nil {
+		return nil
+	}
+
+	err = fmt.Errorf("write audit record failed: %w", err)
+	b.onError(err)
+	if b.failOnError {
+		return err
+	}
+	return nil
+}
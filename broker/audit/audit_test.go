@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type testBroker struct {
+	broker.Broker
+	handler broker.Handler
+}
+
+func (b *testBroker) Name() string            { return "test" }
+func (b *testBroker) Options() broker.Options { return broker.NewOptions() }
+
+func (b *testBroker) Publish(context.Context, string, broker.Any, ...broker.PublishOption) error {
+	return nil
+}
+
+func (b *testBroker) Subscribe(_ string, handler broker.Handler, _ broker.Binder, _ ...broker.SubscribeOption) (broker.Subscriber, error) {
+	b.handler = handler
+	return nil, nil
+}
+
+type testEvent struct {
+	broker.Event
+	msg *broker.Message
+}
+
+func (e *testEvent) Message() *broker.Message { return e.msg }
+
+func TestAuditBroker(t *testing.T) {
+	var records []*Record
+	sink := SinkFunc(func(_ context.Context, r *Record) error {
+		records = append(records, r)
+		return nil
+	})
+
+	tb := &testBroker{}
+	b := NewBroker(tb, sink, WithPayloadHash())
+
+	assert.Nil(t, b.Publish(context.Background(), "orders", "hello",
+		WithHeaders(broker.Headers{MessageIDHeader: "id-1"}),
+	))
+
+	_, err := b.Subscribe("orders", func(context.Context, broker.Event) error {
+		return errors.New("failed")
+	}, nil)
+	assert.Nil(t, err)
+	assert.Error(t, tb.handler(context.Background(), &testEvent{msg: &broker.Message{Body: "hello"}}))
+
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, DirectionPublish, records[0].Direction)
+	assert.Equal(t, "id-1", records[0].MessageID)
+	assert.Equal(t, 5, records[0].Size)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", records[0].PayloadHash)
+	assert.Equal(t, DirectionConsume, records[1].Direction)
+	assert.Equal(t, "failed", records[1].Error)
+	assert.Equal(t, records[0].PayloadHash, records[1].PayloadHash)
+}
+
+func TestAuditBroker_SinkError(t *testing.T) {
+	sink := SinkFunc(func(context.Context, *Record) error { return errors.New("disk full") })
+
+	var reported error
+	b := NewBroker(&testBroker{}, sink, WithSinkErrorHandler(func(err error) { reported = err }))
+	assert.Nil(t, b.Publish(context.Background(), "orders", "hello"))
+	assert.Error(t, reported)
+
+	b = NewBroker(&testBroker{}, sink, WithFailOnSinkError())
+	assert.Error(t, b.Publish(context.Background(), "orders", "hello"))
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := NewFileSink(path)
+	assert.Nil(t, err)
+	assert.Nil(t, sink.Write(context.Background(), &Record{Topic: "a"}))
+	assert.Nil(t, sink.Write(context.Background(), &Record{Topic: "b"}))
+	assert.Nil(t, sink.Close())
+
+	f, err := os.Open(path)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	var topics []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &r))
+		topics = append(topics, r.Topic)
+	}
+	assert.Equal(t, []string{"a", "b"}, topics)
+}
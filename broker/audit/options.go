@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(b *auditBroker)
+
+// WithPayloadHash record the SHA-256 of the encoded payload.
+func WithPayloadHash() Option {
+	return func(b *auditBroker) {
+		b.payloadHash = true
+	}
+}
+
+// WithFailOnSinkError fail the publish, or the handling, when the record cannot be written.
+func WithFailOnSinkError() Option {
+	return func(b *auditBroker) {
+		b.failOnError = true
+	}
+}
+
+// WithSinkErrorHandler set the callback of sink failures.
+func WithSinkErrorHandler(fn func(err error)) Option {
+	return func(b *auditBroker) {
+		b.onError = fn
+	}
+}
+
+type headersKey struct{}
+
+// WithHeaders attach the headers to the audit record of a publish, the
+// message id is read from MessageIDHeader.
+func WithHeaders(headers broker.Headers) broker.PublishOption {
+	return broker.PublishContextWithValue(headersKey{}, headers)
+}
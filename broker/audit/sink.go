@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Sink stores audit records, records must never be modified once written.
+type Sink interface {
+	Write(ctx context.Context, record *Record) error
+}
+
+// SinkFunc adapts a function to Sink.
+type SinkFunc func(ctx context.Context, record *Record) error
+
+func (f SinkFunc) Write(ctx context.Context, record *Record) error {
+	return f(ctx, record)
+}
+
+// FileSink appends records as JSON lines to a file.
+type FileSink struct {
+	sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (s *FileSink) Write(_ context.Context, record *Record) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.enc.Encode(record)
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// BrokerSink publishes records to an audit topic, e.g. on a dedicated Kafka cluster.
+type BrokerSink struct {
+	b     broker.Broker
+	topic string
+	opts  []broker.PublishOption
+}
+
+func NewBrokerSink(b broker.Broker, topic string, opts ...broker.PublishOption) *BrokerSink {
+	return &BrokerSink{b: b, topic: topic, opts: opts}
+}
+
+func (s *BrokerSink) Write(ctx context.Context, record *Record) error {
+	buf, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.b.Publish(ctx, s.topic, buf, s.opts...)
+}
+
+// SQLSink inserts records with query, which takes in order: time, direction,
+// broker, topic, message_id, headers (JSON), size, payload_hash and error.
+// For example:
+//
+//	INSERT INTO message_audit (time, direction, broker, topic, message_id, headers, size, payload_hash, error) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+type SQLSink struct {
+	db    *sql.DB
+	query string
+}
+
+func NewSQLSink(db *sql.DB, query string) *SQLSink {
+	return &SQLSink{db: db, query: strings.TrimSpace(query)}
+}
+
+func (s *SQLSink) Write(ctx context.Context, record *Record) error {
+	headers, err := json.Marshal(record.Headers)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, s.query,
+		record.Time,
+		record.Direction,
+		record.Broker,
+		record.Topic,
+		record.MessageID,
+		string(headers),
+		record.Size,
+		record.PayloadHash,
+		record.Error,
+	)
+	return err
+}
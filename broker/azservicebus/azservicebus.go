@@ -0,0 +1,618 @@
+package azservicebus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	serviceBus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"go.opentelemetry.io/otel/attribute"
+	semConv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/tracing"
+)
+
+const (
+	defaultMaxMessages        = 1
+	defaultSessionIdleTimeout = time.Minute
+	retryInterval             = time.Second
+
+	// defaultMaxMessageSize is the message size limit of the standard tier.
+	defaultMaxMessageSize = 256 * 1024
+)
+
+type serviceBusBroker struct {
+	sync.RWMutex
+
+	options broker.Options
+
+	client  *serviceBus.Client
+	senders map[string]*serviceBus.Sender
+
+	subscribers *broker.SubscriberSyncMap
+
+	producerTracer *tracing.Tracer
+	consumerTracer *tracing.Tracer
+
+	metrics *broker.Metrics
+}
+
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.NewOptionsAndApply(append([]broker.Option{broker.WithMaxMessageSize(defaultMaxMessageSize)}, opts...)...)
+
+	b := &serviceBusBroker{
+		options:     options,
+		senders:     make(map[string]*serviceBus.Sender),
+		subscribers: broker.NewSubscriberSyncMap(),
+	}
+
+	return b
+}
+
+func (b *serviceBusBroker) Name() string {
+	return "azservicebus"
+}
+
+func (b *serviceBusBroker) Options() broker.Options {
+	if b.options.Context == nil {
+		b.options.Context = context.Background()
+	}
+	return b.options
+}
+
+func (b *serviceBusBroker) Address() string {
+	if len(b.options.Addrs) > 0 {
+		return b.options.Addrs[0]
+	}
+	return ""
+}
+
+func (b *serviceBusBroker) Init(opts ...broker.Option) error {
+	b.options.Apply(opts...)
+
+	if len(b.options.Tracings) > 0 {
+		b.producerTracer = tracing.NewTracer(trace.SpanKindProducer, "azservicebus-producer", b.options.Tracings...)
+		b.consumerTracer = tracing.NewTracer(trace.SpanKindConsumer, "azservicebus-consumer", b.options.Tracings...)
+	}
+
+	b.metrics = broker.NewMetrics("azservicebus", b.options.MeterProvider)
+
+	return nil
+}
+
+func (b *serviceBusBroker) Connect() error {
+	address := b.Address()
+	if address == "" {
+		return errors.New("the connection string or namespace is empty")
+	}
+
+	c := ConfigFromOptions(b.options)
+
+	var client *serviceBus.Client
+	var err error
+	if c.Credential != nil {
+		client, err = serviceBus.NewClient(address, c.Credential, c.ClientOptions)
+	} else {
+		client, err = serviceBus.NewClientFromConnectionString(address, c.ClientOptions)
+	}
+	if err != nil {
+		return err
+	}
+
+	b.Lock()
+	b.client = client
+	b.Unlock()
+
+	return nil
+}
+
+func (b *serviceBusBroker) Disconnect() error {
+	b.subscribers.Clear()
+
+	b.Lock()
+	defer b.Unlock()
+
+	ctx := context.Background()
+
+	for _, sender := range b.senders {
+		_ = sender.Close(ctx)
+	}
+	b.senders = make(map[string]*serviceBus.Sender)
+
+	var err error
+	if b.client != nil {
+		err = b.client.Close(ctx)
+		b.client = nil
+	}
+
+	return err
+}
+
+// Publish sends to a queue or a topic, the topic is the name of the entity.
+func (b *serviceBusBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	m, options, err := b.newMessage(ctx, topic, msg, opts)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = b.publish(ctx, topic, m, options)
+	b.metrics.RecordPublish(ctx, topic, start, err)
+
+	return err
+}
+
+// newMessage encode msg and run the publish interceptors, it returns the
+// message to send to topic with the options of the publish.
+func (b *serviceBusBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) (*serviceBus.Message, broker.PublishOptions, error) {
+	options := broker.PublishOptions{
+		Context: ctx,
+	}
+
+	buf, err := broker.Marshal(b.options.Codec, msg)
+	if err != nil {
+		return nil, options, err
+	}
+	buf, opts, err = b.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return nil, options, err
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	m := &serviceBus.Message{
+		Body:                  buf,
+		ApplicationProperties: make(map[string]any),
+	}
+
+	c := PublishConfigFromOptions(options)
+	for k, v := range c.Headers {
+		m.ApplicationProperties[k] = v
+	}
+	for k, v := range options.Headers {
+		m.ApplicationProperties[k] = v
+	}
+	if c.MessageID != "" {
+		m.MessageID = &c.MessageID
+	}
+	if c.SessionID != "" {
+		m.SessionID = &c.SessionID
+	}
+	if c.PartitionKey != "" {
+		m.PartitionKey = &c.PartitionKey
+	}
+	if c.CorrelationID != "" {
+		m.CorrelationID = &c.CorrelationID
+	}
+	if c.Subject != "" {
+		m.Subject = &c.Subject
+	}
+	if c.ContentType != "" {
+		m.ContentType = &c.ContentType
+	}
+	if c.TTL != 0 {
+		m.TimeToLive = &c.TTL
+	}
+	if !c.ScheduledEnqueueTime.IsZero() {
+		m.ScheduledEnqueueTime = &c.ScheduledEnqueueTime
+	}
+
+	return m, options, nil
+}
+
+func (b *serviceBusBroker) publish(ctx context.Context, topic string, msg *serviceBus.Message, options broker.PublishOptions) error {
+	sender, err := b.sender(topic)
+	if err != nil {
+		return err
+	}
+
+	span := b.startProducerSpan(options.Context, topic, msg)
+
+	err = sender.SendMessage(ctx, msg, nil)
+
+	b.finishProducerSpan(span, err)
+
+	return err
+}
+
+func (b *serviceBusBroker) sender(topic string) (*serviceBus.Sender, error) {
+	b.RLock()
+	sender, ok := b.senders[topic]
+	client := b.client
+	b.RUnlock()
+	if ok {
+		return sender, nil
+	}
+	if client == nil {
+		return nil, errors.New("not connected")
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if sender, ok = b.senders[topic]; ok {
+		return sender, nil
+	}
+
+	sender, err := client.NewSender(topic, nil)
+	if err != nil {
+		return nil, err
+	}
+	b.senders[topic] = sender
+
+	return sender, nil
+}
+
+// Subscribe receives from the queue named topic, or from the subscription
+// of the topic when a subscription name is set with broker.WithQueueName.
+func (b *serviceBusBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	b.RLock()
+	client := b.client
+	b.RUnlock()
+	if client == nil {
+		return nil, errors.New("not connected")
+	}
+
+	options := broker.SubscribeOptions{
+		Context: context.Background(),
+		AutoAck: true,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	handler = options.TimeoutHandler(handler)
+	handler = b.metrics.Handler(topic, handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sc := SubscribeConfigFromOptions(options)
+
+	sub := &subscriber{
+		b:       b,
+		options: options,
+		config:  sc,
+		topic:   topic,
+		cancel:  cancel,
+	}
+
+	maxMessages := sc.MaxMessages
+
+	receiveMode := serviceBus.ReceiveModePeekLock
+	if sc.ReceiveAndDelete {
+		receiveMode = serviceBus.ReceiveModeReceiveAndDelete
+	}
+
+	sessions, sessionId := sc.Sessions, sc.AcceptSession
+
+	if sessions > 0 || sessionId != "" {
+		idleTimeout := sc.SessionIdleTimeout
+
+		if sessionId != "" {
+			sessions = 1
+			idleTimeout = 0
+		}
+
+		sessionOptions := &serviceBus.SessionReceiverOptions{
+			ReceiveMode: receiveMode,
+		}
+
+		accept := func(ctx context.Context) (*serviceBus.SessionReceiver, error) {
+			switch {
+			case sessionId != "" && options.Queue != "":
+				return client.AcceptSessionForSubscription(ctx, topic, options.Queue, sessionId, sessionOptions)
+			case sessionId != "":
+				return client.AcceptSessionForQueue(ctx, topic, sessionId, sessionOptions)
+			case options.Queue != "":
+				return client.AcceptNextSessionForSubscription(ctx, topic, options.Queue, sessionOptions)
+			default:
+				return client.AcceptNextSessionForQueue(ctx, topic, sessionOptions)
+			}
+		}
+
+		for i := 0; i < sessions; i++ {
+			sub.done.Add(1)
+			go func() {
+				defer sub.done.Done()
+				b.sessionLoop(ctx, sub, accept, idleTimeout, maxMessages, handler, binder)
+			}()
+		}
+	} else {
+		receiverOptions := &serviceBus.ReceiverOptions{
+			ReceiveMode: receiveMode,
+		}
+		if sc.DeadLetterQueue {
+			receiverOptions.SubQueue = serviceBus.SubQueueDeadLetter
+		}
+
+		var receiver *serviceBus.Receiver
+		var err error
+		if options.Queue != "" {
+			receiver, err = client.NewReceiverForSubscription(topic, options.Queue, receiverOptions)
+		} else {
+			receiver, err = client.NewReceiverForQueue(topic, receiverOptions)
+		}
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		sub.done.Add(1)
+		go func() {
+			defer sub.done.Done()
+			b.receiveLoop(ctx, sub, receiver, maxMessages, handler, binder)
+		}()
+	}
+
+	b.subscribers.Add(topic, sub)
+
+	return sub, nil
+}
+
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (b *serviceBusBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
+
+func (b *serviceBusBroker) receiveLoop(ctx context.Context, sub *subscriber, receiver *serviceBus.Receiver, maxMessages int, handler broker.Handler, binder broker.Binder) {
+	defer func() {
+		_ = receiver.Close(context.Background())
+	}()
+
+	var s settler = receiver
+	if b.isReceiveAndDelete(sub) {
+		s = nil
+	}
+
+	for {
+		msgs, err := receiver.ReceiveMessages(ctx, maxMessages, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Errorf("[azservicebus] receive from [%s] failed: %s", sub.topic, err)
+			sub.options.ReportError(sub.options.Context, broker.ErrReceive, err, nil)
+			if !sleep(ctx, b.options.Clock, retryInterval) {
+				return
+			}
+			continue
+		}
+
+		var wg sync.WaitGroup
+		for _, msg := range msgs {
+			wg.Add(1)
+			go func(msg *serviceBus.ReceivedMessage) {
+				defer wg.Done()
+				b.handleMessage(sub, s, msg, handler, binder)
+			}(msg)
+		}
+		wg.Wait()
+	}
+}
+
+// sessionLoop accepts one session at a time and handles its messages in
+// order, the session is released once it has been idle for idleTimeout.
+func (b *serviceBusBroker) sessionLoop(ctx context.Context, sub *subscriber, accept func(context.Context) (*serviceBus.SessionReceiver, error), idleTimeout time.Duration, maxMessages int, handler broker.Handler, binder broker.Binder) {
+	for ctx.Err() == nil {
+		receiver, err := accept(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			var sbErr *serviceBus.Error
+			if errors.As(err, &sbErr) && sbErr.Code == serviceBus.CodeTimeout {
+				// no session is available yet
+				continue
+			}
+			log.Errorf("[azservicebus] accept session from [%s] failed: %s", sub.topic, err)
+			sub.options.ReportError(sub.options.Context, broker.ErrReceive, err, nil)
+			if !sleep(ctx, b.options.Clock, retryInterval) {
+				return
+			}
+			continue
+		}
+
+		var s settler = receiver
+		if b.isReceiveAndDelete(sub) {
+			s = nil
+		}
+
+		b.receiveSession(ctx, sub, receiver, s, idleTimeout, maxMessages, handler, binder)
+
+		_ = receiver.Close(context.Background())
+	}
+}
+
+func (b *serviceBusBroker) receiveSession(ctx context.Context, sub *subscriber, receiver *serviceBus.SessionReceiver, s settler, idleTimeout time.Duration, maxMessages int, handler broker.Handler, binder broker.Binder) {
+	for {
+		var msgs []*serviceBus.ReceivedMessage
+		var err error
+		if idleTimeout > 0 {
+			receiveCtx, cancel := context.WithTimeout(ctx, idleTimeout)
+			msgs, err = receiver.ReceiveMessages(receiveCtx, maxMessages, nil)
+			cancel()
+		} else {
+			msgs, err = receiver.ReceiveMessages(ctx, maxMessages, nil)
+		}
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, context.DeadlineExceeded) {
+				log.Errorf("[azservicebus] receive from session [%s] of [%s] failed: %s", receiver.SessionID(), sub.topic, err)
+				sub.options.ReportError(sub.options.Context, broker.ErrReceive, err, nil)
+			}
+			return
+		}
+		if len(msgs) == 0 {
+			return
+		}
+
+		for _, msg := range msgs {
+			b.handleMessage(sub, s, msg, handler, binder)
+		}
+	}
+}
+
+func (b *serviceBusBroker) isReceiveAndDelete(sub *subscriber) bool {
+	return sub.config.ReceiveAndDelete
+}
+
+func (b *serviceBusBroker) handleMessage(sub *subscriber, s settler, msg *serviceBus.ReceivedMessage, handler broker.Handler, binder broker.Binder) {
+	m := &broker.Message{
+		Headers: messageHeaders(b.options.HeaderCodec, msg),
+	}
+
+	p := &publication{settler: s, msg: msg, m: m, topic: sub.topic}
+
+	ctx, span := b.startConsumerSpan(sub.options.Context, sub.topic, msg)
+
+	if binder != nil && !sub.options.RawBody {
+		m.Body = binder()
+	} else {
+		m.Body = msg.Body
+	}
+
+	if err := b.options.Decode(&sub.options, m.Headers, msg.Body, &m.Body); err != nil {
+		p.err = err
+		log.Errorf("[azservicebus] unmarshal message failed: %s", err)
+		sub.options.ReportError(ctx, broker.ErrUnmarshal, err, p)
+
+		// the messages received and deleted are settled already.
+		action := sub.options.HandleUnmarshalFailure(ctx, sub.topic, m.Headers, msg.Body, err)
+		if s != nil {
+			var settleErr error
+			switch action {
+			case broker.UnmarshalActionAck:
+				settleErr = p.Ack()
+			case broker.UnmarshalActionRetry, broker.UnmarshalActionStop:
+				settleErr = p.Nack(func() error {
+					return s.AbandonMessage(context.Background(), msg, nil)
+				})
+			default:
+				settleErr = p.Nack(func() error {
+					reason := "UnmarshalFailed"
+					description := err.Error()
+					return s.DeadLetterMessage(context.Background(), msg, &serviceBus.DeadLetterOptions{
+						Reason:           &reason,
+						ErrorDescription: &description,
+					})
+				})
+			}
+			if settleErr != nil {
+				sub.options.ReportError(ctx, broker.ErrAck, settleErr, p)
+			}
+		}
+		if action == broker.UnmarshalActionStop {
+			// unsubscribe outside of the receive loop, which it waits for.
+			go func() { _ = sub.Unsubscribe(true) }()
+		}
+		b.finishConsumerSpan(span, err)
+		return
+	}
+
+	var err error
+	if p.err = handler(ctx, p); p.err != nil {
+		if s != nil && !broker.IsSettled(p) {
+			err = p.Nack(func() error {
+				if reason := sub.config.DeadLetterOnError; reason != "" {
+					description := p.err.Error()
+					return s.DeadLetterMessage(context.Background(), msg, &serviceBus.DeadLetterOptions{
+						Reason:           &reason,
+						ErrorDescription: &description,
+					})
+				}
+				return s.AbandonMessage(context.Background(), msg, nil)
+			})
+			if err != nil {
+				log.Errorf("[azservicebus] settle message failed: %s", err)
+				sub.options.ReportError(ctx, broker.ErrAck, err, p)
+			}
+		}
+		b.finishConsumerSpan(span, p.err)
+		return
+	}
+
+	if sub.options.AutoAck && s != nil {
+		if err = broker.AutoAck(p); err != nil {
+			log.Errorf("[azservicebus] complete message failed: %s", err)
+			sub.options.ReportError(ctx, broker.ErrAck, err, p)
+		}
+	}
+
+	b.finishConsumerSpan(span, err)
+}
+
+func (b *serviceBusBroker) startProducerSpan(ctx context.Context, topic string, msg *serviceBus.Message) trace.Span {
+	if b.producerTracer == nil {
+		return nil
+	}
+
+	carrier := NewMessageCarrier(msg.ApplicationProperties)
+
+	attrs := []attribute.KeyValue{
+		semConv.MessagingSystemKey.String("azservicebus"),
+		semConv.MessagingDestinationKindTopic,
+		semConv.MessagingDestinationKey.String(topic),
+	}
+
+	var span trace.Span
+	_, span = b.producerTracer.Start(ctx, carrier, attrs...)
+
+	return span
+}
+
+func (b *serviceBusBroker) finishProducerSpan(span trace.Span, err error) {
+	if b.producerTracer == nil {
+		return
+	}
+
+	b.producerTracer.End(context.Background(), span, err)
+}
+
+func (b *serviceBusBroker) startConsumerSpan(ctx context.Context, topic string, msg *serviceBus.ReceivedMessage) (context.Context, trace.Span) {
+	if b.consumerTracer == nil {
+		return ctx, nil
+	}
+
+	carrier := NewMessageCarrier(msg.ApplicationProperties)
+
+	attrs := []attribute.KeyValue{
+		semConv.MessagingSystemKey.String("azservicebus"),
+		semConv.MessagingDestinationKindTopic,
+		semConv.MessagingDestinationKey.String(topic),
+		semConv.MessagingOperationReceive,
+		semConv.MessagingMessageIDKey.String(msg.MessageID),
+	}
+
+	var span trace.Span
+	ctx, span = b.consumerTracer.Start(ctx, carrier, attrs...)
+
+	return ctx, span
+}
+
+func (b *serviceBusBroker) finishConsumerSpan(span trace.Span, err error) {
+	if b.consumerTracer == nil {
+		return
+	}
+
+	b.consumerTracer.End(context.Background(), span, err)
+}
+
+// sleep waits for d on clock, it returns false if ctx is done first.
+func sleep(ctx context.Context, clock broker.Clock, d time.Duration) bool {
+	t := clock.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C():
+		return true
+	}
+}
@@ -0,0 +1,131 @@
+package azservicebus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	api "github.com/tx7do/kratos-transport/testing/api/manual"
+)
+
+const (
+	testQueue        = "test_queue"
+	testTopic        = "test_topic"
+	testSubscription = "test_subscription"
+)
+
+func handleHygrothermograph(_ context.Context, topic string, headers broker.Headers, msg *api.Hygrothermograph) error {
+	log.Infof("Topic %s, Headers: %+v, Payload: %+v\n", topic, headers, msg)
+	return nil
+}
+
+func createBroker(t *testing.T) broker.Broker {
+	connectionString := os.Getenv("SERVICEBUS_CONNECTION_STRING")
+	if connectionString == "" {
+		t.Skip("SERVICEBUS_CONNECTION_STRING is not set, skip")
+	}
+
+	b := NewBroker(
+		broker.WithAddress(connectionString),
+		broker.WithCodec("json"),
+	)
+
+	_ = b.Init()
+
+	if err := b.Connect(); err != nil {
+		t.Logf("cant connect to broker, skip: %v", err)
+		t.Skip()
+	}
+
+	return b
+}
+
+func Test_Publish_WithJsonCodec(t *testing.T) {
+	ctx := context.Background()
+
+	b := createBroker(t)
+	defer b.Disconnect()
+
+	var msg api.Hygrothermograph
+	const count = 10
+	for i := 0; i < count; i++ {
+		msg.Humidity = float64(i)
+		msg.Temperature = float64(i)
+		err := b.Publish(ctx, testQueue, msg)
+		assert.Nil(t, err)
+	}
+
+	fmt.Printf("total send %d messages\n", count)
+}
+
+func Test_Publish_Scheduled(t *testing.T) {
+	ctx := context.Background()
+
+	b := createBroker(t)
+	defer b.Disconnect()
+
+	msg := api.Hygrothermograph{Humidity: 1, Temperature: 1}
+	err := b.Publish(ctx, testQueue, msg, WithScheduleDelay(time.Minute))
+	assert.Nil(t, err)
+}
+
+func Test_Subscribe_WithJsonCodec(t *testing.T) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	b := createBroker(t)
+	defer b.Disconnect()
+
+	_, err := b.Subscribe(testQueue,
+		api.RegisterHygrothermographJsonHandler(handleHygrothermograph),
+		api.HygrothermographCreator,
+		WithMaxMessages(10),
+	)
+	assert.Nil(t, err)
+
+	<-interrupt
+}
+
+func Test_Subscribe_TopicSubscription(t *testing.T) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	b := createBroker(t)
+	defer b.Disconnect()
+
+	_, err := b.Subscribe(testTopic,
+		api.RegisterHygrothermographJsonHandler(handleHygrothermograph),
+		api.HygrothermographCreator,
+		broker.WithQueueName(testSubscription),
+		WithDeadLetterOnError("HandlerFailed"),
+	)
+	assert.Nil(t, err)
+
+	<-interrupt
+}
+
+func Test_Subscribe_DeadLetterQueue(t *testing.T) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	b := createBroker(t)
+	defer b.Disconnect()
+
+	_, err := b.Subscribe(testQueue,
+		api.RegisterHygrothermographJsonHandler(handleHygrothermograph),
+		api.HygrothermographCreator,
+		WithDeadLetterQueue(),
+	)
+	assert.Nil(t, err)
+
+	<-interrupt
+}
@@ -0,0 +1,196 @@
+package azservicebus
+
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	serviceBus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed azservicebus configuration of a broker, the fields are
+// the ones set one by one with the broker options of the package.
+type Config struct {
+	// Credential makes the address a namespace instead of a connection
+	// string, see WithCredential.
+	Credential    azcore.TokenCredential
+	ClientOptions *serviceBus.ClientOptions
+}
+
+// WithConfig set the whole configuration at once, the zero fields are left
+// unset. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		var opts []broker.Option
+		if c.Credential != nil {
+			opts = append(opts, WithCredential(c.Credential))
+		}
+		if c.ClientOptions != nil {
+			opts = append(opts, WithClientOptions(c.ClientOptions))
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	var c Config
+	if opts.Context == nil {
+		return c
+	}
+
+	c.Credential, _ = opts.Context.Value(credentialKey{}).(azcore.TokenCredential)
+	c.ClientOptions, _ = opts.Context.Value(clientOptionsKey{}).(*serviceBus.ClientOptions)
+
+	return c
+}
+
+// SubscribeConfig is the typed azservicebus configuration of a subscription.
+type SubscribeConfig struct {
+	MaxMessages      int
+	ReceiveAndDelete bool
+	DeadLetterQueue  bool
+	// DeadLetterOnError is the dead-letter reason of a failed message, which
+	// is abandoned when empty.
+	DeadLetterOnError  string
+	Sessions           int
+	AcceptSession      string
+	SessionIdleTimeout time.Duration
+}
+
+// DefaultSubscribeConfig return the configuration of a subscription without
+// options.
+func DefaultSubscribeConfig() SubscribeConfig {
+	return SubscribeConfig{
+		MaxMessages:        defaultMaxMessages,
+		SessionIdleTimeout: defaultSessionIdleTimeout,
+	}
+}
+
+// WithSubscribeConfig set the whole subscription configuration at once, c
+// should start from DefaultSubscribeConfig.
+func WithSubscribeConfig(c SubscribeConfig) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		opts := []broker.SubscribeOption{
+			WithMaxMessages(c.MaxMessages),
+			WithSessionIdleTimeout(c.SessionIdleTimeout),
+		}
+		if c.ReceiveAndDelete {
+			opts = append(opts, WithReceiveAndDelete())
+		}
+		if c.DeadLetterQueue {
+			opts = append(opts, WithDeadLetterQueue())
+		}
+		if c.DeadLetterOnError != "" {
+			opts = append(opts, WithDeadLetterOnError(c.DeadLetterOnError))
+		}
+		if c.Sessions != 0 {
+			opts = append(opts, WithSessions(c.Sessions))
+		}
+		if c.AcceptSession != "" {
+			opts = append(opts, WithAcceptSession(c.AcceptSession))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// SubscribeConfigFromOptions return the subscription configuration set in
+// the context of opts, a max messages or idle timeout not above zero keeps
+// the default.
+func SubscribeConfigFromOptions(opts broker.SubscribeOptions) SubscribeConfig {
+	c := DefaultSubscribeConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(maxMessagesKey{}).(int); ok && v > 0 {
+		c.MaxMessages = v
+	}
+	c.ReceiveAndDelete, _ = opts.Context.Value(receiveAndDeleteKey{}).(bool)
+	c.DeadLetterQueue, _ = opts.Context.Value(deadLetterQueueKey{}).(bool)
+	c.DeadLetterOnError, _ = opts.Context.Value(deadLetterOnErrorKey{}).(string)
+	c.Sessions, _ = opts.Context.Value(sessionsKey{}).(int)
+	c.AcceptSession, _ = opts.Context.Value(acceptSessionKey{}).(string)
+	if v, ok := opts.Context.Value(sessionIdleTimeoutKey{}).(time.Duration); ok && v > 0 {
+		c.SessionIdleTimeout = v
+	}
+
+	return c
+}
+
+// PublishConfig is the typed azservicebus configuration of a publishing, the
+// zero fields are left unset.
+type PublishConfig struct {
+	// Headers are set as application properties.
+	Headers              map[string]string
+	MessageID            string
+	SessionID            string
+	PartitionKey         string
+	CorrelationID        string
+	Subject              string
+	ContentType          string
+	TTL                  time.Duration
+	ScheduledEnqueueTime time.Time
+}
+
+// WithPublishConfig set the whole publishing configuration at once.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		var opts []broker.PublishOption
+		if c.Headers != nil {
+			opts = append(opts, WithHeaders(c.Headers))
+		}
+		if c.MessageID != "" {
+			opts = append(opts, WithMessageID(c.MessageID))
+		}
+		if c.SessionID != "" {
+			opts = append(opts, WithSessionID(c.SessionID))
+		}
+		if c.PartitionKey != "" {
+			opts = append(opts, WithPartitionKey(c.PartitionKey))
+		}
+		if c.CorrelationID != "" {
+			opts = append(opts, WithCorrelationID(c.CorrelationID))
+		}
+		if c.Subject != "" {
+			opts = append(opts, WithSubject(c.Subject))
+		}
+		if c.ContentType != "" {
+			opts = append(opts, WithContentType(c.ContentType))
+		}
+		if c.TTL != 0 {
+			opts = append(opts, WithTTL(c.TTL))
+		}
+		if !c.ScheduledEnqueueTime.IsZero() {
+			opts = append(opts, WithScheduledEnqueueTime(c.ScheduledEnqueueTime))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	var c PublishConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.Headers, _ = opts.Context.Value(headerKey{}).(map[string]string)
+	c.MessageID, _ = opts.Context.Value(messageIdKey{}).(string)
+	c.SessionID, _ = opts.Context.Value(sessionIdKey{}).(string)
+	c.PartitionKey, _ = opts.Context.Value(partitionKeyKey{}).(string)
+	c.CorrelationID, _ = opts.Context.Value(correlationIdKey{}).(string)
+	c.Subject, _ = opts.Context.Value(subjectKey{}).(string)
+	c.ContentType, _ = opts.Context.Value(contentTypeKey{}).(string)
+	c.TTL, _ = opts.Context.Value(ttlKey{}).(time.Duration)
+	c.ScheduledEnqueueTime, _ = opts.Context.Value(scheduledEnqueueTimeKey{}).(time.Time)
+
+	return c
+}
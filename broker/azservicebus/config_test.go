@@ -0,0 +1,63 @@
+package azservicebus
+
+import (
+	"testing"
+	"time"
+
+	serviceBus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, Config{}, ConfigFromOptions(broker.NewOptions()))
+
+	clientOptions := &serviceBus.ClientOptions{}
+
+	c := Config{ClientOptions: clientOptions}
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithClientOptions(clientOptions))))
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+}
+
+func TestSubscribeConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultSubscribeConfig(), SubscribeConfigFromOptions(broker.NewSubscribeOptions()))
+
+	legacy := SubscribeConfigFromOptions(broker.NewSubscribeOptions(
+		WithMaxMessages(10),
+		WithDeadLetterOnError("failed"),
+		WithSessions(2),
+	))
+
+	c := DefaultSubscribeConfig()
+	c.MaxMessages = 10
+	c.DeadLetterOnError = "failed"
+	c.Sessions = 2
+
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c), WithMaxMessages(1)))
+	assert.Equal(t, 1, overridden.MaxMessages)
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	assert.Equal(t, PublishConfig{}, PublishConfigFromOptions(broker.PublishOptions{}))
+
+	at := time.Now().Add(time.Minute)
+
+	legacy := PublishConfigFromOptions(broker.NewPublishOptions(
+		WithSessionID("order-1"),
+		WithTTL(time.Hour),
+		WithScheduledEnqueueTime(at),
+	))
+
+	c := PublishConfig{
+		SessionID:            "order-1",
+		TTL:                  time.Hour,
+		ScheduledEnqueueTime: at,
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+}
@@ -0,0 +1,78 @@
+package azservicebus
+
+import (
+	"strconv"
+
+	serviceBus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var _ propagation.TextMapCarrier = (*MessageCarrier)(nil)
+
+// MessageCarrier injects and extracts trace context with the application
+// properties of a message.
+type MessageCarrier struct {
+	properties map[string]any
+}
+
+func NewMessageCarrier(properties map[string]any) MessageCarrier {
+	return MessageCarrier{properties: properties}
+}
+
+func (c MessageCarrier) Get(key string) string {
+	if v, ok := c.properties[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func (c MessageCarrier) Set(key, val string) {
+	if c.properties == nil {
+		return
+	}
+	c.properties[key] = val
+}
+
+func (c MessageCarrier) Keys() []string {
+	out := make([]string, 0, len(c.properties))
+	for k := range c.properties {
+		out = append(out, k)
+	}
+	return out
+}
+
+func messageHeaders(codec broker.HeaderCodec, msg *serviceBus.ReceivedMessage) map[string]string {
+	m := make(map[string]string, len(msg.ApplicationProperties)+8)
+	for k, v := range msg.ApplicationProperties {
+		m[k] = broker.EncodeHeader(codec, v)
+	}
+
+	m["message-id"] = msg.MessageID
+	m["delivery-count"] = strconv.FormatUint(uint64(msg.DeliveryCount), 10)
+	if msg.SequenceNumber != nil {
+		m["sequence-number"] = strconv.FormatInt(*msg.SequenceNumber, 10)
+	}
+	if msg.SessionID != nil {
+		m["session-id"] = *msg.SessionID
+	}
+	if msg.Subject != nil {
+		m["subject"] = *msg.Subject
+	}
+	if msg.ContentType != nil {
+		m["content-type"] = *msg.ContentType
+	}
+	if msg.CorrelationID != nil {
+		m["correlation-id"] = *msg.CorrelationID
+	}
+	if msg.DeadLetterReason != nil {
+		m["dead-letter-reason"] = *msg.DeadLetterReason
+	}
+	if msg.DeadLetterErrorDescription != nil {
+		m["dead-letter-error-description"] = *msg.DeadLetterErrorDescription
+	}
+
+	return m
+}
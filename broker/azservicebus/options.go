@@ -0,0 +1,135 @@
+package azservicebus
+
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	serviceBus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+
+type credentialKey struct{}
+type clientOptionsKey struct{}
+
+// WithCredential authenticate with Microsoft Entra ID, the address is then the
+// fully qualified namespace, such as <namespace>.servicebus.windows.net.
+// Without a credential the address is used as a connection string.
+func WithCredential(credential azcore.TokenCredential) broker.Option {
+	return broker.OptionContextWithValue(credentialKey{}, credential)
+}
+
+func WithClientOptions(options *serviceBus.ClientOptions) broker.Option {
+	return broker.OptionContextWithValue(clientOptionsKey{}, options)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+type headerKey struct{}
+type messageIdKey struct{}
+type sessionIdKey struct{}
+type partitionKeyKey struct{}
+type correlationIdKey struct{}
+type subjectKey struct{}
+type contentTypeKey struct{}
+type ttlKey struct{}
+type scheduledEnqueueTimeKey struct{}
+
+// WithHeaders set the application properties of the message.
+func WithHeaders(h map[string]string) broker.PublishOption {
+	return broker.PublishContextWithValue(headerKey{}, h)
+}
+
+func WithMessageID(id string) broker.PublishOption {
+	return broker.PublishContextWithValue(messageIdKey{}, id)
+}
+
+// WithSessionID publish to a session enabled entity, messages of the same
+// session are delivered in order to a single consumer.
+func WithSessionID(id string) broker.PublishOption {
+	return broker.PublishContextWithValue(sessionIdKey{}, id)
+}
+
+func WithPartitionKey(key string) broker.PublishOption {
+	return broker.PublishContextWithValue(partitionKeyKey{}, key)
+}
+
+func WithCorrelationID(id string) broker.PublishOption {
+	return broker.PublishContextWithValue(correlationIdKey{}, id)
+}
+
+func WithSubject(subject string) broker.PublishOption {
+	return broker.PublishContextWithValue(subjectKey{}, subject)
+}
+
+func WithContentType(contentType string) broker.PublishOption {
+	return broker.PublishContextWithValue(contentTypeKey{}, contentType)
+}
+
+func WithTTL(ttl time.Duration) broker.PublishOption {
+	return broker.PublishContextWithValue(ttlKey{}, ttl)
+}
+
+// WithScheduledEnqueueTime keep the message invisible until the given time.
+func WithScheduledEnqueueTime(t time.Time) broker.PublishOption {
+	return broker.PublishContextWithValue(scheduledEnqueueTimeKey{}, t)
+}
+
+// WithScheduleDelay keep the message invisible for the given delay.
+func WithScheduleDelay(delay time.Duration) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		WithScheduledEnqueueTime(time.Now().Add(delay))(o)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+
+type maxMessagesKey struct{}
+type receiveAndDeleteKey struct{}
+type deadLetterQueueKey struct{}
+type deadLetterOnErrorKey struct{}
+type sessionsKey struct{}
+type acceptSessionKey struct{}
+type sessionIdleTimeoutKey struct{}
+
+// WithMaxMessages set the number of messages received at once, which are
+// handled concurrently unless sessions are used.
+func WithMaxMessages(n int) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(maxMessagesKey{}, n)
+}
+
+// WithReceiveAndDelete remove the messages from the entity as soon as they are
+// received, they are lost if the handler fails.
+func WithReceiveAndDelete() broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(receiveAndDeleteKey{}, true)
+}
+
+// WithDeadLetterQueue receive from the dead-letter subqueue of the entity.
+func WithDeadLetterQueue() broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(deadLetterQueueKey{}, true)
+}
+
+// WithDeadLetterOnError dead-letter the message with the given reason when the
+// handler fails, instead of abandoning it for redelivery.
+func WithDeadLetterOnError(reason string) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(deadLetterOnErrorKey{}, reason)
+}
+
+// WithSessions receive from a session enabled entity, up to maxConcurrent
+// sessions are locked at once and the messages of a session are handled in order.
+func WithSessions(maxConcurrent int) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(sessionsKey{}, maxConcurrent)
+}
+
+// WithAcceptSession receive only from the session with the given id.
+func WithAcceptSession(id string) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(acceptSessionKey{}, id)
+}
+
+// WithSessionIdleTimeout release a session after it has been idle for the
+// given timeout, so that another session can be accepted.
+func WithSessionIdleTimeout(timeout time.Duration) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(sessionIdleTimeoutKey{}, timeout)
+}
@@ -0,0 +1,52 @@
+package azservicebus
+
+import (
+	"context"
+
+	serviceBus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// settler is implemented by both serviceBus.Receiver and serviceBus.SessionReceiver.
+type settler interface {
+	CompleteMessage(ctx context.Context, message *serviceBus.ReceivedMessage, options *serviceBus.CompleteMessageOptions) error
+	AbandonMessage(ctx context.Context, message *serviceBus.ReceivedMessage, options *serviceBus.AbandonMessageOptions) error
+	DeadLetterMessage(ctx context.Context, message *serviceBus.ReceivedMessage, options *serviceBus.DeadLetterOptions) error
+}
+
+type publication struct {
+	broker.AckState
+
+	settler settler
+	msg     *serviceBus.ReceivedMessage
+	m       *broker.Message
+	topic   string
+	err     error
+}
+
+// Ack completes the message, it is a no-op in receive-and-delete mode.
+func (p *publication) Ack() error {
+	return p.AckState.Ack(func() error {
+		if p.settler == nil {
+			return nil
+		}
+		return p.settler.CompleteMessage(context.Background(), p.msg, nil)
+	})
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (p *publication) Topic() string {
+	return p.topic
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (p *publication) RawMessage() interface{} {
+	return p.msg
+}
@@ -0,0 +1,27 @@
+package azservicebus
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func TestPublishPath(t *testing.T) {
+	mocks.TestPublishPath(t, func(opts []broker.Option, topic string, msg broker.Any, popts ...broker.PublishOption) (broker.Headers, []byte, error) {
+		b := NewBroker(opts...).(*serviceBusBroker)
+
+		m, _, err := b.newMessage(context.Background(), topic, msg, popts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		headers := broker.Headers{}
+		for k, v := range m.ApplicationProperties {
+			headers[k] = fmt.Sprint(v)
+		}
+		return headers, m.Body, nil
+	}, true)
+}
@@ -0,0 +1,83 @@
+package azservicebus
+
+import (
+	"context"
+	"testing"
+
+	serviceBus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+// settleRecorder records how the messages are settled.
+type settleRecorder struct {
+	completed, abandoned, deadLettered int
+}
+
+func (s *settleRecorder) CompleteMessage(context.Context, *serviceBus.ReceivedMessage, *serviceBus.CompleteMessageOptions) error {
+	s.completed++
+	return nil
+}
+
+func (s *settleRecorder) AbandonMessage(context.Context, *serviceBus.ReceivedMessage, *serviceBus.AbandonMessageOptions) error {
+	s.abandoned++
+	return nil
+}
+
+func (s *settleRecorder) DeadLetterMessage(context.Context, *serviceBus.ReceivedMessage, *serviceBus.DeadLetterOptions) error {
+	s.deadLettered++
+	return nil
+}
+
+// newTestSubscriber return a subscriber handling messages without a server.
+func newTestSubscriber(opts []broker.Option, topic string, sopts ...broker.SubscribeOption) (*serviceBusBroker, *subscriber) {
+	b := NewBroker(opts...).(*serviceBusBroker)
+	options := broker.NewSubscribeOptions(sopts...)
+
+	sub := &subscriber{
+		b:       b,
+		options: options,
+		config:  SubscribeConfigFromOptions(options),
+		topic:   topic,
+		cancel:  func() {},
+	}
+
+	return b, sub
+}
+
+func TestSubscribePath(t *testing.T) {
+	mocks.TestSubscribePath(t, func(opts []broker.Option, topic string, headers broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) {
+		b, sub := newTestSubscriber(opts, topic, sopts...)
+
+		properties := make(map[string]any, len(headers))
+		for k, v := range headers {
+			properties[k] = v
+		}
+
+		b.handleMessage(sub, &settleRecorder{}, &serviceBus.ReceivedMessage{Body: body, ApplicationProperties: properties}, handler, binder)
+	}, true)
+}
+
+func TestUnmarshalFailureSettlement(t *testing.T) {
+	binder := func() broker.Any { return new(string) }
+	handler := func(context.Context, broker.Event) error { return nil }
+
+	for _, tt := range []struct {
+		name                               string
+		sopts                              []broker.SubscribeOption
+		completed, abandoned, deadLettered int
+	}{
+		{name: "default", deadLettered: 1},
+		{name: "skip", sopts: []broker.SubscribeOption{broker.WithUnmarshalSkip()}, completed: 1},
+		{name: "fail", sopts: []broker.SubscribeOption{broker.WithUnmarshalFail()}, abandoned: 1},
+	} {
+		b, sub := newTestSubscriber([]broker.Option{broker.WithCodec("json")}, "orders", tt.sopts...)
+		s := &settleRecorder{}
+
+		b.handleMessage(sub, s, &serviceBus.ReceivedMessage{Body: []byte(`{`)}, handler, binder)
+		if s.completed != tt.completed || s.abandoned != tt.abandoned || s.deadLettered != tt.deadLettered {
+			t.Errorf("%s: settled %+v", tt.name, *s)
+		}
+	}
+}
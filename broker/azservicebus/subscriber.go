@@ -0,0 +1,63 @@
+package azservicebus
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type subscriber struct {
+	sync.RWMutex
+
+	b *serviceBusBroker
+
+	options broker.SubscribeOptions
+	config  SubscribeConfig
+	topic   string
+	cancel  context.CancelFunc
+	done    sync.WaitGroup
+	closed  bool
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.options
+}
+
+func (s *subscriber) Topic() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.topic
+}
+
+// Unsubscribe stops receiving and waits for the in-flight handlers, the
+// receivers are closed by their receive loops.
+func (s *subscriber) Unsubscribe(removeFromManager bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	s.cancel()
+	s.done.Wait()
+
+	if s.b != nil && s.b.subscribers != nil && removeFromManager {
+		_ = s.b.subscribers.RemoveOnly(s.topic)
+	}
+
+	return nil
+}
+
+func (s *subscriber) IsClosed() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.closed
+}
@@ -0,0 +1,337 @@
+package bluegreen
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var (
+	ErrAlreadyStarted = errors.New("bluegreen: consumer already started")
+	ErrNoGeneration   = errors.New("bluegreen: generation is required")
+)
+
+// DefaultGroupFormat suffix the group with the generation, e.g. "billing.green".
+func DefaultGroupFormat(group, generation string) string {
+	return group + "." + generation
+}
+
+// WithGeneration subscribe under group suffixed with generation.
+func WithGeneration(group, generation string) broker.SubscribeOption {
+	return broker.WithQueueName(DefaultGroupFormat(group, generation))
+}
+
+type registration struct {
+	topic   string
+	handler broker.Handler
+	binder  broker.Binder
+	opts    []broker.SubscribeOption
+}
+
+// Consumer runs the subscriptions of one generation of a consumer group,
+// only while the generation is the active one of the store:
+//
+//	c := bluegreen.NewConsumer(b, store, "billing", os.Getenv("GENERATION"))
+//	_ = c.Subscribe("orders", handler, binder)
+//	_ = c.Start(ctx)
+//
+// and, once the new generation is deployed:
+//
+//	_ = bluegreen.Switch(ctx, store, "billing", "green", 10*time.Second)
+type Consumer struct {
+	sync.Mutex
+
+	b          broker.Broker
+	store      Store
+	group      string
+	generation string
+
+	interval    time.Duration
+	groupFormat func(group, generation string) string
+	onSwitch    func(active bool)
+	clock       broker.Clock
+
+	registrations []registration
+	subs          []broker.Subscriber
+	running       bool
+	inflight      inflight
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewConsumer(b broker.Broker, store Store, group, generation string, opts ...Option) *Consumer {
+	c := &Consumer{
+		b:          b,
+		store:      store,
+		group:      group,
+		generation: generation,
+
+		interval:    time.Second,
+		groupFormat: DefaultGroupFormat,
+		clock:       broker.SystemClock,
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
+}
+
+// Subscribe register a subscription of the generation, it is made while the
+// generation is active and removed while it is not.
+func (c *Consumer) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) error {
+	c.Lock()
+	defer c.Unlock()
+
+	r := registration{topic: topic, handler: handler, binder: binder, opts: opts}
+	c.registrations = append(c.registrations, r)
+
+	if !c.running {
+		return nil
+	}
+	sub, err := c.subscribe(r)
+	if err != nil {
+		return err
+	}
+	c.subs = append(c.subs, sub)
+	return nil
+}
+
+// Start follow the state of the group. The first generation started claims
+// the group when it has no state yet.
+func (c *Consumer) Start(ctx context.Context) error {
+	if c.generation == "" {
+		return ErrNoGeneration
+	}
+
+	c.Lock()
+	if c.done != nil {
+		c.Unlock()
+		return ErrAlreadyStarted
+	}
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+	c.Unlock()
+
+	state, err := c.store.Load(ctx, c.group)
+	if err == nil && state.Active == "" && state.Next == "" {
+		state.Active = c.generation
+		err = c.store.Save(ctx, c.group, state)
+	}
+	if err == nil {
+		err = c.apply(state)
+	}
+	if err != nil {
+		c.Lock()
+		c.cancel()
+		c.cancel, c.done = nil, nil
+		c.Unlock()
+		return err
+	}
+
+	go c.watch(ctx, c.done)
+
+	return nil
+}
+
+// Stop stop following the group and remove the subscriptions.
+func (c *Consumer) Stop() error {
+	c.Lock()
+	cancel, done := c.cancel, c.done
+	c.cancel, c.done = nil, nil
+	c.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	<-done
+
+	return c.pause()
+}
+
+// Active reports whether the subscriptions of the generation are running.
+func (c *Consumer) Active() bool {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.running
+}
+
+func (c *Consumer) watch(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := c.clock.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			state, err := c.store.Load(ctx, c.group)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Errorf("[bluegreen] load state of [%s] failed: %v", c.group, err)
+				}
+				continue
+			}
+			if err = c.apply(state); err != nil {
+				log.Errorf("[bluegreen] apply state of [%s] failed: %v", c.group, err)
+			}
+		}
+	}
+}
+
+func (c *Consumer) apply(state State) error {
+	if state.Active == c.generation {
+		return c.resume()
+	}
+	return c.pause()
+}
+
+func (c *Consumer) resume() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.running {
+		return nil
+	}
+
+	for _, r := range c.registrations {
+		sub, err := c.subscribe(r)
+		if err != nil {
+			_ = c.unsubscribe()
+			return err
+		}
+		c.subs = append(c.subs, sub)
+	}
+	c.running = true
+
+	if c.onSwitch != nil {
+		c.onSwitch(true)
+	}
+	log.Infof("[bluegreen] generation [%s] of [%s] started", c.generation, c.group)
+
+	return nil
+}
+
+// pause remove the subscriptions and wait for the running handlers.
+func (c *Consumer) pause() error {
+	c.Lock()
+	if !c.running {
+		c.Unlock()
+		return nil
+	}
+	err := c.unsubscribe()
+	c.running = false
+	if c.onSwitch != nil {
+		c.onSwitch(false)
+	}
+	c.Unlock()
+
+	c.inflight.wait()
+	log.Infof("[bluegreen] generation [%s] of [%s] paused", c.generation, c.group)
+
+	return err
+}
+
+func (c *Consumer) subscribe(r registration) (broker.Subscriber, error) {
+	handler := func(ctx context.Context, event broker.Event) error {
+		c.inflight.add()
+		defer c.inflight.done()
+		return r.handler(ctx, event)
+	}
+
+	opts := append(append([]broker.SubscribeOption{}, r.opts...),
+		broker.WithQueueName(c.groupFormat(c.group, c.generation)))
+
+	return c.b.Subscribe(r.topic, handler, r.binder, opts...)
+}
+
+func (c *Consumer) unsubscribe() error {
+	var errs []error
+	for _, sub := range c.subs {
+		if err := sub.Unsubscribe(true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.subs = nil
+	return errors.Join(errs...)
+}
+
+// Switch cut group over to generation: the active generation is paused
+// first, then, after drain, the new one is started, so that no message is
+// processed by both. drain must cover the poll interval of the consumers
+// and their longest handler. Run Switch again when it fails, no generation
+// runs until it succeeds.
+func Switch(ctx context.Context, store Store, group, generation string, drain time.Duration) error {
+	if generation == "" {
+		return ErrNoGeneration
+	}
+
+	state, err := store.Load(ctx, group)
+	if err != nil {
+		return err
+	}
+	if state.Active == generation && state.Next == "" {
+		return nil
+	}
+
+	// an interrupted switch left Next set, drain again as it may not have been.
+	if state.Active != "" || state.Next != "" {
+		if err = store.Save(ctx, group, State{Next: generation}); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(drain):
+		}
+	}
+
+	return store.Save(ctx, group, State{Active: generation})
+}
+
+// inflight counts the running handlers, unlike a sync.WaitGroup it may be
+// waited while handlers still start.
+type inflight struct {
+	mtx  sync.Mutex
+	n    int
+	idle *sync.Cond
+}
+
+func (f *inflight) add() {
+	f.mtx.Lock()
+	f.n++
+	f.mtx.Unlock()
+}
+
+func (f *inflight) done() {
+	f.mtx.Lock()
+	f.n--
+	if f.n == 0 && f.idle != nil {
+		f.idle.Broadcast()
+	}
+	f.mtx.Unlock()
+}
+
+func (f *inflight) wait() {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.idle == nil {
+		f.idle = sync.NewCond(&f.mtx)
+	}
+	for f.n > 0 {
+		f.idle.Wait()
+	}
+}
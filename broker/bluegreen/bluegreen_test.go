@@ -0,0 +1,121 @@
+package bluegreen
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+// subscribed return the queues subscribed to on b.
+func subscribed(b *mocks.FakeBroker) []string {
+	var queues []string
+	seen := map[string]bool{}
+	for _, topic := range []string{"orders", "payments"} {
+		for _, sub := range b.Subscribers(topic) {
+			if q := sub.Opts.Queue; !seen[q] {
+				seen[q] = true
+				queues = append(queues, q)
+			}
+		}
+	}
+	return queues
+}
+
+func TestSwitch(t *testing.T) {
+	b := mocks.NewFakeBroker()
+	store := NewMemoryStore()
+	ctx := context.Background()
+	handler := func(context.Context, broker.Event) error { return nil }
+
+	blue := NewConsumer(b, store, "billing", "blue", WithPollInterval(5*time.Millisecond))
+	assert.NoError(t, blue.Subscribe("orders", handler, nil))
+	assert.NoError(t, blue.Start(ctx))
+	assert.ErrorIs(t, blue.Start(ctx), ErrAlreadyStarted)
+	defer blue.Stop()
+
+	var mtx sync.Mutex
+	var switches []bool
+	green := NewConsumer(b, store, "billing", "green",
+		WithPollInterval(5*time.Millisecond),
+		WithSwitchCallback(func(active bool) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			switches = append(switches, active)
+		}),
+	)
+	assert.NoError(t, green.Subscribe("orders", handler, nil))
+	assert.NoError(t, green.Start(ctx))
+	defer green.Stop()
+
+	// blue claimed the group.
+	assert.True(t, blue.Active())
+	assert.False(t, green.Active())
+	assert.Equal(t, []string{"billing.blue"}, subscribed(b))
+
+	assert.NoError(t, Switch(ctx, store, "billing", "green", 50*time.Millisecond))
+	assert.False(t, blue.Active())
+	assert.Eventually(t, green.Active, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"billing.green"}, subscribed(b))
+
+	state, err := store.Load(ctx, "billing")
+	assert.NoError(t, err)
+	assert.Equal(t, State{Active: "green"}, state)
+
+	// the subscriptions registered later follow the generation.
+	assert.NoError(t, green.Subscribe("payments", handler, nil, broker.WithQueueName("ignored")))
+	assert.NoError(t, blue.Subscribe("payments", handler, nil))
+	assert.Equal(t, []string{"billing.green"}, subscribed(b))
+
+	assert.NoError(t, green.Stop())
+	assert.Empty(t, subscribed(b))
+
+	mtx.Lock()
+	assert.Equal(t, []bool{true, false}, switches)
+	mtx.Unlock()
+}
+
+func TestSwitch_WaitsHandlers(t *testing.T) {
+	b := mocks.NewFakeBroker()
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	blue := NewConsumer(b, store, "billing", "blue", WithPollInterval(5*time.Millisecond))
+	assert.NoError(t, blue.Subscribe("orders", func(context.Context, broker.Event) error {
+		close(started)
+		<-release
+		return nil
+	}, nil))
+	assert.NoError(t, blue.Start(ctx))
+
+	go func() { _, _ = b.Deliver(ctx, "orders", "o1", nil) }()
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		_ = blue.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("stopped with a running handler")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+}
+
+func TestSwitch_NoGeneration(t *testing.T) {
+	assert.ErrorIs(t, Switch(context.Background(), NewMemoryStore(), "billing", "", 0), ErrNoGeneration)
+	c := NewConsumer(mocks.NewFakeBroker(), NewMemoryStore(), "billing", "")
+	assert.ErrorIs(t, c.Start(context.Background()), ErrNoGeneration)
+	assert.Equal(t, "billing.green", broker.NewSubscribeOptions(WithGeneration("billing", "green")).Queue)
+}
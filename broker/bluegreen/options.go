@@ -0,0 +1,40 @@
+package bluegreen
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(c *Consumer)
+
+// WithPollInterval set how often the state of the group is loaded, default is 1s.
+func WithPollInterval(interval time.Duration) Option {
+	return func(c *Consumer) {
+		c.interval = interval
+	}
+}
+
+// WithGroupFormat set how the group and the generation make the queue, or
+// consumer group, name, default is DefaultGroupFormat. Returning the group
+// alone shares the position of the group between the generations, e.g.
+// the Kafka offsets.
+func WithGroupFormat(fn func(group, generation string) string) Option {
+	return func(c *Consumer) {
+		c.groupFormat = fn
+	}
+}
+
+// WithSwitchCallback observe the generation being started or paused.
+func WithSwitchCallback(fn func(active bool)) Option {
+	return func(c *Consumer) {
+		c.onSwitch = fn
+	}
+}
+
+// WithClock set the clock of the state polling, broker.SystemClock by default.
+func WithClock(clock broker.Clock) Option {
+	return func(c *Consumer) {
+		c.clock = clock
+	}
+}
@@ -0,0 +1,120 @@
+package bluegreen
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker/sqlutil"
+)
+
+// State is the coordination state of a consumer group. Only the consumers of
+// the Active generation run, Next is set while a switch drains the
+// previous one.
+type State struct {
+	Active string
+	Next   string
+}
+
+// Store keeps the state of the groups, shared by every deployment of them.
+type Store interface {
+	Load(ctx context.Context, group string) (State, error)
+	Save(ctx context.Context, group string, state State) error
+}
+
+// MemoryStore keeps the states in memory, for the consumers of one process and tests.
+type MemoryStore struct {
+	sync.Mutex
+	states map[string]State
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: map[string]State{}}
+}
+
+func (s *MemoryStore) Load(_ context.Context, group string) (State, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.states[group], nil
+}
+
+func (s *MemoryStore) Save(_ context.Context, group string, state State) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.states[group] = state
+	return nil
+}
+
+const DefaultTableName = "consumer_generations"
+
+// SQLStore keeps the states in a table shaped like:
+//
+//	CREATE TABLE consumer_generations (
+//	    group_name VARCHAR(255) NOT NULL PRIMARY KEY,
+//	    active     VARCHAR(255) NOT NULL,
+//	    next       VARCHAR(255) NOT NULL,
+//	    updated_at TIMESTAMP    NOT NULL
+//	);
+type SQLStore struct {
+	db          *sql.DB
+	table       string
+	placeholder sqlutil.PlaceholderFormat
+}
+
+func NewSQLStore(db *sql.DB, table string, placeholder sqlutil.PlaceholderFormat) *SQLStore {
+	if table == "" {
+		table = DefaultTableName
+	}
+	if placeholder == nil {
+		placeholder = sqlutil.Question
+	}
+	return &SQLStore{
+		db:          db,
+		table:       table,
+		placeholder: placeholder,
+	}
+}
+
+func (s *SQLStore) Load(ctx context.Context, group string) (State, error) {
+	query := fmt.Sprintf("SELECT active, next FROM %s WHERE group_name = %s", s.table, s.placeholder(1))
+
+	var state State
+	err := s.db.QueryRowContext(ctx, query, group).Scan(&state.Active, &state.Next)
+	if err == sql.ErrNoRows {
+		return State{}, nil
+	}
+	return state, err
+}
+
+func (s *SQLStore) Save(ctx context.Context, group string, state State) error {
+	now := time.Now()
+
+	query := fmt.Sprintf("UPDATE %s SET active = %s, next = %s, updated_at = %s WHERE group_name = %s",
+		s.table, s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4))
+	res, err := s.db.ExecContext(ctx, query, state.Active, state.Next, now, group)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	// mysql reports no affected row when the values are unchanged.
+	var found int
+	query = fmt.Sprintf("SELECT 1 FROM %s WHERE group_name = %s", s.table, s.placeholder(1))
+	switch err = s.db.QueryRowContext(ctx, query, group).Scan(&found); {
+	case err == nil:
+		return nil
+	case err != sql.ErrNoRows:
+		return err
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (group_name, active, next, updated_at) VALUES (%s, %s, %s, %s)",
+		s.table, s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4))
+	_, err = s.db.ExecContext(ctx, query, group, state.Active, state.Next, now)
+	return err
+}
@@ -0,0 +1,150 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+const MessageIDHeader = "x-message-id"
+
+var ErrSnapshot = errors.New("bootstrap: load snapshot failed")
+
+// KeyFunc return the dedup key of a message, empty when it has none.
+type KeyFunc func(event broker.Event) string
+
+// HeaderKeyFunc read the dedup key from header.
+func HeaderKeyFunc(header string) KeyFunc {
+	return func(event broker.Event) string {
+		if event == nil || event.Message() == nil {
+			return ""
+		}
+		return event.Message().Headers[header]
+	}
+}
+
+// Bootstrapper builds the state of a consumer from a snapshot, then
+// switches to the live stream from the position the snapshot ends at:
+//
+//	bs := bootstrap.New(b, "prices", bootstrap.TopicSnapshot(b, "prices.compacted", binder, 5*time.Second), handler, binder)
+//	sub, err := bs.Run(ctx)
+//
+// The live messages already read from the snapshot, at the boundary
+// between both, are acked without reaching the handler.
+type Bootstrapper struct {
+	b        broker.Broker
+	topic    string
+	snapshot Snapshot
+	handler  broker.Handler
+	binder   broker.Binder
+
+	keyFunc       KeyFunc
+	dedupSize     int
+	subscribeOpts []broker.SubscribeOption
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+	ring []string
+	next int
+}
+
+func New(b broker.Broker, topic string, snapshot Snapshot, handler broker.Handler, binder broker.Binder, opts ...Option) *Bootstrapper {
+	bs := &Bootstrapper{
+		b:         b,
+		topic:     topic,
+		snapshot:  snapshot,
+		handler:   handler,
+		binder:    binder,
+		keyFunc:   HeaderKeyFunc(MessageIDHeader),
+		dedupSize: 10000,
+	}
+
+	for _, o := range opts {
+		o(bs)
+	}
+
+	return bs
+}
+
+// Run load the snapshot, then subscribe to the live stream. A failed
+// snapshot fails with ErrSnapshot before subscribing.
+func (bs *Bootstrapper) Run(ctx context.Context) (broker.Subscriber, error) {
+	bs.mu.Lock()
+	bs.seen = make(map[string]struct{}, bs.dedupSize)
+	bs.ring = make([]string, bs.dedupSize)
+	bs.next = 0
+	bs.mu.Unlock()
+
+	pos, err := bs.snapshot.Load(ctx, bs.decode, func(ctx context.Context, event broker.Event) error {
+		if err := bs.handler(ctx, event); err != nil {
+			return err
+		}
+		bs.remember(bs.keyFunc(event))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSnapshot, err)
+	}
+
+	log.Infof("[bootstrap] snapshot of [%s] loaded, resume at %s", bs.topic, pos)
+
+	opts := append([]broker.SubscribeOption{broker.WithStartPosition(pos)}, bs.subscribeOpts...)
+	return bs.b.Subscribe(bs.topic, bs.live, bs.binder, opts...)
+}
+
+// live skip the messages read from the snapshot.
+func (bs *Bootstrapper) live(ctx context.Context, event broker.Event) error {
+	if bs.forget(bs.keyFunc(event)) {
+		return broker.AutoAck(event)
+	}
+	return bs.handler(ctx, event)
+}
+
+func (bs *Bootstrapper) decode(body []byte) (broker.Any, error) {
+	if bs.binder == nil {
+		return body, nil
+	}
+	v := bs.binder()
+	if err := broker.Unmarshal(bs.b.Options().Codec, body, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// remember add key to the keys of the last entries of the snapshot.
+func (bs *Bootstrapper) remember(key string) {
+	if key == "" || bs.dedupSize <= 0 {
+		return
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if old := bs.ring[bs.next]; old != "" {
+		delete(bs.seen, old)
+	}
+	bs.ring[bs.next] = key
+	bs.next = (bs.next + 1) % bs.dedupSize
+	bs.seen[key] = struct{}{}
+}
+
+// forget report whether key was read from the snapshot, once.
+func (bs *Bootstrapper) forget(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if _, ok := bs.seen[key]; !ok {
+		return false
+	}
+	delete(bs.seen, key)
+	return true
+}
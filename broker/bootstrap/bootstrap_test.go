@@ -0,0 +1,136 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/archive"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func writeSegment(t *testing.T, records ...*archive.Record) []byte {
+	var buf bytes.Buffer
+	w := archive.JSONLines.NewWriter(&buf)
+	for _, r := range records {
+		assert.Nil(t, w.Write(r))
+	}
+	assert.Nil(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestBootstrapper(t *testing.T) {
+	t0 := time.Now().Truncate(time.Millisecond)
+	segment := writeSegment(t,
+		&archive.Record{Topic: "prices", Headers: broker.Headers{MessageIDHeader: "1"}, Body: []byte("p1"), Time: t0},
+		&archive.Record{Topic: "prices", Headers: broker.Headers{MessageIDHeader: "2"}, Body: []byte("p2"), Time: t0.Add(time.Second)},
+	)
+	snapshot := ArchiveSnapshot(func(context.Context) ([]io.ReadCloser, error) {
+		return []io.ReadCloser{io.NopCloser(bytes.NewReader(segment))}, nil
+	}, archive.JSONLines, archive.CompressionNone, time.Minute)
+
+	b := mocks.NewFakeBroker()
+	var handled []string
+	bs := New(b, "prices", snapshot, func(_ context.Context, event broker.Event) error {
+		handled = append(handled, string(event.Message().Body.([]byte)))
+		return nil
+	}, nil)
+
+	sub, err := bs.Run(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"p1", "p2"}, handled)
+	if assert.NotNil(t, sub.Options().StartPosition) {
+		assert.Equal(t, broker.PositionTime, sub.Options().StartPosition.Kind)
+		assert.True(t, t0.Add(time.Second-time.Minute).Equal(sub.Options().StartPosition.Time))
+	}
+
+	ctx := context.Background()
+	events, err := b.Deliver(ctx, "prices", []byte("p2"), broker.Headers{MessageIDHeader: "2"})
+	assert.Nil(t, err)
+	assert.True(t, events[0].IsAcked(), "read from the snapshot")
+
+	_, _ = b.Deliver(ctx, "prices", []byte("p3"), broker.Headers{MessageIDHeader: "3"})
+	_, _ = b.Deliver(ctx, "prices", []byte("p2"), broker.Headers{MessageIDHeader: "2"})
+	_, _ = b.Deliver(ctx, "prices", []byte("p4"), nil)
+	assert.Equal(t, []string{"p1", "p2", "p3", "p2", "p4"}, handled)
+}
+
+func TestBootstrapper_SnapshotFailed(t *testing.T) {
+	b := mocks.NewFakeBroker()
+	failed := errors.New("unavailable")
+	bs := New(b, "prices", SnapshotFunc(func(context.Context, DecodeFunc, broker.Handler) (broker.Position, error) {
+		return broker.Position{}, failed
+	}), func(context.Context, broker.Event) error { return nil }, nil)
+
+	_, err := bs.Run(context.Background())
+	assert.ErrorIs(t, err, ErrSnapshot)
+	assert.ErrorIs(t, err, failed)
+}
+
+func TestHTTPSnapshot(t *testing.T) {
+	segment := writeSegment(t, &archive.Record{Topic: "prices", Body: []byte("p1"), Time: time.Now()})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/snapshot" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(segment)
+	}))
+	defer srv.Close()
+
+	var bodies []string
+	handler := func(_ context.Context, event broker.Event) error {
+		bodies = append(bodies, string(event.Message().Body.([]byte)))
+		return nil
+	}
+	raw := func(body []byte) (broker.Any, error) { return body, nil }
+
+	pos, err := HTTPSnapshot(nil, srv.URL+"/snapshot", archive.JSONLines, archive.CompressionNone, 0).Load(context.Background(), raw, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, broker.PositionTime, pos.Kind)
+	assert.Equal(t, []string{"p1"}, bodies)
+
+	_, err = HTTPSnapshot(nil, srv.URL+"/missing", archive.JSONLines, archive.CompressionNone, 0).Load(context.Background(), raw, handler)
+	assert.Error(t, err)
+}
+
+// retainedBroker delivers the retained messages of a topic to its new subscribers.
+type retainedBroker struct {
+	*mocks.FakeBroker
+	retained []string
+}
+
+func (b *retainedBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	sub, err := b.FakeBroker.Subscribe(topic, handler, binder, opts...)
+	go func() {
+		for _, m := range b.retained {
+			_, _ = b.Deliver(context.Background(), topic, m, nil)
+		}
+	}()
+	return sub, err
+}
+
+func TestTopicSnapshot(t *testing.T) {
+	b := &retainedBroker{FakeBroker: mocks.NewFakeBroker(), retained: []string{"p1", "p2"}}
+
+	var bodies []string
+	pos, err := TopicSnapshot(b, "prices.compacted", nil, 50*time.Millisecond).Load(context.Background(), nil,
+		func(_ context.Context, event broker.Event) error {
+			bodies = append(bodies, event.Message().Body.(string))
+			return nil
+		})
+	assert.Nil(t, err)
+	assert.Equal(t, broker.Beginning(), pos)
+	assert.Equal(t, []string{"p1", "p2"}, bodies)
+
+	_, _ = b.Deliver(context.Background(), "prices.compacted", "p3", nil)
+	assert.Equal(t, []string{"p1", "p2"}, bodies, "unsubscribed")
+}
@@ -0,0 +1,31 @@
+package bootstrap
+
+import (
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(b *Bootstrapper)
+
+// WithKeyFunc set the key the entries of the snapshot and the live messages
+// are deduplicated by, default reads the x-message-id header. The messages
+// without key are never skipped.
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(b *Bootstrapper) {
+		b.keyFunc = fn
+	}
+}
+
+// WithDedupSize set the number of the last entries of the snapshot the
+// live messages are checked against, default is 10000.
+func WithDedupSize(n int) Option {
+	return func(b *Bootstrapper) {
+		b.dedupSize = n
+	}
+}
+
+// WithSubscribeOptions add opts to the live subscription.
+func WithSubscribeOptions(opts ...broker.SubscribeOption) Option {
+	return func(b *Bootstrapper) {
+		b.subscribeOpts = append(b.subscribeOpts, opts...)
+	}
+}
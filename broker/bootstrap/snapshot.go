@@ -0,0 +1,179 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/archive"
+)
+
+// DecodeFunc decode the body of a snapshot entry for the handler.
+type DecodeFunc func(body []byte) (broker.Any, error)
+
+// Snapshot is the state a consumer loads before the live stream.
+type Snapshot interface {
+	// Load pass the entries of the snapshot to handler in order, and return
+	// the position of the live stream to resume from.
+	Load(ctx context.Context, decode DecodeFunc, handler broker.Handler) (broker.Position, error)
+}
+
+// SnapshotFunc is a Snapshot function.
+type SnapshotFunc func(ctx context.Context, decode DecodeFunc, handler broker.Handler) (broker.Position, error)
+
+func (f SnapshotFunc) Load(ctx context.Context, decode DecodeFunc, handler broker.Handler) (broker.Position, error) {
+	return f(ctx, decode, handler)
+}
+
+// snapshotEvent is an entry of a snapshot.
+type snapshotEvent struct {
+	topic string
+	m     *broker.Message
+	raw   interface{}
+}
+
+func (e *snapshotEvent) Topic() string            { return e.topic }
+func (e *snapshotEvent) Message() *broker.Message { return e.m }
+func (e *snapshotEvent) RawMessage() interface{}  { return e.raw }
+func (e *snapshotEvent) Ack() error               { return nil }
+func (e *snapshotEvent) Error() error             { return nil }
+
+// ArchiveSnapshot read the segments written by archive.Archiver, e.g. from
+// S3, in order. The live stream resumes at the archive time of the last
+// record less overlap, the messages of the overlap delivered twice are
+// skipped by the Bootstrapper.
+func ArchiveSnapshot(open func(ctx context.Context) ([]io.ReadCloser, error), format archive.Format, compression archive.Compression, overlap time.Duration) Snapshot {
+	return SnapshotFunc(func(ctx context.Context, decode DecodeFunc, handler broker.Handler) (broker.Position, error) {
+		segments, err := open(ctx)
+		if err != nil {
+			return broker.Position{}, err
+		}
+		defer func() {
+			for _, s := range segments {
+				_ = s.Close()
+			}
+		}()
+
+		var last time.Time
+		for _, s := range segments {
+			err = archive.ReadSegment(s, format, compression, func(rec *archive.Record) error {
+				body, err := decode(rec.Body)
+				if err != nil {
+					return err
+				}
+				if rec.Time.After(last) {
+					last = rec.Time
+				}
+				return handler(ctx, &snapshotEvent{topic: rec.Topic, m: &broker.Message{Headers: rec.Headers, Body: body}, raw: rec})
+			})
+			if err != nil {
+				return broker.Position{}, err
+			}
+		}
+
+		if last.IsZero() {
+			return broker.Beginning(), nil
+		}
+		return broker.AtTime(last.Add(-overlap)), nil
+	})
+}
+
+// HTTPSnapshot read a segment of format served at url, e.g. by the service
+// owning the state.
+func HTTPSnapshot(client *http.Client, url string, format archive.Format, compression archive.Compression, overlap time.Duration) Snapshot {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return ArchiveSnapshot(func(ctx context.Context) ([]io.ReadCloser, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("bootstrap: snapshot %s: %s", url, resp.Status)
+		}
+		return []io.ReadCloser{resp.Body}, nil
+	}, format, compression, overlap)
+}
+
+// TopicSnapshot read topic from its beginning with b until no message came
+// for idle, e.g. a compacted topic holding the latest state of every key.
+// The live stream resumes after the last message read, on the brokers
+// tracking the positions of their events.
+func TopicSnapshot(b broker.Broker, topic string, binder broker.Binder, idle time.Duration, opts ...broker.SubscribeOption) Snapshot {
+	return SnapshotFunc(func(ctx context.Context, _ DecodeFunc, handler broker.Handler) (broker.Position, error) {
+		var (
+			mu       sync.Mutex
+			pos      = broker.Beginning()
+			firstErr error
+		)
+		activity := make(chan struct{}, 1)
+		failed := make(chan struct{})
+
+		subOpts := append([]broker.SubscribeOption{broker.WithStartPosition(broker.Beginning())}, opts...)
+		sub, err := b.Subscribe(topic, func(ctx context.Context, event broker.Event) error {
+			select {
+			case activity <- struct{}{}:
+			default:
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if firstErr != nil {
+				return firstErr
+			}
+			if err := handler(ctx, event); err != nil {
+				firstErr = err
+				close(failed)
+				return err
+			}
+			if p, ok := broker.EventPosition(event); ok {
+				pos = p
+			}
+			return nil
+		}, binder, subOpts...)
+		if err != nil {
+			return broker.Position{}, err
+		}
+
+		timer := time.NewTimer(idle)
+		defer timer.Stop()
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				_ = sub.Unsubscribe(false)
+				return broker.Position{}, ctx.Err()
+			case <-failed:
+				break wait
+			case <-activity:
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(idle)
+			case <-timer.C:
+				break wait
+			}
+		}
+
+		if err = sub.Unsubscribe(false); err != nil {
+			return broker.Position{}, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr != nil {
+			return broker.Position{}, firstErr
+		}
+		return pos, nil
+	})
+}
@@ -0,0 +1,93 @@
+package cdc
+
+type canalMessage struct {
+	Data     []map[string]interface{} `json:"data"`
+	Old      []map[string]interface{} `json:"old"`
+	Database string                   `json:"database"`
+	Table    string                   `json:"table"`
+	Type     string                   `json:"type"`
+	IsDdl    bool                     `json:"isDdl"`
+	PkNames  []string                 `json:"pkNames"`
+	SQL      string                   `json:"sql"`
+	Es       int64                    `json:"es"`
+	Ts       int64                    `json:"ts"`
+}
+
+// Canal decodes Canal flat JSON messages, one event per row. The Before of
+// an update is the new row overlaid with the changed columns in "old".
+var Canal Decoder = DecoderFunc(decodeCanal)
+
+func decodeCanal(data []byte) ([]*ChangeEvent, error) {
+	if isNull(data) {
+		return nil, nil
+	}
+
+	var m canalMessage
+	if err := unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	if m.Type == "" {
+		return nil, ErrMissingOperation
+	}
+
+	source := Source{
+		Connector: "canal",
+		Database:  m.Database,
+		Table:     m.Table,
+		Timestamp: millis(m.Es),
+	}
+
+	newEvent := func(op Op) *ChangeEvent {
+		return &ChangeEvent{
+			Op:          op,
+			Source:      source,
+			Timestamp:   millis(m.Ts),
+			PrimaryKeys: m.PkNames,
+		}
+	}
+
+	if m.IsDdl {
+		e := newEvent(OpDDL)
+		e.SQL = m.SQL
+		if m.Type == "TRUNCATE" {
+			e.Op = OpTruncate
+		}
+		return []*ChangeEvent{e}, nil
+	}
+
+	var events []*ChangeEvent
+	switch m.Type {
+	case "INSERT":
+		for _, row := range m.Data {
+			e := newEvent(OpCreate)
+			e.After = row
+			events = append(events, e)
+		}
+	case "UPDATE":
+		for i, row := range m.Data {
+			e := newEvent(OpUpdate)
+			e.After = row
+			e.Before = make(map[string]interface{}, len(row))
+			for k, v := range row {
+				e.Before[k] = v
+			}
+			if i < len(m.Old) {
+				for k, v := range m.Old[i] {
+					e.Before[k] = v
+				}
+			}
+			events = append(events, e)
+		}
+	case "DELETE":
+		for _, row := range m.Data {
+			e := newEvent(OpDelete)
+			e.Before = row
+			events = append(events, e)
+		}
+	default:
+		return nil, ErrUnknownOperation
+	}
+
+	return events, nil
+}
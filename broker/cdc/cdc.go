@@ -0,0 +1,189 @@
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var (
+	ErrUnsupportedBody   = errors.New("cdc: message body is not raw bytes")
+	ErrUnknownFormat     = errors.New("cdc: unknown change event format")
+	ErrMissingOperation  = errors.New("cdc: change event has no operation")
+	ErrUnknownOperation  = errors.New("cdc: unknown change event operation")
+	ErrMissingRowPayload = errors.New("cdc: change event has no row image")
+)
+
+type Op string
+
+const (
+	OpCreate   Op = "c"
+	OpUpdate   Op = "u"
+	OpDelete   Op = "d"
+	OpRead     Op = "r"
+	OpTruncate Op = "t"
+	OpDDL      Op = "ddl"
+)
+
+// Source identifies where a change happened.
+type Source struct {
+	Connector string
+	Database  string
+	Schema    string
+	Table     string
+	// Timestamp is when the change was made in the database.
+	Timestamp time.Time
+}
+
+// ChangeEvent is one row change decoded from a Debezium or Canal envelope.
+// Before is nil for inserts and After is nil for deletes.
+type ChangeEvent struct {
+	Op     Op
+	Before map[string]interface{}
+	After  map[string]interface{}
+	Source Source
+
+	// Timestamp is when the connector processed the change.
+	Timestamp time.Time
+
+	// PrimaryKeys is the primary key column names, only set by Canal.
+	PrimaryKeys []string
+	// SQL is the statement of a DDL event.
+	SQL string
+}
+
+// ScanBefore decode the row image before the change into v.
+func (e *ChangeEvent) ScanBefore(v interface{}) error {
+	return scanRow(e.Before, v)
+}
+
+// ScanAfter decode the row image after the change into v.
+func (e *ChangeEvent) ScanAfter(v interface{}) error {
+	return scanRow(e.After, v)
+}
+
+func scanRow(row map[string]interface{}, v interface{}) error {
+	if row == nil {
+		return ErrMissingRowPayload
+	}
+	buf, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, v)
+}
+
+// Decoder turns one message payload into change events. A payload may carry
+// several rows (Canal) or none (Debezium tombstone).
+type Decoder interface {
+	Decode(data []byte) ([]*ChangeEvent, error)
+}
+
+type DecoderFunc func(data []byte) ([]*ChangeEvent, error)
+
+func (f DecoderFunc) Decode(data []byte) ([]*ChangeEvent, error) {
+	return f(data)
+}
+
+// Auto detects a Debezium or Canal envelope for every payload.
+var Auto Decoder = DecoderFunc(func(data []byte) ([]*ChangeEvent, error) {
+	if isNull(data) {
+		return nil, nil
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+
+	if _, ok := probe["payload"]; ok {
+		return Debezium.Decode(data)
+	}
+	if _, ok := probe["op"]; ok {
+		return Debezium.Decode(data)
+	}
+	if _, ok := probe["type"]; ok {
+		return Canal.Decode(data)
+	}
+	return nil, ErrUnknownFormat
+})
+
+// ChangeHandler handles one decoded change event.
+type ChangeHandler func(ctx context.Context, event *ChangeEvent) error
+
+// Handler wraps handler into a broker.Handler. Subscribe without a codec so
+// the message body stays the raw payload. Events of one message are handled
+// in order and the first error is returned.
+func Handler(decoder Decoder, handler ChangeHandler, opts ...Option) broker.Handler {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(ctx context.Context, event broker.Event) error {
+		data, err := payload(event.Message())
+		if err != nil {
+			return err
+		}
+
+		changes, err := decoder.Decode(data)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			if !o.match(change) {
+				continue
+			}
+			if err = handler(ctx, change); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func payload(msg *broker.Message) ([]byte, error) {
+	if msg == nil {
+		return nil, nil
+	}
+	switch body := msg.Body.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return body, nil
+	case json.RawMessage:
+		return body, nil
+	case string:
+		return []byte(body), nil
+	case *[]byte:
+		if body == nil {
+			return nil, nil
+		}
+		return *body, nil
+	default:
+		return nil, ErrUnsupportedBody
+	}
+}
+
+func isNull(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) == 0 || bytes.Equal(data, []byte("null"))
+}
+
+func unmarshal(data []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+func millis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
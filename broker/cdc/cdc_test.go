@@ -0,0 +1,115 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type testEvent struct {
+	broker.Event
+	msg *broker.Message
+}
+
+func (e *testEvent) Message() *broker.Message { return e.msg }
+
+type order struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+const debeziumUpdate = `{
+	"schema": {"type": "struct"},
+	"payload": {
+		"before": {"id": 1, "status": "new"},
+		"after": {"id": 1, "status": "paid"},
+		"source": {"connector": "mysql", "db": "shop", "table": "orders", "ts_ms": 1700000000000},
+		"op": "u",
+		"ts_ms": 1700000000100
+	}
+}`
+
+const canalUpdate = `{
+	"data": [{"id": "1", "status": "paid"}, {"id": "2", "status": "paid"}],
+	"old": [{"status": "new"}, {"status": "new"}],
+	"database": "shop",
+	"table": "orders",
+	"type": "UPDATE",
+	"isDdl": false,
+	"pkNames": ["id"],
+	"es": 1700000000000,
+	"ts": 1700000000100
+}`
+
+func TestDebezium(t *testing.T) {
+	events, err := Debezium.Decode([]byte(debeziumUpdate))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events))
+
+	e := events[0]
+	assert.Equal(t, OpUpdate, e.Op)
+	assert.Equal(t, "shop", e.Source.Database)
+	assert.Equal(t, "orders", e.Source.Table)
+	assert.Equal(t, int64(1700000000000), e.Source.Timestamp.UnixMilli())
+	assert.Equal(t, json.Number("1"), e.After["id"])
+
+	var before, after order
+	assert.Nil(t, e.ScanBefore(&before))
+	assert.Nil(t, e.ScanAfter(&after))
+	assert.Equal(t, order{ID: 1, Status: "new"}, before)
+	assert.Equal(t, order{ID: 1, Status: "paid"}, after)
+
+	events, err = Debezium.Decode([]byte(`{"before": {"id": 1}, "after": null, "source": {"table": "orders"}, "op": "d"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, OpDelete, events[0].Op)
+	assert.Nil(t, events[0].After)
+	assert.ErrorIs(t, events[0].ScanAfter(&after), ErrMissingRowPayload)
+
+	events, err = Debezium.Decode(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, events)
+
+	_, err = Debezium.Decode([]byte(`{"op": "x"}`))
+	assert.ErrorIs(t, err, ErrUnknownOperation)
+}
+
+func TestCanal(t *testing.T) {
+	events, err := Canal.Decode([]byte(canalUpdate))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(events))
+
+	e := events[1]
+	assert.Equal(t, OpUpdate, e.Op)
+	assert.Equal(t, "canal", e.Source.Connector)
+	assert.Equal(t, []string{"id"}, e.PrimaryKeys)
+	assert.Equal(t, map[string]interface{}{"id": "2", "status": "new"}, e.Before)
+	assert.Equal(t, map[string]interface{}{"id": "2", "status": "paid"}, e.After)
+
+	events, err = Canal.Decode([]byte(`{"database": "shop", "table": "orders", "type": "ALTER", "isDdl": true, "sql": "ALTER TABLE orders ADD c INT"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, OpDDL, events[0].Op)
+	assert.Equal(t, "ALTER TABLE orders ADD c INT", events[0].SQL)
+}
+
+func TestHandler(t *testing.T) {
+	var events []*ChangeEvent
+	h := Handler(Auto, func(_ context.Context, e *ChangeEvent) error {
+		events = append(events, e)
+		return nil
+	}, WithOps(OpUpdate), WithTables("shop.orders"))
+
+	assert.Nil(t, h(context.Background(), &testEvent{msg: &broker.Message{Body: []byte(debeziumUpdate)}}))
+	assert.Nil(t, h(context.Background(), &testEvent{msg: &broker.Message{Body: canalUpdate}}))
+	assert.Nil(t, h(context.Background(), &testEvent{msg: &broker.Message{Body: []byte(`{"after": {"id": 3}, "source": {"db": "shop", "table": "orders"}, "op": "c"}`)}}))
+	assert.Equal(t, 3, len(events))
+
+	err := h(context.Background(), &testEvent{msg: &broker.Message{Body: 1}})
+	assert.ErrorIs(t, err, ErrUnsupportedBody)
+
+	err = h(context.Background(), &testEvent{msg: &broker.Message{Body: []byte(`{"foo": 1}`)}})
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
@@ -0,0 +1,78 @@
+package cdc
+
+import (
+	"encoding/json"
+)
+
+type debeziumSource struct {
+	Connector string `json:"connector"`
+	Database  string `json:"db"`
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	// Collection is the table of the MongoDB connector.
+	Collection string `json:"collection"`
+	TsMs       int64  `json:"ts_ms"`
+}
+
+type debeziumPayload struct {
+	Op     Op                     `json:"op"`
+	Before map[string]interface{} `json:"before"`
+	After  map[string]interface{} `json:"after"`
+	Source debeziumSource         `json:"source"`
+	TsMs   int64                  `json:"ts_ms"`
+}
+
+// Debezium decodes Debezium JSON change events, with or without the schema
+// envelope. Tombstones decode to no events.
+var Debezium Decoder = DecoderFunc(decodeDebezium)
+
+func decodeDebezium(data []byte) ([]*ChangeEvent, error) {
+	if isNull(data) {
+		return nil, nil
+	}
+
+	var envelope struct {
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Payload != nil {
+		if isNull(envelope.Payload) {
+			return nil, nil
+		}
+		data = envelope.Payload
+	}
+
+	var p debeziumPayload
+	if err := unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+
+	switch p.Op {
+	case "":
+		return nil, ErrMissingOperation
+	case OpCreate, OpUpdate, OpDelete, OpRead, OpTruncate:
+	default:
+		return nil, ErrUnknownOperation
+	}
+
+	table := p.Source.Table
+	if table == "" {
+		table = p.Source.Collection
+	}
+
+	return []*ChangeEvent{{
+		Op:     p.Op,
+		Before: p.Before,
+		After:  p.After,
+		Source: Source{
+			Connector: p.Source.Connector,
+			Database:  p.Source.Database,
+			Schema:    p.Source.Schema,
+			Table:     table,
+			Timestamp: millis(p.Source.TsMs),
+		},
+		Timestamp: millis(p.TsMs),
+	}}, nil
+}
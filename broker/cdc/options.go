@@ -0,0 +1,51 @@
+package cdc
+
+type options struct {
+	ops    map[Op]struct{}
+	tables map[string]struct{}
+}
+
+type Option func(o *options)
+
+// WithOps only pass events of the given operations to the handler.
+func WithOps(ops ...Op) Option {
+	return func(o *options) {
+		if o.ops == nil {
+			o.ops = make(map[Op]struct{}, len(ops))
+		}
+		for _, op := range ops {
+			o.ops[op] = struct{}{}
+		}
+	}
+}
+
+// WithTables only pass events of the given tables to the handler, a table is
+// either "table" or "database.table".
+func WithTables(tables ...string) Option {
+	return func(o *options) {
+		if o.tables == nil {
+			o.tables = make(map[string]struct{}, len(tables))
+		}
+		for _, t := range tables {
+			o.tables[t] = struct{}{}
+		}
+	}
+}
+
+func (o *options) match(e *ChangeEvent) bool {
+	if o.ops != nil {
+		if _, ok := o.ops[e.Op]; !ok {
+			return false
+		}
+	}
+	if o.tables != nil {
+		if _, ok := o.tables[e.Source.Table]; ok {
+			return true
+		}
+		if _, ok := o.tables[e.Source.Database+"."+e.Source.Table]; ok {
+			return true
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var ErrNoPosition = errors.New("checkpoint: event has no position")
+
+// Checkpoint is the position of a consumer group on a partition of a topic,
+// kept apart from the broker, e.g. to move the group to another cluster.
+type Checkpoint struct {
+	Group     string
+	Topic     string
+	Partition int
+	Position  broker.Position
+	UpdatedAt time.Time
+}
+
+// Store keeps the checkpoints, one per group, topic and partition.
+type Store interface {
+	Save(ctx context.Context, c *Checkpoint) error
+	// Load return the checkpoints of the partitions of topic, by partition.
+	Load(ctx context.Context, group, topic string) ([]*Checkpoint, error)
+	Delete(ctx context.Context, group, topic string) error
+}
+
+// Save the position after event, ErrNoPosition is returned when its broker
+// does not track one. The positions are saved in the order the events
+// complete, consume a partition with a single handler at a time.
+func Save(ctx context.Context, store Store, group, topic string, event broker.Event) error {
+	pos, ok := broker.EventPosition(event)
+	if !ok {
+		return ErrNoPosition
+	}
+
+	return store.Save(ctx, &Checkpoint{
+		Group:     group,
+		Topic:     topic,
+		Partition: pos.Partition,
+		Position:  pos,
+		UpdatedAt: time.Now(),
+	})
+}
+
+// Handler save the position of the events handled without error. A failed
+// save fails the event, to be delivered again.
+func Handler(store Store, group, topic string) func(broker.Handler) broker.Handler {
+	return func(h broker.Handler) broker.Handler {
+		return func(ctx context.Context, event broker.Event) error {
+			if err := h(ctx, event); err != nil {
+				return err
+			}
+			return Save(ctx, store, group, topic, event)
+		}
+	}
+}
+
+// Checkpointer save the position of the processed events, then acknowledge
+// them, a pipeline.Checkpointer.
+type Checkpointer struct {
+	store Store
+	group string
+	topic string
+}
+
+func NewCheckpointer(store Store, group, topic string) *Checkpointer {
+	return &Checkpointer{store: store, group: group, topic: topic}
+}
+
+func (c *Checkpointer) Checkpoint(ctx context.Context, event broker.Event) error {
+	if err := Save(ctx, c.store, c.group, c.topic, event); err != nil {
+		return err
+	}
+	return event.Ack()
+}
+
+// Resume seek sub to the positions saved for group, nothing is done without
+// checkpoint. The positions are those of the cluster the checkpoints were
+// saved on, see ResumeAtTime to resume on another one.
+func Resume(ctx context.Context, store Store, group, topic string, sub broker.Subscriber) error {
+	checkpoints, err := store.Load(ctx, group, topic)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range checkpoints {
+		if err = broker.Seek(ctx, sub, c.Position); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResumeAtTime seek sub to the publish time of the oldest position saved
+// for group, the offsets and message ids being specific to a cluster. The
+// messages published since, on any partition, are delivered again.
+func ResumeAtTime(ctx context.Context, store Store, group, topic string, sub broker.Subscriber) error {
+	checkpoints, err := store.Load(ctx, group, topic)
+	if err != nil || len(checkpoints) == 0 {
+		return err
+	}
+
+	oldest := checkpoints[0].Position.Time
+	for _, c := range checkpoints[1:] {
+		if c.Position.Time.Before(oldest) {
+			oldest = c.Position.Time
+		}
+	}
+
+	return broker.Seek(ctx, sub, broker.AtTime(oldest))
+}
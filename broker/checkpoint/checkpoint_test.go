@@ -0,0 +1,115 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type memoryEvent struct {
+	topic string
+	pos   *broker.Position
+	acked bool
+}
+
+func (e *memoryEvent) Topic() string            { return e.topic }
+func (e *memoryEvent) Message() *broker.Message { return &broker.Message{} }
+func (e *memoryEvent) RawMessage() interface{}  { return nil }
+func (e *memoryEvent) Error() error             { return nil }
+func (e *memoryEvent) Ack() error {
+	e.acked = true
+	return nil
+}
+
+type positionEvent struct {
+	memoryEvent
+}
+
+func (e *positionEvent) Position() broker.Position { return *e.pos }
+
+type seekSubscriber struct {
+	broker.Subscriber
+	positions []broker.Position
+}
+
+func (s *seekSubscriber) Seek(_ context.Context, pos broker.Position) error {
+	s.positions = append(s.positions, pos)
+	return nil
+}
+
+func offsetAt(partition int, offset int64, at time.Time) *broker.Position {
+	pos := broker.AtOffset(partition, offset)
+	pos.Time = at
+	return &pos
+}
+
+func TestHandler(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	failed := errors.New("boom")
+	var err error
+	h := Handler(store, "billing", "orders")(func(context.Context, broker.Event) error { return err })
+
+	assert.NoError(t, h(ctx, &positionEvent{memoryEvent{topic: "orders", pos: offsetAt(0, 10, now)}}))
+	assert.NoError(t, h(ctx, &positionEvent{memoryEvent{topic: "orders", pos: offsetAt(1, 5, now)}}))
+	assert.NoError(t, h(ctx, &positionEvent{memoryEvent{topic: "orders", pos: offsetAt(0, 11, now)}}))
+	err = failed
+	assert.ErrorIs(t, h(ctx, &positionEvent{memoryEvent{topic: "orders", pos: offsetAt(0, 12, now)}}), failed)
+	err = nil
+	assert.ErrorIs(t, h(ctx, &memoryEvent{topic: "orders"}), ErrNoPosition)
+
+	checkpoints, err := store.Load(ctx, "billing", "orders")
+	assert.NoError(t, err)
+	assert.Len(t, checkpoints, 2)
+	assert.Equal(t, *offsetAt(0, 11, now), checkpoints[0].Position)
+	assert.Equal(t, *offsetAt(1, 5, now), checkpoints[1].Position)
+
+	assert.NoError(t, store.Delete(ctx, "billing", "orders"))
+	checkpoints, err = store.Load(ctx, "billing", "orders")
+	assert.NoError(t, err)
+	assert.Empty(t, checkpoints)
+}
+
+func TestCheckpointer(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	event := &positionEvent{memoryEvent{topic: "orders", pos: offsetAt(2, 7, time.Time{})}}
+	assert.NoError(t, NewCheckpointer(store, "billing", "orders").Checkpoint(ctx, event))
+	assert.True(t, event.acked)
+
+	checkpoints, _ := store.Load(ctx, "billing", "orders")
+	assert.Len(t, checkpoints, 1)
+	assert.Equal(t, 2, checkpoints[0].Partition)
+}
+
+func TestResume(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	earlier := time.Now().Add(-time.Hour)
+	later := time.Now()
+
+	sub := &seekSubscriber{}
+	assert.NoError(t, Resume(ctx, store, "billing", "orders", sub))
+	assert.NoError(t, ResumeAtTime(ctx, store, "billing", "orders", sub))
+	assert.Empty(t, sub.positions)
+
+	assert.NoError(t, store.Save(ctx, &Checkpoint{Group: "billing", Topic: "orders", Partition: 1, Position: *offsetAt(1, 5, earlier)}))
+	assert.NoError(t, store.Save(ctx, &Checkpoint{Group: "billing", Topic: "orders", Partition: 0, Position: *offsetAt(0, 11, later)}))
+
+	assert.NoError(t, Resume(ctx, store, "billing", "orders", sub))
+	assert.Equal(t, []broker.Position{*offsetAt(0, 11, later), *offsetAt(1, 5, earlier)}, sub.positions)
+
+	sub.positions = nil
+	assert.NoError(t, ResumeAtTime(ctx, store, "billing", "orders", sub))
+	assert.Equal(t, []broker.Position{broker.AtTime(earlier)}, sub.positions)
+
+	assert.ErrorIs(t, Resume(ctx, store, "billing", "orders", sub.Subscriber), broker.ErrSeekUnsupported)
+}
@@ -0,0 +1,57 @@
+package checkpoint
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// MemoryStore keeps the checkpoints in memory, for tests.
+type MemoryStore struct {
+	sync.Mutex
+
+	checkpoints map[string]map[int]Checkpoint
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{checkpoints: make(map[string]map[int]Checkpoint)}
+}
+
+func memoryKey(group, topic string) string {
+	return group + "\x00" + topic
+}
+
+func (s *MemoryStore) Save(_ context.Context, c *Checkpoint) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key := memoryKey(c.Group, c.Topic)
+	if s.checkpoints[key] == nil {
+		s.checkpoints[key] = make(map[int]Checkpoint)
+	}
+	s.checkpoints[key][c.Partition] = *c
+	return nil
+}
+
+func (s *MemoryStore) Load(_ context.Context, group, topic string) ([]*Checkpoint, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var checkpoints []*Checkpoint
+	for _, c := range s.checkpoints[memoryKey(group, topic)] {
+		c := c
+		checkpoints = append(checkpoints, &c)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Partition < checkpoints[j].Partition
+	})
+	return checkpoints, nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, group, topic string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.checkpoints, memoryKey(group, topic))
+	return nil
+}
@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/tx7do/kratos-transport/broker/checkpoint"
+)
+
+const defaultPrefix = "checkpoint:"
+
+var _ checkpoint.Store = (*Store)(nil)
+
+type StoreOption func(s *Store)
+
+// WithPrefix set the prefix of all keys, default is "checkpoint:".
+func WithPrefix(prefix string) StoreOption {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// Store keeps the checkpoints in redis, a hash per group and topic:
+//
+//	{prefix}{group}:{topic}  partition -> checkpoint as json
+type Store struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+func NewStore(pool *redis.Pool, opts ...StoreOption) *Store {
+	s := &Store{
+		pool:   pool,
+		prefix: defaultPrefix,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+func (s *Store) key(group, topic string) string { return s.prefix + group + ":" + topic }
+
+func (s *Store) Save(ctx context.Context, c *checkpoint.Checkpoint) error {
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "HSET", s.key(c.Group, c.Topic), strconv.Itoa(c.Partition), buf)
+	return err
+}
+
+func (s *Store) Load(ctx context.Context, group, topic string) ([]*checkpoint.Checkpoint, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	values, err := redis.ByteSlices(redis.DoContext(conn, ctx, "HVALS", s.key(group, topic)))
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := make([]*checkpoint.Checkpoint, 0, len(values))
+	for _, v := range values {
+		var c checkpoint.Checkpoint
+		if err = json.Unmarshal(v, &c); err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, &c)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Partition < checkpoints[j].Partition
+	})
+	return checkpoints, nil
+}
+
+func (s *Store) Delete(ctx context.Context, group, topic string) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "DEL", s.key(group, topic))
+	return err
+}
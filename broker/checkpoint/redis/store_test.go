@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/checkpoint"
+	"github.com/tx7do/kratos-transport/internal/redistest"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(redistest.NewPool(t), WithPrefix("checkpoint-test:"))
+	defer store.Delete(ctx, "billing", "orders")
+
+	pos := broker.AtOffset(1, 42)
+	pos.Time = time.Now().UTC()
+	assert.Nil(t, store.Save(ctx, &checkpoint.Checkpoint{Group: "billing", Topic: "orders", Partition: 1, Position: pos}))
+	assert.Nil(t, store.Save(ctx, &checkpoint.Checkpoint{Group: "billing", Topic: "orders", Partition: 0, Position: broker.AtMessageID([]byte{8, 1})}))
+
+	checkpoints, err := store.Load(ctx, "billing", "orders")
+	assert.Nil(t, err)
+	assert.Len(t, checkpoints, 2)
+	assert.Equal(t, []byte{8, 1}, checkpoints[0].Position.MessageID)
+	assert.Equal(t, int64(42), checkpoints[1].Position.Offset)
+	assert.True(t, pos.Time.Equal(checkpoints[1].Position.Time))
+
+	assert.Nil(t, store.Delete(ctx, "billing", "orders"))
+	checkpoints, err = store.Load(ctx, "billing", "orders")
+	assert.Nil(t, err)
+	assert.Empty(t, checkpoints)
+}
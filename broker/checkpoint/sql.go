@@ -0,0 +1,129 @@
+package checkpoint
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/sqlutil"
+)
+
+const DefaultTableName = "consumer_checkpoints"
+
+// SQLStore keeps the checkpoints in a table shaped like:
+//
+//	CREATE TABLE consumer_checkpoints (
+//	    group_name   VARCHAR(255) NOT NULL,
+//	    topic        VARCHAR(255) NOT NULL,
+//	    partition_id INT          NOT NULL,
+//	    kind         INT          NOT NULL,
+//	    offset_value BIGINT       NOT NULL,
+//	    message_id   BLOB,        -- BYTEA on postgres
+//	    published_at BIGINT       NOT NULL, -- unix nanoseconds
+//	    updated_at   TIMESTAMP    NOT NULL,
+//	    PRIMARY KEY (group_name, topic, partition_id)
+//	);
+type SQLStore struct {
+	db          *sql.DB
+	table       string
+	placeholder sqlutil.PlaceholderFormat
+}
+
+func NewSQLStore(db *sql.DB, table string, placeholder sqlutil.PlaceholderFormat) *SQLStore {
+	if table == "" {
+		table = DefaultTableName
+	}
+	if placeholder == nil {
+		placeholder = sqlutil.Question
+	}
+	return &SQLStore{
+		db:          db,
+		table:       table,
+		placeholder: placeholder,
+	}
+}
+
+func (s *SQLStore) placeholders(from, n int) []interface{} {
+	p := make([]interface{}, n)
+	for i := range p {
+		p[i] = s.placeholder(from + i)
+	}
+	return p
+}
+
+func (s *SQLStore) Save(ctx context.Context, c *Checkpoint) error {
+	var publishedAt int64
+	if !c.Position.Time.IsZero() {
+		publishedAt = c.Position.Time.UnixNano()
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET kind = %s, offset_value = %s, message_id = %s, published_at = %s, updated_at = %s"+
+		" WHERE group_name = %s AND topic = %s AND partition_id = %s",
+		append([]interface{}{s.table}, s.placeholders(1, 8)...)...)
+	res, err := s.db.ExecContext(ctx, query,
+		int(c.Position.Kind), c.Position.Offset, c.Position.MessageID, publishedAt, c.UpdatedAt,
+		c.Group, c.Topic, c.Partition)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	// mysql reports no affected row when the values are unchanged.
+	var found int
+	query = fmt.Sprintf("SELECT 1 FROM %s WHERE group_name = %s AND topic = %s AND partition_id = %s",
+		append([]interface{}{s.table}, s.placeholders(1, 3)...)...)
+	switch err = s.db.QueryRowContext(ctx, query, c.Group, c.Topic, c.Partition).Scan(&found); {
+	case err == nil:
+		return nil
+	case err != sql.ErrNoRows:
+		return err
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (group_name, topic, partition_id, kind, offset_value, message_id, published_at, updated_at)"+
+		" VALUES (%s, %s, %s, %s, %s, %s, %s, %s)",
+		append([]interface{}{s.table}, s.placeholders(1, 8)...)...)
+	_, err = s.db.ExecContext(ctx, query,
+		c.Group, c.Topic, c.Partition,
+		int(c.Position.Kind), c.Position.Offset, c.Position.MessageID, publishedAt, c.UpdatedAt)
+	return err
+}
+
+func (s *SQLStore) Load(ctx context.Context, group, topic string) ([]*Checkpoint, error) {
+	query := fmt.Sprintf("SELECT partition_id, kind, offset_value, message_id, published_at, updated_at FROM %s"+
+		" WHERE group_name = %s AND topic = %s ORDER BY partition_id",
+		append([]interface{}{s.table}, s.placeholders(1, 2)...)...)
+
+	rows, err := s.db.QueryContext(ctx, query, group, topic)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checkpoints []*Checkpoint
+	for rows.Next() {
+		c := &Checkpoint{Group: group, Topic: topic}
+		var kind int
+		var publishedAt int64
+		if err = rows.Scan(&c.Partition, &kind, &c.Position.Offset, &c.Position.MessageID, &publishedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		c.Position.Kind = broker.PositionKind(kind)
+		c.Position.Partition = c.Partition
+		if publishedAt != 0 {
+			c.Position.Time = time.Unix(0, publishedAt)
+		}
+		checkpoints = append(checkpoints, c)
+	}
+	return checkpoints, rows.Err()
+}
+
+func (s *SQLStore) Delete(ctx context.Context, group, topic string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE group_name = %s AND topic = %s",
+		append([]interface{}{s.table}, s.placeholders(1, 2)...)...)
+	_, err := s.db.ExecContext(ctx, query, group, topic)
+	return err
+}
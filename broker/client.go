@@ -0,0 +1,402 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultClientMinBackoff = 100 * time.Millisecond
+	defaultClientMaxBackoff = 30 * time.Second
+)
+
+var (
+	ErrClientStarted = errors.New("broker client already started")
+	ErrClientClosed  = errors.New("broker client closed")
+)
+
+// ClientState is the connection state of a Client.
+type ClientState int32
+
+const (
+	ClientDisconnected ClientState = iota
+	ClientConnecting
+	ClientConnected
+	ClientClosed
+)
+
+func (s ClientState) String() string {
+	switch s {
+	case ClientDisconnected:
+		return "disconnected"
+	case ClientConnecting:
+		return "connecting"
+	case ClientConnected:
+		return "connected"
+	case ClientClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// StateHandler is called on every state change of a Client.
+type StateHandler func(old, new ClientState)
+
+// KeepaliveProbe checks that the connection of b is still usable.
+type KeepaliveProbe func(ctx context.Context, b Broker) error
+
+type ClientOption func(c *Client)
+
+// WithClientBackoff set the first and the longest delay between two
+// connection attempts, the delay doubles after every failure. A zero or
+// negative initial delay keeps the default.
+func WithClientBackoff(initial, limit time.Duration) ClientOption {
+	return func(c *Client) {
+		c.minBackoff = initial
+		c.maxBackoff = limit
+	}
+}
+
+// WithClientKeepalive run probe every interval, the connection is recreated
+// when it fails.
+func WithClientKeepalive(interval time.Duration, probe KeepaliveProbe) ClientOption {
+	return func(c *Client) {
+		c.keepaliveInterval = interval
+		c.probe = probe
+	}
+}
+
+// WithClientStateHandler set the hook called on every state change.
+func WithClientStateHandler(handler StateHandler) ClientOption {
+	return func(c *Client) {
+		c.onState = handler
+	}
+}
+
+// WithClientErrorHandler set the hook called with the connect, probe and
+// publish errors.
+func WithClientErrorHandler(handler func(err error)) ClientOption {
+	return func(c *Client) {
+		c.onError = handler
+	}
+}
+
+// WithClientClock set the clock of the backoff and keepalive timers,
+// SystemClock by default.
+func WithClientClock(clock Clock) ClientOption {
+	return func(c *Client) {
+		c.clock = clock
+	}
+}
+
+// WithClientReconnectOn decide which publish errors recreate the connection,
+// default IsConnectionError.
+func WithClientReconnectOn(fn func(err error) bool) ClientOption {
+	return func(c *Client) {
+		c.reconnectOn = fn
+	}
+}
+
+// Client owns the lifecycle of a broker for publish-only services: it
+// connects in the background, reconnects with exponential backoff when the
+// connection is lost, and publishes once connected. It can be registered as
+// a kratos server to follow the lifecycle of the app.
+type Client struct {
+	b Broker
+
+	minBackoff        time.Duration
+	maxBackoff        time.Duration
+	keepaliveInterval time.Duration
+	probe             KeepaliveProbe
+	onState           StateHandler
+	onError           func(err error)
+	reconnectOn       func(err error) bool
+	clock             Clock
+
+	tracker ConnectionTracker
+
+	mtx       sync.Mutex
+	state     ClientState
+	connected chan struct{}
+	started   bool
+
+	reconnect chan struct{}
+	quit      chan struct{}
+	quitOnce  sync.Once
+	done      chan struct{}
+}
+
+func NewClient(b Broker, opts ...ClientOption) *Client {
+	c := &Client{
+		b:          b,
+		minBackoff: defaultClientMinBackoff,
+		maxBackoff: defaultClientMaxBackoff,
+		clock:      SystemClock,
+		connected:  make(chan struct{}),
+		reconnect:  make(chan struct{}, 1),
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	if c.minBackoff <= 0 {
+		c.minBackoff = defaultClientMinBackoff
+	}
+	if c.maxBackoff < c.minBackoff {
+		c.maxBackoff = c.minBackoff
+	}
+
+	return c
+}
+
+// Broker return the managed broker.
+func (c *Client) Broker() Broker {
+	return c.b
+}
+
+func (c *Client) State() ClientState {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.state
+}
+
+// Healthy report whether the client is connected.
+func (c *Client) Healthy() bool {
+	return c.State() == ClientConnected
+}
+
+// ConnectionStats return the state history, reconnects and last error of
+// the client, with the channels, subscribers and latest error of the broker
+// when it implements ConnectionIntrospector.
+func (c *Client) ConnectionStats() ConnectionStats {
+	s := c.tracker.Stats()
+	if ci, ok := c.b.(ConnectionIntrospector); ok {
+		bs := ci.ConnectionStats()
+		if bs.LastErrorAt.After(s.LastErrorAt) {
+			s.LastError, s.LastErrorAt = bs.LastError, bs.LastErrorAt
+		}
+		s.Channels = bs.Channels
+		s.Subscribers = bs.Subscribers
+		s.Goroutines = bs.Goroutines
+	}
+	s.Broker = c.b.Name()
+	s.Address = c.b.Address()
+	return s
+}
+
+// Start connect in the background, it does not wait for the connection.
+func (c *Client) Start(_ context.Context) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.state == ClientClosed {
+		return ErrClientClosed
+	}
+	if c.started {
+		return ErrClientStarted
+	}
+	c.started = true
+
+	go c.run()
+
+	return nil
+}
+
+// Stop close the client and disconnect the broker.
+func (c *Client) Stop(ctx context.Context) error {
+	c.mtx.Lock()
+	if c.state == ClientClosed {
+		c.mtx.Unlock()
+		return nil
+	}
+	started := c.started
+	c.mtx.Unlock()
+
+	c.quitOnce.Do(func() {
+		close(c.quit)
+	})
+
+	if started {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	wasConnected := c.State() == ClientConnected
+	c.setState(ClientClosed)
+
+	if wasConnected {
+		return c.b.Disconnect()
+	}
+	return nil
+}
+
+// Publish wait for the connection until ctx is done, then publish. Errors
+// accepted by the reconnect filter recreate the connection in the background.
+func (c *Client) Publish(ctx context.Context, topic string, msg Any, opts ...PublishOption) error {
+	c.mtx.Lock()
+	connected := c.connected
+	c.mtx.Unlock()
+
+	select {
+	case <-connected:
+	case <-c.quit:
+		return ErrClientClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-c.quit:
+		return ErrClientClosed
+	default:
+	}
+
+	err := c.b.Publish(ctx, topic, msg, opts...)
+	if err != nil {
+		c.error(err)
+		if c.shouldReconnect(err) {
+			c.Reconnect()
+		}
+	}
+	return err
+}
+
+// Reconnect recreate the connection in the background.
+func (c *Client) Reconnect() {
+	select {
+	case c.reconnect <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Client) shouldReconnect(err error) bool {
+	if c.reconnectOn != nil {
+		return c.reconnectOn(err)
+	}
+	return IsConnectionError(err)
+}
+
+// IsConnectionError report whether err is a network error or a closed
+// connection. The codec, size and context errors are not: the connection is
+// still usable.
+func IsConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, ErrBrokerClosed) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func (c *Client) error(err error) {
+	c.tracker.RecordError(err)
+	if c.onError != nil {
+		c.onError(err)
+	}
+}
+
+func (c *Client) setState(state ClientState) {
+	c.mtx.Lock()
+	old := c.state
+	if old == state || old == ClientClosed {
+		c.mtx.Unlock()
+		return
+	}
+	c.state = state
+	c.tracker.SetState(state)
+	if state == ClientConnected {
+		close(c.connected)
+	} else if old == ClientConnected {
+		c.connected = make(chan struct{})
+	}
+	c.mtx.Unlock()
+
+	if c.onState != nil {
+		c.onState(old, state)
+	}
+}
+
+func (c *Client) run() {
+	defer close(c.done)
+
+	var keepalive <-chan time.Time
+	if c.keepaliveInterval > 0 && c.probe != nil {
+		ticker := c.clock.NewTicker(c.keepaliveInterval)
+		defer ticker.Stop()
+		keepalive = ticker.C()
+	}
+
+	backoff := c.minBackoff
+	for {
+		if c.State() != ClientConnected {
+			c.setState(ClientConnecting)
+			if err := c.b.Connect(); err != nil {
+				c.setState(ClientDisconnected)
+				c.error(err)
+
+				timer := c.clock.NewTimer(backoff)
+				select {
+				case <-c.quit:
+					timer.Stop()
+					return
+				case <-timer.C():
+				}
+
+				backoff *= 2
+				if backoff > c.maxBackoff {
+					backoff = c.maxBackoff
+				}
+				continue
+			}
+			backoff = c.minBackoff
+			c.setState(ClientConnected)
+		}
+
+		select {
+		case <-c.quit:
+			return
+
+		case <-c.reconnect:
+			c.disconnect()
+
+		case <-keepalive:
+			ctx, cancel := context.WithTimeout(context.Background(), c.keepaliveInterval)
+			err := c.probe(ctx, c.b)
+			cancel()
+			if err != nil {
+				c.error(err)
+				c.disconnect()
+			}
+		}
+	}
+}
+
+func (c *Client) disconnect() {
+	c.setState(ClientDisconnected)
+	if err := c.b.Disconnect(); err != nil {
+		c.error(err)
+	}
+}
@@ -0,0 +1,171 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type flakyBroker struct {
+	recordBroker
+
+	mtx          sync.Mutex
+	connectFails int
+	publishErr   error
+	connects     int
+	disconnects  int
+}
+
+func (b *flakyBroker) Connect() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.connects++
+	if b.connectFails > 0 {
+		b.connectFails--
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (b *flakyBroker) Disconnect() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.disconnects++
+	return nil
+}
+
+func (b *flakyBroker) Publish(ctx context.Context, topic string, msg Any, opts ...PublishOption) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if err := b.publishErr; err != nil {
+		b.publishErr = nil
+		return err
+	}
+	return b.recordBroker.Publish(ctx, topic, msg, opts...)
+}
+
+func (b *flakyBroker) counts() (int, int) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.connects, b.disconnects
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	fb := &flakyBroker{recordBroker: *newRecordBroker("flaky"), connectFails: 2}
+
+	var mtx sync.Mutex
+	var states []ClientState
+	c := NewClient(fb,
+		WithClientBackoff(time.Millisecond, 5*time.Millisecond),
+		WithClientStateHandler(func(_, state ClientState) {
+			mtx.Lock()
+			states = append(states, state)
+			mtx.Unlock()
+		}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.Nil(t, c.Start(ctx))
+	assert.ErrorIs(t, c.Start(ctx), ErrClientStarted)
+
+	assert.Nil(t, c.Publish(ctx, "orders", "msg"))
+	assert.True(t, c.Healthy())
+	connects, _ := fb.counts()
+	assert.Equal(t, 3, connects)
+
+	fb.mtx.Lock()
+	fb.publishErr = syscall.EPIPE
+	fb.mtx.Unlock()
+	assert.Error(t, c.Publish(ctx, "orders", "msg"))
+
+	assert.Eventually(t, func() bool {
+		connects, disconnects := fb.counts()
+		return connects == 4 && disconnects == 1 && c.Healthy()
+	}, 5*time.Second, time.Millisecond)
+
+	assert.Nil(t, c.Publish(ctx, "orders", "msg"))
+	assert.Equal(t, []string{"orders", "orders"}, fb.published)
+
+	assert.Nil(t, c.Stop(ctx))
+	assert.Equal(t, ClientClosed, c.State())
+	assert.ErrorIs(t, c.Publish(ctx, "orders", "msg"), ErrClientClosed)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.Equal(t, ClientConnected, states[len(states)-2])
+	assert.Equal(t, ClientClosed, states[len(states)-1])
+}
+
+func TestClient_Keepalive(t *testing.T) {
+	fb := &flakyBroker{recordBroker: *newRecordBroker("flaky")}
+
+	probes := make(chan struct{}, 1)
+	c := NewClient(fb,
+		WithClientKeepalive(5*time.Millisecond, func(context.Context, Broker) error {
+			select {
+			case probes <- struct{}{}:
+				return errors.New("probe failed")
+			default:
+				return nil
+			}
+		}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.Nil(t, c.Start(ctx))
+	defer c.Stop(ctx)
+
+	assert.Eventually(t, func() bool {
+		connects, disconnects := fb.counts()
+		return connects >= 2 && disconnects >= 1
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestClient_PublishTimeout(t *testing.T) {
+	fb := &flakyBroker{recordBroker: *newRecordBroker("flaky"), connectFails: 1000}
+	c := NewClient(fb, WithClientBackoff(time.Millisecond, time.Millisecond))
+
+	assert.Nil(t, c.Start(context.Background()))
+	defer c.Stop(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, c.Publish(ctx, "orders", "msg"), context.DeadlineExceeded)
+	assert.False(t, c.Healthy())
+}
+
+func TestClient_ReconnectOnConnectionErrors(t *testing.T) {
+	fb := &flakyBroker{recordBroker: *newRecordBroker("flaky")}
+	c := NewClient(fb, WithClientBackoff(0, 0))
+	assert.Equal(t, defaultClientMinBackoff, c.minBackoff)
+	assert.Equal(t, defaultClientMinBackoff, c.maxBackoff)
+
+	for _, err := range []error{
+		errors.New("json: unsupported type"),
+		fmt.Errorf("%w: 2048 bytes published to orders, limit is 1024", ErrPayloadTooLarge),
+		context.DeadlineExceeded,
+		nil,
+	} {
+		assert.False(t, c.shouldReconnect(err), err)
+	}
+
+	for _, err := range []error{
+		syscall.ECONNRESET,
+		fmt.Errorf("write: %w", syscall.EPIPE),
+		&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("no route to host")},
+		ErrBrokerClosed,
+	} {
+		assert.True(t, c.shouldReconnect(err), err)
+	}
+}
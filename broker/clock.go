@@ -0,0 +1,228 @@
+package broker
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the time source of the components waiting on timers: backoffs,
+// retries, tickers and visibility extensions. Tests inject a FakeClock to
+// advance the time deterministically instead of sleeping.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is the timer of a Clock.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is the ticker of a Clock.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock is the Clock of the time package, the default of every component.
+var SystemClock Clock = systemClock{}
+
+// WithClock set the clock of the timers of the broker, SystemClock by default.
+func WithClock(clock Clock) Option {
+	return func(o *Options) {
+		o.Clock = clock
+	}
+}
+
+// ClockOrSystem return c, SystemClock when nil.
+func ClockOrSystem(c Clock) Clock {
+	if c == nil {
+		return SystemClock
+	}
+	return c
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return &systemTimer{t: time.NewTimer(d)}
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return &systemTicker{t: time.NewTicker(d)}
+}
+
+type systemTimer struct {
+	t *time.Timer
+}
+
+func (t *systemTimer) C() <-chan time.Time        { return t.t.C }
+func (t *systemTimer) Stop() bool                 { return t.t.Stop() }
+func (t *systemTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+type systemTicker struct {
+	t *time.Ticker
+}
+
+func (t *systemTicker) C() <-chan time.Time { return t.t.C }
+func (t *systemTicker) Stop()               { t.t.Stop() }
+
+// FakeClock is a Clock whose time only moves with Advance, the timers and
+// tickers due fire then, in order.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// NewFakeClock return a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+type fakeWaiter struct {
+	clock  *FakeClock
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// Sleep block until the clock is advanced by d.
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	w := &fakeWaiter{clock: c, c: make(chan time.Time, 1)}
+	c.schedule(w, d)
+	return w
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	w := &fakeWaiter{clock: c, period: d, c: make(chan time.Time, 1)}
+	c.schedule(w, d)
+	return &fakeTicker{fakeWaiter: w}
+}
+
+// Waiters return the number of pending timers and tickers, tests wait for
+// the component under test to arm its timer before advancing the clock.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.waiters)
+}
+
+// BlockUntil wait until n timers or tickers are pending.
+func (c *FakeClock) BlockUntil(n int) {
+	for c.Waiters() < n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Advance move the time forward by d, firing the timers and tickers due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		sort.Slice(c.waiters, func(i, j int) bool { return c.waiters[i].at.Before(c.waiters[j].at) })
+		if len(c.waiters) == 0 || c.waiters[0].at.After(end) {
+			break
+		}
+
+		w := c.waiters[0]
+		c.now = w.at
+		select {
+		case w.c <- c.now:
+		default:
+		}
+
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+	c.now = end
+}
+
+func (c *FakeClock) schedule(w *fakeWaiter, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w.at = c.now.Add(d)
+	if d <= 0 {
+		select {
+		case w.c <- c.now:
+		default:
+		}
+		return
+	}
+	c.waiters = append(c.waiters, w)
+}
+
+// remove unschedule w, reporting whether it was pending.
+func (c *FakeClock) remove(w *fakeWaiter) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, pending := range c.waiters {
+		if pending == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (w *fakeWaiter) C() <-chan time.Time { return w.c }
+
+func (w *fakeWaiter) Stop() bool {
+	return w.clock.remove(w)
+}
+
+func (w *fakeWaiter) Reset(d time.Duration) bool {
+	pending := w.clock.remove(w)
+	w.clock.schedule(w, d)
+	return pending
+}
+
+type fakeTicker struct {
+	*fakeWaiter
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.remove(t.fakeWaiter)
+}
@@ -0,0 +1,101 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock_Timer(t *testing.T) {
+	start := time.Date(2024, 5, 17, 9, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+
+	timer := c.NewTimer(time.Second)
+	after := c.After(2 * time.Second)
+	assert.Equal(t, 2, c.Waiters())
+
+	c.Advance(500 * time.Millisecond)
+	assert.Len(t, timer.C(), 0)
+	assert.Equal(t, start.Add(500*time.Millisecond), c.Now())
+
+	c.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-timer.C())
+	assert.Len(t, after, 0)
+
+	c.Advance(time.Second)
+	assert.Equal(t, start.Add(2*time.Second), <-after)
+	assert.Equal(t, 0, c.Waiters())
+	assert.Equal(t, 2500*time.Millisecond, c.Since(start))
+}
+
+func TestFakeClock_StopReset(t *testing.T) {
+	c := NewFakeClock(time.Now())
+
+	timer := c.NewTimer(time.Second)
+	assert.True(t, timer.Stop())
+	assert.False(t, timer.Stop())
+	c.Advance(time.Minute)
+	assert.Len(t, timer.C(), 0)
+
+	assert.False(t, timer.Reset(time.Second))
+	assert.True(t, timer.Reset(2*time.Second))
+	c.Advance(time.Second)
+	assert.Len(t, timer.C(), 0)
+	c.Advance(time.Second)
+	assert.Len(t, timer.C(), 1)
+}
+
+func TestFakeClock_Ticker(t *testing.T) {
+	c := NewFakeClock(time.Now())
+
+	ticker := c.NewTicker(time.Second)
+	c.Advance(time.Second)
+	<-ticker.C()
+
+	// the ticks missed while the channel is full are dropped.
+	c.Advance(3 * time.Second)
+	<-ticker.C()
+	assert.Len(t, ticker.C(), 0)
+
+	ticker.Stop()
+	c.Advance(time.Minute)
+	assert.Len(t, ticker.C(), 0)
+	assert.Equal(t, 0, c.Waiters())
+}
+
+func TestFakeClock_Sleep(t *testing.T) {
+	c := NewFakeClock(time.Now())
+
+	done := make(chan struct{})
+	go func() {
+		c.Sleep(time.Minute)
+		close(done)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+	<-done
+}
+
+func TestClient_FakeClockBackoff(t *testing.T) {
+	fb := &flakyBroker{recordBroker: *newRecordBroker("flaky"), connectFails: 2}
+	clock := NewFakeClock(time.Now())
+	c := NewClient(fb, WithClientBackoff(time.Hour, time.Hour), WithClientClock(clock))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.Nil(t, c.Start(ctx))
+
+	// every failed attempt waits for an hour of backoff, advanced at once.
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Hour)
+	}
+
+	assert.Nil(t, c.Publish(ctx, "orders", "msg"))
+	assert.Equal(t, ClientConnected, c.State())
+	assert.Nil(t, c.Stop(ctx))
+}
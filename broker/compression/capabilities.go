@@ -0,0 +1,84 @@
+package compression
+
+import (
+	"sync"
+	"time"
+)
+
+// announcement is published on the control topic by the consumers.
+type announcement struct {
+	Consumer  string   `json:"consumer"`
+	Topic     string   `json:"topic"`
+	Encodings []string `json:"encodings"`
+}
+
+type capability struct {
+	encodings map[string]struct{}
+	seen      time.Time
+}
+
+// capabilities are the encodings of the live consumers of every topic.
+type capabilities struct {
+	sync.Mutex
+
+	ttl    time.Duration
+	topics map[string]map[string]capability
+	now    func() time.Time
+}
+
+func newCapabilities(ttl time.Duration) *capabilities {
+	return &capabilities{
+		ttl:    ttl,
+		topics: map[string]map[string]capability{},
+		now:    time.Now,
+	}
+}
+
+func (c *capabilities) update(a *announcement) {
+	encodings := make(map[string]struct{}, len(a.Encodings))
+	for _, e := range a.Encodings {
+		encodings[e] = struct{}{}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	consumers, ok := c.topics[a.Topic]
+	if !ok {
+		consumers = map[string]capability{}
+		c.topics[a.Topic] = consumers
+	}
+	consumers[a.Consumer] = capability{encodings: encodings, seen: c.now()}
+}
+
+// choose return the first of preferred every live consumer of topic
+// supports, Identity when there is none or no consumer is known.
+func (c *capabilities) choose(topic string, preferred []string) string {
+	c.Lock()
+	defer c.Unlock()
+
+	consumers := c.topics[topic]
+	now := c.now()
+	for id, cp := range consumers {
+		if now.Sub(cp.seen) > c.ttl {
+			delete(consumers, id)
+		}
+	}
+	if len(consumers) == 0 {
+		return Identity
+	}
+
+	for _, name := range preferred {
+		supported := true
+		for _, cp := range consumers {
+			if _, ok := cp.encodings[name]; !ok {
+				supported = false
+				break
+			}
+		}
+		if supported {
+			return name
+		}
+	}
+	return Identity
+}
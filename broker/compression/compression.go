@@ -0,0 +1,279 @@
+package compression
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/google/uuid"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+const (
+	// ContentEncodingHeader carries the encoding of a compressed message,
+	// absent for the uncompressed ones.
+	ContentEncodingHeader = "content-encoding"
+
+	// DefaultControlTopic is where the consumers advertise their encodings.
+	DefaultControlTopic = "compression.capabilities"
+)
+
+type compressionBroker struct {
+	broker.Broker
+
+	o    *options
+	id   string
+	caps *capabilities
+
+	mu      sync.Mutex
+	topics  map[string]struct{}
+	control broker.Subscriber
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewBroker wraps b to compress the payloads it publishes and decompress
+// the ones it consumes. The payloads are encoded with the codec of
+// WithCodec, b must have none. The encoding travels in a header, so the
+// brokers whose messages have none, see broker.CarriesHeaders, are refused
+// with broker.ErrHeadersUnsupported. A driver dropping the headers without
+// saying so delivers the compressed payloads as they are.
+//
+// Unless WithoutNegotiation is set, the consumers advertise the encodings
+// they support on the control topic, and a publish uses the first of its
+// encodings every live consumer of the topic supports, or none, so the
+// consumers can be upgraded one at a time.
+func NewBroker(b broker.Broker, opts ...Option) broker.Broker {
+	o := newOptions(opts...)
+	caps := newCapabilities(3 * o.announceInterval)
+	caps.now = broker.ClockOrSystem(o.clock).Now
+	return &compressionBroker{
+		Broker: b,
+		o:      o,
+		id:     uuid.New().String(),
+		caps:   caps,
+		topics: map[string]struct{}{},
+	}
+}
+
+func (b *compressionBroker) Connect() error {
+	if err := broker.RequireHeaders(b.Broker); err != nil {
+		return err
+	}
+	if err := b.Broker.Connect(); err != nil {
+		return err
+	}
+	if !b.o.negotiate {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.control != nil {
+		return nil
+	}
+
+	sub, err := b.Broker.Subscribe(b.o.controlTopic, b.handleAnnouncement, nil, b.o.controlOptions...)
+	if err != nil {
+		return fmt.Errorf("subscribe control topic [%s]: %w", b.o.controlTopic, err)
+	}
+	b.control = sub
+
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go b.announceLoop(b.stop, b.done)
+
+	return nil
+}
+
+func (b *compressionBroker) Disconnect() error {
+	b.mu.Lock()
+	control, stop, done := b.control, b.stop, b.done
+	b.control = nil
+	b.mu.Unlock()
+
+	if control != nil {
+		close(stop)
+		<-done
+		_ = control.Unsubscribe(true)
+	}
+
+	return b.Broker.Disconnect()
+}
+
+func (b *compressionBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	if err := broker.RequireHeaders(b.Broker); err != nil {
+		return err
+	}
+
+	buf, err := broker.Marshal(b.o.codec, msg)
+	if err != nil {
+		return err
+	}
+
+	encoding := b.encoding(topic)
+	if encoding == Identity || len(buf) < b.o.minSize {
+		return b.Broker.Publish(ctx, topic, buf, opts...)
+	}
+
+	c, ok := Get(encoding)
+	if !ok {
+		return fmt.Errorf("compression: unknown encoding %q", encoding)
+	}
+	if buf, err = c.Compress(buf); err != nil {
+		return err
+	}
+
+	opts = append(opts, broker.WithHeaders(broker.Headers{ContentEncodingHeader: encoding}))
+	return b.Broker.Publish(ctx, topic, buf, opts...)
+}
+
+// encoding return the encoding to publish to topic with.
+func (b *compressionBroker) encoding(topic string) string {
+	if !b.o.negotiate {
+		if len(b.o.encodings) == 0 {
+			return Identity
+		}
+		return b.o.encodings[0]
+	}
+	return b.caps.choose(topic, b.o.encodings)
+}
+
+func (b *compressionBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	if err := broker.RequireHeaders(b.Broker); err != nil {
+		return nil, err
+	}
+
+	sub, err := b.Broker.Subscribe(topic, b.decompress(handler, binder), nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.o.negotiate {
+		b.mu.Lock()
+		b.topics[topic] = struct{}{}
+		b.mu.Unlock()
+
+		b.announce(topic)
+	}
+
+	return sub, nil
+}
+
+// decompress adapt handler to the compressed events of the wrapped broker.
+func (b *compressionBroker) decompress(handler broker.Handler, binder broker.Binder) broker.Handler {
+	return func(ctx context.Context, event broker.Event) error {
+		msg := event.Message()
+		if msg == nil {
+			return handler(ctx, event)
+		}
+
+		data, _ := msg.Body.([]byte)
+		headers := make(broker.Headers, len(msg.Headers))
+		for k, v := range msg.Headers {
+			headers[k] = v
+		}
+
+		if encoding := headers[ContentEncodingHeader]; encoding != "" && encoding != Identity {
+			c, ok := Get(encoding)
+			if !ok {
+				return fmt.Errorf("compression: unknown encoding %q", encoding)
+			}
+			var err error
+			if data, err = c.Decompress(data); err != nil {
+				return fmt.Errorf("compression: decompress %s: %w", encoding, err)
+			}
+			delete(headers, ContentEncodingHeader)
+		}
+
+		m := &broker.Message{Headers: headers}
+		if binder != nil {
+			m.Body = binder()
+		} else {
+			m.Body = data
+		}
+		if b.o.codec != nil {
+			if err := broker.Unmarshal(b.o.codec, data, &m.Body); err != nil {
+				return err
+			}
+		}
+
+		return handler(ctx, &decodedEvent{Event: event, m: m})
+	}
+}
+
+// decodedEvent is an event of the wrapped broker with the decompressed and
+// decoded message.
+type decodedEvent struct {
+	broker.Event
+	m *broker.Message
+}
+
+func (e *decodedEvent) Message() *broker.Message {
+	return e.m
+}
+
+func (b *compressionBroker) handleAnnouncement(_ context.Context, event broker.Event) error {
+	msg := event.Message()
+	if msg == nil {
+		return nil
+	}
+	data, _ := msg.Body.([]byte)
+
+	var a announcement
+	if err := json.Unmarshal(data, &a); err != nil {
+		log.Errorf("[compression] invalid announcement: %v", err)
+		return nil
+	}
+	b.caps.update(&a)
+	return nil
+}
+
+// announce advertise the registered encodings of the consumer of topic.
+func (b *compressionBroker) announce(topic string) {
+	encodings := []string{Identity}
+	for _, name := range b.o.encodings {
+		if _, ok := Get(name); ok {
+			encodings = append(encodings, name)
+		}
+	}
+
+	buf, _ := json.Marshal(&announcement{
+		Consumer:  b.id,
+		Topic:     topic,
+		Encodings: encodings,
+	})
+	if err := b.Broker.Publish(context.Background(), b.o.controlTopic, buf); err != nil {
+		log.Errorf("[compression] announce encodings of [%s] failed: %v", topic, err)
+	}
+}
+
+func (b *compressionBroker) announceLoop(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := broker.ClockOrSystem(b.o.clock).NewTicker(b.o.announceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C():
+		}
+
+		b.mu.Lock()
+		topics := make([]string, 0, len(b.topics))
+		for topic := range b.topics {
+			topics = append(topics, topic)
+		}
+		b.mu.Unlock()
+
+		for _, topic := range topics {
+			b.announce(topic)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package compression
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func newFakeBroker(t *testing.T) *mocks.FakeBroker {
+	b := mocks.NewFakeBroker()
+	assert.Nil(t, b.Connect())
+	return b
+}
+
+type order struct {
+	ID   string `json:"id"`
+	Note string `json:"note"`
+}
+
+func subscribeOrders(t *testing.T, b broker.Broker, received *[]order) {
+	_, err := broker.Subscribe(b, "orders", func(_ context.Context, _ string, headers broker.Headers, o *order) error {
+		assert.Empty(t, headers[ContentEncodingHeader])
+		*received = append(*received, *o)
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func TestNegotiation(t *testing.T) {
+	ctx := context.Background()
+	mb := newFakeBroker(t)
+
+	publisher := NewBroker(mb, WithCodec("json"))
+	assert.Nil(t, publisher.Connect())
+	defer publisher.Disconnect()
+
+	// no consumer known yet, the message is published uncompressed.
+	assert.Nil(t, publisher.Publish(ctx, "orders", &order{ID: "1"}))
+	assert.Empty(t, mb.PublishedTo("orders")[0].Headers[ContentEncodingHeader])
+
+	// an old consumer only accepts deflate.
+	var oldReceived []order
+	old := NewBroker(mb, WithCodec("json"), WithEncodings("deflate"))
+	subscribeOrders(t, old, &oldReceived)
+
+	assert.Nil(t, publisher.Publish(ctx, "orders", &order{ID: "2"}))
+	assert.Equal(t, "deflate", mb.PublishedTo("orders")[1].Headers[ContentEncodingHeader])
+
+	// a consumer supporting nothing in common forces the identity encoding.
+	var newReceived []order
+	upgraded := NewBroker(mb, WithCodec("json"), WithEncodings("zstd"))
+	subscribeOrders(t, upgraded, &newReceived)
+
+	assert.Nil(t, publisher.Publish(ctx, "orders", &order{ID: "3"}))
+	assert.Empty(t, mb.PublishedTo("orders")[2].Headers[ContentEncodingHeader])
+
+	assert.Equal(t, []order{{ID: "2"}, {ID: "3"}}, oldReceived)
+	assert.Equal(t, []order{{ID: "3"}}, newReceived)
+}
+
+func TestWithoutNegotiation(t *testing.T) {
+	ctx := context.Background()
+	mb := newFakeBroker(t)
+
+	b := NewBroker(mb, WithCodec("json"), WithoutNegotiation(), WithMinSize(64))
+
+	var received []order
+	subscribeOrders(t, b, &received)
+
+	assert.Nil(t, b.Publish(ctx, "orders", &order{ID: "1"}))
+	assert.Empty(t, mb.PublishedTo("orders")[0].Headers[ContentEncodingHeader])
+
+	long := order{ID: "2", Note: string(make([]byte, 256))}
+	assert.Nil(t, b.Publish(ctx, "orders", &long))
+	assert.Equal(t, "gzip", mb.PublishedTo("orders")[1].Headers[ContentEncodingHeader])
+	assert.Less(t, len(mb.PublishedTo("orders")[1].Body.([]byte)), 256)
+
+	assert.Equal(t, []order{{ID: "1"}, long}, received)
+}
+
+func TestCapabilities_Expire(t *testing.T) {
+	now := time.Now()
+	c := newCapabilities(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.update(&announcement{Consumer: "a", Topic: "orders", Encodings: []string{"identity", "gzip"}})
+	c.update(&announcement{Consumer: "b", Topic: "orders", Encodings: []string{"identity", "gzip", "deflate"}})
+	assert.Equal(t, "gzip", c.choose("orders", []string{"deflate", "gzip"}))
+
+	// b has upgraded, a stopped announcing and is forgotten.
+	now = now.Add(50 * time.Second)
+	c.update(&announcement{Consumer: "b", Topic: "orders", Encodings: []string{"identity", "gzip", "deflate"}})
+	now = now.Add(20 * time.Second)
+	assert.Equal(t, "deflate", c.choose("orders", []string{"deflate", "gzip"}))
+	assert.Equal(t, Identity, c.choose("payments", []string{"deflate", "gzip"}))
+}
+
+func TestHeaderlessBroker(t *testing.T) {
+	ctx := context.Background()
+	hb := mocks.NewHeaderlessBroker()
+	assert.Nil(t, hb.Connect())
+
+	b := NewBroker(hb, WithCodec("json"), WithoutNegotiation())
+	assert.ErrorIs(t, b.Connect(), broker.ErrHeadersUnsupported)
+
+	// the encoding could not reach the consumers.
+	long := order{ID: "1", Note: string(make([]byte, 256))}
+	assert.ErrorIs(t, b.Publish(ctx, "orders", &long), broker.ErrHeadersUnsupported)
+	assert.Empty(t, hb.Published())
+
+	_, err := broker.Subscribe(b, "orders", func(context.Context, string, broker.Headers, *order) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, broker.ErrHeadersUnsupported)
+}
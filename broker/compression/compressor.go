@@ -0,0 +1,128 @@
+package compression
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sync"
+)
+
+// Identity is the encoding of the uncompressed messages, supported by every
+// consumer.
+const Identity = "identity"
+
+// Compressor is a content encoding, registered under its Name.
+type Compressor interface {
+	// Name is the content-encoding token, e.g. gzip.
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Compressor{}
+)
+
+// Register make c available to the brokers of this package, e.g. snappy or
+// zstd, replacing the compressor of the same name.
+func Register(c Compressor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry[c.Name()] = c
+}
+
+// Get return the compressor registered under name.
+func Get(name string) (Compressor, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	c, ok := registry[name]
+	return c, ok
+}
+
+func init() {
+	Register(gzipCompressor{})
+	Register(zlibCompressor{})
+	Register(flateCompressor{})
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string { return "gzip" }
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// zlibCompressor is the deflate content encoding, zlib framed as in HTTP.
+type zlibCompressor struct{}
+
+func (zlibCompressor) Name() string { return "deflate" }
+
+func (zlibCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (zlibCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// flateCompressor is raw deflate without framing.
+type flateCompressor struct{}
+
+func (flateCompressor) Name() string { return "flate" }
+
+func (flateCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (flateCompressor) Decompress(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	return io.ReadAll(r)
+}
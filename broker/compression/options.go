@@ -0,0 +1,103 @@
+package compression
+
+import (
+	"time"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(o *options)
+
+type options struct {
+	codec     encoding.Codec
+	encodings []string
+	minSize   int
+
+	negotiate        bool
+	controlTopic     string
+	announceInterval time.Duration
+	controlOptions   []broker.SubscribeOption
+
+	clock broker.Clock
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		encodings:        []string{"gzip", "deflate"},
+		negotiate:        true,
+		controlTopic:     DefaultControlTopic,
+		announceInterval: 30 * time.Second,
+		clock:            broker.SystemClock,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithCodec set the codec of the payloads, which are encoded before being
+// compressed, so the wrapped broker must have no codec.
+func WithCodec(name string) Option {
+	return func(o *options) {
+		o.codec = encoding.GetCodec(name)
+	}
+}
+
+// WithEncodings set the encodings by order of preference, used to publish
+// and advertised by the consumers, default is gzip then deflate.
+func WithEncodings(names ...string) Option {
+	return func(o *options) {
+		o.encodings = names
+	}
+}
+
+// WithMinSize publish the payloads smaller than n bytes uncompressed.
+func WithMinSize(n int) Option {
+	return func(o *options) {
+		o.minSize = n
+	}
+}
+
+// WithoutNegotiation always publish with the first encoding, for the
+// deployments whose consumers are all known to support it.
+func WithoutNegotiation() Option {
+	return func(o *options) {
+		o.negotiate = false
+	}
+}
+
+// WithControlTopic set the topic the consumers advertise their encodings
+// on, default is DefaultControlTopic.
+func WithControlTopic(topic string) Option {
+	return func(o *options) {
+		o.controlTopic = topic
+	}
+}
+
+// WithAnnounceInterval set how often the consumers advertise their
+// encodings, default is 30s. A consumer not heard of for three intervals is
+// forgotten by the publishers.
+func WithAnnounceInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.announceInterval = interval
+	}
+}
+
+// WithControlSubscribeOptions set the options of the control topic
+// subscription, which must receive every announcement, e.g. a queue or a
+// consumer group of its own.
+func WithControlSubscribeOptions(opts ...broker.SubscribeOption) Option {
+	return func(o *options) {
+		o.controlOptions = opts
+	}
+}
+
+// WithClock set the clock of the announcements and of the consumer
+// expiration, broker.SystemClock by default.
+func WithClock(clock broker.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
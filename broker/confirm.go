@@ -0,0 +1,14 @@
+package broker
+
+import "errors"
+
+// The publish outcomes reported by the brokers waiting for the confirm of
+// the server, wrapped in the error of Publish.
+var (
+	// ErrPublishNacked is the refusal of the message by the server, e.g. a
+	// full queue with the reject-publish overflow.
+	ErrPublishNacked = errors.New("publish nacked by the broker")
+
+	// ErrPublishReturned is a message the server could route to no queue.
+	ErrPublishReturned = errors.New("publish returned as unroutable")
+)
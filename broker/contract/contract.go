@@ -0,0 +1,206 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/schema"
+)
+
+var ErrContractBroken = errors.New("contract: broken")
+
+// Example is a message a producer publishes to a topic.
+type Example struct {
+	Topic   string
+	Name    string
+	Headers broker.Headers
+	Body    broker.Any
+}
+
+// Expectation is how a consumer reads a topic. Schema is the schema the
+// consumer relies on, derived from the binder when nil.
+type Expectation struct {
+	Topic   string
+	Name    string
+	Binder  broker.Binder
+	Handler broker.Handler
+	Schema  *schema.Schema
+}
+
+// Error lists every broken expectation.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("contract: %d problem(s): %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+func (e *Error) Unwrap() error {
+	return ErrContractBroken
+}
+
+// Contract gathers the examples of the producers and the expectations of the
+// consumers of topics, usually from the test packages of each service:
+//
+//	c := contract.New(contract.WithCodec("json"))
+//	c.Produce(contract.Example{Topic: "orders", Name: "paid", Body: &Order{ID: "1", Paid: true}})
+//	c.Consume(contract.Expectation{Topic: "orders", Name: "billing", Binder: newOrder, Handler: billing.HandleOrder})
+//	c.Require(t)
+type Contract struct {
+	codec encoding.Codec
+
+	examples     []Example
+	expectations []Expectation
+}
+
+func New(opts ...Option) *Contract {
+	c := &Contract{
+		codec: broker.DefaultCodec,
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
+}
+
+// Produce declare examples of the messages published by a producer.
+func (c *Contract) Produce(examples ...Example) {
+	c.examples = append(c.examples, examples...)
+}
+
+// Consume declare the expectations of a consumer.
+func (c *Contract) Consume(expectations ...Expectation) {
+	c.expectations = append(c.expectations, expectations...)
+}
+
+// Verify deliver every example of a topic to every expectation of the topic,
+// encoding and decoding it like the brokers do, and check the schema of the
+// examples against the one of the consumers. A topic expected without any
+// example is a problem too.
+func (c *Contract) Verify(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var problems []string
+	for _, exp := range c.expectations {
+		var delivered int
+		for _, ex := range c.examples {
+			if ex.Topic != exp.Topic {
+				continue
+			}
+			delivered++
+			if err := c.deliver(ctx, ex, exp); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: example %s to consumer %s: %v", exp.Topic, ex.Name, exp.Name, err))
+			}
+		}
+		if delivered == 0 {
+			problems = append(problems, fmt.Sprintf("%s: no example for consumer %s", exp.Topic, exp.Name))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &Error{Problems: problems}
+}
+
+// Require fail t with every problem found by Verify.
+func (c *Contract) Require(t testing.TB) {
+	t.Helper()
+
+	var ce *Error
+	if err := c.Verify(context.Background()); errors.As(err, &ce) {
+		for _, p := range ce.Problems {
+			t.Error(p)
+		}
+	} else if err != nil {
+		t.Error(err)
+	}
+}
+
+func (c *Contract) deliver(ctx context.Context, ex Example, exp Expectation) error {
+	buf, err := broker.Marshal(c.codec, ex.Body)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	m := &broker.Message{Headers: broker.Headers{}}
+	for k, v := range ex.Headers {
+		m.Headers[k] = v
+	}
+	if exp.Binder != nil {
+		m.Body = exp.Binder()
+	} else {
+		m.Body = buf
+	}
+
+	if err = broker.Unmarshal(c.codec, buf, &m.Body); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+
+	if err = checkSchema(ex, exp); err != nil {
+		return err
+	}
+
+	if exp.Handler != nil {
+		if err = exp.Handler(ctx, &event{topic: ex.Topic, m: m}); err != nil {
+			return fmt.Errorf("handler: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkSchema compare the schema of the example with the consumer one, the
+// consumers must still be able to read the example as forward compatibility
+// requires. Bodies which are neither structs nor protobuf messages are skipped.
+func checkSchema(ex Example, exp Expectation) error {
+	expected := exp.Schema
+	if expected == nil && exp.Binder != nil {
+		expected = schemaOf(exp.Topic, exp.Binder())
+	}
+	produced := schemaOf(ex.Topic, ex.Body)
+	if expected == nil || produced == nil {
+		return nil
+	}
+	var ie *schema.IncompatibleError
+	if err := schema.Check(expected, produced, schema.CompatibilityForward); errors.As(err, &ie) {
+		return fmt.Errorf("schema: %s", strings.Join(ie.Problems, ", "))
+	}
+	return nil
+}
+
+func schemaOf(topic string, v broker.Any) *schema.Schema {
+	var s *schema.Schema
+	var err error
+	if pm, ok := v.(proto.Message); ok {
+		s, err = schema.FromDescriptor(topic, pm.ProtoReflect().Descriptor())
+	} else {
+		s, err = schema.FromStruct(topic, v)
+	}
+	if err != nil {
+		return nil
+	}
+	return s
+}
+
+type event struct {
+	topic string
+	m     *broker.Message
+}
+
+func (e *event) Topic() string            { return e.topic }
+func (e *event) Message() *broker.Message { return e.m }
+func (e *event) RawMessage() interface{}  { return e.m }
+func (e *event) Ack() error               { return nil }
+func (e *event) Error() error             { return nil }
@@ -0,0 +1,81 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type order struct {
+	ID     string `json:"id"`
+	Amount int64  `json:"amount"`
+}
+
+type legacyOrder struct {
+	ID     string `json:"id"`
+	Amount string `json:"amount"`
+}
+
+type billingOrder struct {
+	ID     string `json:"id"`
+	Amount int64  `json:"amount"`
+	Paid   bool   `json:"paid"`
+}
+
+func newOrder() broker.Any { return &order{} }
+
+func TestContract(t *testing.T) {
+	var handled []string
+
+	c := New(WithCodec("json"))
+	c.Produce(Example{Topic: "orders", Name: "created", Body: &order{ID: "1", Amount: 10}})
+	c.Consume(Expectation{
+		Topic:  "orders",
+		Name:   "shipping",
+		Binder: newOrder,
+		Handler: func(_ context.Context, event broker.Event) error {
+			o := event.Message().Body.(*order)
+			handled = append(handled, o.ID)
+			return nil
+		},
+	})
+	c.Require(t)
+	assert.Equal(t, []string{"1"}, handled)
+}
+
+func TestContract_Broken(t *testing.T) {
+	c := New(WithCodec("json"))
+	c.Produce(
+		Example{Topic: "orders", Name: "legacy", Body: &legacyOrder{ID: "1", Amount: "10"}},
+		Example{Topic: "orders", Name: "refused", Body: &order{ID: "2"}},
+	)
+	c.Consume(
+		Expectation{
+			Topic:  "orders",
+			Name:   "shipping",
+			Binder: newOrder,
+			Handler: func(_ context.Context, event broker.Event) error {
+				if event.Message().Body.(*order).Amount == 0 {
+					return errors.New("amount is required")
+				}
+				return nil
+			},
+		},
+		Expectation{Topic: "orders", Name: "billing", Binder: func() broker.Any { return &billingOrder{} }},
+		Expectation{Topic: "payments", Name: "billing"},
+	)
+
+	err := c.Verify(context.Background())
+	assert.ErrorIs(t, err, ErrContractBroken)
+	assert.Equal(t, []string{
+		"orders: example legacy to consumer shipping: unmarshal: json: cannot unmarshal string into Go struct field Any.amount of type int64",
+		"orders: example refused to consumer shipping: handler: amount is required",
+		"orders: example legacy to consumer billing: unmarshal: json: cannot unmarshal string into Go struct field Any.amount of type int64",
+		"orders: example refused to consumer billing: schema: required field paid removed",
+		"payments: no example for consumer billing",
+	}, err.(*Error).Problems)
+}
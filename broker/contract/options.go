@@ -0,0 +1,15 @@
+package contract
+
+import (
+	"github.com/go-kratos/kratos/v2/encoding"
+)
+
+type Option func(c *Contract)
+
+// WithCodec encode and decode the examples with the codec name, as the brokers
+// configured with broker.WithCodec do.
+func WithCodec(name string) Option {
+	return func(c *Contract) {
+		c.codec = encoding.GetCodec(name)
+	}
+}
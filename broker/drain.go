@@ -0,0 +1,182 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+var ErrDrainUnsupported = errors.New("broker does not support draining")
+
+// DrainProgress is reported while draining, once when it starts and every
+// time a handler returns.
+type DrainProgress struct {
+	InFlight int64
+	Elapsed  time.Duration
+}
+
+// Drainer is implemented by the brokers and the servers able to stop
+// fetching and wait for their running handlers, e.g. in a preStop hook.
+type Drainer interface {
+	// Drain stop fetching and wait until the running handlers return or
+	// ctx is done, reporting the progress to progress when not nil.
+	Drain(ctx context.Context, progress chan<- DrainProgress) error
+}
+
+// Drain drain b, e.g. a GracefulBroker. ErrDrainUnsupported is returned
+// for the brokers not implementing Drainer.
+func Drain(ctx context.Context, b Broker, progress chan<- DrainProgress) error {
+	d, ok := b.(Drainer)
+	if !ok {
+		return ErrDrainUnsupported
+	}
+	return d.Drain(ctx, progress)
+}
+
+// HandlerTracker counts the running handlers it wraps, for the brokers and
+// the servers implementing Drainer. The zero value is ready to use.
+type HandlerTracker struct {
+	mu       sync.Mutex
+	stopped  bool
+	inflight int64
+	changed  chan struct{}
+}
+
+// Track wrap handler to count its runs, once the tracker is stopped the
+// messages are rejected with ErrConsumingStopped so they are redelivered.
+func (t *HandlerTracker) Track(handler Handler) Handler {
+	return func(ctx context.Context, event Event) error {
+		if !t.begin() {
+			return ErrConsumingStopped
+		}
+		defer t.end()
+
+		return handler(ctx, event)
+	}
+}
+
+// InFlight return the number of running handlers.
+func (t *HandlerTracker) InFlight() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.inflight
+}
+
+// Stop reject the messages arriving from now on.
+func (t *HandlerTracker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stopped = true
+}
+
+// Drain stop the tracker and wait until the running handlers return or ctx
+// is done. The progress is sent without blocking, a slow reader misses some.
+func (t *HandlerTracker) Drain(ctx context.Context, progress chan<- DrainProgress) error {
+	start := time.Now()
+
+	t.mu.Lock()
+	t.stopped = true
+	t.mu.Unlock()
+
+	for {
+		t.mu.Lock()
+		n := t.inflight
+		if t.changed == nil {
+			t.changed = make(chan struct{})
+		}
+		changed := t.changed
+		t.mu.Unlock()
+
+		if progress != nil {
+			select {
+			case progress <- DrainProgress{InFlight: n, Elapsed: time.Since(start)}:
+			default:
+			}
+		}
+		if n == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("drain with %d handlers in flight: %w", n, ctx.Err())
+		case <-changed:
+		}
+	}
+}
+
+func (t *HandlerTracker) begin() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return false
+	}
+	t.inflight++
+	return true
+}
+
+func (t *HandlerTracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inflight--
+	if t.changed != nil {
+		close(t.changed)
+		t.changed = nil
+	}
+}
+
+// DrainHook return a hook for kratos.BeforeStop draining b for timeout at
+// most and logging the progress, so the handlers are done before the
+// servers stop:
+//
+//	b := broker.NewGracefulBroker(kafka.NewBroker(opts...))
+//	app := kratos.New(kratos.Server(srv), kratos.BeforeStop(broker.DrainHook(b, 30*time.Second)))
+func DrainHook(b Broker, timeout time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		progress := make(chan DrainProgress, 1)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for p := range progress {
+				log.Infof("[broker] draining [%s], %d handlers in flight after %s", b.Name(), p.InFlight, p.Elapsed)
+			}
+		}()
+
+		err := Drain(ctx, b, progress)
+		close(progress)
+		<-done
+
+		if err != nil {
+			log.Errorf("[broker] drain [%s] failed: %v", b.Name(), err)
+		}
+		return err
+	}
+}
+
+// DrainHandler return an http.Handler draining b for timeout at most, for
+// a preStop httpGet hook. It answers 200 once drained, 503 otherwise.
+func DrainHandler(b Broker, timeout time.Duration) http.Handler {
+	hook := DrainHook(b, timeout)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := hook(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("drained\n"))
+	})
+}
@@ -0,0 +1,83 @@
+package broker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerTracker_Drain(t *testing.T) {
+	var tracker HandlerTracker
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	handler := tracker.Track(func(context.Context, Event) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+
+	handled := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			handled <- handler(context.Background(), nil)
+		}()
+		<-started
+	}
+	assert.Equal(t, int64(2), tracker.InFlight())
+
+	progress := make(chan DrainProgress, 4)
+	drained := make(chan error)
+	go func() {
+		drained <- tracker.Drain(context.Background(), progress)
+	}()
+	assert.Equal(t, int64(2), (<-progress).InFlight)
+
+	assert.ErrorIs(t, handler(context.Background(), nil), ErrConsumingStopped)
+
+	close(release)
+	assert.Nil(t, <-handled)
+	assert.Nil(t, <-handled)
+	assert.Nil(t, <-drained)
+	assert.Equal(t, int64(0), tracker.InFlight())
+}
+
+func TestHandlerTracker_DrainTimeout(t *testing.T) {
+	var tracker HandlerTracker
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := tracker.Track(func(context.Context, Event) error {
+		close(started)
+		<-release
+		return nil
+	})
+	go func() {
+		_ = handler(context.Background(), nil)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, tracker.Drain(ctx, nil), context.DeadlineExceeded)
+}
+
+func TestDrainHandler(t *testing.T) {
+	rb := newRecordBroker("shared")
+	b := NewGracefulBroker(rb)
+
+	_, err := b.Subscribe("orders", func(context.Context, Event) error { return nil }, nil)
+	assert.Nil(t, err)
+
+	rec := httptest.NewRecorder()
+	DrainHandler(b, time.Second).ServeHTTP(rec, httptest.NewRequest("GET", "/drain", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.ErrorIs(t, rb.handlers["orders"](context.Background(), nil), ErrConsumingStopped)
+
+	assert.ErrorIs(t, Drain(context.Background(), rb, nil), ErrDrainUnsupported)
+}
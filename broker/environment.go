@@ -0,0 +1,46 @@
+package broker
+
+import (
+	"context"
+)
+
+type EnvOption func(b *tenantBroker)
+
+// WithEnvFormat set how the names are prefixed, default is "env.name".
+func WithEnvFormat(fn func(env, name string) string) EnvOption {
+	return func(b *tenantBroker) {
+		b.format = fn
+	}
+}
+
+// WithEnvSharedTopics keep the topics matching patterns, in path.Match
+// syntax, shared by all the environments, e.g. reference data fed once.
+func WithEnvSharedTopics(patterns ...string) EnvOption {
+	return func(b *tenantBroker) {
+		b.shared = append(b.shared, patterns...)
+	}
+}
+
+// NewEnvBroker isolates the environments sharing b, e.g. preview
+// deployments of branches: topics, queues and consumer groups are prefixed
+// with env, so the environments never consume each other's messages. b is
+// returned as is when env is empty, e.g. NewEnvBroker(b, os.Getenv("PREVIEW_ENV")).
+//
+// It is a tenant broker whose tenant is always env.
+func NewEnvBroker(b Broker, env string, opts ...EnvOption) Broker {
+	if env == "" {
+		return b
+	}
+
+	eb := newTenantBroker(b)
+	eb.resolve = func(context.Context) (string, bool) {
+		return env, true
+	}
+	eb.untagged = true
+
+	for _, o := range opts {
+		o(eb)
+	}
+
+	return eb
+}
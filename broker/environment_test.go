@@ -0,0 +1,46 @@
+package broker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvBroker(t *testing.T) {
+	rb := newRecordBroker("shared")
+	assert.Same(t, rb, NewEnvBroker(rb, "").(*recordBroker))
+
+	b := NewEnvBroker(rb, "pr-42", WithEnvSharedTopics("currency.*"))
+
+	assert.Nil(t, b.Publish(context.Background(), "orders", "msg"))
+	assert.Nil(t, b.Publish(context.Background(), "currency.rates", "msg"))
+	assert.Equal(t, []string{"pr-42.orders", "currency.rates"}, rb.published)
+
+	_, err := b.Subscribe("orders", nil, nil, WithQueueName("billing"))
+	assert.Nil(t, err)
+	_, err = b.Subscribe("currency.rates", nil, nil, WithQueueName("billing"))
+	assert.Nil(t, err)
+	assert.Equal(t, "pr-42.orders", rb.subscribed[0].topic)
+	assert.Equal(t, "pr-42.billing", rb.subscribed[0].options.Queue)
+	assert.Equal(t, "currency.rates", rb.subscribed[1].topic)
+	assert.Equal(t, "pr-42.billing", rb.subscribed[1].options.Queue)
+
+	b = NewEnvBroker(rb, "pr-42", WithEnvFormat(func(env, name string) string { return name + "-" + env }))
+	assert.Nil(t, b.Publish(context.Background(), "orders", "msg"))
+	assert.Equal(t, "orders-pr-42", rb.published[2])
+}
+
+func TestEnvBroker_HandlerContext(t *testing.T) {
+	rb := newRecordBroker("shared")
+	b := NewEnvBroker(rb, "pr-42")
+
+	_, err := b.Subscribe("orders", func(ctx context.Context, _ Event) error {
+		// the environment is not a tenant.
+		_, ok := TenantFromContext(ctx)
+		assert.False(t, ok)
+		return nil
+	}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, rb.handlers["pr-42.orders"](context.Background(), nil))
+}
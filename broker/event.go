@@ -1,6 +1,12 @@
 package broker
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrHandlerTimeout = errors.New("handler timeout")
 
 type Event interface {
 	Topic() string
@@ -14,3 +20,44 @@ type Event interface {
 }
 
 type Handler func(ctx context.Context, evt Event) error
+
+// TimeoutHandler runs handler with a context canceled after timeout and returns
+// ErrHandlerTimeout without waiting for a handler ignoring the cancellation.
+//
+// The cancellation is cooperative: a handler not watching its context keeps
+// running after the timeout, concurrently with the redelivery of its message.
+func TimeoutHandler(handler Handler, timeout time.Duration) Handler {
+	return timeoutHandler(handler, timeout, nil)
+}
+
+// timeoutHandler is TimeoutHandler calling onTimeout, when set, for the
+// result of the messages timing out.
+func timeoutHandler(handler Handler, timeout time.Duration, onTimeout Handler) Handler {
+	if timeout <= 0 {
+		return handler
+	}
+
+	return func(ctx context.Context, evt Event) error {
+		ctx, cancel := context.WithTimeoutCause(ctx, timeout, ErrHandlerTimeout)
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- handler(ctx, evt)
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			// the parent was canceled, e.g. on shutdown, it is no timeout.
+			if cause := context.Cause(ctx); !errors.Is(cause, ErrHandlerTimeout) {
+				return cause
+			}
+			if onTimeout != nil {
+				return onTimeout(context.WithoutCancel(ctx), evt)
+			}
+			return ErrHandlerTimeout
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutHandler(t *testing.T) {
+	slow := TimeoutHandler(func(ctx context.Context, _ Event) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, 10*time.Millisecond)
+	assert.ErrorIs(t, slow(context.Background(), nil), ErrHandlerTimeout)
+
+	fast := TimeoutHandler(func(ctx context.Context, _ Event) error {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		return nil
+	}, time.Second)
+	assert.Nil(t, fast(context.Background(), nil))
+
+	// a canceled parent, e.g. on shutdown, is not reported as a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	go func() {
+		<-started
+		cancel()
+	}()
+	stopped := TimeoutHandler(func(ctx context.Context, _ Event) error {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return ctx.Err()
+	}, time.Minute)
+	err := stopped(ctx, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrHandlerTimeout)
+}
+
+func TestSubscribeOptions_TimeoutHandler(t *testing.T) {
+	slow := func(ctx context.Context, _ Event) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	event := &testEvent{topic: "orders", message: &Message{Headers: Headers{"id": "1"}, Body: "order"}}
+
+	so := NewSubscribeOptions(WithHandlerTimeout(10 * time.Millisecond))
+	assert.ErrorIs(t, so.TimeoutHandler(slow)(context.Background(), event), ErrHandlerTimeout)
+
+	pb := &parkBroker{recordBroker: *newRecordBroker("park")}
+	so = NewSubscribeOptions(WithHandlerTimeout(10*time.Millisecond), WithParkOnTimeout(pb, "orders.slow"))
+	assert.Nil(t, so.TimeoutHandler(slow)(context.Background(), event))
+	assert.Equal(t, []string{"orders.slow"}, pb.published)
+	assert.Equal(t, "1", pb.headers["id"])
+	assert.Equal(t, "orders", pb.headers[OriginTopicHeader])
+
+	pb.err = errors.New("unavailable")
+	assert.ErrorIs(t, so.TimeoutHandler(slow)(context.Background(), event), ErrHandlerTimeout)
+}
@@ -0,0 +1,33 @@
+package exactlyonce
+
+import "database/sql"
+
+type Option func(p *Processor)
+
+// WithStore set the dedup record store, default is SQLStore on table processed_messages.
+func WithStore(store Store) Option {
+	return func(p *Processor) {
+		p.store = store
+	}
+}
+
+// WithConsumerName set the name dedup records are scoped to, default is the topic.
+func WithConsumerName(name string) Option {
+	return func(p *Processor) {
+		p.consumer = name
+	}
+}
+
+// WithKeyFunc set the dedup key extractor, default reads header x-message-id.
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(p *Processor) {
+		p.keyFunc = fn
+	}
+}
+
+// WithTxOptions set the options of the processing transaction.
+func WithTxOptions(opts *sql.TxOptions) Option {
+	return func(p *Processor) {
+		p.txOptions = opts
+	}
+}
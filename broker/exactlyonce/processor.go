@@ -0,0 +1,105 @@
+package exactlyonce
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/sqlutil"
+)
+
+const MessageKeyHeader = "x-message-id"
+
+var ErrMissingMessageKey = errors.New("exactlyonce: message key is empty")
+
+// TxHandler performs the side effect of a message inside tx.
+type TxHandler func(ctx context.Context, tx *sql.Tx, event broker.Event) error
+
+// KeyFunc extracts the dedup key of a message.
+type KeyFunc func(event broker.Event) (string, error)
+
+// Processor runs handlers so that the side effect and the dedup record are
+// committed in the same database transaction. A redelivered message whose
+// record already exists is acknowledged without calling the handler again.
+type Processor struct {
+	db        *sql.DB
+	store     Store
+	consumer  string
+	keyFunc   KeyFunc
+	txOptions *sql.TxOptions
+}
+
+func NewProcessor(db *sql.DB, opts ...Option) *Processor {
+	p := &Processor{
+		db:      db,
+		store:   NewSQLStore(DefaultTableName, sqlutil.Question),
+		keyFunc: HeaderKeyFunc(MessageKeyHeader),
+	}
+
+	for _, o := range opts {
+		o(p)
+	}
+
+	return p
+}
+
+// Handler wraps handler into a broker.Handler.
+func (p *Processor) Handler(handler TxHandler) broker.Handler {
+	return func(ctx context.Context, event broker.Event) error {
+		return p.Process(ctx, event, handler)
+	}
+}
+
+func (p *Processor) Process(ctx context.Context, event broker.Event, handler TxHandler) (err error) {
+	key, err := p.keyFunc(event)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return ErrMissingMessageKey
+	}
+
+	consumer := p.consumer
+	if consumer == "" {
+		consumer = event.Topic()
+	}
+
+	tx, err := p.db.BeginTx(ctx, p.txOptions)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var exists bool
+	if exists, err = p.store.Exists(ctx, tx, consumer, key); err != nil {
+		return err
+	}
+	if exists {
+		return tx.Rollback()
+	}
+
+	if err = handler(ctx, tx, event); err != nil {
+		return err
+	}
+
+	if err = p.store.Save(ctx, tx, consumer, key); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// HeaderKeyFunc uses the value of a message header as the dedup key.
+func HeaderKeyFunc(header string) KeyFunc {
+	return func(event broker.Event) (string, error) {
+		if event.Message() == nil {
+			return "", ErrMissingMessageKey
+		}
+		return event.Message().GetHeader(header), nil
+	}
+}
@@ -0,0 +1,128 @@
+package exactlyonce
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type testEvent struct {
+	topic string
+	m     *broker.Message
+}
+
+func (e *testEvent) Topic() string            { return e.topic }
+func (e *testEvent) Message() *broker.Message { return e.m }
+func (e *testEvent) RawMessage() interface{}  { return e.m }
+func (e *testEvent) Ack() error               { return nil }
+func (e *testEvent) Error() error             { return nil }
+
+type txCounter struct {
+	sync.Mutex
+	commits   int
+	rollbacks int
+}
+
+type testDriver struct{ c *txCounter }
+type testConn struct{ c *txCounter }
+type testTx struct{ c *txCounter }
+
+func (d testDriver) Open(string) (driver.Conn, error) { return testConn(d), nil }
+
+// testDriver is its own connector, so every test opens its db without
+// registering a driver name.
+func (d testDriver) Connect(context.Context) (driver.Conn, error) { return testConn(d), nil }
+func (d testDriver) Driver() driver.Driver                        { return d }
+
+func (c testConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c testConn) Close() error                        { return nil }
+func (c testConn) Begin() (driver.Tx, error)           { return testTx(c), nil }
+
+func (t testTx) Commit() error {
+	t.c.Lock()
+	defer t.c.Unlock()
+	t.c.commits++
+	return nil
+}
+
+func (t testTx) Rollback() error {
+	t.c.Lock()
+	defer t.c.Unlock()
+	t.c.rollbacks++
+	return nil
+}
+
+type memoryStore struct {
+	keys map[string]bool
+}
+
+func (s *memoryStore) Exists(_ context.Context, _ *sql.Tx, consumer, key string) (bool, error) {
+	return s.keys[consumer+"/"+key], nil
+}
+
+func (s *memoryStore) Save(_ context.Context, _ *sql.Tx, consumer, key string) error {
+	s.keys[consumer+"/"+key] = true
+	return nil
+}
+
+func newTestProcessor(t *testing.T) (*Processor, *txCounter, *memoryStore) {
+	counter := &txCounter{}
+	db := sql.OpenDB(testDriver{c: counter})
+	t.Cleanup(func() { _ = db.Close() })
+
+	store := &memoryStore{keys: map[string]bool{}}
+	return NewProcessor(db, WithStore(store)), counter, store
+}
+
+func TestProcessor_Dedup(t *testing.T) {
+	p, counter, store := newTestProcessor(t)
+
+	var calls int
+	h := p.Handler(func(_ context.Context, _ *sql.Tx, _ broker.Event) error {
+		calls++
+		return nil
+	})
+
+	evt := &testEvent{topic: "orders", m: &broker.Message{Headers: broker.Headers{MessageKeyHeader: "1"}}}
+
+	assert.Nil(t, h(context.Background(), evt))
+	assert.Nil(t, h(context.Background(), evt))
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, counter.commits)
+	assert.Equal(t, 1, counter.rollbacks)
+	assert.True(t, store.keys["orders/1"])
+}
+
+func TestProcessor_HandlerError(t *testing.T) {
+	p, counter, store := newTestProcessor(t)
+
+	h := p.Handler(func(_ context.Context, _ *sql.Tx, _ broker.Event) error {
+		return errors.New("write failed")
+	})
+
+	evt := &testEvent{topic: "orders", m: &broker.Message{Headers: broker.Headers{MessageKeyHeader: "1"}}}
+
+	assert.NotNil(t, h(context.Background(), evt))
+	assert.Equal(t, 0, counter.commits)
+	assert.Equal(t, 1, counter.rollbacks)
+	assert.False(t, store.keys["orders/1"])
+}
+
+func TestProcessor_MissingKey(t *testing.T) {
+	p, _, _ := newTestProcessor(t)
+
+	h := p.Handler(func(_ context.Context, _ *sql.Tx, _ broker.Event) error {
+		return nil
+	})
+
+	err := h(context.Background(), &testEvent{topic: "orders", m: &broker.Message{}})
+	assert.ErrorIs(t, err, ErrMissingMessageKey)
+}
@@ -0,0 +1,69 @@
+package exactlyonce
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker/sqlutil"
+)
+
+// Store records which messages have already been processed by a consumer.
+// Both methods run inside the transaction of the handler's side effect.
+type Store interface {
+	Exists(ctx context.Context, tx *sql.Tx, consumer, key string) (bool, error)
+	Save(ctx context.Context, tx *sql.Tx, consumer, key string) error
+}
+
+const DefaultTableName = "processed_messages"
+
+// SQLStore keeps dedup records in a table shaped like:
+//
+//	CREATE TABLE processed_messages (
+//	    consumer     VARCHAR(255) NOT NULL,
+//	    message_key  VARCHAR(255) NOT NULL,
+//	    processed_at TIMESTAMP    NOT NULL,
+//	    PRIMARY KEY (consumer, message_key)
+//	);
+type SQLStore struct {
+	table       string
+	placeholder sqlutil.PlaceholderFormat
+}
+
+func NewSQLStore(table string, placeholder sqlutil.PlaceholderFormat) *SQLStore {
+	if table == "" {
+		table = DefaultTableName
+	}
+	if placeholder == nil {
+		placeholder = sqlutil.Question
+	}
+	return &SQLStore{
+		table:       table,
+		placeholder: placeholder,
+	}
+}
+
+func (s *SQLStore) Exists(ctx context.Context, tx *sql.Tx, consumer, key string) (bool, error) {
+	query := fmt.Sprintf("SELECT 1 FROM %s WHERE consumer = %s AND message_key = %s",
+		s.table, s.placeholder(1), s.placeholder(2))
+
+	var found int
+	err := tx.QueryRowContext(ctx, query, consumer, key).Scan(&found)
+	switch {
+	case err == sql.ErrNoRows:
+		return false, nil
+	case err != nil:
+		return false, err
+	default:
+		return true, nil
+	}
+}
+
+func (s *SQLStore) Save(ctx context.Context, tx *sql.Tx, consumer, key string) error {
+	query := fmt.Sprintf("INSERT INTO %s (consumer, message_key, processed_at) VALUES (%s, %s, %s)",
+		s.table, s.placeholder(1), s.placeholder(2), s.placeholder(3))
+
+	_, err := tx.ExecContext(ctx, query, consumer, key, time.Now())
+	return err
+}
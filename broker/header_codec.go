@@ -0,0 +1,218 @@
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ErrHeaderNotFound is returned by the typed getters of Headers for a missing key.
+var ErrHeaderNotFound = errors.New("header not found")
+
+// HeaderCodec serialize the typed header values of the drivers to the strings
+// of Headers and back.
+type HeaderCodec interface {
+	// EncodeHeader return the string of v.
+	EncodeHeader(v any) (string, error)
+	// DecodeHeader decode s into the value v points to.
+	DecodeHeader(s string, v any) error
+}
+
+// DefaultHeaderCodec is the codec of the typed getters of Headers and of the
+// drivers without WithHeaderCodec. Strings are kept as is, the numbers and
+// booleans are formatted with strconv, time.Time as RFC 3339, time.Duration
+// as by its String method and the other values, the nested tables among them,
+// as JSON.
+var DefaultHeaderCodec HeaderCodec = textHeaderCodec{}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+type textHeaderCodec struct{}
+
+func (textHeaderCodec) EncodeHeader(v any) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case []byte:
+		return string(t), nil
+	case time.Time:
+		return t.Format(time.RFC3339Nano), nil
+	case time.Duration:
+		return t.String(), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64), nil
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func (textHeaderCodec) DecodeHeader(s string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("decode header into non-pointer %T", v)
+	}
+	e := rv.Elem()
+
+	switch e.Type() {
+	case timeType:
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		e.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		e.SetInt(int64(d))
+		return nil
+	}
+
+	switch e.Kind() {
+	case reflect.String:
+		e.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		e.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, e.Type().Bits())
+		if err != nil {
+			return err
+		}
+		e.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, e.Type().Bits())
+		if err != nil {
+			return err
+		}
+		e.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, e.Type().Bits())
+		if err != nil {
+			return err
+		}
+		e.SetFloat(f)
+	case reflect.Slice:
+		if e.Type().Elem().Kind() == reflect.Uint8 {
+			e.SetBytes([]byte(s))
+			return nil
+		}
+		return json.Unmarshal([]byte(s), v)
+	default:
+		return json.Unmarshal([]byte(s), v)
+	}
+	return nil
+}
+
+// EncodeHeader return the string of the typed header value v with codec,
+// DefaultHeaderCodec when nil. The values codec fails on are formatted with
+// fmt.Sprint rather than dropped.
+func EncodeHeader(codec HeaderCodec, v any) string {
+	if codec == nil {
+		codec = DefaultHeaderCodec
+	}
+	s, err := codec.EncodeHeader(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return s
+}
+
+// Get return the value of key, empty when missing.
+func (h Headers) Get(key string) string {
+	return h[key]
+}
+
+// Set encode v with DefaultHeaderCodec and set it as the value of key.
+func (h Headers) Set(key string, v any) error {
+	s, err := DefaultHeaderCodec.EncodeHeader(v)
+	if err != nil {
+		return err
+	}
+	h[key] = s
+	return nil
+}
+
+// Decode decode the value of key into the value v points to with
+// DefaultHeaderCodec.
+func (h Headers) Decode(key string, v any) error {
+	s, ok := h[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrHeaderNotFound, key)
+	}
+	return DefaultHeaderCodec.DecodeHeader(s, v)
+}
+
+func (h Headers) GetInt(key string) (int64, error) {
+	var v int64
+	err := h.Decode(key, &v)
+	return v, err
+}
+
+func (h Headers) GetUint(key string) (uint64, error) {
+	var v uint64
+	err := h.Decode(key, &v)
+	return v, err
+}
+
+func (h Headers) GetFloat(key string) (float64, error) {
+	var v float64
+	err := h.Decode(key, &v)
+	return v, err
+}
+
+func (h Headers) GetBool(key string) (bool, error) {
+	var v bool
+	err := h.Decode(key, &v)
+	return v, err
+}
+
+func (h Headers) GetTime(key string) (time.Time, error) {
+	var v time.Time
+	err := h.Decode(key, &v)
+	return v, err
+}
+
+func (h Headers) GetDuration(key string) (time.Duration, error) {
+	var v time.Duration
+	err := h.Decode(key, &v)
+	return v, err
+}
+
+// GetMap return the nested table of key.
+func (h Headers) GetMap(key string) (map[string]any, error) {
+	var v map[string]any
+	err := h.Decode(key, &v)
+	return v, err
+}
@@ -0,0 +1,72 @@
+package broker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaders_Typed(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 10, 30, 0, 123, time.UTC)
+
+	h := Headers{}
+	assert.Nil(t, h.Set("name", "order"))
+	assert.Nil(t, h.Set("count", 42))
+	assert.Nil(t, h.Set("size", uint16(7)))
+	assert.Nil(t, h.Set("ratio", 0.25))
+	assert.Nil(t, h.Set("urgent", true))
+	assert.Nil(t, h.Set("at", ts))
+	assert.Nil(t, h.Set("ttl", 90*time.Second))
+	assert.Nil(t, h.Set("meta", map[string]any{"region": "eu", "retries": 3}))
+
+	assert.Equal(t, "order", h.Get("name"))
+	assert.Equal(t, "42", h.Get("count"))
+
+	n, err := h.GetInt("count")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), n)
+
+	u, err := h.GetUint("size")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(7), u)
+
+	f, err := h.GetFloat("ratio")
+	assert.Nil(t, err)
+	assert.Equal(t, 0.25, f)
+
+	b, err := h.GetBool("urgent")
+	assert.Nil(t, err)
+	assert.True(t, b)
+
+	at, err := h.GetTime("at")
+	assert.Nil(t, err)
+	assert.True(t, ts.Equal(at))
+
+	ttl, err := h.GetDuration("ttl")
+	assert.Nil(t, err)
+	assert.Equal(t, 90*time.Second, ttl)
+
+	meta, err := h.GetMap("meta")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"region": "eu", "retries": float64(3)}, meta)
+
+	_, err = h.GetInt("missing")
+	assert.True(t, errors.Is(err, ErrHeaderNotFound))
+
+	_, err = h.GetInt("name")
+	assert.NotNil(t, err)
+}
+
+func TestEncodeHeader(t *testing.T) {
+	assert.Equal(t, "", EncodeHeader(nil, nil))
+	assert.Equal(t, "raw", EncodeHeader(nil, []byte("raw")))
+	assert.Equal(t, "-3", EncodeHeader(nil, int8(-3)))
+	assert.Equal(t, "1.5", EncodeHeader(nil, float32(1.5)))
+	assert.Equal(t, `{"a":{"b":[1,"x"]}}`, EncodeHeader(nil, map[string]any{"a": map[string]any{"b": []any{1, "x"}}}))
+
+	// the values the codec fails on are formatted rather than dropped.
+	ch := make(chan int)
+	assert.NotEqual(t, "", EncodeHeader(nil, ch))
+}
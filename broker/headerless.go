@@ -0,0 +1,40 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrHeadersUnsupported = errors.New("broker does not support message headers")
+
+// HeaderCarrier is implemented by the drivers whose messages may have no
+// room for headers, e.g. MQTT 3.1.1, Redis pub/sub, NSQ or MNS. These refuse
+// the publishes with headers instead of dropping them.
+type HeaderCarrier interface {
+	CarriesHeaders() bool
+}
+
+// CarriesHeaders report whether the headers of the messages published with b
+// reach the subscribers, true unless b implements HeaderCarrier saying otherwise.
+func CarriesHeaders(b Broker) bool {
+	hc, ok := b.(HeaderCarrier)
+	return !ok || hc.CarriesHeaders()
+}
+
+// RequireHeaders return ErrHeadersUnsupported when b drops the headers, for
+// the wrappers whose messages cannot be read back without them.
+func RequireHeaders(b Broker) error {
+	if !CarriesHeaders(b) {
+		return fmt.Errorf("%w: %s", ErrHeadersUnsupported, b.Name())
+	}
+	return nil
+}
+
+// RejectHeaders return ErrHeadersUnsupported when a message published to
+// topic has headers, for the drivers unable to carry them.
+func RejectHeaders(topic string, headers Headers) error {
+	if len(headers) > 0 {
+		return fmt.Errorf("%w: %d headers published to %s", ErrHeadersUnsupported, len(headers), topic)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package broker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type headerlessBroker struct {
+	Broker
+}
+
+func (headerlessBroker) Name() string         { return "headerless" }
+func (headerlessBroker) CarriesHeaders() bool { return false }
+
+func (b headerlessBroker) Publish(ctx context.Context, topic string, msg Any, opts ...PublishOption) error {
+	if err := RejectHeaders(topic, NewPublishOptions(opts...).Headers); err != nil {
+		return err
+	}
+	return b.Broker.Publish(ctx, topic, msg, opts...)
+}
+
+func TestCarriesHeaders(t *testing.T) {
+	assert.True(t, CarriesHeaders(&recordBroker{}))
+	assert.NoError(t, RequireHeaders(&recordBroker{}))
+
+	assert.False(t, CarriesHeaders(headerlessBroker{}))
+	assert.ErrorIs(t, RequireHeaders(headerlessBroker{}), ErrHeadersUnsupported)
+
+	assert.NoError(t, RejectHeaders("t", nil))
+	assert.ErrorIs(t, RejectHeaders("t", Headers{"k": "v"}), ErrHeadersUnsupported)
+}
@@ -0,0 +1,191 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// MessageIDHeader carries the id shared by the copies of a hedged message,
+// the header exactlyonce deduplicates on too.
+const MessageIDHeader = "x-message-id"
+
+type hedgeBroker struct {
+	broker.Broker
+
+	hedges  []broker.Broker
+	delay   time.Duration
+	all     bool
+	onHedge func(topic string, attempt int)
+	clock   broker.Clock
+
+	// headers is false when a target drops the headers, hence the id.
+	headers bool
+}
+
+// NewBroker wraps b to hedge latency critical publishes: when b has not
+// acknowledged a publish within the delay, or failed it, the message is
+// sent again through the next of hedges, other connections or nodes of
+// the same cluster, and the first ack wins. The copies share an id in
+// MessageIDHeader, consume them through Dedup.
+//
+// Only synchronous publishes are hedged usefully, an asynchronous producer
+// acknowledges before the broker does. Nor are the publishes through brokers
+// whose messages have no headers, see broker.CarriesHeaders: the copies could
+// not be deduplicated, so these are published once, unhedged.
+func NewBroker(b broker.Broker, hedges []broker.Broker, opts ...Option) broker.Broker {
+	hb := &hedgeBroker{
+		Broker:  b,
+		hedges:  hedges,
+		delay:   50 * time.Millisecond,
+		clock:   broker.SystemClock,
+		headers: broker.CarriesHeaders(b),
+	}
+	for _, h := range hedges {
+		hb.headers = hb.headers && broker.CarriesHeaders(h)
+	}
+
+	for _, o := range opts {
+		o(hb)
+	}
+
+	return hb
+}
+
+func (b *hedgeBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	options := broker.NewPublishOptions(opts...)
+	if hedged, _ := options.Context.Value(hedgedKey{}).(bool); !hedged && !b.all || len(b.hedges) == 0 || !b.headers {
+		return b.Broker.Publish(ctx, topic, msg, opts...)
+	}
+
+	if options.Headers[MessageIDHeader] == "" {
+		opts = append(opts, broker.WithHeaders(broker.Headers{MessageIDHeader: uuid.New().String()}))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	targets := append([]broker.Broker{b.Broker}, b.hedges...)
+	results := make(chan error, len(targets))
+
+	next := 0
+	launch := func() {
+		target := targets[next]
+		if next > 0 && b.onHedge != nil {
+			b.onHedge(topic, next)
+		}
+		next++
+		go func() {
+			results <- target.Publish(ctx, topic, msg, opts...)
+		}()
+	}
+
+	launch()
+	timer := b.clock.NewTimer(b.delay)
+	defer timer.Stop()
+
+	var errs []error
+	for pending := 1; pending > 0; {
+		select {
+		case err := <-results:
+			pending--
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err)
+			// a failure does not wait for the delay.
+			if next < len(targets) {
+				launch()
+				pending++
+				resetTimer(timer, b.delay)
+			}
+		case <-timer.C():
+			if next < len(targets) {
+				launch()
+				pending++
+				timer.Reset(b.delay)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func resetTimer(t broker.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C():
+		default:
+		}
+	}
+	t.Reset(d)
+}
+
+type dedup struct {
+	sync.Mutex
+
+	window time.Duration
+	seen   map[string]time.Time
+	pruned time.Time
+}
+
+func (d *dedup) isSeen(id string, now time.Time) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	t, ok := d.seen[id]
+	return ok && now.Sub(t) <= d.window
+}
+
+func (d *dedup) mark(id string, now time.Time) {
+	d.Lock()
+	defer d.Unlock()
+
+	if now.Sub(d.pruned) > d.window {
+		for k, t := range d.seen {
+			if now.Sub(t) > d.window {
+				delete(d.seen, k)
+			}
+		}
+		d.pruned = now
+	}
+	d.seen[id] = now
+}
+
+// Dedup drop the copies of the messages handled within window, by their
+// MessageIDHeader. The ids are remembered in memory, by a single consumer;
+// use exactlyonce to deduplicate across instances.
+func Dedup(window time.Duration) func(broker.Handler) broker.Handler {
+	d := &dedup{
+		window: window,
+		seen:   make(map[string]time.Time),
+	}
+
+	return func(h broker.Handler) broker.Handler {
+		return func(ctx context.Context, event broker.Event) error {
+			var id string
+			if m := event.Message(); m != nil {
+				id = m.Headers[MessageIDHeader]
+			}
+			if id == "" {
+				return h(ctx, event)
+			}
+
+			if d.isSeen(id, time.Now()) {
+				return nil
+			}
+			if err := h(ctx, event); err != nil {
+				return err
+			}
+			d.mark(id, time.Now())
+			return nil
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func newFakeBroker(t *testing.T) *mocks.FakeBroker {
+	b := mocks.NewFakeBroker()
+	assert.NoError(t, b.Connect())
+	return b
+}
+
+// ids return the message ids published to b.
+func ids(b *mocks.FakeBroker) []string {
+	var ids []string
+	for _, p := range b.Published() {
+		ids = append(ids, p.Headers[MessageIDHeader])
+	}
+	return ids
+}
+
+func TestNewBroker(t *testing.T) {
+	ctx := context.Background()
+	slow := newFakeBroker(t)
+	fast := newFakeBroker(t)
+
+	var hedges []int
+	b := NewBroker(slow, []broker.Broker{fast},
+		WithDelay(10*time.Millisecond),
+		WithHedgeCallback(func(_ string, attempt int) { hedges = append(hedges, attempt) }),
+	)
+
+	// not hedged unless asked.
+	slow.ExpectPublish("orders")
+	slow.ExpectPublish("orders").Delay(time.Second)
+	assert.NoError(t, b.Publish(ctx, "orders", "o1"))
+	assert.Equal(t, []string{""}, ids(slow))
+	assert.Empty(t, ids(fast))
+
+	start := time.Now()
+	assert.NoError(t, b.Publish(ctx, "orders", "o2", Hedged()))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, []int{1}, hedges)
+
+	published := ids(slow)
+	assert.Len(t, published, 2)
+	assert.NotEmpty(t, published[1])
+	assert.Equal(t, []string{published[1]}, ids(fast))
+}
+
+func TestNewBroker_Failure(t *testing.T) {
+	ctx := context.Background()
+	failed := errors.New("boom")
+	primary := newFakeBroker(t)
+	primary.ExpectPublish("orders").Return(failed).Times(2)
+	secondary := newFakeBroker(t)
+	secondary.ExpectPublish("orders").Return(failed)
+
+	b := NewBroker(primary, []broker.Broker{secondary}, WithDelay(time.Hour), WithAllPublishes())
+
+	// failures hedge at once, the id set by the caller is kept.
+	err := b.Publish(ctx, "orders", "o1", broker.WithHeaders(broker.Headers{MessageIDHeader: "id-1"}))
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, []string{"id-1"}, ids(primary))
+	assert.Equal(t, []string{"id-1"}, ids(secondary))
+
+	assert.NoError(t, b.Publish(ctx, "orders", "o2"))
+}
+
+func TestNewBroker_Headerless(t *testing.T) {
+	ctx := context.Background()
+	primary := mocks.NewHeaderlessBroker()
+	assert.NoError(t, primary.Connect())
+	primary.ExpectPublish("orders").Delay(50 * time.Millisecond)
+	secondary := newFakeBroker(t)
+
+	var hedges []int
+	b := NewBroker(primary, []broker.Broker{secondary},
+		WithDelay(time.Millisecond),
+		WithHedgeCallback(func(_ string, attempt int) { hedges = append(hedges, attempt) }),
+	)
+
+	// the copies could not be told apart, the message is published once.
+	assert.NoError(t, b.Publish(ctx, "orders", "o1", Hedged()))
+	assert.Len(t, primary.Published(), 1)
+	assert.Empty(t, primary.Published()[0].Headers[MessageIDHeader])
+	assert.Empty(t, secondary.Published())
+	assert.Empty(t, hedges)
+}
+
+func TestDedup(t *testing.T) {
+	ctx := context.Background()
+
+	var handled []string
+	var err error
+	h := Dedup(time.Minute)(func(_ context.Context, event broker.Event) error {
+		handled = append(handled, event.Message().Body.(string))
+		return err
+	})
+
+	event := func(id, body string) broker.Event {
+		return mocks.NewEvent("orders", body, broker.Headers{MessageIDHeader: id})
+	}
+
+	failed := errors.New("boom")
+	err = failed
+	assert.ErrorIs(t, h(ctx, event("1", "a")), failed)
+	err = nil
+	assert.NoError(t, h(ctx, event("1", "a")))
+	assert.NoError(t, h(ctx, event("1", "a copy")))
+	assert.NoError(t, h(ctx, event("2", "b")))
+	assert.NoError(t, h(ctx, event("", "c")))
+	assert.NoError(t, h(ctx, event("", "d")))
+
+	assert.Equal(t, []string{"a", "a", "b", "c", "d"}, handled)
+}
@@ -0,0 +1,46 @@
+package hedge
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(b *hedgeBroker)
+
+// WithDelay set how long a publish waits for its ack before the next
+// broker is tried, default is 50ms. Pick about the p95 publish latency.
+func WithDelay(delay time.Duration) Option {
+	return func(b *hedgeBroker) {
+		b.delay = delay
+	}
+}
+
+// WithClock set the clock of the hedge delay, broker.SystemClock by default.
+func WithClock(clock broker.Clock) Option {
+	return func(b *hedgeBroker) {
+		b.clock = clock
+	}
+}
+
+// WithAllPublishes hedge every publish, not only those made with Hedged.
+func WithAllPublishes() Option {
+	return func(b *hedgeBroker) {
+		b.all = true
+	}
+}
+
+// WithHedgeCallback observe the publishes sent to the attempt-th broker,
+// starting at 1 for the first hedge.
+func WithHedgeCallback(fn func(topic string, attempt int)) Option {
+	return func(b *hedgeBroker) {
+		b.onHedge = fn
+	}
+}
+
+type hedgedKey struct{}
+
+// Hedged mark a latency critical publish to be hedged.
+func Hedged() broker.PublishOption {
+	return broker.PublishContextWithValue(hedgedKey{}, true)
+}
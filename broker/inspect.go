@@ -0,0 +1,44 @@
+package broker
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrInspectUnsupported = errors.New("broker does not support queue inspection")
+	ErrPurgeUnsupported   = errors.New("broker does not support queue purge")
+)
+
+// QueueInspector is implemented by the brokers able to count the messages
+// waiting in a queue, a topic for the log based brokers.
+type QueueInspector interface {
+	QueueDepth(ctx context.Context, queue string) (int64, error)
+}
+
+// QueuePurger is implemented by the brokers able to drop the messages waiting
+// in a queue.
+type QueuePurger interface {
+	// PurgeQueue return the number of messages dropped.
+	PurgeQueue(ctx context.Context, queue string) (int64, error)
+}
+
+// QueueDepth count the messages waiting in queue, ErrInspectUnsupported is
+// returned when b does not implement QueueInspector.
+func QueueDepth(ctx context.Context, b Broker, queue string) (int64, error) {
+	qi, ok := b.(QueueInspector)
+	if !ok {
+		return 0, ErrInspectUnsupported
+	}
+	return qi.QueueDepth(ctx, queue)
+}
+
+// PurgeQueue drop the messages waiting in queue, ErrPurgeUnsupported is
+// returned when b does not implement QueuePurger.
+func PurgeQueue(ctx context.Context, b Broker, queue string) (int64, error) {
+	qp, ok := b.(QueuePurger)
+	if !ok {
+		return 0, ErrPurgeUnsupported
+	}
+	return qp.PurgeQueue(ctx, queue)
+}
@@ -0,0 +1,89 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrPayloadTooLarge = errors.New("payload too large")
+
+// PublishInterceptor enriches or validates a message before it is published.
+// The body of msg is the encoded payload as []byte and must stay a []byte,
+// the headers set are added to the message, returning an error rejects it.
+type PublishInterceptor func(ctx context.Context, topic string, msg *Message) error
+
+// WithPublishInterceptors append interceptors to the publish path of the broker.
+func WithPublishInterceptors(interceptors ...PublishInterceptor) Option {
+	return func(o *Options) {
+		o.PublishInterceptors = append(o.PublishInterceptors, interceptors...)
+	}
+}
+
+// InterceptPublish run the publish interceptors on the encoded body buf and
+// return the body to publish, the topic defaults, the headers set by the
+// interceptors and the negotiated content type are passed to the driver in opts.
+func (o *Options) InterceptPublish(ctx context.Context, topic string, buf []byte, opts []PublishOption) ([]byte, []PublishOption, error) {
+	opts = o.TopicPublishOptions(topic, opts)
+
+	if len(o.PublishInterceptors) == 0 && !o.NegotiateContent {
+		if err := o.CheckMessageSize(topic, buf); err != nil {
+			return nil, nil, err
+		}
+		return buf, opts, nil
+	}
+
+	// the interceptors see the headers of the publish options.
+	msg := &Message{Headers: Headers{}, Body: buf}
+	for k, v := range NewPublishOptions(opts...).Headers {
+		msg.Headers[k] = v
+	}
+	if o.NegotiateContent && o.Codec != nil {
+		msg.Headers[ContentTypeHeader] = ContentType(o.Codec)
+	}
+	for _, interceptor := range o.PublishInterceptors {
+		if err := interceptor(ctx, topic, msg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	body, ok := msg.Body.([]byte)
+	if !ok {
+		return nil, nil, fmt.Errorf("publish interceptor set a %T body, want []byte", msg.Body)
+	}
+	if err := o.CheckMessageSize(topic, body); err != nil {
+		return nil, nil, err
+	}
+
+	if len(msg.Headers) > 0 {
+		opts = append(opts[:len(opts):len(opts)], func(po *PublishOptions) {
+			if po.Headers == nil {
+				po.Headers = Headers{}
+			}
+			for k, v := range msg.Headers {
+				po.Headers[k] = v
+			}
+		})
+	}
+
+	return body, opts, nil
+}
+
+// MaxPayloadSize reject the messages whose encoded body exceeds n bytes.
+func MaxPayloadSize(n int) PublishInterceptor {
+	return func(_ context.Context, topic string, msg *Message) error {
+		if body, _ := msg.Body.([]byte); len(body) > n {
+			return fmt.Errorf("%w: %d bytes published to %s, limit is %d", ErrPayloadTooLarge, len(body), topic, n)
+		}
+		return nil
+	}
+}
+
+// SetHeader stamp the header key with value on every message, for app ids or
+// schema versions.
+func SetHeader(key, value string) PublishInterceptor {
+	return func(_ context.Context, _ string, msg *Message) error {
+		msg.Headers[key] = value
+		return nil
+	}
+}
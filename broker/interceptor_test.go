@@ -0,0 +1,52 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterceptPublish(t *testing.T) {
+	none := NewOptions()
+	body, opts, err := none.InterceptPublish(context.Background(), "orders", []byte("msg"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("msg"), body)
+	assert.Empty(t, opts)
+
+	options := NewOptionsAndApply(WithPublishInterceptors(
+		SetHeader("app-id", "billing"),
+		func(_ context.Context, topic string, msg *Message) error {
+			msg.Headers["topic"] = topic
+			msg.Body = append(msg.Body.([]byte), '!')
+			return nil
+		},
+		MaxPayloadSize(4),
+	))
+
+	body, opts, err = options.InterceptPublish(context.Background(), "orders", []byte("msg"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("msg!"), body)
+	po := NewPublishOptions(opts...)
+	assert.Equal(t, Headers{"app-id": "billing", "topic": "orders"}, po.Headers)
+
+	_, _, err = options.InterceptPublish(context.Background(), "orders", []byte("large"), nil)
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+}
+
+func TestInterceptPublish_Errors(t *testing.T) {
+	rejected := errors.New("schema invalid")
+	options := NewOptionsAndApply(WithPublishInterceptors(func(context.Context, string, *Message) error {
+		return rejected
+	}))
+	_, _, err := options.InterceptPublish(context.Background(), "orders", []byte("msg"), nil)
+	assert.ErrorIs(t, err, rejected)
+
+	options = NewOptionsAndApply(WithPublishInterceptors(func(_ context.Context, _ string, msg *Message) error {
+		msg.Body = "msg"
+		return nil
+	}))
+	_, _, err = options.InterceptPublish(context.Background(), "orders", []byte("msg"), nil)
+	assert.EqualError(t, err, "publish interceptor set a string body, want []byte")
+}
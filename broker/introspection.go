@@ -0,0 +1,175 @@
+package broker
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const maxStateHistory = 32
+
+// StateChange is an entry of the state history of a connection.
+type StateChange struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// ConnectionStats is a snapshot of the connection of a broker, for debugging
+// flaky connectivity.
+type ConnectionStats struct {
+	Broker  string `json:"broker"`
+	Address string `json:"address"`
+	State   string `json:"state"`
+
+	// History is the last state changes, oldest first.
+	History []StateChange `json:"history,omitempty"`
+	// Reconnects counts the connections established after the first one.
+	Reconnects  int64     `json:"reconnects"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at"`
+
+	// Channels is the number of channels or sessions open on the connection.
+	Channels int `json:"channels"`
+	// Subscribers is the number of active subscriptions, Goroutines the
+	// number of goroutines consuming for them.
+	Subscribers int `json:"subscribers"`
+	Goroutines  int `json:"goroutines"`
+}
+
+// ConnectionIntrospector is implemented by the brokers and the clients
+// reporting the state of their connection.
+type ConnectionIntrospector interface {
+	ConnectionStats() ConnectionStats
+}
+
+// ConnectionTracker records the state changes and the errors of a
+// connection, for the drivers implementing ConnectionIntrospector. The zero
+// value is disconnected.
+type ConnectionTracker struct {
+	mu sync.Mutex
+
+	state     ClientState
+	history   []StateChange
+	connects  int64
+	lastErr   error
+	lastErrAt time.Time
+}
+
+func (t *ConnectionTracker) SetState(state ClientState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.state == state {
+		return
+	}
+	if state == ClientConnected {
+		t.connects++
+	}
+
+	t.history = append(t.history, StateChange{From: t.state.String(), To: state.String(), At: time.Now()})
+	if len(t.history) > maxStateHistory {
+		t.history = t.history[len(t.history)-maxStateHistory:]
+	}
+	t.state = state
+}
+
+func (t *ConnectionTracker) RecordError(err error) {
+	if err == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lastErr = err
+	t.lastErrAt = time.Now()
+}
+
+func (t *ConnectionTracker) State() ClientState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.state
+}
+
+// Stats return the state, history, reconnects and last error of the
+// connection.
+func (t *ConnectionTracker) Stats() ConnectionStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := ConnectionStats{
+		State:       t.state.String(),
+		History:     append([]StateChange(nil), t.history...),
+		LastErrorAt: t.lastErrAt,
+	}
+	if t.connects > 1 {
+		s.Reconnects = t.connects - 1
+	}
+	if t.lastErr != nil {
+		s.LastError = t.lastErr.Error()
+	}
+	return s
+}
+
+// Introspection collects the connection stats of named brokers or clients.
+// It is an http.Handler serving them as JSON, mount it on a debug endpoint,
+// and an expvar.Var, expvar.Publish("brokers", introspection) exposes them
+// on /debug/vars.
+type Introspection struct {
+	mu      sync.RWMutex
+	entries map[string]ConnectionIntrospector
+}
+
+func NewIntrospection() *Introspection {
+	return &Introspection{entries: map[string]ConnectionIntrospector{}}
+}
+
+// Register add ci under name, replacing the one registered before.
+func (i *Introspection) Register(name string, ci ConnectionIntrospector) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.entries[name] = ci
+}
+
+func (i *Introspection) Unregister(name string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.entries, name)
+}
+
+// Snapshot return the current stats of every registered connection.
+func (i *Introspection) Snapshot() map[string]ConnectionStats {
+	i.mu.RLock()
+	names := make([]string, 0, len(i.entries))
+	entries := make([]ConnectionIntrospector, 0, len(i.entries))
+	for name, ci := range i.entries {
+		names = append(names, name)
+		entries = append(entries, ci)
+	}
+	i.mu.RUnlock()
+
+	// collect outside the lock, the drivers take their own.
+	stats := make(map[string]ConnectionStats, len(names))
+	for n, ci := range entries {
+		stats[names[n]] = ci.ConnectionStats()
+	}
+	return stats
+}
+
+func (i *Introspection) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(i.Snapshot())
+}
+
+// String return the stats as JSON for expvar.
+func (i *Introspection) String() string {
+	buf, _ := json.Marshal(i.Snapshot())
+	return string(buf)
+}
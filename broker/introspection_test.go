@@ -0,0 +1,75 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectionTracker(t *testing.T) {
+	var tracker ConnectionTracker
+	assert.Equal(t, ClientDisconnected, tracker.State())
+
+	tracker.SetState(ClientConnecting)
+	tracker.SetState(ClientConnected)
+	tracker.RecordError(errors.New("connection reset"))
+	tracker.SetState(ClientDisconnected)
+	tracker.SetState(ClientConnecting)
+	tracker.SetState(ClientConnected)
+	tracker.SetState(ClientConnected)
+
+	s := tracker.Stats()
+	assert.Equal(t, "connected", s.State)
+	assert.Equal(t, int64(1), s.Reconnects)
+	assert.Equal(t, "connection reset", s.LastError)
+	assert.False(t, s.LastErrorAt.IsZero())
+	assert.Len(t, s.History, 5)
+	assert.Equal(t, StateChange{From: "disconnected", To: "connecting", At: s.History[0].At}, s.History[0])
+
+	for i := 0; i < maxStateHistory; i++ {
+		tracker.SetState(ClientDisconnected)
+		tracker.SetState(ClientConnected)
+	}
+	assert.Len(t, tracker.Stats().History, maxStateHistory)
+}
+
+func TestIntrospection(t *testing.T) {
+	fb := &flakyBroker{recordBroker: *newRecordBroker("flaky"), connectFails: 1}
+	c := NewClient(fb, WithClientBackoff(time.Millisecond, time.Millisecond))
+
+	introspection := NewIntrospection()
+	introspection.Register("orders", c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.Nil(t, c.Start(ctx))
+	assert.Nil(t, c.Publish(ctx, "orders", "msg"))
+
+	s := introspection.Snapshot()["orders"]
+	assert.Equal(t, "flaky", s.Broker)
+	assert.Equal(t, "connected", s.State)
+	assert.Equal(t, "connection refused", s.LastError)
+	assert.Equal(t, int64(0), s.Reconnects)
+
+	rec := httptest.NewRecorder()
+	introspection.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/brokers", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var served map[string]ConnectionStats
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	assert.Equal(t, "connected", served["orders"].State)
+
+	var vars map[string]ConnectionStats
+	assert.Nil(t, json.Unmarshal([]byte(introspection.String()), &vars))
+	assert.Equal(t, "connected", vars["orders"].State)
+
+	assert.Nil(t, c.Stop(ctx))
+	introspection.Unregister("orders")
+	assert.Empty(t, introspection.Snapshot())
+}
@@ -0,0 +1,53 @@
+package invalidation
+
+// Cache is a local cache the invalidations are applied to.
+type Cache interface {
+	Delete(key string)
+	Clear()
+}
+
+// CacheFuncs adapt a pair of functions to Cache.
+type CacheFuncs struct {
+	DeleteFunc func(key string)
+	ClearFunc  func()
+}
+
+func (c CacheFuncs) Delete(key string) {
+	if c.DeleteFunc != nil {
+		c.DeleteFunc(key)
+	}
+}
+
+func (c CacheFuncs) Clear() {
+	if c.ClearFunc != nil {
+		c.ClearFunc()
+	}
+}
+
+// RistrettoCache is the part of a *ristretto.Cache the invalidations use.
+type RistrettoCache interface {
+	Del(key interface{})
+	Clear()
+}
+
+// Ristretto adapt a ristretto cache, keyed by string.
+func Ristretto(c RistrettoCache) Cache {
+	return CacheFuncs{
+		DeleteFunc: func(key string) { c.Del(key) },
+		ClearFunc:  c.Clear,
+	}
+}
+
+// BigCache is the part of a *bigcache.BigCache the invalidations use.
+type BigCache interface {
+	Delete(key string) error
+	Reset() error
+}
+
+// Bigcache adapt a bigcache, the missing keys are ignored.
+func Bigcache(c BigCache) Cache {
+	return CacheFuncs{
+		DeleteFunc: func(key string) { _ = c.Delete(key) },
+		ClearFunc:  func() { _ = c.Reset() },
+	}
+}
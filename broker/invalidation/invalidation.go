@@ -0,0 +1,163 @@
+package invalidation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/google/uuid"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+const DefaultTopic = "cache.invalidation"
+
+var (
+	ErrAlreadyStarted = errors.New("invalidation: already started")
+	ErrUnknownCache   = errors.New("invalidation: unknown cache")
+)
+
+// Invalidation is the message invalidating keys of a cache, or all of them.
+type Invalidation struct {
+	Cache  string   `json:"cache"`
+	Keys   []string `json:"keys,omitempty"`
+	All    bool     `json:"all,omitempty"`
+	Origin string   `json:"origin"`
+}
+
+// Invalidator keeps the local caches of the instances of a service
+// consistent, by broadcasting their invalidations to every instance, e.g.
+// over the pub/sub of Redis:
+//
+//	inv := invalidation.New(b)
+//	inv.Register("users", invalidation.Ristretto(cache))
+//	_ = inv.Start(ctx)
+//	...
+//	err := inv.Invalidate(ctx, "users", user.ID)
+//
+// The subscription is made without queue, so that every instance receives
+// every invalidation.
+type Invalidator struct {
+	b     broker.Broker
+	topic string
+	id    string
+
+	subscribeOpts []broker.SubscribeOption
+
+	mu     sync.RWMutex
+	caches map[string]Cache
+	sub    broker.Subscriber
+}
+
+func New(b broker.Broker, opts ...Option) *Invalidator {
+	i := &Invalidator{
+		b:      b,
+		topic:  DefaultTopic,
+		id:     uuid.NewString(),
+		caches: make(map[string]Cache),
+	}
+
+	for _, o := range opts {
+		o(i)
+	}
+
+	return i
+}
+
+// Register add the local cache named name.
+func (i *Invalidator) Register(name string, c Cache) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.caches[name] = c
+}
+
+// Invalidate delete keys from the cache named name of every instance.
+func (i *Invalidator) Invalidate(ctx context.Context, name string, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return i.publish(ctx, &Invalidation{Cache: name, Keys: keys, Origin: i.id})
+}
+
+// InvalidateAll clear the cache named name of every instance.
+func (i *Invalidator) InvalidateAll(ctx context.Context, name string) error {
+	return i.publish(ctx, &Invalidation{Cache: name, All: true, Origin: i.id})
+}
+
+// Start subscribe to the invalidations of the other instances.
+func (i *Invalidator) Start(_ context.Context) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.sub != nil {
+		return ErrAlreadyStarted
+	}
+
+	sub, err := i.b.Subscribe(i.topic, i.handle, func() broker.Any {
+		return &Invalidation{}
+	}, i.subscribeOpts...)
+	if err != nil {
+		return err
+	}
+	i.sub = sub
+
+	return nil
+}
+
+// Stop unsubscribe from the invalidations.
+func (i *Invalidator) Stop(_ context.Context) error {
+	i.mu.Lock()
+	sub := i.sub
+	i.sub = nil
+	i.mu.Unlock()
+
+	if sub == nil {
+		return nil
+	}
+	return sub.Unsubscribe(true)
+}
+
+// publish apply inv locally, then broadcast it. The instances without the
+// cache, e.g. the writers, still broadcast its invalidations.
+func (i *Invalidator) publish(ctx context.Context, inv *Invalidation) error {
+	_ = i.apply(inv)
+	return i.b.Publish(ctx, i.topic, inv)
+}
+
+func (i *Invalidator) handle(_ context.Context, event broker.Event) error {
+	inv, ok := event.Message().Body.(*Invalidation)
+	if !ok {
+		log.Warnf("[invalidation] ignore message of type %T", event.Message().Body)
+		return nil
+	}
+	if inv.Origin == i.id {
+		return nil
+	}
+
+	if err := i.apply(inv); err != nil {
+		log.Debugf("[invalidation] %v", err)
+	}
+	return nil
+}
+
+func (i *Invalidator) apply(inv *Invalidation) error {
+	i.mu.RLock()
+	c, ok := i.caches[inv.Cache]
+	i.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w [%s]", ErrUnknownCache, inv.Cache)
+	}
+
+	if inv.All {
+		c.Clear()
+		return nil
+	}
+	for _, key := range inv.Keys {
+		c.Delete(key)
+	}
+	return nil
+}
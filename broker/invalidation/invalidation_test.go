@@ -0,0 +1,96 @@
+package invalidation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+type mapCache map[string]string
+
+func (c mapCache) Delete(key string) { delete(c, key) }
+
+func (c mapCache) Clear() {
+	for k := range c {
+		delete(c, k)
+	}
+}
+
+func TestInvalidator(t *testing.T) {
+	ctx := context.Background()
+	b := mocks.NewFakeBroker(broker.WithCodec("json"))
+	assert.Nil(t, b.Connect())
+
+	local := mapCache{"1": "alice", "2": "bob"}
+	remote := mapCache{"1": "alice", "2": "bob"}
+
+	a := New(b, WithInstanceID("a"))
+	a.Register("users", local)
+	other := New(b, WithInstanceID("b"))
+	other.Register("users", remote)
+
+	assert.Nil(t, a.Start(ctx))
+	assert.ErrorIs(t, a.Start(ctx), ErrAlreadyStarted)
+	assert.Nil(t, other.Start(ctx))
+
+	assert.Nil(t, a.Invalidate(ctx, "users", "1"))
+	assert.Equal(t, mapCache{"2": "bob"}, local)
+	assert.Equal(t, mapCache{"2": "bob"}, remote)
+	if assert.Len(t, b.Published(), 1) {
+		assert.Equal(t, DefaultTopic, b.Published()[0].Topic)
+	}
+
+	// received back by its origin
+	remote["1"] = "alice"
+	_, err := b.Deliver(ctx, DefaultTopic, &Invalidation{Cache: "users", Keys: []string{"1"}, Origin: "b"}, nil)
+	assert.Nil(t, err)
+	assert.Len(t, remote, 2, "skipped by its origin")
+	assert.Len(t, local, 1)
+
+	assert.Nil(t, other.InvalidateAll(ctx, "users"))
+	assert.Empty(t, local)
+	assert.Empty(t, remote)
+
+	assert.Nil(t, a.Stop(ctx))
+	assert.Nil(t, a.Stop(ctx))
+	assert.Nil(t, other.Stop(ctx))
+}
+
+func TestInvalidator_UnknownCache(t *testing.T) {
+	ctx := context.Background()
+	b := mocks.NewFakeBroker()
+	assert.Nil(t, b.Connect())
+
+	writer := New(b)
+	assert.Nil(t, writer.Invalidate(ctx, "users", "1"))
+	assert.Len(t, b.Published(), 1, "published without local cache")
+
+	assert.ErrorIs(t, writer.apply(&Invalidation{Cache: "users"}), ErrUnknownCache)
+}
+
+type fakeRistretto struct{ deleted []interface{} }
+
+func (c *fakeRistretto) Del(key interface{}) { c.deleted = append(c.deleted, key) }
+func (c *fakeRistretto) Clear()              { c.deleted = nil }
+
+type fakeBigCache struct{ deleted []string }
+
+func (c *fakeBigCache) Delete(key string) error {
+	c.deleted = append(c.deleted, key)
+	return nil
+}
+func (c *fakeBigCache) Reset() error { return nil }
+
+func TestAdapters(t *testing.T) {
+	r := &fakeRistretto{}
+	Ristretto(r).Delete("1")
+	assert.Equal(t, []interface{}{"1"}, r.deleted)
+
+	bc := &fakeBigCache{}
+	Bigcache(bc).Delete("1")
+	assert.Equal(t, []string{"1"}, bc.deleted)
+}
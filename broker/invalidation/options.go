@@ -0,0 +1,31 @@
+package invalidation
+
+import (
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(i *Invalidator)
+
+// WithTopic set the topic of the invalidations, default is "cache.invalidation".
+func WithTopic(topic string) Option {
+	return func(i *Invalidator) {
+		i.topic = topic
+	}
+}
+
+// WithInstanceID set the id of the instance, default is a random one. The
+// invalidations published by the instance are applied when published, and
+// skipped when received back.
+func WithInstanceID(id string) Option {
+	return func(i *Invalidator) {
+		i.id = id
+	}
+}
+
+// WithSubscribeOptions add opts to the subscription of the topic, e.g. a
+// consumer group unique to the instance on the brokers without broadcast.
+func WithSubscribeOptions(opts ...broker.SubscribeOption) Option {
+	return func(i *Invalidator) {
+		i.subscribeOpts = append(i.subscribeOpts, opts...)
+	}
+}
@@ -0,0 +1,109 @@
+package kafka
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	kafkaGo "github.com/segmentio/kafka-go"
+)
+
+// StickyGroupBalancer assigns the partitions by the InstanceID of the
+// consumers, stable across restarts unlike their member id: an instance
+// restarted during a rolling deploy gets its partitions back, and one
+// joining or leaving moves about its share of partitions only, keeping
+// their local state and caches warm.
+//
+// kafka-go rebalances eagerly, every consumer stops during the rebalance,
+// and sends no group.instance.id, the broker side static membership and
+// cooperative rebalancing are not available.
+type StickyGroupBalancer struct {
+	// InstanceID identifies the consumer across restarts, e.g. the pod name
+	// of a StatefulSet. The member id is used when it is empty.
+	InstanceID string
+}
+
+func (b StickyGroupBalancer) ProtocolName() string {
+	return "kratos-sticky"
+}
+
+func (b StickyGroupBalancer) UserData() ([]byte, error) {
+	return []byte(b.InstanceID), nil
+}
+
+// AssignGroups give each partition to the consumer of the topic weighing the
+// most for it, rendezvous hashing, among those not yet holding their share.
+func (b StickyGroupBalancer) AssignGroups(members []kafkaGo.GroupMember, partitions []kafkaGo.Partition) kafkaGo.GroupMemberAssignments {
+	assignments := kafkaGo.GroupMemberAssignments{}
+	for _, m := range members {
+		assignments[m.ID] = map[string][]int{}
+	}
+
+	byTopic := map[string][]int{}
+	for _, p := range partitions {
+		byTopic[p.Topic] = append(byTopic[p.Topic], p.ID)
+	}
+
+	for topic, ids := range byTopic {
+		var consumers []kafkaGo.GroupMember
+		for _, m := range members {
+			for _, t := range m.Topics {
+				if t == topic {
+					consumers = append(consumers, m)
+					break
+				}
+			}
+		}
+		if len(consumers) == 0 {
+			continue
+		}
+		sort.Slice(consumers, func(i, j int) bool { return consumers[i].ID < consumers[j].ID })
+		sort.Ints(ids)
+
+		// every consumer takes share partitions, extra of them one more.
+		share, extra := len(ids)/len(consumers), len(ids)%len(consumers)
+		counts := make([]int, len(consumers))
+		for _, id := range ids {
+			owner := -1
+			var best uint64
+			for i, m := range consumers {
+				if counts[i] > share || (counts[i] == share && extra == 0) {
+					continue
+				}
+				if w := stickyWeight(instanceID(m), topic, id); owner < 0 || w > best {
+					owner, best = i, w
+				}
+			}
+			if counts[owner]++; counts[owner] > share {
+				extra--
+			}
+			m := consumers[owner].ID
+			assignments[m][topic] = append(assignments[m][topic], id)
+		}
+	}
+
+	return assignments
+}
+
+func instanceID(m kafkaGo.GroupMember) string {
+	if len(m.UserData) > 0 {
+		return string(m.UserData)
+	}
+	return m.ID
+}
+
+func stickyWeight(instance, topic string, partition int) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(instance))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(topic))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(strconv.Itoa(partition)))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
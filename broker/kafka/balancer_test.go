@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"testing"
+
+	kafkaGo "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func stickyMembers(ids ...string) []kafkaGo.GroupMember {
+	var members []kafkaGo.GroupMember
+	for _, id := range ids {
+		members = append(members, kafkaGo.GroupMember{
+			ID:       "member-" + id,
+			Topics:   []string{"orders"},
+			UserData: []byte(id),
+		})
+	}
+	return members
+}
+
+func stickyPartitions(n int) []kafkaGo.Partition {
+	var partitions []kafkaGo.Partition
+	for i := 0; i < n; i++ {
+		partitions = append(partitions, kafkaGo.Partition{Topic: "orders", ID: i})
+	}
+	return partitions
+}
+
+func TestStickyGroupBalancer(t *testing.T) {
+	b := StickyGroupBalancer{InstanceID: "a"}
+	assert.Equal(t, "kratos-sticky", b.ProtocolName())
+	userData, err := b.UserData()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a"), userData)
+
+	members := stickyMembers("a", "b", "c")
+	assignments := b.AssignGroups(members, stickyPartitions(10))
+
+	total := 0
+	for _, m := range members {
+		n := len(assignments[m.ID]["orders"])
+		assert.GreaterOrEqual(t, n, 3)
+		assert.LessOrEqual(t, n, 4)
+		total += n
+	}
+	assert.Equal(t, 10, total)
+
+	// restarted consumers join with new member ids and get their partitions back.
+	restarted := stickyMembers("c", "a", "b")
+	for i := range restarted {
+		restarted[i].ID += "-restarted"
+	}
+	again := b.AssignGroups(restarted, stickyPartitions(10))
+	for _, m := range members {
+		assert.Equal(t, assignments[m.ID]["orders"], again[m.ID+"-restarted"]["orders"])
+	}
+}
+
+func TestStickyGroupBalancer_Topics(t *testing.T) {
+	members := []kafkaGo.GroupMember{
+		{ID: "m1", Topics: []string{"orders"}},
+		{ID: "m2", Topics: []string{"orders", "payments"}},
+	}
+	partitions := append(stickyPartitions(2), kafkaGo.Partition{Topic: "payments", ID: 0})
+
+	assignments := StickyGroupBalancer{}.AssignGroups(members, partitions)
+	assert.Len(t, assignments["m1"]["orders"], 1)
+	assert.Len(t, assignments["m2"]["orders"], 1)
+	assert.Empty(t, assignments["m1"]["payments"])
+	assert.Equal(t, []int{0}, assignments["m2"]["payments"])
+}
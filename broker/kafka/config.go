@@ -0,0 +1,355 @@
+package kafka
+
+import (
+	"hash"
+	"time"
+
+	kafkaGo "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed kafka configuration of a broker, the fields are the
+// ones set one by one with the broker options of the package. The nil
+// pointers keep the default of the broker.
+type Config struct {
+	ReaderConfig  *kafkaGo.ReaderConfig
+	WriterConfig  *WriterConfig
+	Dialer        *kafkaGo.Dialer
+	DialerTimeout time.Duration
+	Mechanism     sasl.Mechanism
+	RetriesCount  int
+
+	// reader
+	QueueCapacity          int
+	MinBytes               int
+	MaxBytes               int
+	MaxWait                time.Duration
+	ReadLagInterval        time.Duration
+	HeartbeatInterval      time.Duration
+	CommitInterval         time.Duration
+	PartitionWatchInterval time.Duration
+	WatchPartitionChanges  *bool
+	SessionTimeout         time.Duration
+	RebalanceTimeout       time.Duration
+	GroupBalancers         []kafkaGo.GroupBalancer
+	RetentionTime          time.Duration
+	StartOffset            int64
+	IsolationLevel         kafkaGo.IsolationLevel
+
+	// reader and writer
+	MaxAttempts       int
+	Logger            kafkaGo.Logger
+	ErrorLogger       kafkaGo.Logger
+	EnableLogger      *bool
+	EnableErrorLogger *bool
+
+	// writer
+	EnableOneTopicOneWriter *bool
+	BatchSize               int
+	BatchTimeout            time.Duration
+	BatchBytes              int64
+	Async                   *bool
+	ReadTimeout             time.Duration
+	WriteTimeout            time.Duration
+	AllowAutoTopicCreation  bool
+	Completion              func(messages []kafkaGo.Message, err error)
+}
+
+// WithConfig set the whole configuration at once, the zero fields are left
+// unset. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		var opts []broker.Option
+		if c.ReaderConfig != nil {
+			opts = append(opts, WithReaderConfig(*c.ReaderConfig))
+		}
+		if c.WriterConfig != nil {
+			opts = append(opts, WithWriterConfig(*c.WriterConfig))
+		}
+		if c.Dialer != nil {
+			opts = append(opts, WithDialer(c.Dialer))
+		}
+		if c.DialerTimeout != 0 {
+			opts = append(opts, WithDialerTimeout(c.DialerTimeout))
+		}
+		if c.Mechanism != nil {
+			opts = append(opts, broker.OptionContextWithValue(mechanismKey{}, c.Mechanism))
+		}
+		if c.RetriesCount != 0 {
+			opts = append(opts, WithRetries(c.RetriesCount))
+		}
+		if c.QueueCapacity != 0 {
+			opts = append(opts, WithQueueCapacity(c.QueueCapacity))
+		}
+		if c.MinBytes != 0 {
+			opts = append(opts, WithMinBytes(c.MinBytes))
+		}
+		if c.MaxBytes != 0 {
+			opts = append(opts, WithMaxBytes(c.MaxBytes))
+		}
+		if c.MaxWait != 0 {
+			opts = append(opts, WithMaxWait(c.MaxWait))
+		}
+		if c.ReadLagInterval != 0 {
+			opts = append(opts, WithReadLagInterval(c.ReadLagInterval))
+		}
+		if c.HeartbeatInterval != 0 {
+			opts = append(opts, WithHeartbeatInterval(c.HeartbeatInterval))
+		}
+		if c.CommitInterval != 0 {
+			opts = append(opts, WithCommitInterval(c.CommitInterval))
+		}
+		if c.PartitionWatchInterval != 0 {
+			opts = append(opts, WithPartitionWatchInterval(c.PartitionWatchInterval))
+		}
+		if c.WatchPartitionChanges != nil {
+			opts = append(opts, WithWatchPartitionChanges(*c.WatchPartitionChanges))
+		}
+		if c.SessionTimeout != 0 {
+			opts = append(opts, WithSessionTimeout(c.SessionTimeout))
+		}
+		if c.RebalanceTimeout != 0 {
+			opts = append(opts, WithRebalanceTimeout(c.RebalanceTimeout))
+		}
+		if c.GroupBalancers != nil {
+			opts = append(opts, WithGroupBalancers(c.GroupBalancers...))
+		}
+		if c.RetentionTime != 0 {
+			opts = append(opts, WithRetentionTime(c.RetentionTime))
+		}
+		if c.StartOffset != 0 {
+			opts = append(opts, WithStartOffset(c.StartOffset))
+		}
+		if c.IsolationLevel != 0 {
+			opts = append(opts, WithIsolationLevel(c.IsolationLevel))
+		}
+		if c.MaxAttempts != 0 {
+			opts = append(opts, WithMaxAttempts(c.MaxAttempts))
+		}
+		if c.Logger != nil {
+			opts = append(opts, WithLogger(c.Logger))
+		}
+		if c.ErrorLogger != nil {
+			opts = append(opts, WithErrorLogger(c.ErrorLogger))
+		}
+		if c.EnableLogger != nil {
+			opts = append(opts, WithEnableLogger(*c.EnableLogger))
+		}
+		if c.EnableErrorLogger != nil {
+			opts = append(opts, WithEnableErrorLogger(*c.EnableErrorLogger))
+		}
+		if c.EnableOneTopicOneWriter != nil {
+			opts = append(opts, WithEnableOneTopicOneWriter(*c.EnableOneTopicOneWriter))
+		}
+		if c.BatchSize != 0 {
+			opts = append(opts, WithBatchSize(c.BatchSize))
+		}
+		if c.BatchTimeout != 0 {
+			opts = append(opts, WithBatchTimeout(c.BatchTimeout))
+		}
+		if c.BatchBytes != 0 {
+			opts = append(opts, WithBatchBytes(c.BatchBytes))
+		}
+		if c.Async != nil {
+			opts = append(opts, WithAsync(*c.Async))
+		}
+		if c.ReadTimeout != 0 {
+			opts = append(opts, WithReadTimeout(c.ReadTimeout))
+		}
+		if c.WriteTimeout != 0 {
+			opts = append(opts, WithWriteTimeout(c.WriteTimeout))
+		}
+		if c.AllowAutoTopicCreation {
+			opts = append(opts, WithAllowPublishAutoTopicCreation(true))
+		}
+		if c.Completion != nil {
+			opts = append(opts, WithCompletion(c.Completion))
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	var c Config
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(readerConfigKey{}).(kafkaGo.ReaderConfig); ok {
+		c.ReaderConfig = &v
+	}
+	if v, ok := opts.Context.Value(writerConfigKey{}).(WriterConfig); ok {
+		c.WriterConfig = &v
+	}
+	c.Dialer, _ = opts.Context.Value(dialerConfigKey{}).(*kafkaGo.Dialer)
+	c.DialerTimeout, _ = opts.Context.Value(dialerTimeoutKey{}).(time.Duration)
+	c.Mechanism, _ = opts.Context.Value(mechanismKey{}).(sasl.Mechanism)
+	c.RetriesCount, _ = opts.Context.Value(retriesCountKey{}).(int)
+
+	c.QueueCapacity, _ = opts.Context.Value(queueCapacityKey{}).(int)
+	c.MinBytes, _ = opts.Context.Value(minBytesKey{}).(int)
+	c.MaxBytes, _ = opts.Context.Value(maxBytesKey{}).(int)
+	c.MaxWait, _ = opts.Context.Value(maxWaitKey{}).(time.Duration)
+	c.ReadLagInterval, _ = opts.Context.Value(readLagIntervalKey{}).(time.Duration)
+	c.HeartbeatInterval, _ = opts.Context.Value(heartbeatIntervalKey{}).(time.Duration)
+	c.CommitInterval, _ = opts.Context.Value(commitIntervalKey{}).(time.Duration)
+	c.PartitionWatchInterval, _ = opts.Context.Value(partitionWatchIntervalKey{}).(time.Duration)
+	c.WatchPartitionChanges = boolFromContext(opts, watchPartitionChangesKey{})
+	c.SessionTimeout, _ = opts.Context.Value(sessionTimeoutKey{}).(time.Duration)
+	c.RebalanceTimeout, _ = opts.Context.Value(rebalanceTimeoutKey{}).(time.Duration)
+	c.GroupBalancers, _ = opts.Context.Value(groupBalancersKey{}).([]kafkaGo.GroupBalancer)
+	c.RetentionTime, _ = opts.Context.Value(retentionTimeKey{}).(time.Duration)
+	c.StartOffset, _ = opts.Context.Value(startOffsetKey{}).(int64)
+	c.IsolationLevel, _ = opts.Context.Value(isolationLevelKey{}).(kafkaGo.IsolationLevel)
+
+	c.MaxAttempts, _ = opts.Context.Value(maxAttemptsKey{}).(int)
+	c.Logger, _ = opts.Context.Value(loggerKey{}).(kafkaGo.Logger)
+	c.ErrorLogger, _ = opts.Context.Value(errorLoggerKey{}).(kafkaGo.Logger)
+	c.EnableLogger = boolFromContext(opts, enableLoggerKey{})
+	c.EnableErrorLogger = boolFromContext(opts, enableErrorLoggerKey{})
+
+	c.EnableOneTopicOneWriter = boolFromContext(opts, enableOneTopicOneWriterKey{})
+	c.BatchSize, _ = opts.Context.Value(batchSizeKey{}).(int)
+	c.BatchTimeout, _ = opts.Context.Value(batchTimeoutKey{}).(time.Duration)
+	c.BatchBytes, _ = opts.Context.Value(batchBytesKey{}).(int64)
+	c.Async = boolFromContext(opts, asyncKey{})
+	c.ReadTimeout, _ = opts.Context.Value(readTimeoutKey{}).(time.Duration)
+	c.WriteTimeout, _ = opts.Context.Value(writeTimeoutKey{}).(time.Duration)
+	c.AllowAutoTopicCreation, _ = opts.Context.Value(allowPublishAutoTopicCreationKey{}).(bool)
+	if v, ok := opts.Context.Value(completionKey{}).(*func(messages []kafkaGo.Message, err error)); ok && v != nil {
+		c.Completion = *v
+	}
+
+	return c
+}
+
+func boolFromContext(opts broker.Options, key interface{}) *bool {
+	if v, ok := opts.Context.Value(key).(bool); ok {
+		return &v
+	}
+	return nil
+}
+
+// AutoCreateTopicConfig is the topic created before subscribing.
+type AutoCreateTopicConfig struct {
+	Topic             string
+	NumPartitions     int
+	ReplicationFactor int
+}
+
+// SubscribeConfig is the typed kafka configuration of a subscription, the
+// nil fields are left unset.
+type SubscribeConfig struct {
+	AutoCreateTopic *AutoCreateTopicConfig
+	// IsolationLevel keeps the isolation level of the broker when nil.
+	IsolationLevel *kafkaGo.IsolationLevel
+}
+
+// WithSubscribeConfig set the whole subscription configuration at once.
+func WithSubscribeConfig(c SubscribeConfig) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		var opts []broker.SubscribeOption
+		if c.AutoCreateTopic != nil {
+			opts = append(opts, WithSubscribeAutoCreateTopic(c.AutoCreateTopic.Topic, c.AutoCreateTopic.NumPartitions, c.AutoCreateTopic.ReplicationFactor))
+		}
+		if c.IsolationLevel != nil {
+			opts = append(opts, WithSubscribeIsolationLevel(*c.IsolationLevel))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// SubscribeConfigFromOptions return the subscription configuration set in
+// the context of opts.
+func SubscribeConfigFromOptions(opts broker.SubscribeOptions) SubscribeConfig {
+	var c SubscribeConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(autoSubscribeCreateTopicKey{}).(*autoSubscribeCreateTopicValue); ok && v != nil {
+		c.AutoCreateTopic = &AutoCreateTopicConfig{
+			Topic:             v.Topic,
+			NumPartitions:     v.NumPartitions,
+			ReplicationFactor: v.ReplicationFactor,
+		}
+	}
+	if v, ok := opts.Context.Value(subscribeIsolationLevelKey{}).(kafkaGo.IsolationLevel); ok {
+		c.IsolationLevel = &v
+	}
+
+	return c
+}
+
+// BalancerConfig is the partition balancer of a publishing, Hasher is used
+// by the hash balancers and Consistent by the CRC32 and Murmur2 ones.
+type BalancerConfig struct {
+	Name       BalancerName
+	Consistent bool
+	Hasher     hash.Hash32
+}
+
+// PublishConfig is the typed kafka configuration of a publishing, the zero
+// fields are left unset.
+type PublishConfig struct {
+	Headers  map[string]interface{}
+	Key      []byte
+	Offset   int64
+	Balancer *BalancerConfig
+}
+
+// WithPublishConfig set the whole publishing configuration at once.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		var opts []broker.PublishOption
+		if c.Headers != nil {
+			opts = append(opts, WithHeaders(c.Headers))
+		}
+		if c.Key != nil {
+			opts = append(opts, WithMessageKey(c.Key))
+		}
+		if c.Offset != 0 {
+			opts = append(opts, WithMessageOffset(c.Offset))
+		}
+		if c.Balancer != nil {
+			opts = append(opts, broker.PublishContextWithValue(balancerKey{},
+				&balancerValue{
+					Name:       c.Balancer.Name,
+					Consistent: c.Balancer.Consistent,
+					Hasher:     c.Balancer.Hasher,
+				},
+			))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	var c PublishConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.Headers, _ = opts.Context.Value(messageHeadersKey{}).(map[string]interface{})
+	c.Key, _ = opts.Context.Value(messageKeyKey{}).([]byte)
+	c.Offset, _ = opts.Context.Value(messageOffsetKey{}).(int64)
+	if v, ok := opts.Context.Value(balancerKey{}).(*balancerValue); ok && v != nil {
+		c.Balancer = &BalancerConfig{
+			Name:       v.Name,
+			Consistent: v.Consistent,
+			Hasher:     v.Hasher,
+		}
+	}
+
+	return c
+}
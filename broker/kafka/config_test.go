@@ -0,0 +1,90 @@
+package kafka
+
+import (
+	"hash/fnv"
+	"testing"
+	"time"
+
+	kafkaGo "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, Config{}, ConfigFromOptions(broker.NewOptions()))
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithRetries(3),
+		WithMinBytes(10),
+		WithMaxWait(time.Second),
+		WithWatchPartitionChanges(false),
+		WithStartOffset(kafkaGo.LastOffset),
+		WithIsolationLevel(kafkaGo.ReadCommitted),
+		WithEnableLogger(true),
+		WithBatchSize(50),
+		WithAsync(false),
+		WithAllowPublishAutoTopicCreation(true),
+	))
+
+	disabled, enabled := false, true
+	c := Config{
+		RetriesCount:           3,
+		MinBytes:               10,
+		MaxWait:                time.Second,
+		WatchPartitionChanges:  &disabled,
+		StartOffset:            kafkaGo.LastOffset,
+		IsolationLevel:         kafkaGo.ReadCommitted,
+		EnableLogger:           &enabled,
+		BatchSize:              50,
+		Async:                  &disabled,
+		AllowAutoTopicCreation: true,
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c), WithBatchSize(200)))
+	assert.Equal(t, 200, overridden.BatchSize)
+
+	completion := ConfigFromOptions(broker.NewOptionsAndApply(WithCompletion(func([]kafkaGo.Message, error) {})))
+	assert.NotNil(t, completion.Completion)
+}
+
+func TestSubscribeConfigFromOptions(t *testing.T) {
+	assert.Equal(t, SubscribeConfig{}, SubscribeConfigFromOptions(broker.NewSubscribeOptions()))
+
+	legacy := SubscribeConfigFromOptions(broker.NewSubscribeOptions(
+		WithSubscribeAutoCreateTopic(testTopic, 3, 1),
+		WithReadCommitted(),
+	))
+
+	level := kafkaGo.ReadCommitted
+	c := SubscribeConfig{
+		AutoCreateTopic: &AutoCreateTopicConfig{Topic: testTopic, NumPartitions: 3, ReplicationFactor: 1},
+		IsolationLevel:  &level,
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c))))
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	assert.Equal(t, PublishConfig{}, PublishConfigFromOptions(broker.PublishOptions{}))
+
+	hasher := fnv.New32a()
+	legacy := PublishConfigFromOptions(broker.NewPublishOptions(
+		WithMessageKey([]byte("key")),
+		WithHashBalancer(hasher),
+	))
+
+	c := PublishConfig{
+		Key:      []byte("key"),
+		Balancer: &BalancerConfig{Name: HashBalancer, Hasher: hasher},
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c), WithRoundRobinBalancer()))
+	assert.Equal(t, RoundRobinBalancer, overridden.Balancer.Name)
+}
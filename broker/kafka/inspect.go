@@ -0,0 +1,51 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+
+	kafkaGo "github.com/segmentio/kafka-go"
+)
+
+// QueueDepth return the number of messages retained by the partitions of the
+// topic queue, from their first to their last offset. The messages consumed
+// are counted until the retention deletes them.
+func (b *kafkaBroker) QueueDepth(ctx context.Context, queue string) (int64, error) {
+	if len(b.options.Addrs) == 0 {
+		return 0, errors.New("no available commons")
+	}
+
+	client := b.newClient(b.options.Addrs)
+
+	meta, err := client.Metadata(ctx, &kafkaGo.MetadataRequest{Topics: []string{queue}})
+	if err != nil {
+		return 0, err
+	}
+	if len(meta.Topics) == 0 {
+		return 0, kafkaGo.UnknownTopicOrPartition
+	}
+	if meta.Topics[0].Error != nil {
+		return 0, meta.Topics[0].Error
+	}
+
+	var requests []kafkaGo.OffsetRequest
+	for _, p := range meta.Topics[0].Partitions {
+		requests = append(requests, kafkaGo.FirstOffsetOf(p.ID), kafkaGo.LastOffsetOf(p.ID))
+	}
+
+	resp, err := client.ListOffsets(ctx, &kafkaGo.ListOffsetsRequest{
+		Topics: map[string][]kafkaGo.OffsetRequest{queue: requests},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var depth int64
+	for _, p := range resp.Topics[queue] {
+		if p.Error != nil {
+			return 0, p.Error
+		}
+		depth += p.LastOffset - p.FirstOffset
+	}
+	return depth, nil
+}
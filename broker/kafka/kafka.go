@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/gob"
 	"errors"
+	"fmt"
 	"strconv"
 	"sync"
 	"time"
@@ -26,6 +27,9 @@ import (
 
 const (
 	defaultAddr = "127.0.0.1:9092"
+
+	// defaultMaxMessageSize is the default message.max.bytes of the brokers.
+	defaultMaxMessageSize = 1024 * 1024
 )
 
 type kafkaBroker struct {
@@ -40,15 +44,18 @@ type kafkaBroker struct {
 	connected    bool
 	options      broker.Options
 	retriesCount int
+	mode         broker.Mode
 
 	subscribers *broker.SubscriberSyncMap
 
 	producerTracer *tracing.Tracer
 	consumerTracer *tracing.Tracer
+
+	metrics *broker.Metrics
 }
 
 func NewBroker(opts ...broker.Option) broker.Broker {
-	options := broker.NewOptionsAndApply(opts...)
+	options := broker.NewOptionsAndApply(append([]broker.Option{broker.WithMaxMessageSize(defaultMaxMessageSize)}, opts...)...)
 
 	b := &kafkaBroker{
 		readerConfig: kafkaGo.ReaderConfig{
@@ -90,8 +97,10 @@ func (b *kafkaBroker) Options() broker.Options {
 func (b *kafkaBroker) Init(opts ...broker.Option) error {
 	b.options.Apply(opts...)
 
-	if value, ok := b.options.Context.Value(writerConfigKey{}).(WriterConfig); ok {
-		b.writerConfig = value
+	c := ConfigFromOptions(b.options)
+
+	if c.WriterConfig != nil {
+		b.writerConfig = *c.WriterConfig
 	}
 
 	var addrs []string
@@ -108,64 +117,74 @@ func (b *kafkaBroker) Init(opts ...broker.Option) error {
 	b.readerConfig.Brokers = addrs
 	b.writerConfig.Brokers = addrs
 
-	enableOneTopicOneWriter := true
-	if value, ok := b.options.Context.Value(enableOneTopicOneWriterKey{}).(bool); ok {
-		enableOneTopicOneWriter = value
-	}
-	b.writer = NewWriter(enableOneTopicOneWriter)
+	b.mode = broker.ModeFromContext(b.options.Context)
 
-	if value, ok := b.options.Context.Value(completionKey{}).(func(messages []kafkaGo.Message, err error)); ok {
-		b.writer.Writer.Completion = value
+	if b.mode.CanPublish() == nil {
+		enableOneTopicOneWriter := true
+		if c.EnableOneTopicOneWriter != nil {
+			enableOneTopicOneWriter = *c.EnableOneTopicOneWriter
+		}
+		b.writer = NewWriter(enableOneTopicOneWriter)
+
+		if c.Completion != nil {
+			b.writer.Writer.Completion = c.Completion
+		}
 	}
 
 	if b.readerConfig.Dialer == nil {
 		b.readerConfig.Dialer = kafkaGo.DefaultDialer
 	}
 
-	if value, ok := b.options.Context.Value(queueCapacityKey{}).(int); ok {
-		b.readerConfig.QueueCapacity = value
+	if c.QueueCapacity != 0 {
+		b.readerConfig.QueueCapacity = c.QueueCapacity
+	}
+	if c.MinBytes != 0 {
+		b.readerConfig.MinBytes = c.MinBytes
+	}
+	if c.MaxBytes != 0 {
+		b.readerConfig.MaxBytes = c.MaxBytes
 	}
-	if value, ok := b.options.Context.Value(minBytesKey{}).(int); ok {
-		b.readerConfig.MinBytes = value
+	if c.MaxWait != 0 {
+		b.readerConfig.MaxWait = c.MaxWait
 	}
-	if value, ok := b.options.Context.Value(maxBytesKey{}).(int); ok {
-		b.readerConfig.MaxBytes = value
+	if c.ReadLagInterval != 0 {
+		b.readerConfig.ReadLagInterval = c.ReadLagInterval
 	}
-	if value, ok := b.options.Context.Value(maxWaitKey{}).(time.Duration); ok {
-		b.readerConfig.MaxWait = value
+	if c.HeartbeatInterval != 0 {
+		b.readerConfig.HeartbeatInterval = c.HeartbeatInterval
 	}
-	if value, ok := b.options.Context.Value(readLagIntervalKey{}).(time.Duration); ok {
-		b.readerConfig.ReadLagInterval = value
+	if c.CommitInterval != 0 {
+		b.readerConfig.CommitInterval = c.CommitInterval
 	}
-	if value, ok := b.options.Context.Value(heartbeatIntervalKey{}).(time.Duration); ok {
-		b.readerConfig.HeartbeatInterval = value
+	if c.PartitionWatchInterval != 0 {
+		b.readerConfig.PartitionWatchInterval = c.PartitionWatchInterval
 	}
-	if value, ok := b.options.Context.Value(commitIntervalKey{}).(time.Duration); ok {
-		b.readerConfig.CommitInterval = value
+	if c.WatchPartitionChanges != nil {
+		b.readerConfig.WatchPartitionChanges = *c.WatchPartitionChanges
 	}
-	if value, ok := b.options.Context.Value(partitionWatchIntervalKey{}).(time.Duration); ok {
-		b.readerConfig.PartitionWatchInterval = value
+	if c.SessionTimeout != 0 {
+		b.readerConfig.SessionTimeout = c.SessionTimeout
 	}
-	if value, ok := b.options.Context.Value(watchPartitionChangesKey{}).(bool); ok {
-		b.readerConfig.WatchPartitionChanges = value
+	if c.RebalanceTimeout != 0 {
+		b.readerConfig.RebalanceTimeout = c.RebalanceTimeout
 	}
-	if value, ok := b.options.Context.Value(sessionTimeoutKey{}).(time.Duration); ok {
-		b.readerConfig.SessionTimeout = value
+	if c.GroupBalancers != nil {
+		b.readerConfig.GroupBalancers = c.GroupBalancers
 	}
-	if value, ok := b.options.Context.Value(rebalanceTimeoutKey{}).(time.Duration); ok {
-		b.readerConfig.RebalanceTimeout = value
+	if c.RetentionTime != 0 {
+		b.readerConfig.RetentionTime = c.RetentionTime
 	}
-	if value, ok := b.options.Context.Value(retentionTimeKey{}).(time.Duration); ok {
-		b.readerConfig.RetentionTime = value
+	if c.StartOffset != 0 {
+		b.readerConfig.StartOffset = c.StartOffset
 	}
-	if value, ok := b.options.Context.Value(startOffsetKey{}).(int64); ok {
-		b.readerConfig.StartOffset = value
+	if c.MaxAttempts != 0 {
+		b.readerConfig.MaxAttempts = c.MaxAttempts
 	}
-	if value, ok := b.options.Context.Value(maxAttemptsKey{}).(int); ok {
-		b.readerConfig.MaxAttempts = value
+	if c.IsolationLevel != 0 {
+		b.readerConfig.IsolationLevel = c.IsolationLevel
 	}
-	if value, ok := b.options.Context.Value(mechanismKey{}).(sasl.Mechanism); ok {
-		b.saslMechanism = value
+	if c.Mechanism != nil {
+		b.saslMechanism = c.Mechanism
 
 		if b.readerConfig.Dialer == nil {
 			dialer := &kafkaGo.Dialer{
@@ -178,15 +197,15 @@ func (b *kafkaBroker) Init(opts ...broker.Option) error {
 			b.readerConfig.Dialer.SASLMechanism = b.saslMechanism
 		}
 	}
-	if value, ok := b.options.Context.Value(readerConfigKey{}).(kafkaGo.ReaderConfig); ok {
-		b.readerConfig = value
+	if c.ReaderConfig != nil {
+		b.readerConfig = *c.ReaderConfig
 	}
-	if value, ok := b.options.Context.Value(dialerConfigKey{}).(*kafkaGo.Dialer); ok {
-		b.readerConfig.Dialer = value
+	if c.Dialer != nil {
+		b.readerConfig.Dialer = c.Dialer
 	}
-	if value, ok := b.options.Context.Value(dialerTimeoutKey{}).(time.Duration); ok {
+	if c.DialerTimeout != 0 {
 		if b.readerConfig.Dialer != nil {
-			b.readerConfig.Dialer.Timeout = value
+			b.readerConfig.Dialer.Timeout = c.DialerTimeout
 		}
 	}
 
@@ -201,8 +220,8 @@ func (b *kafkaBroker) Init(opts ...broker.Option) error {
 		b.readerConfig.Dialer.TLS = b.options.TLSConfig
 	}
 
-	if cnt, ok := b.options.Context.Value(retriesCountKey{}).(int); ok {
-		b.retriesCount = cnt
+	if c.RetriesCount != 0 {
+		b.retriesCount = c.RetriesCount
 	}
 
 	if len(b.options.Tracings) > 0 {
@@ -210,17 +229,17 @@ func (b *kafkaBroker) Init(opts ...broker.Option) error {
 		b.consumerTracer = tracing.NewTracer(trace.SpanKindConsumer, "kafka-consumer", b.options.Tracings...)
 	}
 
-	if value, ok := b.options.Context.Value(loggerKey{}).(kafkaGo.Logger); ok {
-		b.readerConfig.Logger = value
-		b.writerConfig.Logger = value
+	if c.Logger != nil {
+		b.readerConfig.Logger = c.Logger
+		b.writerConfig.Logger = c.Logger
 	}
-	if value, ok := b.options.Context.Value(errorLoggerKey{}).(kafkaGo.Logger); ok {
-		b.readerConfig.ErrorLogger = value
-		b.writerConfig.ErrorLogger = value
+	if c.ErrorLogger != nil {
+		b.readerConfig.ErrorLogger = c.ErrorLogger
+		b.writerConfig.ErrorLogger = c.ErrorLogger
 	}
 
-	if value, ok := b.options.Context.Value(enableLoggerKey{}).(bool); ok {
-		if value {
+	if c.EnableLogger != nil {
+		if *c.EnableLogger {
 			b.readerConfig.Logger = Logger{}
 			b.writerConfig.Logger = Logger{}
 		} else {
@@ -228,8 +247,8 @@ func (b *kafkaBroker) Init(opts ...broker.Option) error {
 			b.writerConfig.Logger = nil
 		}
 	}
-	if value, ok := b.options.Context.Value(enableErrorLoggerKey{}).(bool); ok {
-		if value {
+	if c.EnableErrorLogger != nil {
+		if *c.EnableErrorLogger {
 			b.readerConfig.ErrorLogger = ErrorLogger{}
 			b.writerConfig.ErrorLogger = ErrorLogger{}
 		} else {
@@ -262,38 +281,40 @@ func (b *kafkaBroker) Init(opts ...broker.Option) error {
 	//	}
 	//}
 
-	if value, ok := b.options.Context.Value(batchSizeKey{}).(int); ok {
-		b.writerConfig.BatchSize = value
+	if c.BatchSize != 0 {
+		b.writerConfig.BatchSize = c.BatchSize
 	}
 
-	if value, ok := b.options.Context.Value(batchTimeoutKey{}).(time.Duration); ok {
-		b.writerConfig.BatchTimeout = value
+	if c.BatchTimeout != 0 {
+		b.writerConfig.BatchTimeout = c.BatchTimeout
 	}
 
-	if value, ok := b.options.Context.Value(batchBytesKey{}).(int64); ok {
-		b.writerConfig.BatchBytes = value
+	if c.BatchBytes != 0 {
+		b.writerConfig.BatchBytes = c.BatchBytes
 	}
 
-	if value, ok := b.options.Context.Value(asyncKey{}).(bool); ok {
-		b.writerConfig.Async = value
+	if c.Async != nil {
+		b.writerConfig.Async = *c.Async
 	}
 
-	if value, ok := b.options.Context.Value(maxAttemptsKey{}).(int); ok {
-		b.writerConfig.MaxAttempts = value
+	if c.MaxAttempts != 0 {
+		b.writerConfig.MaxAttempts = c.MaxAttempts
 	}
 
-	if value, ok := b.options.Context.Value(readTimeoutKey{}).(time.Duration); ok {
-		b.writerConfig.ReadTimeout = value
+	if c.ReadTimeout != 0 {
+		b.writerConfig.ReadTimeout = c.ReadTimeout
 	}
 
-	if value, ok := b.options.Context.Value(writeTimeoutKey{}).(time.Duration); ok {
-		b.writerConfig.WriteTimeout = value
+	if c.WriteTimeout != 0 {
+		b.writerConfig.WriteTimeout = c.WriteTimeout
 	}
 
-	if value, ok := b.options.Context.Value(allowPublishAutoTopicCreationKey{}).(bool); ok {
-		b.writerConfig.AllowAutoTopicCreation = value
+	if c.AllowAutoTopicCreation {
+		b.writerConfig.AllowAutoTopicCreation = true
 	}
 
+	b.metrics = broker.NewMetrics("kafka", b.options.MeterProvider)
+
 	return nil
 }
 
@@ -314,6 +335,12 @@ func (b *kafkaBroker) Connect() error {
 		return errors.New("no available commons")
 	}
 
+	if t, ok := broker.VerifyOnStartFromContext(b.options.Context); ok {
+		if err := b.verifyTopology(b.options.Context, kAddrs, t); err != nil {
+			return err
+		}
+	}
+
 	b.Lock()
 	b.options.Addrs = kAddrs
 	b.readerConfig.Brokers = kAddrs
@@ -334,7 +361,9 @@ func (b *kafkaBroker) Disconnect() error {
 	b.Lock()
 	defer b.Unlock()
 
-	b.writer.Close()
+	if b.writer != nil {
+		b.writer.Close()
+	}
 	b.subscribers.Clear()
 
 	b.connected = false
@@ -343,7 +372,7 @@ func (b *kafkaBroker) Disconnect() error {
 
 func (b *kafkaBroker) initPublishOption(writer *kafkaGo.Writer, options broker.PublishOptions) {
 	//writer.BalancerName = b.writerConfig.BalancerName
-	if value, ok := options.Context.Value(balancerKey{}).(*balancerValue); ok {
+	if value := PublishConfigFromOptions(options).Balancer; value != nil {
 		switch value.Name {
 		default:
 		case LeastBytesBalancer:
@@ -377,59 +406,29 @@ func (b *kafkaBroker) initPublishOption(writer *kafkaGo.Writer, options broker.P
 }
 
 func (b *kafkaBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
-	buf, err := broker.Marshal(b.options.Codec, msg)
-	if err != nil {
+	if err := b.mode.CanPublish(); err != nil {
 		return err
 	}
 
-	if b.writer.EnableOneTopicOneWriter {
-		return b.publishMultipleWriter(ctx, topic, buf, opts...)
-	} else {
-		return b.publishOneWriter(ctx, topic, buf, opts...)
-	}
-}
-
-func (b *kafkaBroker) publishMultipleWriter(ctx context.Context, topic string, buf []byte, opts ...broker.PublishOption) error {
-	options := broker.PublishOptions{
-		Context: ctx,
-	}
-	for _, o := range opts {
-		o(&options)
+	kMsg, options, err := b.newMessage(ctx, topic, msg, opts)
+	if err != nil {
+		return err
 	}
 
-	kMsg := kafkaGo.Message{
-		Topic: topic,
-		Value: buf,
-	}
+	start := time.Now()
 
-	if headers, ok := options.Context.Value(messageHeadersKey{}).(map[string]interface{}); ok {
-		for k, v := range headers {
-			header := kafkaGo.Header{Key: k}
-			switch t := v.(type) {
-			case string:
-				header.Value = []byte(t)
-			case []byte:
-				header.Value = t
-			default:
-				var buf bytes.Buffer
-				enc := gob.NewEncoder(&buf)
-				if err := enc.Encode(v); err != nil {
-					continue
-				}
-				header.Value = buf.Bytes()
-			}
-			kMsg.Headers = append(kMsg.Headers, header)
-		}
+	if b.writer.EnableOneTopicOneWriter {
+		err = b.publishMultipleWriter(topic, kMsg, options)
+	} else {
+		err = b.publishOneWriter(kMsg, options)
 	}
 
-	if value, ok := options.Context.Value(messageKeyKey{}).([]byte); ok {
-		kMsg.Key = value
-	}
+	b.metrics.RecordPublish(ctx, topic, start, err)
 
-	if value, ok := options.Context.Value(messageOffsetKey{}).(int64); ok {
-		kMsg.Offset = value
-	}
+	return err
+}
 
+func (b *kafkaBroker) publishMultipleWriter(topic string, kMsg kafkaGo.Message, options broker.PublishOptions) error {
 	var cached bool
 	b.Lock()
 	writer, ok := b.writer.Writers[topic]
@@ -455,7 +454,7 @@ func (b *kafkaBroker) publishMultipleWriter(ctx context.Context, topic string, b
 			var kerr kafkaGo.Error
 			if errors.As(err, &kerr) {
 				if kerr.Temporary() && !kerr.Timeout() {
-					time.Sleep(200 * time.Millisecond)
+					b.options.Clock.Sleep(200 * time.Millisecond)
 					err = writer.WriteMessages(options.Context, kMsg)
 				}
 			}
@@ -484,47 +483,7 @@ func (b *kafkaBroker) publishMultipleWriter(ctx context.Context, topic string, b
 	return err
 }
 
-func (b *kafkaBroker) publishOneWriter(ctx context.Context, topic string, buf []byte, opts ...broker.PublishOption) error {
-	options := broker.PublishOptions{
-		Context: ctx,
-	}
-	for _, o := range opts {
-		o(&options)
-	}
-
-	kMsg := kafkaGo.Message{
-		Topic: topic,
-		Value: buf,
-	}
-
-	if headers, ok := options.Context.Value(messageHeadersKey{}).(map[string]interface{}); ok {
-		for k, v := range headers {
-			header := kafkaGo.Header{Key: k}
-			switch t := v.(type) {
-			case string:
-				header.Value = []byte(t)
-			case []byte:
-				header.Value = t
-			default:
-				var buf bytes.Buffer
-				enc := gob.NewEncoder(&buf)
-				if err := enc.Encode(v); err != nil {
-					continue
-				}
-				header.Value = buf.Bytes()
-			}
-			kMsg.Headers = append(kMsg.Headers, header)
-		}
-	}
-
-	if value, ok := options.Context.Value(messageKeyKey{}).([]byte); ok {
-		kMsg.Key = value
-	}
-
-	if value, ok := options.Context.Value(messageOffsetKey{}).(int64); ok {
-		kMsg.Offset = value
-	}
-
+func (b *kafkaBroker) publishOneWriter(kMsg kafkaGo.Message, options broker.PublishOptions) error {
 	var cached bool
 	b.Lock()
 	if b.writer.Writer == nil {
@@ -548,7 +507,7 @@ func (b *kafkaBroker) publishOneWriter(ctx context.Context, topic string, buf []
 			var kerr kafkaGo.Error
 			if errors.As(err, &kerr) {
 				if kerr.Temporary() && !kerr.Timeout() {
-					time.Sleep(200 * time.Millisecond)
+					b.options.Clock.Sleep(200 * time.Millisecond)
 					err = b.writer.Writer.WriteMessages(options.Context, kMsg)
 				}
 			}
@@ -577,7 +536,75 @@ func (b *kafkaBroker) publishOneWriter(ctx context.Context, topic string, buf []
 	return err
 }
 
+// newMessage encode msg and run the publish interceptors, it returns the
+// message to write to topic with the options of the publish.
+func (b *kafkaBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) (kafkaGo.Message, broker.PublishOptions, error) {
+	options := broker.PublishOptions{
+		Context: ctx,
+	}
+
+	buf, err := broker.Marshal(b.options.Codec, msg)
+	if err != nil {
+		return kafkaGo.Message{}, options, err
+	}
+	buf, opts, err = b.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return kafkaGo.Message{}, options, err
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return newKafkaMessage(topic, buf, options), options, nil
+}
+
+func newKafkaMessage(topic string, buf []byte, options broker.PublishOptions) kafkaGo.Message {
+	c := PublishConfigFromOptions(options)
+
+	kMsg := kafkaGo.Message{
+		Topic:  topic,
+		Value:  buf,
+		Key:    c.Key,
+		Offset: c.Offset,
+	}
+
+	if c.Headers != nil {
+		for k, v := range c.Headers {
+			header := kafkaGo.Header{Key: k}
+			switch t := v.(type) {
+			case string:
+				header.Value = []byte(t)
+			case []byte:
+				header.Value = t
+			default:
+				var buf bytes.Buffer
+				enc := gob.NewEncoder(&buf)
+				if err := enc.Encode(v); err != nil {
+					continue
+				}
+				header.Value = buf.Bytes()
+			}
+			kMsg.Headers = append(kMsg.Headers, header)
+		}
+	}
+	for k, v := range options.Headers {
+		kMsg.Headers = append(kMsg.Headers, kafkaGo.Header{Key: k, Value: []byte(v)})
+	}
+
+	return kMsg
+}
+
+// SubscribeRaw subscribe topic and deliver the values as received.
+func (b *kafkaBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
+
 func (b *kafkaBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	if err := b.mode.CanSubscribe(); err != nil {
+		return nil, err
+	}
+
 	options := broker.SubscribeOptions{
 		Context: context.Background(),
 		AutoAck: true,
@@ -587,7 +614,16 @@ func (b *kafkaBroker) Subscribe(topic string, handler broker.Handler, binder bro
 		o(&options)
 	}
 
-	if value, ok := options.Context.Value(autoSubscribeCreateTopicKey{}).(*autoSubscribeCreateTopicValue); ok {
+	handler = options.TimeoutHandler(handler)
+	handler = b.metrics.Handler(topic, handler)
+
+	if options.RawBody {
+		binder = nil
+	}
+
+	sc := SubscribeConfigFromOptions(options)
+
+	if value := sc.AutoCreateTopic; value != nil {
 		if err := CreateTopic(b.Address(), value.Topic, value.NumPartitions, value.ReplicationFactor); err != nil {
 			log.Errorf("[kafka] create topic error: %s", err.Error())
 		}
@@ -597,11 +633,23 @@ func (b *kafkaBroker) Subscribe(topic string, handler broker.Handler, binder bro
 	readerConfig.Topic = topic
 	readerConfig.GroupID = options.Queue
 
+	if sc.IsolationLevel != nil {
+		readerConfig.IsolationLevel = *sc.IsolationLevel
+	}
+
+	if options.StartPosition != nil {
+		if err := b.resetOffsets(options.Context, readerConfig.GroupID, topic, *options.StartPosition); err != nil {
+			return nil, fmt.Errorf("start [%s] at %s: %w", topic, options.StartPosition, err)
+		}
+	}
+
 	sub := &subscriber{
-		options: options,
-		topic:   topic,
-		handler: handler,
-		reader:  kafkaGo.NewReader(readerConfig),
+		k:            b,
+		options:      options,
+		topic:        topic,
+		handler:      handler,
+		reader:       kafkaGo.NewReader(readerConfig),
+		readerConfig: readerConfig,
 	}
 
 	go func() {
@@ -614,9 +662,17 @@ func (b *kafkaBroker) Subscribe(topic string, handler broker.Handler, binder bro
 				if _, ok := <-options.Context.Done(); ok {
 					return
 				}
-				msg, err := sub.reader.FetchMessage(options.Context)
+				reader := sub.getReader()
+				msg, err := reader.FetchMessage(options.Context)
 				if err != nil {
+					if reader != sub.getReader() {
+						// closed by Seek.
+						continue
+					}
 					log.Errorf("[kafka] FetchMessage error: %s", err.Error())
+					if options.Context.Err() == nil {
+						sub.options.ReportError(options.Context, broker.ErrReceive, err, nil)
+					}
 					continue
 				}
 
@@ -627,7 +683,7 @@ func (b *kafkaBroker) Subscribe(topic string, handler broker.Handler, binder bro
 					Body:    nil,
 				}
 
-				p := &publication{topic: msg.Topic, reader: sub.reader, m: m, km: msg, ctx: options.Context}
+				p := &publication{topic: msg.Topic, reader: reader, m: m, km: msg, ctx: options.Context}
 
 				if binder != nil {
 					m.Body = binder()
@@ -635,10 +691,22 @@ func (b *kafkaBroker) Subscribe(topic string, handler broker.Handler, binder bro
 					m.Body = msg.Value
 				}
 
-				if err = broker.Unmarshal(b.options.Codec, msg.Value, &m.Body); err != nil {
+				if err = b.options.Decode(&sub.options, m.Headers, msg.Value, &m.Body); err != nil {
 					p.err = err
 					log.Errorf("[kafka] unmarshal message failed: %v", err)
+					sub.options.ReportError(ctx, broker.ErrUnmarshal, err, p)
 					b.finishConsumerSpan(span, err)
+
+					// a kafka message cannot be redelivered alone, retry leaves it uncommitted.
+					switch sub.options.HandleUnmarshalFailure(ctx, msg.Topic, m.Headers, msg.Value, err) {
+					case broker.UnmarshalActionAck:
+						if err = p.Ack(); err != nil {
+							sub.options.ReportError(ctx, broker.ErrAck, err, p)
+						}
+					case broker.UnmarshalActionStop:
+						_ = sub.Unsubscribe(true)
+						return
+					}
 					continue
 				}
 
@@ -649,8 +717,9 @@ func (b *kafkaBroker) Subscribe(topic string, handler broker.Handler, binder bro
 				}
 
 				if sub.options.AutoAck {
-					if err = p.Ack(); err != nil {
+					if err = broker.AutoAck(p); err != nil {
 						log.Errorf("[kafka] unable to commit msg: %v", err)
+						sub.options.ReportError(ctx, broker.ErrAck, err, p)
 					}
 				}
 
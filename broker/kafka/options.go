@@ -66,6 +66,8 @@ type readTimeoutKey struct{}
 type writeTimeoutKey struct{}
 type allowPublishAutoTopicCreationKey struct{}
 type completionKey struct{}
+type isolationLevelKey struct{}
+type groupBalancersKey struct{}
 
 // WithReaderConfig .
 func WithReaderConfig(cfg kafkaGo.ReaderConfig) broker.Option {
@@ -179,6 +181,22 @@ func WithRebalanceTimeout(timeout time.Duration) broker.Option {
 	return broker.OptionContextWithValue(rebalanceTimeoutKey{}, timeout)
 }
 
+// WithGroupBalancers set the consumer group assignors, in priority order.
+func WithGroupBalancers(balancers ...kafkaGo.GroupBalancer) broker.Option {
+	return broker.OptionContextWithValue(groupBalancersKey{}, balancers)
+}
+
+// WithStickyAssignment assign the partitions with a StickyGroupBalancer of
+// instanceID, the range and round robin assignors remain while the
+// consumers of a group are rolled out to it.
+func WithStickyAssignment(instanceID string) broker.Option {
+	return WithGroupBalancers(
+		StickyGroupBalancer{InstanceID: instanceID},
+		kafkaGo.RangeGroupBalancer{},
+		kafkaGo.RoundRobinGroupBalancer{},
+	)
+}
+
 // WithRetentionTime .
 func WithRetentionTime(time time.Duration) broker.Option {
 	return broker.OptionContextWithValue(retentionTimeKey{}, time)
@@ -265,6 +283,12 @@ func WithCompletion(completion func(messages []kafkaGo.Message, err error)) brok
 	return broker.OptionContextWithValue(completionKey{}, &completion)
 }
 
+// WithIsolationLevel 事务消息可见性，ReadCommitted 只读取已提交的事务消息。
+// 仅对其他客户端（如Java、franz-go）的事务生产者写入的Topic有意义，本驱动不支持事务发布。
+func WithIsolationLevel(level kafkaGo.IsolationLevel) broker.Option {
+	return broker.OptionContextWithValue(isolationLevelKey{}, level)
+}
+
 ///
 /// PublishOption
 ///
@@ -357,6 +381,7 @@ func WithMurmur2Balancer(consistent bool) broker.PublishOption {
 ///
 
 type autoSubscribeCreateTopicKey struct{}
+type subscribeIsolationLevelKey struct{}
 type autoSubscribeCreateTopicValue struct {
 	Topic             string
 	NumPartitions     int
@@ -372,3 +397,13 @@ func WithSubscribeAutoCreateTopic(topic string, numPartitions, replicationFactor
 		},
 	)
 }
+
+// WithSubscribeIsolationLevel 覆盖单个订阅的事务消息可见性
+func WithSubscribeIsolationLevel(level kafkaGo.IsolationLevel) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(subscribeIsolationLevelKey{}, level)
+}
+
+// WithReadCommitted 只读取已提交的事务消息 (isolation.level=read_committed)
+func WithReadCommitted() broker.SubscribeOption {
+	return WithSubscribeIsolationLevel(kafkaGo.ReadCommitted)
+}
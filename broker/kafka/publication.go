@@ -10,6 +10,8 @@ import (
 )
 
 type publication struct {
+	broker.AckState
+
 	topic  string
 	err    error
 	m      *broker.Message
@@ -30,11 +32,19 @@ func (p *publication) RawMessage() interface{} {
 	return p.km
 }
 
+func (p *publication) Position() broker.Position {
+	pos := broker.AtOffset(p.km.Partition, p.km.Offset+1)
+	pos.Time = p.km.Time
+	return pos
+}
+
 func (p *publication) Ack() error {
-	if p.reader == nil {
-		return errors.New("read is nil")
-	}
-	return p.reader.CommitMessages(p.ctx, p.km)
+	return p.AckState.Ack(func() error {
+		if p.reader == nil {
+			return errors.New("read is nil")
+		}
+		return p.reader.CommitMessages(p.ctx, p.km)
+	})
 }
 
 func (p *publication) Error() error {
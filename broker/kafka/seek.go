@@ -0,0 +1,166 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	kafkaGo "github.com/segmentio/kafka-go"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var _ broker.Seeker = (*subscriber)(nil)
+
+// Seek move the consumer group of the subscriber to pos. The reader leaves
+// the group, the offsets of the group are committed at pos and a new reader
+// joins it, the other members of the group must be stopped first, Kafka
+// refusing the commits of a group with members.
+func (s *subscriber) Seek(ctx context.Context, pos broker.Position) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return errors.New("subscriber closed")
+	}
+
+	if err := s.reader.Close(); err != nil {
+		return err
+	}
+
+	err := s.k.resetOffsets(ctx, s.readerConfig.GroupID, s.topic, pos)
+
+	// resume even when the reset failed, at the committed offsets.
+	s.reader = kafkaGo.NewReader(s.readerConfig)
+
+	return err
+}
+
+// resetOffsets commit the offsets of pos for the partitions of topic, group
+// must have no member.
+func (b *kafkaBroker) resetOffsets(ctx context.Context, group, topic string, pos broker.Position) error {
+	if len(b.options.Addrs) == 0 {
+		return errors.New("no available commons")
+	}
+
+	client := b.newClient(b.options.Addrs)
+
+	meta, err := client.Metadata(ctx, &kafkaGo.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return err
+	}
+	if len(meta.Topics) == 0 {
+		return kafkaGo.UnknownTopicOrPartition
+	}
+	if meta.Topics[0].Error != nil {
+		return meta.Topics[0].Error
+	}
+
+	var partitions []int
+	for _, p := range meta.Topics[0].Partitions {
+		partitions = append(partitions, p.ID)
+	}
+
+	offsets, err := b.positionOffsets(ctx, client, topic, partitions, pos)
+	if err != nil {
+		return err
+	}
+
+	var commits []kafkaGo.OffsetCommit
+	for _, p := range partitions {
+		if offset, ok := offsets[p]; ok {
+			commits = append(commits, kafkaGo.OffsetCommit{Partition: p, Offset: offset})
+		}
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("no partition of [%s] at %s", topic, pos)
+	}
+
+	// a commit out of any generation is accepted for a group without member.
+	resp, err := client.OffsetCommit(ctx, &kafkaGo.OffsetCommitRequest{
+		GroupID:      group,
+		GenerationID: -1,
+		Topics:       map[string][]kafkaGo.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return fmt.Errorf("commit partition %d of [%s]: %w", p.Partition, topic, p.Error)
+		}
+	}
+	return nil
+}
+
+// positionOffsets resolve pos to the offset of each partition.
+func (b *kafkaBroker) positionOffsets(ctx context.Context, client *kafkaGo.Client, topic string, partitions []int, pos broker.Position) (map[int]int64, error) {
+	offsets := map[int]int64{}
+
+	var requests []kafkaGo.OffsetRequest
+	for _, p := range partitions {
+		switch pos.Kind {
+		case broker.PositionBeginning:
+			requests = append(requests, kafkaGo.FirstOffsetOf(p))
+		case broker.PositionEnd:
+			requests = append(requests, kafkaGo.LastOffsetOf(p))
+		case broker.PositionTime:
+			requests = append(requests, kafkaGo.TimeOffsetOf(p, pos.Time))
+		case broker.PositionOffset:
+			if pos.Partition < 0 || pos.Partition == p {
+				offsets[p] = pos.Offset
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s", broker.ErrSeekUnsupported, pos)
+		}
+	}
+	if len(requests) == 0 {
+		return offsets, nil
+	}
+
+	resp, err := client.ListOffsets(ctx, &kafkaGo.ListOffsetsRequest{
+		Topics: map[string][]kafkaGo.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []kafkaGo.OffsetRequest
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return nil, p.Error
+		}
+		switch pos.Kind {
+		case broker.PositionBeginning:
+			offsets[p.Partition] = p.FirstOffset
+		case broker.PositionEnd:
+			offsets[p.Partition] = p.LastOffset
+		case broker.PositionTime:
+			for offset := range p.Offsets {
+				if offset >= 0 {
+					offsets[p.Partition] = offset
+				}
+			}
+			if _, ok := offsets[p.Partition]; !ok {
+				missing = append(missing, kafkaGo.LastOffsetOf(p.Partition))
+			}
+		}
+	}
+
+	// no message at or after the time, the partitions start at their end.
+	if len(missing) > 0 {
+		if resp, err = client.ListOffsets(ctx, &kafkaGo.ListOffsetsRequest{
+			Topics: map[string][]kafkaGo.OffsetRequest{topic: missing},
+		}); err != nil {
+			return nil, err
+		}
+		for _, p := range resp.Topics[topic] {
+			if p.Error != nil {
+				return nil, p.Error
+			}
+			offsets[p.Partition] = p.LastOffset
+		}
+	}
+
+	return offsets, nil
+}
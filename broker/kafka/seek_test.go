@@ -0,0 +1,26 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestPositionOffsets(t *testing.T) {
+	b := &kafkaBroker{}
+	ctx := context.Background()
+
+	offsets, err := b.positionOffsets(ctx, nil, "orders", []int{0, 1, 2}, broker.AtOffset(1, 42))
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int64{1: 42}, offsets)
+
+	offsets, err = b.positionOffsets(ctx, nil, "orders", []int{0, 1}, broker.AtOffset(-1, 7))
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int64{0: 7, 1: 7}, offsets)
+
+	_, err = b.positionOffsets(ctx, nil, "orders", []int{0}, broker.AtMessageID([]byte{1}))
+	assert.ErrorIs(t, err, broker.ErrSeekUnsupported)
+}
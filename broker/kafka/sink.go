@@ -0,0 +1,443 @@
+package kafka
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	kafkaGo "github.com/segmentio/kafka-go"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/sqlutil"
+)
+
+const (
+	DefaultSinkOffsetTable   = "kafka_sink_offsets"
+	defaultSinkRetryInterval = time.Second
+)
+
+var (
+	ErrNotKafkaBroker     = errors.New("[kafka] broker is not a kafka broker")
+	ErrSinkRunning        = errors.New("[kafka] sink already running")
+	ErrSinkOffsetConflict = errors.New("[kafka] sink offset moved by another consumer")
+)
+
+// SinkHandler writes one consumed event to the database. The transaction is
+// committed together with the offset of the event.
+type SinkHandler func(ctx context.Context, tx *sql.Tx, event broker.Event) error
+
+// OffsetStore keeps the next offset to consume for each partition. Load runs
+// when a partition is assigned, Save runs inside the transaction of the write.
+type OffsetStore interface {
+	Load(ctx context.Context, db *sql.DB, group, topic string, partition int) (offset int64, found bool, err error)
+	Save(ctx context.Context, tx *sql.Tx, group, topic string, partition int, prev, next int64, found bool) error
+}
+
+// SQLOffsetStore keeps offsets in a table shaped like:
+//
+//	CREATE TABLE kafka_sink_offsets (
+//	    group_id     VARCHAR(255) NOT NULL,
+//	    topic        VARCHAR(255) NOT NULL,
+//	    partition_id INT          NOT NULL,
+//	    next_offset  BIGINT       NOT NULL,
+//	    updated_at   TIMESTAMP    NOT NULL,
+//	    PRIMARY KEY (group_id, topic, partition_id)
+//	);
+//
+// Updates are conditional on the previous offset, so a consumer that lost its
+// partition in a rebalance can not overwrite the progress of the new owner.
+type SQLOffsetStore struct {
+	table       string
+	placeholder sqlutil.PlaceholderFormat
+}
+
+func NewSQLOffsetStore(table string, placeholder sqlutil.PlaceholderFormat) *SQLOffsetStore {
+	if table == "" {
+		table = DefaultSinkOffsetTable
+	}
+	if placeholder == nil {
+		placeholder = sqlutil.Question
+	}
+	return &SQLOffsetStore{
+		table:       table,
+		placeholder: placeholder,
+	}
+}
+
+func (s *SQLOffsetStore) Load(ctx context.Context, db *sql.DB, group, topic string, partition int) (int64, bool, error) {
+	query := fmt.Sprintf("SELECT next_offset FROM %s WHERE group_id = %s AND topic = %s AND partition_id = %s",
+		s.table, s.placeholder(1), s.placeholder(2), s.placeholder(3))
+
+	var offset int64
+	err := db.QueryRowContext(ctx, query, group, topic, partition).Scan(&offset)
+	switch {
+	case err == sql.ErrNoRows:
+		return 0, false, nil
+	case err != nil:
+		return 0, false, err
+	default:
+		return offset, true, nil
+	}
+}
+
+func (s *SQLOffsetStore) Save(ctx context.Context, tx *sql.Tx, group, topic string, partition int, prev, next int64, found bool) error {
+	if !found {
+		query := fmt.Sprintf("INSERT INTO %s (group_id, topic, partition_id, next_offset, updated_at) VALUES (%s, %s, %s, %s, %s)",
+			s.table, s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4), s.placeholder(5))
+
+		_, err := tx.ExecContext(ctx, query, group, topic, partition, next, time.Now())
+		if isUniqueViolation(err) {
+			return ErrSinkOffsetConflict
+		}
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET next_offset = %s, updated_at = %s WHERE group_id = %s AND topic = %s AND partition_id = %s AND next_offset = %s",
+		s.table, s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4), s.placeholder(5), s.placeholder(6))
+
+	res, err := tx.ExecContext(ctx, query, next, time.Now(), group, topic, partition, prev)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrSinkOffsetConflict
+	}
+	return nil
+}
+
+// isUniqueViolation report whether err is the duplicate key error of
+// postgres, mysql or sqlite, i.e. another consumer stored the first offset.
+func isUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) && state.SQLState() == "23505" {
+		return true
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "duplicate key value") ||
+		strings.Contains(msg, "Duplicate entry") ||
+		strings.Contains(msg, "UNIQUE constraint failed")
+}
+
+type SinkOption func(s *Sink)
+
+// WithSinkGroupID set the consumer group that shares the partitions, default is "sink-" + topic.
+func WithSinkGroupID(id string) SinkOption {
+	return func(s *Sink) {
+		s.groupID = id
+	}
+}
+
+// WithSinkOffsetStore set the offset store, default is SQLOffsetStore on table kafka_sink_offsets.
+func WithSinkOffsetStore(store OffsetStore) SinkOption {
+	return func(s *Sink) {
+		s.store = store
+	}
+}
+
+// WithSinkTxOptions set the options of the write transaction.
+func WithSinkTxOptions(opts *sql.TxOptions) SinkOption {
+	return func(s *Sink) {
+		s.txOptions = opts
+	}
+}
+
+// WithSinkRetryInterval set the delay before a failed write is retried.
+func WithSinkRetryInterval(interval time.Duration) SinkOption {
+	return func(s *Sink) {
+		s.retryInterval = interval
+	}
+}
+
+// WithSinkSubscribeOptions set the unmarshal failure policy and the error
+// handler of the sink, e.g. broker.WithUnmarshalDeadLetter. A message which
+// cannot be decoded is retried in place by default.
+func WithSinkSubscribeOptions(opts ...broker.SubscribeOption) SinkOption {
+	return func(s *Sink) {
+		for _, o := range opts {
+			o(&s.subscribeOptions)
+		}
+	}
+}
+
+// Sink consumes a topic and writes every message to a SQL database, storing
+// the consumed offset in the same transaction as the write. Offsets are never
+// committed to Kafka; on every partition assignment the sink resumes from the
+// offset found in the database, so each message is applied exactly once.
+//
+// The consumer group only distributes partitions between sink instances. A
+// write that keeps failing is retried in place and blocks its partition. The
+// messages skipped or dead-lettered by the unmarshal failure policy only
+// advance the stored offset.
+type Sink struct {
+	sync.Mutex
+
+	b       *kafkaBroker
+	db      *sql.DB
+	topic   string
+	handler SinkHandler
+	binder  broker.Binder
+
+	groupID       string
+	store         OffsetStore
+	txOptions     *sql.TxOptions
+	retryInterval time.Duration
+
+	subscribeOptions broker.SubscribeOptions
+
+	group *kafkaGo.ConsumerGroup
+	done  chan struct{}
+}
+
+// NewSink create a sink of topic on a broker created by this package.
+func NewSink(b broker.Broker, db *sql.DB, topic string, handler SinkHandler, binder broker.Binder, opts ...SinkOption) (*Sink, error) {
+	kb, ok := b.(*kafkaBroker)
+	if !ok {
+		return nil, ErrNotKafkaBroker
+	}
+
+	s := &Sink{
+		b:             kb,
+		db:            db,
+		topic:         topic,
+		handler:       handler,
+		binder:        binder,
+		groupID:       "sink-" + topic,
+		retryInterval: defaultSinkRetryInterval,
+		subscribeOptions: broker.SubscribeOptions{
+			Context: context.Background(),
+		},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	if s.store == nil {
+		s.store = NewSQLOffsetStore(DefaultSinkOffsetTable, sqlutil.Question)
+	}
+
+	return s, nil
+}
+
+// Start join the consumer group and consume the assigned partitions until Stop.
+func (s *Sink) Start() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.group != nil {
+		return ErrSinkRunning
+	}
+
+	cfg := kafkaGo.ConsumerGroupConfig{
+		ID:          s.groupID,
+		Brokers:     s.b.readerConfig.Brokers,
+		Dialer:      s.b.readerConfig.Dialer,
+		Topics:      []string{s.topic},
+		ErrorLogger: s.b.readerConfig.ErrorLogger,
+	}
+	if s.b.readerConfig.StartOffset == kafkaGo.LastOffset {
+		cfg.StartOffset = kafkaGo.LastOffset
+	}
+
+	group, err := kafkaGo.NewConsumerGroup(cfg)
+	if err != nil {
+		return err
+	}
+
+	s.group = group
+	s.done = make(chan struct{})
+
+	go s.run(group, s.done)
+
+	return nil
+}
+
+// Stop leave the consumer group and wait for the in-flight writes.
+func (s *Sink) Stop() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.group == nil {
+		return nil
+	}
+
+	err := s.group.Close()
+	<-s.done
+
+	s.group = nil
+	s.done = nil
+
+	return err
+}
+
+func (s *Sink) run(group *kafkaGo.ConsumerGroup, done chan struct{}) {
+	defer close(done)
+
+	for {
+		gen, err := group.Next(context.Background())
+		if err != nil {
+			if errors.Is(err, kafkaGo.ErrGroupClosed) {
+				return
+			}
+			log.Errorf("[kafka] sink join group error: %s", err.Error())
+			s.b.options.Clock.Sleep(s.retryInterval)
+			continue
+		}
+
+		for _, assignment := range gen.Assignments[s.topic] {
+			partition, offset := assignment.ID, assignment.Offset
+			gen.Start(func(ctx context.Context) {
+				s.consume(ctx, partition, offset)
+			})
+		}
+	}
+}
+
+func (s *Sink) consume(ctx context.Context, partition int, startOffset int64) {
+	offset, found, err := s.store.Load(ctx, s.db, s.groupID, s.topic, partition)
+	for err != nil {
+		log.Errorf("[kafka] sink load offset of partition %d error: %s", partition, err.Error())
+		if !s.wait(ctx) {
+			return
+		}
+		offset, found, err = s.store.Load(ctx, s.db, s.groupID, s.topic, partition)
+	}
+	if !found {
+		offset = startOffset
+	}
+
+	readerConfig := s.b.readerConfig
+	readerConfig.Topic = s.topic
+	readerConfig.Partition = partition
+	readerConfig.GroupID = ""
+	readerConfig.GroupTopics = nil
+	readerConfig.WatchPartitionChanges = false
+
+	reader := kafkaGo.NewReader(readerConfig)
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	if err = reader.SetOffset(offset); err != nil {
+		log.Errorf("[kafka] sink seek partition %d error: %s", partition, err.Error())
+		return
+	}
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Errorf("[kafka] sink FetchMessage error: %s", err.Error())
+			if !s.wait(ctx) {
+				return
+			}
+			continue
+		}
+
+		for {
+			err = s.process(ctx, msg, offset, found)
+			if err == nil {
+				break
+			}
+			if errors.Is(err, ErrSinkOffsetConflict) {
+				log.Errorf("[kafka] sink lost partition %d: %s", partition, err.Error())
+				return
+			}
+			if errors.Is(err, broker.ErrSubscriptionStopped) {
+				log.Errorf("[kafka] sink stopped partition %d: %s", partition, err.Error())
+				return
+			}
+			log.Errorf("[kafka] sink write message failed: %v", err)
+			if !s.wait(ctx) {
+				return
+			}
+		}
+
+		offset, found = msg.Offset+1, true
+	}
+}
+
+// process apply msg and advance the stored offset of its partition from prev
+// to msg.Offset+1 in one transaction.
+func (s *Sink) process(ctx context.Context, msg kafkaGo.Message, prev int64, found bool) (err error) {
+	ctx, span := s.b.startConsumerSpan(ctx, &msg)
+	defer func() {
+		s.b.finishConsumerSpan(span, err)
+	}()
+
+	m := &broker.Message{
+		Headers: kafkaHeaderToMap(msg.Headers),
+		Body:    nil,
+	}
+
+	if s.binder != nil {
+		m.Body = s.binder()
+	} else {
+		m.Body = msg.Value
+	}
+
+	if err = s.b.options.Decode(&s.subscribeOptions, m.Headers, msg.Value, &m.Body); err != nil {
+		s.subscribeOptions.ReportError(ctx, broker.ErrUnmarshal, err, nil)
+
+		switch s.subscribeOptions.HandleUnmarshalFailure(ctx, msg.Topic, m.Headers, msg.Value, err) {
+		case broker.UnmarshalActionAck:
+			return s.commit(ctx, msg, prev, found, nil)
+		case broker.UnmarshalActionStop:
+			return errors.Join(broker.ErrSubscriptionStopped, err)
+		default:
+			return err
+		}
+	}
+
+	return s.commit(ctx, msg, prev, found, &publication{topic: msg.Topic, m: m, km: msg, ctx: ctx})
+}
+
+// commit apply event, if any, and store the offset after msg in one transaction.
+func (s *Sink) commit(ctx context.Context, msg kafkaGo.Message, prev int64, found bool, event broker.Event) error {
+	tx, err := s.db.BeginTx(ctx, s.txOptions)
+	if err != nil {
+		return err
+	}
+
+	if event != nil {
+		if err = s.handler(ctx, tx, event); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if err = s.store.Save(ctx, tx, s.groupID, msg.Topic, msg.Partition, prev, msg.Offset+1, found); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Sink) wait(ctx context.Context) bool {
+	timer := s.b.options.Clock.NewTimer(s.retryInterval)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
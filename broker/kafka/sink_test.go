@@ -0,0 +1,197 @@
+package kafka
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	kafkaGo "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type sinkTxCounter struct {
+	sync.Mutex
+	commits   int
+	rollbacks int
+	execErr   error
+}
+
+type sinkTestDriver struct{ c *sinkTxCounter }
+type sinkTestConn struct{ c *sinkTxCounter }
+type sinkTestTx struct{ c *sinkTxCounter }
+
+func (d sinkTestDriver) Open(string) (driver.Conn, error) { return sinkTestConn(d), nil }
+
+// sinkTestDriver is its own connector, so every test opens its db without
+// registering a driver name.
+func (d sinkTestDriver) Connect(context.Context) (driver.Conn, error) { return sinkTestConn(d), nil }
+func (d sinkTestDriver) Driver() driver.Driver                        { return d }
+
+func (c sinkTestConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c sinkTestConn) Close() error                        { return nil }
+func (c sinkTestConn) Begin() (driver.Tx, error)           { return sinkTestTx(c), nil }
+
+func (c sinkTestConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	if c.c.execErr != nil {
+		return nil, c.c.execErr
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (t sinkTestTx) Commit() error {
+	t.c.Lock()
+	defer t.c.Unlock()
+	t.c.commits++
+	return nil
+}
+
+func (t sinkTestTx) Rollback() error {
+	t.c.Lock()
+	defer t.c.Unlock()
+	t.c.rollbacks++
+	return nil
+}
+
+type memoryOffsetStore struct {
+	offsets map[int]int64
+}
+
+func (s *memoryOffsetStore) Load(_ context.Context, _ *sql.DB, _, _ string, partition int) (int64, bool, error) {
+	offset, ok := s.offsets[partition]
+	return offset, ok, nil
+}
+
+func (s *memoryOffsetStore) Save(_ context.Context, _ *sql.Tx, _, _ string, partition int, prev, next int64, found bool) error {
+	if current, ok := s.offsets[partition]; ok != found || (found && current != prev) {
+		return ErrSinkOffsetConflict
+	}
+	s.offsets[partition] = next
+	return nil
+}
+
+func newTestSink(t *testing.T, b broker.Broker, handler SinkHandler, opts ...SinkOption) (*Sink, *sinkTxCounter, *memoryOffsetStore) {
+	counter := &sinkTxCounter{}
+	db := sql.OpenDB(sinkTestDriver{c: counter})
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	store := &memoryOffsetStore{offsets: map[int]int64{}}
+
+	s, err := NewSink(b, db, testTopic, handler, nil, append([]SinkOption{WithSinkOffsetStore(store)}, opts...)...)
+	assert.Nil(t, err)
+
+	return s, counter, store
+}
+
+func TestSink_Process(t *testing.T) {
+	var bodies []string
+	s, counter, store := newTestSink(t, NewBroker(), func(_ context.Context, _ *sql.Tx, event broker.Event) error {
+		bodies = append(bodies, string(event.Message().Body.([]byte)))
+		return nil
+	})
+
+	ctx := context.Background()
+
+	msg := kafkaGo.Message{Topic: testTopic, Partition: 1, Offset: 5, Value: []byte("hello")}
+	assert.Nil(t, s.process(ctx, msg, 0, false))
+	assert.Equal(t, int64(6), store.offsets[1])
+
+	msg = kafkaGo.Message{Topic: testTopic, Partition: 1, Offset: 6, Value: []byte("world")}
+	assert.Nil(t, s.process(ctx, msg, 6, true))
+	assert.Equal(t, int64(7), store.offsets[1])
+
+	assert.Equal(t, []string{"hello", "world"}, bodies)
+	assert.Equal(t, 2, counter.commits)
+	assert.Equal(t, 0, counter.rollbacks)
+}
+
+func TestSink_HandlerError(t *testing.T) {
+	s, counter, store := newTestSink(t, NewBroker(), func(_ context.Context, _ *sql.Tx, _ broker.Event) error {
+		return errors.New("write failed")
+	})
+
+	msg := kafkaGo.Message{Topic: testTopic, Partition: 0, Offset: 0, Value: []byte("hello")}
+	assert.NotNil(t, s.process(context.Background(), msg, 0, false))
+
+	_, found := store.offsets[0]
+	assert.False(t, found)
+	assert.Equal(t, 0, counter.commits)
+	assert.Equal(t, 1, counter.rollbacks)
+}
+
+func TestSink_OffsetConflict(t *testing.T) {
+	s, counter, store := newTestSink(t, NewBroker(), func(_ context.Context, _ *sql.Tx, _ broker.Event) error {
+		return nil
+	})
+	store.offsets[0] = 10
+
+	msg := kafkaGo.Message{Topic: testTopic, Partition: 0, Offset: 3, Value: []byte("hello")}
+	assert.ErrorIs(t, s.process(context.Background(), msg, 3, true), ErrSinkOffsetConflict)
+
+	assert.Equal(t, int64(10), store.offsets[0])
+	assert.Equal(t, 0, counter.commits)
+	assert.Equal(t, 1, counter.rollbacks)
+}
+
+func TestSink_NotKafkaBroker(t *testing.T) {
+	_, err := NewSink(nil, nil, testTopic, nil, nil)
+	assert.ErrorIs(t, err, ErrNotKafkaBroker)
+}
+
+func TestSink_UnmarshalFailure(t *testing.T) {
+	var handled int
+	handler := func(_ context.Context, _ *sql.Tx, _ broker.Event) error {
+		handled++
+		return nil
+	}
+	msg := kafkaGo.Message{Topic: testTopic, Partition: 0, Offset: 0, Value: []byte("not json")}
+
+	// retried in place by default.
+	s, counter, store := newTestSink(t, NewBroker(broker.WithCodec("json")), handler)
+	assert.NotNil(t, s.process(context.Background(), msg, 0, false))
+	assert.Empty(t, store.offsets)
+	assert.Equal(t, 0, counter.commits)
+
+	// skipped, only the offset advances.
+	s, counter, store = newTestSink(t, NewBroker(broker.WithCodec("json")), handler,
+		WithSinkSubscribeOptions(broker.WithUnmarshalSkip()))
+	assert.Nil(t, s.process(context.Background(), msg, 0, false))
+	assert.Equal(t, int64(1), store.offsets[0])
+	assert.Equal(t, 1, counter.commits)
+
+	s, _, store = newTestSink(t, NewBroker(broker.WithCodec("json")), handler,
+		WithSinkSubscribeOptions(broker.WithUnmarshalFail()))
+	assert.ErrorIs(t, s.process(context.Background(), msg, 0, false), broker.ErrSubscriptionStopped)
+	assert.Empty(t, store.offsets)
+
+	assert.Equal(t, 0, handled)
+}
+
+func TestSQLOffsetStore_InsertConflict(t *testing.T) {
+	counter := &sinkTxCounter{}
+	db := sql.OpenDB(sinkTestDriver{c: counter})
+	defer db.Close()
+
+	store := NewSQLOffsetStore("", nil)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, store.Save(ctx, tx, "group", testTopic, 0, 0, 1, false))
+
+	counter.execErr = errors.New(`pq: duplicate key value violates unique constraint "kafka_sink_offsets_pkey"`)
+	assert.ErrorIs(t, store.Save(ctx, tx, "group", testTopic, 0, 0, 1, false), ErrSinkOffsetConflict)
+
+	counter.execErr = errors.New("connection reset")
+	err = store.Save(ctx, tx, "group", testTopic, 0, 0, 1, false)
+	assert.NotErrorIs(t, err, ErrSinkOffsetConflict)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, tx.Rollback())
+}
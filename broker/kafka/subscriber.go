@@ -18,6 +18,8 @@ type subscriber struct {
 	reader  *kafkaGo.Reader
 	closed  bool
 	done    chan struct{}
+
+	readerConfig kafkaGo.ReaderConfig
 }
 
 func (s *subscriber) Options() broker.SubscribeOptions {
@@ -51,6 +53,14 @@ func (s *subscriber) Unsubscribe(removeFromManager bool) error {
 	return err
 }
 
+// getReader return the reader, replaced by Seek.
+func (s *subscriber) getReader() *kafkaGo.Reader {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.reader
+}
+
 func (s *subscriber) IsClosed() bool {
 	s.RLock()
 	defer s.RUnlock()
@@ -0,0 +1,70 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+
+	kafkaGo "github.com/segmentio/kafka-go"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var errGroupNotFound = errors.New("consumer group does not exist")
+
+// newClient return a client of the cluster for the admin requests.
+func (b *kafkaBroker) newClient(addrs []string) *kafkaGo.Client {
+	return &kafkaGo.Client{
+		Addr: kafkaGo.TCP(addrs...),
+		Transport: &kafkaGo.Transport{
+			SASL: b.saslMechanism,
+			TLS:  b.options.TLSConfig,
+		},
+	}
+}
+
+// verifyTopology check the topics and consumer groups of t exist. The topics
+// are fetched in a single metadata request; a group in the Dead state is
+// reported as missing.
+func (b *kafkaBroker) verifyTopology(ctx context.Context, addrs []string, t broker.Topology) error {
+	client := b.newClient(addrs)
+
+	topics := map[string]error{}
+	if len(t.Topics) > 0 {
+		resp, err := client.Metadata(ctx, &kafkaGo.MetadataRequest{Topics: t.Topics})
+		if err != nil {
+			return err
+		}
+		for _, topic := range resp.Topics {
+			topics[topic.Name] = topic.Error
+		}
+	}
+
+	groups := map[string]error{}
+	if len(t.Groups) > 0 {
+		resp, err := client.DescribeGroups(ctx, &kafkaGo.DescribeGroupsRequest{GroupIDs: t.Groups})
+		if err != nil {
+			return err
+		}
+		for _, group := range resp.Groups {
+			if group.Error == nil && group.GroupState == "Dead" {
+				group.Error = errGroupNotFound
+			}
+			groups[group.GroupID] = group.Error
+		}
+	}
+
+	lookup := func(found map[string]error, notFound error) broker.TopologyChecker {
+		return func(_ context.Context, name string) error {
+			err, ok := found[name]
+			if !ok {
+				return notFound
+			}
+			return err
+		}
+	}
+
+	return broker.VerifyTopology(ctx, "kafka", t, map[string]broker.TopologyChecker{
+		broker.ResourceTopic: lookup(topics, kafkaGo.UnknownTopicOrPartition),
+		broker.ResourceGroup: lookup(groups, errGroupNotFound),
+	})
+}
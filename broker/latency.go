@@ -0,0 +1,293 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// PublishTimeHeader carries the publish time of a message in Unix
+// milliseconds, set by WithPublishTimestamp.
+const PublishTimeHeader = "x-published-at"
+
+// WithPublishTimestamp stamp the published messages with PublishTimeHeader,
+// read by LatencyMetrics on the consumers.
+func WithPublishTimestamp() Option {
+	return func(o *Options) {
+		o.PublishInterceptors = append(o.PublishInterceptors, func(_ context.Context, _ string, msg *Message) error {
+			msg.Headers[PublishTimeHeader] = strconv.FormatInt(ClockOrSystem(o.Clock).Now().UnixMilli(), 10)
+			return nil
+		})
+	}
+}
+
+// PublishTime return the publish time of headers stamped by WithPublishTimestamp.
+func PublishTime(headers Headers) (time.Time, bool) {
+	ms, err := headers.GetInt(PublishTimeHeader)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// LatencySLO is a latency objective of the messages of a topic, e.g. 99% of
+// them handled within 5s of their publish, measured over Window.
+type LatencySLO struct {
+	Threshold time.Duration
+	Objective float64
+	Window    time.Duration
+
+	// BurnRate is the rate the error budget is spent at which OnBurn is
+	// called, e.g. 14.4 spends the budget of 30 days in 2 days. OnBurn is
+	// called again once the rate went back below it.
+	BurnRate float64
+	OnBurn   func(topic string, rate float64)
+
+	// MinEvents is the number of messages in the window under which the
+	// burn rate is not evaluated, default is 10.
+	MinEvents int64
+}
+
+type LatencyOption func(m *LatencyMetrics)
+
+// WithSkewTolerance set how far in the future a publish time may be, for
+// the clock skew between the publishers and the consumers, default is 1s.
+// The latencies within the tolerance count as zero, the others are not
+// recorded but counted as skewed.
+func WithSkewTolerance(d time.Duration) LatencyOption {
+	return func(m *LatencyMetrics) {
+		m.skewTolerance = d
+	}
+}
+
+// WithLatencyBuckets set the bucket boundaries of the histogram, in seconds.
+func WithLatencyBuckets(bounds ...float64) LatencyOption {
+	return func(m *LatencyMetrics) {
+		m.buckets = bounds
+	}
+}
+
+// WithLatencySLO evaluate slo on the latencies of every topic.
+func WithLatencySLO(slo LatencySLO) LatencyOption {
+	return func(m *LatencyMetrics) {
+		if slo.MinEvents <= 0 {
+			slo.MinEvents = 10
+		}
+		m.slo = &slo
+	}
+}
+
+// WithLatencyClock set the clock the latencies are measured with.
+func WithLatencyClock(clock Clock) LatencyOption {
+	return func(m *LatencyMetrics) {
+		m.clock = clock
+	}
+}
+
+// LatencyMetrics records the end-to-end latency of the messages, from the
+// publish time stamped by WithPublishTimestamp to the completion of their
+// handler, as a histogram per topic for the consumer lag objectives.
+type LatencyMetrics struct {
+	system        string
+	clock         Clock
+	skewTolerance time.Duration
+	buckets       []float64
+	slo           *LatencySLO
+
+	latency metric.Float64Histogram
+	skewed  metric.Int64Counter
+
+	mu     sync.Mutex
+	topics map[string]*sloWindow
+}
+
+// NewLatencyMetrics record through provider, nothing is recorded when it
+// is nil but the SLO is still evaluated.
+func NewLatencyMetrics(system string, provider metric.MeterProvider, opts ...LatencyOption) *LatencyMetrics {
+	m := &LatencyMetrics{
+		system:        system,
+		clock:         SystemClock,
+		skewTolerance: time.Second,
+		topics:        map[string]*sloWindow{},
+	}
+	for _, o := range opts {
+		o(m)
+	}
+
+	if provider == nil {
+		return m
+	}
+	meter := provider.Meter(meterName)
+
+	histogramOpts := []metric.Float64HistogramOption{
+		metric.WithUnit("s"),
+		metric.WithDescription("Measures the duration from the publish of a message to the completion of its handler."),
+	}
+	if len(m.buckets) > 0 {
+		histogramOpts = append(histogramOpts, metric.WithExplicitBucketBoundaries(m.buckets...))
+	}
+
+	var err error
+	if m.latency, err = meter.Float64Histogram("messaging.process.end_to_end.duration", histogramOpts...); err != nil {
+		otel.Handle(err)
+	}
+	if m.skewed, err = meter.Int64Counter("messaging.process.skewed.messages",
+		metric.WithUnit("{message}"),
+		metric.WithDescription("Measures the number of messages published in the future beyond the skew tolerance."),
+	); err != nil {
+		otel.Handle(err)
+	}
+
+	return m
+}
+
+// Handler wraps handler to record the latency of the messages of topic.
+func (m *LatencyMetrics) Handler(topic string, handler Handler) Handler {
+	return func(ctx context.Context, event Event) error {
+		err := handler(ctx, event)
+
+		if event != nil && event.Message() != nil {
+			if published, ok := PublishTime(event.Message().Headers); ok {
+				m.Observe(ctx, topic, published, err)
+			}
+		}
+		return err
+	}
+}
+
+// Observe record a message of topic published at published and handled now.
+func (m *LatencyMetrics) Observe(ctx context.Context, topic string, published time.Time, err error) {
+	latency := m.clock.Since(published)
+	if latency < 0 {
+		if -latency > m.skewTolerance {
+			if m.skewed != nil {
+				m.skewed.Add(ctx, 1, metric.WithAttributes(m.attributes(topic, nil)...))
+			}
+			return
+		}
+		latency = 0
+	}
+
+	if m.latency != nil {
+		m.latency.Record(ctx, latency.Seconds(), metric.WithAttributes(m.attributes(topic, err)...))
+	}
+	if m.slo != nil {
+		m.evaluate(topic, err == nil && latency <= m.slo.Threshold)
+	}
+}
+
+// BurnRate return the rate the error budget of topic is spent at, 1 spends
+// it exactly over the period of the objective.
+func (m *LatencyMetrics) BurnRate(topic string) float64 {
+	if m.slo == nil {
+		return 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := m.topics[topic]
+	if w == nil {
+		return 0
+	}
+	return w.burnRate(m.clock.Now(), m.slo.Objective)
+}
+
+func (m *LatencyMetrics) evaluate(topic string, good bool) {
+	m.mu.Lock()
+	w := m.topics[topic]
+	if w == nil {
+		w = newSLOWindow(m.slo.Window)
+		m.topics[topic] = w
+	}
+
+	now := m.clock.Now()
+	w.add(now, good)
+
+	var rate float64
+	burning := false
+	if w.total(now) >= m.slo.MinEvents {
+		rate = w.burnRate(now, m.slo.Objective)
+		burning = m.slo.BurnRate > 0 && rate >= m.slo.BurnRate
+	}
+	notify := burning && !w.burning
+	w.burning = burning
+	m.mu.Unlock()
+
+	if notify && m.slo.OnBurn != nil {
+		m.slo.OnBurn(topic, rate)
+	}
+}
+
+func (m *LatencyMetrics) attributes(topic string, err error) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", m.system),
+		attribute.String("messaging.destination.name", topic),
+	}
+	if err != nil {
+		attrs = append(attrs, attribute.String("error.type", fmt.Sprintf("%T", err)))
+	}
+	return attrs
+}
+
+const sloBuckets = 60
+
+// sloWindow counts the good and the total messages of a sliding window in
+// sloBuckets buckets.
+type sloWindow struct {
+	width   time.Duration
+	good    [sloBuckets]int64
+	all     [sloBuckets]int64
+	epochs  [sloBuckets]int64
+	burning bool
+}
+
+func newSLOWindow(window time.Duration) *sloWindow {
+	width := window / sloBuckets
+	if width <= 0 {
+		width = time.Second
+	}
+	return &sloWindow{width: width}
+}
+
+func (w *sloWindow) add(now time.Time, good bool) {
+	epoch := now.UnixNano() / int64(w.width)
+	i := epoch % sloBuckets
+	if w.epochs[i] != epoch {
+		w.epochs[i], w.good[i], w.all[i] = epoch, 0, 0
+	}
+	w.all[i]++
+	if good {
+		w.good[i]++
+	}
+}
+
+func (w *sloWindow) counts(now time.Time) (good, total int64) {
+	epoch := now.UnixNano() / int64(w.width)
+	for i := range w.epochs {
+		if epoch-w.epochs[i] < sloBuckets {
+			good += w.good[i]
+			total += w.all[i]
+		}
+	}
+	return good, total
+}
+
+func (w *sloWindow) total(now time.Time) int64 {
+	_, total := w.counts(now)
+	return total
+}
+
+func (w *sloWindow) burnRate(now time.Time, objective float64) float64 {
+	good, total := w.counts(now)
+	if total == 0 || objective >= 1 {
+		return 0
+	}
+	return float64(total-good) / float64(total) / (1 - objective)
+}
@@ -0,0 +1,97 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPublishTimestamp(t *testing.T) {
+	clock := NewFakeClock(time.UnixMilli(1700000000000))
+	o := NewOptionsAndApply(WithPublishTimestamp(), WithClock(clock))
+
+	_, opts, err := o.InterceptPublish(context.Background(), "orders", []byte("{}"), nil)
+	assert.Nil(t, err)
+
+	headers := NewPublishOptions(opts...).Headers
+	assert.Equal(t, "1700000000000", headers[PublishTimeHeader])
+
+	published, ok := PublishTime(headers)
+	assert.True(t, ok)
+	assert.Equal(t, clock.Now(), published)
+
+	_, ok = PublishTime(Headers{})
+	assert.False(t, ok)
+}
+
+func TestLatencyMetrics(t *testing.T) {
+	meter := &recordMeter{
+		histograms: map[string]*recordHistogram{},
+		counters:   map[string]*recordCounter{},
+	}
+	clock := NewFakeClock(time.Now())
+	m := NewLatencyMetrics("test", &recordMeterProvider{meter: meter},
+		WithLatencyClock(clock),
+		WithSkewTolerance(time.Second),
+	)
+
+	event := func(published time.Time) Event {
+		return &testEvent{topic: "orders", message: &Message{Headers: Headers{
+			PublishTimeHeader: strconv.FormatInt(published.UnixMilli(), 10),
+		}}}
+	}
+	handler := m.Handler("orders", func(context.Context, Event) error { return nil })
+
+	assert.Nil(t, handler(context.Background(), event(clock.Now().Add(-time.Second))))
+	// within the tolerance, recorded as zero.
+	assert.Nil(t, handler(context.Background(), event(clock.Now().Add(500*time.Millisecond))))
+	// beyond it, only counted.
+	assert.Nil(t, handler(context.Background(), event(clock.Now().Add(time.Minute))))
+	// no publish time, nothing recorded.
+	assert.Nil(t, handler(context.Background(), &testEvent{topic: "orders", message: &Message{}}))
+
+	assert.Equal(t, 2, meter.histograms["messaging.process.end_to_end.duration"].count)
+	assert.Equal(t, int64(1), meter.counters["messaging.process.skewed.messages"].value)
+}
+
+func TestLatencyMetrics_SLO(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+
+	var burns []float64
+	m := NewLatencyMetrics("test", nil,
+		WithLatencyClock(clock),
+		WithLatencySLO(LatencySLO{
+			Threshold: time.Second,
+			Objective: 0.9,
+			Window:    time.Minute,
+			BurnRate:  2,
+			OnBurn: func(topic string, rate float64) {
+				assert.Equal(t, "orders", topic)
+				burns = append(burns, rate)
+			},
+		}),
+	)
+	ctx := context.Background()
+
+	for i := 0; i < 8; i++ {
+		m.Observe(ctx, "orders", clock.Now().Add(-100*time.Millisecond), nil)
+	}
+	m.Observe(ctx, "orders", clock.Now().Add(-5*time.Second), nil)
+	assert.Empty(t, burns, "below the minimum events")
+
+	m.Observe(ctx, "orders", clock.Now(), errors.New("failed"))
+	assert.InDelta(t, 2, m.BurnRate("orders"), 0.001)
+	assert.Len(t, burns, 1)
+
+	m.Observe(ctx, "orders", clock.Now().Add(-5*time.Second), nil)
+	assert.Len(t, burns, 1, "notified once while burning")
+
+	// the window slides past the failures.
+	clock.Advance(2 * time.Minute)
+	assert.Equal(t, float64(0), m.BurnRate("orders"))
+	assert.Equal(t, float64(0), m.BurnRate("payments"))
+}
@@ -0,0 +1,130 @@
+package broker
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LineageHeader lists the hops of a message, the origin first, separated by
+// commas. Every hop is URL-encoded, e.g.
+// "at=1700000000000&service=billing&span=00f067aa0ba902b7&topic=orders".
+const LineageHeader = "x-lineage"
+
+// MaxLineageHops is the number of hops kept in LineageHeader, the oldest are
+// dropped beyond it.
+const MaxLineageHops = 32
+
+// Hop is a topic a message went through before being republished.
+type Hop struct {
+	// Service is the service which republished the message.
+	Service string
+	// Topic is the topic the message was consumed from.
+	Topic string
+	// Time is when the message left the topic.
+	Time time.Time
+	// SpanID is the span of the republish, if traced.
+	SpanID string
+}
+
+// WithServiceName set the service name recorded in the lineage hops.
+func WithServiceName(name string) Option {
+	return func(o *Options) {
+		o.ServiceName = name
+	}
+}
+
+// AppendHop append to the lineage of headers the hop of a message of topic
+// republished by service, with the span of ctx. The republish and bridge
+// helpers call it, so do the custom ones.
+func AppendHop(ctx context.Context, headers Headers, service, topic string) {
+	v := url.Values{}
+	v.Set("topic", topic)
+	if service != "" {
+		v.Set("service", service)
+	}
+	v.Set("at", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	if sc := trace.SpanContextFromContext(ctx); sc.HasSpanID() {
+		v.Set("span", sc.SpanID().String())
+	}
+
+	var hops []string
+	if lineage := headers[LineageHeader]; lineage != "" {
+		hops = strings.Split(lineage, ",")
+	}
+	hops = append(hops, v.Encode())
+	if len(hops) > MaxLineageHops {
+		hops = hops[len(hops)-MaxLineageHops:]
+	}
+	headers[LineageHeader] = strings.Join(hops, ",")
+}
+
+// Hops return the hops of the message of headers, the origin first. The
+// malformed hops are skipped.
+func Hops(headers Headers) []Hop {
+	lineage := headers[LineageHeader]
+	if lineage == "" {
+		return nil
+	}
+
+	var hops []Hop
+	for _, s := range strings.Split(lineage, ",") {
+		v, err := url.ParseQuery(s)
+		if err != nil || v.Get("topic") == "" {
+			continue
+		}
+		hop := Hop{
+			Service: v.Get("service"),
+			Topic:   v.Get("topic"),
+			SpanID:  v.Get("span"),
+		}
+		if ms, err := strconv.ParseInt(v.Get("at"), 10, 64); err == nil {
+			hop.Time = time.UnixMilli(ms)
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// Lineage return the topics the message of headers went through, the origin first.
+func Lineage(headers Headers) []string {
+	hops := Hops(headers)
+	if len(hops) == 0 {
+		return nil
+	}
+
+	topics := make([]string, len(hops))
+	for i, hop := range hops {
+		topics[i] = hop.Topic
+	}
+	return topics
+}
+
+type lineageKey struct{}
+
+// LineageFromContext return the hops of the message handled with ctx, set
+// by LineageHandler.
+func LineageFromContext(ctx context.Context) []Hop {
+	if ctx == nil {
+		return nil
+	}
+	hops, _ := ctx.Value(lineageKey{}).([]Hop)
+	return hops
+}
+
+// LineageHandler wraps handler to parse the lineage of the messages once,
+// read in the handler with LineageFromContext.
+func LineageHandler(handler Handler) Handler {
+	return func(ctx context.Context, event Event) error {
+		if event != nil && event.Message() != nil {
+			if hops := Hops(event.Message().Headers); len(hops) > 0 {
+				ctx = context.WithValue(ctx, lineageKey{}, hops)
+			}
+		}
+		return handler(ctx, event)
+	}
+}
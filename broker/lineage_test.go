@@ -0,0 +1,72 @@
+package broker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAppendHop(t *testing.T) {
+	spanID := trace.SpanID{0, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  spanID,
+	}))
+
+	headers := Headers{}
+	AppendHop(ctx, headers, "billing", "orders")
+	AppendHop(context.Background(), headers, "", "orders,retry")
+
+	hops := Hops(headers)
+	if assert.Len(t, hops, 2) {
+		assert.Equal(t, "billing", hops[0].Service)
+		assert.Equal(t, "orders", hops[0].Topic)
+		assert.Equal(t, spanID.String(), hops[0].SpanID)
+		assert.WithinDuration(t, time.Now(), hops[0].Time, time.Second)
+
+		assert.Equal(t, "", hops[1].Service)
+		assert.Equal(t, "orders,retry", hops[1].Topic)
+		assert.Equal(t, "", hops[1].SpanID)
+	}
+	assert.Equal(t, []string{"orders", "orders,retry"}, Lineage(headers))
+
+	headers[LineageHeader] += ",%zz"
+	assert.Len(t, Hops(headers), 2, "malformed hop skipped")
+
+	assert.Nil(t, Hops(Headers{}))
+	assert.Nil(t, Lineage(Headers{}))
+}
+
+func TestAppendHop_Max(t *testing.T) {
+	headers := Headers{}
+	for i := 0; i < MaxLineageHops+2; i++ {
+		AppendHop(context.Background(), headers, "", strings.Repeat("t", i+1))
+	}
+
+	topics := Lineage(headers)
+	assert.Len(t, topics, MaxLineageHops)
+	assert.Equal(t, "ttt", topics[0])
+}
+
+func TestLineageHandler(t *testing.T) {
+	pb := &parkBroker{recordBroker: *newRecordBroker("retry")}
+	event := &testEvent{topic: "orders", message: &Message{Headers: Headers{}}}
+	assert.Nil(t, Republish(context.Background(), pb, event, "orders.retry"))
+
+	var hops []Hop
+	handler := LineageHandler(func(ctx context.Context, _ Event) error {
+		hops = LineageFromContext(ctx)
+		return nil
+	})
+	assert.Nil(t, handler(context.Background(), &testEvent{topic: "orders.retry", message: &Message{Headers: pb.headers}}))
+	if assert.Len(t, hops, 1) {
+		assert.Equal(t, "orders", hops[0].Topic)
+	}
+
+	assert.Nil(t, handler(context.Background(), event))
+	assert.Nil(t, hops)
+}
@@ -0,0 +1,202 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// MessageIDHeader is the header read by the default message id extractor.
+const MessageIDHeader = "x-message-id"
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		sampleRate:  1,
+		messageID:   defaultMessageID,
+		attempt:     defaultAttempt,
+		logPublish:  true,
+		logConsumed: true,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Handler is a consumer logging middleware, it logs one line per consumed message.
+func Handler(logger log.Logger, component, topic string, opts ...Option) func(broker.Handler) broker.Handler {
+	o := newOptions(opts...)
+
+	return func(handler broker.Handler) broker.Handler {
+		return func(ctx context.Context, event broker.Event) error {
+			startTime := time.Now()
+
+			err := handler(ctx, event)
+
+			if !o.sampled(err) {
+				return err
+			}
+
+			var (
+				messageID string
+				attempt   int64
+				size      int
+				msg       *broker.Message
+			)
+			if event != nil {
+				messageID = o.messageID(event)
+				attempt = o.attempt(event)
+				if msg = event.Message(); msg != nil {
+					size = bodySize(msg.Body)
+				}
+			}
+
+			code, reason := extractCode(err)
+			level, stack := extractError(err)
+			keyvals := []interface{}{
+				"kind", "consumer",
+				"component", component,
+				"operation", "consume",
+				"topic", topic,
+				"message_id", messageID,
+				"size", size,
+				"attempt", attempt,
+				"code", code,
+				"reason", reason,
+				"stack", stack,
+				"latency", time.Since(startTime).Seconds(),
+			}
+			if msg != nil {
+				keyvals = o.appendMessage(keyvals, msg.Headers, msg.Body)
+			}
+			log.NewHelper(log.WithContext(ctx, logger)).Log(level, keyvals...)
+
+			return err
+		}
+	}
+}
+
+type loggingBroker struct {
+	broker.Broker
+
+	logger log.Logger
+	opts   []Option
+	o      *options
+}
+
+// NewBroker wraps b to log every publish and consumed message.
+func NewBroker(b broker.Broker, logger log.Logger, opts ...Option) broker.Broker {
+	return &loggingBroker{
+		Broker: b,
+		logger: logger,
+		opts:   opts,
+		o:      newOptions(opts...),
+	}
+}
+
+func (b *loggingBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	startTime := time.Now()
+
+	err := b.Broker.Publish(ctx, topic, msg, opts...)
+
+	if !b.o.logPublish || !b.o.sampled(err) {
+		return err
+	}
+
+	code, reason := extractCode(err)
+	level, stack := extractError(err)
+	keyvals := []interface{}{
+		"kind", "producer",
+		"component", b.Name(),
+		"operation", "publish",
+		"topic", topic,
+		"size", bodySize(msg),
+		"code", code,
+		"reason", reason,
+		"stack", stack,
+		"latency", time.Since(startTime).Seconds(),
+	}
+	keyvals = b.o.appendMessage(keyvals, nil, msg)
+	log.NewHelper(log.WithContext(ctx, b.logger)).Log(level, keyvals...)
+
+	return err
+}
+
+func (b *loggingBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	if b.o.logConsumed {
+		handler = Handler(b.logger, b.Name(), topic, b.opts...)(handler)
+	}
+	return b.Broker.Subscribe(topic, handler, binder, opts...)
+}
+
+func (o *options) sampled(err error) bool {
+	return err != nil || o.sampleRate >= 1 || rand.Float64() < o.sampleRate
+}
+
+func (o *options) appendMessage(keyvals []interface{}, headers broker.Headers, body broker.Any) []interface{} {
+	if o.headers && headers != nil {
+		if o.redactor != nil {
+			headers = o.redactor.RedactHeaders(headers)
+		}
+		keyvals = append(keyvals, "headers", headers)
+	}
+	if o.payload {
+		if o.redactor != nil {
+			body = o.redactor.RedactBody(body)
+		}
+		if b, ok := body.([]byte); ok {
+			body = string(b)
+		}
+		keyvals = append(keyvals, "payload", body)
+	}
+	return keyvals
+}
+
+func defaultMessageID(event broker.Event) string {
+	if v, ok := event.(interface{ MessageID() string }); ok {
+		return v.MessageID()
+	}
+	if msg := event.Message(); msg != nil {
+		return msg.GetHeader(MessageIDHeader)
+	}
+	return ""
+}
+
+func defaultAttempt(event broker.Event) int64 {
+	if v, ok := event.(interface{ ConsumedTimes() int64 }); ok {
+		return v.ConsumedTimes()
+	}
+	return 1
+}
+
+func bodySize(body broker.Any) int {
+	switch t := body.(type) {
+	case []byte:
+		return len(t)
+	case string:
+		return len(t)
+	default:
+		return 0
+	}
+}
+
+func extractCode(err error) (int32, string) {
+	if se := errors.FromError(err); se != nil {
+		return se.Code, se.Reason
+	}
+	return 0, ""
+}
+
+// extractError returns the string of the error
+func extractError(err error) (log.Level, string) {
+	if err != nil {
+		return log.LevelError, fmt.Sprintf("%+v", err)
+	}
+	return log.LevelInfo, ""
+}
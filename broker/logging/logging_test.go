@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type testEvent struct {
+	msg *broker.Message
+}
+
+func (e *testEvent) Topic() string            { return "orders" }
+func (e *testEvent) Message() *broker.Message { return e.msg }
+func (e *testEvent) RawMessage() interface{}  { return nil }
+func (e *testEvent) Ack() error               { return nil }
+func (e *testEvent) Error() error             { return nil }
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewStdLogger(&buf)
+
+	event := &testEvent{msg: &broker.Message{
+		Headers: broker.Headers{MessageIDHeader: "id-1"},
+		Body:    []byte("hello"),
+	}}
+
+	h := Handler(logger, "kafka", "orders")(func(context.Context, broker.Event) error {
+		return errors.New("failed")
+	})
+	assert.Error(t, h(context.Background(), event))
+
+	line := buf.String()
+	assert.True(t, strings.HasPrefix(line, "ERROR"))
+	assert.True(t, strings.Contains(line, "operation=consume"))
+	assert.True(t, strings.Contains(line, "message_id=id-1"))
+	assert.True(t, strings.Contains(line, "size=5"))
+	assert.True(t, strings.Contains(line, "attempt=1"))
+}
+
+func TestHandler_Sampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewStdLogger(&buf)
+
+	h := Handler(logger, "kafka", "orders", WithSampleRate(0))(func(context.Context, broker.Event) error {
+		return nil
+	})
+	assert.Nil(t, h(context.Background(), &testEvent{msg: &broker.Message{}}))
+	assert.Empty(t, buf.String())
+}
+
+func TestHandler_Redaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewStdLogger(&buf)
+
+	h := Handler(logger, "kafka", "orders",
+		WithHeaders(),
+		WithPayload(),
+		WithRedactor(broker.NewKeyRedactor("token", "card")),
+	)(func(context.Context, broker.Event) error {
+		return nil
+	})
+
+	assert.Nil(t, h(context.Background(), &testEvent{msg: &broker.Message{
+		Headers: broker.Headers{"token": "secret"},
+		Body:    []byte(`{"card":"4111"}`),
+	}}))
+
+	line := buf.String()
+	assert.False(t, strings.Contains(line, "secret"))
+	assert.False(t, strings.Contains(line, "4111"))
+	assert.True(t, strings.Contains(line, broker.RedactedValue))
+}
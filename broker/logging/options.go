@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(o *options)
+
+type options struct {
+	sampleRate  float64
+	messageID   func(event broker.Event) string
+	attempt     func(event broker.Event) int64
+	logPublish  bool
+	logConsumed bool
+	headers     bool
+	payload     bool
+	redactor    broker.Redactor
+}
+
+// WithSampleRate log only a fraction of the successful messages, failures are always logged.
+func WithSampleRate(rate float64) Option {
+	return func(o *options) {
+		o.sampleRate = rate
+	}
+}
+
+// WithMessageID set how the message id is read from a consumed event.
+func WithMessageID(fn func(event broker.Event) string) Option {
+	return func(o *options) {
+		o.messageID = fn
+	}
+}
+
+// WithAttempt set how the delivery attempt is read from a consumed event.
+func WithAttempt(fn func(event broker.Event) int64) Option {
+	return func(o *options) {
+		o.attempt = fn
+	}
+}
+
+// WithoutPublish do not log publishes.
+func WithoutPublish() Option {
+	return func(o *options) {
+		o.logPublish = false
+	}
+}
+
+// WithoutConsume do not log consumed messages.
+func WithoutConsume() Option {
+	return func(o *options) {
+		o.logConsumed = false
+	}
+}
+
+// WithHeaders log the message headers.
+func WithHeaders() Option {
+	return func(o *options) {
+		o.headers = true
+	}
+}
+
+// WithPayload log the message body.
+func WithPayload() Option {
+	return func(o *options) {
+		o.payload = true
+	}
+}
+
+// WithRedactor mask the logged headers and payload.
+func WithRedactor(r broker.Redactor) Option {
+	return func(o *options) {
+		o.redactor = r
+	}
+}
@@ -21,3 +21,9 @@ func (m Message) GetHeader(key string) string {
 	}
 	return m.Headers[key]
 }
+
+// ContentType return the content type the body was decoded with, set when the
+// broker negotiates content.
+func (m Message) ContentType() string {
+	return m.GetHeader(ContentTypeHeader)
+}
@@ -0,0 +1,102 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const meterName = "github.com/tx7do/kratos-transport/broker"
+
+// Metrics records the OpenTelemetry messaging semantic-convention metrics,
+// a nil *Metrics records nothing so drivers can call it unconditionally.
+type Metrics struct {
+	system string
+
+	publishDuration metric.Float64Histogram
+	processDuration metric.Float64Histogram
+	consumed        metric.Int64Counter
+}
+
+// NewMetrics returns nil when provider is nil.
+func NewMetrics(system string, provider metric.MeterProvider) *Metrics {
+	if provider == nil {
+		return nil
+	}
+
+	meter := provider.Meter(meterName)
+
+	m := &Metrics{system: system}
+
+	var err error
+	if m.publishDuration, err = meter.Float64Histogram("messaging.publish.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Measures the duration of publish operation."),
+	); err != nil {
+		otel.Handle(err)
+	}
+	if m.processDuration, err = meter.Float64Histogram("messaging.process.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Measures the duration of process operation."),
+	); err != nil {
+		otel.Handle(err)
+	}
+	if m.consumed, err = meter.Int64Counter("messaging.client.consumed.messages",
+		metric.WithUnit("{message}"),
+		metric.WithDescription("Measures the number of consumed messages."),
+	); err != nil {
+		otel.Handle(err)
+	}
+
+	return m
+}
+
+// RecordPublish records a publish to topic started at start.
+func (m *Metrics) RecordPublish(ctx context.Context, topic string, start time.Time, err error) {
+	if m == nil || m.publishDuration == nil {
+		return
+	}
+
+	m.publishDuration.Record(ctx, time.Since(start).Seconds(),
+		metric.WithAttributes(m.attributes("publish", topic, err)...),
+	)
+}
+
+// Handler wraps handler to record the process duration and consumed messages of topic.
+func (m *Metrics) Handler(topic string, handler Handler) Handler {
+	if m == nil {
+		return handler
+	}
+
+	return func(ctx context.Context, event Event) error {
+		start := time.Now()
+
+		err := handler(ctx, event)
+
+		attrs := metric.WithAttributes(m.attributes("process", topic, err)...)
+		if m.processDuration != nil {
+			m.processDuration.Record(ctx, time.Since(start).Seconds(), attrs)
+		}
+		if m.consumed != nil {
+			m.consumed.Add(ctx, 1, attrs)
+		}
+
+		return err
+	}
+}
+
+func (m *Metrics) attributes(operation, topic string, err error) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", m.system),
+		attribute.String("messaging.operation.name", operation),
+		attribute.String("messaging.destination.name", topic),
+	}
+	if err != nil {
+		attrs = append(attrs, attribute.String("error.type", fmt.Sprintf("%T", err)))
+	}
+	return attrs
+}
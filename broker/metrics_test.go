@@ -0,0 +1,80 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+type recordMeterProvider struct {
+	noop.MeterProvider
+	meter *recordMeter
+}
+
+func (p *recordMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return p.meter
+}
+
+type recordMeter struct {
+	noop.Meter
+	histograms map[string]*recordHistogram
+	counters   map[string]*recordCounter
+}
+
+func (m *recordMeter) Float64Histogram(name string, _ ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	h := &recordHistogram{}
+	m.histograms[name] = h
+	return h, nil
+}
+
+func (m *recordMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	c := &recordCounter{}
+	m.counters[name] = c
+	return c, nil
+}
+
+type recordHistogram struct {
+	noop.Float64Histogram
+	count int
+}
+
+func (h *recordHistogram) Record(context.Context, float64, ...metric.RecordOption) {
+	h.count++
+}
+
+type recordCounter struct {
+	noop.Int64Counter
+	value int64
+}
+
+func (c *recordCounter) Add(_ context.Context, incr int64, _ ...metric.AddOption) {
+	c.value += incr
+}
+
+func TestMetrics(t *testing.T) {
+	assert.Nil(t, NewMetrics("test", nil))
+
+	meter := &recordMeter{
+		histograms: map[string]*recordHistogram{},
+		counters:   map[string]*recordCounter{},
+	}
+	m := NewMetrics("test", &recordMeterProvider{meter: meter})
+
+	m.RecordPublish(context.Background(), "orders", time.Now(), nil)
+
+	handler := m.Handler("orders", func(context.Context, Event) error { return errors.New("failed") })
+	assert.Error(t, handler(context.Background(), nil))
+
+	assert.Equal(t, 1, meter.histograms["messaging.publish.duration"].count)
+	assert.Equal(t, 1, meter.histograms["messaging.process.duration"].count)
+	assert.Equal(t, int64(1), meter.counters["messaging.client.consumed.messages"].value)
+
+	var nilMetrics *Metrics
+	nilMetrics.RecordPublish(context.Background(), "orders", time.Now(), nil)
+	assert.Nil(t, nilMetrics.Handler("orders", nil))
+}
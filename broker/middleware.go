@@ -0,0 +1,119 @@
+package broker
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+var _ transport.Transporter = &Transport{}
+
+// Transport is the transport.Transporter of a consumed message, put in the
+// context of its handler by MiddlewareHandler. The operation is the topic
+// and the request header reads the headers of the message.
+type Transport struct {
+	kind        transport.Kind
+	operation   string
+	reqHeader   headerCarrier
+	replyHeader headerCarrier
+}
+
+// Kind returns the transport kind.
+func (tr *Transport) Kind() transport.Kind {
+	return tr.kind
+}
+
+// Endpoint returns the transport endpoint, none for a message.
+func (tr *Transport) Endpoint() string {
+	return ""
+}
+
+// Operation returns the transport operation.
+func (tr *Transport) Operation() string {
+	return tr.operation
+}
+
+// RequestHeader returns the request header.
+func (tr *Transport) RequestHeader() transport.Header {
+	return tr.reqHeader
+}
+
+// ReplyHeader returns the reply header.
+func (tr *Transport) ReplyHeader() transport.Header {
+	return tr.replyHeader
+}
+
+type headerCarrier Headers
+
+// Get returns the value associated with the passed key.
+func (hc headerCarrier) Get(key string) string {
+	return hc[key]
+}
+
+// Set stores the key-value pair.
+func (hc headerCarrier) Set(key string, value string) {
+	hc[key] = value
+}
+
+// Add append value to key-values pair, the headers holding one value per
+// key it replaces the current one.
+func (hc headerCarrier) Add(key string, value string) {
+	hc[key] = value
+}
+
+// Keys lists the keys stored in this carrier.
+func (hc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range hc {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Values returns a slice of values associated with the passed key.
+func (hc headerCarrier) Values(key string) []string {
+	if v, ok := hc[key]; ok {
+		return []string{v}
+	}
+	return nil
+}
+
+// MiddlewareHandler wraps the handler of topic with the kratos middlewares
+// ms, so the stack of the HTTP and gRPC servers, e.g. recovery.Recovery(),
+// ratelimit.Server(), metrics.Server() or validate.Validator(), applies to
+// the messages too. The middlewares see the decoded body as the request, no
+// reply, and a Transport of kind in transport.FromServerContext.
+//
+// An error of a middleware, e.g. the rejection of the rate limiter or the
+// panic recovered, is returned like one of handler: the message is left
+// unsettled so the broker redelivers it.
+func MiddlewareHandler(kind transport.Kind, topic string, handler Handler, ms ...middleware.Middleware) Handler {
+	if len(ms) == 0 {
+		return handler
+	}
+
+	return func(ctx context.Context, event Event) error {
+		var req interface{}
+		headers := Headers{}
+		if event != nil && event.Message() != nil {
+			req = event.Message().Body
+			if event.Message().Headers != nil {
+				headers = event.Message().Headers
+			}
+		}
+
+		ctx = transport.NewServerContext(ctx, &Transport{
+			kind:        kind,
+			operation:   topic,
+			reqHeader:   headerCarrier(headers),
+			replyHeader: headerCarrier{},
+		})
+
+		next := middleware.Chain(ms...)(func(ctx context.Context, _ interface{}) (interface{}, error) {
+			return nil, handler(ctx, event)
+		})
+		_, err := next(ctx, req)
+		return err
+	}
+}
@@ -0,0 +1,72 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	kerrors "github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/recovery"
+	"github.com/go-kratos/kratos/v2/middleware/validate"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOrder struct {
+	ID string
+}
+
+func (o *testOrder) Validate() error {
+	if o.ID == "" {
+		return errors.New("missing id")
+	}
+	return nil
+}
+
+func TestMiddlewareHandler(t *testing.T) {
+	var operation, tenant string
+	var kind transport.Kind
+	inspect := func(next middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				kind, operation = tr.Kind(), tr.Operation()
+				tenant = tr.RequestHeader().Get("x-tenant")
+			}
+			return next(ctx, req)
+		}
+	}
+
+	handled := 0
+	handler := MiddlewareHandler("kafka", "orders", func(context.Context, Event) error {
+		handled++
+		return nil
+	}, recovery.Recovery(), inspect, validate.Validator())
+
+	event := &testEvent{topic: "orders", message: &Message{
+		Headers: Headers{"x-tenant": "acme"},
+		Body:    &testOrder{ID: "1"},
+	}}
+	assert.Nil(t, handler(context.Background(), event))
+	assert.Equal(t, 1, handled)
+	assert.Equal(t, transport.Kind("kafka"), kind)
+	assert.Equal(t, "orders", operation)
+	assert.Equal(t, "acme", tenant)
+
+	event.message.Body = &testOrder{}
+	err := handler(context.Background(), event)
+	assert.True(t, kerrors.IsBadRequest(err))
+	assert.Equal(t, 1, handled, "rejected by the validator")
+}
+
+func TestMiddlewareHandler_Recovery(t *testing.T) {
+	handler := MiddlewareHandler("kafka", "orders", func(context.Context, Event) error {
+		panic("boom")
+	}, recovery.Recovery())
+
+	err := handler(context.Background(), &testEvent{topic: "orders", message: &Message{}})
+	assert.ErrorIs(t, err, recovery.ErrUnknownRequest)
+
+	plain := func(context.Context, Event) error { return nil }
+	assert.Nil(t, MiddlewareHandler("kafka", "orders", plain)(context.Background(), nil))
+}
@@ -0,0 +1,161 @@
+package mirror
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Headers added to the copies published by TopicSink.
+const (
+	TopicHeader     = "x-mirror-topic"
+	DirectionHeader = "x-mirror-direction"
+	ErrorHeader     = "x-mirror-error"
+)
+
+type Direction string
+
+const (
+	DirectionPublish Direction = "publish"
+	DirectionConsume Direction = "consume"
+)
+
+// Message is the copy of a published or consumed message handed to a sink.
+type Message struct {
+	Direction Direction
+	Topic     string
+	Headers   broker.Headers
+	Body      broker.Any
+	// Err is the error of the publish or of the handler.
+	Err error
+}
+
+// Sink receives the mirrored messages, its errors are logged and never
+// reach the publisher nor the handler.
+type Sink func(ctx context.Context, m *Message) error
+
+// TopicSink publish the mirrored messages to topic on b, with their
+// original headers, the x-mirror-* ones and a lineage hop of the mirrored topic.
+// The copies are told apart by their headers, so they are not mirrored again:
+// a broker whose messages have none, see broker.CarriesHeaders, fails every
+// copy with broker.ErrHeadersUnsupported.
+func TopicSink(b broker.Broker, topic string, opts ...broker.PublishOption) Sink {
+	return func(ctx context.Context, m *Message) error {
+		if err := broker.RequireHeaders(b); err != nil {
+			return err
+		}
+
+		headers := make(broker.Headers, len(m.Headers)+3)
+		for k, v := range m.Headers {
+			headers[k] = v
+		}
+		headers[TopicHeader] = m.Topic
+		headers[DirectionHeader] = string(m.Direction)
+		if m.Err != nil {
+			headers[ErrorHeader] = m.Err.Error()
+		}
+		broker.AppendHop(ctx, headers, b.Options().ServiceName, m.Topic)
+		return b.Publish(ctx, topic, m.Body, append([]broker.PublishOption{broker.WithHeaders(headers)}, opts...)...)
+	}
+}
+
+// Handler is a consumer middleware copying a sample of the consumed messages to sink.
+func Handler(sink Sink, opts ...Option) func(broker.Handler) broker.Handler {
+	o := newOptions(opts...)
+
+	return func(handler broker.Handler) broker.Handler {
+		return func(ctx context.Context, event broker.Event) error {
+			err := handler(ctx, event)
+
+			if event == nil || event.Message() == nil {
+				return err
+			}
+			msg := event.Message()
+			o.mirror(ctx, sink, &Message{
+				Direction: DirectionConsume,
+				Topic:     event.Topic(),
+				Headers:   msg.Headers,
+				Body:      msg.Body,
+				Err:       err,
+			})
+
+			return err
+		}
+	}
+}
+
+type mirrorBroker struct {
+	broker.Broker
+
+	sink Sink
+	opts []Option
+	o    *options
+}
+
+// NewBroker wraps b to copy a sample of the published and consumed messages
+// to sink. A sink publishing to b itself must use the unwrapped broker.
+func NewBroker(b broker.Broker, sink Sink, opts ...Option) broker.Broker {
+	return &mirrorBroker{
+		Broker: b,
+		sink:   sink,
+		opts:   opts,
+		o:      newOptions(opts...),
+	}
+}
+
+func (b *mirrorBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	err := b.Broker.Publish(ctx, topic, msg, opts...)
+
+	if b.o.mirrorPublish {
+		b.o.mirror(ctx, b.sink, &Message{
+			Direction: DirectionPublish,
+			Topic:     topic,
+			Headers:   broker.NewPublishOptions(opts...).Headers,
+			Body:      msg,
+			Err:       err,
+		})
+	}
+
+	return err
+}
+
+func (b *mirrorBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	if b.o.mirrorConsume {
+		handler = Handler(b.sink, b.opts...)(handler)
+	}
+	return b.Broker.Subscribe(topic, handler, binder, opts...)
+}
+
+func (o *options) selected(m *Message) bool {
+	for _, match := range o.matchers {
+		if match(m) {
+			return true
+		}
+	}
+	if o.sampleRate < 0 {
+		return len(o.matchers) == 0
+	}
+	return o.sampleRate >= 1 || rand.Float64() < o.sampleRate
+}
+
+func (o *options) mirror(ctx context.Context, sink Sink, m *Message) {
+	// never mirror a copy, the debug topic may be consumed through a mirrored broker.
+	if _, ok := m.Headers[TopicHeader]; ok {
+		return
+	}
+	if !o.selected(m) {
+		return
+	}
+
+	if o.redactor != nil {
+		m.Headers = o.redactor.RedactHeaders(m.Headers)
+		m.Body = o.redactor.RedactBody(m.Body)
+	}
+
+	if err := sink(ctx, m); err != nil {
+		log.Errorf("[mirror] mirror message of [%s] failed: %v", m.Topic, err)
+	}
+}
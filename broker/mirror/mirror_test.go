@@ -0,0 +1,131 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func newFakeBroker(t *testing.T) *mocks.FakeBroker {
+	b := mocks.NewFakeBroker()
+	assert.NoError(t, b.Connect())
+	return b
+}
+
+func deliver(b *mocks.FakeBroker, topic string, headers broker.Headers, body broker.Any) error {
+	_, err := b.Deliver(context.Background(), topic, body, headers)
+	return err
+}
+
+func TestNewBroker_TopicSink(t *testing.T) {
+	inner := newFakeBroker(t)
+	b := NewBroker(inner, TopicSink(inner, "debug"))
+
+	assert.NoError(t, b.Publish(context.Background(), "orders", "o1", broker.WithHeaders(broker.Headers{"id": "1"})))
+	assert.Len(t, inner.PublishedTo("orders"), 1)
+	assert.Len(t, inner.PublishedTo("debug"), 1)
+
+	m := inner.PublishedTo("debug")[0]
+	assert.Equal(t, "o1", m.Body)
+	assert.Equal(t, []string{"orders"}, broker.Lineage(m.Headers))
+	delete(m.Headers, broker.LineageHeader)
+	assert.Equal(t, broker.Headers{
+		"id":            "1",
+		TopicHeader:     "orders",
+		DirectionHeader: "publish",
+	}, m.Headers)
+
+	failed := errors.New("boom")
+	_, err := b.Subscribe("orders", func(context.Context, broker.Event) error { return failed }, nil)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, deliver(inner, "orders", broker.Headers{"id": "2"}, "o2"), failed)
+	assert.Len(t, inner.PublishedTo("debug"), 2)
+
+	m = inner.PublishedTo("debug")[1]
+	assert.Equal(t, "o2", m.Body)
+	assert.Equal(t, "consume", m.Headers[DirectionHeader])
+	assert.Equal(t, "boom", m.Headers[ErrorHeader])
+
+	// the copies consumed through the mirrored broker are not mirrored again.
+	_, err = b.Subscribe("debug", func(context.Context, broker.Event) error { return nil }, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, deliver(inner, "debug", m.Headers, m.Body))
+	assert.Len(t, inner.PublishedTo("debug"), 2)
+}
+
+func TestNewBroker_Selection(t *testing.T) {
+	inner := newFakeBroker(t)
+
+	var mirrored []*Message
+	sink := func(_ context.Context, m *Message) error {
+		mirrored = append(mirrored, m)
+		return nil
+	}
+	b := NewBroker(inner, sink,
+		WithSampleRate(0),
+		WithHeaderMatch("x-debug", "on*"),
+		WithoutConsume(),
+	)
+
+	assert.NoError(t, b.Publish(context.Background(), "orders", "o1"))
+	assert.NoError(t, b.Publish(context.Background(), "orders", "o2", broker.WithHeaders(broker.Headers{"x-debug": "off"})))
+	assert.NoError(t, b.Publish(context.Background(), "orders", "o3", broker.WithHeaders(broker.Headers{"x-debug": "on"})))
+	assert.Len(t, mirrored, 1)
+	assert.Equal(t, "o3", mirrored[0].Body)
+
+	_, err := b.Subscribe("orders", func(context.Context, broker.Event) error { return nil }, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, deliver(inner, "orders", broker.Headers{"x-debug": "on"}, "o4"))
+	assert.Len(t, mirrored, 1)
+}
+
+func TestNewBroker_Matcher(t *testing.T) {
+	inner := newFakeBroker(t)
+
+	var mirrored []*Message
+	sink := func(_ context.Context, m *Message) error {
+		mirrored = append(mirrored, m)
+		return nil
+	}
+	b := NewBroker(inner, sink, WithMatcher(func(m *Message) bool { return m.Topic == "payments" }))
+
+	assert.NoError(t, b.Publish(context.Background(), "orders", "o1"))
+	assert.NoError(t, b.Publish(context.Background(), "payments", "p1"))
+	assert.Len(t, mirrored, 1)
+	assert.Equal(t, "payments", mirrored[0].Topic)
+}
+
+func TestHandler_Redactor(t *testing.T) {
+	var mirrored *Message
+	sink := func(_ context.Context, m *Message) error {
+		mirrored = m
+		return errors.New("sink down")
+	}
+
+	handler := Handler(sink, WithRedactor(broker.NewKeyRedactor("token", "card")))(
+		func(context.Context, broker.Event) error { return nil },
+	)
+
+	body := map[string]interface{}{"card": "4111", "amount": 10}
+	err := handler(context.Background(), mocks.NewEvent("orders", body, broker.Headers{"token": "secret"}))
+	assert.NoError(t, err)
+
+	assert.Equal(t, broker.RedactedValue, mirrored.Headers["token"])
+	assert.Equal(t, broker.RedactedValue, mirrored.Body.(map[string]interface{})["card"])
+	assert.Equal(t, "4111", body["card"])
+}
+
+func TestTopicSink_Headerless(t *testing.T) {
+	inner := mocks.NewHeaderlessBroker()
+	assert.NoError(t, inner.Connect())
+	sink := TopicSink(inner, "debug")
+
+	err := sink(context.Background(), &Message{Topic: "orders", Direction: "publish", Body: "o1"})
+	assert.ErrorIs(t, err, broker.ErrHeadersUnsupported)
+	assert.Empty(t, inner.PublishedTo("debug"))
+}
@@ -0,0 +1,78 @@
+package mirror
+
+import (
+	"path"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(o *options)
+
+type options struct {
+	sampleRate    float64
+	matchers      []func(m *Message) bool
+	mirrorPublish bool
+	mirrorConsume bool
+	redactor      broker.Redactor
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		sampleRate:    -1,
+		mirrorPublish: true,
+		mirrorConsume: true,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithSampleRate mirror a fraction of the messages. Without it every
+// message is mirrored, unless a matcher is set.
+func WithSampleRate(rate float64) Option {
+	return func(o *options) {
+		o.sampleRate = rate
+	}
+}
+
+// WithMatcher always mirror the messages fn accepts, whatever the sample rate.
+func WithMatcher(fn func(m *Message) bool) Option {
+	return func(o *options) {
+		o.matchers = append(o.matchers, fn)
+	}
+}
+
+// WithHeaderMatch always mirror the messages whose header key matches
+// pattern, in path.Match syntax, e.g. WithHeaderMatch("x-debug", "1").
+func WithHeaderMatch(key, pattern string) Option {
+	return WithMatcher(func(m *Message) bool {
+		v, ok := m.Headers[key]
+		if !ok {
+			return false
+		}
+		matched, _ := path.Match(pattern, v)
+		return matched
+	})
+}
+
+// WithoutPublish do not mirror publishes.
+func WithoutPublish() Option {
+	return func(o *options) {
+		o.mirrorPublish = false
+	}
+}
+
+// WithoutConsume do not mirror consumed messages.
+func WithoutConsume() Option {
+	return func(o *options) {
+		o.mirrorConsume = false
+	}
+}
+
+// WithRedactor mask the headers and body of the mirrored copies.
+func WithRedactor(r broker.Redactor) Option {
+	return func(o *options) {
+		o.redactor = r
+	}
+}
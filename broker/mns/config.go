@@ -0,0 +1,162 @@
+package mns
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed mns configuration of a broker, the fields are the
+// ones set one by one with the broker options of the package.
+type Config struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	// QueueQPS limits the requests per second of every queue and topic
+	// client, no limit when zero.
+	QueueQPS int32
+}
+
+// WithConfig set the whole configuration at once, the zero fields are left
+// unset. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		var opts []broker.Option
+		if c.AccessKeyID != "" || c.AccessKeySecret != "" {
+			opts = append(opts, WithAccessKey(c.AccessKeyID, c.AccessKeySecret))
+		}
+		if c.QueueQPS != 0 {
+			opts = append(opts, WithQueueQPS(c.QueueQPS))
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	var c Config
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(accessKeyKey{}).(*accessKeyRecord); ok {
+		c.AccessKeyID, c.AccessKeySecret = v.accessKeyId, v.accessKeySecret
+	}
+	c.QueueQPS, _ = opts.Context.Value(queueQpsKey{}).(int32)
+
+	return c
+}
+
+// SubscribeConfig is the typed mns configuration of a subscription.
+type SubscribeConfig struct {
+	// FilterTag requires broker.WithQueueName, see WithFilterTag.
+	FilterTag   string
+	WaitSeconds int64
+	BatchSize   int32
+	// RetryDelay is negative to leave a failed message invisible until the
+	// visibility timeout of the queue.
+	RetryDelay time.Duration
+}
+
+// DefaultSubscribeConfig return the configuration of a subscription without
+// options.
+func DefaultSubscribeConfig() SubscribeConfig {
+	return SubscribeConfig{
+		WaitSeconds: defaultWaitSeconds,
+		BatchSize:   defaultBatchSize,
+		RetryDelay:  -1,
+	}
+}
+
+// WithSubscribeConfig set the whole subscription configuration at once, c
+// should start from DefaultSubscribeConfig.
+func WithSubscribeConfig(c SubscribeConfig) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		opts := []broker.SubscribeOption{
+			WithWaitSeconds(c.WaitSeconds),
+			WithBatchSize(c.BatchSize),
+		}
+		if c.FilterTag != "" {
+			opts = append(opts, WithFilterTag(c.FilterTag))
+		}
+		if c.RetryDelay >= 0 {
+			opts = append(opts, WithRetryDelay(c.RetryDelay))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// SubscribeConfigFromOptions return the subscription configuration set in
+// the context of opts, a wait or batch size not above zero keeps the default.
+func SubscribeConfigFromOptions(opts broker.SubscribeOptions) SubscribeConfig {
+	c := DefaultSubscribeConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	c.FilterTag, _ = opts.Context.Value(filterTagKey{}).(string)
+	if v, ok := opts.Context.Value(waitSecondsKey{}).(int64); ok && v > 0 {
+		c.WaitSeconds = v
+	}
+	if v, ok := opts.Context.Value(batchSizeKey{}).(int32); ok && v > 0 {
+		c.BatchSize = v
+	}
+	if v, ok := opts.Context.Value(retryDelayKey{}).(int64); ok {
+		c.RetryDelay = time.Duration(v) * time.Second
+	}
+
+	return c
+}
+
+// PublishConfig is the typed mns configuration of a publishing, the zero
+// fields are left unset.
+type PublishConfig struct {
+	// PublishTopic publishes to the MNS topic instead of the queue, the tag
+	// only applies to a topic, the delay and priority only to a queue.
+	PublishTopic bool
+	MessageTag   string
+	Delay        time.Duration
+	Priority     int64
+}
+
+// WithPublishConfig set the whole publishing configuration at once.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		var opts []broker.PublishOption
+		if c.PublishTopic {
+			opts = append(opts, WithPublishTopic())
+		}
+		if c.MessageTag != "" {
+			opts = append(opts, WithMessageTag(c.MessageTag))
+		}
+		if c.Delay != 0 {
+			opts = append(opts, WithDelay(c.Delay))
+		}
+		if c.Priority != 0 {
+			opts = append(opts, WithPriority(c.Priority))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	var c PublishConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.PublishTopic, _ = opts.Context.Value(publishTopicKey{}).(bool)
+	c.MessageTag, _ = opts.Context.Value(messageTagKey{}).(string)
+	if v, ok := opts.Context.Value(delaySecondsKey{}).(int64); ok {
+		c.Delay = time.Duration(v) * time.Second
+	}
+	c.Priority, _ = opts.Context.Value(priorityKey{}).(int64)
+
+	return c
+}
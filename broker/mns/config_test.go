@@ -0,0 +1,65 @@
+package mns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, Config{}, ConfigFromOptions(broker.NewOptions()))
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithAccessKey("id", "secret"),
+		WithQueueQPS(100),
+	))
+
+	c := Config{
+		AccessKeyID:     "id",
+		AccessKeySecret: "secret",
+		QueueQPS:        100,
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c), WithQueueQPS(10)))
+	assert.Equal(t, int32(10), overridden.QueueQPS)
+}
+
+func TestSubscribeConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultSubscribeConfig(), SubscribeConfigFromOptions(broker.NewSubscribeOptions()))
+
+	legacy := SubscribeConfigFromOptions(broker.NewSubscribeOptions(
+		WithFilterTag("orders"),
+		WithBatchSize(16),
+		WithRetryDelay(10*time.Second),
+	))
+
+	c := DefaultSubscribeConfig()
+	c.FilterTag = "orders"
+	c.BatchSize = 16
+	c.RetryDelay = 10 * time.Second
+
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c))))
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	assert.Equal(t, PublishConfig{}, PublishConfigFromOptions(broker.PublishOptions{}))
+
+	legacy := PublishConfigFromOptions(broker.NewPublishOptions(
+		WithDelay(time.Minute),
+		WithPriority(1),
+	))
+
+	c := PublishConfig{
+		Delay:    time.Minute,
+		Priority: 1,
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+}
@@ -0,0 +1,11 @@
+package mns
+
+// well-known headers filled from the received message.
+const (
+	HeaderMessageID        = "x-mns-message-id"
+	HeaderEnqueueTime      = "x-mns-enqueue-time"
+	HeaderFirstDequeueTime = "x-mns-first-dequeue-time"
+	HeaderNextVisibleTime  = "x-mns-next-visible-time"
+	HeaderDequeueCount     = "x-mns-dequeue-count"
+	HeaderPriority         = "x-mns-priority"
+)
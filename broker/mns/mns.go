@@ -0,0 +1,544 @@
+package mns
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semConv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+
+	ali_mns "github.com/aliyun/aliyun-mns-go-sdk"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/tracing"
+)
+
+const (
+	defaultWaitSeconds = 3
+	defaultBatchSize   = 1
+
+	// defaultMaxMessageSize is the default MaximumMessageSize of a queue.
+	defaultMaxMessageSize = 64 * 1024
+)
+
+type mnsBroker struct {
+	sync.RWMutex
+
+	options broker.Options
+
+	config Config
+
+	connected bool
+
+	client ali_mns.MNSClient
+	queues map[string]ali_mns.AliMNSQueue
+	topics map[string]ali_mns.AliMNSTopic
+
+	subscribers *broker.SubscriberSyncMap
+
+	producerTracer *tracing.Tracer
+	consumerTracer *tracing.Tracer
+
+	metrics *broker.Metrics
+}
+
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.NewOptionsAndApply(append([]broker.Option{broker.WithMaxMessageSize(defaultMaxMessageSize)}, opts...)...)
+	return &mnsBroker{
+		options:     options,
+		queues:      make(map[string]ali_mns.AliMNSQueue),
+		topics:      make(map[string]ali_mns.AliMNSTopic),
+		subscribers: broker.NewSubscriberSyncMap(),
+	}
+}
+
+func (b *mnsBroker) Name() string {
+	return "mns"
+}
+
+// CarriesHeaders is false, MNS messages have no user properties.
+func (b *mnsBroker) CarriesHeaders() bool {
+	return false
+}
+
+// Address returns the endpoint, such as http://<account id>.mns.cn-hangzhou.aliyuncs.com.
+func (b *mnsBroker) Address() string {
+	if len(b.options.Addrs) > 0 {
+		return b.options.Addrs[0]
+	}
+	return ""
+}
+
+func (b *mnsBroker) Options() broker.Options {
+	return b.options
+}
+
+func (b *mnsBroker) Init(opts ...broker.Option) error {
+	b.options.Apply(opts...)
+
+	b.config = ConfigFromOptions(b.options)
+
+	if len(b.options.Tracings) > 0 {
+		b.producerTracer = tracing.NewTracer(trace.SpanKindProducer, "mns-producer", b.options.Tracings...)
+		b.consumerTracer = tracing.NewTracer(trace.SpanKindConsumer, "mns-consumer", b.options.Tracings...)
+	}
+
+	b.metrics = broker.NewMetrics("mns", b.options.MeterProvider)
+
+	return nil
+}
+
+func (b *mnsBroker) Connect() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.connected {
+		return nil
+	}
+
+	endpoint := b.Address()
+	if endpoint == "" {
+		return errors.New("endpoint is empty")
+	}
+
+	b.client = ali_mns.NewAliMNSClient(endpoint, b.config.AccessKeyID, b.config.AccessKeySecret)
+	b.connected = true
+
+	return nil
+}
+
+func (b *mnsBroker) Disconnect() error {
+	b.RLock()
+	if !b.connected {
+		b.RUnlock()
+		return nil
+	}
+	b.RUnlock()
+
+	b.subscribers.Clear()
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.client = nil
+	b.queues = make(map[string]ali_mns.AliMNSQueue)
+	b.topics = make(map[string]ali_mns.AliMNSTopic)
+
+	b.connected = false
+	return nil
+}
+
+func (b *mnsBroker) qpsLimit() []int32 {
+	if b.config.QueueQPS > 0 {
+		return []int32{b.config.QueueQPS}
+	}
+	return nil
+}
+
+func (b *mnsBroker) getQueue(name string) (ali_mns.AliMNSQueue, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.client == nil {
+		return nil, errors.New("client is nil")
+	}
+
+	q, ok := b.queues[name]
+	if !ok {
+		q = ali_mns.NewMNSQueue(name, b.client, b.qpsLimit()...)
+		b.queues[name] = q
+	}
+	return q, nil
+}
+
+func (b *mnsBroker) getTopic(name string) (ali_mns.AliMNSTopic, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.client == nil {
+		return nil, errors.New("client is nil")
+	}
+
+	t, ok := b.topics[name]
+	if !ok {
+		t = ali_mns.NewMNSTopic(name, b.client, b.qpsLimit()...)
+		b.topics[name] = t
+	}
+	return t, nil
+}
+
+// Publish sends to the queue named topic, or to the MNS topic with WithPublishTopic.
+func (b *mnsBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	buf, options, err := b.newMessage(ctx, topic, msg, opts)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = b.publish(topic, buf, options)
+	b.metrics.RecordPublish(ctx, topic, start, err)
+
+	return err
+}
+
+// newMessage encode msg and run the publish interceptors, it returns the
+// body to send to topic with the options of the publish. The publishes with
+// headers are refused, MNS has no room for them.
+func (b *mnsBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) ([]byte, broker.PublishOptions, error) {
+	options := broker.PublishOptions{
+		Context: ctx,
+	}
+
+	buf, err := broker.Marshal(b.options.Codec, msg)
+	if err != nil {
+		return nil, options, err
+	}
+	buf, opts, err = b.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return nil, options, err
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+	if err = broker.RejectHeaders(topic, options.Headers); err != nil {
+		return nil, options, err
+	}
+
+	return buf, options, nil
+}
+
+func (b *mnsBroker) publish(topic string, msg []byte, options broker.PublishOptions) error {
+	span := b.startProducerSpan(options.Context, topic)
+
+	var messageId string
+	var err error
+	c := PublishConfigFromOptions(options)
+	if c.PublishTopic {
+		messageId, err = b.publishTopic(c, topic, msg)
+	} else {
+		messageId, err = b.sendQueue(c, topic, msg)
+	}
+
+	b.finishProducerSpan(span, messageId, err)
+
+	return err
+}
+
+func (b *mnsBroker) sendQueue(c PublishConfig, name string, msg []byte) (string, error) {
+	q, err := b.getQueue(name)
+	if err != nil {
+		return "", err
+	}
+
+	req := ali_mns.MessageSendRequest{
+		MessageBody:  string(msg),
+		DelaySeconds: int64(c.Delay / time.Second),
+		Priority:     c.Priority,
+	}
+
+	resp, err := q.SendMessage(req)
+	if err != nil {
+		return "", err
+	}
+	return resp.MessageId, nil
+}
+
+func (b *mnsBroker) publishTopic(c PublishConfig, name string, msg []byte) (string, error) {
+	t, err := b.getTopic(name)
+	if err != nil {
+		return "", err
+	}
+
+	req := ali_mns.MessagePublishRequest{
+		MessageBody: string(msg),
+		MessageTag:  c.MessageTag,
+	}
+
+	resp, err := t.PublishMessage(req)
+	if err != nil {
+		return "", err
+	}
+	return resp.MessageId, nil
+}
+
+// Subscribe receives from the queue named topic. With broker.WithQueueName,
+// topic is an MNS topic that the named queue is subscribed to, the messages
+// pushed to the queue are then received from it.
+func (b *mnsBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	options := broker.SubscribeOptions{
+		Context: context.Background(),
+		AutoAck: true,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	handler = options.TimeoutHandler(handler)
+	handler = b.metrics.Handler(topic, handler)
+
+	config := SubscribeConfigFromOptions(options)
+
+	queueName := topic
+	if options.Queue != "" {
+		queueName = options.Queue
+		if err := b.subscribeTopic(topic, queueName, config.FilterTag); err != nil {
+			return nil, err
+		}
+	}
+
+	q, err := b.getQueue(queueName)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &Subscriber{
+		b:         b,
+		options:   options,
+		config:    config,
+		topic:     topic,
+		queueName: queueName,
+		handler:   handler,
+		binder:    binder,
+		queue:     q,
+		done:      make(chan struct{}),
+	}
+	sub.ctx, sub.cancel = context.WithCancel(context.Background())
+
+	b.subscribers.Add(topic, sub)
+
+	go b.doConsume(sub)
+
+	return sub, nil
+}
+
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (b *mnsBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
+
+// subscribeTopic pushes the messages of the topic to the queue, the
+// subscription is named after the queue and kept when it already exists.
+func (b *mnsBroker) subscribeTopic(topic, queueName, filterTag string) error {
+	t, err := b.getTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	req := ali_mns.MessageSubsribeRequest{
+		Endpoint:            t.GenerateQueueEndpoint(queueName),
+		NotifyContentFormat: ali_mns.SIMPLIFIED,
+		FilterTag:           filterTag,
+	}
+
+	if err = t.Subscribe(queueName, req); err != nil && !strings.Contains(err.Error(), "SubscriptionAlreadyExist") {
+		return err
+	}
+	return nil
+}
+
+func (b *mnsBroker) doConsume(sub *Subscriber) {
+	defer close(sub.done)
+
+	waitSeconds, batchSize := sub.config.WaitSeconds, sub.config.BatchSize
+
+	for sub.ctx.Err() == nil {
+		// buffered, so an abandoned poll can still deliver its result and exit.
+		respChan := make(chan ali_mns.BatchMessageReceiveResponse, 1)
+		errChan := make(chan error, 1)
+
+		go sub.queue.BatchReceiveMessage(respChan, errChan, batchSize, waitSeconds)
+
+		select {
+		case <-sub.ctx.Done():
+			return
+
+		case resp := <-respChan:
+			b.consume(sub, resp.Messages)
+
+		case err := <-errChan:
+			// 队列中没有消息可消费。
+			if !strings.Contains(err.Error(), "MessageNotExist") {
+				log.Errorf("[mns] receive from [%s] failed: %s", sub.queueName, err)
+				sub.options.ReportError(sub.ctx, broker.ErrReceive, err, nil)
+				sleep(sub.ctx, b.options.Clock, 3*time.Second)
+			}
+
+		case <-b.options.Clock.After(time.Duration(waitSeconds)*time.Second + 30*time.Second):
+		}
+	}
+}
+
+func (b *mnsBroker) consume(sub *Subscriber, msgs []ali_mns.MessageReceiveResponse) {
+	for i := range msgs {
+		// the rest becomes visible again after the visibility timeout.
+		if sub.ctx.Err() != nil {
+			return
+		}
+
+		p, span, err := b.handleMessage(sub, &msgs[i])
+		if err != nil {
+			b.retry(sub, &msgs[i])
+		} else if sub.options.AutoAck && !broker.IsSettled(p) {
+			if err = broker.AutoAck(p); err != nil {
+				log.Errorf("[mns] delete message failed: %s", err)
+				sub.options.ReportError(sub.ctx, broker.ErrAck, err, p)
+			}
+		}
+		b.finishConsumerSpan(span, err)
+	}
+}
+
+// retry makes the failed message visible again after the retry delay.
+func (b *mnsBroker) retry(sub *Subscriber, msg *ali_mns.MessageReceiveResponse) {
+	if sub.config.RetryDelay < 0 {
+		return
+	}
+	if _, err := sub.queue.ChangeMessageVisibility(msg.ReceiptHandle, int64(sub.config.RetryDelay/time.Second)); err != nil {
+		log.Errorf("[mns] change message visibility failed: %s", err)
+		sub.options.ReportError(sub.ctx, broker.ErrAck, err, nil)
+	}
+}
+
+// handleMessage decodes msg and runs the handler, the span is left open for the caller.
+func (b *mnsBroker) handleMessage(sub *Subscriber, msg *ali_mns.MessageReceiveResponse) (*Publication, trace.Span, error) {
+	ctx, span := b.startConsumerSpan(sub, msg)
+
+	var m broker.Message
+	p := &Publication{
+		topic: sub.topic,
+		m:     &m,
+		queue: sub.queue,
+		entry: msg,
+	}
+
+	m.Headers = messageHeaders(msg)
+
+	switch {
+	case sub.options.RawBody:
+		m.Body = []byte(msg.MessageBody)
+	case sub.binder != nil:
+		m.Body = sub.binder()
+	default:
+		m.Body = msg.MessageBody
+	}
+
+	if err := b.options.Decode(&sub.options, m.Headers, []byte(msg.MessageBody), &m.Body); err != nil {
+		p.err = err
+		log.Errorf("[mns] unmarshal message failed: %s", err)
+		sub.options.ReportError(ctx, broker.ErrUnmarshal, err, p)
+
+		// the message left unsettled is retried by the caller.
+		switch sub.options.HandleUnmarshalFailure(ctx, sub.topic, m.Headers, []byte(msg.MessageBody), err) {
+		case broker.UnmarshalActionAck:
+			if ackErr := p.Ack(); ackErr != nil {
+				sub.options.ReportError(ctx, broker.ErrAck, ackErr, p)
+			}
+			return p, span, nil
+		case broker.UnmarshalActionStop:
+			// unsubscribe outside of the consume loop, which it waits for.
+			go func() { _ = sub.Unsubscribe(true) }()
+		}
+		return p, span, err
+	}
+
+	if err := sub.handler(ctx, p); err != nil {
+		p.err = err
+		log.Errorf("[mns] process message failed: %v", err)
+		return p, span, err
+	}
+
+	return p, span, nil
+}
+
+// sleep waits for d on clock or until ctx is done.
+func sleep(ctx context.Context, clock broker.Clock, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	t := clock.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-t.C():
+	}
+}
+
+func messageHeaders(msg *ali_mns.MessageReceiveResponse) broker.Headers {
+	return broker.Headers{
+		HeaderMessageID:        msg.MessageId,
+		HeaderEnqueueTime:      strconv.FormatInt(msg.EnqueueTime, 10),
+		HeaderFirstDequeueTime: strconv.FormatInt(msg.FirstDequeueTime, 10),
+		HeaderNextVisibleTime:  strconv.FormatInt(msg.NextVisibleTime, 10),
+		HeaderDequeueCount:     strconv.FormatInt(msg.DequeueCount, 10),
+		HeaderPriority:         strconv.FormatInt(msg.Priority, 10),
+	}
+}
+
+// MNS messages carry no properties, so the trace context is not propagated.
+func (b *mnsBroker) startProducerSpan(ctx context.Context, topic string) trace.Span {
+	if b.producerTracer == nil {
+		return nil
+	}
+
+	attrs := []attribute.KeyValue{
+		semConv.MessagingSystemKey.String("mns"),
+		semConv.MessagingDestinationKindTopic,
+		semConv.MessagingDestinationKey.String(topic),
+	}
+
+	var span trace.Span
+	_, span = b.producerTracer.Start(ctx, propagation.MapCarrier{}, attrs...)
+
+	return span
+}
+
+func (b *mnsBroker) finishProducerSpan(span trace.Span, messageId string, err error) {
+	if b.producerTracer == nil {
+		return
+	}
+
+	attrs := []attribute.KeyValue{
+		semConv.MessagingMessageIDKey.String(messageId),
+	}
+
+	b.producerTracer.End(context.Background(), span, err, attrs...)
+}
+
+func (b *mnsBroker) startConsumerSpan(sub *Subscriber, msg *ali_mns.MessageReceiveResponse) (context.Context, trace.Span) {
+	ctx := sub.options.Context
+	if b.consumerTracer == nil {
+		return ctx, nil
+	}
+
+	attrs := []attribute.KeyValue{
+		semConv.MessagingSystemKey.String("mns"),
+		semConv.MessagingDestinationKindTopic,
+		semConv.MessagingDestinationKey.String(sub.topic),
+		semConv.MessagingOperationReceive,
+		semConv.MessagingMessageIDKey.String(msg.MessageId),
+	}
+
+	var span trace.Span
+	ctx, span = b.consumerTracer.Start(ctx, propagation.MapCarrier{}, attrs...)
+
+	return ctx, span
+}
+
+func (b *mnsBroker) finishConsumerSpan(span trace.Span, err error) {
+	if b.consumerTracer == nil {
+		return
+	}
+
+	b.consumerTracer.End(context.Background(), span, err)
+}
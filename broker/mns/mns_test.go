@@ -0,0 +1,118 @@
+package mns
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	api "github.com/tx7do/kratos-transport/testing/api/manual"
+)
+
+const (
+	testQueue = "test-queue"
+	testTopic = "test-topic"
+)
+
+func handleHygrothermograph(_ context.Context, topic string, headers broker.Headers, msg *api.Hygrothermograph) error {
+	log.Infof("Topic %s, Headers: %+v, Payload: %+v\n", topic, headers, msg)
+	return nil
+}
+
+func createBroker(t *testing.T) broker.Broker {
+	endpoint := os.Getenv("MNS_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("MNS_ENDPOINT is not set, skip")
+	}
+
+	b := NewBroker(
+		broker.WithAddress(endpoint),
+		broker.WithCodec("json"),
+		WithAccessKey(os.Getenv("MNS_ACCESS_KEY_ID"), os.Getenv("MNS_ACCESS_KEY_SECRET")),
+	)
+
+	_ = b.Init()
+
+	if err := b.Connect(); err != nil {
+		t.Logf("cant connect to broker, skip: %v", err)
+		t.Skip()
+	}
+
+	return b
+}
+
+func Test_Publish_Queue(t *testing.T) {
+	ctx := context.Background()
+
+	b := createBroker(t)
+	defer b.Disconnect()
+
+	var msg api.Hygrothermograph
+	const count = 10
+	for i := 0; i < count; i++ {
+		msg.Humidity = float64(i)
+		msg.Temperature = float64(i)
+		err := b.Publish(ctx, testQueue, msg)
+		assert.Nil(t, err)
+	}
+
+	err := b.Publish(ctx, testQueue, msg, WithDelay(10*time.Second), WithPriority(1))
+	assert.Nil(t, err)
+
+	fmt.Printf("total send %d messages\n", count+1)
+}
+
+func Test_Publish_Topic(t *testing.T) {
+	ctx := context.Background()
+
+	b := createBroker(t)
+	defer b.Disconnect()
+
+	msg := api.Hygrothermograph{Humidity: 1, Temperature: 1}
+	err := b.Publish(ctx, testTopic, msg, WithPublishTopic(), WithMessageTag("tag1"))
+	assert.Nil(t, err)
+}
+
+func Test_Subscribe_Queue(t *testing.T) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	b := createBroker(t)
+	defer b.Disconnect()
+
+	_, err := b.Subscribe(testQueue,
+		api.RegisterHygrothermographJsonHandler(handleHygrothermograph),
+		api.HygrothermographCreator,
+		WithBatchSize(16),
+		WithWaitSeconds(30),
+	)
+	assert.Nil(t, err)
+
+	<-interrupt
+}
+
+func Test_Subscribe_Topic(t *testing.T) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	b := createBroker(t)
+	defer b.Disconnect()
+
+	_, err := b.Subscribe(testTopic,
+		api.RegisterHygrothermographJsonHandler(handleHygrothermograph),
+		api.HygrothermographCreator,
+		broker.WithQueueName(testQueue),
+		WithFilterTag("tag1"),
+		WithRetryDelay(10*time.Second),
+	)
+	assert.Nil(t, err)
+
+	<-interrupt
+}
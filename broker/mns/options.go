@@ -0,0 +1,91 @@
+package mns
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+///
+/// Option
+///
+
+type accessKeyKey struct{}
+type queueQpsKey struct{}
+
+type accessKeyRecord struct {
+	accessKeyId     string
+	accessKeySecret string
+}
+
+// WithAccessKey set the AccessKey of the Alibaba Cloud account.
+func WithAccessKey(accessKeyId, accessKeySecret string) broker.Option {
+	return broker.OptionContextWithValue(accessKeyKey{}, &accessKeyRecord{
+		accessKeyId:     accessKeyId,
+		accessKeySecret: accessKeySecret,
+	})
+}
+
+// WithQueueQPS limit the requests per second of every queue and topic client.
+func WithQueueQPS(qps int32) broker.Option {
+	return broker.OptionContextWithValue(queueQpsKey{}, qps)
+}
+
+///
+/// PublishOption
+///
+
+type publishTopicKey struct{}
+type messageTagKey struct{}
+type delaySecondsKey struct{}
+type priorityKey struct{}
+
+// WithPublishTopic publish to the MNS topic named topic instead of the queue.
+func WithPublishTopic() broker.PublishOption {
+	return broker.PublishContextWithValue(publishTopicKey{}, true)
+}
+
+// WithMessageTag set the tag of a topic message, which subscriptions filter on.
+func WithMessageTag(tag string) broker.PublishOption {
+	return broker.PublishContextWithValue(messageTagKey{}, tag)
+}
+
+// WithDelay keep a queue message invisible for the given delay, up to 7 days.
+func WithDelay(delay time.Duration) broker.PublishOption {
+	return broker.PublishContextWithValue(delaySecondsKey{}, int64(delay/time.Second))
+}
+
+// WithPriority set the priority of a queue message, from 1 (highest) to 16 (lowest).
+func WithPriority(priority int64) broker.PublishOption {
+	return broker.PublishContextWithValue(priorityKey{}, priority)
+}
+
+///
+/// SubscribeOption
+///
+
+type filterTagKey struct{}
+type waitSecondsKey struct{}
+type batchSizeKey struct{}
+type retryDelayKey struct{}
+
+// WithFilterTag only receive the topic messages with the given tag, requires broker.WithQueueName.
+func WithFilterTag(tag string) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(filterTagKey{}, tag)
+}
+
+// WithWaitSeconds set the long polling time, up to 30 seconds.
+func WithWaitSeconds(seconds int64) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(waitSecondsKey{}, seconds)
+}
+
+// WithBatchSize set the number of messages received at once, up to 16.
+func WithBatchSize(n int32) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(batchSizeKey{}, n)
+}
+
+// WithRetryDelay make a failed message visible again after delay, instead of
+// after the visibility timeout of the queue.
+func WithRetryDelay(delay time.Duration) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(retryDelayKey{}, int64(delay/time.Second))
+}
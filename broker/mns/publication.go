@@ -0,0 +1,69 @@
+package mns
+
+import (
+	"errors"
+	"time"
+
+	ali_mns "github.com/aliyun/aliyun-mns-go-sdk"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Publication struct {
+	broker.AckState
+
+	topic string
+	err   error
+	m     *broker.Message
+	queue ali_mns.AliMNSQueue
+	entry *ali_mns.MessageReceiveResponse
+}
+
+func (p *Publication) Topic() string {
+	return p.topic
+}
+
+func (p *Publication) Message() *broker.Message {
+	return p.m
+}
+
+func (p *Publication) RawMessage() interface{} {
+	return p.entry
+}
+
+func (p *Publication) MessageID() string {
+	if p.entry == nil {
+		return ""
+	}
+	return p.entry.MessageId
+}
+
+// DequeueCount returns how many times the message has been received, including this one.
+func (p *Publication) DequeueCount() int64 {
+	if p.entry == nil {
+		return 0
+	}
+	return p.entry.DequeueCount
+}
+
+func (p *Publication) EnqueueTime() time.Time {
+	if p.entry == nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(p.entry.EnqueueTime)
+}
+
+// Ack deletes the message from the queue.
+func (p *Publication) Ack() error {
+	return p.AckState.Ack(func() error {
+		if p.queue == nil || p.entry == nil {
+			return errors.New("queue is nil")
+		}
+		p.err = p.queue.DeleteMessage(p.entry.ReceiptHandle)
+		return p.err
+	})
+}
+
+func (p *Publication) Error() error {
+	return p.err
+}
@@ -0,0 +1,57 @@
+package mns
+
+import (
+	"context"
+	"testing"
+
+	ali_mns "github.com/aliyun/aliyun-mns-go-sdk"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+// settleQueue records how the messages are settled.
+type settleQueue struct {
+	ali_mns.AliMNSQueue
+
+	deleted, retried []string
+}
+
+func (q *settleQueue) DeleteMessage(receiptHandle string) error {
+	q.deleted = append(q.deleted, receiptHandle)
+	return nil
+}
+
+func (q *settleQueue) ChangeMessageVisibility(receiptHandle string, _ int64) (ali_mns.MessageVisibilityChangeResponse, error) {
+	q.retried = append(q.retried, receiptHandle)
+	return ali_mns.MessageVisibilityChangeResponse{}, nil
+}
+
+// newTestSubscriber return a subscriber consuming queue without a server.
+func newTestSubscriber(opts []broker.Option, topic string, queue ali_mns.AliMNSQueue, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) (*mnsBroker, *Subscriber) {
+	b := NewBroker(opts...).(*mnsBroker)
+	options := broker.NewSubscribeOptions(sopts...)
+
+	sub := &Subscriber{
+		b:       b,
+		topic:   topic,
+		options: options,
+		config:  SubscribeConfigFromOptions(options),
+		handler: handler,
+		binder:  binder,
+		queue:   queue,
+		done:    make(chan struct{}),
+	}
+	sub.ctx, sub.cancel = context.WithCancel(context.Background())
+	close(sub.done)
+
+	return b, sub
+}
+
+func TestSubscribePath(t *testing.T) {
+	mocks.TestSubscribePath(t, func(opts []broker.Option, topic string, _ broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) {
+		b, sub := newTestSubscriber(opts, topic, &settleQueue{}, handler, binder, sopts...)
+
+		b.consume(sub, []ali_mns.MessageReceiveResponse{{MessageBody: string(body), ReceiptHandle: "handle"}})
+	}, false)
+}
@@ -0,0 +1,63 @@
+package mns
+
+import (
+	"context"
+	"sync"
+
+	ali_mns "github.com/aliyun/aliyun-mns-go-sdk"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Subscriber struct {
+	sync.RWMutex
+	b         *mnsBroker
+	topic     string
+	queueName string
+	options   broker.SubscribeOptions
+	config    SubscribeConfig
+	handler   broker.Handler
+	binder    broker.Binder
+	queue     ali_mns.AliMNSQueue
+	closed    bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (s *Subscriber) Options() broker.SubscribeOptions {
+	return s.options
+}
+
+func (s *Subscriber) Topic() string {
+	return s.topic
+}
+
+// Unsubscribe stops receiving from the queue, the topic subscription is kept
+// so that messages published meanwhile are not lost.
+func (s *Subscriber) Unsubscribe(removeFromManager bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	s.cancel()
+	<-s.done
+
+	if s.b != nil && s.b.subscribers != nil && removeFromManager {
+		_ = s.b.subscribers.RemoveOnly(s.topic)
+	}
+
+	return nil
+}
+
+func (s *Subscriber) IsClosed() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.closed
+}
@@ -0,0 +1,384 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var (
+	ErrNotConnected = errors.New("mocks: broker not connected")
+
+	// ErrUnexpectedPublish is returned by a strict FakeBroker for the
+	// publishes no expectation matches.
+	ErrUnexpectedPublish = errors.New("mocks: unexpected publish")
+)
+
+var _ broker.Broker = (*FakeBroker)(nil)
+
+// Published is a message published to a FakeBroker.
+type Published struct {
+	Topic   string
+	Body    broker.Any
+	Headers broker.Headers
+	Options broker.PublishOptions
+}
+
+// Expectation is a publish expected by a FakeBroker.
+type Expectation struct {
+	topic string
+	match func(p *Published) bool
+	err   error
+	delay time.Duration
+	times int
+	calls int
+}
+
+// Matching expect the publishes match accepts only.
+func (e *Expectation) Matching(match func(p *Published) bool) *Expectation {
+	e.match = match
+	return e
+}
+
+// Return make the matching publishes fail with err.
+func (e *Expectation) Return(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Delay make the matching publishes take d, or until their context is done,
+// e.g. to test the timeouts of the callers.
+func (e *Expectation) Delay(d time.Duration) *Expectation {
+	e.delay = d
+	return e
+}
+
+// Times expect exactly n publishes, once by default.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+func (e *Expectation) matches(p *Published) bool {
+	if e.calls >= e.times || e.topic != p.Topic {
+		return false
+	}
+	return e.match == nil || e.match(p)
+}
+
+func (e *Expectation) String() string {
+	return fmt.Sprintf("publish to [%s] expected %d times, got %d", e.topic, e.times, e.calls)
+}
+
+type fakeSubscription struct {
+	sub     *Subscriber
+	handler broker.Handler
+	binder  broker.Binder
+}
+
+// FakeBroker is an in-memory broker for the tests of the code using a
+// broker. Every publish is recorded and delivered synchronously to the
+// subscribers of its topic, through the codec of the options when set.
+//
+//	b := mocks.NewFakeBroker(broker.WithCodec("json"))
+//	b.ExpectPublish("orders").Times(2)
+//	b.ExpectPublish("payments").Return(errors.New("unavailable"))
+//	...
+//	b.AssertExpectations(t)
+type FakeBroker struct {
+	sync.Mutex
+
+	options   broker.Options
+	connected bool
+	strict    bool
+
+	published    []Published
+	expectations []*Expectation
+	subs         map[string][]*fakeSubscription
+	connectErr   error
+}
+
+func NewFakeBroker(opts ...broker.Option) *FakeBroker {
+	return &FakeBroker{
+		options: broker.NewOptionsAndApply(opts...),
+		subs:    map[string][]*fakeSubscription{},
+	}
+}
+
+func (b *FakeBroker) Name() string {
+	return "fake"
+}
+
+func (b *FakeBroker) Options() broker.Options {
+	return b.options
+}
+
+func (b *FakeBroker) Address() string {
+	return ""
+}
+
+func (b *FakeBroker) Init(opts ...broker.Option) error {
+	b.Lock()
+	defer b.Unlock()
+
+	b.options.Apply(opts...)
+	return nil
+}
+
+func (b *FakeBroker) Connect() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.connectErr != nil {
+		return b.connectErr
+	}
+	b.connected = true
+	return nil
+}
+
+func (b *FakeBroker) Disconnect() error {
+	b.Lock()
+	defer b.Unlock()
+
+	b.connected = false
+	return nil
+}
+
+// FailConnect make Connect fail with err, nil restores it.
+func (b *FakeBroker) FailConnect(err error) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.connectErr = err
+}
+
+// Strict reject with ErrUnexpectedPublish the publishes no expectation matches.
+func (b *FakeBroker) Strict() *FakeBroker {
+	b.Lock()
+	defer b.Unlock()
+
+	b.strict = true
+	return b
+}
+
+// ExpectPublish expect a publish to topic.
+func (b *FakeBroker) ExpectPublish(topic string) *Expectation {
+	b.Lock()
+	defer b.Unlock()
+
+	e := &Expectation{topic: topic, times: 1}
+	b.expectations = append(b.expectations, e)
+	return e
+}
+
+// AssertExpectations report the expectations not met to t.
+func (b *FakeBroker) AssertExpectations(t testing.TB) bool {
+	t.Helper()
+
+	b.Lock()
+	defer b.Unlock()
+
+	ok := true
+	for _, e := range b.expectations {
+		if e.calls != e.times {
+			t.Errorf("mocks: %s", e)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// Published return the messages published, failed ones included.
+func (b *FakeBroker) Published() []Published {
+	b.Lock()
+	defer b.Unlock()
+
+	return append([]Published(nil), b.published...)
+}
+
+// PublishedTo return the messages published to topic.
+func (b *FakeBroker) PublishedTo(topic string) []Published {
+	b.Lock()
+	defer b.Unlock()
+
+	var published []Published
+	for _, p := range b.published {
+		if p.Topic == topic {
+			published = append(published, p)
+		}
+	}
+	return published
+}
+
+// PublishedBodies return the bodies of the messages published to topic.
+func (b *FakeBroker) PublishedBodies(topic string) []broker.Any {
+	var bodies []broker.Any
+	for _, p := range b.PublishedTo(topic) {
+		bodies = append(bodies, p.Body)
+	}
+	return bodies
+}
+
+// Reset forget the published messages and the expectations.
+func (b *FakeBroker) Reset() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.published = nil
+	b.expectations = nil
+}
+
+func (b *FakeBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	options := broker.NewPublishOptions(opts...)
+	p := Published{Topic: topic, Body: msg, Headers: options.Headers, Options: options}
+
+	b.Lock()
+	if !b.connected {
+		b.Unlock()
+		return ErrNotConnected
+	}
+	b.published = append(b.published, p)
+
+	var matched *Expectation
+	for _, e := range b.expectations {
+		if e.matches(&p) {
+			matched = e
+			break
+		}
+	}
+	strict := b.strict
+	var delay time.Duration
+	if matched != nil {
+		matched.calls++
+		delay = matched.delay
+	}
+	b.Unlock()
+
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	switch {
+	case matched != nil && matched.err != nil:
+		return matched.err
+	case matched == nil && strict:
+		return fmt.Errorf("%w to [%s]", ErrUnexpectedPublish, topic)
+	}
+
+	_, err := b.Deliver(ctx, topic, msg, options.Headers)
+	return err
+}
+
+func (b *FakeBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	fs := &fakeSubscription{handler: handler, binder: binder}
+	fs.sub = &Subscriber{
+		TopicName: topic,
+		Opts:      broker.NewSubscribeOptions(opts...),
+		UnsubscribeFunc: func(bool) error {
+			b.unsubscribe(topic, fs)
+			return nil
+		},
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.subs[topic] = append(b.subs[topic], fs)
+	return fs.sub, nil
+}
+
+// Subscribers return the active subscriptions of topic.
+func (b *FakeBroker) Subscribers(topic string) []*Subscriber {
+	b.Lock()
+	defer b.Unlock()
+
+	var subs []*Subscriber
+	for _, fs := range b.subs[topic] {
+		subs = append(subs, fs.sub)
+	}
+	return subs
+}
+
+func (b *FakeBroker) unsubscribe(topic string, fs *fakeSubscription) {
+	b.Lock()
+	defer b.Unlock()
+
+	subs := b.subs[topic]
+	for i, s := range subs {
+		if s == fs {
+			b.subs[topic] = append(subs[:i:i], subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Deliver hand msg to the subscribers of topic without publishing it, e.g.
+// to test a consumer, and return the events for their settlement.
+func (b *FakeBroker) Deliver(ctx context.Context, topic string, msg broker.Any, headers broker.Headers) ([]*Event, error) {
+	b.Lock()
+	subs := append([]*fakeSubscription(nil), b.subs[topic]...)
+	codec := b.options.Codec
+	b.Unlock()
+
+	var events []*Event
+	var errs []error
+	for _, fs := range subs {
+		body, err := decode(codec, msg, fs.binder)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		evt := NewEvent(topic, body, copyHeaders(headers))
+		events = append(events, evt)
+		if err = fs.handler(ctx, evt); err != nil {
+			evt.Err = err
+			errs = append(errs, err)
+		}
+	}
+	return events, errors.Join(errs...)
+}
+
+// decode pass msg through codec the way a driver would, msg is delivered
+// as is without a codec.
+func decode(codec encoding.Codec, msg broker.Any, binder broker.Binder) (broker.Any, error) {
+	if codec == nil {
+		return msg, nil
+	}
+
+	buf, err := broker.Marshal(codec, msg)
+	if err != nil {
+		return nil, err
+	}
+	if binder == nil {
+		return buf, nil
+	}
+
+	body := binder()
+	if err = broker.Unmarshal(codec, buf, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func copyHeaders(headers broker.Headers) broker.Headers {
+	out := make(broker.Headers, len(headers))
+	for k, v := range headers {
+		out[k] = v
+	}
+	return out
+}
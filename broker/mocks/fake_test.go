@@ -0,0 +1,133 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// recordT records the errors instead of failing the test.
+type recordT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordT) Helper() {}
+
+func (t *recordT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+type order struct {
+	ID string `json:"id"`
+}
+
+func TestFakeBroker(t *testing.T) {
+	ctx := context.Background()
+	b := NewFakeBroker(broker.WithCodec("json"))
+	assert.ErrorIs(t, b.Publish(ctx, "orders", &order{ID: "0"}), ErrNotConnected)
+	assert.Nil(t, b.Connect())
+
+	var received []string
+	sub, err := broker.Subscribe(b, "orders", func(_ context.Context, _ string, headers broker.Headers, o *order) error {
+		assert.Equal(t, "eu", headers["region"])
+		received = append(received, o.ID)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	unavailable := errors.New("unavailable")
+	b.ExpectPublish("orders").Times(2)
+	b.ExpectPublish("payments").Return(unavailable)
+
+	assert.Nil(t, b.Publish(ctx, "orders", &order{ID: "1"}, broker.WithHeaders(broker.Headers{"region": "eu"})))
+	assert.Nil(t, b.Publish(ctx, "orders", &order{ID: "2"}, broker.WithHeaders(broker.Headers{"region": "eu"})))
+	assert.ErrorIs(t, b.Publish(ctx, "payments", &order{ID: "1"}), unavailable)
+	assert.Equal(t, []string{"1", "2"}, received)
+	assert.Len(t, b.Published(), 3)
+	assert.Equal(t, []broker.Any{&order{ID: "1"}, &order{ID: "2"}}, b.PublishedBodies("orders"))
+	assert.Len(t, b.PublishedTo("payments"), 1)
+	assert.True(t, b.AssertExpectations(t))
+
+	assert.Nil(t, sub.Unsubscribe(true))
+	assert.Nil(t, b.Publish(ctx, "orders", &order{ID: "3"}))
+	assert.Len(t, received, 2)
+
+	b.Reset()
+	b.Strict().ExpectPublish("orders").Matching(func(p *Published) bool {
+		return p.Body.(*order).ID == "4"
+	})
+	assert.ErrorIs(t, b.Publish(ctx, "orders", &order{ID: "5"}), ErrUnexpectedPublish)
+	assert.Nil(t, b.Publish(ctx, "orders", &order{ID: "4"}))
+
+	rt := &recordT{TB: t}
+	b.ExpectPublish("refunds")
+	assert.False(t, b.AssertExpectations(rt))
+	assert.Equal(t, []string{"mocks: publish to [refunds] expected 1 times, got 0"}, rt.errors)
+}
+
+func TestFakeBroker_Delay(t *testing.T) {
+	b := NewFakeBroker()
+	assert.Nil(t, b.Connect())
+	b.ExpectPublish("orders").Delay(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, b.Publish(ctx, "orders", "o1"), context.DeadlineExceeded)
+	assert.Nil(t, b.Publish(context.Background(), "orders", "o2"), "delays the matching publishes only")
+
+	_, err := b.Subscribe("payments", func(context.Context, broker.Event) error { return nil }, nil, broker.WithQueueName("billing"))
+	assert.Nil(t, err)
+	if subs := b.Subscribers("payments"); assert.Len(t, subs, 1) {
+		assert.Equal(t, "billing", subs[0].Opts.Queue)
+	}
+}
+
+func TestFakeBroker_Deliver(t *testing.T) {
+	b := NewFakeBroker()
+
+	failed := errors.New("failed")
+	_, err := b.Subscribe("orders", func(_ context.Context, evt broker.Event) error {
+		if string(evt.Message().Body.([]byte)) == "bad" {
+			return failed
+		}
+		return evt.Ack()
+	}, nil)
+	assert.Nil(t, err)
+
+	events, err := b.Deliver(context.Background(), "orders", []byte("good"), nil)
+	assert.Nil(t, err)
+	assert.True(t, broker.IsAcked(events[0]))
+
+	events, err = b.Deliver(context.Background(), "orders", []byte("bad"), nil)
+	assert.ErrorIs(t, err, failed)
+	assert.False(t, broker.IsAcked(events[0]))
+	assert.Equal(t, failed, events[0].Error())
+}
+
+func TestBroker(t *testing.T) {
+	var topics []string
+	m := &Broker{
+		PublishFunc: func(_ context.Context, topic string, _ broker.Any, _ ...broker.PublishOption) error {
+			topics = append(topics, topic)
+			return nil
+		},
+	}
+
+	assert.Nil(t, m.Connect())
+	assert.Nil(t, m.Publish(context.Background(), "orders", "msg"))
+	sub, err := m.Subscribe("orders", nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "orders", sub.Topic())
+
+	assert.Equal(t, []string{"orders"}, topics)
+	assert.Equal(t, 1, m.Calls("Publish"))
+	assert.Equal(t, 1, m.Calls("Connect"))
+	assert.Equal(t, 0, m.Calls("Disconnect"))
+}
@@ -0,0 +1,34 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// HeaderlessBroker is a FakeBroker behaving like the drivers whose messages
+// have no headers, e.g. MQTT 3.1.1, Redis pub/sub, NSQ or MNS: it reports
+// broker.CarriesHeaders false and refuses the publishes with headers with
+// broker.ErrHeadersUnsupported.
+type HeaderlessBroker struct {
+	*FakeBroker
+}
+
+func NewHeaderlessBroker(opts ...broker.Option) *HeaderlessBroker {
+	return &HeaderlessBroker{FakeBroker: NewFakeBroker(opts...)}
+}
+
+func (b *HeaderlessBroker) Name() string {
+	return "headerless"
+}
+
+func (b *HeaderlessBroker) CarriesHeaders() bool {
+	return false
+}
+
+func (b *HeaderlessBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	if err := broker.RejectHeaders(topic, broker.NewPublishOptions(opts...).Headers); err != nil {
+		return err
+	}
+	return b.FakeBroker.Publish(ctx, topic, msg, opts...)
+}
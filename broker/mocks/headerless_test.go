@@ -0,0 +1,23 @@
+package mocks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestHeaderlessBroker(t *testing.T) {
+	ctx := context.Background()
+	b := NewHeaderlessBroker(broker.WithCodec("json"))
+	assert.Nil(t, b.Connect())
+
+	assert.False(t, broker.CarriesHeaders(b))
+	assert.ErrorIs(t, broker.RequireHeaders(b), broker.ErrHeadersUnsupported)
+
+	assert.ErrorIs(t, b.Publish(ctx, "orders", &order{ID: "1"}, broker.WithHeaders(broker.Headers{"region": "eu"})), broker.ErrHeadersUnsupported)
+	assert.Nil(t, b.Publish(ctx, "orders", &order{ID: "2"}))
+	assert.Equal(t, []broker.Any{&order{ID: "2"}}, b.PublishedBodies("orders"))
+}
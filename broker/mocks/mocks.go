@@ -0,0 +1,243 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var (
+	_ broker.Broker      = (*Broker)(nil)
+	_ broker.Subscriber  = (*Subscriber)(nil)
+	_ broker.Event       = (*Event)(nil)
+	_ broker.RawEvent    = (*RawEvent)(nil)
+	_ broker.AckTracker  = (*Event)(nil)
+	_ broker.HeaderCodec = (*HeaderCodec)(nil)
+	_ broker.Redactor    = (*Redactor)(nil)
+)
+
+// calls counts the calls of the methods of a mock.
+type calls struct {
+	mu sync.Mutex
+	n  map[string]int
+}
+
+func (c *calls) add(method string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.n == nil {
+		c.n = map[string]int{}
+	}
+	c.n[method]++
+}
+
+// Calls return how many times method was called.
+func (c *calls) Calls(method string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.n[method]
+}
+
+// Broker is a broker.Broker whose methods run the func of the same name,
+// the zero value succeeds doing nothing.
+type Broker struct {
+	calls
+
+	NameFunc       func() string
+	OptionsFunc    func() broker.Options
+	AddressFunc    func() string
+	InitFunc       func(opts ...broker.Option) error
+	ConnectFunc    func() error
+	DisconnectFunc func() error
+	PublishFunc    func(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error
+	SubscribeFunc  func(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error)
+}
+
+func (b *Broker) Name() string {
+	b.add("Name")
+	if b.NameFunc != nil {
+		return b.NameFunc()
+	}
+	return "mock"
+}
+
+func (b *Broker) Options() broker.Options {
+	b.add("Options")
+	if b.OptionsFunc != nil {
+		return b.OptionsFunc()
+	}
+	return broker.NewOptions()
+}
+
+func (b *Broker) Address() string {
+	b.add("Address")
+	if b.AddressFunc != nil {
+		return b.AddressFunc()
+	}
+	return ""
+}
+
+func (b *Broker) Init(opts ...broker.Option) error {
+	b.add("Init")
+	if b.InitFunc != nil {
+		return b.InitFunc(opts...)
+	}
+	return nil
+}
+
+func (b *Broker) Connect() error {
+	b.add("Connect")
+	if b.ConnectFunc != nil {
+		return b.ConnectFunc()
+	}
+	return nil
+}
+
+func (b *Broker) Disconnect() error {
+	b.add("Disconnect")
+	if b.DisconnectFunc != nil {
+		return b.DisconnectFunc()
+	}
+	return nil
+}
+
+func (b *Broker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	b.add("Publish")
+	if b.PublishFunc != nil {
+		return b.PublishFunc(ctx, topic, msg, opts...)
+	}
+	return nil
+}
+
+func (b *Broker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	b.add("Subscribe")
+	if b.SubscribeFunc != nil {
+		return b.SubscribeFunc(topic, handler, binder, opts...)
+	}
+	return &Subscriber{TopicName: topic, Opts: broker.NewSubscribeOptions(opts...)}, nil
+}
+
+// Subscriber is a broker.Subscriber of TopicName, UnsubscribeFunc is run
+// by Unsubscribe when set.
+type Subscriber struct {
+	calls
+
+	TopicName       string
+	Opts            broker.SubscribeOptions
+	UnsubscribeFunc func(removeFromManager bool) error
+}
+
+func (s *Subscriber) Options() broker.SubscribeOptions {
+	s.add("Options")
+	return s.Opts
+}
+
+func (s *Subscriber) Topic() string {
+	s.add("Topic")
+	return s.TopicName
+}
+
+func (s *Subscriber) Unsubscribe(removeFromManager bool) error {
+	s.add("Unsubscribe")
+	if s.UnsubscribeFunc != nil {
+		return s.UnsubscribeFunc(removeFromManager)
+	}
+	return nil
+}
+
+// Event is a broker.Event tracking its settlement, AckFunc is run by the
+// first Ack when set.
+type Event struct {
+	broker.AckState
+
+	TopicName string
+	Msg       *broker.Message
+	Raw       interface{}
+	Err       error
+	AckFunc   func() error
+}
+
+// NewEvent return an event of topic carrying body and headers.
+func NewEvent(topic string, body broker.Any, headers broker.Headers) *Event {
+	return &Event{
+		TopicName: topic,
+		Msg:       &broker.Message{Headers: headers, Body: body},
+	}
+}
+
+func (e *Event) Topic() string            { return e.TopicName }
+func (e *Event) Message() *broker.Message { return e.Msg }
+func (e *Event) RawMessage() interface{}  { return e.Raw }
+func (e *Event) Error() error             { return e.Err }
+func (e *Event) Ack() error               { return e.AckState.Ack(e.AckFunc) }
+
+// RawEvent is a broker.RawEvent tracking its settlement, AckFunc is run by
+// the first Ack when set.
+type RawEvent struct {
+	broker.AckState
+
+	TopicName   string
+	HeaderValue broker.Headers
+	BodyValue   []byte
+	Raw         interface{}
+	AckFunc     func() error
+}
+
+func (e *RawEvent) Topic() string           { return e.TopicName }
+func (e *RawEvent) Headers() broker.Headers { return e.HeaderValue }
+func (e *RawEvent) Body() []byte            { return e.BodyValue }
+func (e *RawEvent) RawMessage() interface{} { return e.Raw }
+func (e *RawEvent) Ack() error              { return e.AckState.Ack(e.AckFunc) }
+
+// HeaderCodec is a broker.HeaderCodec running the funcs when set, and
+// broker.DefaultHeaderCodec otherwise.
+type HeaderCodec struct {
+	calls
+
+	EncodeFunc func(v any) (string, error)
+	DecodeFunc func(s string, v any) error
+}
+
+func (c *HeaderCodec) EncodeHeader(v any) (string, error) {
+	c.add("EncodeHeader")
+	if c.EncodeFunc != nil {
+		return c.EncodeFunc(v)
+	}
+	return broker.DefaultHeaderCodec.EncodeHeader(v)
+}
+
+func (c *HeaderCodec) DecodeHeader(s string, v any) error {
+	c.add("DecodeHeader")
+	if c.DecodeFunc != nil {
+		return c.DecodeFunc(s, v)
+	}
+	return broker.DefaultHeaderCodec.DecodeHeader(s, v)
+}
+
+// Redactor is a broker.Redactor running the funcs when set, and leaving
+// the message unchanged otherwise.
+type Redactor struct {
+	calls
+
+	RedactHeadersFunc func(headers broker.Headers) broker.Headers
+	RedactBodyFunc    func(body broker.Any) broker.Any
+}
+
+func (r *Redactor) RedactHeaders(headers broker.Headers) broker.Headers {
+	r.add("RedactHeaders")
+	if r.RedactHeadersFunc != nil {
+		return r.RedactHeadersFunc(headers)
+	}
+	return headers
+}
+
+func (r *Redactor) RedactBody(body broker.Any) broker.Any {
+	r.add("RedactBody")
+	if r.RedactBodyFunc != nil {
+		return r.RedactBodyFunc(body)
+	}
+	return body
+}
@@ -0,0 +1,94 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// PublishPath run the publish path of a driver created with opts up to the
+// message it sends to topic, without a server, and return the headers and
+// the body of that message.
+type PublishPath func(opts []broker.Option, topic string, msg broker.Any, popts ...broker.PublishOption) (broker.Headers, []byte, error)
+
+var errRejected = errors.New("mocks: rejected by the interceptor")
+
+// TestPublishPath check the publish path of a driver: the headers of the
+// publish options, of the topic defaults and of the interceptors reach the
+// message, the body set by an interceptor is the one sent, an interceptor
+// error or an oversized body rejects the message. headers is false for the
+// drivers whose messages have no headers, which must refuse the publishes
+// with headers with broker.ErrHeadersUnsupported.
+func TestPublishPath(t *testing.T, publish PublishPath, headers bool) {
+	t.Helper()
+
+	codec := broker.WithCodec("json")
+
+	check := func(name string, opts []broker.Option, topic string, popts []broker.PublishOption, want broker.Headers, body string) {
+		t.Helper()
+
+		h, b, err := publish(append([]broker.Option{codec}, opts...), topic, "hello", popts...)
+		if !headers && len(want) > 0 {
+			if !errors.Is(err, broker.ErrHeadersUnsupported) {
+				t.Errorf("%s: headers published, error %v, want %v", name, err, broker.ErrHeadersUnsupported)
+			}
+			return
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			return
+		}
+		if string(b) != body {
+			t.Errorf("%s: body %q, want %q", name, b, body)
+		}
+		for k, v := range want {
+			if h[k] != v {
+				t.Errorf("%s: header %s is %q, want %q", name, k, h[k], v)
+			}
+		}
+	}
+
+	check("plain", nil, "orders", nil, nil, `"hello"`)
+
+	check("headers", nil, "orders", []broker.PublishOption{
+		broker.WithHeaders(broker.Headers{"x-key": "value"}),
+	}, broker.Headers{"x-key": "value"}, `"hello"`)
+
+	check("topic defaults", []broker.Option{
+		broker.WithTopicDefaults("orders.*", broker.WithHeaders(broker.Headers{"x-default": "orders"})),
+	}, "orders.created", nil, broker.Headers{"x-default": "orders"}, `"hello"`)
+
+	check("topic defaults not matching", []broker.Option{
+		broker.WithTopicDefaults("orders.*", broker.WithHeaders(broker.Headers{"x-default": "orders"})),
+	}, "payments", nil, nil, `"hello"`)
+
+	check("interceptor headers", []broker.Option{
+		broker.WithPublishInterceptors(func(_ context.Context, _ string, msg *broker.Message) error {
+			msg.Headers["x-intercepted"] = msg.Headers["x-key"]
+			return nil
+		}),
+	}, "orders", []broker.PublishOption{
+		broker.WithHeaders(broker.Headers{"x-key": "value"}),
+	}, broker.Headers{"x-key": "value", "x-intercepted": "value"}, `"hello"`)
+
+	check("interceptor body", []broker.Option{
+		broker.WithPublishInterceptors(func(_ context.Context, _ string, msg *broker.Message) error {
+			msg.Body = []byte("intercepted")
+			return nil
+		}),
+	}, "orders", nil, nil, "intercepted")
+
+	_, _, err := publish([]broker.Option{codec, broker.WithPublishInterceptors(func(context.Context, string, *broker.Message) error {
+		return errRejected
+	})}, "orders", "hello")
+	if !errors.Is(err, errRejected) {
+		t.Errorf("interceptor error: %v, want %v", err, errRejected)
+	}
+
+	_, _, err = publish([]broker.Option{codec, broker.WithMaxMessageSize(4)}, "orders", "hello")
+	if !errors.Is(err, broker.ErrPayloadTooLarge) {
+		t.Errorf("max message size: %v, want %v", err, broker.ErrPayloadTooLarge)
+	}
+}
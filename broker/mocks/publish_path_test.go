@@ -0,0 +1,37 @@
+package mocks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// referencePublish is the publish path every driver follows.
+func referencePublish(headers bool) PublishPath {
+	return func(opts []broker.Option, topic string, msg broker.Any, popts ...broker.PublishOption) (broker.Headers, []byte, error) {
+		o := broker.NewOptionsAndApply(opts...)
+
+		buf, err := broker.Marshal(o.Codec, msg)
+		if err != nil {
+			return nil, nil, err
+		}
+		buf, popts, err = o.InterceptPublish(context.Background(), topic, buf, popts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		options := broker.NewPublishOptions(popts...)
+		if !headers {
+			if err = broker.RejectHeaders(topic, options.Headers); err != nil {
+				return nil, nil, err
+			}
+		}
+		return options.Headers, buf, nil
+	}
+}
+
+func TestTestPublishPath(t *testing.T) {
+	TestPublishPath(t, referencePublish(true), true)
+	TestPublishPath(t, referencePublish(false), false)
+}
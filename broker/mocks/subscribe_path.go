@@ -0,0 +1,164 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// SubscribePath deliver a message with headers and body, without a server, to
+// the message handling of a driver created with opts, as a subscription made
+// with Subscribe(topic, handler, binder, sopts...) receives it.
+type SubscribePath func(opts []broker.Option, topic string, headers broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption)
+
+// TestSubscribePath check the subscribe path of a driver: the headers reach
+// the handler, the body is decoded with the codec of the broker into the value
+// of the binder, and a body which cannot be decoded is reported to the error
+// handler of the subscription instead of reaching the handler, then settled
+// by the unmarshal failure policy of the subscription. The raw subscriptions
+// get the body as received and the bodies over the receive size never reach
+// the handler. With content negotiation the codec is chosen by the content
+// type header, the messages without one use the default codec. headers is
+// false for the drivers whose messages have no headers.
+func TestSubscribePath(t *testing.T, deliver SubscribePath, headers bool) {
+	t.Helper()
+
+	codec := broker.WithCodec("json")
+	binder := func() broker.Any { return new(string) }
+
+	var got []interface{}
+	var gotHeaders broker.Headers
+	handler := func(_ context.Context, evt broker.Event) error {
+		got = append(got, evt.Message().Body)
+		gotHeaders = evt.Message().Headers
+		return nil
+	}
+
+	var reported []error
+	report := broker.WithSubscribeErrorHandler(func(_ context.Context, err error, _ broker.Event) {
+		reported = append(reported, err)
+	})
+
+	reset := func() {
+		got, reported = nil, nil
+	}
+
+	var h broker.Headers
+	if headers {
+		h = broker.Headers{"x-key": "value"}
+	}
+
+	deliver([]broker.Option{codec}, "orders", h, []byte(`"hello"`), handler, binder, report)
+	if len(got) != 1 {
+		t.Fatalf("decoded: %d messages handled, want 1", len(got))
+	}
+	if s, ok := got[0].(*string); !ok || *s != "hello" {
+		t.Errorf("decoded: body %#v, want the binder value hello", got[0])
+	}
+	if headers && gotHeaders["x-key"] != "value" {
+		t.Errorf("decoded: headers %v, want x-key=value", gotHeaders)
+	}
+	if len(reported) > 0 {
+		t.Errorf("decoded: errors %v reported", reported)
+	}
+
+	reset()
+	deliver([]broker.Option{codec}, "orders", nil, []byte(`{`), handler, binder, report)
+	if len(got) > 0 {
+		t.Errorf("undecodable: %d messages handled, want 0", len(got))
+	}
+	if !containsError(reported, broker.ErrUnmarshal) {
+		t.Errorf("undecodable: errors %v reported, want %v", reported, broker.ErrUnmarshal)
+	}
+
+	reset()
+	deliver([]broker.Option{codec}, "orders", nil, []byte(`{`), handler, binder, report, broker.WithUnmarshalSkip())
+	if len(got) > 0 {
+		t.Errorf("skip: %d messages handled, want 0", len(got))
+	}
+	if containsError(reported, broker.ErrAck) {
+		t.Errorf("skip: errors %v reported, want no %v", reported, broker.ErrAck)
+	}
+
+	reset()
+	var fallback []string
+	deliver([]broker.Option{codec}, "orders", nil, []byte(`{`), handler, binder, report,
+		broker.WithUnmarshalFallback(func(_ context.Context, _ string, _ broker.Headers, body []byte) error {
+			fallback = append(fallback, string(body))
+			return nil
+		}))
+	if len(got) > 0 {
+		t.Errorf("fallback: %d messages handled, want 0", len(got))
+	}
+	if len(fallback) != 1 || fallback[0] != "{" {
+		t.Errorf("fallback: bodies %q, want the undecoded body", fallback)
+	}
+	if containsError(reported, broker.ErrAck) {
+		t.Errorf("fallback: errors %v reported, want no %v", reported, broker.ErrAck)
+	}
+
+	reset()
+	deliver([]broker.Option{codec}, "orders", nil, []byte(`{`), handler, binder, report, broker.WithUnmarshalFail())
+	if len(got) > 0 {
+		t.Errorf("fail: %d messages handled, want 0", len(got))
+	}
+	if !containsError(reported, broker.ErrSubscriptionStopped) {
+		t.Errorf("fail: errors %v reported, want %v", reported, broker.ErrSubscriptionStopped)
+	}
+
+	reset()
+	deliver([]broker.Option{codec}, "orders", nil, []byte(`{`), handler, binder, report, broker.WithRawBody())
+	if len(got) != 1 {
+		t.Fatalf("raw: %d messages handled, want 1", len(got))
+	}
+	if b, ok := got[0].([]byte); !ok || string(b) != "{" {
+		t.Errorf("raw: body %#v, want the bytes received", got[0])
+	}
+	if len(reported) > 0 {
+		t.Errorf("raw: errors %v reported", reported)
+	}
+
+	reset()
+	deliver([]broker.Option{codec}, "orders", nil, []byte(`"hello"`), handler, binder, report, broker.WithMaxReceiveSize(4))
+	if len(got) > 0 {
+		t.Errorf("receive size: %d messages handled, want 0", len(got))
+	}
+	if !containsError(reported, broker.ErrPayloadTooLarge) {
+		t.Errorf("receive size: errors %v reported, want %v", reported, broker.ErrPayloadTooLarge)
+	}
+
+	negotiate := broker.WithContentNegotiation("json")
+
+	reset()
+	deliver([]broker.Option{codec, negotiate}, "orders", nil, []byte(`"hello"`), handler, binder, report)
+	if len(got) != 1 {
+		t.Fatalf("negotiated: %d messages handled, want 1", len(got))
+	}
+	if s, ok := got[0].(*string); !ok || *s != "hello" {
+		t.Errorf("negotiated: body %#v, want the binder value hello", got[0])
+	}
+
+	if !headers {
+		return
+	}
+
+	reset()
+	deliver([]broker.Option{codec, negotiate}, "orders", broker.Headers{broker.ContentTypeHeader: "application/x-unknown"}, []byte(`"hello"`), handler, binder, report)
+	if len(got) > 0 {
+		t.Errorf("unknown content type: %d messages handled, want 0", len(got))
+	}
+	if !containsError(reported, broker.ErrUnsupportedContentType) {
+		t.Errorf("unknown content type: errors %v reported, want %v", reported, broker.ErrUnsupportedContentType)
+	}
+}
+
+func containsError(errs []error, target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"testing"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// referenceSubscribe is the subscribe path every driver follows.
+func referenceSubscribe(opts []broker.Option, topic string, headers broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) {
+	o := broker.NewOptionsAndApply(opts...)
+	options := broker.NewSubscribeOptions(sopts...)
+
+	evt := NewEvent(topic, body, headers)
+	if binder != nil && !options.RawBody {
+		evt.Msg.Body = binder()
+	}
+
+	if err := o.Decode(&options, headers, body, &evt.Msg.Body); err != nil {
+		options.ReportError(options.Context, broker.ErrUnmarshal, err, evt)
+		if options.HandleUnmarshalFailure(options.Context, topic, headers, body, err) == broker.UnmarshalActionAck {
+			_ = evt.Ack()
+		}
+		return
+	}
+
+	_ = handler(options.Context, evt)
+}
+
+func TestTestSubscribePath(t *testing.T) {
+	TestSubscribePath(t, referenceSubscribe, true)
+	TestSubscribePath(t, referenceSubscribe, false)
+}
@@ -0,0 +1,95 @@
+package broker
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrPublishDisabled   = errors.New("broker is consume-only, publish disabled")
+	ErrSubscribeDisabled = errors.New("broker is publish-only, subscribe disabled")
+)
+
+// Mode restricts the operations a broker instance is allowed to perform.
+type Mode int
+
+const (
+	ModeFull Mode = iota
+	ModePublishOnly
+	ModeConsumeOnly
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeFull:
+		return "full"
+	case ModePublishOnly:
+		return "publish-only"
+	case ModeConsumeOnly:
+		return "consume-only"
+	default:
+		return "unknown"
+	}
+}
+
+// CanPublish return ErrPublishDisabled when the mode does not allow to publish.
+func (m Mode) CanPublish() error {
+	if m == ModeConsumeOnly {
+		return ErrPublishDisabled
+	}
+	return nil
+}
+
+// CanSubscribe return ErrSubscribeDisabled when the mode does not allow to subscribe.
+func (m Mode) CanSubscribe() error {
+	if m == ModePublishOnly {
+		return ErrSubscribeDisabled
+	}
+	return nil
+}
+
+type modeKey struct{}
+
+// WithMode restrict the broker to publish or consume only, the other
+// operation fails fast and its resources are not allocated.
+func WithMode(m Mode) Option {
+	return OptionContextWithValue(modeKey{}, m)
+}
+
+// ModeFromContext return the mode set by WithMode, default ModeFull.
+func ModeFromContext(ctx context.Context) Mode {
+	if ctx == nil {
+		return ModeFull
+	}
+	m, _ := ctx.Value(modeKey{}).(Mode)
+	return m
+}
+
+type modeBroker struct {
+	Broker
+
+	mode Mode
+}
+
+// NewModeBroker restrict b to mode, for the brokers without native WithMode
+// support.
+func NewModeBroker(b Broker, mode Mode) Broker {
+	return &modeBroker{
+		Broker: b,
+		mode:   mode,
+	}
+}
+
+func (b *modeBroker) Publish(ctx context.Context, topic string, msg Any, opts ...PublishOption) error {
+	if err := b.mode.CanPublish(); err != nil {
+		return err
+	}
+	return b.Broker.Publish(ctx, topic, msg, opts...)
+}
+
+func (b *modeBroker) Subscribe(topic string, handler Handler, binder Binder, opts ...SubscribeOption) (Subscriber, error) {
+	if err := b.mode.CanSubscribe(); err != nil {
+		return nil, err
+	}
+	return b.Broker.Subscribe(topic, handler, binder, opts...)
+}
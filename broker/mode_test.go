@@ -0,0 +1,34 @@
+package broker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModeFromContext(t *testing.T) {
+	assert.Equal(t, ModeFull, ModeFromContext(nil))
+	assert.Equal(t, ModeFull, ModeFromContext(NewOptions().Context))
+
+	opts := NewOptionsAndApply(WithMode(ModeConsumeOnly))
+	assert.Equal(t, ModeConsumeOnly, ModeFromContext(opts.Context))
+	assert.Equal(t, "consume-only", ModeConsumeOnly.String())
+}
+
+func TestModeBroker(t *testing.T) {
+	rb := newRecordBroker("test")
+
+	pub := NewModeBroker(rb, ModePublishOnly)
+	assert.Nil(t, pub.Publish(context.Background(), "orders", "msg"))
+	_, err := pub.Subscribe("orders", nil, nil)
+	assert.ErrorIs(t, err, ErrSubscribeDisabled)
+
+	sub := NewModeBroker(rb, ModeConsumeOnly)
+	assert.ErrorIs(t, sub.Publish(context.Background(), "orders", "msg"), ErrPublishDisabled)
+	_, err = sub.Subscribe("orders", nil, nil)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"orders"}, rb.published)
+	assert.Len(t, rb.subscribed, 1)
+}
@@ -0,0 +1,169 @@
+package mqtt
+
+import (
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed mqtt configuration of a broker, the fields are the
+// ones set one by one with the broker options of the package.
+type Config struct {
+	// ClientID is generated when empty.
+	ClientID      string
+	Auth          *AuthRecord
+	CleanSession  bool
+	AutoReconnect bool
+	ResumeSubs    bool
+	OrderMatters  bool
+	// Logger enables the loggers of the paho client, they are global to the
+	// process.
+	Logger LoggerOptions
+}
+
+// DefaultConfig return the configuration of a broker without options.
+func DefaultConfig() Config {
+	return Config{
+		CleanSession:  true,
+		AutoReconnect: false,
+		ResumeSubs:    true,
+		OrderMatters:  true,
+	}
+}
+
+// WithConfig set the whole configuration at once, c should start from
+// DefaultConfig since every field is applied. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		opts := []broker.Option{
+			WithCleanSession(c.CleanSession),
+			WithAutoReconnect(c.AutoReconnect),
+			WithResumeSubs(c.ResumeSubs),
+			WithOrderMatters(c.OrderMatters),
+			WithLogger(c.Logger),
+		}
+		if c.ClientID != "" {
+			opts = append(opts, WithClientId(c.ClientID))
+		}
+		if c.Auth != nil {
+			opts = append(opts, WithAuth(c.Auth.Username, c.Auth.Password))
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	c := DefaultConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(clientIdKey{}).(string); ok {
+		c.ClientID = v
+	}
+	if v, ok := opts.Context.Value(authKey{}).(*AuthRecord); ok {
+		c.Auth = v
+	}
+	if v, ok := opts.Context.Value(cleanSessionKey{}).(bool); ok {
+		c.CleanSession = v
+	}
+	if v, ok := opts.Context.Value(autoReconnectKey{}).(bool); ok {
+		c.AutoReconnect = v
+	}
+	if v, ok := opts.Context.Value(resumeSubsKey{}).(bool); ok {
+		c.ResumeSubs = v
+	}
+	if v, ok := opts.Context.Value(orderMattersKey{}).(bool); ok {
+		c.OrderMatters = v
+	}
+
+	if v, ok := opts.Context.Value(loggerKey{}).(LoggerOptions); ok {
+		c.Logger = v
+	}
+	if _, ok := opts.Context.Value(errorLoggerKey{}).(bool); ok {
+		c.Logger.Error = true
+	}
+	if _, ok := opts.Context.Value(criticalLoggerKey{}).(bool); ok {
+		c.Logger.Critical = true
+	}
+	if _, ok := opts.Context.Value(warnLoggerKey{}).(bool); ok {
+		c.Logger.Warn = true
+	}
+	if _, ok := opts.Context.Value(debugLoggerKey{}).(bool); ok {
+		c.Logger.Debug = true
+	}
+
+	return c
+}
+
+// SubscribeConfig is the typed mqtt configuration of a subscription, the
+// shared group is set with WithSharedGroup.
+type SubscribeConfig struct {
+	QoS byte
+}
+
+// DefaultSubscribeConfig return the configuration of a subscription without
+// options.
+func DefaultSubscribeConfig() SubscribeConfig {
+	return SubscribeConfig{QoS: 1}
+}
+
+// WithSubscribeConfig set the whole subscription configuration at once, c
+// should start from DefaultSubscribeConfig.
+func WithSubscribeConfig(c SubscribeConfig) broker.SubscribeOption {
+	return WithSubscribeQos(c.QoS)
+}
+
+// SubscribeConfigFromOptions return the subscription configuration set in
+// the context of opts.
+func SubscribeConfigFromOptions(opts broker.SubscribeOptions) SubscribeConfig {
+	c := DefaultSubscribeConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(qosSubscribeKey{}).(byte); ok {
+		c.QoS = v
+	}
+
+	return c
+}
+
+// PublishConfig is the typed mqtt configuration of a publishing.
+type PublishConfig struct {
+	QoS      byte
+	Retained bool
+}
+
+// DefaultPublishConfig return the configuration of a publishing without
+// options.
+func DefaultPublishConfig() PublishConfig {
+	return PublishConfig{QoS: 1}
+}
+
+// WithPublishConfig set the whole publishing configuration at once, c
+// should start from DefaultPublishConfig.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		WithPublishQos(c.QoS)(o)
+		WithPublishRetained(c.Retained)(o)
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	c := DefaultPublishConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(qosPublishKey{}).(byte); ok {
+		c.QoS = v
+	}
+	if v, ok := opts.Context.Value(retainedPublishKey{}).(bool); ok {
+		c.Retained = v
+	}
+
+	return c
+}
@@ -0,0 +1,52 @@
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultConfig(), ConfigFromOptions(broker.NewOptions()))
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithClientId("client"),
+		WithAuth("user", "password"),
+		WithCleanSession(false),
+		WithAutoReconnect(true),
+		WithErrorLogger(),
+		WithLogger(LoggerOptions{Warn: true}),
+	))
+
+	c := DefaultConfig()
+	c.ClientID = "client"
+	c.Auth = &AuthRecord{Username: "user", Password: "password"}
+	c.CleanSession = false
+	c.AutoReconnect = true
+	c.Logger = LoggerOptions{Error: true, Warn: true}
+
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c), WithCleanSession(true)))
+	assert.True(t, overridden.CleanSession)
+}
+
+func TestSubscribeConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultSubscribeConfig(), SubscribeConfigFromOptions(broker.NewSubscribeOptions()))
+
+	c := SubscribeConfig{QoS: 2}
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeQos(2))))
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c))))
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultPublishConfig(), PublishConfigFromOptions(broker.PublishOptions{}))
+
+	c := PublishConfig{QoS: 0, Retained: true}
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishQos(0), WithPublishRetained(true))))
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+}
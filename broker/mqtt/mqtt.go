@@ -8,7 +8,7 @@ import (
 
 	MQTT "github.com/eclipse/paho.mqtt.golang"
 	"github.com/go-kratos/kratos/v2/log"
-	"github.com/sleep-go/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker"
 )
 
 type mqttBroker struct {
@@ -17,6 +17,8 @@ type mqttBroker struct {
 	client  MQTT.Client
 
 	subscribers *broker.SubscriberSyncMap
+
+	metrics *broker.Metrics
 }
 
 func NewBroker(opts ...broker.Option) broker.Broker {
@@ -26,12 +28,6 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 func newClient(addrs []string, opts broker.Options, b *mqttBroker) MQTT.Client {
 	cOpts := MQTT.NewClientOptions()
 
-	// 是否清除会话，如果true，mqtt服务端将会清除掉
-	cOpts.SetCleanSession(true)
-	// 设置自动重连接
-	cOpts.SetAutoReconnect(false)
-	// 设置连接之后恢复订阅
-	cOpts.SetResumeSubs(true)
 	// 设置保活时间
 	//cOpts.SetKeepAlive(60)
 	// 设置最大重连时间间隔
@@ -52,52 +48,34 @@ func newClient(addrs []string, opts broker.Options, b *mqttBroker) MQTT.Client {
 	if opts.TLSConfig != nil {
 		cOpts.SetTLSConfig(opts.TLSConfig)
 	}
-	if auth, ok := opts.Context.Value(authKey{}).(*AuthRecord); ok && auth != nil {
-		cOpts.SetUsername(auth.Username)
-		cOpts.SetPassword(auth.Password)
-	}
-	if clientId, ok := opts.Context.Value(clientIdKey{}).(string); ok && clientId != "" {
-		cOpts.SetClientID(clientId)
-	}
-	if enabled, ok := opts.Context.Value(cleanSessionKey{}).(bool); ok {
-		cOpts.SetCleanSession(enabled)
-	}
-	if enabled, ok := opts.Context.Value(autoReconnectKey{}).(bool); ok {
-		cOpts.SetAutoReconnect(enabled)
-	}
-	if enabled, ok := opts.Context.Value(resumeSubsKey{}).(bool); ok {
-		cOpts.SetResumeSubs(enabled)
+	c := ConfigFromOptions(opts)
+	if c.Auth != nil {
+		cOpts.SetUsername(c.Auth.Username)
+		cOpts.SetPassword(c.Auth.Password)
 	}
-	if enabled, ok := opts.Context.Value(orderMattersKey{}).(bool); ok {
-		cOpts.SetOrderMatters(enabled)
+	if c.ClientID != "" {
+		cOpts.SetClientID(c.ClientID)
 	}
+	// 是否清除会话，如果true，mqtt服务端将会清除掉
+	cOpts.SetCleanSession(c.CleanSession)
+	// 设置自动重连接
+	cOpts.SetAutoReconnect(c.AutoReconnect)
+	// 设置连接之后恢复订阅
+	cOpts.SetResumeSubs(c.ResumeSubs)
+	cOpts.SetOrderMatters(c.OrderMatters)
 
-	if _, ok := opts.Context.Value(errorLoggerKey{}).(bool); ok {
+	if c.Logger.Error {
 		MQTT.ERROR = ErrorLogger{}
 	}
-	if _, ok := opts.Context.Value(criticalLoggerKey{}).(bool); ok {
+	if c.Logger.Critical {
 		MQTT.CRITICAL = CriticalLogger{}
 	}
-	if _, ok := opts.Context.Value(warnLoggerKey{}).(bool); ok {
+	if c.Logger.Warn {
 		MQTT.WARN = WarnLogger{}
 	}
-	if _, ok := opts.Context.Value(debugLoggerKey{}).(bool); ok {
+	if c.Logger.Debug {
 		MQTT.DEBUG = DebugLogger{}
 	}
-	if opt, ok := opts.Context.Value(debugLoggerKey{}).(LoggerOptions); ok {
-		if opt.Error {
-			MQTT.ERROR = ErrorLogger{}
-		}
-		if opt.Critical {
-			MQTT.CRITICAL = CriticalLogger{}
-		}
-		if opt.Warn {
-			MQTT.WARN = WarnLogger{}
-		}
-		if opt.Debug {
-			MQTT.DEBUG = DebugLogger{}
-		}
-	}
 
 	return MQTT.NewClient(cOpts)
 }
@@ -120,6 +98,11 @@ func (m *mqttBroker) Name() string {
 	return "MQTT"
 }
 
+// CarriesHeaders is false, MQTT 3.1.1 messages have no properties.
+func (m *mqttBroker) CarriesHeaders() bool {
+	return false
+}
+
 func (m *mqttBroker) Options() broker.Options {
 	return m.options
 }
@@ -139,6 +122,8 @@ func (m *mqttBroker) Init(opts ...broker.Option) error {
 
 	m.addrs = setAddrs(m.options.Addrs)
 	m.client = newClient(m.addrs, m.options, m)
+	m.metrics = broker.NewMetrics("mqtt", m.options.MeterProvider)
+
 	return nil
 }
 
@@ -168,37 +153,53 @@ func (m *mqttBroker) Disconnect() error {
 }
 
 func (m *mqttBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
-	buf, err := broker.Marshal(m.options.Codec, msg)
+	buf, options, err := m.newMessage(ctx, topic, msg, opts)
 	if err != nil {
 		return err
 	}
 
-	return m.publish(ctx, topic, buf, opts...)
-}
+	start := time.Now()
+	err = m.publish(topic, buf, options)
+	m.metrics.RecordPublish(ctx, topic, start, err)
 
-func (m *mqttBroker) publish(ctx context.Context, topic string, buf []byte, opts ...broker.PublishOption) error {
-	if !m.client.IsConnected() {
-		return errors.New("not connected")
-	}
+	return err
+}
 
+// newMessage encode msg and run the publish interceptors, it returns the
+// payload to publish to topic with the options of the publish. MQTT 3.1.1
+// has no message properties, the publishes with headers are refused.
+func (m *mqttBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) ([]byte, broker.PublishOptions, error) {
 	options := broker.PublishOptions{
 		Context: ctx,
 	}
+
+	buf, err := broker.Marshal(m.options.Codec, msg)
+	if err != nil {
+		return nil, options, err
+	}
+	buf, opts, err = m.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return nil, options, err
+	}
+
 	for _, o := range opts {
 		o(&options)
 	}
+	if err = broker.RejectHeaders(topic, options.Headers); err != nil {
+		return nil, options, err
+	}
 
-	var qos byte = 1
-	var retained = false
+	return buf, options, nil
+}
 
-	if value, ok := options.Context.Value(qosPublishKey{}).(byte); ok {
-		qos = value
-	}
-	if value, ok := options.Context.Value(retainedPublishKey{}).(bool); ok {
-		retained = value
+func (m *mqttBroker) publish(topic string, buf []byte, options broker.PublishOptions) error {
+	if !m.client.IsConnected() {
+		return errors.New("not connected")
 	}
 
-	ret := m.client.Publish(topic, qos, retained, buf)
+	c := PublishConfigFromOptions(options)
+
+	ret := m.client.Publish(topic, c.QoS, c.Retained, buf)
 	return ret.Error()
 }
 
@@ -207,54 +208,71 @@ func (m *mqttBroker) Subscribe(topic string, handler broker.Handler, binder brok
 		return nil, errors.New("not connected")
 	}
 
-	var options broker.SubscribeOptions
-	for _, o := range opts {
-		o(&options)
+	options := broker.NewSubscribeOptions(opts...)
+
+	handler = options.TimeoutHandler(handler)
+	handler = m.metrics.Handler(topic, handler)
+
+	qos := SubscribeConfigFromOptions(options).QoS
+
+	filter := sharedTopic(options.Queue, topic)
+
+	sub := &subscriber{
+		m:       m,
+		options: options,
+		topic:   topic,
+		filter:  filter,
+		qos:     qos,
+	}
+	sub.callback = func(_ MQTT.Client, mq MQTT.Message) {
+		m.handleMessage(sub, mq, handler, binder)
 	}
 
-	var qos byte = 1
-	if value, ok := options.Context.Value(qosSubscribeKey{}).(byte); ok {
-		qos = value
+	if err := m.doSubscribe(filter, qos, sub.callback); err != nil {
+		return nil, err
 	}
 
-	callback := func(c MQTT.Client, mq MQTT.Message) {
-		var msg broker.Message
+	m.subscribers.Add(topic, sub)
+
+	return sub, nil
+}
 
-		p := &publication{topic: mq.Topic(), msg: &msg}
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (m *mqttBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return m.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
 
-		if binder != nil {
-			msg.Body = binder()
-		} else {
-			msg.Body = mq.Payload()
-		}
+func (m *mqttBroker) handleMessage(sub *subscriber, mq MQTT.Message, handler broker.Handler, binder broker.Binder) {
+	var msg broker.Message
 
-		if err := broker.Unmarshal(m.options.Codec, mq.Payload(), &msg.Body); err != nil {
-			p.err = err
-			log.Error("[mqtt] unmarshal message failed:", err)
-			return
-		}
+	p := &publication{topic: mq.Topic(), msg: &msg}
 
-		if err := handler(m.options.Context, p); err != nil {
-			p.err = err
-			log.Error("[mqtt] handle message failed:", err)
-		}
+	if binder != nil && !sub.options.RawBody {
+		msg.Body = binder()
+	} else {
+		msg.Body = mq.Payload()
 	}
 
-	if err := m.doSubscribe(topic, qos, callback); err != nil {
-		return nil, err
-	}
+	if err := m.options.Decode(&sub.options, nil, mq.Payload(), &msg.Body); err != nil {
+		p.err = err
+		log.Error("[mqtt] unmarshal message failed:", err)
+		sub.options.ReportError(m.options.Context, broker.ErrUnmarshal, err, p)
 
-	sub := &subscriber{
-		m:        m,
-		options:  options,
-		topic:    topic,
-		qos:      qos,
-		callback: callback,
+		// the message is acknowledged once the callback returns, retry drops it as well.
+		switch sub.options.HandleUnmarshalFailure(m.options.Context, mq.Topic(), nil, mq.Payload(), err) {
+		case broker.UnmarshalActionAck:
+			_ = p.Ack()
+		case broker.UnmarshalActionStop:
+			// unsubscribe outside of the message callback.
+			go func() { _ = sub.Unsubscribe(true) }()
+		}
+		return
 	}
 
-	m.subscribers.Add(topic, sub)
-
-	return sub, nil
+	if err := handler(m.options.Context, p); err != nil {
+		p.err = err
+		log.Error("[mqtt] handle message failed:", err)
+	}
 }
 
 func (m *mqttBroker) doSubscribe(topic string, qos byte, callback MQTT.MessageHandler) error {
@@ -272,8 +290,9 @@ func (m *mqttBroker) onConnect(_ MQTT.Client) {
 
 	m.subscribers.Foreach(func(topic string, sub broker.Subscriber) {
 		aSub := sub.(*subscriber)
-		if err := m.doSubscribe(aSub.topic, aSub.qos, aSub.callback); err != nil {
+		if err := m.doSubscribe(aSub.filter, aSub.qos, aSub.callback); err != nil {
 			log.Error("mqtt broker subscribe message failed:", err)
+			aSub.options.ReportError(aSub.options.Context, broker.ErrResubscribe, err, nil)
 		}
 	})
 }
@@ -291,6 +310,6 @@ func (m *mqttBroker) loopConnect(client MQTT.Client) {
 		} else {
 			break
 		}
-		time.Sleep(1 * time.Second)
+		m.options.Clock.Sleep(1 * time.Second)
 	}
 }
@@ -0,0 +1,202 @@
+package mqtt5
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed mqtt5 configuration of a broker, the fields are the
+// ones set one by one with the broker options of the package.
+type Config struct {
+	// ClientID is generated when empty.
+	ClientID string
+	Auth     *AuthRecord
+	// KeepAlive is in seconds.
+	KeepAlive             uint16
+	CleanStart            bool
+	SessionExpiryInterval time.Duration
+	TopicAliasMaximum     uint16
+	// ConnectTimeout bounds the first connection in Connect, no limit when zero.
+	ConnectTimeout time.Duration
+}
+
+// DefaultConfig return the configuration of a broker without options.
+func DefaultConfig() Config {
+	return Config{
+		KeepAlive:  30,
+		CleanStart: true,
+	}
+}
+
+// WithConfig set the whole configuration at once, c should start from
+// DefaultConfig since the keep alive and clean start are always applied.
+// Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		opts := []broker.Option{
+			WithKeepAlive(c.KeepAlive),
+			WithCleanStart(c.CleanStart),
+		}
+		if c.ClientID != "" {
+			opts = append(opts, WithClientId(c.ClientID))
+		}
+		if c.Auth != nil {
+			opts = append(opts, WithAuth(c.Auth.Username, c.Auth.Password))
+		}
+		if c.SessionExpiryInterval != 0 {
+			opts = append(opts, WithSessionExpiryInterval(c.SessionExpiryInterval))
+		}
+		if c.TopicAliasMaximum != 0 {
+			opts = append(opts, WithTopicAliasMaximum(c.TopicAliasMaximum))
+		}
+		if c.ConnectTimeout != 0 {
+			opts = append(opts, WithConnectTimeout(c.ConnectTimeout))
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	c := DefaultConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(clientIdKey{}).(string); ok {
+		c.ClientID = v
+	}
+	if v, ok := opts.Context.Value(authKey{}).(*AuthRecord); ok {
+		c.Auth = v
+	}
+	if v, ok := opts.Context.Value(keepAliveKey{}).(uint16); ok {
+		c.KeepAlive = v
+	}
+	if v, ok := opts.Context.Value(cleanStartKey{}).(bool); ok {
+		c.CleanStart = v
+	}
+	if v, ok := opts.Context.Value(sessionExpiryIntervalKey{}).(uint32); ok {
+		c.SessionExpiryInterval = time.Duration(v) * time.Second
+	}
+	if v, ok := opts.Context.Value(topicAliasMaximumKey{}).(uint16); ok {
+		c.TopicAliasMaximum = v
+	}
+	if v, ok := opts.Context.Value(connectTimeoutKey{}).(time.Duration); ok {
+		c.ConnectTimeout = v
+	}
+
+	return c
+}
+
+// SubscribeConfig is the typed mqtt5 configuration of a subscription, the
+// shared group is set with WithSharedGroup.
+type SubscribeConfig struct {
+	QoS     byte
+	NoLocal bool
+}
+
+// DefaultSubscribeConfig return the configuration of a subscription without
+// options.
+func DefaultSubscribeConfig() SubscribeConfig {
+	return SubscribeConfig{QoS: 1}
+}
+
+// WithSubscribeConfig set the whole subscription configuration at once, c
+// should start from DefaultSubscribeConfig.
+func WithSubscribeConfig(c SubscribeConfig) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		WithSubscribeQos(c.QoS)(o)
+		if c.NoLocal {
+			WithNoLocal()(o)
+		}
+	}
+}
+
+// SubscribeConfigFromOptions return the subscription configuration set in
+// the context of opts.
+func SubscribeConfigFromOptions(opts broker.SubscribeOptions) SubscribeConfig {
+	c := DefaultSubscribeConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(qosSubscribeKey{}).(byte); ok {
+		c.QoS = v
+	}
+	c.NoLocal, _ = opts.Context.Value(noLocalKey{}).(bool)
+
+	return c
+}
+
+// PublishConfig is the typed mqtt5 configuration of a publishing, apart from
+// the QoS and retained flag the zero fields are left unset.
+type PublishConfig struct {
+	QoS      byte
+	Retained bool
+	// Headers are sent as user properties.
+	Headers         map[string]string
+	MessageExpiry   time.Duration
+	ContentType     string
+	ResponseTopic   string
+	CorrelationData []byte
+}
+
+// DefaultPublishConfig return the configuration of a publishing without
+// options.
+func DefaultPublishConfig() PublishConfig {
+	return PublishConfig{QoS: 1}
+}
+
+// WithPublishConfig set the whole publishing configuration at once, c
+// should start from DefaultPublishConfig.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		opts := []broker.PublishOption{
+			WithPublishQos(c.QoS),
+			WithPublishRetained(c.Retained),
+		}
+		if c.Headers != nil {
+			opts = append(opts, WithHeaders(c.Headers))
+		}
+		if c.MessageExpiry != 0 {
+			opts = append(opts, WithMessageExpiry(c.MessageExpiry))
+		}
+		if c.ContentType != "" {
+			opts = append(opts, WithContentType(c.ContentType))
+		}
+		if c.ResponseTopic != "" {
+			opts = append(opts, WithResponseTopic(c.ResponseTopic))
+		}
+		if c.CorrelationData != nil {
+			opts = append(opts, WithCorrelationData(c.CorrelationData))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	c := DefaultPublishConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(qosPublishKey{}).(byte); ok {
+		c.QoS = v
+	}
+	c.Retained, _ = opts.Context.Value(retainedPublishKey{}).(bool)
+	c.Headers, _ = opts.Context.Value(headersPublishKey{}).(map[string]string)
+	if v, ok := opts.Context.Value(messageExpiryKey{}).(uint32); ok {
+		c.MessageExpiry = time.Duration(v) * time.Second
+	}
+	c.ContentType, _ = opts.Context.Value(contentTypeKey{}).(string)
+	c.ResponseTopic, _ = opts.Context.Value(responseTopicKey{}).(string)
+	c.CorrelationData, _ = opts.Context.Value(correlationDataKey{}).([]byte)
+
+	return c
+}
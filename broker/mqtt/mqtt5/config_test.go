@@ -0,0 +1,62 @@
+package mqtt5
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultConfig(), ConfigFromOptions(broker.NewOptions()))
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithClientId("client"),
+		WithKeepAlive(60),
+		WithCleanStart(false),
+		WithSessionExpiryInterval(time.Hour),
+		WithTopicAliasMaximum(10),
+	))
+
+	c := DefaultConfig()
+	c.ClientID = "client"
+	c.KeepAlive = 60
+	c.CleanStart = false
+	c.SessionExpiryInterval = time.Hour
+	c.TopicAliasMaximum = 10
+
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c), WithKeepAlive(10)))
+	assert.Equal(t, uint16(10), overridden.KeepAlive)
+}
+
+func TestSubscribeConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultSubscribeConfig(), SubscribeConfigFromOptions(broker.NewSubscribeOptions()))
+
+	c := SubscribeConfig{QoS: 2, NoLocal: true}
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeQos(2), WithNoLocal())))
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c))))
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultPublishConfig(), PublishConfigFromOptions(broker.PublishOptions{}))
+
+	legacy := PublishConfigFromOptions(broker.NewPublishOptions(
+		WithHeaders(map[string]string{"trace": "1"}),
+		WithMessageExpiry(time.Minute),
+		WithResponseTopic("replies"),
+	))
+
+	c := DefaultPublishConfig()
+	c.Headers = map[string]string{"trace": "1"}
+	c.MessageExpiry = time.Minute
+	c.ResponseTopic = "replies"
+
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+}
@@ -0,0 +1,442 @@
+package mqtt5
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+const (
+	HeaderContentType   = "content-type"
+	HeaderResponseTopic = "response-topic"
+)
+
+type topicAlias struct {
+	alias       uint16
+	established bool
+}
+
+type mqttBroker struct {
+	sync.RWMutex
+
+	addrs   []*url.URL
+	options broker.Options
+
+	cm     *autopaho.ConnectionManager
+	cancel context.CancelFunc
+
+	aliasMtx sync.Mutex
+	aliasMax uint16
+	aliases  map[string]*topicAlias
+
+	subscribers *broker.SubscriberSyncMap
+
+	metrics *broker.Metrics
+}
+
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.NewOptionsAndApply(opts...)
+
+	b := &mqttBroker{
+		options:     options,
+		aliases:     make(map[string]*topicAlias),
+		subscribers: broker.NewSubscriberSyncMap(),
+	}
+
+	return b
+}
+
+func (m *mqttBroker) Name() string {
+	return "MQTT5"
+}
+
+func (m *mqttBroker) Options() broker.Options {
+	return m.options
+}
+
+func (m *mqttBroker) Address() string {
+	addrs := make([]string, 0, len(m.addrs))
+	for _, u := range m.addrs {
+		addrs = append(addrs, u.String())
+	}
+	return strings.Join(addrs, ",")
+}
+
+func (m *mqttBroker) Init(opts ...broker.Option) error {
+	if m.connection() != nil {
+		return errors.New("cannot init while connected")
+	}
+
+	for _, o := range opts {
+		o(&m.options)
+	}
+
+	addrs, err := parseAddrs(m.options.Addrs)
+	if err != nil {
+		return err
+	}
+	m.addrs = addrs
+
+	m.aliasMax = ConfigFromOptions(m.options).TopicAliasMaximum
+
+	m.metrics = broker.NewMetrics("mqtt", m.options.MeterProvider)
+
+	return nil
+}
+
+func (m *mqttBroker) connection() *autopaho.ConnectionManager {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.cm
+}
+
+func (m *mqttBroker) Connect() error {
+	if m.connection() != nil {
+		return nil
+	}
+
+	if len(m.addrs) == 0 {
+		addrs, err := parseAddrs(m.options.Addrs)
+		if err != nil {
+			return err
+		}
+		m.addrs = addrs
+	}
+
+	c := ConfigFromOptions(m.options)
+
+	cfg := autopaho.ClientConfig{
+		ServerUrls:                    m.addrs,
+		TlsCfg:                        m.options.TLSConfig,
+		KeepAlive:                     c.KeepAlive,
+		CleanStartOnInitialConnection: c.CleanStart,
+		SessionExpiryInterval:         uint32(c.SessionExpiryInterval / time.Second),
+		OnConnectionUp:                m.onConnectionUp,
+		OnConnectError: func(err error) {
+			log.Errorf("[mqtt5] connect error: %s", err)
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: generateClientId(),
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+				m.onPublishReceived,
+			},
+			OnClientError: func(err error) {
+				log.Errorf("[mqtt5] client error: %s", err)
+			},
+		},
+	}
+
+	if c.Auth != nil {
+		cfg.ConnectUsername = c.Auth.Username
+		cfg.ConnectPassword = []byte(c.Auth.Password)
+	}
+	if c.ClientID != "" {
+		cfg.ClientConfig.ClientID = c.ClientID
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cm, err := autopaho.NewConnection(ctx, cfg)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	connectCtx := ctx
+	if c.ConnectTimeout > 0 {
+		var connectCancel context.CancelFunc
+		connectCtx, connectCancel = context.WithTimeout(ctx, c.ConnectTimeout)
+		defer connectCancel()
+	}
+
+	if err = cm.AwaitConnection(connectCtx); err != nil {
+		cancel()
+		return err
+	}
+
+	m.Lock()
+	m.cm = cm
+	m.cancel = cancel
+	m.Unlock()
+
+	return nil
+}
+
+func (m *mqttBroker) Disconnect() error {
+	m.Lock()
+	cm, cancel := m.cm, m.cancel
+	m.cm, m.cancel = nil, nil
+	m.Unlock()
+
+	if cm == nil {
+		return nil
+	}
+
+	m.subscribers.Clear()
+
+	err := cm.Disconnect(context.Background())
+	cancel()
+
+	return err
+}
+
+func (m *mqttBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	pub, err := m.newMessage(ctx, topic, msg, opts)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = m.publish(ctx, topic, pub)
+	m.metrics.RecordPublish(ctx, topic, start, err)
+
+	return err
+}
+
+// newMessage encode msg and run the publish interceptors, it returns the
+// message to publish to topic, the headers are sent as user properties.
+func (m *mqttBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) (*paho.Publish, error) {
+	buf, err := broker.Marshal(m.options.Codec, msg)
+	if err != nil {
+		return nil, err
+	}
+	buf, opts, err = m.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	options := broker.PublishOptions{
+		Context: ctx,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	c := PublishConfigFromOptions(options)
+
+	pub := &paho.Publish{
+		QoS:     c.QoS,
+		Retain:  c.Retained,
+		Topic:   topic,
+		Payload: buf,
+		Properties: &paho.PublishProperties{
+			ContentType:     c.ContentType,
+			ResponseTopic:   c.ResponseTopic,
+			CorrelationData: c.CorrelationData,
+		},
+	}
+
+	for k, val := range c.Headers {
+		pub.Properties.User.Add(k, val)
+	}
+	for k, val := range options.Headers {
+		pub.Properties.User.Add(k, val)
+	}
+	if c.MessageExpiry != 0 {
+		expiry := uint32(c.MessageExpiry / time.Second)
+		pub.Properties.MessageExpiry = &expiry
+	}
+
+	return pub, nil
+}
+
+func (m *mqttBroker) publish(ctx context.Context, topic string, pub *paho.Publish) error {
+	cm := m.connection()
+	if cm == nil {
+		return errors.New("not connected")
+	}
+
+	alias := m.topicAlias(topic)
+	if alias != nil {
+		pub.Properties.TopicAlias = &alias.alias
+		if alias.established {
+			pub.Topic = ""
+		}
+	}
+
+	if _, err := cm.Publish(ctx, pub); err != nil {
+		return err
+	}
+
+	if alias != nil && !alias.established {
+		m.aliasMtx.Lock()
+		if a, ok := m.aliases[topic]; ok && a.alias == alias.alias {
+			a.established = true
+		}
+		m.aliasMtx.Unlock()
+	}
+
+	return nil
+}
+
+// topicAlias returns a copy of the alias of the topic, assigning a new one while
+// there are aliases left. The topic is sent along until the alias is established
+// by a successful publish.
+func (m *mqttBroker) topicAlias(topic string) *topicAlias {
+	if m.aliasMax == 0 {
+		return nil
+	}
+
+	m.aliasMtx.Lock()
+	defer m.aliasMtx.Unlock()
+
+	a, ok := m.aliases[topic]
+	if !ok {
+		if len(m.aliases) >= int(m.aliasMax) {
+			return nil
+		}
+		a = &topicAlias{alias: uint16(len(m.aliases) + 1)}
+		m.aliases[topic] = a
+	}
+
+	alias := *a
+	return &alias
+}
+
+func (m *mqttBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	cm := m.connection()
+	if cm == nil {
+		return nil, errors.New("not connected")
+	}
+
+	options := broker.NewSubscribeOptions(opts...)
+
+	handler = options.TimeoutHandler(handler)
+	handler = m.metrics.Handler(topic, handler)
+
+	sc := SubscribeConfigFromOptions(options)
+	qos, noLocal := sc.QoS, sc.NoLocal
+
+	sub := &subscriber{
+		m:       m,
+		options: options,
+		topic:   topic,
+		filter:  sharedTopic(options.Queue, topic),
+		qos:     qos,
+		noLocal: noLocal,
+	}
+	sub.callback = func(pub *paho.Publish) {
+		m.handleMessage(sub, pub, handler, binder)
+	}
+
+	if err := m.doSubscribe(context.Background(), cm, sub); err != nil {
+		return nil, err
+	}
+
+	m.subscribers.Add(topic, sub)
+
+	return sub, nil
+}
+
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (m *mqttBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return m.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
+
+func (m *mqttBroker) handleMessage(sub *subscriber, pub *paho.Publish, handler broker.Handler, binder broker.Binder) {
+	msg := broker.Message{
+		Headers: messageHeaders(pub),
+	}
+
+	p := &publication{topic: pub.Topic, msg: &msg, raw: pub}
+
+	if binder != nil && !sub.options.RawBody {
+		msg.Body = binder()
+	} else {
+		msg.Body = pub.Payload
+	}
+
+	if err := m.options.Decode(&sub.options, msg.Headers, pub.Payload, &msg.Body); err != nil {
+		p.err = err
+		log.Error("[mqtt5] unmarshal message failed:", err)
+		sub.options.ReportError(m.options.Context, broker.ErrUnmarshal, err, p)
+
+		// the message is acknowledged once the callback returns, retry drops it as well.
+		switch sub.options.HandleUnmarshalFailure(m.options.Context, pub.Topic, msg.Headers, pub.Payload, err) {
+		case broker.UnmarshalActionAck:
+			_ = p.Ack()
+		case broker.UnmarshalActionStop:
+			// unsubscribe outside of the message callback.
+			go func() { _ = sub.Unsubscribe(true) }()
+		}
+		return
+	}
+
+	if err := handler(m.options.Context, p); err != nil {
+		p.err = err
+		log.Error("[mqtt5] handle message failed:", err)
+	}
+}
+
+func (m *mqttBroker) doSubscribe(ctx context.Context, cm *autopaho.ConnectionManager, sub *subscriber) error {
+	_, err := cm.Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{
+			{Topic: sub.filter, QoS: sub.qos, NoLocal: sub.noLocal},
+		},
+	})
+	return err
+}
+
+func (m *mqttBroker) onConnectionUp(cm *autopaho.ConnectionManager, _ *paho.Connack) {
+	log.Debug("[mqtt5] on connect")
+
+	// topic aliases only live as long as the connection.
+	m.aliasMtx.Lock()
+	m.aliases = make(map[string]*topicAlias)
+	m.aliasMtx.Unlock()
+
+	m.subscribers.Foreach(func(topic string, sub broker.Subscriber) {
+		aSub := sub.(*subscriber)
+		if err := m.doSubscribe(context.Background(), cm, aSub); err != nil {
+			log.Error("[mqtt5] subscribe failed:", err)
+			aSub.options.ReportError(aSub.options.Context, broker.ErrResubscribe, err, nil)
+		}
+	})
+}
+
+func (m *mqttBroker) onPublishReceived(pr paho.PublishReceived) (bool, error) {
+	var matched []*subscriber
+	m.subscribers.Foreach(func(topic string, sub broker.Subscriber) {
+		aSub := sub.(*subscriber)
+		if matchTopic(aSub.filter, pr.Packet.Topic) {
+			matched = append(matched, aSub)
+		}
+	})
+
+	for _, sub := range matched {
+		sub.callback(pr.Packet)
+	}
+
+	return len(matched) > 0, nil
+}
+
+// messageHeaders maps the user properties of the message to headers.
+func messageHeaders(pub *paho.Publish) broker.Headers {
+	headers := make(broker.Headers)
+	if pub.Properties == nil {
+		return headers
+	}
+
+	for _, p := range pub.Properties.User {
+		headers[p.Key] = p.Value
+	}
+	if pub.Properties.ContentType != "" {
+		headers[HeaderContentType] = pub.Properties.ContentType
+	}
+	if pub.Properties.ResponseTopic != "" {
+		headers[HeaderResponseTopic] = pub.Properties.ResponseTopic
+	}
+
+	return headers
+}
@@ -0,0 +1,25 @@
+package mqtt5
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchTopic(t *testing.T) {
+	assert.True(t, matchTopic("topic/bobo/1", "topic/bobo/1"))
+	assert.True(t, matchTopic("topic/+/1", "topic/bobo/1"))
+	assert.True(t, matchTopic("topic/#", "topic/bobo/1"))
+	assert.True(t, matchTopic("topic/bobo/#", "topic/bobo"))
+	assert.True(t, matchTopic("$share/group1/topic/+/1", "topic/bobo/1"))
+
+	assert.False(t, matchTopic("topic/bobo", "topic/bobo/1"))
+	assert.False(t, matchTopic("topic/+", "topic/bobo/1"))
+	assert.False(t, matchTopic("#", "$SYS/broker/uptime"))
+	assert.False(t, matchTopic("$share/group1", "topic/bobo/1"))
+}
+
+func TestSharedTopic(t *testing.T) {
+	assert.Equal(t, "topic/bobo/#", sharedTopic("", "topic/bobo/#"))
+	assert.Equal(t, "$share/group1/topic/bobo/#", sharedTopic("group1", "topic/bobo/#"))
+}
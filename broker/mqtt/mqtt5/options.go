@@ -0,0 +1,130 @@
+package mqtt5
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+///
+/// Option
+///
+
+type authKey struct{}
+type clientIdKey struct{}
+type keepAliveKey struct{}
+type cleanStartKey struct{}
+type sessionExpiryIntervalKey struct{}
+type topicAliasMaximumKey struct{}
+type connectTimeoutKey struct{}
+
+type AuthRecord struct {
+	Username string
+	Password string
+}
+
+// WithAuth set username & password options
+func WithAuth(username string, password string) broker.Option {
+	return broker.OptionContextWithValue(authKey{}, &AuthRecord{
+		Username: username,
+		Password: password,
+	})
+}
+
+// WithClientId set client id option
+func WithClientId(clientId string) broker.Option {
+	return broker.OptionContextWithValue(clientIdKey{}, clientId)
+}
+
+// WithKeepAlive set the keep alive interval in seconds
+func WithKeepAlive(seconds uint16) broker.Option {
+	return broker.OptionContextWithValue(keepAliveKey{}, seconds)
+}
+
+// WithCleanStart discard the session on the server at the first connection
+func WithCleanStart(enable bool) broker.Option {
+	return broker.OptionContextWithValue(cleanStartKey{}, enable)
+}
+
+// WithSessionExpiryInterval keep the session on the server for the given interval after disconnecting
+func WithSessionExpiryInterval(interval time.Duration) broker.Option {
+	return broker.OptionContextWithValue(sessionExpiryIntervalKey{}, uint32(interval/time.Second))
+}
+
+// WithTopicAliasMaximum replace the topic of published messages with up to n aliases,
+// n must not exceed the topic alias maximum of the server.
+func WithTopicAliasMaximum(n uint16) broker.Option {
+	return broker.OptionContextWithValue(topicAliasMaximumKey{}, n)
+}
+
+// WithConnectTimeout wait for the first connection up to timeout in Connect
+func WithConnectTimeout(timeout time.Duration) broker.Option {
+	return broker.OptionContextWithValue(connectTimeoutKey{}, timeout)
+}
+
+///
+/// SubscribeOption
+///
+
+type qosSubscribeKey struct{}
+type noLocalKey struct{}
+
+// WithSubscribeQos QOS
+func WithSubscribeQos(qos byte) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(qosSubscribeKey{}, qos)
+}
+
+// WithSharedGroup subscribe to $share/{group}/{topic}, the messages are load-balanced
+// between the subscribers of the group, same as broker.WithQueueName.
+func WithSharedGroup(group string) broker.SubscribeOption {
+	return broker.WithQueueName(group)
+}
+
+// WithNoLocal do not receive the messages published by this client
+func WithNoLocal() broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(noLocalKey{}, true)
+}
+
+///
+/// PublishOption
+///
+
+type qosPublishKey struct{}
+type retainedPublishKey struct{}
+type headersPublishKey struct{}
+type messageExpiryKey struct{}
+type contentTypeKey struct{}
+type responseTopicKey struct{}
+type correlationDataKey struct{}
+
+// WithPublishQos QOS
+func WithPublishQos(qos byte) broker.PublishOption {
+	return broker.PublishContextWithValue(qosPublishKey{}, qos)
+}
+
+// WithPublishRetained retained
+func WithPublishRetained(retain bool) broker.PublishOption {
+	return broker.PublishContextWithValue(retainedPublishKey{}, retain)
+}
+
+// WithHeaders send the headers as user properties
+func WithHeaders(headers map[string]string) broker.PublishOption {
+	return broker.PublishContextWithValue(headersPublishKey{}, headers)
+}
+
+// WithMessageExpiry drop the message on the server if it is not delivered within expiry
+func WithMessageExpiry(expiry time.Duration) broker.PublishOption {
+	return broker.PublishContextWithValue(messageExpiryKey{}, uint32(expiry/time.Second))
+}
+
+func WithContentType(contentType string) broker.PublishOption {
+	return broker.PublishContextWithValue(contentTypeKey{}, contentType)
+}
+
+func WithResponseTopic(topic string) broker.PublishOption {
+	return broker.PublishContextWithValue(responseTopicKey{}, topic)
+}
+
+func WithCorrelationData(data []byte) broker.PublishOption {
+	return broker.PublishContextWithValue(correlationDataKey{}, data)
+}
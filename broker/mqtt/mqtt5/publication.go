@@ -0,0 +1,36 @@
+package mqtt5
+
+import (
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type publication struct {
+	broker.AckState
+
+	topic string
+	msg   *broker.Message
+	raw   *paho.Publish
+	err   error
+}
+
+func (p *publication) Ack() error {
+	return p.AckState.Ack(nil)
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (p *publication) Topic() string {
+	return p.topic
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.msg
+}
+
+func (p *publication) RawMessage() interface{} {
+	return p.raw
+}
@@ -0,0 +1,26 @@
+package mqtt5
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func TestPublishPath(t *testing.T) {
+	mocks.TestPublishPath(t, func(opts []broker.Option, topic string, msg broker.Any, popts ...broker.PublishOption) (broker.Headers, []byte, error) {
+		m := NewBroker(opts...).(*mqttBroker)
+
+		pub, err := m.newMessage(context.Background(), topic, msg, popts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		headers := broker.Headers{}
+		for _, p := range pub.Properties.User {
+			headers[p.Key] = p.Value
+		}
+		return headers, pub.Payload, nil
+	}, true)
+}
@@ -0,0 +1,24 @@
+package mqtt5
+
+import (
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func TestSubscribePath(t *testing.T) {
+	mocks.TestSubscribePath(t, func(opts []broker.Option, topic string, headers broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) {
+		m := NewBroker(opts...).(*mqttBroker)
+		sub := &subscriber{m: m, options: broker.NewSubscribeOptions(sopts...), topic: topic, filter: topic}
+
+		pub := &paho.Publish{Topic: topic, Payload: body, Properties: &paho.PublishProperties{}}
+		for k, v := range headers {
+			pub.Properties.User.Add(k, v)
+		}
+
+		m.handleMessage(sub, pub, handler, binder)
+	}, true)
+}
@@ -0,0 +1,67 @@
+package mqtt5
+
+import (
+	"context"
+	"sync"
+
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type subscriber struct {
+	sync.RWMutex
+
+	options broker.SubscribeOptions
+	m       *mqttBroker
+
+	closed  bool
+	topic   string
+	filter  string
+	qos     byte
+	noLocal bool
+
+	callback func(*paho.Publish)
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.options
+}
+
+func (s *subscriber) Topic() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.topic
+}
+
+func (s *subscriber) Unsubscribe(removeFromManager bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	var err error
+
+	if cm := s.m.connection(); cm != nil {
+		_, err = cm.Unsubscribe(context.Background(), &paho.Unsubscribe{
+			Topics: []string{s.filter},
+		})
+	}
+
+	s.closed = true
+
+	if s.m != nil && s.m.subscribers != nil && removeFromManager {
+		_ = s.m.subscribers.RemoveOnly(s.topic)
+	}
+
+	return err
+}
+
+func (s *subscriber) IsClosed() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.closed
+}
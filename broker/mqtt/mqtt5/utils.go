@@ -0,0 +1,85 @@
+package mqtt5
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAddr = "mqtt://127.0.0.1:1883"
+	sharePrefix = "$share/"
+)
+
+func parseAddrs(addrs []string) ([]*url.URL, error) {
+	urls := make([]*url.URL, 0, len(addrs))
+	for _, addr := range addrs {
+		if len(addr) == 0 {
+			continue
+		}
+		if !strings.Contains(addr, "://") {
+			addr = "mqtt://" + addr
+		}
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+
+	if len(urls) == 0 {
+		u, _ := url.Parse(defaultAddr)
+		urls = append(urls, u)
+	}
+
+	return urls, nil
+}
+
+// sharedTopic returns the shared subscription topic filter of the group,
+// topics which are already shared are kept.
+func sharedTopic(group, topic string) string {
+	if group == "" || strings.HasPrefix(topic, sharePrefix) {
+		return topic
+	}
+	return sharePrefix + group + "/" + topic
+}
+
+// matchTopic reports whether the topic matches the filter, the $share/{group}
+// prefix of a shared subscription is ignored.
+func matchTopic(filter, topic string) bool {
+	if strings.HasPrefix(filter, sharePrefix) {
+		parts := strings.SplitN(filter, "/", 3)
+		if len(parts) < 3 {
+			return false
+		}
+		filter = parts[2]
+	}
+
+	// topics starting with $ are not matched by wildcards at the first level.
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
+
+func generateClientId() string {
+	return fmt.Sprintf("%d%d", time.Now().UnixNano(), rand.Intn(10))
+}
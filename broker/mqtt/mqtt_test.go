@@ -186,3 +186,9 @@ func Test_Subscribe_WithJsonCodec(t *testing.T) {
 
 	<-interrupt
 }
+
+func TestSharedTopic(t *testing.T) {
+	assert.Equal(t, "topic/bobo/#", sharedTopic("", "topic/bobo/#"))
+	assert.Equal(t, "$share/group1/topic/bobo/#", sharedTopic("group1", "topic/bobo/#"))
+	assert.Equal(t, "$share/group2/topic/bobo/#", sharedTopic("group1", "$share/group2/topic/bobo/#"))
+}
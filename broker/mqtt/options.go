@@ -1,7 +1,7 @@
 package mqtt
 
 import (
-	"github.com/sleep-go/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker"
 )
 
 ///
@@ -96,6 +96,12 @@ func WithSubscribeQos(qos byte) broker.SubscribeOption {
 	return broker.SubscribeContextWithValue(qosSubscribeKey{}, qos)
 }
 
+// WithSharedGroup subscribe to $share/{group}/{topic}, the messages are load-balanced
+// between the subscribers of the group, same as broker.WithQueueName.
+func WithSharedGroup(group string) broker.SubscribeOption {
+	return broker.WithQueueName(group)
+}
+
 ///
 /// PublishOption
 ///
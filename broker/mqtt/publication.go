@@ -1,15 +1,17 @@
 package mqtt
 
-import "github.com/sleep-go/kratos-transport/broker"
+import "github.com/tx7do/kratos-transport/broker"
 
 type publication struct {
+	broker.AckState
+
 	topic string
 	msg   *broker.Message
 	err   error
 }
 
 func (p *publication) Ack() error {
-	return nil
+	return p.AckState.Ack(nil)
 }
 
 func (p *publication) Error() error {
@@ -0,0 +1,29 @@
+package mqtt
+
+import (
+	"testing"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+type testMessage struct {
+	MQTT.Message
+
+	topic   string
+	payload []byte
+}
+
+func (m *testMessage) Topic() string   { return m.topic }
+func (m *testMessage) Payload() []byte { return m.payload }
+
+func TestSubscribePath(t *testing.T) {
+	mocks.TestSubscribePath(t, func(opts []broker.Option, topic string, _ broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) {
+		m := NewBroker(opts...).(*mqttBroker)
+		sub := &subscriber{m: m, options: broker.NewSubscribeOptions(sopts...), topic: topic, filter: topic}
+
+		m.handleMessage(sub, &testMessage{topic: topic, payload: body}, handler, binder)
+	}, false)
+}
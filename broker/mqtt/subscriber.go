@@ -4,7 +4,7 @@ import (
 	"sync"
 
 	MQTT "github.com/eclipse/paho.mqtt.golang"
-	"github.com/sleep-go/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker"
 )
 
 type subscriber struct {
@@ -15,6 +15,7 @@ type subscriber struct {
 
 	closed bool
 	topic  string
+	filter string
 	qos    byte
 
 	callback MQTT.MessageHandler
@@ -41,7 +42,7 @@ func (s *subscriber) Unsubscribe(removeFromManager bool) error {
 	var err error
 
 	if s.m != nil && s.m.client != nil {
-		token := s.m.client.Unsubscribe(s.topic)
+		token := s.m.client.Unsubscribe(s.filter)
 		err = token.Error()
 	}
 
@@ -81,6 +81,17 @@ func setAddrs(addrs []string) []string {
 	return cAddrs
 }
 
+const sharePrefix = "$share/"
+
+// sharedTopic returns the shared subscription topic filter of the group,
+// topics which are already shared are kept.
+func sharedTopic(group, topic string) string {
+	if group == "" || strings.HasPrefix(topic, sharePrefix) {
+		return topic
+	}
+	return sharePrefix + group + "/" + topic
+}
+
 func generateClientId() string {
 	return fmt.Sprintf("%d%d", time.Now().UnixNano(), rand.Intn(10))
 }
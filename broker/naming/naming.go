@@ -0,0 +1,379 @@
+package naming
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var (
+	ErrInvalidTopic    = errors.New("naming: invalid topic")
+	ErrInvalidTemplate = errors.New("naming: invalid template")
+)
+
+// DefaultValuePattern is the default syntax of the variables of a topic.
+const DefaultValuePattern = `[a-z0-9][a-z0-9_-]*`
+
+// TopicError lists every naming problem of a topic.
+type TopicError struct {
+	Topic    string
+	Problems []string
+}
+
+func (e *TopicError) Error() string {
+	return fmt.Sprintf("invalid topic %q, %d problem(s): %s",
+		e.Topic, len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+func (e *TopicError) Unwrap() error {
+	return ErrInvalidTopic
+}
+
+// Vars are the values of the variables of a template.
+type Vars map[string]string
+
+// Rule reports the problem of a topic, or nil.
+type Rule func(topic string) error
+
+// MaxLength limit the topics to n bytes.
+func MaxLength(n int) Rule {
+	return func(topic string) error {
+		if len(topic) > n {
+			return fmt.Errorf("longer than %d bytes", n)
+		}
+		return nil
+	}
+}
+
+// Lowercase reject the topics with upper case letters.
+func Lowercase() Rule {
+	return func(topic string) error {
+		if strings.ToLower(topic) != topic {
+			return errors.New("not lower case")
+		}
+		return nil
+	}
+}
+
+type segment struct {
+	literal string
+	name    string
+}
+
+// Convention is a topic template, e.g. "{env}.{service}.{event}", with
+// the rules its topics must follow.
+type Convention struct {
+	template string
+	segments []segment
+
+	defaultPattern string
+	patterns       map[string]string
+	values         map[string]map[string]struct{}
+	vars           Vars
+	rules          []Rule
+	exempt         []string
+	wildcards      map[string]struct{}
+
+	re       *regexp.Regexp
+	valueRes map[string]*regexp.Regexp
+}
+
+// New parse template, whose variables are names between braces.
+func New(template string, opts ...Option) (*Convention, error) {
+	c := &Convention{
+		template:       template,
+		defaultPattern: DefaultValuePattern,
+		patterns:       map[string]string{},
+		values:         map[string]map[string]struct{}{},
+		vars:           Vars{},
+		wildcards:      map[string]struct{}{"*": {}, "#": {}, "+": {}, ">": {}},
+	}
+
+	var err error
+	if c.segments, err = parseTemplate(template); err != nil {
+		return nil, err
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	c.valueRes = map[string]*regexp.Regexp{}
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, s := range c.segments {
+		if s.name == "" {
+			expr.WriteString(regexp.QuoteMeta(s.literal))
+			continue
+		}
+		pattern := c.defaultPattern
+		if p, ok := c.patterns[s.name]; ok {
+			pattern = p
+		}
+		if c.valueRes[s.name], err = regexp.Compile("^(?:" + pattern + ")$"); err != nil {
+			return nil, fmt.Errorf("%w: pattern of %s: %s", ErrInvalidTemplate, s.name, err)
+		}
+		fmt.Fprintf(&expr, "(?P<%s>%s)", s.name, pattern)
+	}
+	expr.WriteString("$")
+
+	if c.re, err = regexp.Compile(expr.String()); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
+	}
+
+	return c, nil
+}
+
+// MustNew is New panicking on error, for package level conventions.
+func MustNew(template string, opts ...Option) *Convention {
+	c, err := New(template, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func parseTemplate(template string) ([]segment, error) {
+	var segments []segment
+	names := map[string]struct{}{}
+
+	for rest := template; rest != ""; {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			segments = append(segments, segment{literal: rest})
+			break
+		}
+		if open > 0 {
+			segments = append(segments, segment{literal: rest[:open]})
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unclosed brace in %q", ErrInvalidTemplate, template)
+		}
+		name := rest[open+1 : open+end]
+		if !variableName.MatchString(name) {
+			return nil, fmt.Errorf("%w: bad variable name %q in %q", ErrInvalidTemplate, name, template)
+		}
+		if _, ok := names[name]; ok {
+			return nil, fmt.Errorf("%w: duplicate variable %q in %q", ErrInvalidTemplate, name, template)
+		}
+		if n := len(segments); n > 0 && segments[n-1].name != "" {
+			return nil, fmt.Errorf("%w: adjacent variables in %q", ErrInvalidTemplate, template)
+		}
+		names[name] = struct{}{}
+		segments = append(segments, segment{name: name})
+		rest = rest[open+end+1:]
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: no variable in %q", ErrInvalidTemplate, template)
+	}
+	return segments, nil
+}
+
+// Template return the template of the convention.
+func (c *Convention) Template() string {
+	return c.template
+}
+
+// Topic expand the template with vars, completed by the fixed ones set
+// with WithVars, and validate the result.
+func (c *Convention) Topic(vars Vars) (string, error) {
+	var topic strings.Builder
+	var missing []string
+	for _, s := range c.segments {
+		if s.name == "" {
+			topic.WriteString(s.literal)
+			continue
+		}
+		v, ok := vars[s.name]
+		if !ok {
+			v, ok = c.vars[s.name]
+		}
+		if !ok {
+			missing = append(missing, s.name)
+		}
+		topic.WriteString(v)
+	}
+
+	if len(missing) > 0 {
+		return "", &TopicError{Topic: topic.String(), Problems: []string{
+			fmt.Sprintf("missing variable(s) %s", strings.Join(missing, ", ")),
+		}}
+	}
+
+	t := topic.String()
+	return t, c.Validate(t)
+}
+
+// MustTopic is Topic panicking on error.
+func (c *Convention) MustTopic(vars Vars) string {
+	t, err := c.Topic(vars)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Parse return the variables of topic, false when it does not follow the template.
+func (c *Convention) Parse(topic string) (Vars, bool) {
+	m := c.re.FindStringSubmatch(topic)
+	if m == nil {
+		return nil, false
+	}
+	vars := Vars{}
+	for i, name := range c.re.SubexpNames() {
+		if name != "" {
+			vars[name] = m[i]
+		}
+	}
+	return vars, true
+}
+
+// Validate check topic follows the template, the allowed values and the
+// rules. The exempt topics are always valid.
+func (c *Convention) Validate(topic string) error {
+	return c.validate(topic, false)
+}
+
+// ValidateSubscription is Validate accepting wildcards, e.g. "*" or "#",
+// as the value of whole variables.
+func (c *Convention) ValidateSubscription(topic string) error {
+	return c.validate(topic, true)
+}
+
+func (c *Convention) validate(topic string, wildcards bool) error {
+	if c.Exempt(topic) {
+		return nil
+	}
+
+	var problems []string
+
+	vars, ok := c.Parse(topic)
+	if !ok && wildcards {
+		vars, ok = c.parseWildcards(topic)
+	}
+	if !ok {
+		problems = append(problems, fmt.Sprintf("does not follow %q", c.template))
+	}
+
+	for _, s := range c.segments {
+		allowed, ok := c.values[s.name]
+		if s.name == "" || !ok || vars == nil {
+			continue
+		}
+		v := vars[s.name]
+		if _, ok = c.wildcards[v]; ok && wildcards {
+			continue
+		}
+		if _, ok = allowed[v]; !ok {
+			problems = append(problems, fmt.Sprintf("%s %q is not allowed", s.name, v))
+		}
+	}
+
+	for _, rule := range c.rules {
+		if err := rule(topic); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return &TopicError{Topic: topic, Problems: problems}
+	}
+	return nil
+}
+
+// parseWildcards split topic on the literals of the template, accepting a
+// wildcard where a variable is expected.
+func (c *Convention) parseWildcards(topic string) (Vars, bool) {
+	vars := Vars{}
+	rest := topic
+	for i, s := range c.segments {
+		if s.name == "" {
+			if !strings.HasPrefix(rest, s.literal) {
+				return nil, false
+			}
+			rest = rest[len(s.literal):]
+			continue
+		}
+
+		v := rest
+		if i+1 < len(c.segments) {
+			end := strings.Index(rest, c.segments[i+1].literal)
+			if end < 0 {
+				return nil, false
+			}
+			v = rest[:end]
+		}
+		rest = rest[len(v):]
+
+		if _, ok := c.wildcards[v]; !ok && !c.valueRes[s.name].MatchString(v) {
+			return nil, false
+		}
+		vars[s.name] = v
+	}
+	return vars, rest == ""
+}
+
+// Exempt reports whether topic escapes the convention, see WithExempt.
+func (c *Convention) Exempt(topic string) bool {
+	for _, pattern := range c.exempt {
+		if pattern == topic {
+			return true
+		}
+		if ok, _ := path.Match(pattern, topic); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type uncheckedKey struct{}
+
+// Unchecked skip the naming check of one publish, the escape hatch for
+// legacy or third party topics.
+func Unchecked() broker.PublishOption {
+	return broker.PublishContextWithValue(uncheckedKey{}, true)
+}
+
+// UncheckedSubscription skip the naming check of one subscription.
+func UncheckedSubscription() broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(uncheckedKey{}, true)
+}
+
+type namingBroker struct {
+	broker.Broker
+
+	c *Convention
+}
+
+// NewBroker wraps b to reject, with a *TopicError, the publishes and
+// subscriptions to the topics not following c.
+func NewBroker(b broker.Broker, c *Convention) broker.Broker {
+	return &namingBroker{Broker: b, c: c}
+}
+
+func (b *namingBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	if unchecked, _ := broker.NewPublishOptions(opts...).Context.Value(uncheckedKey{}).(bool); !unchecked {
+		if err := b.c.Validate(topic); err != nil {
+			return err
+		}
+	}
+	return b.Broker.Publish(ctx, topic, msg, opts...)
+}
+
+func (b *namingBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	if unchecked, _ := broker.NewSubscribeOptions(opts...).Context.Value(uncheckedKey{}).(bool); !unchecked {
+		if err := b.c.ValidateSubscription(topic); err != nil {
+			return nil, err
+		}
+	}
+	return b.Broker.Subscribe(topic, handler, binder, opts...)
+}
@@ -0,0 +1,102 @@
+package naming
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func TestNew_InvalidTemplate(t *testing.T) {
+	for _, template := range []string{
+		"orders",
+		"{env}.{service",
+		"{env}{service}",
+		"{env}.{env}",
+		"{1env}.orders",
+	} {
+		_, err := New(template)
+		assert.ErrorIs(t, err, ErrInvalidTemplate, template)
+	}
+
+	_, err := New("{env}.orders", WithValuePattern("env", "[a-z"))
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+}
+
+func TestConvention(t *testing.T) {
+	c := MustNew("{env}.{service}.{event}",
+		WithAllowedValues("env", "dev", "staging", "prod"),
+		WithValuePattern("event", `[a-z][a-z_.]*`),
+		WithVars(Vars{"env": "prod", "service": "billing"}),
+		WithRules(MaxLength(40), Lowercase()),
+		WithExempt("legacy_*", "__consumer_offsets"),
+	)
+	assert.Equal(t, "{env}.{service}.{event}", c.Template())
+
+	topic, err := c.Topic(Vars{"event": "invoice.paid"})
+	assert.NoError(t, err)
+	assert.Equal(t, "prod.billing.invoice.paid", topic)
+	assert.Equal(t, "dev.billing.invoice", c.MustTopic(Vars{"env": "dev", "event": "invoice"}))
+
+	_, err = c.Topic(Vars{"env": "qa", "event": "invoice"})
+	assert.ErrorIs(t, err, ErrInvalidTopic)
+
+	vars, ok := c.Parse("staging.orders.order.created")
+	assert.True(t, ok)
+	assert.Equal(t, Vars{"env": "staging", "service": "orders", "event": "order.created"}, vars)
+
+	assert.NoError(t, c.Validate("legacy_orders"))
+	assert.NoError(t, c.Validate("__consumer_offsets"))
+
+	err = c.Validate("qa.Billing.Invoice")
+	var te *TopicError
+	assert.ErrorAs(t, err, &te)
+	assert.Equal(t, "qa.Billing.Invoice", te.Topic)
+	assert.Equal(t, []string{
+		`does not follow "{env}.{service}.{event}"`,
+		"not lower case",
+	}, te.Problems)
+
+	err = c.Validate("qa.billing.invoice")
+	assert.ErrorAs(t, err, &te)
+	assert.Equal(t, []string{`env "qa" is not allowed`}, te.Problems)
+
+	assert.Error(t, c.Validate("prod.billing.a_very_long_event_name_over_the_limit"))
+
+	assert.Error(t, c.Validate("prod.*.invoice"))
+	assert.NoError(t, c.ValidateSubscription("prod.*.invoice"))
+	assert.NoError(t, c.ValidateSubscription("*.billing.#"))
+	assert.Error(t, c.ValidateSubscription("qa.*.invoice"))
+	assert.Error(t, c.ValidateSubscription("prod.bill*.invoice"))
+}
+
+func TestNewBroker(t *testing.T) {
+	inner := mocks.NewFakeBroker()
+	assert.NoError(t, inner.Connect())
+	b := NewBroker(inner, MustNew("{env}.{service}.{event}"))
+	ctx := context.Background()
+	handler := func(context.Context, broker.Event) error { return nil }
+
+	assert.NoError(t, b.Publish(ctx, "prod.billing.invoice", "msg"))
+	assert.ErrorIs(t, b.Publish(ctx, "InvoicePaid", "msg"), ErrInvalidTopic)
+	assert.NoError(t, b.Publish(ctx, "InvoicePaid", "msg", Unchecked()))
+	var published []string
+	for _, p := range inner.Published() {
+		published = append(published, p.Topic)
+	}
+	assert.Equal(t, []string{"prod.billing.invoice", "InvoicePaid"}, published)
+
+	_, err := b.Subscribe("prod.*.invoice", handler, nil)
+	assert.NoError(t, err)
+	_, err = b.Subscribe("invoices", handler, nil)
+	assert.ErrorIs(t, err, ErrInvalidTopic)
+	_, err = b.Subscribe("invoices", handler, nil, UncheckedSubscription())
+	assert.NoError(t, err)
+	for _, topic := range []string{"prod.*.invoice", "invoices"} {
+		events, _ := inner.Deliver(ctx, topic, "msg", nil)
+		assert.Len(t, events, 1, topic)
+	}
+}
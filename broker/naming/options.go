@@ -0,0 +1,67 @@
+package naming
+
+type Option func(c *Convention)
+
+// WithValuePattern set the regular expression the values of the variable
+// name must match, default is DefaultValuePattern.
+func WithValuePattern(name, pattern string) Option {
+	return func(c *Convention) {
+		c.patterns[name] = pattern
+	}
+}
+
+// WithDefaultValuePattern set the regular expression of the variables
+// without their own pattern.
+func WithDefaultValuePattern(pattern string) Option {
+	return func(c *Convention) {
+		c.defaultPattern = pattern
+	}
+}
+
+// WithAllowedValues restrict the variable name to values, e.g. the environments.
+func WithAllowedValues(name string, values ...string) Option {
+	return func(c *Convention) {
+		if c.values[name] == nil {
+			c.values[name] = map[string]struct{}{}
+		}
+		for _, v := range values {
+			c.values[name][v] = struct{}{}
+		}
+	}
+}
+
+// WithVars set the fixed variables used by Topic, e.g. the env and the
+// service of the application.
+func WithVars(vars Vars) Option {
+	return func(c *Convention) {
+		for k, v := range vars {
+			c.vars[k] = v
+		}
+	}
+}
+
+// WithRules add rules checked on every topic.
+func WithRules(rules ...Rule) Option {
+	return func(c *Convention) {
+		c.rules = append(c.rules, rules...)
+	}
+}
+
+// WithExempt let the topics matching patterns, in path.Match syntax,
+// escape the convention, e.g. the legacy or third party topics.
+func WithExempt(patterns ...string) Option {
+	return func(c *Convention) {
+		c.exempt = append(c.exempt, patterns...)
+	}
+}
+
+// WithWildcards set the tokens accepted as whole variables by
+// ValidateSubscription, default are "*", "#", "+" and ">".
+func WithWildcards(tokens ...string) Option {
+	return func(c *Convention) {
+		c.wildcards = map[string]struct{}{}
+		for _, t := range tokens {
+			c.wildcards[t] = struct{}{}
+		}
+	}
+}
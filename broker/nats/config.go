@@ -0,0 +1,78 @@
+package nats
+
+import (
+	natsGo "github.com/nats-io/nats.go"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed nats configuration of a broker, the fields are the
+// ones set one by one with the broker options of the package.
+type Config struct {
+	Options         natsGo.Options
+	DrainConnection bool
+}
+
+// DefaultConfig return the configuration of a broker without options.
+func DefaultConfig() Config {
+	return Config{
+		Options: natsGo.GetDefaultOptions(),
+	}
+}
+
+// WithConfig set the whole configuration at once, c should start from
+// DefaultConfig since every field is applied. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		opts := []broker.Option{
+			Options(c.Options),
+		}
+		if c.DrainConnection {
+			opts = append(opts, DrainConnection())
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	c := DefaultConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if val, ok := opts.Context.Value(optionsKey{}).(natsGo.Options); ok {
+		c.Options = val
+	}
+	c.DrainConnection = opts.Context.Value(drainConnectionKey{}) != nil
+
+	return c
+}
+
+// PublishConfig is the typed nats configuration of a publishing.
+type PublishConfig struct {
+	Headers map[string][]string
+}
+
+// WithPublishConfig set the whole publishing configuration at once.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if c.Headers != nil {
+			WithHeaders(c.Headers)(o)
+		}
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	var c PublishConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.Headers, _ = opts.Context.Value(headersKey{}).(map[string][]string)
+
+	return c
+}
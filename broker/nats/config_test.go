@@ -0,0 +1,34 @@
+package nats
+
+import (
+	"testing"
+
+	natsGo "github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultConfig(), ConfigFromOptions(broker.NewOptions()))
+
+	natsOpts := natsGo.GetDefaultOptions()
+	natsOpts.Name = "orders"
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		Options(natsOpts),
+		DrainConnection(),
+	))
+
+	c := Config{Options: natsOpts, DrainConnection: true}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	headers := map[string][]string{"trace": {"1"}}
+
+	c := PublishConfig{Headers: headers}
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithHeaders(headers))))
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+}
@@ -7,6 +7,7 @@ import (
 	"google.golang.org/protobuf/proto"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
 	natsGo "github.com/nats-io/nats.go"
@@ -21,10 +22,12 @@ import (
 
 const (
 	defaultAddr = "nats://127.0.0.1:4222"
+
+	// defaultMaxMessageSize is the default max_payload of the server.
+	defaultMaxMessageSize = 1024 * 1024
 )
 
 type natsBroker struct {
-	sync.Once
 	sync.RWMutex
 
 	connected bool
@@ -41,10 +44,12 @@ type natsBroker struct {
 
 	producerTracer *tracing.Tracer
 	consumerTracer *tracing.Tracer
+
+	metrics *broker.Metrics
 }
 
 func NewBroker(opts ...broker.Option) broker.Broker {
-	options := broker.NewOptionsAndApply(opts...)
+	options := broker.NewOptionsAndApply(append([]broker.Option{broker.WithMaxMessageSize(defaultMaxMessageSize)}, opts...)...)
 
 	b := &natsBroker{
 		options:     options,
@@ -82,6 +87,8 @@ func (b *natsBroker) Init(opts ...broker.Option) error {
 		b.consumerTracer = tracing.NewTracer(trace.SpanKindConsumer, "nats-consumer", b.options.Tracings...)
 	}
 
+	b.metrics = broker.NewMetrics("nats", b.options.MeterProvider)
+
 	return nil
 }
 
@@ -108,13 +115,8 @@ func (b *natsBroker) setOption(opts ...broker.Option) {
 		o(&b.options)
 	}
 
-	b.Once.Do(func() {
-		b.natsOpts = natsGo.GetDefaultOptions()
-	})
-
-	if value, ok := b.options.Context.Value(optionsKey{}).(natsGo.Options); ok {
-		b.natsOpts = value
-	}
+	c := ConfigFromOptions(b.options)
+	b.natsOpts = c.Options
 
 	if len(b.options.Addrs) == 0 {
 		b.options.Addrs = b.natsOpts.Servers
@@ -129,7 +131,7 @@ func (b *natsBroker) setOption(opts ...broker.Option) {
 	}
 	b.setAddrs(b.options.Addrs)
 
-	if b.options.Context.Value(drainConnectionKey{}) != nil {
+	if c.DrainConnection {
 		b.drain = true
 		b.closeCh = make(chan error)
 		b.natsOpts.ClosedCB = b.onClose
@@ -198,25 +200,34 @@ func (b *natsBroker) Disconnect() error {
 }
 
 func (b *natsBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
-	buf, err := broker.Marshal(b.options.Codec, msg)
+	m, options, err := b.newMessage(ctx, topic, msg, opts)
 	if err != nil {
 		return err
 	}
 
-	return b.publish(ctx, topic, buf, opts...)
-}
-
-func (b *natsBroker) publish(ctx context.Context, topic string, buf []byte, opts ...broker.PublishOption) error {
-	b.RLock()
-	defer b.RUnlock()
+	start := time.Now()
+	err = b.publish(m, options)
+	b.metrics.RecordPublish(ctx, topic, start, err)
 
-	if b.conn == nil {
-		return errors.New("not connected")
-	}
+	return err
+}
 
+// newMessage encode msg and run the publish interceptors, it returns the
+// message to publish to topic with the options of the publish.
+func (b *natsBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) (*natsGo.Msg, broker.PublishOptions, error) {
 	options := broker.PublishOptions{
 		Context: ctx,
 	}
+
+	buf, err := broker.Marshal(b.options.Codec, msg)
+	if err != nil {
+		return nil, options, err
+	}
+	buf, opts, err = b.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return nil, options, err
+	}
+
 	for _, o := range opts {
 		o(&options)
 	}
@@ -224,13 +235,25 @@ func (b *natsBroker) publish(ctx context.Context, topic string, buf []byte, opts
 	m := natsGo.NewMsg(topic)
 	m.Data = buf
 
-	if headers, ok := options.Context.Value(headersKey{}).(map[string][]string); ok {
-		for k, v := range headers {
-			for _, vv := range v {
-				m.Header.Add(k, vv)
-			}
+	for k, v := range PublishConfigFromOptions(options).Headers {
+		for _, vv := range v {
+			m.Header.Add(k, vv)
 		}
 	}
+	for k, v := range options.Headers {
+		m.Header.Set(k, v)
+	}
+
+	return m, options, nil
+}
+
+func (b *natsBroker) publish(m *natsGo.Msg, options broker.PublishOptions) error {
+	b.RLock()
+	defer b.RUnlock()
+
+	if b.conn == nil {
+		return errors.New("not connected")
+	}
 
 	span := b.startProducerSpan(options.Context, m)
 
@@ -257,6 +280,9 @@ func (b *natsBroker) Subscribe(topic string, handler broker.Handler, binder brok
 		o(&options)
 	}
 
+	handler = options.TimeoutHandler(handler)
+	handler = b.metrics.Handler(topic, handler)
+
 	subs := &subscriber{
 		n:       b,
 		s:       nil,
@@ -264,58 +290,7 @@ func (b *natsBroker) Subscribe(topic string, handler broker.Handler, binder brok
 	}
 
 	fn := func(msg *natsGo.Msg) {
-		var errSub error
-
-		m := &broker.Message{
-			Headers: natsHeaderToMap(msg.Header),
-			Body:    nil,
-		}
-
-		pub := &publication{t: msg.Subject, m: m}
-
-		ctx, span := b.startConsumerSpan(options.Context, msg)
-
-		eh := b.options.ErrorHandler
-
-		if binder != nil {
-			if b.options.Codec.Name() == kProto.Name {
-				m.Body = binder().(proto.Message)
-			} else {
-				m.Body = binder()
-			}
-		} else {
-			m.Body = msg.Data
-		}
-
-		if errSub = broker.Unmarshal(b.options.Codec, msg.Data, &m.Body); errSub != nil {
-			pub.err = errSub
-			log.Errorf("[nats]: unmarshal message failed: %v", errSub)
-			if eh != nil {
-				_ = eh(b.options.Context, pub)
-			}
-
-			b.finishConsumerSpan(span, errSub)
-			return
-		}
-
-		if errSub = handler(ctx, pub); errSub != nil {
-			pub.err = errSub
-			log.Errorf("[nats]: handle message failed: %v", errSub)
-			if eh != nil {
-				_ = eh(b.options.Context, pub)
-			}
-
-			b.finishConsumerSpan(span, errSub)
-			return
-		}
-
-		if options.AutoAck {
-			if errSub = pub.Ack(); errSub != nil {
-				log.Errorf("[nats]: unable to commit msg: %v", errSub)
-			}
-		}
-
-		b.finishConsumerSpan(span, errSub)
+		b.handleMessage(subs, msg, handler, binder)
 	}
 
 	var sub *natsGo.Subscription
@@ -339,14 +314,94 @@ func (b *natsBroker) Subscribe(topic string, handler broker.Handler, binder brok
 	return subs, nil
 }
 
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (b *natsBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
+
+func (b *natsBroker) handleMessage(sub *subscriber, msg *natsGo.Msg, handler broker.Handler, binder broker.Binder) {
+	var errSub error
+
+	m := &broker.Message{
+		Headers: natsHeaderToMap(msg.Header),
+		Body:    nil,
+	}
+
+	pub := &publication{t: msg.Subject, m: m}
+
+	ctx, span := b.startConsumerSpan(sub.options.Context, msg)
+
+	eh := b.options.ErrorHandler
+
+	if binder != nil && !sub.options.RawBody {
+		if b.options.Codec.Name() == kProto.Name {
+			m.Body = binder().(proto.Message)
+		} else {
+			m.Body = binder()
+		}
+	} else {
+		m.Body = msg.Data
+	}
+
+	if errSub = b.options.Decode(&sub.options, m.Headers, msg.Data, &m.Body); errSub != nil {
+		pub.err = errSub
+		log.Errorf("[nats]: unmarshal message failed: %v", errSub)
+		sub.options.ReportError(ctx, broker.ErrUnmarshal, errSub, pub)
+		if eh != nil {
+			_ = eh(b.options.Context, pub)
+		}
+
+		b.finishConsumerSpan(span, errSub)
+
+		// core nats does not redeliver, retry drops the message as well.
+		switch sub.options.HandleUnmarshalFailure(ctx, msg.Subject, m.Headers, msg.Data, errSub) {
+		case broker.UnmarshalActionAck:
+			_ = pub.Ack()
+		case broker.UnmarshalActionStop:
+			_ = sub.Unsubscribe(true)
+		}
+		return
+	}
+
+	if errSub = handler(ctx, pub); errSub != nil {
+		pub.err = errSub
+		log.Errorf("[nats]: handle message failed: %v", errSub)
+		if eh != nil {
+			_ = eh(b.options.Context, pub)
+		}
+
+		b.finishConsumerSpan(span, errSub)
+		return
+	}
+
+	if sub.options.AutoAck {
+		if errSub = broker.AutoAck(pub); errSub != nil {
+			log.Errorf("[nats]: unable to commit msg: %v", errSub)
+			sub.options.ReportError(ctx, broker.ErrAck, errSub, pub)
+		}
+	}
+
+	b.finishConsumerSpan(span, errSub)
+}
+
 func (b *natsBroker) onClose(_ *natsGo.Conn) {
 	b.closeCh <- nil
 }
 
-func (b *natsBroker) onAsyncError(_ *natsGo.Conn, _ *natsGo.Subscription, err error) {
+func (b *natsBroker) onAsyncError(_ *natsGo.Conn, s *natsGo.Subscription, err error) {
 	if errors.Is(err, natsGo.ErrDrainTimeout) {
 		b.closeCh <- err
 	}
+
+	// e.g. a slow consumer dropping messages.
+	if s == nil || b.subscribers == nil {
+		return
+	}
+	b.subscribers.Foreach(func(_ string, sub broker.Subscriber) {
+		if aSub, ok := sub.(*subscriber); ok && aSub.s == s {
+			aSub.options.ReportError(aSub.options.Context, broker.ErrReceive, err, nil)
+		}
+	})
 }
 
 func (b *natsBroker) onDisconnectedError(_ *natsGo.Conn, err error) {
@@ -0,0 +1,26 @@
+package nats
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func TestPublishPath(t *testing.T) {
+	mocks.TestPublishPath(t, func(opts []broker.Option, topic string, msg broker.Any, popts ...broker.PublishOption) (broker.Headers, []byte, error) {
+		b := NewBroker(opts...).(*natsBroker)
+
+		m, _, err := b.newMessage(context.Background(), topic, msg, popts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		headers := broker.Headers{}
+		for k := range m.Header {
+			headers[k] = m.Header.Get(k)
+		}
+		return headers, m.Data, nil
+	}, true)
+}
@@ -0,0 +1,25 @@
+package nats
+
+import (
+	"testing"
+
+	natsGo "github.com/nats-io/nats.go"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func TestSubscribePath(t *testing.T) {
+	mocks.TestSubscribePath(t, func(opts []broker.Option, topic string, headers broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) {
+		b := NewBroker(opts...).(*natsBroker)
+		sub := &subscriber{n: b, options: broker.NewSubscribeOptions(sopts...)}
+
+		msg := natsGo.NewMsg(topic)
+		msg.Data = body
+		for k, v := range headers {
+			msg.Header.Set(k, v)
+		}
+
+		b.handleMessage(sub, msg, handler, binder)
+	}, true)
+}
@@ -0,0 +1,99 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+)
+
+// ContentTypeHeader carries the content type of a message when the broker
+// negotiates content.
+const ContentTypeHeader = "content-type"
+
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+var contentSubtypeAliases = map[string]string{
+	"protobuf":   "proto",
+	"x-protobuf": "proto",
+}
+
+// ContentType return the content type of codec, application/<codec name>.
+func ContentType(codec encoding.Codec) string {
+	if codec == nil {
+		return ""
+	}
+	return "application/" + codec.Name()
+}
+
+// CodecByContentType return the registered codec of contentType, the
+// parameters and the x- prefix of the subtype are ignored.
+func CodecByContentType(contentType string) (encoding.Codec, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+
+	_, subtype, _ := strings.Cut(mediaType, "/")
+	if alias, ok := contentSubtypeAliases[subtype]; ok {
+		subtype = alias
+	}
+	subtype = strings.TrimPrefix(subtype, "x-")
+
+	codec := encoding.GetCodec(subtype)
+	if codec == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+	return codec, nil
+}
+
+// WithContentNegotiation stamp the content type of the codec on the published
+// messages and decode the consumed ones with the codec of their content type,
+// so services sharing topics can migrate codecs one by one. The messages
+// without content type are decoded with the codec named defaultCodec, or the
+// codec of the broker when empty.
+func WithContentNegotiation(defaultCodec string) Option {
+	return func(o *Options) {
+		o.NegotiateContent = true
+		o.DefaultContentCodec = nil
+		if defaultCodec != "" {
+			o.DefaultContentCodec = encoding.GetCodec(defaultCodec)
+		}
+	}
+}
+
+// Decode decode the body data of a consumed message into out. With content
+// negotiation the codec is chosen by the content type in headers, which is set
+// to the default one when missing. The messages of raw subscriptions are left
+// undecoded, the ones exceeding the receive size fail with ErrPayloadTooLarge.
+func (o *Options) Decode(so *SubscribeOptions, headers Headers, data []byte, out interface{}) error {
+	if so != nil {
+		if err := so.checkReceiveSize(data); err != nil {
+			return err
+		}
+		if so.RawBody {
+			return nil
+		}
+	}
+	if !o.NegotiateContent {
+		return Unmarshal(o.Codec, data, out)
+	}
+
+	codec := o.DefaultContentCodec
+	if codec == nil {
+		codec = o.Codec
+	}
+
+	if contentType := headers[ContentTypeHeader]; contentType != "" {
+		var err error
+		if codec, err = CodecByContentType(contentType); err != nil {
+			return err
+		}
+	} else if headers != nil && codec != nil {
+		headers[ContentTypeHeader] = ContentType(codec)
+	}
+
+	return Unmarshal(codec, data, out)
+}
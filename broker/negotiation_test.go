@@ -0,0 +1,50 @@
+package broker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecByContentType(t *testing.T) {
+	codec, err := CodecByContentType("application/json; charset=utf-8")
+	assert.Nil(t, err)
+	assert.Equal(t, "json", codec.Name())
+
+	codec, err = CodecByContentType("application/x-protobuf")
+	assert.Nil(t, err)
+	assert.Equal(t, "proto", codec.Name())
+
+	_, err = CodecByContentType("application/avro")
+	assert.ErrorIs(t, err, ErrUnsupportedContentType)
+
+	assert.Equal(t, "application/json", ContentType(encoding.GetCodec("json")))
+	assert.Equal(t, "", ContentType(nil))
+}
+
+func TestContentNegotiation(t *testing.T) {
+	options := NewOptionsAndApply(WithCodec("proto"), WithContentNegotiation("json"))
+
+	_, opts, err := options.InterceptPublish(context.Background(), "orders", []byte{}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "application/proto", NewPublishOptions(opts...).Headers[ContentTypeHeader])
+
+	var legacy map[string]string
+	headers := Headers{}
+	assert.Nil(t, options.Decode(nil, headers, []byte(`{"id":"1"}`), &legacy))
+	assert.Equal(t, "1", legacy["id"])
+	assert.Equal(t, "application/json", Message{Headers: headers}.ContentType())
+
+	var tagged map[string]string
+	headers = Headers{ContentTypeHeader: "application/json"}
+	assert.Nil(t, options.Decode(nil, headers, []byte(`{"id":"2"}`), &tagged))
+	assert.Equal(t, "2", tagged["id"])
+
+	headers = Headers{ContentTypeHeader: "application/avro"}
+	assert.ErrorIs(t, options.Decode(nil, headers, []byte{}, &tagged), ErrUnsupportedContentType)
+
+	raw := NewSubscribeOptions(WithRawBody())
+	assert.Nil(t, options.Decode(&raw, headers, []byte{}, &tagged))
+}
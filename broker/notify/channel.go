@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+)
+
+// Message is a notification rendered for a channel.
+type Message struct {
+	Event   string
+	To      []string
+	Subject string
+	Body    string
+	Data    map[string]interface{}
+}
+
+// Channel sends the messages, e.g. by email, SMS or push.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, msg *Message) error
+}
+
+type channelFunc struct {
+	name string
+	send func(ctx context.Context, msg *Message) error
+}
+
+func (c *channelFunc) Name() string { return c.name }
+
+func (c *channelFunc) Send(ctx context.Context, msg *Message) error { return c.send(ctx, msg) }
+
+// ChannelFunc adapt fn to the channel named name.
+func ChannelFunc(name string, fn func(ctx context.Context, msg *Message) error) Channel {
+	return &channelFunc{name: name, send: fn}
+}
+
+// SMTP send the messages as plain text emails from from through the server
+// at addr, auth may be nil.
+func SMTP(name, addr string, auth smtp.Auth, from string) Channel {
+	return ChannelFunc(name, func(_ context.Context, msg *Message) error {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "From: %s\r\n", from)
+		fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+		fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+		buf.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+		buf.WriteString(msg.Body)
+
+		return smtp.SendMail(addr, auth, from, msg.To, buf.Bytes())
+	})
+}
+
+// Webhook POST the messages as JSON to url, e.g. to a chat or an SMS
+// gateway. A response out of 2xx fails the send.
+func Webhook(name, url string, client *http.Client) Channel {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return ChannelFunc(name, func(ctx context.Context, msg *Message) error {
+		body, err := json.Marshal(struct {
+			Event   string                 `json:"event"`
+			To      []string               `json:"to"`
+			Subject string                 `json:"subject,omitempty"`
+			Body    string                 `json:"body"`
+			Data    map[string]interface{} `json:"data,omitempty"`
+		}{msg.Event, msg.To, msg.Subject, msg.Body, msg.Data})
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("notify: webhook [%s] responded %s", name, resp.Status)
+		}
+		return nil
+	})
+}
+
+// PushProvider sends push notifications to device tokens, e.g. with FCM or APNs.
+type PushProvider interface {
+	Push(ctx context.Context, tokens []string, title, body string, data map[string]string) error
+}
+
+// Push send the messages through p, the subject is the title of the push
+// and the data is passed as strings.
+func Push(name string, p PushProvider) Channel {
+	return ChannelFunc(name, func(ctx context.Context, msg *Message) error {
+		data := make(map[string]string, len(msg.Data))
+		for k, v := range msg.Data {
+			data[k] = fmt.Sprint(v)
+		}
+		return p.Push(ctx, msg.To, msg.Subject, msg.Body, data)
+	})
+}
@@ -0,0 +1,182 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+const DefaultTopic = "notifications"
+
+var (
+	ErrAlreadyStarted = errors.New("notify: already started")
+	ErrNoTemplate     = errors.New("notify: no template")
+	ErrUnknownChannel = errors.New("notify: unknown channel")
+)
+
+// Notification is the message of the notifications topic, the recipients
+// are the addresses per channel, e.g. {"email": ["a@example.com"]}.
+type Notification struct {
+	ID         string                 `json:"id,omitempty"`
+	Event      string                 `json:"event"`
+	Recipients map[string][]string    `json:"recipients"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+}
+
+// Rule route the notifications of Event, or of every event when "*", to Channels.
+type Rule struct {
+	Event    string
+	Channels []string
+}
+
+func (r Rule) match(event string) bool {
+	return r.Event == "*" || r.Event == event
+}
+
+// Notifier consumes the notifications topic and fans every notification out
+// to its channels, rendered with the templates of its event:
+//
+//	tpl := notify.NewTemplates()
+//	_ = tpl.Add("order.shipped", "", "Order {{.order}} shipped", "Hello {{.name}}, ...")
+//	n := notify.New(b,
+//		notify.WithChannel(notify.SMTP("email", "smtp.example.com:587", auth, "shop@example.com")),
+//		notify.WithChannel(notify.Push("push", fcm)),
+//		notify.WithTemplates(tpl),
+//		notify.WithRule(notify.Rule{Event: "order.shipped", Channels: []string{"email", "push"}}),
+//	)
+//	_ = n.Start(ctx)
+//
+// A notification is sent to the channels of the first rule matching its
+// event, or to every channel it has recipients for when none matches. A
+// failed channel fails the message, so it is redelivered to all of them:
+// send the notifications which must not be duplicated through a single
+// channel.
+type Notifier struct {
+	b     broker.Broker
+	topic string
+
+	channels      map[string]Channel
+	templates     *Templates
+	rules         []Rule
+	subscribeOpts []broker.SubscribeOption
+
+	mu  sync.Mutex
+	sub broker.Subscriber
+}
+
+func New(b broker.Broker, opts ...Option) *Notifier {
+	n := &Notifier{
+		b:         b,
+		topic:     DefaultTopic,
+		channels:  make(map[string]Channel),
+		templates: NewTemplates(),
+	}
+
+	for _, o := range opts {
+		o(n)
+	}
+
+	return n
+}
+
+// Notify publish notification to the notifications topic.
+func (n *Notifier) Notify(ctx context.Context, notification *Notification, opts ...broker.PublishOption) error {
+	return n.b.Publish(ctx, n.topic, notification, opts...)
+}
+
+// Start subscribe to the notifications topic.
+func (n *Notifier) Start(_ context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.sub != nil {
+		return ErrAlreadyStarted
+	}
+
+	sub, err := n.b.Subscribe(n.topic, n.Handle, func() broker.Any {
+		return &Notification{}
+	}, n.subscribeOpts...)
+	if err != nil {
+		return err
+	}
+	n.sub = sub
+
+	return nil
+}
+
+// Stop unsubscribe from the notifications topic.
+func (n *Notifier) Stop(_ context.Context) error {
+	n.mu.Lock()
+	sub := n.sub
+	n.sub = nil
+	n.mu.Unlock()
+
+	if sub == nil {
+		return nil
+	}
+	return sub.Unsubscribe(true)
+}
+
+// Handle send the notification of event, for the subscriptions made apart.
+func (n *Notifier) Handle(ctx context.Context, event broker.Event) error {
+	notification, ok := event.Message().Body.(*Notification)
+	if !ok {
+		log.Warnf("[notify] ignore message of type %T", event.Message().Body)
+		return nil
+	}
+	return n.Send(ctx, notification)
+}
+
+// Send render and send notification to its channels right away.
+func (n *Notifier) Send(ctx context.Context, notification *Notification) error {
+	var errs []error
+	for _, name := range n.route(notification) {
+		to := notification.Recipients[name]
+		if len(to) == 0 {
+			continue
+		}
+
+		c, ok := n.channels[name]
+		if !ok {
+			log.Errorf("[notify] %v [%s] for [%s]", ErrUnknownChannel, name, notification.Event)
+			continue
+		}
+
+		subject, body, err := n.templates.Render(notification.Event, name, notification.Data)
+		if err != nil {
+			log.Errorf("[notify] render [%s] for [%s] failed: %v", notification.Event, name, err)
+			continue
+		}
+
+		if err = c.Send(ctx, &Message{
+			Event:   notification.Event,
+			To:      to,
+			Subject: subject,
+			Body:    body,
+			Data:    notification.Data,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("notify: send [%s] through [%s]: %w", notification.Event, name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// route return the channels of notification.
+func (n *Notifier) route(notification *Notification) []string {
+	for _, r := range n.rules {
+		if r.match(notification.Event) {
+			return r.Channels
+		}
+	}
+
+	channels := make([]string, 0, len(notification.Recipients))
+	for name := range notification.Recipients {
+		channels = append(channels, name)
+	}
+	return channels
+}
@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+type recordChannel struct {
+	name string
+	sent []*Message
+	err  error
+}
+
+func (c *recordChannel) Name() string { return c.name }
+
+func (c *recordChannel) Send(_ context.Context, msg *Message) error {
+	c.sent = append(c.sent, msg)
+	return c.err
+}
+
+func TestNotifier(t *testing.T) {
+	ctx := context.Background()
+	b := mocks.NewFakeBroker(broker.WithCodec("json"))
+	assert.Nil(t, b.Connect())
+
+	tpl := NewTemplates()
+	assert.Nil(t, tpl.Add("order.shipped", "", "Order {{.order}} shipped", "Hello {{.name}}"))
+	assert.Nil(t, tpl.Add("order.shipped", "sms", "", "Order {{.order}} shipped"))
+
+	email := &recordChannel{name: "email"}
+	sms := &recordChannel{name: "sms"}
+	n := New(b, WithChannel(email), WithChannel(sms), WithTemplates(tpl),
+		WithRule(Rule{Event: "order.shipped", Channels: []string{"email", "sms"}}))
+
+	assert.Nil(t, n.Start(ctx))
+	assert.ErrorIs(t, n.Start(ctx), ErrAlreadyStarted)
+
+	assert.Nil(t, n.Notify(ctx, &Notification{
+		Event:      "order.shipped",
+		Recipients: map[string][]string{"email": {"alice@example.com"}, "sms": {"+33600000000"}},
+		Data:       map[string]interface{}{"order": "42", "name": "Alice"},
+	}))
+
+	if assert.Len(t, email.sent, 1) {
+		assert.Equal(t, []string{"alice@example.com"}, email.sent[0].To)
+		assert.Equal(t, "Order 42 shipped", email.sent[0].Subject)
+		assert.Equal(t, "Hello Alice", email.sent[0].Body)
+	}
+	if assert.Len(t, sms.sent, 1) {
+		assert.Equal(t, "Order 42 shipped", sms.sent[0].Body)
+	}
+
+	assert.Nil(t, n.Stop(ctx))
+	assert.Nil(t, n.Stop(ctx))
+}
+
+func TestNotifier_Send(t *testing.T) {
+	ctx := context.Background()
+	tpl := NewTemplates()
+	assert.Nil(t, tpl.Add("welcome", "", "Welcome", "Hi {{.name}}"))
+
+	failed := errors.New("unavailable")
+	email := &recordChannel{name: "email", err: failed}
+	push := &recordChannel{name: "push"}
+	n := New(mocks.NewFakeBroker(), WithChannel(email), WithChannel(push), WithTemplates(tpl))
+
+	err := n.Send(ctx, &Notification{
+		Event:      "welcome",
+		Recipients: map[string][]string{"email": {"bob@example.com"}, "push": {"token"}},
+		Data:       map[string]interface{}{"name": "Bob"},
+	})
+	assert.ErrorIs(t, err, failed)
+	assert.Len(t, push.sent, 1, "routed to the channels with recipients")
+
+	err = n.Send(ctx, &Notification{Event: "unknown", Recipients: map[string][]string{"push": {"token"}}})
+	assert.Nil(t, err, "dropped without template")
+	assert.Len(t, push.sent, 1)
+
+	_, _, err = tpl.Render("welcome", "push", map[string]interface{}{})
+	assert.Error(t, err, "missing key")
+}
+
+func TestWebhook(t *testing.T) {
+	var received map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		if r.URL.Path != "/sms" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	msg := &Message{Event: "welcome", To: []string{"+33600000000"}, Body: "Hi"}
+	assert.Nil(t, Webhook("sms", srv.URL+"/sms", nil).Send(context.Background(), msg))
+	assert.Equal(t, "Hi", received["body"])
+
+	assert.Error(t, Webhook("sms", srv.URL+"/down", nil).Send(context.Background(), msg))
+}
+
+type fakePush struct {
+	tokens []string
+	data   map[string]string
+}
+
+func (p *fakePush) Push(_ context.Context, tokens []string, _, _ string, data map[string]string) error {
+	p.tokens, p.data = tokens, data
+	return nil
+}
+
+func TestPush(t *testing.T) {
+	p := &fakePush{}
+	err := Push("push", p).Send(context.Background(), &Message{To: []string{"token"}, Data: map[string]interface{}{"order": 42}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"token"}, p.tokens)
+	assert.Equal(t, map[string]string{"order": "42"}, p.data)
+}
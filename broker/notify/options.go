@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(n *Notifier)
+
+// WithTopic set the topic of the notifications, default is "notifications".
+func WithTopic(topic string) Option {
+	return func(n *Notifier) {
+		n.topic = topic
+	}
+}
+
+// WithChannel add the channel c.
+func WithChannel(c Channel) Option {
+	return func(n *Notifier) {
+		n.channels[c.Name()] = c
+	}
+}
+
+// WithTemplates set the templates of the notifications.
+func WithTemplates(t *Templates) Option {
+	return func(n *Notifier) {
+		n.templates = t
+	}
+}
+
+// WithRule add a routing rule, the rules are matched in order.
+func WithRule(r Rule) Option {
+	return func(n *Notifier) {
+		n.rules = append(n.rules, r)
+	}
+}
+
+// WithSubscribeOptions add opts to the subscription of the topic.
+func WithSubscribeOptions(opts ...broker.SubscribeOption) Option {
+	return func(n *Notifier) {
+		n.subscribeOpts = append(n.subscribeOpts, opts...)
+	}
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"text/template"
+)
+
+// Templates render the notifications for each event and channel, with
+// text/template and the data of the notification as dot.
+type Templates struct {
+	mu sync.RWMutex
+	m  map[string]*messageTemplate
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+func NewTemplates() *Templates {
+	return &Templates{m: make(map[string]*messageTemplate)}
+}
+
+// Add parse the subject and body templates of event for channel, an empty
+// channel is the template of the channels without their own.
+func (t *Templates) Add(event, channel, subject, body string) error {
+	name := templateName(event, channel)
+
+	st, err := template.New(name + ".subject").Option("missingkey=error").Parse(subject)
+	if err != nil {
+		return err
+	}
+	bt, err := template.New(name + ".body").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.m[name] = &messageTemplate{subject: st, body: bt}
+	return nil
+}
+
+// Render return the subject and body of event for channel.
+func (t *Templates) Render(event, channel string, data map[string]interface{}) (string, string, error) {
+	t.mu.RLock()
+	mt, ok := t.m[templateName(event, channel)]
+	if !ok {
+		mt, ok = t.m[templateName(event, "")]
+	}
+	t.mu.RUnlock()
+
+	if !ok {
+		return "", "", fmt.Errorf("%w [%s] on [%s]", ErrNoTemplate, event, channel)
+	}
+
+	var subject, body bytes.Buffer
+	if err := mt.subject.Execute(&subject, data); err != nil {
+		return "", "", err
+	}
+	if err := mt.body.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	return subject.String(), body.String(), nil
+}
+
+func templateName(event, channel string) string {
+	return event + "/" + channel
+}
@@ -0,0 +1,145 @@
+package nsq
+
+import (
+	"time"
+
+	NSQ "github.com/nsqio/go-nsq"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed nsq configuration of a broker, the fields are the
+// ones set one by one with the broker options of the package.
+type Config struct {
+	LookupdAddrs        []string
+	LookupdPollInterval time.Duration
+	ConsumerOptions     []string
+}
+
+// WithConfig set the whole configuration at once, the zero fields are left
+// unset. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		var opts []broker.Option
+		if c.LookupdAddrs != nil {
+			opts = append(opts, WithLookupdAddress(c.LookupdAddrs))
+		}
+		if c.LookupdPollInterval != 0 {
+			opts = append(opts, WithLookupdPollInterval(c.LookupdPollInterval))
+		}
+		if c.ConsumerOptions != nil {
+			opts = append(opts, WithConsumerOptions(c.ConsumerOptions))
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	var c Config
+	if opts.Context == nil {
+		return c
+	}
+
+	c.LookupdAddrs, _ = opts.Context.Value(lookupdAddrsKey{}).([]string)
+	c.LookupdPollInterval, _ = opts.Context.Value(lookupdPollIntervalKey{}).(time.Duration)
+	c.ConsumerOptions, _ = opts.Context.Value(consumerOptsKey{}).([]string)
+
+	return c
+}
+
+// SubscribeConfig is the typed nsq configuration of a subscription.
+type SubscribeConfig struct {
+	ConcurrentHandlers int
+	MaxInFlight        int
+	// RequeueDelay is negative to let nsqd compute the delay and back off.
+	RequeueDelay time.Duration
+	// MaxAttempts replaces the limit of the broker config when not nil.
+	MaxAttempts *uint16
+}
+
+// DefaultSubscribeConfig return the configuration of a subscription without
+// options.
+func DefaultSubscribeConfig() SubscribeConfig {
+	return SubscribeConfig{
+		ConcurrentHandlers: DefaultConcurrentHandlers,
+		MaxInFlight:        DefaultConcurrentHandlers,
+		RequeueDelay:       -1,
+	}
+}
+
+// WithSubscribeConfig set the whole subscription configuration at once, c
+// should start from DefaultSubscribeConfig since every field is applied.
+func WithSubscribeConfig(c SubscribeConfig) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		opts := []broker.SubscribeOption{
+			WithConcurrentHandlers(c.ConcurrentHandlers),
+			WithMaxInFlight(c.MaxInFlight),
+			WithRequeueDelay(c.RequeueDelay),
+		}
+		if c.MaxAttempts != nil {
+			opts = append(opts, WithMaxAttempts(*c.MaxAttempts))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// SubscribeConfigFromOptions return the subscription configuration set in
+// the context of opts, WithConcurrentHandlers also sets the max in flight.
+func SubscribeConfigFromOptions(opts broker.SubscribeOptions) SubscribeConfig {
+	c := DefaultSubscribeConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if val, ok := opts.Context.Value(concurrentHandlerKey{}).(int); ok {
+		c.ConcurrentHandlers, c.MaxInFlight = val, val
+	}
+	if val, ok := opts.Context.Value(maxInFlightKey{}).(int); ok {
+		c.MaxInFlight = val
+	}
+	if val, ok := opts.Context.Value(requeueDelayKey{}).(time.Duration); ok {
+		c.RequeueDelay = val
+	}
+	if val, ok := opts.Context.Value(maxAttemptsKey{}).(uint16); ok {
+		c.MaxAttempts = &val
+	}
+
+	return c
+}
+
+// PublishConfig is the typed nsq configuration of a publishing, the zero
+// fields are left unset.
+type PublishConfig struct {
+	AsyncPublish    chan *NSQ.ProducerTransaction
+	DeferredPublish time.Duration
+}
+
+// WithPublishConfig set the whole publishing configuration at once.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if c.AsyncPublish != nil {
+			WithAsyncPublish(c.AsyncPublish)(o)
+		}
+		if c.DeferredPublish != 0 {
+			WithDeferredPublish(c.DeferredPublish)(o)
+		}
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	var c PublishConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.AsyncPublish, _ = opts.Context.Value(asyncPublishKey{}).(chan *NSQ.ProducerTransaction)
+	c.DeferredPublish, _ = opts.Context.Value(deferredPublishKey{}).(time.Duration)
+
+	return c
+}
@@ -0,0 +1,63 @@
+package nsq
+
+import (
+	"testing"
+	"time"
+
+	NSQ "github.com/nsqio/go-nsq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, Config{}, ConfigFromOptions(broker.NewOptions()))
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithLookupdAddress([]string{"127.0.0.1:4161"}),
+		WithLookupdPollInterval(time.Second),
+		WithConsumerOptions([]string{"max_attempts=3"}),
+	))
+
+	c := Config{
+		LookupdAddrs:        []string{"127.0.0.1:4161"},
+		LookupdPollInterval: time.Second,
+		ConsumerOptions:     []string{"max_attempts=3"},
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c), WithLookupdPollInterval(time.Minute)))
+	assert.Equal(t, time.Minute, overridden.LookupdPollInterval)
+}
+
+func TestSubscribeConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultSubscribeConfig(), SubscribeConfigFromOptions(broker.NewSubscribeOptions()))
+
+	legacy := SubscribeConfigFromOptions(broker.NewSubscribeOptions(
+		WithConcurrentHandlers(4),
+		WithMaxInFlight(8),
+		WithMaxAttempts(5),
+	))
+
+	attempts := uint16(5)
+	c := SubscribeConfig{
+		ConcurrentHandlers: 4,
+		MaxInFlight:        8,
+		RequeueDelay:       -1,
+		MaxAttempts:        &attempts,
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c))))
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	assert.Equal(t, PublishConfig{}, PublishConfigFromOptions(broker.PublishOptions{}))
+
+	c := PublishConfig{
+		AsyncPublish:    make(chan *NSQ.ProducerTransaction, 1),
+		DeferredPublish: time.Second,
+	}
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+}
@@ -19,6 +19,9 @@ var (
 
 const (
 	defaultAddr = "127.0.0.1:4150"
+
+	// defaultMaxMessageSize is the default --max-msg-size of nsqd.
+	defaultMaxMessageSize = 1024 * 1024
 )
 
 type nsqBroker struct {
@@ -35,10 +38,12 @@ type nsqBroker struct {
 	producers []*NSQ.Producer
 
 	subscribers *broker.SubscriberSyncMap
+
+	metrics *broker.Metrics
 }
 
 func NewBroker(opts ...broker.Option) broker.Broker {
-	options := broker.NewOptionsAndApply(opts...)
+	options := broker.NewOptionsAndApply(append([]broker.Option{broker.WithMaxMessageSize(defaultMaxMessageSize)}, opts...)...)
 
 	b := &nsqBroker{
 		options: options,
@@ -56,6 +61,11 @@ func (b *nsqBroker) Name() string {
 	return "NSQ"
 }
 
+// CarriesHeaders is false, NSQ messages are bare payloads.
+func (b *nsqBroker) CarriesHeaders() bool {
+	return false
+}
+
 func (b *nsqBroker) Options() broker.Options {
 	return b.options
 }
@@ -86,21 +96,25 @@ func (b *nsqBroker) Init(opts ...broker.Option) error {
 	}
 
 	b.addrs = addrs
-	b.configure(b.options.Context)
+	b.configure(ConfigFromOptions(b.options))
+
+	b.metrics = broker.NewMetrics("nsq", b.options.MeterProvider)
 
 	return nil
 }
 
-func (b *nsqBroker) configure(ctx context.Context) {
-	if v, ok := ctx.Value(lookupdAddrsKey{}).([]string); ok {
-		b.lookupAddrs = v
+func (b *nsqBroker) configure(c Config) {
+	if c.LookupdAddrs != nil {
+		b.lookupAddrs = c.LookupdAddrs
 	}
 
-	if v, ok := ctx.Value(consumerOptsKey{}).([]string); ok {
-		cfgFlag := &NSQ.ConfigFlag{Config: b.config}
-		for _, opt := range v {
-			_ = cfgFlag.Set(opt)
-		}
+	if c.LookupdPollInterval != 0 {
+		b.config.LookupdPollInterval = c.LookupdPollInterval
+	}
+
+	cfgFlag := &NSQ.ConfigFlag{Config: b.config}
+	for _, opt := range c.ConsumerOptions {
+		_ = cfgFlag.Set(opt)
 	}
 }
 
@@ -136,6 +150,7 @@ func (b *nsqBroker) Connect() error {
 
 		var cm *NSQ.Consumer
 		if cm, err = NSQ.NewConsumer(c.topic, channel, b.config); err != nil {
+			c.options.ReportError(c.options.Context, broker.ErrResubscribe, err, nil)
 			return
 		}
 
@@ -149,6 +164,7 @@ func (b *nsqBroker) Connect() error {
 			_ = c.consumer.ConnectToNSQLookupds(b.lookupAddrs)
 		} else {
 			if err = c.consumer.ConnectToNSQDs(b.addrs); err != nil {
+				c.options.ReportError(c.options.Context, broker.ErrResubscribe, err, nil)
 				return
 			}
 		}
@@ -195,12 +211,16 @@ func (b *nsqBroker) Disconnect() error {
 }
 
 func (b *nsqBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
-	buf, err := broker.Marshal(b.options.Codec, msg)
+	buf, options, err := b.newMessage(ctx, topic, msg, opts)
 	if err != nil {
 		return err
 	}
 
-	return b.publish(ctx, topic, buf, opts...)
+	start := time.Now()
+	err = b.publish(topic, buf, options)
+	b.metrics.RecordPublish(ctx, topic, start, err)
+
+	return err
 }
 
 func (b *nsqBroker) getProducer() *NSQ.Producer {
@@ -211,27 +231,37 @@ func (b *nsqBroker) getProducer() *NSQ.Producer {
 	return b.producers[rand.Intn(producerLen)]
 }
 
-func (b *nsqBroker) publish(ctx context.Context, topic string, msg []byte, opts ...broker.PublishOption) error {
+// newMessage encode msg and run the publish interceptors, it returns the
+// payload to publish to topic with the options of the publish. The publishes
+// with headers are refused, NSQ has no room for them.
+func (b *nsqBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) ([]byte, broker.PublishOptions, error) {
 	options := broker.PublishOptions{
 		Context: ctx,
 	}
+
+	buf, err := broker.Marshal(b.options.Codec, msg)
+	if err != nil {
+		return nil, options, err
+	}
+	buf, opts, err = b.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return nil, options, err
+	}
+
 	for _, o := range opts {
 		o(&options)
 	}
-
-	var (
-		doneChan chan *NSQ.ProducerTransaction
-		delay    time.Duration
-	)
-	if options.Context != nil {
-		if v, ok := options.Context.Value(asyncPublishKey{}).(chan *NSQ.ProducerTransaction); ok {
-			doneChan = v
-		}
-		if v, ok := options.Context.Value(deferredPublishKey{}).(time.Duration); ok {
-			delay = v
-		}
+	if err = broker.RejectHeaders(topic, options.Headers); err != nil {
+		return nil, options, err
 	}
 
+	return buf, options, nil
+}
+
+func (b *nsqBroker) publish(topic string, msg []byte, options broker.PublishOptions) error {
+	c := PublishConfigFromOptions(options)
+	doneChan, delay := c.AsyncPublish, c.DeferredPublish
+
 	p := b.getProducer()
 	if p == nil {
 		return errors.New("producer is null")
@@ -259,18 +289,17 @@ func (b *nsqBroker) Subscribe(topic string, handler broker.Handler, binder broke
 		o(&options)
 	}
 
-	concurrency, maxInFlight := DefaultConcurrentHandlers, DefaultConcurrentHandlers
-	if options.Context != nil {
-		if v, ok := options.Context.Value(concurrentHandlerKey{}).(int); ok {
-			maxInFlight, concurrency = v, v
-		}
-		if v, ok := options.Context.Value(maxInFlightKey{}).(int); ok {
-			maxInFlight = v
-		}
-	}
+	handler = options.TimeoutHandler(handler)
+	handler = b.metrics.Handler(topic, handler)
+
+	sc := SubscribeConfigFromOptions(options)
+	concurrency, requeueDelay := sc.ConcurrentHandlers, sc.RequeueDelay
 
 	config := *b.config
-	config.MaxInFlight = maxInFlight
+	if sc.MaxAttempts != nil {
+		config.MaxAttempts = *sc.MaxAttempts
+	}
+	config.MaxInFlight = sc.MaxInFlight
 
 	channel := options.Queue
 	if len(channel) == 0 {
@@ -282,42 +311,18 @@ func (b *nsqBroker) Subscribe(topic string, handler broker.Handler, binder broke
 		return nil, err
 	}
 
-	h := NSQ.HandlerFunc(func(nm *NSQ.Message) error {
-		if !options.AutoAck {
-			nm.DisableAutoResponse()
-		}
-
-		//fmt.Println("receive message:", nm.ID, nm.Body)
-
-		var m broker.Message
-		var errSub error
-
-		if binder != nil {
-			m.Body = binder()
-		} else {
-			m.Body = nm.Body
-		}
-
-		p := &publication{topic: topic, nsqMsg: nm, msg: &m}
-
-		if errSub = broker.Unmarshal(b.options.Codec, nm.Body, &m.Body); errSub != nil {
-			p.err = errSub
-			return errSub
-		}
-
-		if errSub = handler(b.options.Context, p); errSub != nil {
-			p.err = errSub
-			return errSub
-		}
-
-		if options.AutoAck {
-			if errSub = p.Ack(); err != nil {
-				log.Errorf("[nats]: unable to commit msg: %v", errSub)
-			}
-		}
+	sub := &subscriber{
+		n:           b,
+		consumer:    c,
+		options:     options,
+		topic:       topic,
+		concurrency: concurrency,
+	}
 
-		return p.err
+	h := NSQ.HandlerFunc(func(nm *NSQ.Message) error {
+		return b.handleMessage(sub, nm, handler, binder, requeueDelay)
 	})
+	sub.handlerFunc = h
 
 	c.AddConcurrentHandlers(h, concurrency)
 
@@ -330,16 +335,74 @@ func (b *nsqBroker) Subscribe(topic string, handler broker.Handler, binder broke
 		return nil, err
 	}
 
-	sub := &subscriber{
-		n:           b,
-		consumer:    c,
-		options:     options,
-		topic:       topic,
-		handlerFunc: h,
-		concurrency: concurrency,
-	}
-
 	b.subscribers.Add(topic, sub)
 
 	return sub, nil
 }
+
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (b *nsqBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
+
+func (b *nsqBroker) handleMessage(sub *subscriber, nm *NSQ.Message, handler broker.Handler, binder broker.Binder, requeueDelay time.Duration) error {
+	if !sub.options.AutoAck {
+		nm.DisableAutoResponse()
+	}
+
+	var m broker.Message
+	var errSub error
+
+	if binder != nil && !sub.options.RawBody {
+		m.Body = binder()
+	} else {
+		m.Body = nm.Body
+	}
+
+	p := &publication{topic: sub.topic, nsqMsg: nm, msg: &m}
+
+	if errSub = b.options.Decode(&sub.options, nil, nm.Body, &m.Body); errSub != nil {
+		p.err = errSub
+		log.Errorf("[nsq]: unmarshal message failed: %v", errSub)
+		sub.options.ReportError(b.options.Context, broker.ErrUnmarshal, errSub, p)
+
+		switch sub.options.HandleUnmarshalFailure(b.options.Context, sub.topic, nil, nm.Body, errSub) {
+		case broker.UnmarshalActionAck:
+			if errSub = p.Ack(); errSub != nil {
+				sub.options.ReportError(b.options.Context, broker.ErrAck, errSub, p)
+			}
+			return nil
+		case broker.UnmarshalActionStop:
+			// the consumer stops asynchronously, the message is requeued for the next one.
+			b.requeue(nm, requeueDelay)
+			_ = sub.Unsubscribe(true)
+			return errSub
+		}
+		b.requeue(nm, requeueDelay)
+		return errSub
+	}
+
+	if errSub = handler(b.options.Context, p); errSub != nil {
+		p.err = errSub
+		b.requeue(nm, requeueDelay)
+		return errSub
+	}
+
+	if sub.options.AutoAck {
+		if errSub = broker.AutoAck(p); errSub != nil {
+			log.Errorf("[nsq]: unable to commit msg: %v", errSub)
+			sub.options.ReportError(b.options.Context, broker.ErrAck, errSub, p)
+		}
+	}
+
+	return p.err
+}
+
+// requeue the failed message with delay, a negative delay leaves it to the
+// consumer which requeues with backoff unless auto response is disabled.
+func (b *nsqBroker) requeue(nm *NSQ.Message, delay time.Duration) {
+	if delay < 0 || nm.HasResponded() {
+		return
+	}
+	nm.RequeueWithoutBackoff(delay)
+}
@@ -13,11 +13,18 @@ import (
 
 type lookupdAddrsKey struct{}
 type consumerOptsKey struct{}
+type lookupdPollIntervalKey struct{}
 
 func WithLookupdAddress(addrs []string) broker.Option {
 	return broker.OptionContextWithValue(lookupdAddrsKey{}, addrs)
 }
 
+// WithLookupdPollInterval set how often the consumers query nsqlookupd for
+// the nsqd producing their topics.
+func WithLookupdPollInterval(interval time.Duration) broker.Option {
+	return broker.OptionContextWithValue(lookupdPollIntervalKey{}, interval)
+}
+
 func WithConsumerOptions(consumerOpts []string) broker.Option {
 	return broker.OptionContextWithValue(consumerOptsKey{}, consumerOpts)
 }
@@ -43,6 +50,8 @@ func WithDeferredPublish(delay time.Duration) broker.PublishOption {
 
 type concurrentHandlerKey struct{}
 type maxInFlightKey struct{}
+type requeueDelayKey struct{}
+type maxAttemptsKey struct{}
 
 func WithConcurrentHandlers(n int) broker.SubscribeOption {
 	return broker.SubscribeContextWithValue(concurrentHandlerKey{}, n)
@@ -51,3 +60,16 @@ func WithConcurrentHandlers(n int) broker.SubscribeOption {
 func WithMaxInFlight(n int) broker.SubscribeOption {
 	return broker.SubscribeContextWithValue(maxInFlightKey{}, n)
 }
+
+// WithRequeueDelay requeue the message after delay when the handler fails,
+// without backing off the consumer. By default the message is requeued with
+// a delay computed from its attempts and the consumer backs off.
+func WithRequeueDelay(delay time.Duration) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(requeueDelayKey{}, delay)
+}
+
+// WithMaxAttempts finish the message without handling it once it has been
+// delivered more than n times, zero means no limit.
+func WithMaxAttempts(n uint16) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(maxAttemptsKey{}, n)
+}
@@ -7,6 +7,8 @@ import (
 )
 
 type publication struct {
+	broker.AckState
+
 	topic   string
 	msg     *broker.Message
 	nsqMsg  *NSQ.Message
@@ -27,13 +29,15 @@ func (p *publication) RawMessage() interface{} {
 }
 
 func (p *publication) Ack() error {
-	if p.nsqMsg == nil {
-		p.err = errors.New("nsq message is nil")
-		return p.err
-	}
-
-	p.nsqMsg.Finish()
-	return nil
+	return p.AckState.Ack(func() error {
+		if p.nsqMsg == nil {
+			p.err = errors.New("nsq message is nil")
+			return p.err
+		}
+
+		p.nsqMsg.Finish()
+		return nil
+	})
 }
 
 func (p *publication) Error() error {
@@ -0,0 +1,22 @@
+package nsq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func TestPublishPath(t *testing.T) {
+	mocks.TestPublishPath(t, func(opts []broker.Option, topic string, msg broker.Any, popts ...broker.PublishOption) (broker.Headers, []byte, error) {
+		b := NewBroker(opts...).(*nsqBroker)
+
+		buf, _, err := b.newMessage(context.Background(), topic, msg, popts)
+		return nil, buf, err
+	}, false)
+
+	assert.False(t, broker.CarriesHeaders(NewBroker()))
+}
@@ -0,0 +1,41 @@
+package nsq
+
+import (
+	"testing"
+	"time"
+
+	NSQ "github.com/nsqio/go-nsq"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+// responseRecorder records how the messages are responded to.
+type responseRecorder struct {
+	finished, requeued int
+}
+
+func (r *responseRecorder) OnFinish(*NSQ.Message) {
+	r.finished++
+}
+
+func (r *responseRecorder) OnRequeue(*NSQ.Message, time.Duration, bool) {
+	r.requeued++
+}
+
+func (r *responseRecorder) OnTouch(*NSQ.Message) {}
+
+func newTestMessage(body []byte, delegate NSQ.MessageDelegate) *NSQ.Message {
+	nm := NSQ.NewMessage(NSQ.MessageID{}, body)
+	nm.Delegate = delegate
+	return nm
+}
+
+func TestSubscribePath(t *testing.T) {
+	mocks.TestSubscribePath(t, func(opts []broker.Option, topic string, _ broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) {
+		b := NewBroker(opts...).(*nsqBroker)
+		sub := &subscriber{n: b, options: broker.NewSubscribeOptions(sopts...), topic: topic}
+
+		_ = b.handleMessage(sub, newTestMessage(body, &responseRecorder{}), handler, binder, 0)
+	}, false)
+}
@@ -3,7 +3,11 @@ package broker
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
 
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 
@@ -31,6 +35,31 @@ type Options struct {
 	Context context.Context
 
 	Tracings []tracing.Option
+
+	MeterProvider metric.MeterProvider
+
+	// PublishInterceptors run in order on every message before the driver publishes it.
+	PublishInterceptors []PublishInterceptor
+
+	// NegotiateContent stamps and reads the content type of the messages.
+	NegotiateContent bool
+	// DefaultContentCodec decodes the messages without content type, Codec when nil.
+	DefaultContentCodec encoding.Codec
+
+	// TopicDefaults are applied before the options of every publish to a matching topic.
+	TopicDefaults []TopicDefaults
+
+	// HeaderCodec serialize the typed header values of the drivers to Headers.
+	HeaderCodec HeaderCodec
+
+	// MaxMessageSize is the largest encoded body published, zero means no limit.
+	MaxMessageSize int
+
+	// Clock is the time source of the timers of the driver.
+	Clock Clock
+
+	// ServiceName is recorded in the lineage hops of the messages republished with the broker.
+	ServiceName string
 }
 
 type Option func(*Options)
@@ -54,6 +83,10 @@ func NewOptions() Options {
 		Context: context.Background(),
 
 		Tracings: []tracing.Option{},
+
+		HeaderCodec: DefaultHeaderCodec,
+
+		Clock: SystemClock,
 	}
 
 	return opt
@@ -96,6 +129,13 @@ func WithCodec(name string) Option {
 	}
 }
 
+// WithHeaderCodec set the codec of the typed header values, DefaultHeaderCodec by default.
+func WithHeaderCodec(codec HeaderCodec) Option {
+	return func(o *Options) {
+		o.HeaderCodec = codec
+	}
+}
+
 func WithErrorHandler(handler Handler) Option {
 	return func(o *Options) {
 		o.ErrorHandler = handler
@@ -141,10 +181,27 @@ func WithGlobalPropagator() Option {
 	}
 }
 
+// WithTracingRedactedAttributes mask the value of these span attributes, e.g. message keys.
+func WithTracingRedactedAttributes(keys ...string) Option {
+	return func(opt *Options) {
+		opt.Tracings = append(opt.Tracings, tracing.WithRedactedAttributes(keys...))
+	}
+}
+
+// WithMeterProvider emit the messaging semantic-convention metrics through provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(opt *Options) {
+		opt.MeterProvider = provider
+	}
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 
 type PublishOptions struct {
 	Context context.Context
+
+	// Headers are added to the message by the driver, set by WithHeaders and the publish interceptors.
+	Headers Headers
 }
 
 type PublishOption func(*PublishOptions)
@@ -180,12 +237,60 @@ func WithPublishContext(ctx context.Context) PublishOption {
 	}
 }
 
+// WithHeaders add headers to the published message.
+func WithHeaders(headers Headers) PublishOption {
+	return func(o *PublishOptions) {
+		if o.Headers == nil {
+			o.Headers = Headers{}
+		}
+		for k, v := range headers {
+			o.Headers[k] = v
+		}
+	}
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 
+var (
+	ErrReceive     = errors.New("receive message failed")
+	ErrUnmarshal   = errors.New("unmarshal message failed")
+	ErrAck         = errors.New("ack message failed")
+	ErrResubscribe = errors.New("resubscribe failed")
+)
+
+// SubscribeErrorHandler receives the errors of a subscription the message
+// handler never sees, event is nil when the error is not about a message.
+type SubscribeErrorHandler func(ctx context.Context, err error, event Event)
+
 type SubscribeOptions struct {
 	AutoAck bool
 	Queue   string
 	Context context.Context
+
+	// HandlerTimeout bounds every handler invocation, zero means no limit.
+	HandlerTimeout time.Duration
+	// TimeoutParkBroker publishes the messages timing out to TimeoutParkTopic,
+	// they fail with ErrHandlerTimeout when nil.
+	TimeoutParkBroker Broker
+	TimeoutParkTopic  string
+
+	// ErrorHandler receives the errors the message handler never sees.
+	ErrorHandler SubscribeErrorHandler
+
+	// UnmarshalFailure decides what happens to the messages which cannot be decoded.
+	UnmarshalFailure UnmarshalFailure
+
+	// RawBody skips the binder and the codec, set by the SubscribeRaw implementations.
+	RawBody bool
+
+	// StartPosition is where a new subscription starts, nil keeps the broker default.
+	StartPosition *Position
+
+	// MaxMessageSize is the largest body decoded, zero means no limit.
+	MaxMessageSize int
+	// ParkBroker publishes the messages exceeding MaxMessageSize to ParkTopic.
+	ParkBroker Broker
+	ParkTopic  string
 }
 
 type SubscribeOption func(*SubscribeOptions)
@@ -234,3 +339,56 @@ func WithSubscribeContext(ctx context.Context) SubscribeOption {
 		o.Context = ctx
 	}
 }
+
+// WithSubscribeErrorHandler report the receive, resubscribe, unmarshal and
+// ack failures of the subscription, which are otherwise only logged.
+func WithSubscribeErrorHandler(handler SubscribeErrorHandler) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.ErrorHandler = handler
+	}
+}
+
+// ReportError pass err wrapped in kind to the error handler, if any.
+func (o *SubscribeOptions) ReportError(ctx context.Context, kind, err error, event Event) {
+	if o.ErrorHandler == nil || err == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	o.ErrorHandler(ctx, fmt.Errorf("%w: %w", kind, err), event)
+}
+
+// WithHandlerTimeout cancel the handler context after timeout, the message then fails
+// with ErrHandlerTimeout and is handled like any other handler error, i.e. nacked,
+// or parked with WithParkOnTimeout. The handler is not stopped, see TimeoutHandler.
+func WithHandlerTimeout(timeout time.Duration) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.HandlerTimeout = timeout
+	}
+}
+
+// WithParkOnTimeout republish the messages timing out to topic with b and
+// ack them, instead of failing them. The message fails with ErrHandlerTimeout
+// when the republish fails.
+func WithParkOnTimeout(b Broker, topic string) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.TimeoutParkBroker = b
+		o.TimeoutParkTopic = topic
+	}
+}
+
+// TimeoutHandler wrap handler with the HandlerTimeout and the timeout policy of the subscription.
+func (o *SubscribeOptions) TimeoutHandler(handler Handler) Handler {
+	if o.TimeoutParkBroker == nil {
+		return TimeoutHandler(handler, o.HandlerTimeout)
+	}
+
+	b, topic := o.TimeoutParkBroker, o.TimeoutParkTopic
+	return timeoutHandler(handler, o.HandlerTimeout, func(ctx context.Context, evt Event) error {
+		if err := Republish(ctx, b, evt, topic); err != nil {
+			return errors.Join(ErrHandlerTimeout, err)
+		}
+		return nil
+	})
+}
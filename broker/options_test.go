@@ -0,0 +1,44 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testEvent struct {
+	topic   string
+	message *Message
+}
+
+func (e *testEvent) Topic() string           { return e.topic }
+func (e *testEvent) Message() *Message       { return e.message }
+func (e *testEvent) RawMessage() interface{} { return nil }
+func (e *testEvent) Ack() error              { return nil }
+func (e *testEvent) Error() error            { return nil }
+
+func TestSubscribeReportError(t *testing.T) {
+	noHandler := NewSubscribeOptions()
+	noHandler.ReportError(context.Background(), ErrAck, errors.New("channel closed"), nil)
+
+	var reported error
+	var reportedEvent Event
+	options := NewSubscribeOptions(WithSubscribeErrorHandler(func(_ context.Context, err error, event Event) {
+		reported = err
+		reportedEvent = event
+	}))
+
+	options.ReportError(context.Background(), ErrUnmarshal, nil, nil)
+	assert.Nil(t, reported)
+
+	cause := errors.New("invalid character")
+	event := &testEvent{topic: "orders"}
+	options.ReportError(nil, ErrUnmarshal, cause, event)
+
+	assert.ErrorIs(t, reported, ErrUnmarshal)
+	assert.ErrorIs(t, reported, cause)
+	assert.Equal(t, "unmarshal message failed: invalid character", reported.Error())
+	assert.Equal(t, Event(event), reportedEvent)
+}
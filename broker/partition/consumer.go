@@ -0,0 +1,377 @@
+package partition
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var (
+	ErrAlreadyStarted = errors.New("partition: consumer already started")
+	ErrNoInstance     = errors.New("partition: instance is required")
+)
+
+type registration struct {
+	topic   string
+	handler broker.Handler
+	binder  broker.Binder
+	opts    []broker.SubscribeOption
+}
+
+type ownedPartition struct {
+	subs     []broker.Subscriber
+	inflight inflight
+	renewed  time.Time
+}
+
+// Consumer is one instance of a group consuming the partitions of topics.
+// The instances share the partitions through the store and rebalance them
+// when an instance joins or leaves, a partition is consumed by a single
+// instance at a time, keeping the order of its messages:
+//
+//	c := partition.NewConsumer(b, store, "billing", hostname, 16)
+//	_ = c.Subscribe("orders", handler, binder)
+//	_ = c.Start(ctx)
+//
+// An instance gives up a partition before another takes it, the partition
+// is not consumed in between, up to a rebalance interval.
+type Consumer struct {
+	sync.Mutex
+	options
+
+	b          broker.Broker
+	store      Store
+	group      string
+	instance   string
+	partitions int
+
+	registrations []registration
+	owned         map[int]*ownedPartition
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewConsumer(b broker.Broker, store Store, group, instance string, partitions int, opts ...Option) *Consumer {
+	if partitions < 1 {
+		partitions = 1
+	}
+
+	return &Consumer{
+		options:    newOptions(opts...),
+		b:          b,
+		store:      store,
+		group:      group,
+		instance:   instance,
+		partitions: partitions,
+		owned:      make(map[int]*ownedPartition),
+	}
+}
+
+// Subscribe consume the partitions of topic owned by the instance, now and
+// after every rebalance.
+func (c *Consumer) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) error {
+	c.Lock()
+	defer c.Unlock()
+
+	r := registration{topic: topic, handler: handler, binder: binder, opts: opts}
+	c.registrations = append(c.registrations, r)
+
+	for p, o := range c.owned {
+		sub, err := c.subscribe(r, p, o)
+		if err != nil {
+			return err
+		}
+		o.subs = append(o.subs, sub)
+	}
+	return nil
+}
+
+// Start join the group and take its share of the partitions.
+func (c *Consumer) Start(ctx context.Context) error {
+	if c.instance == "" {
+		return ErrNoInstance
+	}
+
+	c.Lock()
+	if c.done != nil {
+		c.Unlock()
+		return ErrAlreadyStarted
+	}
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+	done := c.done
+	c.Unlock()
+
+	c.rebalance(ctx)
+
+	go c.run(ctx, done)
+
+	return nil
+}
+
+// Stop give up the partitions, once their running handlers return, and leave the group.
+func (c *Consumer) Stop() error {
+	c.Lock()
+	cancel, done := c.cancel, c.done
+	c.cancel, c.done = nil, nil
+	c.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	<-done
+
+	ctx := context.Background()
+	var errs []error
+	revoked := c.ownedPartitions()
+	for _, p := range revoked {
+		errs = append(errs, c.revoke(ctx, p, true))
+	}
+	errs = append(errs, c.store.Leave(ctx, c.group, c.instance))
+	c.notify(nil, revoked)
+
+	return errors.Join(errs...)
+}
+
+// Partitions return the partitions the instance consumes.
+func (c *Consumer) Partitions() []int {
+	return c.ownedPartitions()
+}
+
+func (c *Consumer) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := c.clock.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			c.rebalance(ctx)
+		}
+	}
+}
+
+func (c *Consumer) rebalance(ctx context.Context) {
+	var assigned, revoked []int
+	defer func() { c.notify(assigned, revoked) }()
+
+	if err := c.store.Heartbeat(ctx, c.group, c.instance, c.ttl); err != nil {
+		log.Errorf("[partition] heartbeat of [%s] in [%s] failed: %v", c.instance, c.group, err)
+	}
+
+	members, err := c.store.Members(ctx, c.group)
+	if err != nil {
+		log.Errorf("[partition] load members of [%s] failed: %v", c.group, err)
+		revoked = c.expire(ctx)
+		return
+	}
+	if !contains(members, c.instance) {
+		members = append(members, c.instance)
+	}
+
+	desired := map[int]bool{}
+	for _, p := range Assign(members, c.partitions)[c.instance] {
+		desired[p] = true
+	}
+
+	// give up first, the partitions moving to another instance are taken by
+	// it once released.
+	for _, p := range c.ownedPartitions() {
+		if desired[p] {
+			continue
+		}
+		if err = c.revoke(ctx, p, true); err != nil {
+			log.Errorf("[partition] revoke [%d] of [%s] failed: %v", p, c.group, err)
+		}
+		revoked = append(revoked, p)
+	}
+
+	for p := 0; p < c.partitions; p++ {
+		if !desired[p] {
+			continue
+		}
+
+		now := c.clock.Now()
+		ok, err := c.store.Acquire(ctx, c.group, p, c.instance, c.ttl)
+		if err != nil {
+			log.Errorf("[partition] acquire [%d] of [%s] failed: %v", p, c.group, err)
+			continue
+		}
+
+		c.Lock()
+		o := c.owned[p]
+		if o != nil && ok {
+			o.renewed = now
+		}
+		c.Unlock()
+
+		switch {
+		case o != nil && !ok:
+			// taken over while the lease could not be renewed.
+			_ = c.revoke(ctx, p, false)
+			revoked = append(revoked, p)
+		case o == nil && ok:
+			if err = c.assign(p, now); err != nil {
+				log.Errorf("[partition] subscribe [%d] of [%s] failed: %v", p, c.group, err)
+				_ = c.store.Release(ctx, c.group, p, c.instance)
+				continue
+			}
+			assigned = append(assigned, p)
+		}
+	}
+
+	revoked = append(revoked, c.expire(ctx)...)
+}
+
+// expire give up the partitions whose lease may have been taken over.
+func (c *Consumer) expire(ctx context.Context) []int {
+	var expired []int
+	for _, p := range c.ownedPartitions() {
+		c.Lock()
+		o := c.owned[p]
+		c.Unlock()
+
+		if o != nil && c.clock.Since(o.renewed) > c.ttl {
+			_ = c.revoke(ctx, p, false)
+			expired = append(expired, p)
+		}
+	}
+	return expired
+}
+
+func (c *Consumer) assign(p int, renewed time.Time) error {
+	c.Lock()
+	defer c.Unlock()
+
+	o := &ownedPartition{renewed: renewed}
+	for _, r := range c.registrations {
+		sub, err := c.subscribe(r, p, o)
+		if err != nil {
+			_ = unsubscribe(o.subs)
+			return err
+		}
+		o.subs = append(o.subs, sub)
+	}
+	c.owned[p] = o
+
+	return nil
+}
+
+// revoke stop consuming p, wait for its running handlers and, when
+// release, free its lease for the next owner.
+func (c *Consumer) revoke(ctx context.Context, p int, release bool) error {
+	c.Lock()
+	o, ok := c.owned[p]
+	delete(c.owned, p)
+	c.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	err := unsubscribe(o.subs)
+	o.inflight.wait()
+
+	if release {
+		err = errors.Join(err, c.store.Release(ctx, c.group, p, c.instance))
+	}
+	return err
+}
+
+func (c *Consumer) subscribe(r registration, p int, o *ownedPartition) (broker.Subscriber, error) {
+	handler := func(ctx context.Context, event broker.Event) error {
+		o.inflight.add()
+		defer o.inflight.done()
+		return r.handler(ctx, event)
+	}
+
+	topic := c.topicFormat(r.topic, p)
+	opts := append(append([]broker.SubscribeOption{}, r.opts...),
+		broker.WithQueueName(c.queueFormat(c.group, topic)))
+
+	return c.b.Subscribe(topic, handler, r.binder, opts...)
+}
+
+func (c *Consumer) ownedPartitions() []int {
+	c.Lock()
+	defer c.Unlock()
+
+	partitions := make([]int, 0, len(c.owned))
+	for p := range c.owned {
+		partitions = append(partitions, p)
+	}
+	sort.Ints(partitions)
+	return partitions
+}
+
+func (c *Consumer) notify(assigned, revoked []int) {
+	if c.onRebalance == nil || len(assigned)+len(revoked) == 0 {
+		return
+	}
+	c.onRebalance(assigned, revoked)
+}
+
+func unsubscribe(subs []broker.Subscriber) error {
+	var errs []error
+	for _, sub := range subs {
+		if err := sub.Unsubscribe(true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// inflight counts the running handlers, unlike a sync.WaitGroup it may be
+// waited while handlers still start.
+type inflight struct {
+	mtx  sync.Mutex
+	n    int
+	idle *sync.Cond
+}
+
+func (f *inflight) add() {
+	f.mtx.Lock()
+	f.n++
+	f.mtx.Unlock()
+}
+
+func (f *inflight) done() {
+	f.mtx.Lock()
+	f.n--
+	if f.n == 0 && f.idle != nil {
+		f.idle.Broadcast()
+	}
+	f.mtx.Unlock()
+}
+
+func (f *inflight) wait() {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.idle == nil {
+		f.idle = sync.NewCond(&f.mtx)
+	}
+	for f.n > 0 {
+		f.idle.Wait()
+	}
+}
@@ -0,0 +1,82 @@
+package partition
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type options struct {
+	topicFormat func(topic string, partition int) string
+	queueFormat func(group, topic string) string
+	interval    time.Duration
+	ttl         time.Duration
+	onRebalance func(assigned, revoked []int)
+	clock       broker.Clock
+}
+
+func newOptions(opts ...Option) options {
+	o := options{
+		topicFormat: DefaultTopicFormat,
+		queueFormat: func(group, topic string) string {
+			return group + "." + topic
+		},
+		interval: time.Second,
+		ttl:      10 * time.Second,
+		clock:    broker.SystemClock,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+type Option func(o *options)
+
+// WithTopicFormat set how the partitions of a topic are named, default is
+// DefaultTopicFormat. Publishers and consumers must agree on it.
+func WithTopicFormat(fn func(topic string, partition int) string) Option {
+	return func(o *options) {
+		o.topicFormat = fn
+	}
+}
+
+// WithQueueFormat set the queue of a partition consumed by group, default
+// is "group.partition", e.g. "billing.orders.3".
+func WithQueueFormat(fn func(group, topic string) string) Option {
+	return func(o *options) {
+		o.queueFormat = fn
+	}
+}
+
+// WithRebalanceInterval set how often the consumers heartbeat and
+// rebalance, default is 1s.
+func WithRebalanceInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.interval = interval
+	}
+}
+
+// WithLeaseTTL set how long the membership and the partitions of a consumer
+// survive it, default is 10s. It must be well above the rebalance interval.
+func WithLeaseTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithRebalanceCallback observe the partitions taken and given up by the consumer.
+func WithRebalanceCallback(fn func(assigned, revoked []int)) Option {
+	return func(o *options) {
+		o.onRebalance = fn
+	}
+}
+
+// WithClock set the clock of the rebalances and the leases, broker.SystemClock by default.
+func WithClock(clock broker.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
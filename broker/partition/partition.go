@@ -0,0 +1,108 @@
+package partition
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// DefaultTopicFormat name the queue, or channel, of a partition, e.g. "orders.3".
+func DefaultTopicFormat(topic string, partition int) string {
+	return topic + "." + strconv.Itoa(partition)
+}
+
+// Of return the partition of key among n, the same on every instance.
+func Of(key string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// Assign spread the partitions over members with rendezvous hashing: a
+// member joining or leaving only moves the partitions it takes or had.
+func Assign(members []string, partitions int) map[string][]int {
+	assignment := make(map[string][]int, len(members))
+	if len(members) == 0 {
+		return assignment
+	}
+
+	sorted := append([]string{}, members...)
+	sort.Strings(sorted)
+
+	for p := 0; p < partitions; p++ {
+		var owner string
+		var best uint64
+		for i, m := range sorted {
+			if w := weight(m, p); i == 0 || w > best {
+				owner, best = m, w
+			}
+		}
+		assignment[owner] = append(assignment[owner], p)
+	}
+	return assignment
+}
+
+func weight(member string, partition int) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(member))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(strconv.Itoa(partition)))
+	// fnv alone spreads the close inputs badly, finish with a mix.
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+type keyKey struct{}
+
+// WithKey route the message by key: the messages of a key go to the same
+// partition, so they are consumed in order by one instance.
+func WithKey(key string) broker.PublishOption {
+	return broker.PublishContextWithValue(keyKey{}, key)
+}
+
+type partitionBroker struct {
+	broker.Broker
+
+	partitions  int
+	topicFormat func(topic string, partition int) string
+	next        uint32
+}
+
+// NewBroker wraps b to publish to partitions of the topics, picked by the
+// key set with WithKey, or in turn when there is none. The subscriptions
+// are not partitioned, consume the partitions with a Consumer.
+//
+// On RabbitMQ the messages of a partition are dropped until a queue is
+// bound to it, start the consumers, which declare them, first.
+func NewBroker(b broker.Broker, partitions int, opts ...Option) broker.Broker {
+	if partitions < 1 {
+		partitions = 1
+	}
+
+	o := newOptions(opts...)
+
+	return &partitionBroker{
+		Broker:      b,
+		partitions:  partitions,
+		topicFormat: o.topicFormat,
+	}
+}
+
+func (b *partitionBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	var p int
+	if key, ok := broker.NewPublishOptions(opts...).Context.Value(keyKey{}).(string); ok {
+		p = Of(key, b.partitions)
+	} else {
+		p = int((atomic.AddUint32(&b.next, 1) - 1) % uint32(b.partitions))
+	}
+	return b.Broker.Publish(ctx, b.topicFormat(topic, p), msg, opts...)
+}
@@ -0,0 +1,148 @@
+package partition
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+// subscribed count the subscriptions of every queue of the partitions of orders.
+func subscribed(b *mocks.FakeBroker) map[string]int {
+	queues := map[string]int{}
+	for p := 0; p < 4; p++ {
+		for _, sub := range b.Subscribers(fmt.Sprintf("orders.%d", p)) {
+			queues[sub.Opts.Queue]++
+		}
+	}
+	return queues
+}
+
+func TestAssign(t *testing.T) {
+	assert.Empty(t, Assign(nil, 8))
+
+	before := Assign([]string{"a", "b", "c"}, 64)
+	var all []int
+	for _, partitions := range before {
+		assert.NotEmpty(t, partitions)
+		all = append(all, partitions...)
+	}
+	sort.Ints(all)
+	assert.Len(t, all, 64)
+	for i, p := range all {
+		assert.Equal(t, i, p)
+	}
+
+	// only the partitions of the leaving member move.
+	after := Assign([]string{"c", "a"}, 64)
+	for _, m := range []string{"a", "c"} {
+		assert.Subset(t, after[m], before[m])
+	}
+}
+
+func TestNewBroker(t *testing.T) {
+	inner := mocks.NewFakeBroker()
+	assert.NoError(t, inner.Connect())
+	b := NewBroker(inner, 4)
+	ctx := context.Background()
+
+	assert.NoError(t, b.Publish(ctx, "orders", "o1", WithKey("customer-1")))
+	assert.NoError(t, b.Publish(ctx, "orders", "o2", WithKey("customer-1")))
+	assert.NoError(t, b.Publish(ctx, "orders", "o3"))
+	assert.NoError(t, b.Publish(ctx, "orders", "o4"))
+
+	p := fmt.Sprintf("orders.%d", Of("customer-1", 4))
+	var published []string
+	for _, m := range inner.Published() {
+		published = append(published, m.Topic)
+	}
+	assert.Equal(t, []string{p, p, "orders.0", "orders.1"}, published)
+}
+
+func TestConsumer_Rebalance(t *testing.T) {
+	b := mocks.NewFakeBroker()
+	store := NewMemoryStore()
+	ctx := context.Background()
+	handler := func(context.Context, broker.Event) error { return nil }
+	opts := []Option{WithRebalanceInterval(5 * time.Millisecond), WithLeaseTTL(time.Second)}
+
+	var mtx sync.Mutex
+	var assigned []int
+	c1 := NewConsumer(b, store, "billing", "c1", 4, append(opts,
+		WithRebalanceCallback(func(a, _ []int) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			assigned = append(assigned, a...)
+		}))...)
+	assert.NoError(t, c1.Subscribe("orders", handler, nil))
+	assert.NoError(t, c1.Start(ctx))
+	defer c1.Stop()
+	assert.ErrorIs(t, c1.Start(ctx), ErrAlreadyStarted)
+
+	// alone, c1 consumes every partition.
+	assert.Equal(t, []int{0, 1, 2, 3}, c1.Partitions())
+	assert.Equal(t, map[string]int{
+		"billing.orders.0": 1,
+		"billing.orders.1": 1,
+		"billing.orders.2": 1,
+		"billing.orders.3": 1,
+	}, subscribed(b))
+
+	c2 := NewConsumer(b, store, "billing", "c2", 4, opts...)
+	assert.NoError(t, c2.Subscribe("orders", handler, nil))
+	assert.NoError(t, c2.Start(ctx))
+
+	expected := Assign([]string{"c1", "c2"}, 4)
+	assert.Eventually(t, func() bool {
+		return fmt.Sprint(c1.Partitions()) == fmt.Sprint(expected["c1"]) &&
+			fmt.Sprint(c2.Partitions()) == fmt.Sprint(expected["c2"])
+	}, time.Second, 5*time.Millisecond)
+	for _, n := range subscribed(b) {
+		assert.Equal(t, 1, n)
+	}
+	assert.Len(t, subscribed(b), 4)
+
+	// the partitions of a leaving instance go back to the others.
+	assert.NoError(t, c2.Stop())
+	assert.Eventually(t, func() bool {
+		return len(c1.Partitions()) == 4
+	}, time.Second, 5*time.Millisecond)
+
+	mtx.Lock()
+	assert.Len(t, assigned, 4+len(expected["c2"]))
+	mtx.Unlock()
+
+	assert.NoError(t, c1.Stop())
+	assert.Empty(t, subscribed(b))
+	members, err := store.Members(ctx, "billing")
+	assert.NoError(t, err)
+	assert.Empty(t, members)
+}
+
+func TestMemoryStore_Lease(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	ok, err := store.Acquire(ctx, "billing", 0, "c1", 20*time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, _ = store.Acquire(ctx, "billing", 0, "c2", time.Second)
+	assert.False(t, ok)
+
+	// releasing a lease held by another instance is a no-op.
+	assert.NoError(t, store.Release(ctx, "billing", 0, "c2"))
+	ok, _ = store.Acquire(ctx, "billing", 0, "c2", time.Second)
+	assert.False(t, ok)
+
+	time.Sleep(30 * time.Millisecond)
+	ok, _ = store.Acquire(ctx, "billing", 0, "c2", time.Second)
+	assert.True(t, ok)
+}
@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/tx7do/kratos-transport/broker/partition"
+)
+
+const defaultPrefix = "partition:"
+
+var _ partition.Store = (*Store)(nil)
+
+var (
+	acquireScript = redis.NewScript(1, `
+local owner = redis.call('GET', KEYS[1])
+if owner == false or owner == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0`)
+
+	releaseScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`)
+)
+
+type StoreOption func(s *Store)
+
+// WithPrefix set the prefix of all keys, default is "partition:".
+func WithPrefix(prefix string) StoreOption {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// Store coordinates the consumers of the groups in redis:
+//
+//	{prefix}{group}:members       sorted set of the instances by expiry
+//	{prefix}{group}:lease:{n}     instance holding partition n, expiring
+//
+// The expiry of the members is the clock of the instances, keep them synchronized.
+type Store struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+func NewStore(pool *redis.Pool, opts ...StoreOption) *Store {
+	s := &Store{
+		pool:   pool,
+		prefix: defaultPrefix,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+func (s *Store) membersKey(group string) string { return s.prefix + group + ":members" }
+func (s *Store) leaseKey(group string, partition int) string {
+	return s.prefix + group + ":lease:" + strconv.Itoa(partition)
+}
+
+func (s *Store) Heartbeat(ctx context.Context, group, instance string, ttl time.Duration) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	expiry := time.Now().Add(ttl).UnixMilli()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("ZADD", s.membersKey(group), expiry, instance)
+	_ = conn.Send("PEXPIRE", s.membersKey(group), ttl.Milliseconds())
+	_, err = redis.DoContext(conn, ctx, "EXEC")
+	return err
+}
+
+func (s *Store) Leave(ctx context.Context, group, instance string) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "ZREM", s.membersKey(group), instance)
+	return err
+}
+
+func (s *Store) Members(ctx context.Context, group string) ([]string, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	now := time.Now().UnixMilli()
+	if _, err = redis.DoContext(conn, ctx, "ZREMRANGEBYSCORE", s.membersKey(group), "-inf", now); err != nil {
+		return nil, err
+	}
+	return redis.Strings(redis.DoContext(conn, ctx, "ZRANGE", s.membersKey(group), 0, -1))
+}
+
+func (s *Store) Acquire(ctx context.Context, group string, partition int, instance string, ttl time.Duration) (bool, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	return redis.Bool(acquireScript.DoContext(ctx, conn, s.leaseKey(group, partition), instance, ttl.Milliseconds()))
+}
+
+func (s *Store) Release(ctx context.Context, group string, partition int, instance string) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = releaseScript.DoContext(ctx, conn, s.leaseKey(group, partition), instance)
+	return err
+}
@@ -0,0 +1,47 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/internal/redistest"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(redistest.NewPool(t), WithPrefix("partition-test:"))
+
+	assert.Nil(t, store.Heartbeat(ctx, "billing", "c1", time.Second))
+	assert.Nil(t, store.Heartbeat(ctx, "billing", "c2", 50*time.Millisecond))
+	defer store.Leave(ctx, "billing", "c1")
+
+	members, err := store.Members(ctx, "billing")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"c1", "c2"}, members)
+
+	time.Sleep(60 * time.Millisecond)
+	members, err = store.Members(ctx, "billing")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"c1"}, members)
+
+	ok, err := store.Acquire(ctx, "billing", 0, "c1", time.Second)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	defer store.Release(ctx, "billing", 0, "c1")
+
+	ok, err = store.Acquire(ctx, "billing", 0, "c2", time.Second)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, store.Release(ctx, "billing", 0, "c2"))
+	ok, _ = store.Acquire(ctx, "billing", 0, "c1", time.Second)
+	assert.True(t, ok)
+
+	assert.Nil(t, store.Release(ctx, "billing", 0, "c1"))
+	ok, _ = store.Acquire(ctx, "billing", 0, "c2", time.Second)
+	assert.True(t, ok)
+	assert.Nil(t, store.Release(ctx, "billing", 0, "c2"))
+}
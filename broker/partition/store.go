@@ -0,0 +1,104 @@
+package partition
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store coordinates the consumers of a group: their membership and the
+// leases of the partitions they consume.
+type Store interface {
+	// Heartbeat register, or keep, instance as a member of group for ttl.
+	Heartbeat(ctx context.Context, group, instance string, ttl time.Duration) error
+	// Leave remove instance from group.
+	Leave(ctx context.Context, group, instance string) error
+	// Members return the live members of group.
+	Members(ctx context.Context, group string) ([]string, error)
+
+	// Acquire take, or renew, the lease of partition for ttl, false when
+	// another instance holds it.
+	Acquire(ctx context.Context, group string, partition int, instance string, ttl time.Duration) (bool, error)
+	// Release give up the lease of partition held by instance.
+	Release(ctx context.Context, group string, partition int, instance string) error
+}
+
+type lease struct {
+	instance string
+	expiry   time.Time
+}
+
+// MemoryStore coordinates the consumers of a single process, for tests.
+type MemoryStore struct {
+	sync.Mutex
+
+	members map[string]map[string]time.Time
+	leases  map[string]map[int]lease
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		members: make(map[string]map[string]time.Time),
+		leases:  make(map[string]map[int]lease),
+	}
+}
+
+func (s *MemoryStore) Heartbeat(_ context.Context, group, instance string, ttl time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.members[group] == nil {
+		s.members[group] = make(map[string]time.Time)
+	}
+	s.members[group][instance] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *MemoryStore) Leave(_ context.Context, group, instance string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.members[group], instance)
+	return nil
+}
+
+func (s *MemoryStore) Members(_ context.Context, group string) ([]string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now()
+	var members []string
+	for instance, expiry := range s.members[group] {
+		if now.Before(expiry) {
+			members = append(members, instance)
+		}
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+func (s *MemoryStore) Acquire(_ context.Context, group string, partition int, instance string, ttl time.Duration) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now()
+	if l, ok := s.leases[group][partition]; ok && l.instance != instance && now.Before(l.expiry) {
+		return false, nil
+	}
+	if s.leases[group] == nil {
+		s.leases[group] = make(map[int]lease)
+	}
+	s.leases[group][partition] = lease{instance: instance, expiry: now.Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, group string, partition int, instance string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if l, ok := s.leases[group][partition]; ok && l.instance == instance {
+		delete(s.leases[group], partition)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(p *Pipeline)
+
+// WithParallelism process records on n workers. With n > 1 the broker handler
+// returns as soon as the record is queued, so failed records are only
+// redelivered when the broker redelivers unacknowledged messages. The
+// workers process a copy of the message, with the values of the handler
+// context but not its cancellation.
+func WithParallelism(n int) Option {
+	return func(p *Pipeline) {
+		if n < 1 {
+			n = 1
+		}
+		p.parallelism = n
+	}
+}
+
+// WithErrorTopic publish records failing a stage or the sink to topic and checkpoint them,
+// with their headers plus ErrorHeader and broker.OriginTopicHeader. Run refuses the
+// source brokers whose messages have no headers with broker.ErrHeadersUnsupported.
+func WithErrorTopic(topic string) Option {
+	return func(p *Pipeline) {
+		p.errorTopic = topic
+	}
+}
+
+// WithErrorHandler observe failed records, the record is checkpointed afterward.
+func WithErrorHandler(h func(ctx context.Context, r *Record, err error)) Option {
+	return func(p *Pipeline) {
+		p.errorHandler = h
+	}
+}
+
+// WithCheckpointer set how processed events are checkpointed, default acknowledges them,
+// e.g. checkpoint.NewCheckpointer to also save their positions apart from the broker.
+func WithCheckpointer(c Checkpointer) Option {
+	return func(p *Pipeline) {
+		p.checkpointer = c
+	}
+}
+
+// WithSubscribeOptions pass options to the source subscription.
+func WithSubscribeOptions(opts ...broker.SubscribeOption) Option {
+	return func(p *Pipeline) {
+		p.subscribeOpts = append(p.subscribeOpts, opts...)
+	}
+}
@@ -0,0 +1,380 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var (
+	ErrAlreadyRunning = errors.New("pipeline: already running")
+	ErrStopped        = errors.New("pipeline: stopped")
+)
+
+// The headers set on the records published to the error topic.
+const (
+	// ErrorHeader is the error the record failed with.
+	ErrorHeader = "x-pipeline-error"
+)
+
+// Record is the unit flowing through the stages of a pipeline.
+type Record struct {
+	Topic   string
+	Headers broker.Headers
+	Body    broker.Any
+
+	// Event is the consumed message the record originates from.
+	Event broker.Event
+}
+
+type MapFunc func(ctx context.Context, r *Record) (*Record, error)
+
+type FilterFunc func(ctx context.Context, r *Record) (bool, error)
+
+type SinkFunc func(ctx context.Context, r *Record) error
+
+// Checkpointer marks a consumed event as processed.
+type Checkpointer interface {
+	Checkpoint(ctx context.Context, event broker.Event) error
+}
+
+type CheckpointFunc func(ctx context.Context, event broker.Event) error
+
+func (f CheckpointFunc) Checkpoint(ctx context.Context, event broker.Event) error {
+	return f(ctx, event)
+}
+
+// AckCheckpointer checkpoints by acknowledging the event on the broker.
+var AckCheckpointer = CheckpointFunc(func(_ context.Context, event broker.Event) error {
+	return event.Ack()
+})
+
+type stage func(ctx context.Context, r *Record) (*Record, error)
+
+type job struct {
+	ctx   context.Context
+	event broker.Event
+}
+
+// Pipeline consumes a topic, runs every record through its stages and hands
+// the result to a sink:
+//
+//	pipeline.Source(b, "orders", binder).
+//		Map(enrich).
+//		Filter(isPaid).
+//		Sink("orders.paid").
+//		Run()
+type Pipeline struct {
+	sync.Mutex
+
+	b      broker.Broker
+	topic  string
+	binder broker.Binder
+
+	stages []stage
+	sink   SinkFunc
+
+	parallelism   int
+	errorTopic    string
+	errorHandler  func(ctx context.Context, r *Record, err error)
+	checkpointer  Checkpointer
+	subscribeOpts []broker.SubscribeOption
+
+	sub broker.Subscriber
+
+	// tasks run in the background while running, e.g. to fire idle windows.
+	tasks  []func(ctx context.Context)
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+
+	// jobsMu guards jobs against the dispatches racing stopWorkers.
+	jobsMu sync.RWMutex
+	jobs   chan job
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func Source(b broker.Broker, topic string, binder broker.Binder, opts ...Option) *Pipeline {
+	p := &Pipeline{
+		b:            b,
+		topic:        topic,
+		binder:       binder,
+		parallelism:  1,
+		checkpointer: AckCheckpointer,
+	}
+
+	for _, o := range opts {
+		o(p)
+	}
+
+	return p
+}
+
+func (p *Pipeline) Map(fn MapFunc) *Pipeline {
+	p.stages = append(p.stages, stage(fn))
+	return p
+}
+
+func (p *Pipeline) Filter(fn FilterFunc) *Pipeline {
+	p.stages = append(p.stages, func(ctx context.Context, r *Record) (*Record, error) {
+		keep, err := fn(ctx, r)
+		if err != nil || !keep {
+			return nil, err
+		}
+		return r, nil
+	})
+	return p
+}
+
+// Sink publish the resulting records to topic on the source broker.
+func (p *Pipeline) Sink(topic string, opts ...broker.PublishOption) *Pipeline {
+	return p.SinkTo(p.b, topic, opts...)
+}
+
+// SinkTo publish the resulting records to topic on another broker, with
+// their headers under the ones of opts. A broker whose messages have no
+// headers, see broker.CarriesHeaders, gets the bodies only.
+func (p *Pipeline) SinkTo(b broker.Broker, topic string, opts ...broker.PublishOption) *Pipeline {
+	headers := broker.CarriesHeaders(b)
+	p.sink = func(ctx context.Context, r *Record) error {
+		if !headers {
+			return b.Publish(ctx, topic, r.Body, opts...)
+		}
+		return b.Publish(ctx, topic, r.Body, append([]broker.PublishOption{broker.WithHeaders(r.Headers)}, opts...)...)
+	}
+	return p
+}
+
+func (p *Pipeline) SinkFunc(fn SinkFunc) *Pipeline {
+	p.sink = fn
+	return p
+}
+
+// Run subscribe to the source topic and start processing.
+func (p *Pipeline) Run() error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.sub != nil {
+		return ErrAlreadyRunning
+	}
+	if p.errorTopic != "" {
+		if err := broker.RequireHeaders(p.b); err != nil {
+			return err
+		}
+	}
+
+	handler := p.process
+	if p.parallelism > 1 {
+		jobs := make(chan job, p.parallelism)
+		p.jobsMu.Lock()
+		p.jobs, p.quit = jobs, make(chan struct{})
+		p.jobsMu.Unlock()
+
+		for i := 0; i < p.parallelism; i++ {
+			p.wg.Add(1)
+			go p.worker(jobs)
+		}
+		handler = p.dispatch
+	}
+
+	opts := append([]broker.SubscribeOption{broker.DisableAutoAck()}, p.subscribeOpts...)
+
+	sub, err := p.b.Subscribe(p.topic, handler, p.binder, opts...)
+	if err != nil {
+		p.stopWorkers()
+		return err
+	}
+	p.sub = sub
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	for _, task := range p.tasks {
+		p.done.Add(1)
+		go func(task func(ctx context.Context)) {
+			defer p.done.Done()
+			task(ctx)
+		}(task)
+	}
+
+	return nil
+}
+
+// Stop unsubscribe and wait for in-flight records.
+func (p *Pipeline) Stop() error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.sub == nil {
+		return nil
+	}
+
+	err := p.sub.Unsubscribe(true)
+	p.sub = nil
+
+	p.stopWorkers()
+
+	p.cancel()
+	p.done.Wait()
+
+	return err
+}
+
+// Subscriber return the source subscription while running, e.g. to seek it
+// to its checkpoints.
+func (p *Pipeline) Subscriber() broker.Subscriber {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.sub
+}
+
+// stopWorkers release the blocked dispatches, then let the workers drain the
+// queued records.
+func (p *Pipeline) stopWorkers() {
+	p.jobsMu.RLock()
+	quit := p.quit
+	p.jobsMu.RUnlock()
+
+	if quit == nil {
+		return
+	}
+	close(quit)
+
+	p.jobsMu.Lock()
+	close(p.jobs)
+	p.jobs, p.quit = nil, nil
+	p.jobsMu.Unlock()
+
+	p.wg.Wait()
+}
+
+func (p *Pipeline) dispatch(ctx context.Context, event broker.Event) error {
+	p.jobsMu.RLock()
+	defer p.jobsMu.RUnlock()
+
+	if p.jobs == nil {
+		return ErrStopped
+	}
+
+	// the handler returns before the record is processed, keep its values
+	// and a copy of its message only.
+	j := job{ctx: context.WithoutCancel(ctx), event: detach(event)}
+	select {
+	case p.jobs <- j:
+		return nil
+	case <-p.quit:
+		return ErrStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pipeline) worker(jobs <-chan job) {
+	defer p.wg.Done()
+
+	for j := range jobs {
+		if err := p.process(j.ctx, j.event); err != nil {
+			log.Errorf("[pipeline] process message from [%s] failed: %v", p.topic, err)
+		}
+	}
+}
+
+func (p *Pipeline) process(ctx context.Context, event broker.Event) error {
+	r := &Record{
+		Topic: event.Topic(),
+		Event: event,
+	}
+	if m := event.Message(); m != nil {
+		r.Headers = m.Headers
+		r.Body = m.Body
+	}
+
+	out, err := p.run(ctx, r)
+	if err != nil {
+		if err = p.routeError(ctx, r, err); err != nil {
+			return err
+		}
+	} else if out != nil && p.sink != nil {
+		if err = p.sink(ctx, out); err != nil {
+			if err = p.routeError(ctx, out, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.checkpointer != nil {
+		return p.checkpointer.Checkpoint(ctx, event)
+	}
+	return nil
+}
+
+func (p *Pipeline) run(ctx context.Context, r *Record) (*Record, error) {
+	return p.runFrom(ctx, 0, r)
+}
+
+func (p *Pipeline) runFrom(ctx context.Context, index int, r *Record) (*Record, error) {
+	var err error
+	for _, s := range p.stages[index:] {
+		if r, err = s(ctx, r); err != nil || r == nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// routeError returns nil when the failed record was handled and may be checkpointed.
+func (p *Pipeline) routeError(ctx context.Context, r *Record, err error) error {
+	if p.errorHandler != nil {
+		p.errorHandler(ctx, r, err)
+	}
+
+	if p.errorTopic == "" {
+		if p.errorHandler != nil {
+			return nil
+		}
+		return err
+	}
+
+	headers := broker.Headers{}
+	for k, v := range r.Headers {
+		headers[k] = v
+	}
+	headers[ErrorHeader] = err.Error()
+	if _, ok := headers[broker.OriginTopicHeader]; !ok {
+		headers[broker.OriginTopicHeader] = r.Topic
+	}
+
+	return p.b.Publish(ctx, p.errorTopic, r.Body, broker.WithHeaders(headers))
+}
+
+// detachedEvent is an event processed after its handler returned, with a
+// copy of its message since the drivers may reuse theirs.
+type detachedEvent struct {
+	broker.Event
+	m *broker.Message
+}
+
+func detach(event broker.Event) broker.Event {
+	m := event.Message()
+	if m == nil {
+		return event
+	}
+
+	c := &broker.Message{Headers: make(broker.Headers, len(m.Headers)), Body: m.Body}
+	for k, v := range m.Headers {
+		c.Headers[k] = v
+	}
+	if buf, ok := m.Body.([]byte); ok {
+		c.Body = append([]byte(nil), buf...)
+	}
+	return &detachedEvent{Event: event, m: c}
+}
+
+func (e *detachedEvent) Message() *broker.Message {
+	return e.m
+}
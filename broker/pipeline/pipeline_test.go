@@ -0,0 +1,184 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func newFakeBroker(t *testing.T) *mocks.FakeBroker {
+	b := mocks.NewFakeBroker()
+	assert.Nil(t, b.Connect())
+	return b
+}
+
+// deliver hand body to the single subscriber of topic.
+func deliver(b *mocks.FakeBroker, topic string, body broker.Any) (*mocks.Event, error) {
+	events, err := b.Deliver(context.Background(), topic, body, broker.Headers{})
+	if len(events) == 0 {
+		return nil, err
+	}
+	return events[0], err
+}
+
+func TestPipeline_MapFilterSink(t *testing.T) {
+	b := newFakeBroker(t)
+
+	p := Source(b, "in", nil).
+		Map(func(_ context.Context, r *Record) (*Record, error) {
+			r.Body = strings.ToUpper(r.Body.(string))
+			return r, nil
+		}).
+		Filter(func(_ context.Context, r *Record) (bool, error) {
+			return r.Body.(string) != "SKIP", nil
+		}).
+		Sink("out")
+
+	assert.Nil(t, p.Run())
+	assert.ErrorIs(t, p.Run(), ErrAlreadyRunning)
+
+	events, err := b.Deliver(context.Background(), "in", "hello", broker.Headers{"id": "1"})
+	assert.Nil(t, err)
+	assert.True(t, events[0].IsAcked())
+
+	evt, err := deliver(b, "in", "skip")
+	assert.Nil(t, err)
+	assert.True(t, evt.IsAcked())
+
+	assert.Equal(t, []broker.Any{"HELLO"}, b.PublishedBodies("out"))
+	assert.Equal(t, "1", b.PublishedTo("out")[0].Headers["id"])
+
+	assert.Nil(t, p.Stop())
+	events, _ = b.Deliver(context.Background(), "in", "hello", nil)
+	assert.Empty(t, events, "unsubscribed")
+}
+
+func TestPipeline_ErrorRouting(t *testing.T) {
+	b := newFakeBroker(t)
+
+	failing := func(_ context.Context, r *Record) (*Record, error) {
+		return nil, errors.New("bad record")
+	}
+
+	p := Source(b, "in", nil).Map(failing).Sink("out")
+	assert.Nil(t, p.Run())
+
+	evt, err := deliver(b, "in", "hello")
+	assert.NotNil(t, err)
+	assert.False(t, evt.IsAcked())
+	assert.Nil(t, p.Stop())
+
+	p = Source(b, "in", nil, WithErrorTopic("in.error")).Map(failing).Sink("out")
+	assert.Nil(t, p.Run())
+
+	events, err := b.Deliver(context.Background(), "in", "hello", broker.Headers{"id": "1"})
+	assert.Nil(t, err)
+	assert.True(t, events[0].IsAcked())
+	assert.Equal(t, []broker.Any{"hello"}, b.PublishedBodies("in.error"))
+	headers := b.PublishedTo("in.error")[0].Headers
+	assert.Equal(t, "1", headers["id"])
+	assert.Equal(t, "bad record", headers[ErrorHeader])
+	assert.Equal(t, "in", headers[broker.OriginTopicHeader])
+	assert.Empty(t, b.PublishedBodies("out"))
+	assert.Nil(t, p.Stop())
+}
+
+func TestPipeline_Headerless(t *testing.T) {
+	source := newFakeBroker(t)
+	sink := mocks.NewHeaderlessBroker()
+	assert.Nil(t, sink.Connect())
+
+	// the headers of the records cannot reach the sink, the bodies do.
+	p := Source(source, "in", nil).SinkTo(sink, "out")
+	assert.Nil(t, p.Run())
+	defer p.Stop()
+
+	events, err := source.Deliver(context.Background(), "in", "hello", broker.Headers{"id": "1"})
+	assert.Nil(t, err)
+	assert.True(t, events[0].IsAcked())
+	assert.Equal(t, []broker.Any{"hello"}, sink.PublishedBodies("out"))
+
+	// the error topic would lose the error.
+	hb := mocks.NewHeaderlessBroker()
+	assert.Nil(t, hb.Connect())
+	p = Source(hb, "in", nil, WithErrorTopic("in.errors")).Sink("out")
+	assert.ErrorIs(t, p.Run(), broker.ErrHeadersUnsupported)
+	assert.Empty(t, hb.Subscribers("in"))
+}
+
+func TestPipeline_Parallelism(t *testing.T) {
+	b := newFakeBroker(t)
+
+	p := Source(b, "in", nil, WithParallelism(4)).Sink("out")
+	assert.Nil(t, p.Run())
+
+	var events []*mocks.Event
+	for i := 0; i < 100; i++ {
+		evt, err := deliver(b, "in", i)
+		assert.Nil(t, err)
+		events = append(events, evt)
+	}
+
+	assert.Nil(t, p.Stop())
+
+	assert.Len(t, b.PublishedBodies("out"), 100)
+	for _, evt := range events {
+		assert.True(t, evt.IsAcked())
+	}
+}
+
+func TestPipeline_StopWhileDispatching(t *testing.T) {
+	b := newFakeBroker(t)
+
+	release := make(chan struct{})
+	p := Source(b, "in", nil, WithParallelism(2)).SinkFunc(func(_ context.Context, r *Record) error {
+		<-release
+		return nil
+	})
+	assert.Nil(t, p.Run())
+
+	// the workers and the queue are busy, the next dispatches block.
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := deliver(b, "in", []byte{byte(i)})
+			errs <- err
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	stopped := make(chan error)
+	go func() { stopped <- p.Stop() }()
+
+	// Stop releases the blocked dispatches, then drains the queue.
+	wg.Wait()
+	close(release)
+	assert.Nil(t, <-stopped)
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, ErrStopped)
+		}
+	}
+}
+
+func TestDetach(t *testing.T) {
+	body := []byte("hello")
+	evt := detach(mocks.NewEvent("in", body, broker.Headers{"id": "1"}))
+
+	body[0] = 'j'
+	assert.Equal(t, []byte("hello"), evt.Message().Body)
+	assert.Equal(t, "1", evt.Message().Headers["id"])
+}
@@ -0,0 +1,539 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/tracing"
+)
+
+// maxWindowLinks bounds the span links kept per window, the records past
+// it are aggregated without being linked.
+const maxWindowLinks = 128
+
+// Window is the half-open event time interval [Start, End).
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// WindowResult is the body of the record emitted when a window closes.
+type WindowResult struct {
+	Key    string
+	Window Window
+	Value  broker.Any
+}
+
+// WindowAssigner returns the windows an event timestamp belongs to.
+type WindowAssigner func(ts time.Time) []Window
+
+// Tumbling fixed-size, non-overlapping windows.
+func Tumbling(size time.Duration) WindowAssigner {
+	return func(ts time.Time) []Window {
+		start := ts.Truncate(size)
+		return []Window{{Start: start, End: start.Add(size)}}
+	}
+}
+
+// Sliding windows of size starting every slide.
+func Sliding(size, slide time.Duration) WindowAssigner {
+	return func(ts time.Time) []Window {
+		var windows []Window
+		for start := ts.Truncate(slide); start.After(ts.Add(-size)); start = start.Add(-slide) {
+			windows = append(windows, Window{Start: start, End: start.Add(size)})
+		}
+		return windows
+	}
+}
+
+type KeyFunc func(r *Record) string
+
+type TimestampFunc func(r *Record) time.Time
+
+// AggregateFunc folds r into acc, acc is a new value from the accumulator binder for a new window.
+type AggregateFunc func(acc broker.Any, r *Record) (broker.Any, error)
+
+// StateStore keeps the accumulators of open windows.
+type StateStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte) error
+	Delete(ctx context.Context, key string) error
+	Keys(ctx context.Context) ([]string, error)
+}
+
+type MemoryStateStore struct {
+	sync.RWMutex
+	m map[string][]byte
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{m: make(map[string][]byte)}
+}
+
+func (s *MemoryStateStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.RLock()
+	defer s.RUnlock()
+	v, ok := s.m[key]
+	return v, ok, nil
+}
+
+func (s *MemoryStateStore) Put(_ context.Context, key string, value []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	s.m[key] = value
+	return nil
+}
+
+func (s *MemoryStateStore) Delete(_ context.Context, key string) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.m, key)
+	return nil
+}
+
+func (s *MemoryStateStore) Keys(_ context.Context) ([]string, error) {
+	s.RLock()
+	defer s.RUnlock()
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+type windowOptions struct {
+	store       StateStore
+	codec       encoding.Codec
+	timestamp   TimestampFunc
+	lateness    time.Duration
+	lateHandler func(ctx context.Context, r *Record)
+	idle        time.Duration
+}
+
+type WindowOption func(o *windowOptions)
+
+// WithStateStore default is MemoryStateStore.
+func WithStateStore(store StateStore) WindowOption {
+	return func(o *windowOptions) {
+		o.store = store
+	}
+}
+
+// WithStateCodec set the codec accumulators are stored with, default is json.
+func WithStateCodec(name string) WindowOption {
+	return func(o *windowOptions) {
+		o.codec = encoding.GetCodec(name)
+	}
+}
+
+// WithEventTime set where the event time of a record is read from, default is the processing time.
+func WithEventTime(fn TimestampFunc) WindowOption {
+	return func(o *windowOptions) {
+		o.timestamp = fn
+	}
+}
+
+// WithAllowedLateness delay the watermark behind the latest event time seen,
+// so out-of-order records still reach their window.
+func WithAllowedLateness(d time.Duration) WindowOption {
+	return func(o *windowOptions) {
+		o.lateness = d
+	}
+}
+
+// WithLateDataHandler receive records arriving after all their windows closed, they are dropped otherwise.
+func WithLateDataHandler(h func(ctx context.Context, r *Record)) WindowOption {
+	return func(o *windowOptions) {
+		o.lateHandler = h
+	}
+}
+
+// WithIdleTimeout advance the watermark to the processing time, less the
+// allowed lateness, once no record arrived for d, so the last windows close
+// when the source goes idle. The records of these windows have no Event.
+// Only for event times following the processing time closely, a replay
+// would see its windows closed early.
+func WithIdleTimeout(d time.Duration) WindowOption {
+	return func(o *windowOptions) {
+		o.idle = d
+	}
+}
+
+type windowOperator struct {
+	sync.Mutex
+
+	opts      windowOptions
+	assigner  WindowAssigner
+	key       KeyFunc
+	aggregate AggregateFunc
+	newAcc    broker.Binder
+	tracer    *tracing.Tracer
+	clock     broker.Clock
+	topic     string
+
+	watermark time.Time
+	seen      time.Time
+
+	// inflight are the state keys of the windows being emitted.
+	inflight map[string]struct{}
+}
+
+// firing is a closed window, with the trace contexts of its records.
+type firing struct {
+	stateKey string
+	record   *Record
+	links    []propagation.TextMapCarrier
+}
+
+// Window aggregate records by key into windows. A WindowResult record is
+// passed on to the following stages once the watermark passes the end of
+// its window, the consumed record itself goes no further. The following
+// stages run in a span linked to the spans of the aggregated records.
+//
+// The state of a window is deleted once its record went through the
+// following stages and the sink, a failed window is emitted again on the
+// next firing.
+func (p *Pipeline) Window(assigner WindowAssigner, key KeyFunc, newAcc broker.Binder, aggregate AggregateFunc, opts ...WindowOption) *Pipeline {
+	clock := broker.ClockOrSystem(p.b.Options().Clock)
+	op := &windowOperator{
+		opts: windowOptions{
+			store: NewMemoryStateStore(),
+			codec: encoding.GetCodec("json"),
+			timestamp: func(*Record) time.Time {
+				return clock.Now()
+			},
+		},
+		assigner:  assigner,
+		key:       key,
+		aggregate: aggregate,
+		newAcc:    newAcc,
+		tracer:    tracing.NewTracer(trace.SpanKindConsumer, "pipeline-window", p.b.Options().Tracings...),
+		clock:     clock,
+		topic:     p.topic,
+		inflight:  make(map[string]struct{}),
+	}
+	for _, o := range opts {
+		o(&op.opts)
+	}
+
+	next := len(p.stages) + 1
+	forward := func(ctx context.Context, r *Record) error {
+		out, err := p.runFrom(ctx, next, r)
+		if err != nil || out == nil || p.sink == nil {
+			return err
+		}
+		return p.sink(ctx, out)
+	}
+
+	p.stages = append(p.stages, func(ctx context.Context, r *Record) (*Record, error) {
+		results, err := op.process(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		return nil, op.emitAll(ctx, results, forward)
+	})
+
+	if op.opts.idle > 0 {
+		p.tasks = append(p.tasks, func(ctx context.Context) {
+			op.run(ctx, forward)
+		})
+	}
+
+	return p
+}
+
+// run fire the windows of an idle source until ctx is done.
+func (op *windowOperator) run(ctx context.Context, forward func(ctx context.Context, r *Record) error) {
+	ticker := op.clock.NewTicker(op.opts.idle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		results, err := op.idle(ctx)
+		if err == nil {
+			err = op.emitAll(ctx, results, forward)
+		}
+		if err != nil {
+			log.Errorf("[pipeline] fire idle windows of [%s] failed: %v", op.topic, err)
+		}
+	}
+}
+
+// idle advance the watermark to the processing time once no record arrived
+// for the idle timeout.
+func (op *windowOperator) idle(ctx context.Context) ([]*firing, error) {
+	op.Lock()
+	defer op.Unlock()
+
+	if op.clock.Since(op.seen) < op.opts.idle {
+		return nil, nil
+	}
+	if wm := op.clock.Now().Add(-op.opts.lateness); wm.After(op.watermark) {
+		op.watermark = wm
+	}
+
+	return op.fire(ctx, &Record{Topic: op.topic})
+}
+
+func (op *windowOperator) process(ctx context.Context, r *Record) ([]*firing, error) {
+	op.Lock()
+	defer op.Unlock()
+
+	op.seen = op.clock.Now()
+
+	ts := op.opts.timestamp(r)
+	key := op.key(r)
+
+	var accepted bool
+	for _, w := range op.assigner(ts) {
+		if !op.watermark.IsZero() && !w.End.After(op.watermark) {
+			continue
+		}
+		accepted = true
+
+		stateKey := windowStateKey(key, w)
+		if err := op.update(ctx, stateKey, r); err != nil {
+			return nil, err
+		}
+		if err := op.link(ctx, stateKey, r); err != nil {
+			return nil, err
+		}
+	}
+
+	if !accepted {
+		if op.opts.lateHandler != nil {
+			op.opts.lateHandler(ctx, r)
+		}
+		return nil, nil
+	}
+
+	if wm := ts.Add(-op.opts.lateness); wm.After(op.watermark) {
+		op.watermark = wm
+	}
+
+	return op.fire(ctx, r)
+}
+
+func (op *windowOperator) update(ctx context.Context, stateKey string, r *Record) error {
+	acc := op.newAcc()
+
+	buf, found, err := op.opts.store.Get(ctx, stateKey)
+	if err != nil {
+		return err
+	}
+	if found {
+		if err = op.opts.codec.Unmarshal(buf, acc); err != nil {
+			return err
+		}
+	}
+
+	value, err := op.aggregate(acc, r)
+	if err != nil {
+		return err
+	}
+
+	if buf, err = op.opts.codec.Marshal(value); err != nil {
+		return err
+	}
+
+	return op.opts.store.Put(ctx, stateKey, buf)
+}
+
+// link keep the trace context of r for the span of the window of stateKey.
+func (op *windowOperator) link(ctx context.Context, stateKey string, r *Record) error {
+	sc := trace.SpanContextFromContext(op.tracer.Extract(context.Background(), propagation.MapCarrier(r.Headers)))
+	if !sc.IsValid() {
+		return nil
+	}
+
+	var links []propagation.MapCarrier
+	buf, found, err := op.opts.store.Get(ctx, windowLinksKey(stateKey))
+	if err != nil {
+		return err
+	}
+	if found {
+		if err = json.Unmarshal(buf, &links); err != nil {
+			return err
+		}
+	}
+	if len(links) >= maxWindowLinks {
+		return nil
+	}
+
+	carrier := propagation.MapCarrier{}
+	op.tracer.Inject(trace.ContextWithRemoteSpanContext(context.Background(), sc), carrier)
+	links = append(links, carrier)
+
+	if buf, err = json.Marshal(links); err != nil {
+		return err
+	}
+	return op.opts.store.Put(ctx, windowLinksKey(stateKey), buf)
+}
+
+// emit run fn on the record of f in a span linked to the records of its window.
+func (op *windowOperator) emit(ctx context.Context, f *firing, fn func(ctx context.Context, r *Record) error) error {
+	result := f.record.Body.(*WindowResult)
+
+	// the window span is a new root, the triggering record is only one of its origins.
+	ctx = trace.ContextWithSpanContext(ctx, trace.SpanContext{})
+	ctx, span := op.tracer.StartBatch(ctx, f.links,
+		attribute.String("messaging.operation.name", "process"),
+		attribute.String("messaging.destination.name", f.record.Topic),
+		attribute.String("pipeline.window.key", result.Key),
+		attribute.String("pipeline.window.end", result.Window.End.Format(time.RFC3339Nano)),
+	)
+	op.tracer.Inject(ctx, propagation.MapCarrier(f.record.Headers))
+
+	err := fn(ctx, f.record)
+	op.tracer.End(ctx, span, err)
+	return err
+}
+
+// emitAll emit the windows in order and delete the state of the emitted
+// ones, the windows from the first failure on are kept for the next firing.
+func (op *windowOperator) emitAll(ctx context.Context, firings []*firing, fn func(ctx context.Context, r *Record) error) error {
+	var err error
+	for _, f := range firings {
+		if err == nil {
+			if err = op.emit(ctx, f, fn); err == nil {
+				err = op.clear(ctx, f.stateKey)
+			}
+		}
+
+		op.Lock()
+		delete(op.inflight, f.stateKey)
+		op.Unlock()
+	}
+	return err
+}
+
+// clear delete the state of the window of stateKey.
+func (op *windowOperator) clear(ctx context.Context, stateKey string) error {
+	if err := op.opts.store.Delete(ctx, stateKey); err != nil {
+		return err
+	}
+	return op.opts.store.Delete(ctx, windowLinksKey(stateKey))
+}
+
+// fire return every window whose end is not after the watermark, oldest
+// first, and mark them in flight until emitted.
+func (op *windowOperator) fire(ctx context.Context, origin *Record) ([]*firing, error) {
+	keys, err := op.opts.store.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*firing
+	for _, stateKey := range keys {
+		key, w, err := parseWindowStateKey(stateKey)
+		if err != nil || w.End.After(op.watermark) {
+			continue
+		}
+		if _, ok := op.inflight[stateKey]; ok {
+			continue
+		}
+
+		buf, found, err := op.opts.store.Get(ctx, stateKey)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+
+		acc := op.newAcc()
+		if err = op.opts.codec.Unmarshal(buf, acc); err != nil {
+			return nil, err
+		}
+
+		links, err := op.links(ctx, stateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, &firing{
+			stateKey: stateKey,
+			record: &Record{
+				Topic:   origin.Topic,
+				Headers: broker.Headers{},
+				Body:    &WindowResult{Key: key, Window: w, Value: acc},
+				Event:   origin.Event,
+			},
+			links: links,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].record.Body.(*WindowResult).Window.End.Before(results[j].record.Body.(*WindowResult).Window.End)
+	})
+	for _, f := range results {
+		op.inflight[f.stateKey] = struct{}{}
+	}
+
+	return results, nil
+}
+
+// links return the trace contexts kept for the window of stateKey.
+func (op *windowOperator) links(ctx context.Context, stateKey string) ([]propagation.TextMapCarrier, error) {
+	buf, found, err := op.opts.store.Get(ctx, windowLinksKey(stateKey))
+	if err != nil || !found {
+		return nil, err
+	}
+
+	var carriers []propagation.MapCarrier
+	if err = json.Unmarshal(buf, &carriers); err != nil {
+		return nil, err
+	}
+
+	links := make([]propagation.TextMapCarrier, len(carriers))
+	for i, c := range carriers {
+		links[i] = c
+	}
+	return links, nil
+}
+
+// windowLinksKey is not parsed as a window by fire, its first part is not a number.
+func windowLinksKey(stateKey string) string {
+	return "links|" + stateKey
+}
+
+func windowStateKey(key string, w Window) string {
+	return fmt.Sprintf("%d|%d|%s", w.Start.UnixNano(), w.End.UnixNano(), key)
+}
+
+func parseWindowStateKey(stateKey string) (string, Window, error) {
+	parts := strings.SplitN(stateKey, "|", 3)
+	if len(parts) != 3 {
+		return "", Window{}, fmt.Errorf("invalid window state key: %s", stateKey)
+	}
+
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "", Window{}, err
+	}
+	end, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", Window{}, err
+	}
+
+	return parts[2], Window{Start: time.Unix(0, start), End: time.Unix(0, end)}, nil
+}
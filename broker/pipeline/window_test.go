@@ -0,0 +1,223 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+type sample struct {
+	key string
+	ts  time.Time
+}
+
+func countAggregate(acc broker.Any, _ *Record) (broker.Any, error) {
+	n := acc.(*int)
+	*n++
+	return n, nil
+}
+
+func newCounter() broker.Any {
+	var n int
+	return &n
+}
+
+func TestTumbling(t *testing.T) {
+	base := time.Unix(1000, 0)
+	windows := Tumbling(time.Minute)(base.Add(90 * time.Second))
+	assert.Equal(t, 1, len(windows))
+	assert.Equal(t, time.Unix(1080, 0), windows[0].Start)
+	assert.Equal(t, time.Minute, windows[0].End.Sub(windows[0].Start))
+}
+
+func TestSliding(t *testing.T) {
+	ts := time.Unix(600, 0)
+	windows := Sliding(time.Minute, 20*time.Second)(ts)
+	assert.Equal(t, 3, len(windows))
+	for _, w := range windows {
+		assert.False(t, ts.Before(w.Start))
+		assert.True(t, ts.Before(w.End))
+	}
+}
+
+func TestPipeline_Window(t *testing.T) {
+	b := newFakeBroker(t)
+
+	var late []*Record
+
+	p := Source(b, "in", nil).
+		Window(Tumbling(time.Minute),
+			func(r *Record) string { return r.Body.(sample).key },
+			newCounter,
+			countAggregate,
+			WithEventTime(func(r *Record) time.Time { return r.Body.(sample).ts }),
+			WithLateDataHandler(func(_ context.Context, r *Record) { late = append(late, r) }),
+		).
+		Sink("out")
+	assert.Nil(t, p.Run())
+
+	base := time.Unix(6000, 0)
+	for _, s := range []sample{
+		{key: "a", ts: base},
+		{key: "b", ts: base.Add(10 * time.Second)},
+		{key: "a", ts: base.Add(20 * time.Second)},
+	} {
+		_, err := deliver(b, "in", s)
+		assert.Nil(t, err)
+	}
+	assert.Empty(t, b.PublishedBodies("out"))
+
+	_, err := deliver(b, "in", sample{key: "a", ts: base.Add(time.Minute)})
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, len(b.PublishedBodies("out")))
+	counts := map[string]int{}
+	for _, v := range b.PublishedBodies("out") {
+		res := v.(*WindowResult)
+		assert.Equal(t, base, res.Window.Start)
+		counts[res.Key] = *res.Value.(*int)
+	}
+	assert.Equal(t, map[string]int{"a": 2, "b": 1}, counts)
+
+	_, err = deliver(b, "in", sample{key: "a", ts: base.Add(30 * time.Second)})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(late))
+
+	assert.Nil(t, p.Stop())
+}
+
+func TestPipeline_WindowLinks(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	global := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(global)
+
+	b := newFakeBroker(t)
+	p := Source(b, "in", nil).
+		Window(Tumbling(time.Minute),
+			func(r *Record) string { return r.Body.(sample).key },
+			newCounter,
+			countAggregate,
+			WithEventTime(func(r *Record) time.Time { return r.Body.(sample).ts }),
+		).
+		Sink("out")
+	assert.Nil(t, p.Run())
+
+	// every record is published in a trace of its own.
+	var published []trace.SpanContext
+	deliver := func(s sample) {
+		ctx, span := tp.Tracer("producer").Start(context.Background(), "publish")
+		defer span.End()
+		published = append(published, span.SpanContext())
+
+		headers := broker.Headers{}
+		propagation.TraceContext{}.Inject(ctx, propagation.MapCarrier(headers))
+		_, err := b.Deliver(context.Background(), "in", s, headers)
+		assert.Nil(t, err)
+	}
+
+	base := time.Unix(6000, 0)
+	deliver(sample{key: "a", ts: base})
+	deliver(sample{key: "a", ts: base.Add(10 * time.Second)})
+	deliver(sample{key: "a", ts: base.Add(time.Minute)})
+	assert.Equal(t, 1, len(b.PublishedBodies("out")))
+
+	var window sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "pipeline-window" {
+			window = s
+		}
+	}
+	assert.NotNil(t, window)
+	assert.False(t, window.Parent().IsValid())
+	assert.Equal(t, trace.SpanKindConsumer, window.SpanKind())
+
+	var linked []trace.SpanContext
+	for _, l := range window.Links() {
+		linked = append(linked, l.SpanContext.WithRemote(false))
+	}
+	assert.Equal(t, published[:2], linked)
+
+	assert.Nil(t, p.Stop())
+}
+
+func TestPipeline_WindowRetry(t *testing.T) {
+	b := newFakeBroker(t)
+
+	var emitted []string
+	failed := errors.New("unavailable")
+	p := Source(b, "in", nil).
+		Window(Tumbling(time.Minute),
+			func(r *Record) string { return r.Body.(sample).key },
+			newCounter,
+			countAggregate,
+			WithEventTime(func(r *Record) time.Time { return r.Body.(sample).ts }),
+		).
+		SinkFunc(func(_ context.Context, r *Record) error {
+			res := r.Body.(*WindowResult)
+			emitted = append(emitted, res.Key)
+			if len(emitted) == 1 {
+				return failed
+			}
+			return nil
+		})
+	assert.Nil(t, p.Run())
+
+	base := time.Unix(6000, 0)
+	_, err := deliver(b, "in", sample{key: "a", ts: base})
+	assert.Nil(t, err)
+
+	// the sink fails, the window is kept.
+	_, err = deliver(b, "in", sample{key: "b", ts: base.Add(time.Minute)})
+	assert.ErrorIs(t, err, failed)
+
+	_, err = deliver(b, "in", sample{key: "b", ts: base.Add(time.Minute + time.Second)})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "a"}, emitted)
+
+	assert.Nil(t, p.Stop())
+}
+
+func TestPipeline_WindowIdle(t *testing.T) {
+	clock := broker.NewFakeClock(time.Unix(6000, 0))
+	b := mocks.NewFakeBroker(broker.WithClock(clock))
+	assert.Nil(t, b.Connect())
+
+	p := Source(b, "in", nil).
+		Window(Tumbling(time.Minute),
+			func(r *Record) string { return r.Body.(string) },
+			newCounter,
+			countAggregate,
+			WithIdleTimeout(time.Second),
+		).
+		Sink("out")
+	assert.Nil(t, p.Run())
+
+	_, err := deliver(b, "in", "a")
+	assert.Nil(t, err)
+
+	clock.BlockUntil(1)
+	clock.Advance(61 * time.Second)
+
+	// no record follows, the idle source closes the window.
+	assert.Eventually(t, func() bool {
+		return len(b.PublishedBodies("out")) == 1
+	}, time.Second, time.Millisecond)
+	res := b.PublishedBodies("out")[0].(*WindowResult)
+	assert.Equal(t, "a", res.Key)
+	assert.Equal(t, 1, *res.Value.(*int))
+
+	assert.Nil(t, p.Stop())
+}
@@ -0,0 +1,77 @@
+package priority
+
+import (
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(c *Consumer)
+
+// WithPriorityHeader route to the fast lane the messages whose header key
+// has one of values, default is "x-priority" set to "high".
+func WithPriorityHeader(key string, values ...string) Option {
+	return func(c *Consumer) {
+		set := make(map[string]struct{}, len(values))
+		for _, v := range values {
+			set[v] = struct{}{}
+		}
+		c.fast = func(m *broker.Message) bool {
+			if m == nil {
+				return false
+			}
+			_, ok := set[m.Headers[key]]
+			return ok
+		}
+	}
+}
+
+// WithMatcher route to the fast lane the messages matching fn.
+func WithMatcher(fn func(m *broker.Message) bool) Option {
+	return func(c *Consumer) {
+		c.fast = fn
+	}
+}
+
+// WithFastWorkers set the workers reserved to the fast lane, default is 4.
+func WithFastWorkers(n int) Option {
+	return func(c *Consumer) {
+		if n < 1 {
+			n = 1
+		}
+		c.fastWorkers = n
+	}
+}
+
+// WithBulkWorkers set the workers of the bulk lane, default is 4.
+func WithBulkWorkers(n int) Option {
+	return func(c *Consumer) {
+		if n < 1 {
+			n = 1
+		}
+		c.bulkWorkers = n
+	}
+}
+
+// WithQueueSize set how many messages each lane buffers, default is the
+// number of its workers. The delivery of the subscription blocks while the
+// lane of a message is full.
+func WithQueueSize(n int) Option {
+	return func(c *Consumer) {
+		c.queueSize = n
+	}
+}
+
+// WithStarvationRatio let a fast worker serve a waiting bulk message after
+// every n fast ones, default is 10, so the bulk lane moves on even when
+// its workers are all busy. Zero never lends the fast workers.
+func WithStarvationRatio(n int) Option {
+	return func(c *Consumer) {
+		c.ratio = n
+	}
+}
+
+// WithSubscribeOptions pass options to the subscription.
+func WithSubscribeOptions(opts ...broker.SubscribeOption) Option {
+	return func(c *Consumer) {
+		c.subscribeOpts = append(c.subscribeOpts, opts...)
+	}
+}
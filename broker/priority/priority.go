@@ -0,0 +1,217 @@
+package priority
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var ErrAlreadyRunning = errors.New("priority: already running")
+
+type Lane int
+
+const (
+	LaneBulk Lane = iota
+	LaneFast
+)
+
+func (l Lane) String() string {
+	if l == LaneFast {
+		return "fast"
+	}
+	return "bulk"
+}
+
+type job struct {
+	ctx   context.Context
+	event broker.Event
+}
+
+// Consumer splits the messages of a topic between two lanes: those with a
+// priority header go to workers reserved to them, the others to the bulk
+// workers, so interactive messages are not stuck behind a batch backlog:
+//
+//	c := priority.NewConsumer(b, "orders", handler, binder)
+//	_ = c.Run()
+//
+// The messages are acknowledged by the workers once handled, the
+// subscription delivering the next ones meanwhile.
+type Consumer struct {
+	sync.RWMutex
+
+	b       broker.Broker
+	topic   string
+	handler broker.Handler
+	binder  broker.Binder
+
+	fast          func(m *broker.Message) bool
+	fastWorkers   int
+	bulkWorkers   int
+	queueSize     int
+	ratio         int
+	subscribeOpts []broker.SubscribeOption
+
+	sub       broker.Subscriber
+	fastQueue chan job
+	bulkQueue chan job
+	wg        sync.WaitGroup
+}
+
+func NewConsumer(b broker.Broker, topic string, handler broker.Handler, binder broker.Binder, opts ...Option) *Consumer {
+	c := &Consumer{
+		b:           b,
+		topic:       topic,
+		handler:     handler,
+		binder:      binder,
+		fastWorkers: 4,
+		bulkWorkers: 4,
+		ratio:       10,
+	}
+	WithPriorityHeader("x-priority", "high")(c)
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
+}
+
+// Lane return the lane of m.
+func (c *Consumer) Lane(m *broker.Message) Lane {
+	if c.fast(m) {
+		return LaneFast
+	}
+	return LaneBulk
+}
+
+// Run start the workers and subscribe to the topic.
+func (c *Consumer) Run() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.sub != nil {
+		return ErrAlreadyRunning
+	}
+
+	fastSize, bulkSize := c.queueSize, c.queueSize
+	if c.queueSize <= 0 {
+		fastSize, bulkSize = c.fastWorkers, c.bulkWorkers
+	}
+	c.fastQueue = make(chan job, fastSize)
+	c.bulkQueue = make(chan job, bulkSize)
+
+	for i := 0; i < c.fastWorkers; i++ {
+		c.wg.Add(1)
+		go c.fastWorker()
+	}
+	for i := 0; i < c.bulkWorkers; i++ {
+		c.wg.Add(1)
+		go c.bulkWorker()
+	}
+
+	opts := append([]broker.SubscribeOption{broker.DisableAutoAck()}, c.subscribeOpts...)
+
+	sub, err := c.b.Subscribe(c.topic, c.dispatch, c.binder, opts...)
+	if err != nil {
+		c.stopWorkers()
+		return err
+	}
+	c.sub = sub
+
+	return nil
+}
+
+// Stop unsubscribe and wait for the messages queued in the lanes.
+func (c *Consumer) Stop() error {
+	c.Lock()
+	sub := c.sub
+	c.sub = nil
+	c.Unlock()
+
+	if sub == nil {
+		return nil
+	}
+
+	err := sub.Unsubscribe(true)
+
+	c.Lock()
+	c.stopWorkers()
+	c.Unlock()
+
+	return err
+}
+
+func (c *Consumer) stopWorkers() {
+	close(c.fastQueue)
+	close(c.bulkQueue)
+	c.wg.Wait()
+	c.fastQueue, c.bulkQueue = nil, nil
+}
+
+func (c *Consumer) dispatch(ctx context.Context, event broker.Event) error {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.fastQueue == nil {
+		return errors.New("priority: consumer stopped")
+	}
+
+	// the handler returns before the message is handled, keep its values only.
+	j := job{ctx: context.WithoutCancel(ctx), event: event}
+	if c.Lane(event.Message()) == LaneFast {
+		c.fastQueue <- j
+	} else {
+		c.bulkQueue <- j
+	}
+	return nil
+}
+
+func (c *Consumer) fastWorker() {
+	defer c.wg.Done()
+
+	fastQueue, bulkQueue := c.fastQueue, c.bulkQueue
+
+	served := 0
+	for {
+		// lend the worker to the bulk lane once in a while.
+		if c.ratio > 0 && served >= c.ratio {
+			select {
+			case j, ok := <-bulkQueue:
+				if ok {
+					c.process(LaneBulk, j)
+				}
+			default:
+			}
+			served = 0
+		}
+
+		j, ok := <-fastQueue
+		if !ok {
+			return
+		}
+		c.process(LaneFast, j)
+		served++
+	}
+}
+
+func (c *Consumer) bulkWorker() {
+	defer c.wg.Done()
+
+	for j := range c.bulkQueue {
+		c.process(LaneBulk, j)
+	}
+}
+
+func (c *Consumer) process(lane Lane, j job) {
+	if err := c.handler(j.ctx, j.event); err != nil {
+		log.Errorf("[priority] handle %s message from [%s] failed: %v", lane, c.topic, err)
+		return
+	}
+	if err := broker.AutoAck(j.event); err != nil {
+		log.Errorf("[priority] ack %s message from [%s] failed: %v", lane, c.topic, err)
+	}
+}
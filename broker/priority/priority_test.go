@@ -0,0 +1,110 @@
+package priority
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func deliver(t *testing.T, b *mocks.FakeBroker, body string, headers broker.Headers) *mocks.Event {
+	events, err := b.Deliver(context.Background(), "orders", body, headers)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	return events[0]
+}
+
+func message(t *testing.T, b *mocks.FakeBroker, priority string) *mocks.Event {
+	return deliver(t, b, priority, broker.Headers{"x-priority": priority})
+}
+
+func TestConsumer_Lanes(t *testing.T) {
+	b := mocks.NewFakeBroker()
+
+	release := make(chan struct{})
+	handled := make(chan string, 10)
+	c := NewConsumer(b, "orders", func(_ context.Context, event broker.Event) error {
+		body := event.Message().Body.(string)
+		if body == "low" {
+			<-release
+		}
+		handled <- body
+		return nil
+	}, nil, WithFastWorkers(1), WithBulkWorkers(1), WithQueueSize(4), WithStarvationRatio(0))
+
+	assert.NoError(t, c.Run())
+	assert.ErrorIs(t, c.Run(), ErrAlreadyRunning)
+	assert.False(t, b.Subscribers("orders")[0].Opts.AutoAck)
+
+	ctx := context.Background()
+	bulk := []*mocks.Event{message(t, b, "low"), message(t, b, "low")}
+
+	// the fast lane runs while the bulk workers are stuck.
+	fast := message(t, b, "high")
+	assert.Equal(t, "high", <-handled)
+	assert.Eventually(t, fast.IsAcked, time.Second, time.Millisecond)
+	assert.False(t, bulk[0].IsAcked())
+
+	close(release)
+	assert.Equal(t, "low", <-handled)
+	assert.Equal(t, "low", <-handled)
+
+	assert.NoError(t, c.Stop())
+	assert.True(t, bulk[0].IsAcked())
+	assert.True(t, bulk[1].IsAcked())
+	assert.Error(t, c.dispatch(ctx, mocks.NewEvent("orders", "high", broker.Headers{"x-priority": "high"})))
+}
+
+func TestConsumer_Starvation(t *testing.T) {
+	b := mocks.NewFakeBroker()
+
+	release := make(chan struct{})
+	var mtx sync.Mutex
+	var order []string
+	c := NewConsumer(b, "orders", func(_ context.Context, event broker.Event) error {
+		body := event.Message().Body.(string)
+		if body == "blocker" {
+			<-release
+			return nil
+		}
+		mtx.Lock()
+		order = append(order, body)
+		mtx.Unlock()
+		return nil
+	}, nil,
+		WithMatcher(func(m *broker.Message) bool { return m.Body != "low" && m.Body != "blocker" }),
+		WithFastWorkers(1), WithBulkWorkers(1), WithQueueSize(8), WithStarvationRatio(2),
+	)
+	assert.NoError(t, c.Run())
+
+	// the bulk worker is stuck, the fast worker serves the bulk lane every 2 messages.
+	deliver(t, b, "blocker", nil)
+	assert.Eventually(t, func() bool { return len(c.bulkQueue) == 0 }, time.Second, time.Millisecond)
+	deliver(t, b, "low", nil)
+	for _, body := range []string{"f1", "f2", "f3"} {
+		deliver(t, b, body, nil)
+	}
+
+	assert.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(order) == 4
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"f1", "f2", "low", "f3"}, order)
+
+	close(release)
+	assert.NoError(t, c.Stop())
+}
+
+func TestConsumer_Lane(t *testing.T) {
+	c := NewConsumer(mocks.NewFakeBroker(), "orders", nil, nil, WithPriorityHeader("x-class", "interactive", "urgent"))
+	assert.Equal(t, LaneFast, c.Lane(&broker.Message{Headers: broker.Headers{"x-class": "urgent"}}))
+	assert.Equal(t, LaneBulk, c.Lane(&broker.Message{Headers: broker.Headers{"x-priority": "high"}}))
+	assert.Equal(t, LaneBulk, c.Lane(nil))
+	assert.Equal(t, "fast", LaneFast.String())
+}
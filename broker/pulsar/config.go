@@ -0,0 +1,263 @@
+package pulsar
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed pulsar configuration of a broker, the fields are the
+// ones set one by one with the broker options of the package.
+type Config struct {
+	ConnectionTimeout       time.Duration
+	OperationTimeout        time.Duration
+	ListenerName            string
+	MaxConnectionsPerBroker int
+	CustomMetricsLabels     map[string]string
+	TLS                     *TLSConfig
+	AuthenticationToken     string
+}
+
+// DefaultConfig return the configuration of a broker without options.
+func DefaultConfig() Config {
+	return Config{
+		ConnectionTimeout: 30 * time.Second,
+		OperationTimeout:  30 * time.Second,
+	}
+}
+
+// WithConfig set the whole configuration at once, c should start from
+// DefaultConfig since every field is applied. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		opts := []broker.Option{
+			WithConnectionTimeout(c.ConnectionTimeout),
+			WithOperationTimeout(c.OperationTimeout),
+		}
+		if c.ListenerName != "" {
+			opts = append(opts, WithListenerName(c.ListenerName))
+		}
+		if c.MaxConnectionsPerBroker != 0 {
+			opts = append(opts, WithMaxConnectionsPerBroker(c.MaxConnectionsPerBroker))
+		}
+		if c.CustomMetricsLabels != nil {
+			opts = append(opts, WithCustomMetricsLabels(c.CustomMetricsLabels))
+		}
+		if c.TLS != nil {
+			opts = append(opts, broker.OptionContextWithValue(tlsKey{}, *c.TLS))
+		}
+		if c.AuthenticationToken != "" {
+			opts = append(opts, WithAuthenticationToken(c.AuthenticationToken))
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	c := DefaultConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if val, ok := opts.Context.Value(connectionTimeoutKey{}).(time.Duration); ok {
+		c.ConnectionTimeout = val
+	}
+	if val, ok := opts.Context.Value(operationTimeoutKey{}).(time.Duration); ok {
+		c.OperationTimeout = val
+	}
+	c.ListenerName, _ = opts.Context.Value(listenerNameKey{}).(string)
+	c.MaxConnectionsPerBroker, _ = opts.Context.Value(maxConnectionsPerBrokerKey{}).(int)
+	c.CustomMetricsLabels, _ = opts.Context.Value(customMetricsLabelsKey{}).(map[string]string)
+	if val, ok := opts.Context.Value(tlsKey{}).(TLSConfig); ok {
+		c.TLS = &val
+	}
+	c.AuthenticationToken, _ = opts.Context.Value(authenticationTokenKey{}).(string)
+
+	return c
+}
+
+// SubscribeConfig is the typed pulsar configuration of a subscription.
+type SubscribeConfig struct {
+	SubscriptionName       string
+	ConsumerProperties     map[string]string
+	SubscriptionProperties map[string]string
+	TopicsPattern          string
+	AutoDiscoveryPeriod    time.Duration
+	NackRedeliveryDelay    time.Duration
+	RetryEnable            bool
+	ReceiverQueueSize      int
+}
+
+// DefaultSubscribeConfig return the configuration of a subscription without
+// options.
+func DefaultSubscribeConfig() SubscribeConfig {
+	return SubscribeConfig{
+		SubscriptionName: "my-subscription",
+	}
+}
+
+// WithSubscribeConfig set the whole subscription configuration at once, c
+// should start from DefaultSubscribeConfig.
+func WithSubscribeConfig(c SubscribeConfig) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		opts := []broker.SubscribeOption{
+			WithSubscriptionName(c.SubscriptionName),
+			WithSubscriptionRetryEnable(c.RetryEnable),
+		}
+		if c.ConsumerProperties != nil {
+			opts = append(opts, WithConsumerProperties(c.ConsumerProperties))
+		}
+		if c.SubscriptionProperties != nil {
+			opts = append(opts, WithSubscriptionProperties(c.SubscriptionProperties))
+		}
+		if c.TopicsPattern != "" {
+			opts = append(opts, WithSubscriptionTopicsPattern(c.TopicsPattern))
+		}
+		if c.AutoDiscoveryPeriod != 0 {
+			opts = append(opts, WithAutoDiscoveryPeriod(c.AutoDiscoveryPeriod))
+		}
+		if c.NackRedeliveryDelay != 0 {
+			opts = append(opts, WithNackRedeliveryDelay(c.NackRedeliveryDelay))
+		}
+		if c.ReceiverQueueSize != 0 {
+			opts = append(opts, WithReceiverQueueSize(c.ReceiverQueueSize))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// SubscribeConfigFromOptions return the subscription configuration set in
+// the context of opts.
+func SubscribeConfigFromOptions(opts broker.SubscribeOptions) SubscribeConfig {
+	c := DefaultSubscribeConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if val, ok := opts.Context.Value(subscriptionNameKey{}).(string); ok {
+		c.SubscriptionName = val
+	}
+	c.ConsumerProperties, _ = opts.Context.Value(consumerPropertiesKey{}).(map[string]string)
+	c.SubscriptionProperties, _ = opts.Context.Value(subscriptionPropertiesKey{}).(map[string]string)
+	c.TopicsPattern, _ = opts.Context.Value(topicsPatternKey{}).(string)
+	c.AutoDiscoveryPeriod, _ = opts.Context.Value(autoDiscoveryPeriodKey{}).(time.Duration)
+	c.NackRedeliveryDelay, _ = opts.Context.Value(nackRedeliveryDelayKey{}).(time.Duration)
+	c.RetryEnable, _ = opts.Context.Value(subscriptionRetryEnableKey{}).(bool)
+	c.ReceiverQueueSize, _ = opts.Context.Value(receiverQueueSizeKey{}).(int)
+
+	return c
+}
+
+// PublishConfig is the typed pulsar configuration of a publishing, the
+// zero fields are left unset. The producer fields apply when the producer
+// of the topic is created.
+type PublishConfig struct {
+	ProducerName            string
+	ProducerProperties      map[string]string
+	SendTimeout             time.Duration
+	DisableBatching         bool
+	BatchingMaxPublishDelay time.Duration
+	BatchingMaxMessages     uint
+	BatchingMaxSize         uint
+
+	Headers            map[string]string
+	DeliverAfter       time.Duration
+	DeliverAt          time.Time
+	SequenceID         *int64
+	Key                string
+	Value              interface{}
+	OrderingKey        string
+	EventTime          time.Time
+	DisableReplication bool
+}
+
+// WithPublishConfig set the whole publishing configuration at once.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		var opts []broker.PublishOption
+		if c.ProducerName != "" {
+			opts = append(opts, WithProducerName(c.ProducerName))
+		}
+		if c.ProducerProperties != nil {
+			opts = append(opts, WithProducerProperties(c.ProducerProperties))
+		}
+		if c.SendTimeout != 0 {
+			opts = append(opts, WithSendTimeout(c.SendTimeout))
+		}
+		if c.DisableBatching {
+			opts = append(opts, WithDisableBatching(true))
+		}
+		if c.BatchingMaxPublishDelay != 0 {
+			opts = append(opts, WithBatchingMaxPublishDelay(c.BatchingMaxPublishDelay))
+		}
+		if c.BatchingMaxMessages != 0 {
+			opts = append(opts, WithBatchingMaxMessages(c.BatchingMaxMessages))
+		}
+		if c.BatchingMaxSize != 0 {
+			opts = append(opts, WithBatchingMaxSize(c.BatchingMaxSize))
+		}
+		if c.Headers != nil {
+			opts = append(opts, WithHeaders(c.Headers))
+		}
+		if c.DeliverAfter != 0 {
+			opts = append(opts, WithDeliverAfter(c.DeliverAfter))
+		}
+		if !c.DeliverAt.IsZero() {
+			opts = append(opts, WithDeliverAt(c.DeliverAt))
+		}
+		if c.SequenceID != nil {
+			opts = append(opts, WithSequenceID(c.SequenceID))
+		}
+		if c.Key != "" {
+			opts = append(opts, WithMessageKey(c.Key))
+		}
+		if c.Value != nil {
+			opts = append(opts, WithMessageValue(c.Value))
+		}
+		if c.OrderingKey != "" {
+			opts = append(opts, WithMessageOrderingKey(c.OrderingKey))
+		}
+		if !c.EventTime.IsZero() {
+			opts = append(opts, WithMessageEventTime(c.EventTime))
+		}
+		if c.DisableReplication {
+			opts = append(opts, WithMessageDisableReplication(true))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	var c PublishConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.ProducerName, _ = opts.Context.Value(producerNameKey{}).(string)
+	c.ProducerProperties, _ = opts.Context.Value(producerPropertiesKey{}).(map[string]string)
+	c.SendTimeout, _ = opts.Context.Value(sendTimeoutKey{}).(time.Duration)
+	c.DisableBatching, _ = opts.Context.Value(disableBatchingKey{}).(bool)
+	c.BatchingMaxPublishDelay, _ = opts.Context.Value(batchingMaxPublishDelayKey{}).(time.Duration)
+	c.BatchingMaxMessages, _ = opts.Context.Value(batchingMaxMessagesKey{}).(uint)
+	c.BatchingMaxSize, _ = opts.Context.Value(batchingMaxSizeKey{}).(uint)
+
+	c.Headers, _ = opts.Context.Value(messageHeadersKey{}).(map[string]string)
+	c.DeliverAfter, _ = opts.Context.Value(messageDeliverAfterKey{}).(time.Duration)
+	c.DeliverAt, _ = opts.Context.Value(messageDeliverAtKey{}).(time.Time)
+	c.SequenceID, _ = opts.Context.Value(messageSequenceIdKey{}).(*int64)
+	c.Key, _ = opts.Context.Value(messageKeyKey{}).(string)
+	c.Value = opts.Context.Value(messageValueKey{})
+	c.OrderingKey, _ = opts.Context.Value(messageOrderingKeyKey{}).(string)
+	c.EventTime, _ = opts.Context.Value(messageEventTimeKey{}).(time.Time)
+	c.DisableReplication, _ = opts.Context.Value(messageDisableReplication{}).(bool)
+
+	return c
+}
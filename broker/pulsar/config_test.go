@@ -0,0 +1,79 @@
+package pulsar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultConfig(), ConfigFromOptions(broker.NewOptions()))
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithConnectionTimeout(time.Second),
+		WithListenerName("external"),
+		WithTLSConfig("ca.pem", "cert.pem", "key.pem", false, true),
+		WithAuthenticationToken("token"),
+	))
+
+	c := DefaultConfig()
+	c.ConnectionTimeout = time.Second
+	c.ListenerName = "external"
+	c.TLS = &TLSConfig{
+		ClientCertPath:   "cert.pem",
+		ClientKeyPath:    "key.pem",
+		CaCertsPath:      "ca.pem",
+		ValidateHostname: true,
+	}
+	c.AuthenticationToken = "token"
+
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c), WithListenerName("internal")))
+	assert.Equal(t, "internal", overridden.ListenerName)
+}
+
+func TestSubscribeConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultSubscribeConfig(), SubscribeConfigFromOptions(broker.NewSubscribeOptions()))
+
+	legacy := SubscribeConfigFromOptions(broker.NewSubscribeOptions(
+		WithSubscriptionName("orders"),
+		WithNackRedeliveryDelay(time.Second),
+		WithReceiverQueueSize(10),
+	))
+
+	c := SubscribeConfig{
+		SubscriptionName:    "orders",
+		NackRedeliveryDelay: time.Second,
+		ReceiverQueueSize:   10,
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c))))
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	now := time.Now()
+
+	legacy := PublishConfigFromOptions(broker.NewPublishOptions(
+		WithProducerName("orders"),
+		WithDisableBatching(true),
+		WithMessageKey("1"),
+		WithDeliverAt(now),
+		WithHeaders(map[string]string{"trace": "1"}),
+	))
+
+	c := PublishConfig{
+		ProducerName:    "orders",
+		DisableBatching: true,
+		Key:             "1",
+		DeliverAt:       now,
+		Headers:         map[string]string{"trace": "1"},
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+}
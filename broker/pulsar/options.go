@@ -15,6 +15,7 @@ type listenerNameKey struct{}
 type maxConnectionsPerBrokerKey struct{}
 type customMetricsLabelsKey struct{}
 type tlsKey struct{}
+type authenticationTokenKey struct{}
 
 // WithConnectionTimeout ClientOptions.ConnectionTimeout
 func WithConnectionTimeout(timeout time.Duration) broker.Option {
@@ -41,7 +42,8 @@ func WithCustomMetricsLabels(labels map[string]string) broker.Option {
 	return broker.OptionContextWithValue(customMetricsLabelsKey{}, labels)
 }
 
-type tlsConfig struct {
+// TLSConfig is the tls configuration of the client, see WithTLSConfig.
+type TLSConfig struct {
 	ClientCertPath          string
 	ClientKeyPath           string
 	CaCertsPath             string
@@ -51,7 +53,7 @@ type tlsConfig struct {
 
 // WithTLSConfig set tls config for client
 func WithTLSConfig(caCertsPath, tlsClientCertPath, tlsClientKeyPath string, allowInsecureConnection, validateHostname bool) broker.Option {
-	cfg := tlsConfig{
+	cfg := TLSConfig{
 		tlsClientCertPath,
 		tlsClientKeyPath,
 		caCertsPath,
@@ -61,6 +63,12 @@ func WithTLSConfig(caCertsPath, tlsClientCertPath, tlsClientKeyPath string, allo
 	return broker.OptionContextWithValue(tlsKey{}, cfg)
 }
 
+// WithAuthenticationToken authenticate with a JWT token, such as the role
+// token of a Tencent Cloud TDMQ cluster.
+func WithAuthenticationToken(token string) broker.Option {
+	return broker.OptionContextWithValue(authenticationTokenKey{}, token)
+}
+
 ///
 /// PublishOption
 ///
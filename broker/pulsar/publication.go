@@ -8,6 +8,8 @@ import (
 )
 
 type publication struct {
+	broker.AckState
+
 	topic     string
 	err       error
 	ctx       context.Context
@@ -28,11 +30,22 @@ func (p *publication) RawMessage() interface{} {
 	return p.pulsarMsg
 }
 
+// Position resume at the message, which may then be received again.
+func (p *publication) Position() broker.Position {
+	m := *p.pulsarMsg
+	pos := broker.AtMessageID(m.ID().Serialize())
+	pos.Partition = int(m.ID().PartitionIdx())
+	pos.Time = m.PublishTime()
+	return pos
+}
+
 func (p *publication) Ack() error {
-	if p.reader == nil {
-		return errors.New("reader is nil")
-	}
-	return p.reader.Ack(*p.pulsarMsg)
+	return p.AckState.Ack(func() error {
+		if p.reader == nil {
+			return errors.New("reader is nil")
+		}
+		return p.reader.Ack(*p.pulsarMsg)
+	})
 }
 
 func (p *publication) Error() error {
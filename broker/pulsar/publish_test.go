@@ -0,0 +1,21 @@
+package pulsar
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func TestPublishPath(t *testing.T) {
+	mocks.TestPublishPath(t, func(opts []broker.Option, topic string, msg broker.Any, popts ...broker.PublishOption) (broker.Headers, []byte, error) {
+		pb := NewBroker(opts...).(*pulsarBroker)
+
+		pulsarMsg, _, err := pb.newMessage(context.Background(), topic, msg, popts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pulsarMsg.Properties, pulsarMsg.Payload, nil
+	}, true)
+}
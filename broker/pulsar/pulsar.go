@@ -3,6 +3,7 @@ package pulsar
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
 	"sync"
 	"time"
@@ -21,6 +22,9 @@ import (
 
 const (
 	defaultAddr = "pulsar://127.0.0.1:6650"
+
+	// defaultMaxMessageSize is the default maxMessageSize of the brokers.
+	defaultMaxMessageSize = 5 * 1024 * 1024
 )
 
 type pulsarBroker struct {
@@ -36,10 +40,12 @@ type pulsarBroker struct {
 
 	producerTracer *tracing.Tracer
 	consumerTracer *tracing.Tracer
+
+	metrics *broker.Metrics
 }
 
 func NewBroker(opts ...broker.Option) broker.Broker {
-	options := broker.NewOptionsAndApply(opts...)
+	options := broker.NewOptionsAndApply(append([]broker.Option{broker.WithMaxMessageSize(defaultMaxMessageSize)}, opts...)...)
 
 	b := &pulsarBroker{
 		options:     options,
@@ -68,30 +74,19 @@ func (pb *pulsarBroker) Options() broker.Options {
 func (pb *pulsarBroker) Init(opts ...broker.Option) error {
 	pb.options.Apply(opts...)
 
-	pulsarOptions := pulsar.ClientOptions{
-		URL:               defaultAddr,
-		OperationTimeout:  30 * time.Second,
-		ConnectionTimeout: 30 * time.Second,
-	}
+	c := ConfigFromOptions(pb.options)
 
-	if v, ok := pb.options.Context.Value(connectionTimeoutKey{}).(time.Duration); ok {
-		pulsarOptions.OperationTimeout = v
-	}
-	if v, ok := pb.options.Context.Value(operationTimeoutKey{}).(time.Duration); ok {
-		pulsarOptions.ConnectionTimeout = v
-	}
-	if v, ok := pb.options.Context.Value(listenerNameKey{}).(string); ok {
-		pulsarOptions.ListenerName = v
-	}
-	if v, ok := pb.options.Context.Value(maxConnectionsPerBrokerKey{}).(int); ok {
-		pulsarOptions.MaxConnectionsPerBroker = v
-	}
-	if v, ok := pb.options.Context.Value(customMetricsLabelsKey{}).(map[string]string); ok {
-		pulsarOptions.CustomMetricsLabels = v
+	pulsarOptions := pulsar.ClientOptions{
+		URL:                     defaultAddr,
+		OperationTimeout:        c.OperationTimeout,
+		ConnectionTimeout:       c.ConnectionTimeout,
+		ListenerName:            c.ListenerName,
+		MaxConnectionsPerBroker: c.MaxConnectionsPerBroker,
+		CustomMetricsLabels:     c.CustomMetricsLabels,
 	}
 
 	var enableTLS = false
-	if v, ok := pb.options.Context.Value(tlsKey{}).(tlsConfig); ok {
+	if v := c.TLS; v != nil {
 		pulsarOptions.TLSTrustCertsFilePath = v.CaCertsPath
 		if v.ClientCertPath != "" && v.ClientKeyPath != "" {
 			pulsarOptions.Authentication = pulsar.NewAuthenticationTLS(v.ClientCertPath, v.ClientKeyPath)
@@ -102,6 +97,10 @@ func (pb *pulsarBroker) Init(opts ...broker.Option) error {
 		enableTLS = true
 	}
 
+	if c.AuthenticationToken != "" {
+		pulsarOptions.Authentication = pulsar.NewAuthenticationToken(c.AuthenticationToken)
+	}
+
 	var cAddrs []string
 	for _, addr := range pb.options.Addrs {
 		if len(addr) == 0 {
@@ -128,6 +127,8 @@ func (pb *pulsarBroker) Init(opts ...broker.Option) error {
 		pb.consumerTracer = tracing.NewTracer(trace.SpanKindConsumer, "pulsar-consumer", pb.options.Tracings...)
 	}
 
+	pb.metrics = broker.NewMetrics("pulsar", pb.options.MeterProvider)
+
 	return nil
 }
 
@@ -170,47 +171,78 @@ func (pb *pulsarBroker) Disconnect() error {
 }
 
 func (pb *pulsarBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
-	buf, err := broker.Marshal(pb.options.Codec, msg)
+	pulsarMsg, options, err := pb.newMessage(ctx, topic, msg, opts)
 	if err != nil {
 		return err
 	}
 
-	return pb.publish(ctx, topic, buf, opts...)
+	start := time.Now()
+	err = pb.publish(topic, pulsarMsg, options)
+	pb.metrics.RecordPublish(ctx, topic, start, err)
+
+	return err
 }
 
-func (pb *pulsarBroker) publish(ctx context.Context, topic string, msg []byte, opts ...broker.PublishOption) error {
+// newMessage encode msg and run the publish interceptors, it returns the
+// message to send to topic with the options of the publish.
+func (pb *pulsarBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) (*pulsar.ProducerMessage, broker.PublishOptions, error) {
 	options := broker.PublishOptions{
 		Context: ctx,
 	}
+
+	buf, err := broker.Marshal(pb.options.Codec, msg)
+	if err != nil {
+		return nil, options, err
+	}
+	buf, opts, err = pb.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return nil, options, err
+	}
+
 	for _, o := range opts {
 		o(&options)
 	}
 
-	pulsarOptions := pulsar.ProducerOptions{
-		Topic:           topic,
-		DisableBatching: false,
-	}
+	c := PublishConfigFromOptions(options)
 
-	if v, ok := options.Context.Value(producerNameKey{}).(string); ok {
-		pulsarOptions.Name = v
-	}
-	if v, ok := options.Context.Value(producerPropertiesKey{}).(map[string]string); ok {
-		pulsarOptions.Properties = v
-	}
-	if v, ok := options.Context.Value(sendTimeoutKey{}).(time.Duration); ok {
-		pulsarOptions.SendTimeout = v
-	}
-	if v, ok := options.Context.Value(disableBatchingKey{}).(bool); ok {
-		pulsarOptions.DisableBatching = v
-	}
-	if v, ok := options.Context.Value(batchingMaxPublishDelayKey{}).(time.Duration); ok {
-		pulsarOptions.BatchingMaxPublishDelay = v
+	pulsarMsg := &pulsar.ProducerMessage{
+		Payload:            buf,
+		Properties:         c.Headers,
+		DeliverAfter:       c.DeliverAfter,
+		DeliverAt:          c.DeliverAt,
+		SequenceID:         c.SequenceID,
+		Key:                c.Key,
+		Value:              c.Value,
+		OrderingKey:        c.OrderingKey,
+		EventTime:          c.EventTime,
+		DisableReplication: c.DisableReplication,
 	}
-	if v, ok := options.Context.Value(batchingMaxMessagesKey{}).(uint); ok {
-		pulsarOptions.BatchingMaxMessages = v
+
+	if len(options.Headers) > 0 {
+		pulsarMsg.Properties = make(map[string]string, len(c.Headers)+len(options.Headers))
+		for k, v := range c.Headers {
+			pulsarMsg.Properties[k] = v
+		}
+		for k, v := range options.Headers {
+			pulsarMsg.Properties[k] = v
+		}
 	}
-	if v, ok := options.Context.Value(batchingMaxSizeKey{}).(uint); ok {
-		pulsarOptions.BatchingMaxSize = v
+
+	return pulsarMsg, options, nil
+}
+
+func (pb *pulsarBroker) publish(topic string, pulsarMsg *pulsar.ProducerMessage, options broker.PublishOptions) error {
+	c := PublishConfigFromOptions(options)
+
+	pulsarOptions := pulsar.ProducerOptions{
+		Topic:                   topic,
+		Name:                    c.ProducerName,
+		Properties:              c.ProducerProperties,
+		SendTimeout:             c.SendTimeout,
+		DisableBatching:         c.DisableBatching,
+		BatchingMaxPublishDelay: c.BatchingMaxPublishDelay,
+		BatchingMaxMessages:     c.BatchingMaxMessages,
+		BatchingMaxSize:         c.BatchingMaxSize,
 	}
 
 	var cached bool
@@ -230,41 +262,11 @@ func (pb *pulsarBroker) publish(ctx context.Context, topic string, msg []byte, o
 	}
 	pb.Unlock()
 
-	pulsarMsg := pulsar.ProducerMessage{Payload: msg}
-
-	if headers, ok := options.Context.Value(messageHeadersKey{}).(map[string]string); ok {
-		pulsarMsg.Properties = headers
-	}
-	if v, ok := options.Context.Value(messageDeliverAfterKey{}).(time.Duration); ok {
-		pulsarMsg.DeliverAfter = v
-	}
-	if v, ok := options.Context.Value(messageDeliverAtKey{}).(time.Time); ok {
-		pulsarMsg.DeliverAt = v
-	}
-	if v, ok := options.Context.Value(messageSequenceIdKey{}).(*int64); ok {
-		pulsarMsg.SequenceID = v
-	}
-	if v, ok := options.Context.Value(messageKeyKey{}).(string); ok {
-		pulsarMsg.Key = v
-	}
-	if v, ok := options.Context.Value(messageValueKey{}).(interface{}); ok {
-		pulsarMsg.Value = v
-	}
-	if v, ok := options.Context.Value(messageOrderingKeyKey{}).(string); ok {
-		pulsarMsg.OrderingKey = v
-	}
-	if v, ok := options.Context.Value(messageEventTimeKey{}).(time.Time); ok {
-		pulsarMsg.EventTime = v
-	}
-	if v, ok := options.Context.Value(messageDisableReplication{}).(bool); ok {
-		pulsarMsg.DisableReplication = v
-	}
-
-	span := pb.startProducerSpan(options.Context, topic, &pulsarMsg)
+	span := pb.startProducerSpan(options.Context, topic, pulsarMsg)
 
 	var err error
 	var messageId pulsar.MessageID
-	messageId, err = producer.Send(pb.options.Context, &pulsarMsg)
+	messageId, err = producer.Send(pb.options.Context, pulsarMsg)
 	if err != nil {
 		log.Errorf("[pulsar]: send message error: %s\n", err)
 		switch cached {
@@ -280,7 +282,7 @@ func (pb *pulsarBroker) publish(ctx context.Context, topic string, msg []byte, o
 				pb.Unlock()
 				break
 			}
-			if _, err = producer.Send(pb.options.Context, &pulsarMsg); err == nil {
+			if _, err = producer.Send(pb.options.Context, pulsarMsg); err == nil {
 				pb.Lock()
 				pb.producers[topic] = producer
 				pb.Unlock()
@@ -308,45 +310,39 @@ func (pb *pulsarBroker) Subscribe(topic string, handler broker.Handler, binder b
 		o(&options)
 	}
 
+	handler = options.TimeoutHandler(handler)
+	handler = pb.metrics.Handler(topic, handler)
+
+	sc := SubscribeConfigFromOptions(options)
+
 	pulsarOptions := pulsar.ConsumerOptions{
-		Topic:            topic,
-		SubscriptionName: "my-subscription",
-		Type:             pulsar.Shared,
+		Topic:                  topic,
+		SubscriptionName:       sc.SubscriptionName,
+		Type:                   pulsar.Shared,
+		Properties:             sc.ConsumerProperties,
+		SubscriptionProperties: sc.SubscriptionProperties,
+		TopicsPattern:          sc.TopicsPattern,
+		AutoDiscoveryPeriod:    sc.AutoDiscoveryPeriod,
+		NackRedeliveryDelay:    sc.NackRedeliveryDelay,
+		RetryEnable:            sc.RetryEnable,
+		ReceiverQueueSize:      sc.ReceiverQueueSize,
 	}
 
 	channel := make(chan pulsar.ConsumerMessage, 100)
 	pulsarOptions.MessageChannel = channel
 
-	if v, ok := options.Context.Value(subscriptionNameKey{}).(string); ok {
-		pulsarOptions.SubscriptionName = v
-	}
-	if v, ok := options.Context.Value(consumerPropertiesKey{}).(map[string]string); ok {
-		pulsarOptions.Properties = v
-	}
-	if v, ok := options.Context.Value(subscriptionPropertiesKey{}).(map[string]string); ok {
-		pulsarOptions.SubscriptionProperties = v
-	}
-	if v, ok := options.Context.Value(topicsPatternKey{}).(string); ok {
-		pulsarOptions.TopicsPattern = v
-	}
-	if v, ok := options.Context.Value(autoDiscoveryPeriodKey{}).(time.Duration); ok {
-		pulsarOptions.AutoDiscoveryPeriod = v
-	}
-	if v, ok := options.Context.Value(nackRedeliveryDelayKey{}).(time.Duration); ok {
-		pulsarOptions.NackRedeliveryDelay = v
-	}
-	if v, ok := options.Context.Value(subscriptionRetryEnableKey{}).(bool); ok {
-		pulsarOptions.RetryEnable = v
-	}
-	if v, ok := options.Context.Value(receiverQueueSizeKey{}).(int); ok {
-		pulsarOptions.ReceiverQueueSize = v
-	}
-
 	c, _ := pb.client.Subscribe(pulsarOptions)
 	if c == nil {
 		return nil, errors.New("create consumer error")
 	}
 
+	if options.StartPosition != nil {
+		if err := seekConsumer(c, *options.StartPosition); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("start [%s] at %s: %w", topic, options.StartPosition, err)
+		}
+	}
+
 	sub := &subscriber{
 		r:       pb,
 		options: options,
@@ -357,48 +353,73 @@ func (pb *pulsarBroker) Subscribe(topic string, handler broker.Handler, binder b
 	}
 
 	go func() {
-		var err error
-		var m broker.Message
 		for cm := range channel {
-			p := &publication{topic: cm.Topic(), reader: sub.reader, msg: &m, pulsarMsg: &cm.Message, ctx: options.Context}
-			m.Headers = cm.Properties()
+			pb.handleMessage(sub, cm, binder)
+		}
+	}()
 
-			ctx, span := pb.startConsumerSpan(sub.options.Context, &cm)
+	pb.subscribers.Add(topic, sub)
 
-			if binder != nil {
-				m.Body = binder()
-			} else {
-				m.Body = cm.Payload()
-			}
+	return sub, nil
+}
 
-			if err = broker.Unmarshal(pb.options.Codec, cm.Payload(), &m.Body); err != nil {
-				p.err = err
-				log.Errorf("[pulsar]: unmarshal message failed: %v", err)
-				pb.finishConsumerSpan(span, err)
-				continue
-			}
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (pb *pulsarBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return pb.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
 
-			if err = sub.handler(ctx, p); err != nil {
-				p.err = err
-				log.Errorf("[pulsar]: handle message failed: %v", err)
-				pb.finishConsumerSpan(span, err)
-				continue
-			}
+func (pb *pulsarBroker) handleMessage(sub *subscriber, cm pulsar.ConsumerMessage, binder broker.Binder) {
+	m := &broker.Message{Headers: cm.Properties()}
+	p := &publication{topic: cm.Topic(), reader: sub.reader, msg: m, pulsarMsg: &cm.Message, ctx: sub.options.Context}
 
-			if sub.options.AutoAck {
-				if err = p.Ack(); err != nil {
-					p.err = err
-					log.Errorf("[pulsar]: unable to commit msg: %v", err)
-				}
-			}
+	ctx, span := pb.startConsumerSpan(sub.options.Context, &cm)
 
-			pb.finishConsumerSpan(span, err)
+	if binder != nil && !sub.options.RawBody {
+		m.Body = binder()
+	} else {
+		m.Body = cm.Payload()
+	}
+
+	var err error
+	if err = pb.options.Decode(&sub.options, m.Headers, cm.Payload(), &m.Body); err != nil {
+		p.err = err
+		log.Errorf("[pulsar]: unmarshal message failed: %v", err)
+		sub.options.ReportError(ctx, broker.ErrUnmarshal, err, p)
+		pb.finishConsumerSpan(span, err)
+
+		switch sub.options.HandleUnmarshalFailure(ctx, p.topic, m.Headers, cm.Payload(), err) {
+		case broker.UnmarshalActionAck:
+			if err = p.Ack(); err != nil {
+				sub.options.ReportError(ctx, broker.ErrAck, err, p)
+			}
+		case broker.UnmarshalActionRetry:
+			_ = p.Nack(func() error {
+				sub.reader.Nack(cm.Message)
+				return nil
+			})
+		case broker.UnmarshalActionStop:
+			// unsubscribe outside of the consume loop.
+			go func() { _ = sub.Unsubscribe(true) }()
 		}
-	}()
+		return
+	}
 
-	pb.subscribers.Add(topic, sub)
+	if err = sub.handler(ctx, p); err != nil {
+		p.err = err
+		log.Errorf("[pulsar]: handle message failed: %v", err)
+		pb.finishConsumerSpan(span, err)
+		return
+	}
 
-	return sub, nil
+	if sub.options.AutoAck {
+		if err = broker.AutoAck(p); err != nil {
+			p.err = err
+			log.Errorf("[pulsar]: unable to commit msg: %v", err)
+			sub.options.ReportError(ctx, broker.ErrAck, err, p)
+		}
+	}
+
+	pb.finishConsumerSpan(span, err)
 }
 
 func (pb *pulsarBroker) startProducerSpan(ctx context.Context, topic string, msg *pulsar.ProducerMessage) trace.Span {
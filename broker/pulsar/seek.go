@@ -0,0 +1,41 @@
+package pulsar
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var _ broker.Seeker = (*subscriber)(nil)
+
+// Seek reset the subscription to pos, for every consumer of it. A
+// PositionMessageID is a pulsar.MessageID serialized, e.g. of the
+// RawMessage of an event, offsets are not supported.
+func (s *subscriber) Seek(_ context.Context, pos broker.Position) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	return seekConsumer(s.reader, pos)
+}
+
+func seekConsumer(c pulsar.Consumer, pos broker.Position) error {
+	switch pos.Kind {
+	case broker.PositionBeginning:
+		return c.Seek(pulsar.EarliestMessageID())
+	case broker.PositionEnd:
+		return c.Seek(pulsar.LatestMessageID())
+	case broker.PositionTime:
+		return c.SeekByTime(pos.Time)
+	case broker.PositionMessageID:
+		id, err := pulsar.DeserializeMessageID(pos.MessageID)
+		if err != nil {
+			return err
+		}
+		return c.Seek(id)
+	default:
+		return fmt.Errorf("%w: %s", broker.ErrSeekUnsupported, pos)
+	}
+}
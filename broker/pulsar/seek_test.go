@@ -0,0 +1,44 @@
+package pulsar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type memoryConsumer struct {
+	pulsar.Consumer
+
+	id pulsar.MessageID
+	at time.Time
+}
+
+func (c *memoryConsumer) Seek(id pulsar.MessageID) error {
+	c.id = id
+	return nil
+}
+
+func (c *memoryConsumer) SeekByTime(at time.Time) error {
+	c.at = at
+	return nil
+}
+
+func TestSeekConsumer(t *testing.T) {
+	c := &memoryConsumer{}
+
+	assert.NoError(t, seekConsumer(c, broker.Beginning()))
+	assert.Equal(t, pulsar.EarliestMessageID().Serialize(), c.id.Serialize())
+
+	at := time.Now().Add(-time.Hour)
+	assert.NoError(t, seekConsumer(c, broker.AtTime(at)))
+	assert.Equal(t, at, c.at)
+
+	assert.NoError(t, seekConsumer(c, broker.AtMessageID(pulsar.LatestMessageID().Serialize())))
+	assert.Equal(t, pulsar.LatestMessageID().Serialize(), c.id.Serialize())
+
+	assert.ErrorIs(t, seekConsumer(c, broker.AtOffset(0, 1)), broker.ErrSeekUnsupported)
+}
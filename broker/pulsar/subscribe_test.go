@@ -0,0 +1,67 @@
+package pulsar
+
+import (
+	"testing"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+type testMessage struct {
+	pulsar.Message
+
+	topic      string
+	payload    []byte
+	properties map[string]string
+}
+
+func (m *testMessage) Topic() string                 { return m.topic }
+func (m *testMessage) Payload() []byte               { return m.payload }
+func (m *testMessage) Properties() map[string]string { return m.properties }
+
+// ackRecorder records how the messages are acked.
+type ackRecorder struct {
+	pulsar.Consumer
+
+	acked, nacked int
+}
+
+func (c *ackRecorder) Ack(pulsar.Message) error {
+	c.acked++
+	return nil
+}
+
+func (c *ackRecorder) Nack(pulsar.Message) {
+	c.nacked++
+}
+
+func (c *ackRecorder) Unsubscribe() error { return nil }
+
+func (c *ackRecorder) Close() {}
+
+// newTestSubscriber return a subscriber handling messages without a server.
+func newTestSubscriber(opts []broker.Option, topic string, reader pulsar.Consumer, handler broker.Handler, sopts ...broker.SubscribeOption) (*pulsarBroker, *subscriber) {
+	pb := NewBroker(opts...).(*pulsarBroker)
+
+	sub := &subscriber{
+		r:       pb,
+		topic:   topic,
+		options: broker.NewSubscribeOptions(sopts...),
+		handler: handler,
+		reader:  reader,
+		channel: make(chan pulsar.ConsumerMessage),
+	}
+
+	return pb, sub
+}
+
+func TestSubscribePath(t *testing.T) {
+	mocks.TestSubscribePath(t, func(opts []broker.Option, topic string, headers broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) {
+		pb, sub := newTestSubscriber(opts, topic, &ackRecorder{}, handler, sopts...)
+
+		msg := &testMessage{topic: topic, payload: body, properties: headers}
+		pb.handleMessage(sub, pulsar.ConsumerMessage{Message: msg}, binder)
+	}, true)
+}
@@ -4,7 +4,8 @@ import (
 	"regexp"
 )
 
-var re = regexp.MustCompile("^pulsar(\\+ssl)?://.*")
+// http(s) service urls use the HTTP lookup service.
+var re = regexp.MustCompile("^(pulsar(\\+ssl)?|https?)://.*")
 
 func hasUrlPrefix(url string) bool {
 	return re.MatchString(url)
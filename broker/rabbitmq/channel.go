@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/tx7do/kratos-transport/broker"
 )
 
 type rabbitChannel struct {
@@ -51,7 +52,32 @@ func (r *rabbitChannel) Publish(ctx context.Context, exchangeName, key string, m
 	return r.channel.PublishWithContext(ctx, exchangeName, key, false, false, message)
 }
 
-func (r *rabbitChannel) DeclareExchange(exchangeName, kind string, durable, autoDelete bool) error {
+// Confirm put the channel in confirm mode.
+func (r *rabbitChannel) Confirm() error {
+	return r.channel.Confirm(false)
+}
+
+// PublishConfirm publish and wait for the confirm of the server, the
+// channel must be in confirm mode.
+func (r *rabbitChannel) PublishConfirm(ctx context.Context, exchangeName, key string, message amqp.Publishing) error {
+	if r.channel == nil {
+		return errors.New("channel is nil")
+	}
+	confirm, err := r.channel.PublishWithDeferredConfirmWithContext(ctx, exchangeName, key, false, false, message)
+	if err != nil {
+		return err
+	}
+	ack, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !ack {
+		return broker.ErrPublishNacked
+	}
+	return nil
+}
+
+func (r *rabbitChannel) DeclareExchange(exchangeName, kind string, durable, autoDelete bool, args amqp.Table) error {
 	return r.channel.ExchangeDeclare(
 		exchangeName,
 		kind,
@@ -59,7 +85,7 @@ func (r *rabbitChannel) DeclareExchange(exchangeName, kind string, durable, auto
 		autoDelete,
 		false,
 		false,
-		nil,
+		args,
 	)
 }
 
@@ -75,6 +101,33 @@ func (r *rabbitChannel) DeclareQueue(queueName string, args amqp.Table, durable,
 	return err
 }
 
+// CheckExchange fail when the exchange does not exist, the server closes
+// the channel then.
+func (r *rabbitChannel) CheckExchange(exchangeName string) error {
+	return r.channel.ExchangeDeclarePassive(exchangeName, "", false, false, false, false, nil)
+}
+
+// CheckQueue fail when the queue does not exist, the server closes the
+// channel then.
+func (r *rabbitChannel) CheckQueue(queueName string) error {
+	_, err := r.channel.QueueDeclarePassive(queueName, false, false, false, false, nil)
+	return err
+}
+
+// QueueDepth return the number of ready messages of the queue.
+func (r *rabbitChannel) QueueDepth(queueName string) (int, error) {
+	q, err := r.channel.QueueDeclarePassive(queueName, false, false, false, false, nil)
+	if err != nil {
+		return 0, err
+	}
+	return q.Messages, nil
+}
+
+// PurgeQueue drop the ready messages of the queue.
+func (r *rabbitChannel) PurgeQueue(queueName string) (int, error) {
+	return r.channel.QueuePurge(queueName, false)
+}
+
 func (r *rabbitChannel) ConsumeQueue(queueName string, autoAck bool) (<-chan amqp.Delivery, error) {
 	return r.channel.Consume(
 		queueName,
@@ -0,0 +1,318 @@
+package rabbitmq
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed rabbitmq configuration of a broker, the fields are
+// the ones set one by one with the broker options of the package.
+type Config struct {
+	Exchange          Exchange
+	Qos               Qos
+	VirtualHost       string
+	ExternalAuth      bool
+	ConnectionManager *ConnectionManager
+	AlternateExchange AlternateExchange
+	PublisherConfirms bool
+}
+
+// DefaultConfig return the configuration of a broker without options.
+func DefaultConfig() Config {
+	return Config{
+		Exchange: DefaultExchange,
+		Qos:      DefaultQos,
+	}
+}
+
+// WithConfig set the whole configuration at once, c should start from
+// DefaultConfig since every field is applied. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		opts := []broker.Option{
+			broker.OptionContextWithValue(exchangeNameKey{}, c.Exchange.Name),
+			broker.OptionContextWithValue(exchangeKindKey{}, c.Exchange.Type),
+			broker.OptionContextWithValue(exchangeDurableKey{}, c.Exchange.Durable),
+			broker.OptionContextWithValue(prefetchCountKey{}, c.Qos.PrefetchCount),
+			broker.OptionContextWithValue(prefetchSizeKey{}, c.Qos.PrefetchSize),
+			broker.OptionContextWithValue(prefetchGlobalKey{}, c.Qos.PrefetchGlobal),
+		}
+		if c.Exchange.Arguments != nil {
+			opts = append(opts, WithExchangeArguments(c.Exchange.Arguments))
+		}
+		if c.VirtualHost != "" {
+			opts = append(opts, WithVirtualHost(c.VirtualHost))
+		}
+		if c.ExternalAuth {
+			opts = append(opts, WithExternalAuth())
+		}
+		if c.ConnectionManager != nil {
+			opts = append(opts, WithConnectionManager(c.ConnectionManager))
+		}
+		if c.AlternateExchange.Name != "" {
+			opts = append(opts, WithAlternateExchange(c.AlternateExchange.Name, c.AlternateExchange.Queue))
+		}
+		if c.PublisherConfirms {
+			opts = append(opts, WithPublisherConfirms())
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	c := DefaultConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if val, ok := opts.Context.Value(exchangeNameKey{}).(string); ok {
+		c.Exchange.Name = val
+	}
+	if val, ok := opts.Context.Value(exchangeKindKey{}).(string); ok {
+		c.Exchange.Type = val
+	}
+	if val, ok := opts.Context.Value(exchangeDurableKey{}).(bool); ok {
+		c.Exchange.Durable = val
+	}
+	if val, ok := opts.Context.Value(exchangeArgumentsKey{}).(map[string]interface{}); ok {
+		c.Exchange.Arguments = val
+	}
+
+	if val, ok := opts.Context.Value(prefetchCountKey{}).(int); ok {
+		c.Qos.PrefetchCount = val
+	}
+	if val, ok := opts.Context.Value(prefetchSizeKey{}).(int); ok {
+		c.Qos.PrefetchSize = val
+	}
+	if val, ok := opts.Context.Value(prefetchGlobalKey{}).(bool); ok {
+		c.Qos.PrefetchGlobal = val
+	}
+
+	if val, ok := opts.Context.Value(virtualHostKey{}).(string); ok {
+		c.VirtualHost = val
+	}
+	_, c.ExternalAuth = opts.Context.Value(externalAuthKey{}).(ExternalAuthentication)
+	if val, ok := opts.Context.Value(connectionManagerKey{}).(*ConnectionManager); ok {
+		c.ConnectionManager = val
+	}
+	if val, ok := opts.Context.Value(alternateExchangeKey{}).(AlternateExchange); ok {
+		c.AlternateExchange = val
+	}
+	c.PublisherConfirms, _ = opts.Context.Value(publisherConfirmsKey{}).(bool)
+
+	return c
+}
+
+// SubscribeConfig is the typed rabbitmq configuration of a subscription.
+type SubscribeConfig struct {
+	DurableQueue    bool
+	AutoDeleteQueue bool
+	BindArguments   map[string]interface{}
+	QueueArguments  map[string]interface{}
+	RequeueOnError  bool
+	AckOnSuccess    bool
+	VirtualHost     string
+	BindExchange    string
+	BindingKey      string
+}
+
+// DefaultSubscribeConfig return the configuration of a subscription without
+// options, the queue is durable.
+func DefaultSubscribeConfig() SubscribeConfig {
+	return SubscribeConfig{
+		DurableQueue: true,
+	}
+}
+
+// WithSubscribeConfig set the whole subscription configuration at once, an
+// auto delete queue is never durable.
+func WithSubscribeConfig(c SubscribeConfig) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		opts := []broker.SubscribeOption{
+			broker.SubscribeContextWithValue(durableQueueKey{}, c.DurableQueue),
+			broker.SubscribeContextWithValue(requeueOnErrorKey{}, c.RequeueOnError),
+			broker.SubscribeContextWithValue(ackSuccessKey{}, c.AckOnSuccess),
+		}
+		if c.AutoDeleteQueue {
+			opts = append(opts, WithAutoDeleteQueue())
+		}
+		if c.BindArguments != nil {
+			opts = append(opts, WithBindArguments(c.BindArguments))
+		}
+		if c.QueueArguments != nil {
+			opts = append(opts, WithQueueArguments(c.QueueArguments))
+		}
+		if c.VirtualHost != "" {
+			opts = append(opts, WithSubscribeVirtualHost(c.VirtualHost))
+		}
+		if c.BindExchange != "" {
+			opts = append(opts, WithBindExchange(c.BindExchange))
+		}
+		if c.BindingKey != "" {
+			opts = append(opts, WithBindingKey(c.BindingKey))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// SubscribeConfigFromOptions return the subscription configuration set in
+// the context of opts.
+func SubscribeConfigFromOptions(opts broker.SubscribeOptions) SubscribeConfig {
+	c := DefaultSubscribeConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if val, ok := opts.Context.Value(durableQueueKey{}).(bool); ok {
+		c.DurableQueue = val
+		c.AutoDeleteQueue = false
+	}
+	if val, ok := opts.Context.Value(autoDeleteQueueKey{}).(bool); ok {
+		c.AutoDeleteQueue = val
+		c.DurableQueue = false
+	}
+	if val, ok := opts.Context.Value(subscribeBindArgsKey{}).(map[string]interface{}); ok {
+		c.BindArguments = val
+	}
+	if val, ok := opts.Context.Value(subscribeQueueArgsKey{}).(map[string]interface{}); ok {
+		c.QueueArguments = val
+	}
+	if val, ok := opts.Context.Value(requeueOnErrorKey{}).(bool); ok {
+		c.RequeueOnError = val
+	}
+	if val, ok := opts.Context.Value(ackSuccessKey{}).(bool); ok {
+		c.AckOnSuccess = val
+	}
+	if val, ok := opts.Context.Value(subscribeVirtualHostKey{}).(string); ok {
+		c.VirtualHost = val
+	}
+	if val, ok := opts.Context.Value(bindExchangeKey{}).(string); ok {
+		c.BindExchange = val
+	}
+	if val, ok := opts.Context.Value(bindingKeyKey{}).(string); ok {
+		c.BindingKey = val
+	}
+
+	return c
+}
+
+// PublishConfig is the typed rabbitmq configuration of a publishing, the
+// zero fields are left unset.
+type PublishConfig struct {
+	DeliveryMode    uint8
+	Priority        uint8
+	ContentType     string
+	ContentEncoding string
+	CorrelationID   string
+	ReplyTo         string
+	Expiration      string
+	MessageID       string
+	Timestamp       time.Time
+	Type            string
+	UserID          string
+	AppID           string
+	Headers         map[string]interface{}
+	DeclareQueue    *DeclarePublishQueueInfo
+	VirtualHost     string
+
+	// Exchange replaces the broker exchange when not nil, see WithExchange.
+	Exchange   *string
+	RoutingKey string
+}
+
+// WithPublishConfig set the whole publishing configuration at once.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		var opts []broker.PublishOption
+		if c.DeliveryMode != 0 {
+			opts = append(opts, WithDeliveryMode(c.DeliveryMode))
+		}
+		if c.Priority != 0 {
+			opts = append(opts, WithPriority(c.Priority))
+		}
+		if c.ContentType != "" {
+			opts = append(opts, WithContentType(c.ContentType))
+		}
+		if c.ContentEncoding != "" {
+			opts = append(opts, WithContentEncoding(c.ContentEncoding))
+		}
+		if c.CorrelationID != "" {
+			opts = append(opts, WithCorrelationID(c.CorrelationID))
+		}
+		if c.ReplyTo != "" {
+			opts = append(opts, WithReplyTo(c.ReplyTo))
+		}
+		if c.Expiration != "" {
+			opts = append(opts, WithExpiration(c.Expiration))
+		}
+		if c.MessageID != "" {
+			opts = append(opts, WithMessageId(c.MessageID))
+		}
+		if !c.Timestamp.IsZero() {
+			opts = append(opts, WithTimestamp(c.Timestamp))
+		}
+		if c.Type != "" {
+			opts = append(opts, WithTypeMsg(c.Type))
+		}
+		if c.UserID != "" {
+			opts = append(opts, WithUserID(c.UserID))
+		}
+		if c.AppID != "" {
+			opts = append(opts, WithAppID(c.AppID))
+		}
+		if c.Headers != nil {
+			opts = append(opts, WithPublishHeaders(c.Headers))
+		}
+		if c.DeclareQueue != nil {
+			opts = append(opts, broker.PublishContextWithValue(publishDeclareQueueKey{}, c.DeclareQueue))
+		}
+		if c.VirtualHost != "" {
+			opts = append(opts, WithPublishVirtualHost(c.VirtualHost))
+		}
+		if c.Exchange != nil {
+			opts = append(opts, WithExchange(*c.Exchange))
+		}
+		if c.RoutingKey != "" {
+			opts = append(opts, WithRoutingKey(c.RoutingKey))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	var c PublishConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.DeliveryMode, _ = opts.Context.Value(deliveryModeKey{}).(uint8)
+	c.Priority, _ = opts.Context.Value(priorityKey{}).(uint8)
+	c.ContentType, _ = opts.Context.Value(contentTypeKey{}).(string)
+	c.ContentEncoding, _ = opts.Context.Value(contentEncodingKey{}).(string)
+	c.CorrelationID, _ = opts.Context.Value(correlationIDKey{}).(string)
+	c.ReplyTo, _ = opts.Context.Value(replyToKey{}).(string)
+	c.Expiration, _ = opts.Context.Value(expirationKey{}).(string)
+	c.MessageID, _ = opts.Context.Value(messageIDKey{}).(string)
+	c.Timestamp, _ = opts.Context.Value(timestampKey{}).(time.Time)
+	c.Type, _ = opts.Context.Value(messageTypeKey{}).(string)
+	c.UserID, _ = opts.Context.Value(userIDKey{}).(string)
+	c.AppID, _ = opts.Context.Value(appIDKey{}).(string)
+	c.Headers, _ = opts.Context.Value(publishHeadersKey{}).(map[string]interface{})
+	c.DeclareQueue, _ = opts.Context.Value(publishDeclareQueueKey{}).(*DeclarePublishQueueInfo)
+	c.VirtualHost, _ = opts.Context.Value(publishVirtualHostKey{}).(string)
+	if val, ok := opts.Context.Value(publishExchangeKey{}).(string); ok {
+		c.Exchange = &val
+	}
+	c.RoutingKey, _ = opts.Context.Value(publishRoutingKeyKey{}).(string)
+
+	return c
+}
@@ -0,0 +1,93 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultConfig(), ConfigFromOptions(broker.NewOptions()))
+
+	m := NewConnectionManager()
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithExchangeName("orders"),
+		WithExchangeType("direct"),
+		WithPrefetchCount(10),
+		WithVirtualHost("billing"),
+		WithExternalAuth(),
+		WithConnectionManager(m),
+		WithAlternateExchange("unroutable", ""),
+		WithPublisherConfirms(),
+	))
+
+	c := DefaultConfig()
+	c.Exchange.Name = "orders"
+	c.Exchange.Type = "direct"
+	c.Qos.PrefetchCount = 10
+	c.VirtualHost = "billing"
+	c.ExternalAuth = true
+	c.ConnectionManager = m
+	c.AlternateExchange = AlternateExchange{Name: "unroutable", Queue: "unroutable"}
+	c.PublisherConfirms = true
+
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c), WithExchangeName("payments")))
+	assert.Equal(t, "payments", overridden.Exchange.Name)
+}
+
+func TestSubscribeConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultSubscribeConfig(), SubscribeConfigFromOptions(broker.NewSubscribeOptions()))
+
+	legacy := SubscribeConfigFromOptions(broker.NewSubscribeOptions(
+		WithAutoDeleteQueue(),
+		WithRequeueOnError(),
+		WithAckOnSuccess(),
+		WithSubscribeVirtualHost("billing"),
+		WithBindExchange("audit"),
+	))
+
+	c := SubscribeConfig{
+		AutoDeleteQueue: true,
+		RequeueOnError:  true,
+		AckOnSuccess:    true,
+		VirtualHost:     "billing",
+		BindExchange:    "audit",
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c))))
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	now := time.Now()
+
+	legacy := PublishConfigFromOptions(broker.NewPublishOptions(
+		WithDeliveryMode(2),
+		WithContentType("application/json"),
+		WithTimestamp(now),
+		WithPublishHeaders(map[string]interface{}{"trace": "1"}),
+	))
+
+	c := PublishConfig{
+		DeliveryMode: 2,
+		ContentType:  "application/json",
+		Timestamp:    now,
+		Headers:      map[string]interface{}{"trace": "1"},
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+
+	// the default exchange is set explicitly.
+	routed := PublishConfigFromOptions(broker.NewPublishOptions(WithExchange(""), WithRoutingKey("reply.42")))
+	if assert.NotNil(t, routed.Exchange) {
+		assert.Equal(t, "", *routed.Exchange)
+	}
+	assert.Equal(t, "reply.42", routed.RoutingKey)
+	assert.Equal(t, routed, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(routed))))
+}
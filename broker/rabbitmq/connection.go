@@ -28,6 +28,15 @@ type Exchange struct {
 	Name    string
 	Type    string // "direct", "fanout", "topic", "headers"
 	Durable bool
+
+	// Arguments of the declaration, e.g. the "hash-header" of a consistent hash exchange.
+	Arguments map[string]interface{}
+}
+
+// AlternateExchange receives the messages the broker exchange cannot route.
+type AlternateExchange struct {
+	Name  string
+	Queue string
 }
 
 var (
@@ -61,13 +70,23 @@ type rabbitConnection struct {
 
 	options broker.Options
 
-	url      string
-	exchange Exchange
-	qos      Qos
+	url       string
+	exchange  Exchange
+	alternate AlternateExchange
+	qos       Qos
+	confirms  bool
+
+	// passive check the exchanges and queues instead of declaring them
+	passive bool
+	mode    broker.Mode
+
+	manager *ConnectionManager
 
 	connected      bool
 	close          chan bool
 	waitConnection chan struct{}
+
+	tracker broker.ConnectionTracker
 }
 
 func newRabbitMQConnection(opts broker.Options) *rabbitConnection {
@@ -92,35 +111,29 @@ func (r *rabbitConnection) init() {
 		r.url = r.options.Addrs[0]
 	}
 
-	if val, ok := r.options.Context.Value(exchangeNameKey{}).(string); ok {
-		r.exchange.Name = val
-	}
-	if val, ok := r.options.Context.Value(exchangeKindKey{}).(string); ok {
-		r.exchange.Type = val
-	}
-	if val, ok := r.options.Context.Value(exchangeDurableKey{}).(bool); ok {
-		r.exchange.Durable = val
-	}
+	c := ConfigFromOptions(r.options)
+	r.exchange = c.Exchange
+	r.alternate = c.AlternateExchange
+	r.qos = c.Qos
+	r.manager = c.ConnectionManager
+	r.confirms = c.PublisherConfirms
 
-	if val, ok := r.options.Context.Value(prefetchCountKey{}).(int); ok {
-		r.qos.PrefetchCount = val
-	}
-	if val, ok := r.options.Context.Value(prefetchSizeKey{}).(int); ok {
-		r.qos.PrefetchSize = val
-	}
-	if val, ok := r.options.Context.Value(prefetchGlobalKey{}).(bool); ok {
-		r.qos.PrefetchGlobal = val
-	}
+	_, r.passive = broker.VerifyOnStartFromContext(r.options.Context)
+	r.mode = broker.ModeFromContext(r.options.Context)
 }
 
 func (r *rabbitConnection) connect(secure bool, config *amqp.Config) error {
+	r.tracker.SetState(broker.ClientConnecting)
 	if err := r.tryConnect(secure, config); err != nil {
+		r.tracker.RecordError(err)
+		r.tracker.SetState(broker.ClientDisconnected)
 		return err
 	}
 
 	r.Lock()
 	r.connected = true
 	r.Unlock()
+	r.tracker.SetState(broker.ClientConnected)
 
 	go r.reconnect(secure, config)
 	return nil
@@ -131,14 +144,17 @@ func (r *rabbitConnection) reconnect(secure bool, config *amqp.Config) {
 
 	for {
 		if connect {
+			r.tracker.SetState(broker.ClientConnecting)
 			if err := r.tryConnect(secure, config); err != nil {
-				time.Sleep(1 * time.Second)
+				r.tracker.RecordError(err)
+				r.options.Clock.Sleep(1 * time.Second)
 				continue
 			}
 
 			r.Lock()
 			r.connected = true
 			r.Unlock()
+			r.tracker.SetState(broker.ClientConnected)
 			close(r.waitConnection)
 		}
 
@@ -169,25 +185,35 @@ func (r *rabbitConnection) reconnect(secure bool, config *amqp.Config) {
 			r.connected = false
 			r.waitConnection = make(chan struct{})
 			r.Unlock()
+			r.lost(err)
 		case err := <-notifyClose:
 			log.Error(err)
 
 			select {
 			case errs := <-chanNotifyClose:
 				log.Error(errs)
-			case <-time.After(time.Second):
+			case <-r.options.Clock.After(time.Second):
 			}
 
 			r.Lock()
 			r.connected = false
 			r.waitConnection = make(chan struct{})
 			r.Unlock()
+			r.lost(err)
 		case <-r.close:
 			return
 		}
 	}
 }
 
+// lost record the loss of the connection or of its exchange channel.
+func (r *rabbitConnection) lost(err *amqp.Error) {
+	if err != nil {
+		r.tracker.RecordError(err)
+	}
+	r.tracker.SetState(broker.ClientDisconnected)
+}
+
 func (r *rabbitConnection) Connect(secure bool, config *amqp.Config) error {
 	r.Lock()
 
@@ -218,14 +244,66 @@ func (r *rabbitConnection) Close() error {
 		close(r.close)
 		r.connected = false
 	}
+	r.tracker.SetState(broker.ClientClosed)
 
 	if r.Connection == nil {
 		return errors.New("connection is nil")
 	}
 
+	if r.manager != nil {
+		// the connection is shared, only close the channels of this broker.
+		if r.ExchangeChannel != nil {
+			_ = r.ExchangeChannel.Close()
+		}
+		if r.Channel != nil {
+			_ = r.Channel.Close()
+		}
+		return r.manager.release(r.Connection)
+	}
+
 	return r.Connection.Close()
 }
 
+func (r *rabbitConnection) isConnected() bool {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.connected
+}
+
+// channels count the broker channels open on the connection.
+func (r *rabbitConnection) channels() int {
+	r.Lock()
+	defer r.Unlock()
+
+	if !r.connected {
+		return 0
+	}
+	n := 0
+	if r.Channel != nil {
+		n++
+	}
+	if r.ExchangeChannel != nil {
+		n++
+	}
+	return n
+}
+
+func (r *rabbitConnection) closed() <-chan bool {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.close
+}
+
+// connectionReady returns a channel closed once the connection is (re)established.
+func (r *rabbitConnection) connectionReady() <-chan struct{} {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.waitConnection
+}
+
 func (r *rabbitConnection) tryConnect(secure bool, config *amqp.Config) error {
 	if config == nil {
 		config = &DefaultAmqpConfig
@@ -244,7 +322,14 @@ func (r *rabbitConnection) tryConnect(secure bool, config *amqp.Config) error {
 	}
 
 	var err error
-	r.Connection, err = amqp.DialConfig(url, *config)
+	if r.manager != nil {
+		if r.Connection != nil {
+			_ = r.manager.release(r.Connection)
+		}
+		r.Connection, err = r.manager.acquire(url, *config)
+	} else {
+		r.Connection, err = amqp.DialConfig(url, *config)
+	}
 	if err != nil {
 		return err
 	}
@@ -253,22 +338,56 @@ func (r *rabbitConnection) tryConnect(secure bool, config *amqp.Config) error {
 		return err
 	}
 
-	_ = r.Channel.DeclareExchange(r.exchange.Name, r.exchange.Type, r.exchange.Durable, false)
+	if !r.passive {
+		if r.alternate.Name == "" {
+			_ = r.Channel.DeclareExchange(r.exchange.Name, r.exchange.Type, r.exchange.Durable, false, r.exchange.Arguments)
+		} else if err = r.declareAlternate(); err != nil {
+			return err
+		}
+	}
 
-	if !EnableLazyInitPublishChannel {
-		r.ExchangeChannel, err = newRabbitChannel(r.Connection, r.qos)
+	if !EnableLazyInitPublishChannel && r.mode != broker.ModeConsumeOnly {
+		r.ExchangeChannel, err = r.newPublishChannel()
 	}
 
 	return err
 }
 
-func (r *rabbitConnection) Consume(queueName, routingKey string, bindArgs amqp.Table, qArgs amqp.Table, autoAck, durableQueue, autoDel bool) (*rabbitChannel, <-chan amqp.Delivery, error) {
+// declareAlternate declare the alternate exchange with its capture queue,
+// then the broker exchange pointing to it. The server refuses to change the
+// arguments of an existing exchange, set the alternate exchange of those
+// with a policy instead.
+func (r *rabbitConnection) declareAlternate() error {
+	if err := r.Channel.DeclareExchange(r.alternate.Name, "fanout", true, false, nil); err != nil {
+		return err
+	}
+	if err := r.Channel.DeclareQueue(r.alternate.Queue, nil, true, false); err != nil {
+		return err
+	}
+	if err := r.Channel.BindQueue(r.alternate.Queue, "", r.alternate.Name, nil); err != nil {
+		return err
+	}
+	args := amqp.Table{"alternate-exchange": r.alternate.Name}
+	for k, v := range r.exchange.Arguments {
+		args[k] = v
+	}
+	return r.Channel.DeclareExchange(r.exchange.Name, r.exchange.Type, r.exchange.Durable, false, args)
+}
+
+// Consume declare the queue, bound to exchange or the broker one when
+// empty, and consume it.
+func (r *rabbitConnection) Consume(queueName, routingKey, exchange string, bindArgs amqp.Table, qArgs amqp.Table, autoAck, durableQueue, autoDel bool) (*rabbitChannel, <-chan amqp.Delivery, error) {
 	consumerChannel, err := newRabbitChannel(r.Connection, r.qos)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	if err = consumerChannel.DeclareQueue(queueName, qArgs, durableQueue, autoDel); err != nil {
+	if r.passive {
+		err = consumerChannel.CheckQueue(queueName)
+	} else {
+		err = consumerChannel.DeclareQueue(queueName, qArgs, durableQueue, autoDel)
+	}
+	if err != nil {
 		return nil, nil, err
 	}
 
@@ -277,42 +396,110 @@ func (r *rabbitConnection) Consume(queueName, routingKey string, bindArgs amqp.T
 		return nil, nil, err
 	}
 
-	if err = consumerChannel.BindQueue(queueName, routingKey, r.exchange.Name, bindArgs); err != nil {
+	if exchange == "" {
+		exchange = r.exchange.Name
+	}
+	if err = consumerChannel.BindQueue(queueName, routingKey, exchange, bindArgs); err != nil {
 		return nil, nil, err
 	}
 
 	return consumerChannel, deliveries, nil
 }
 
+// newPublishChannel open the publish channel, in confirm mode with
+// WithPublisherConfirms.
+func (r *rabbitConnection) newPublishChannel() (*rabbitChannel, error) {
+	ch, err := newRabbitChannel(r.Connection, r.qos)
+	if err != nil {
+		return nil, err
+	}
+	if r.confirms {
+		if err = ch.Confirm(); err != nil {
+			_ = ch.Close()
+			return nil, err
+		}
+	}
+	return ch, nil
+}
+
 func (r *rabbitConnection) DeclarePublishQueue(queueName, routingKey string, bindArgs amqp.Table, queueArgs amqp.Table, durableQueue, autoDel bool) error {
 	if r.ExchangeChannel == nil {
 		var err error
-		r.ExchangeChannel, err = newRabbitChannel(r.Connection, r.qos)
+		r.ExchangeChannel, err = r.newPublishChannel()
 		if err != nil {
 			return err
 		}
 	}
 
-	if err := r.ExchangeChannel.DeclareQueue(queueName, queueArgs, durableQueue, autoDel); err != nil {
+	var err error
+	if r.passive {
+		err = r.ExchangeChannel.CheckQueue(queueName)
+	} else {
+		err = r.ExchangeChannel.DeclareQueue(queueName, queueArgs, durableQueue, autoDel)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := r.ExchangeChannel.BindQueue(queueName, routingKey, r.exchange.Name, bindArgs); err != nil {
+	if err = r.ExchangeChannel.BindQueue(queueName, routingKey, r.exchange.Name, bindArgs); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// VerifyTopology check the exchanges and queues of t exist, each on its own
+// channel since a failed check closes the channel. The broker exchange is
+// checked too, it is not declared in passive mode.
+func (r *rabbitConnection) VerifyTopology(ctx context.Context, t broker.Topology) error {
+	if r.exchange.Name != "" {
+		t.Exchanges = append([]string{r.exchange.Name}, t.Exchanges...)
+	}
+
+	checker := func(check func(ch *rabbitChannel, name string) error) broker.TopologyChecker {
+		return func(_ context.Context, name string) error {
+			ch, err := newRabbitChannel(r.Connection, r.qos)
+			if err != nil {
+				return err
+			}
+			if err = check(ch, name); err != nil {
+				return err
+			}
+			return ch.Close()
+		}
+	}
+
+	return broker.VerifyTopology(ctx, "rabbitmq", t, map[string]broker.TopologyChecker{
+		broker.ResourceExchange: checker((*rabbitChannel).CheckExchange),
+		broker.ResourceQueue:    checker((*rabbitChannel).CheckQueue),
+	})
+}
+
+// withChannel run fn on a fresh channel, closed afterward.
+func (r *rabbitConnection) withChannel(fn func(ch *rabbitChannel) (int, error)) (int, error) {
+	ch, err := newRabbitChannel(r.Connection, r.qos)
+	if err != nil {
+		return 0, err
+	}
+	n, err := fn(ch)
+	if err != nil {
+		return 0, err
+	}
+	return n, ch.Close()
+}
+
 func (r *rabbitConnection) Publish(ctx context.Context, exchangeName, routingKey string, msg amqp.Publishing) error {
 	if r.ExchangeChannel == nil {
 		var err error
 		// lazy init publish channel
-		r.ExchangeChannel, err = newRabbitChannel(r.Connection, r.qos)
+		r.ExchangeChannel, err = r.newPublishChannel()
 		if err != nil {
 			return err
 		}
 	}
 
+	if r.confirms {
+		return r.ExchangeChannel.PublishConfirm(ctx, exchangeName, routingKey, msg)
+	}
 	return r.ExchangeChannel.Publish(ctx, exchangeName, routingKey, msg)
 }
@@ -1,6 +1,7 @@
 package rabbitmq
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -81,3 +82,52 @@ func TestHasRabbitUrlPrefix(t *testing.T) {
 	assert.False(t, hasUrlPrefix("https://example.com"))
 	assert.False(t, hasUrlPrefix("example.com"))
 }
+
+func TestVirtualHostConfig(t *testing.T) {
+	b := NewBroker(broker.WithAddress("amqp://example.com/ignored"), WithVirtualHost("orders")).(*rabbitBroker)
+	_ = b.Init()
+
+	assert.Equal(t, "orders", b.vhost)
+	assert.Equal(t, "orders", b.amqpConfig(b.vhost).Vhost)
+	assert.Equal(t, "billing", b.amqpConfig("billing").Vhost)
+	assert.Equal(t, "", b.amqpConfig("").Vhost)
+
+	_, err := b.connection("")
+	assert.NotNil(t, err)
+}
+
+func TestBrokerMode(t *testing.T) {
+	b := NewBroker(broker.WithMode(broker.ModeConsumeOnly)).(*rabbitBroker)
+	_ = b.Init()
+
+	assert.ErrorIs(t, b.Publish(context.Background(), "orders", "msg"), broker.ErrPublishDisabled)
+	assert.Equal(t, broker.ModeConsumeOnly, newRabbitMQConnection(b.options).mode)
+
+	b = NewBroker(broker.WithMode(broker.ModePublishOnly)).(*rabbitBroker)
+	_ = b.Init()
+
+	_, err := b.Subscribe("orders", nil, nil)
+	assert.ErrorIs(t, err, broker.ErrSubscribeDisabled)
+}
+
+func TestValidate(t *testing.T) {
+	b := NewBroker(WithExchangeType("fanin"), WithPrefetchCount(-1), WithExternalAuth()).(*rabbitBroker)
+
+	err := b.Init()
+	assert.ErrorIs(t, err, broker.ErrInvalidConfig)
+
+	var configErr *broker.ConfigError
+	assert.ErrorAs(t, err, &configErr)
+	assert.Len(t, configErr.Problems, 3)
+
+	b = NewBroker(WithExchangeType("x-delayed-message"), WithPrefetchCount(10)).(*rabbitBroker)
+	assert.Nil(t, b.Init())
+
+	b.conn = newRabbitMQConnection(b.options)
+
+	options := broker.NewSubscribeOptions()
+	assert.ErrorIs(t, b.validateSubscribe("orders", &options, true), broker.ErrInvalidConfig)
+
+	options = broker.NewSubscribeOptions(broker.DisableAutoAck())
+	assert.Nil(t, b.validateSubscribe("orders", &options, true))
+}
@@ -7,4 +7,11 @@ const (
 	defaultMaxResubscribeDelay = 30 * time.Second
 	defaultExpFactor           = time.Duration(2)
 	defaultResubscribeDelay    = defaultMinResubscribeDelay
+
+	// defaultMinConsumeUptime is how long a delivery channel must stay open,
+	// without any delivery, for the resubscribe backoff to be reset.
+	defaultMinConsumeUptime = 5 * time.Second
+
+	// defaultMaxMessageSize is the default max_message_size of the server.
+	defaultMaxMessageSize = 128 * 1024 * 1024
 )
@@ -0,0 +1,118 @@
+package rabbitmq
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+const (
+	// ExchangeTypeConsistentHash is the exchange of the
+	// rabbitmq_consistent_hash_exchange plugin, routing each message to one
+	// bound queue in proportion of the weight of the bindings.
+	ExchangeTypeConsistentHash = "x-consistent-hash"
+
+	// ExchangeTypeModulusHash is the exchange of the rabbitmq_sharding
+	// plugin, routing each message to the bound queue of index
+	// hash(routing key) % queues.
+	ExchangeTypeModulusHash = "x-modulus-hash"
+)
+
+type ConsistentHashOption func(args map[string]interface{})
+
+// HashOnHeader hash the value of header instead of the routing key.
+func HashOnHeader(header string) ConsistentHashOption {
+	return func(args map[string]interface{}) {
+		args["hash-header"] = header
+	}
+}
+
+// HashOnProperty hash the message property instead of the routing key, one
+// of "message_id", "correlation_id" and "timestamp".
+func HashOnProperty(property string) ConsistentHashOption {
+	return func(args map[string]interface{}) {
+		args["hash-property"] = property
+	}
+}
+
+// WithConsistentHashExchange declare the broker exchange as a durable
+// consistent hash exchange, the messages of a routing key always go to
+// the same queue as long as the bindings are the same. Bind the queues
+// with WithHashWeight or DeclareHashQueues.
+func WithConsistentHashExchange(name string, opts ...ConsistentHashOption) broker.Option {
+	args := map[string]interface{}{}
+	for _, o := range opts {
+		o(args)
+	}
+
+	return func(o *broker.Options) {
+		o.Apply(
+			WithExchangeName(name),
+			WithExchangeType(ExchangeTypeConsistentHash),
+			WithDurableExchange(),
+		)
+		if len(args) > 0 {
+			o.Apply(WithExchangeArguments(args))
+		}
+	}
+}
+
+// WithModulusHashExchange declare the broker exchange as a durable modulus
+// hash exchange. Adding or removing a queue moves most of the keys, use a
+// consistent hash exchange when the queues change.
+func WithModulusHashExchange(name string) broker.Option {
+	return func(o *broker.Options) {
+		o.Apply(
+			WithExchangeName(name),
+			WithExchangeType(ExchangeTypeModulusHash),
+			WithDurableExchange(),
+		)
+	}
+}
+
+// WithHashWeight bind the queue to a consistent hash exchange with weight,
+// the share of the keys it receives is weight over the sum of the weights.
+func WithHashWeight(weight int) broker.SubscribeOption {
+	return WithBindingKey(strconv.Itoa(hashWeight(weight)))
+}
+
+func hashWeight(weight int) int {
+	if weight < 1 {
+		return 1
+	}
+	return weight
+}
+
+// HashQueue is a queue bound to a hash exchange, Weight is ignored by the
+// modulus hash exchanges.
+type HashQueue struct {
+	Name      string
+	Weight    int
+	Arguments map[string]interface{}
+}
+
+// DeclareHashQueues declare the durable queues bound to the broker exchange,
+// a consistent or modulus hash exchange, so that no message is dropped
+// before the consumers start. Consume a queue with
+//
+//	b.Subscribe(topic, handler, binder, broker.WithQueueName(q.Name), rabbitmq.WithHashWeight(q.Weight))
+func DeclareHashQueues(_ context.Context, b broker.Broker, queues ...HashQueue) error {
+	rb, ok := b.(*rabbitBroker)
+	if !ok {
+		return ErrNotRabbitBroker
+	}
+
+	conn, err := rb.connection("")
+	if err != nil {
+		return err
+	}
+
+	for _, q := range queues {
+		key := strconv.Itoa(hashWeight(q.Weight))
+		if err = conn.DeclarePublishQueue(q.Name, key, nil, q.Arguments, true, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestWithConsistentHashExchange(t *testing.T) {
+	c := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithConsistentHashExchange("orders", HashOnHeader("customer")),
+	))
+
+	assert.Equal(t, Exchange{
+		Name:      "orders",
+		Type:      ExchangeTypeConsistentHash,
+		Durable:   true,
+		Arguments: map[string]interface{}{"hash-header": "customer"},
+	}, c.Exchange)
+
+	c = ConfigFromOptions(broker.NewOptionsAndApply(WithModulusHashExchange("orders")))
+	assert.Equal(t, Exchange{Name: "orders", Type: ExchangeTypeModulusHash, Durable: true}, c.Exchange)
+
+	b := NewBroker(WithConsistentHashExchange("orders"))
+	assert.Nil(t, b.Init())
+}
+
+func TestWithHashWeight(t *testing.T) {
+	c := SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithHashWeight(3)))
+	assert.Equal(t, "3", c.BindingKey)
+
+	c = SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithHashWeight(0)))
+	assert.Equal(t, "1", c.BindingKey)
+}
+
+func TestDeclareHashQueues(t *testing.T) {
+	err := DeclareHashQueues(context.Background(), &shardBroker{}, HashQueue{Name: "orders.a", Weight: 1})
+	assert.ErrorIs(t, err, ErrNotRabbitBroker)
+}
@@ -0,0 +1,75 @@
+package rabbitmq
+
+import (
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// HeaderMatch is the x-match argument of a binding to a headers exchange.
+type HeaderMatch string
+
+const (
+	// HeaderMatchAll matches the messages with every header of the binding.
+	HeaderMatchAll HeaderMatch = "all"
+	// HeaderMatchAny matches the messages with at least one header of the binding.
+	HeaderMatchAny HeaderMatch = "any"
+	// HeaderMatchAllWithX is HeaderMatchAll comparing the headers starting
+	// with "x-" too, RabbitMQ 3.10 and later.
+	HeaderMatchAllWithX HeaderMatch = "all-with-x"
+	// HeaderMatchAnyWithX is HeaderMatchAny comparing the headers starting
+	// with "x-" too, RabbitMQ 3.10 and later.
+	HeaderMatchAnyWithX HeaderMatch = "any-with-x"
+)
+
+// WithHeadersExchange declare the broker exchange as a durable headers
+// exchange, routing on the headers of the messages, the routing keys are
+// ignored. Bind the queues with WithHeaderBinding.
+func WithHeadersExchange(name string) broker.Option {
+	return func(o *broker.Options) {
+		o.Apply(
+			WithExchangeName(name),
+			WithExchangeType("headers"),
+			WithDurableExchange(),
+		)
+	}
+}
+
+// WithHeaderBinding bind the queue to the messages whose headers match
+// headers, all or any of them. It is merged with the WithBindArguments set
+// before it.
+func WithHeaderBinding(match HeaderMatch, headers map[string]interface{}) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		args := map[string]interface{}{}
+		if o.Context != nil {
+			if prev, ok := o.Context.Value(subscribeBindArgsKey{}).(map[string]interface{}); ok {
+				for k, v := range prev {
+					args[k] = v
+				}
+			}
+		}
+		for k, v := range headers {
+			args[k] = v
+		}
+		args["x-match"] = string(match)
+
+		WithBindArguments(args)(o)
+	}
+}
+
+// WithPublishHeader add the header key to the message, e.g. to be routed by
+// a headers exchange. Unlike WithPublishHeaders it keeps the headers set
+// before it.
+func WithPublishHeader(key string, value interface{}) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		headers := map[string]interface{}{}
+		if o.Context != nil {
+			if prev, ok := o.Context.Value(publishHeadersKey{}).(map[string]interface{}); ok {
+				for k, v := range prev {
+					headers[k] = v
+				}
+			}
+		}
+		headers[key] = value
+
+		WithPublishHeaders(headers)(o)
+	}
+}
@@ -0,0 +1,37 @@
+package rabbitmq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestWithHeaderBinding(t *testing.T) {
+	c := SubscribeConfigFromOptions(broker.NewSubscribeOptions(
+		WithBindArguments(map[string]interface{}{"x-priority": 1}),
+		WithHeaderBinding(HeaderMatchAny, map[string]interface{}{"region": "eu", "tier": "gold"}),
+	))
+
+	assert.Equal(t, map[string]interface{}{
+		"x-priority": 1,
+		"x-match":    "any",
+		"region":     "eu",
+		"tier":       "gold",
+	}, c.BindArguments)
+}
+
+func TestWithPublishHeader(t *testing.T) {
+	c := PublishConfigFromOptions(broker.NewPublishOptions(
+		WithPublishHeaders(map[string]interface{}{"region": "eu"}),
+		WithPublishHeader("tier", "gold"),
+	))
+
+	assert.Equal(t, map[string]interface{}{"region": "eu", "tier": "gold"}, c.Headers)
+}
+
+func TestWithHeadersExchange(t *testing.T) {
+	c := ConfigFromOptions(broker.NewOptionsAndApply(WithHeadersExchange("events")))
+	assert.Equal(t, Exchange{Name: "events", Type: "headers", Durable: true}, c.Exchange)
+}
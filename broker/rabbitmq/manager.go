@@ -0,0 +1,86 @@
+package rabbitmq
+
+import (
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type sharedConnection struct {
+	conn *amqp.Connection
+	refs int
+}
+
+// ConnectionManager shares one TCP connection between the brokers connecting
+// to the same url and vhost, every broker still uses its own channels.
+type ConnectionManager struct {
+	mtx   sync.Mutex
+	conns map[string]*sharedConnection
+
+	dial func(url string, config amqp.Config) (*amqp.Connection, error)
+}
+
+func NewConnectionManager() *ConnectionManager {
+	return &ConnectionManager{
+		conns: make(map[string]*sharedConnection),
+		dial:  amqp.DialConfig,
+	}
+}
+
+func connectionKey(url string, config amqp.Config) string {
+	return url + "|" + config.Vhost
+}
+
+// acquire return the open connection of url and vhost, dialing it when there
+// is none or the previous one is closed.
+func (m *ConnectionManager) acquire(url string, config amqp.Config) (*amqp.Connection, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	key := connectionKey(url, config)
+	if shared, ok := m.conns[key]; ok && !shared.conn.IsClosed() {
+		shared.refs++
+		return shared.conn, nil
+	}
+
+	conn, err := m.dial(url, config)
+	if err != nil {
+		return nil, err
+	}
+	m.conns[key] = &sharedConnection{conn: conn, refs: 1}
+
+	return conn, nil
+}
+
+// release close conn once no broker uses it anymore, a connection already
+// replaced after a reconnection is ignored.
+func (m *ConnectionManager) release(conn *amqp.Connection) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	for key, shared := range m.conns {
+		if shared.conn != conn {
+			continue
+		}
+
+		shared.refs--
+		if shared.refs > 0 {
+			return nil
+		}
+		delete(m.conns, key)
+		if conn.IsClosed() {
+			return nil
+		}
+		return conn.Close()
+	}
+
+	return nil
+}
+
+// Count return the number of connections currently shared.
+func (m *ConnectionManager) Count() int {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return len(m.conns)
+}
@@ -0,0 +1,47 @@
+package rabbitmq
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConnectionManager(t *testing.T) {
+	m := NewConnectionManager()
+
+	dialed := 0
+	m.dial = func(string, amqp.Config) (*amqp.Connection, error) {
+		dialed++
+		return &amqp.Connection{}, nil
+	}
+
+	orders, err := m.acquire("amqp://127.0.0.1:5672", amqp.Config{Vhost: "orders"})
+	assert.Nil(t, err)
+	billing, err := m.acquire("amqp://127.0.0.1:5672", amqp.Config{Vhost: "orders"})
+	assert.Nil(t, err)
+	assert.Same(t, orders, billing)
+	assert.Equal(t, 1, dialed)
+
+	other, err := m.acquire("amqp://127.0.0.1:5672", amqp.Config{Vhost: "billing"})
+	assert.Nil(t, err)
+	assert.NotSame(t, orders, other)
+	assert.Equal(t, 2, dialed)
+	assert.Equal(t, 2, m.Count())
+
+	// still used by the second broker.
+	assert.Nil(t, m.release(orders))
+	assert.Equal(t, 2, m.Count())
+
+	// unknown connections are ignored.
+	assert.Nil(t, m.release(&amqp.Connection{}))
+	assert.Equal(t, 2, m.Count())
+}
+
+func TestConnectionManagerOption(t *testing.T) {
+	m := NewConnectionManager()
+
+	conn := newRabbitMQConnection(broker.NewOptionsAndApply(WithConnectionManager(m)))
+	assert.Same(t, m, conn.manager)
+}
@@ -19,6 +19,11 @@ type prefetchCountKey struct{}
 type prefetchSizeKey struct{}
 type prefetchGlobalKey struct{}
 type externalAuthKey struct{}
+type virtualHostKey struct{}
+type connectionManagerKey struct{}
+type alternateExchangeKey struct{}
+type exchangeArgumentsKey struct{}
+type publisherConfirmsKey struct{}
 
 // WithDurableExchange Exchange.Durable
 func WithDurableExchange() broker.Option {
@@ -54,6 +59,41 @@ func WithExternalAuth() broker.Option {
 	return broker.OptionContextWithValue(externalAuthKey{}, ExternalAuthentication{})
 }
 
+// WithVirtualHost amqp.Config.Vhost, overrides the vhost of the url.
+func WithVirtualHost(vhost string) broker.Option {
+	return broker.OptionContextWithValue(virtualHostKey{}, vhost)
+}
+
+// WithConnectionManager share the TCP connection with the other brokers of
+// the manager connecting to the same url and vhost.
+func WithConnectionManager(m *ConnectionManager) broker.Option {
+	return broker.OptionContextWithValue(connectionManagerKey{}, m)
+}
+
+// WithAlternateExchange declare the broker exchange with name as alternate
+// exchange, a fanout exchange into the durable queue, which captures the
+// unroutable messages instead of dropping them. queue defaults to name.
+// Consume them with SubscribeUnroutable.
+func WithAlternateExchange(name, queue string) broker.Option {
+	if queue == "" {
+		queue = name
+	}
+	return broker.OptionContextWithValue(alternateExchangeKey{}, AlternateExchange{Name: name, Queue: queue})
+}
+
+// WithExchangeArguments Exchange.Arguments, the arguments the broker
+// exchange is declared with.
+func WithExchangeArguments(args map[string]interface{}) broker.Option {
+	return broker.OptionContextWithValue(exchangeArgumentsKey{}, args)
+}
+
+// WithPublisherConfirms put the publish channel in confirm mode, Publish
+// then waits for the confirm of the server and fails with
+// broker.ErrPublishNacked when it is a nack.
+func WithPublisherConfirms() broker.Option {
+	return broker.OptionContextWithValue(publisherConfirmsKey{}, true)
+}
+
 ///
 /// SubscribeOption
 ///
@@ -65,6 +105,9 @@ type requeueOnErrorKey struct{}
 type subscribeContextKey struct{}
 type ackSuccessKey struct{}
 type autoDeleteQueueKey struct{}
+type subscribeVirtualHostKey struct{}
+type bindExchangeKey struct{}
+type bindingKeyKey struct{}
 
 func WithDurableQueue() broker.SubscribeOption {
 	return broker.SubscribeContextWithValue(durableQueueKey{}, true)
@@ -94,6 +137,22 @@ func WithAckOnSuccess() broker.SubscribeOption {
 	return broker.SubscribeContextWithValue(ackSuccessKey{}, true)
 }
 
+// WithSubscribeVirtualHost consume from another vhost over its own connection.
+func WithSubscribeVirtualHost(vhost string) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(subscribeVirtualHostKey{}, vhost)
+}
+
+// WithBindExchange bind the queue to exchange instead of the broker one.
+func WithBindExchange(exchange string) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(bindExchangeKey{}, exchange)
+}
+
+// WithBindingKey bind the queue with key instead of the subscribed topic,
+// the events keep the routing key of their message as topic.
+func WithBindingKey(key string) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(bindingKeyKey{}, key)
+}
+
 ///
 /// PublishOption
 ///
@@ -120,6 +179,9 @@ type userIDKey struct{}
 type appIDKey struct{}
 type publishHeadersKey struct{}
 type publishDeclareQueueKey struct{}
+type publishVirtualHostKey struct{}
+type publishExchangeKey struct{}
+type publishRoutingKeyKey struct{}
 
 // WithDeliveryMode amqp.Publishing.DeliveryMode
 func WithDeliveryMode(value uint8) broker.PublishOption {
@@ -197,3 +259,20 @@ func WithPublishDeclareQueue(queueName string, durableQueue, autoDelete bool, qu
 	}
 	return broker.PublishContextWithValue(publishDeclareQueueKey{}, val)
 }
+
+// WithExchange publish to exchange instead of the broker one, "" being the
+// default exchange which routes to the queue named by the routing key.
+func WithExchange(exchange string) broker.PublishOption {
+	return broker.PublishContextWithValue(publishExchangeKey{}, exchange)
+}
+
+// WithRoutingKey publish with key instead of the topic, which is still
+// the topic of the metrics and of the interceptors.
+func WithRoutingKey(key string) broker.PublishOption {
+	return broker.PublishContextWithValue(publishRoutingKeyKey{}, key)
+}
+
+// WithPublishVirtualHost publish to another vhost over its own connection.
+func WithPublishVirtualHost(vhost string) broker.PublishOption {
+	return broker.PublishContextWithValue(publishVirtualHostKey{}, vhost)
+}
@@ -6,6 +6,8 @@ import (
 )
 
 type publication struct {
+	broker.AckState
+
 	d       amqp.Delivery
 	message *broker.Message
 	topic   string
@@ -13,7 +15,9 @@ type publication struct {
 }
 
 func (p *publication) Ack() error {
-	return p.d.Ack(false)
+	return p.AckState.Ack(func() error {
+		return p.d.Ack(false)
+	})
 }
 
 func (p *publication) Error() error {
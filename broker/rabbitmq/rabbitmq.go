@@ -3,6 +3,7 @@ package rabbitmq
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/tx7do/kratos-transport/broker"
 	"github.com/tx7do/kratos-transport/tracing"
+	"golang.org/x/sync/errgroup"
 
 	"go.opentelemetry.io/otel/attribute"
 	semConv "go.opentelemetry.io/otel/semconv/v1.12.0"
@@ -18,23 +20,35 @@ import (
 
 type rabbitBroker struct {
 	mtx sync.Mutex
-	wg  sync.WaitGroup
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	group  *errgroup.Group
 
 	conn    *rabbitConnection
 	options broker.Options
 
+	mode broker.Mode
+
+	vhost      string
+	vhostMtx   sync.Mutex
+	vhostConns map[string]*rabbitConnection
+
 	subscribers *broker.SubscriberSyncMap
 
 	producerTracer *tracing.Tracer
 	consumerTracer *tracing.Tracer
+
+	metrics *broker.Metrics
 }
 
 func NewBroker(opts ...broker.Option) broker.Broker {
-	options := broker.NewOptionsAndApply(opts...)
+	options := broker.NewOptionsAndApply(append([]broker.Option{broker.WithMaxMessageSize(defaultMaxMessageSize)}, opts...)...)
 
 	b := &rabbitBroker{
 		options:     options,
 		subscribers: broker.NewSubscriberSyncMap(),
+		vhostConns:  make(map[string]*rabbitConnection),
 	}
 
 	return b
@@ -73,12 +87,53 @@ func (b *rabbitBroker) Init(opts ...broker.Option) error {
 	}
 	b.options.Addrs = addrs
 
+	b.vhost = ConfigFromOptions(b.options).VirtualHost
+
+	b.mode = broker.ModeFromContext(b.options.Context)
+
 	if len(b.options.Tracings) > 0 {
 		b.producerTracer = tracing.NewTracer(trace.SpanKindProducer, "rabbitmq-producer", b.options.Tracings...)
 		b.consumerTracer = tracing.NewTracer(trace.SpanKindConsumer, "rabbitmq-consumer", b.options.Tracings...)
 	}
 
-	return nil
+	b.metrics = broker.NewMetrics("rabbitmq", b.options.MeterProvider)
+
+	return b.Validate()
+}
+
+// Validate check the options set at Init.
+func (b *rabbitBroker) Validate() error {
+	v := broker.NewConfigValidator(b.Name())
+	c := ConfigFromOptions(b.options)
+
+	switch c.Exchange.Type {
+	case "direct", "fanout", "topic", "headers":
+	default:
+		v.Require(strings.HasPrefix(c.Exchange.Type, "x-"), "unknown exchange type %q", c.Exchange.Type)
+	}
+	v.Require(c.Qos.PrefetchCount >= 0, "prefetch count %d must not be negative", c.Qos.PrefetchCount)
+	v.Require(c.Qos.PrefetchSize >= 0, "prefetch size %d must not be negative", c.Qos.PrefetchSize)
+	if c.ExternalAuth {
+		v.Require(b.options.TLSConfig != nil, "external authentication requires a tls config")
+	}
+
+	return v.Err()
+}
+
+// validateSubscribe check the subscribe options against the broker ones, the
+// server ignores the prefetch and no message is requeued with auto ack.
+func (b *rabbitBroker) validateSubscribe(routingKey string, options *broker.SubscribeOptions, requeueOnError bool) error {
+	v := broker.NewConfigValidator(b.Name())
+
+	if options.AutoAck {
+		qos := b.conn.qos
+		v.Require(qos.PrefetchCount == 0 && qos.PrefetchSize == 0,
+			"prefetch has no effect on %s with auto ack, use broker.DisableAutoAck or WithAckOnSuccess", routingKey)
+		v.Require(!requeueOnError,
+			"requeue on error has no effect on %s with auto ack, use broker.DisableAutoAck or WithAckOnSuccess", routingKey)
+	}
+
+	return v.Err()
 }
 
 func (b *rabbitBroker) Connect() error {
@@ -86,15 +141,77 @@ func (b *rabbitBroker) Connect() error {
 		b.conn = newRabbitMQConnection(b.options)
 	}
 
+	conf := b.amqpConfig(b.vhost)
+
+	if err := b.conn.Connect(b.options.Secure, &conf); err != nil {
+		return err
+	}
+
+	if t, ok := broker.VerifyOnStartFromContext(b.options.Context); ok {
+		if err := b.conn.VerifyTopology(b.options.Context, t); err != nil {
+			_ = b.conn.Close()
+			return err
+		}
+	}
+
+	b.startGroup()
+
+	return nil
+}
+
+// startGroup prepares the context and group the subscribers run in.
+func (b *rabbitBroker) startGroup() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.group != nil {
+		return
+	}
+
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	b.group = &errgroup.Group{}
+}
+
+func (b *rabbitBroker) amqpConfig(vhost string) amqp.Config {
 	conf := DefaultAmqpConfig
 
-	if auth, ok := b.options.Context.Value(externalAuthKey{}).(ExternalAuthentication); ok {
-		conf.SASL = []amqp.Authentication{&auth}
+	if ConfigFromOptions(b.options).ExternalAuth {
+		conf.SASL = []amqp.Authentication{&ExternalAuthentication{}}
 	}
 
 	conf.TLSClientConfig = b.options.TLSConfig
 
-	return b.conn.Connect(b.options.Secure, &conf)
+	if vhost != "" {
+		conf.Vhost = vhost
+	}
+
+	return conf
+}
+
+// connection returns the connection of vhost, connecting to it on first use.
+func (b *rabbitBroker) connection(vhost string) (*rabbitConnection, error) {
+	if vhost == "" || vhost == b.vhost {
+		if b.conn == nil {
+			return nil, errors.New("connection is nil")
+		}
+		return b.conn, nil
+	}
+
+	b.vhostMtx.Lock()
+	defer b.vhostMtx.Unlock()
+
+	if conn, ok := b.vhostConns[vhost]; ok {
+		return conn, nil
+	}
+
+	conn := newRabbitMQConnection(b.options)
+	conf := b.amqpConfig(vhost)
+	if err := conn.Connect(b.options.Secure, &conf); err != nil {
+		return nil, err
+	}
+	b.vhostConns[vhost] = conn
+
+	return conn, nil
 }
 
 func (b *rabbitBroker) Disconnect() error {
@@ -102,113 +219,142 @@ func (b *rabbitBroker) Disconnect() error {
 		return errors.New("connection is nil")
 	}
 
+	b.mtx.Lock()
+	if b.cancel != nil {
+		b.cancel()
+	}
+	group := b.group
+	b.group = nil
+	b.mtx.Unlock()
+
 	b.subscribers.Clear()
 
 	ret := b.conn.Close()
-	b.wg.Wait()
+
+	b.vhostMtx.Lock()
+	for vhost, conn := range b.vhostConns {
+		_ = conn.Close()
+		delete(b.vhostConns, vhost)
+	}
+	b.vhostMtx.Unlock()
+
+	// wait for the delivery loops and in-flight handlers.
+	if group != nil {
+		_ = group.Wait()
+	}
 
 	return ret
 }
 
 func (b *rabbitBroker) Publish(ctx context.Context, routingKey string, msg broker.Any, opts ...broker.PublishOption) error {
-	buf, err := broker.Marshal(b.options.Codec, msg)
+	if err := b.mode.CanPublish(); err != nil {
+		return err
+	}
+
+	pub, options, err := b.newMessage(ctx, routingKey, msg, opts)
 	if err != nil {
 		return err
 	}
 
-	return b.publish(ctx, routingKey, buf, opts...)
-}
+	start := time.Now()
+	err = b.publish(ctx, routingKey, pub, options)
+	b.metrics.RecordPublish(ctx, routingKey, start, err)
 
-func (b *rabbitBroker) publish(ctx context.Context, routingKey string, buf []byte, opts ...broker.PublishOption) error {
-	if b.conn == nil {
-		return errors.New("connection is nil")
-	}
+	return err
+}
 
+// newMessage encode msg and run the publish interceptors, it returns the
+// message to publish with the options of the publish.
+func (b *rabbitBroker) newMessage(ctx context.Context, routingKey string, msg broker.Any, opts []broker.PublishOption) (amqp.Publishing, broker.PublishOptions, error) {
 	options := broker.PublishOptions{
 		Context: ctx,
 	}
-	for _, o := range opts {
-		o(&options)
-	}
 
-	msg := amqp.Publishing{
-		Body:    buf,
-		Headers: amqp.Table{},
+	buf, err := broker.Marshal(b.options.Codec, msg)
+	if err != nil {
+		return amqp.Publishing{}, options, err
 	}
-
-	if value, ok := options.Context.Value(deliveryModeKey{}).(uint8); ok {
-		msg.DeliveryMode = value
+	buf, opts, err = b.options.InterceptPublish(ctx, routingKey, buf, opts)
+	if err != nil {
+		return amqp.Publishing{}, options, err
 	}
 
-	if value, ok := options.Context.Value(priorityKey{}).(uint8); ok {
-		msg.Priority = value
+	for _, o := range opts {
+		o(&options)
 	}
 
-	if value, ok := options.Context.Value(contentTypeKey{}).(string); ok {
-		msg.ContentType = value
-	}
+	c := PublishConfigFromOptions(options)
 
-	if value, ok := options.Context.Value(contentEncodingKey{}).(string); ok {
-		msg.ContentEncoding = value
+	pub := amqp.Publishing{
+		Headers:         amqp.Table{},
+		ContentType:     c.ContentType,
+		ContentEncoding: c.ContentEncoding,
+		DeliveryMode:    c.DeliveryMode,
+		Priority:        c.Priority,
+		CorrelationId:   c.CorrelationID,
+		ReplyTo:         c.ReplyTo,
+		Expiration:      c.Expiration,
+		MessageId:       c.MessageID,
+		Timestamp:       c.Timestamp,
+		Type:            c.Type,
+		UserId:          c.UserID,
+		AppId:           c.AppID,
+		Body:            buf,
 	}
 
-	if value, ok := options.Context.Value(correlationIDKey{}).(string); ok {
-		msg.CorrelationId = value
+	for k, v := range c.Headers {
+		pub.Headers[k] = v
 	}
-
-	if value, ok := options.Context.Value(replyToKey{}).(string); ok {
-		msg.ReplyTo = value
+	for k, v := range options.Headers {
+		pub.Headers[k] = v
 	}
 
-	if value, ok := options.Context.Value(expirationKey{}).(string); ok {
-		msg.Expiration = value
-	}
+	return pub, options, nil
+}
 
-	if value, ok := options.Context.Value(messageIDKey{}).(string); ok {
-		msg.MessageId = value
+func (b *rabbitBroker) publish(ctx context.Context, routingKey string, msg amqp.Publishing, options broker.PublishOptions) error {
+	if b.conn == nil {
+		return errors.New("connection is nil")
 	}
 
-	if value, ok := options.Context.Value(timestampKey{}).(time.Time); ok {
-		msg.Timestamp = value
-	}
+	c := PublishConfigFromOptions(options)
 
-	if value, ok := options.Context.Value(messageTypeKey{}).(string); ok {
-		msg.Type = value
+	conn, err := b.connection(c.VirtualHost)
+	if err != nil {
+		return err
 	}
 
-	if value, ok := options.Context.Value(userIDKey{}).(string); ok {
-		msg.UserId = value
+	if c.RoutingKey != "" {
+		routingKey = c.RoutingKey
 	}
-
-	if value, ok := options.Context.Value(appIDKey{}).(string); ok {
-		msg.AppId = value
+	exchange := conn.exchange.Name
+	if c.Exchange != nil {
+		exchange = *c.Exchange
 	}
 
-	if headers, ok := options.Context.Value(publishHeadersKey{}).(map[string]interface{}); ok {
-		for k, v := range headers {
-			msg.Headers[k] = v
-		}
-	}
-
-	if val, ok := options.Context.Value(publishDeclareQueueKey{}).(*DeclarePublishQueueInfo); ok {
+	if val := c.DeclareQueue; val != nil {
 		if val.Durable {
 			val.AutoDelete = false
 		}
-		if err := b.conn.DeclarePublishQueue(val.Queue, routingKey, val.BindArguments, val.QueueArguments, val.Durable, val.AutoDelete); err != nil {
+		if err := conn.DeclarePublishQueue(val.Queue, routingKey, val.BindArguments, val.QueueArguments, val.Durable, val.AutoDelete); err != nil {
 			return err
 		}
 	}
 
 	span := b.startProducerSpan(options.Context, routingKey, &msg)
 
-	err := b.conn.Publish(ctx, b.conn.exchange.Name, routingKey, msg)
+	err = conn.Publish(ctx, exchange, routingKey, msg)
 
 	b.finishProducerSpan(span, routingKey, err)
 
-	return nil
+	return err
 }
 
 func (b *rabbitBroker) Subscribe(routingKey string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	if err := b.mode.CanSubscribe(); err != nil {
+		return nil, err
+	}
+
 	if b.conn == nil {
 		return nil, errors.New("not connected")
 	}
@@ -221,20 +367,29 @@ func (b *rabbitBroker) Subscribe(routingKey string, handler broker.Handler, bind
 		o(&options)
 	}
 
-	var requeueOnError = false
-	if val, ok := options.Context.Value(requeueOnErrorKey{}).(bool); ok {
-		requeueOnError = val
-	}
+	handler = options.TimeoutHandler(handler)
+	handler = b.metrics.Handler(routingKey, handler)
 
-	var ackSuccess = false
-	if val, ok := options.Context.Value(ackSuccessKey{}).(bool); ok && val {
+	c := SubscribeConfigFromOptions(options)
+
+	requeueOnError := c.RequeueOnError
+	ackSuccess := c.AckOnSuccess
+	if ackSuccess {
 		options.AutoAck = false
-		ackSuccess = true
 	}
 
+	if err := b.validateSubscribe(routingKey, &options, requeueOnError); err != nil {
+		return nil, err
+	}
+
+	if options.RawBody {
+		binder = nil
+	}
+
+	var sub *subscriber
 	fn := func(msg amqp.Delivery) {
 		m := &broker.Message{
-			Headers: rabbitHeaderToMap(msg.Headers),
+			Headers: rabbitHeaderToMap(b.options.HeaderCodec, msg.Headers),
 			Body:    nil,
 		}
 
@@ -248,56 +403,174 @@ func (b *rabbitBroker) Subscribe(routingKey string, handler broker.Handler, bind
 			m.Body = msg.Body
 		}
 
-		if p.err = broker.Unmarshal(b.options.Codec, msg.Body, &m.Body); p.err != nil {
+		if p.err = b.options.Decode(&options, m.Headers, msg.Body, &m.Body); p.err != nil {
 			log.Errorf("[rabbitmq] unmarshal message failed: %v", p.err)
+			options.ReportError(ctx, broker.ErrUnmarshal, p.err, p)
+
+			if action := options.HandleUnmarshalFailure(ctx, msg.RoutingKey, m.Headers, msg.Body, p.err); action != broker.UnmarshalActionDefault {
+				b.settleUnmarshalFailure(ctx, sub, &options, p, action)
+				b.finishConsumerSpan(span, p.err)
+				return
+			}
 		}
 
 		p.err = handler(ctx, p)
 		if p.err == nil && ackSuccess && !options.AutoAck {
-			_ = msg.Ack(false)
-		} else if p.err != nil && !options.AutoAck {
-			_ = msg.Nack(false, requeueOnError)
+			if err := broker.AutoAck(p); err != nil {
+				options.ReportError(ctx, broker.ErrAck, err, p)
+			}
+		} else if p.err != nil && !options.AutoAck && !broker.IsSettled(p) {
+			if err := p.Nack(func() error { return msg.Nack(false, requeueOnError) }); err != nil {
+				options.ReportError(ctx, broker.ErrAck, err, p)
+			}
 		}
 
 		b.finishConsumerSpan(span, p.err)
 	}
 
-	sub := &subscriber{
-		topic:        routingKey,
-		options:      options,
-		r:            b,
-		durableQueue: true,
-		autoDelete:   false,
-		fn:           fn,
-		headers:      nil,
-		queueArgs:    nil,
+	conn, err := b.connection(c.VirtualHost)
+	if err != nil {
+		return nil, err
 	}
 
-	if val, ok := options.Context.Value(durableQueueKey{}).(bool); ok {
-		sub.durableQueue = val
-		sub.autoDelete = false
+	sub = &subscriber{
+		topic:         routingKey,
+		exchange:      c.BindExchange,
+		bindingKey:    c.BindingKey,
+		options:       options,
+		r:             b,
+		conn:          conn,
+		durableQueue:  c.DurableQueue,
+		autoDelete:    c.AutoDeleteQueue,
+		fn:            fn,
+		headers:       c.BindArguments,
+		queueArgs:     c.QueueArguments,
+		inactiveSince: b.options.Clock.Now(),
 	}
 
-	if val, ok := options.Context.Value(autoDeleteQueueKey{}).(bool); ok {
-		sub.autoDelete = val
-		sub.durableQueue = false
-	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
 
-	if val, ok := options.Context.Value(subscribeBindArgsKey{}).(map[string]interface{}); ok {
-		sub.headers = val
+	if b.group == nil {
+		return nil, errors.New("not connected")
 	}
 
-	if val, ok := options.Context.Value(subscribeQueueArgsKey{}).(map[string]interface{}); ok {
-		sub.queueArgs = val
-	}
+	sub.ctx, sub.cancel = context.WithCancel(b.ctx)
 
 	b.subscribers.Add(routingKey, sub)
 
-	go sub.resubscribe()
+	b.group.Go(sub.run)
 
 	return sub, nil
 }
 
+// settleUnmarshalFailure ack or requeue a message which cannot be decoded,
+// with auto ack the message is already acked.
+func (b *rabbitBroker) settleUnmarshalFailure(ctx context.Context, sub *subscriber, options *broker.SubscribeOptions, p *publication, action broker.UnmarshalAction) {
+	var err error
+	switch {
+	case options.AutoAck:
+	case action == broker.UnmarshalActionAck:
+		err = p.Ack()
+	default:
+		err = p.Nack(func() error { return p.d.Nack(false, true) })
+	}
+	if err != nil {
+		options.ReportError(ctx, broker.ErrAck, err, p)
+	}
+
+	if action == broker.UnmarshalActionStop {
+		_ = sub.Unsubscribe(true)
+	}
+}
+
+// QueueDepth return the number of ready messages of queue, the unacked ones
+// are not counted.
+// ConnectionStats report the connection of the broker, the vhost
+// connections are counted in its channels, reconnects and last error.
+// Every subscriber consumes in a goroutine of its own.
+func (b *rabbitBroker) ConnectionStats() broker.ConnectionStats {
+	s := broker.ConnectionStats{State: broker.ClientDisconnected.String()}
+	if b.conn != nil {
+		s = b.conn.tracker.Stats()
+		s.Channels = b.conn.channels()
+	}
+
+	b.vhostMtx.Lock()
+	for _, conn := range b.vhostConns {
+		cs := conn.tracker.Stats()
+		s.Reconnects += cs.Reconnects
+		if cs.LastErrorAt.After(s.LastErrorAt) {
+			s.LastError, s.LastErrorAt = cs.LastError, cs.LastErrorAt
+		}
+		s.Channels += conn.channels()
+	}
+	b.vhostMtx.Unlock()
+
+	b.subscribers.Foreach(func(_ string, sub broker.Subscriber) {
+		rs, ok := sub.(*subscriber)
+		if !ok || rs.IsClosed() {
+			return
+		}
+		s.Subscribers++
+		s.Goroutines++
+		if rs.consuming() {
+			s.Channels++
+		}
+	})
+
+	s.Broker = b.Name()
+	s.Address = b.Address()
+	return s
+}
+
+func (b *rabbitBroker) QueueDepth(_ context.Context, queue string) (int64, error) {
+	if b.conn == nil {
+		return 0, errors.New("not connected")
+	}
+	n, err := b.conn.withChannel(func(ch *rabbitChannel) (int, error) {
+		return ch.QueueDepth(queue)
+	})
+	return int64(n), err
+}
+
+// PurgeQueue drop the ready messages of queue.
+func (b *rabbitBroker) PurgeQueue(_ context.Context, queue string) (int64, error) {
+	if b.conn == nil {
+		return 0, errors.New("not connected")
+	}
+	n, err := b.conn.withChannel(func(ch *rabbitChannel) (int, error) {
+		return ch.PurgeQueue(queue)
+	})
+	return int64(n), err
+}
+
+var ErrNoAlternateExchange = errors.New("rabbitmq: no alternate exchange, see WithAlternateExchange")
+
+// SubscribeUnroutable consume the messages captured by the alternate
+// exchange, see WithAlternateExchange. The topic of the events is the
+// routing key they were published with.
+func (b *rabbitBroker) SubscribeUnroutable(handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	if b.conn == nil {
+		return nil, errors.New("not connected")
+	}
+	alternate := b.conn.alternate
+	if alternate.Name == "" {
+		return nil, ErrNoAlternateExchange
+	}
+
+	opts = append(append([]broker.SubscribeOption{WithDurableQueue()}, opts...),
+		broker.WithQueueName(alternate.Queue),
+		WithBindExchange(alternate.Name),
+	)
+	return b.Subscribe("", handler, binder, opts...)
+}
+
+// SubscribeRaw subscribe routingKey and deliver the bodies as received.
+func (b *rabbitBroker) SubscribeRaw(routingKey string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Subscribe(routingKey, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
+
 func (b *rabbitBroker) startProducerSpan(ctx context.Context, routingKey string, msg *amqp.Publishing) trace.Span {
 	if b.producerTracer == nil {
 		return nil
@@ -0,0 +1,152 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/partition"
+)
+
+var ErrNotRabbitBroker = errors.New("rabbitmq: the declaration needs a rabbitmq broker")
+
+type ShardOption func(q *ShardedQueue)
+
+// WithShardConsumer consume only the shards i with i % count == index,
+// spreading the shards over count consumers.
+func WithShardConsumer(index, count int) ShardOption {
+	return func(q *ShardedQueue) {
+		if count > 0 && index >= 0 && index < count {
+			q.index, q.count = index, count
+		}
+	}
+}
+
+// WithShardQueueArguments set the arguments of the shard queues, e.g.
+// {"x-queue-type": "quorum"}.
+func WithShardQueueArguments(args map[string]interface{}) ShardOption {
+	return func(q *ShardedQueue) {
+		q.queueArgs = args
+	}
+}
+
+// ShardedQueue spreads one logical queue over shards durable queues, named
+// "name.0" to "name.<shards-1>" and bound to the exchange with their own
+// name as routing key, to go over the throughput of a single queue:
+//
+//	q := rabbitmq.NewShardedQueue(b, "orders", 8, rabbitmq.WithShardConsumer(index, replicas))
+//	_ = q.Declare(ctx)
+//	_ = q.Publish(ctx, order.CustomerID, order)
+//	subs, _ := q.Subscribe(handler, binder)
+//
+// The shards follow partition.DefaultTopicFormat and partition.Of, so a
+// partition.Consumer may consume them instead, spreading the shards
+// over the running instances dynamically.
+type ShardedQueue struct {
+	b      broker.Broker
+	name   string
+	shards int
+
+	index, count int
+	queueArgs    map[string]interface{}
+
+	next uint32
+}
+
+func NewShardedQueue(b broker.Broker, name string, shards int, opts ...ShardOption) *ShardedQueue {
+	if shards < 1 {
+		shards = 1
+	}
+
+	q := &ShardedQueue{
+		b:      b,
+		name:   name,
+		shards: shards,
+		count:  1,
+	}
+
+	for _, o := range opts {
+		o(q)
+	}
+
+	return q
+}
+
+// Shard return the shard of key.
+func (q *ShardedQueue) Shard(key string) int {
+	return partition.Of(key, q.shards)
+}
+
+// ShardName return the queue, and routing key, of shard i.
+func (q *ShardedQueue) ShardName(i int) string {
+	return partition.DefaultTopicFormat(q.name, i)
+}
+
+// Shards return the shards consumed by Subscribe.
+func (q *ShardedQueue) Shards() []int {
+	var shards []int
+	for i := q.index; i < q.shards; i += q.count {
+		shards = append(shards, i)
+	}
+	return shards
+}
+
+// Declare declare every shard queue and bind it to the exchange, so that no
+// message is dropped before the consumers start.
+func (q *ShardedQueue) Declare(_ context.Context) error {
+	rb, ok := q.b.(*rabbitBroker)
+	if !ok {
+		return ErrNotRabbitBroker
+	}
+
+	conn, err := rb.connection("")
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < q.shards; i++ {
+		name := q.ShardName(i)
+		if err = conn.DeclarePublishQueue(name, name, nil, q.queueArgs, true, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Publish publish msg to the shard of key, the messages of a key are
+// consumed in order. An empty key picks the shards in turn.
+func (q *ShardedQueue) Publish(ctx context.Context, key string, msg broker.Any, opts ...broker.PublishOption) error {
+	var shard int
+	if key != "" {
+		shard = q.Shard(key)
+	} else {
+		shard = int((atomic.AddUint32(&q.next, 1) - 1) % uint32(q.shards))
+	}
+	return q.b.Publish(ctx, q.ShardName(shard), msg, opts...)
+}
+
+// Subscribe consume the shards of the consumer, see WithShardConsumer. On
+// failure the subscriptions already made are removed.
+func (q *ShardedQueue) Subscribe(handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) ([]broker.Subscriber, error) {
+	var subs []broker.Subscriber
+	for _, i := range q.Shards() {
+		name := q.ShardName(i)
+
+		subOpts := []broker.SubscribeOption{WithDurableQueue()}
+		if q.queueArgs != nil {
+			subOpts = append(subOpts, WithQueueArguments(q.queueArgs))
+		}
+		subOpts = append(append(subOpts, opts...), broker.WithQueueName(name))
+
+		sub, err := q.b.Subscribe(name, handler, binder, subOpts...)
+		if err != nil {
+			for _, s := range subs {
+				_ = s.Unsubscribe(true)
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
@@ -0,0 +1,67 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type shardSubscriber struct {
+	topic string
+	opts  broker.SubscribeOptions
+}
+
+func (s *shardSubscriber) Options() broker.SubscribeOptions { return s.opts }
+func (s *shardSubscriber) Topic() string                    { return s.topic }
+func (s *shardSubscriber) Unsubscribe(bool) error           { return nil }
+
+type shardBroker struct {
+	broker.Broker
+
+	published  []string
+	subscribed []*shardSubscriber
+}
+
+func (b *shardBroker) Publish(_ context.Context, topic string, _ broker.Any, _ ...broker.PublishOption) error {
+	b.published = append(b.published, topic)
+	return nil
+}
+
+func (b *shardBroker) Subscribe(topic string, _ broker.Handler, _ broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	sub := &shardSubscriber{topic: topic, opts: broker.NewSubscribeOptions(opts...)}
+	b.subscribed = append(b.subscribed, sub)
+	return sub, nil
+}
+
+func TestShardedQueue(t *testing.T) {
+	b := &shardBroker{}
+	ctx := context.Background()
+	q := NewShardedQueue(b, "orders", 4, WithShardConsumer(1, 2))
+
+	assert.Equal(t, "orders.3", q.ShardName(3))
+	assert.Equal(t, []int{1, 3}, q.Shards())
+	assert.ErrorIs(t, q.Declare(ctx), ErrNotRabbitBroker)
+
+	assert.NoError(t, q.Publish(ctx, "customer-1", "msg"))
+	assert.NoError(t, q.Publish(ctx, "customer-1", "msg"))
+	assert.Equal(t, b.published[0], b.published[1])
+	assert.Equal(t, q.ShardName(q.Shard("customer-1")), b.published[0])
+
+	b.published = nil
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, q.Publish(ctx, "", "msg"))
+	}
+	assert.Equal(t, []string{"orders.0", "orders.1", "orders.2", "orders.3"}, b.published)
+
+	subs, err := q.Subscribe(func(context.Context, broker.Event) error { return nil }, nil)
+	assert.NoError(t, err)
+	assert.Len(t, subs, 2)
+	for i, shard := range []string{"orders.1", "orders.3"} {
+		assert.Equal(t, shard, b.subscribed[i].topic)
+		assert.Equal(t, shard, b.subscribed[i].opts.Queue)
+		assert.Equal(t, true, b.subscribed[i].opts.Context.Value(durableQueueKey{}))
+	}
+}
@@ -0,0 +1,133 @@
+package stream
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed stream configuration of a broker, the fields are the
+// ones set one by one with the broker options of the package.
+type Config struct {
+	MaxLengthBytes int64
+	MaxAge         time.Duration
+	// SuperStreams maps the name of the super streams to their number of
+	// partitions, see WithSuperStream.
+	SuperStreams map[string]int
+	SubEntrySize int
+	// Compression only applies with a SubEntrySize above 1.
+	Compression string
+	BatchSize   int
+}
+
+// WithConfig set the whole configuration at once, the zero fields are left
+// unset. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		var opts []broker.Option
+		if c.MaxLengthBytes != 0 {
+			opts = append(opts, WithMaxLengthBytes(c.MaxLengthBytes))
+		}
+		if c.MaxAge != 0 {
+			opts = append(opts, WithMaxAge(c.MaxAge))
+		}
+		for name, partitions := range c.SuperStreams {
+			opts = append(opts, WithSuperStream(name, partitions))
+		}
+		if c.SubEntrySize != 0 {
+			opts = append(opts, WithSubEntrySize(c.SubEntrySize))
+		}
+		if c.Compression != "" {
+			opts = append(opts, WithCompression(c.Compression))
+		}
+		if c.BatchSize != 0 {
+			opts = append(opts, WithBatchSize(c.BatchSize))
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	var c Config
+	if opts.Context == nil {
+		return c
+	}
+
+	c.MaxLengthBytes, _ = opts.Context.Value(maxLengthBytesKey{}).(int64)
+	c.MaxAge, _ = opts.Context.Value(maxAgeKey{}).(time.Duration)
+	c.SuperStreams, _ = opts.Context.Value(superStreamsKey{}).(map[string]int)
+	c.SubEntrySize, _ = opts.Context.Value(subEntrySizeKey{}).(int)
+	c.Compression, _ = opts.Context.Value(compressionKey{}).(string)
+	c.BatchSize, _ = opts.Context.Value(batchSizeKey{}).(int)
+
+	return c
+}
+
+// SubscribeConfig is the typed stream configuration of a subscription.
+type SubscribeConfig struct {
+	StoreOffsetEvery int
+}
+
+// DefaultSubscribeConfig return the configuration of a subscription without
+// options.
+func DefaultSubscribeConfig() SubscribeConfig {
+	return SubscribeConfig{StoreOffsetEvery: 1}
+}
+
+// WithSubscribeConfig set the whole subscription configuration at once, c
+// should start from DefaultSubscribeConfig.
+func WithSubscribeConfig(c SubscribeConfig) broker.SubscribeOption {
+	return WithStoreOffsetEvery(c.StoreOffsetEvery)
+}
+
+// SubscribeConfigFromOptions return the subscription configuration set in
+// the context of opts, a store interval not above zero keeps the default.
+func SubscribeConfigFromOptions(opts broker.SubscribeOptions) SubscribeConfig {
+	c := DefaultSubscribeConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	if v, ok := opts.Context.Value(storeOffsetEveryKey{}).(int); ok && v > 0 {
+		c.StoreOffsetEvery = v
+	}
+
+	return c
+}
+
+// PublishConfig is the typed stream configuration of a publishing, the zero
+// fields are left unset.
+type PublishConfig struct {
+	// Headers are set as application properties, along with the headers of
+	// the broker publish options.
+	Headers    map[string]string
+	RoutingKey string
+}
+
+// WithPublishConfig set the whole publishing configuration at once.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if c.Headers != nil {
+			WithHeaders(c.Headers)(o)
+		}
+		if c.RoutingKey != "" {
+			WithRoutingKey(c.RoutingKey)(o)
+		}
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	var c PublishConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.Headers, _ = opts.Context.Value(headersKey{}).(map[string]string)
+	c.RoutingKey, _ = opts.Context.Value(routingKeyKey{}).(string)
+
+	return c
+}
@@ -0,0 +1,56 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, Config{}, ConfigFromOptions(broker.NewOptions()))
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithMaxAge(time.Hour),
+		WithSuperStream("orders", 3),
+		WithSubEntrySize(100),
+		WithCompression("gzip"),
+	))
+
+	c := Config{
+		MaxAge:       time.Hour,
+		SuperStreams: map[string]int{"orders": 3},
+		SubEntrySize: 100,
+		Compression:  "gzip",
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c), WithSuperStream("orders", 5)))
+	assert.Equal(t, map[string]int{"orders": 5}, overridden.SuperStreams)
+}
+
+func TestSubscribeConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultSubscribeConfig(), SubscribeConfigFromOptions(broker.NewSubscribeOptions()))
+
+	c := SubscribeConfig{StoreOffsetEvery: 100}
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithStoreOffsetEvery(100))))
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c))))
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	assert.Equal(t, PublishConfig{}, PublishConfigFromOptions(broker.PublishOptions{}))
+
+	c := PublishConfig{
+		Headers:    map[string]string{"trace": "1"},
+		RoutingKey: "order-1",
+	}
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(
+		WithHeaders(map[string]string{"trace": "1"}),
+		WithRoutingKey("order-1"),
+	)))
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+}
@@ -0,0 +1,51 @@
+package stream
+
+import (
+	streamAmqp "github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var _ propagation.TextMapCarrier = (*MessageCarrier)(nil)
+
+// MessageCarrier injects and extracts trace context with the application
+// properties of a message.
+type MessageCarrier struct {
+	properties map[string]interface{}
+}
+
+func NewMessageCarrier(properties map[string]interface{}) MessageCarrier {
+	return MessageCarrier{properties: properties}
+}
+
+func (c MessageCarrier) Get(key string) string {
+	if v, ok := c.properties[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func (c MessageCarrier) Set(key, val string) {
+	c.properties[key] = val
+}
+
+func (c MessageCarrier) Keys() []string {
+	out := make([]string, 0, len(c.properties))
+	for k := range c.properties {
+		out = append(out, k)
+	}
+	return out
+}
+
+func messageHeaders(codec broker.HeaderCodec, msg *streamAmqp.Message) map[string]string {
+	m := make(map[string]string, len(msg.ApplicationProperties))
+	for k, v := range msg.ApplicationProperties {
+		if k == routingKeyHeader {
+			continue
+		}
+		m[k] = broker.EncodeHeader(codec, v)
+	}
+	return m
+}
@@ -0,0 +1,92 @@
+package stream
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+///
+/// Option
+///
+
+type maxLengthBytesKey struct{}
+type maxAgeKey struct{}
+type superStreamsKey struct{}
+type subEntrySizeKey struct{}
+type compressionKey struct{}
+type batchSizeKey struct{}
+
+// WithMaxLengthBytes StreamOptions.MaxLengthBytes of the declared streams.
+func WithMaxLengthBytes(n int64) broker.Option {
+	return broker.OptionContextWithValue(maxLengthBytesKey{}, n)
+}
+
+// WithMaxAge StreamOptions.MaxAge of the declared streams.
+func WithMaxAge(d time.Duration) broker.Option {
+	return broker.OptionContextWithValue(maxAgeKey{}, d)
+}
+
+// WithSuperStream declare name as a super stream of partitions streams on
+// connect, its publishes are routed by WithRoutingKey and its subscriptions
+// consume every partition. Call it once per super stream.
+func WithSuperStream(name string, partitions int) broker.Option {
+	return func(o *broker.Options) {
+		streams, _ := o.Context.Value(superStreamsKey{}).(map[string]int)
+		next := map[string]int{name: partitions}
+		for k, v := range streams {
+			if k != name {
+				next[k] = v
+			}
+		}
+		broker.OptionContextWithValue(superStreamsKey{}, next)(o)
+	}
+}
+
+// WithSubEntrySize ProducerOptions.SubEntrySize, pack up to n messages in
+// one sub-entry, compressed together.
+func WithSubEntrySize(n int) broker.Option {
+	return broker.OptionContextWithValue(subEntrySizeKey{}, n)
+}
+
+// WithCompression ProducerOptions.Compression of the sub-entries: "gzip",
+// "snappy", "lz4", "zstd" or "none".
+func WithCompression(kind string) broker.Option {
+	return broker.OptionContextWithValue(compressionKey{}, kind)
+}
+
+// WithBatchSize ProducerOptions.BatchSize
+func WithBatchSize(n int) broker.Option {
+	return broker.OptionContextWithValue(batchSizeKey{}, n)
+}
+
+///
+/// PublishOption
+///
+
+type headersKey struct{}
+type routingKeyKey struct{}
+
+// WithHeaders set the application properties of the message.
+func WithHeaders(h map[string]string) broker.PublishOption {
+	return broker.PublishContextWithValue(headersKey{}, h)
+}
+
+// WithRoutingKey route the message of a super stream to the partition of
+// key, hashed like the other stream clients do.
+func WithRoutingKey(key string) broker.PublishOption {
+	return broker.PublishContextWithValue(routingKeyKey{}, key)
+}
+
+///
+/// SubscribeOption
+///
+
+type storeOffsetEveryKey struct{}
+
+// WithStoreOffsetEvery store the offset of an auto acked subscription on the
+// server every n messages, 1 by default. The offset is stored on
+// unsubscribe too.
+func WithStoreOffsetEvery(n int) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(storeOffsetEveryKey{}, n)
+}
@@ -0,0 +1,52 @@
+package stream
+
+import (
+	streamAmqp "github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+	streamGo "github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var _ broker.PositionEvent = (*publication)(nil)
+
+type publication struct {
+	broker.AckState
+
+	pc       *partitionConsumer
+	consumer *streamGo.Consumer
+	offset   int64
+	topic    string
+	msg      *streamAmqp.Message
+	m        *broker.Message
+	err      error
+}
+
+// Ack store the offset of the message, right away whatever
+// WithStoreOffsetEvery, for the named subscriptions.
+func (p *publication) Ack() error {
+	return p.AckState.Ack(func() error {
+		return p.pc.ack(p.consumer, p.offset, 1)
+	})
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (p *publication) Topic() string {
+	return p.topic
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (p *publication) RawMessage() interface{} {
+	return p.msg
+}
+
+// Position is the offset after the message in its partition, the index of
+// its stream in the partitions of a super stream.
+func (p *publication) Position() broker.Position {
+	return broker.AtOffset(p.pc.partition, p.offset+1)
+}
@@ -0,0 +1,221 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	streamAmqp "github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+	streamGo "github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var _ broker.Seeker = (*subscriber)(nil)
+
+// partitionConsumer consumes one stream, a partition of a super stream.
+type partitionConsumer struct {
+	sync.Mutex
+
+	stream    string
+	partition int
+	name      string
+	consumer  *streamGo.Consumer
+
+	// acked offset not stored yet, and the number of acks since the last store.
+	offset  int64
+	pending int
+}
+
+// ack store offset with consumer every n acks, the offsets are only
+// tracked by the server for the named subscriptions.
+func (pc *partitionConsumer) ack(consumer *streamGo.Consumer, offset int64, n int) error {
+	if pc.name == "" {
+		return nil
+	}
+
+	pc.Lock()
+	defer pc.Unlock()
+
+	pc.offset = offset
+	pc.pending++
+	if pc.pending < n {
+		return nil
+	}
+	pc.pending = 0
+	return consumer.StoreCustomOffset(offset)
+}
+
+// flush store the acked offset not stored yet.
+func (pc *partitionConsumer) flush() error {
+	pc.Lock()
+	defer pc.Unlock()
+
+	if pc.name == "" || pc.pending == 0 || pc.consumer == nil {
+		return nil
+	}
+	pc.pending = 0
+	return pc.consumer.StoreCustomOffset(pc.offset)
+}
+
+type subscriber struct {
+	sync.RWMutex
+
+	b   *streamBroker
+	env *streamGo.Environment
+
+	options    broker.SubscribeOptions
+	topic      string
+	streams    []string
+	handler    broker.Handler
+	binder     broker.Binder
+	storeEvery int
+
+	consumers []*partitionConsumer
+	closed    bool
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.options
+}
+
+func (s *subscriber) Topic() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.topic
+}
+
+// start create a consumer per stream at pos, at the stored offset of the
+// named subscriptions when pos is nil, at the next message otherwise.
+func (s *subscriber) start(pos *broker.Position) error {
+	var consumers []*partitionConsumer
+
+	for i, stream := range s.streams {
+		offset, err := s.offsetSpecification(stream, i, pos)
+		if err != nil {
+			closeConsumers(consumers)
+			return err
+		}
+
+		pc := &partitionConsumer{
+			stream:    stream,
+			partition: i,
+			name:      s.options.Queue,
+		}
+
+		opts := streamGo.NewConsumerOptions().SetOffset(offset)
+		if pc.name != "" {
+			opts = opts.SetConsumerName(pc.name)
+		}
+
+		// the first messages may be delivered before NewConsumer returns.
+		consumer, err := s.env.NewConsumer(stream, func(ctx streamGo.ConsumerContext, msg *streamAmqp.Message) {
+			s.b.handleMessage(s, pc, ctx.Consumer, msg)
+		}, opts)
+		if err != nil {
+			closeConsumers(consumers)
+			return fmt.Errorf("failed to consume [%s]: %v", stream, err)
+		}
+
+		pc.Lock()
+		pc.consumer = consumer
+		pc.Unlock()
+
+		consumers = append(consumers, pc)
+	}
+
+	s.consumers = consumers
+
+	return nil
+}
+
+func (s *subscriber) offsetSpecification(stream string, partition int, pos *broker.Position) (streamGo.OffsetSpecification, error) {
+	if pos != nil {
+		switch pos.Kind {
+		case broker.PositionBeginning:
+			return streamGo.OffsetSpecification{}.First(), nil
+		case broker.PositionEnd:
+			return streamGo.OffsetSpecification{}.Next(), nil
+		case broker.PositionTime:
+			return streamGo.OffsetSpecification{}.Timestamp(pos.Time.UnixMilli()), nil
+		case broker.PositionOffset:
+			if pos.Partition < 0 || pos.Partition == partition {
+				return streamGo.OffsetSpecification{}.Offset(pos.Offset), nil
+			}
+		default:
+			return streamGo.OffsetSpecification{}, fmt.Errorf("%w: %s", broker.ErrSeekUnsupported, pos)
+		}
+	}
+
+	if s.options.Queue != "" {
+		if offset, err := s.env.QueryOffset(s.options.Queue, stream); err == nil {
+			return streamGo.OffsetSpecification{}.Offset(offset + 1), nil
+		}
+	}
+
+	return streamGo.OffsetSpecification{}.Next(), nil
+}
+
+func closeConsumers(consumers []*partitionConsumer) {
+	for _, pc := range consumers {
+		_ = pc.consumer.Close()
+	}
+}
+
+// Seek close the consumers and consume the streams again from pos, the
+// offsets acked but not stored yet are dropped. It must not be called from
+// the handler, closing a consumer waits for its handler to return.
+func (s *subscriber) Seek(_ context.Context, pos broker.Position) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return errors.New("subscriber closed")
+	}
+
+	closeConsumers(s.consumers)
+	s.consumers = nil
+
+	return s.start(&pos)
+}
+
+// Unsubscribe store the acked offsets of a named subscription and close its
+// consumers.
+func (s *subscriber) Unsubscribe(removeFromManager bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	var errs []error
+	for _, pc := range s.consumers {
+		if err := pc.flush(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := pc.consumer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.consumers = nil
+
+	if s.b != nil && s.b.subscribers != nil && removeFromManager {
+		_ = s.b.subscribers.RemoveOnly(s.topic)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *subscriber) IsClosed() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.closed
+}
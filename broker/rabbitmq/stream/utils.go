@@ -0,0 +1,22 @@
+package stream
+
+import (
+	"regexp"
+)
+
+var re = regexp.MustCompile("^rabbitmq-stream(\\+tls)?://.*")
+
+func hasUrlPrefix(url string) bool {
+	return re.MatchString(url)
+}
+
+func refitUrl(url string, enableTLS bool) string {
+	if !hasUrlPrefix(url) {
+		prefix := "rabbitmq-stream://"
+		if enableTLS {
+			prefix = "rabbitmq-stream+tls://"
+		}
+		url = prefix + url
+	}
+	return url
+}
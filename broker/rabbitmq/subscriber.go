@@ -1,6 +1,8 @@
 package rabbitmq
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"time"
 
@@ -11,11 +13,16 @@ import (
 type subscriber struct {
 	sync.RWMutex
 
-	r *rabbitBroker
+	r    *rabbitBroker
+	conn *rabbitConnection
 
-	options broker.SubscribeOptions
-	topic   string
-	ch      *rabbitChannel
+	options  broker.SubscribeOptions
+	topic    string
+	exchange string
+	ch       *rabbitChannel
+
+	// bindingKey binds the queue instead of topic when set.
+	bindingKey string
 
 	queueArgs map[string]interface{}
 	fn        func(msg amqp.Delivery)
@@ -24,6 +31,12 @@ type subscriber struct {
 	durableQueue bool
 	autoDelete   bool
 	closed       bool
+
+	// when the delivery channel was lost, meaningful while ch is nil.
+	inactiveSince time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func (s *subscriber) Options() broker.SubscribeOptions {
@@ -45,10 +58,14 @@ func (s *subscriber) Unsubscribe(removeFromManager bool) error {
 	defer s.Unlock()
 
 	s.closed = true
+	if s.cancel != nil {
+		s.cancel()
+	}
 
 	var err error
 	if s.ch != nil {
 		err = s.ch.Close()
+		s.ch = nil
 	}
 
 	if s.r != nil && s.r.subscribers != nil && removeFromManager {
@@ -58,64 +75,147 @@ func (s *subscriber) Unsubscribe(removeFromManager bool) error {
 	return err
 }
 
-func (s *subscriber) resubscribe() {
-	minResubscribeDelay := defaultMinResubscribeDelay
-	maxResubscribeDelay := defaultMaxResubscribeDelay
-	expFactor := defaultExpFactor
+// run consumes until the subscriber context is canceled, the channel is
+// recreated with a backoff whenever the connection drops.
+func (s *subscriber) run() error {
 	reSubscribeDelay := defaultResubscribeDelay
 
 	for {
-		closed := s.IsClosed()
-		if closed {
-			// we are unsubscribed, showdown routine
-			return
-		}
-
 		select {
-		case <-s.r.conn.close:
-			return
-		case <-s.r.conn.waitConnection:
+		case <-s.ctx.Done():
+			return nil
+		case <-s.conn.closed():
+			return nil
+		case <-s.conn.connectionReady():
 		}
 
-		s.r.mtx.Lock()
-		if !s.r.conn.connected {
-			s.r.mtx.Unlock()
+		deliveries, err := s.consume()
+		switch {
+		case s.ctx.Err() != nil:
+			return nil
+		case err != nil:
+			s.options.ReportError(s.ctx, broker.ErrResubscribe, err, nil)
+			if !s.backoff(&reSubscribeDelay) {
+				return nil
+			}
 			continue
 		}
 
-		ch, sub, err := s.r.conn.Consume(
-			s.options.Queue,
-			s.topic,
-			s.headers,
-			s.queueArgs,
-			s.options.AutoAck,
-			s.durableQueue,
-			s.autoDelete,
-		)
-
-		s.r.mtx.Unlock()
-		switch err {
-		case nil:
-			reSubscribeDelay = minResubscribeDelay
-			s.Lock()
-			s.ch = ch
-			s.Unlock()
-		default:
-			if reSubscribeDelay > maxResubscribeDelay {
-				reSubscribeDelay = maxResubscribeDelay
-			}
-			time.Sleep(reSubscribeDelay)
-			reSubscribeDelay *= expFactor
+		start := s.r.options.Clock.Now()
+		if s.deliver(deliveries) || s.r.options.Clock.Since(start) >= defaultMinConsumeUptime {
+			reSubscribeDelay = defaultMinResubscribeDelay
 			continue
 		}
-		for d := range sub {
-			s.r.wg.Add(1)
+
+		// the channel closed before any delivery, e.g. the queue was deleted
+		// or the consumer canceled, consuming again at once would spin.
+		if !s.backoff(&reSubscribeDelay) {
+			return nil
+		}
+	}
+}
+
+// backoff waits for delay, then doubles it, it returns false once the
+// subscriber context is canceled.
+func (s *subscriber) backoff(delay *time.Duration) bool {
+	if *delay > defaultMaxResubscribeDelay {
+		*delay = defaultMaxResubscribeDelay
+	}
+	timer := s.r.options.Clock.NewTimer(*delay)
+	select {
+	case <-s.ctx.Done():
+		timer.Stop()
+		return false
+	case <-timer.C():
+	}
+	*delay *= defaultExpFactor
+	return true
+}
+
+func (s *subscriber) consume() (<-chan amqp.Delivery, error) {
+	// serialize with Disconnect, no channel is opened once the broker is closing.
+	s.r.mtx.Lock()
+	defer s.r.mtx.Unlock()
+
+	if s.ctx.Err() != nil {
+		return nil, s.ctx.Err()
+	}
+	if !s.conn.isConnected() {
+		return nil, errors.New("not connected")
+	}
+
+	key := s.topic
+	if s.bindingKey != "" {
+		key = s.bindingKey
+	}
+
+	ch, deliveries, err := s.conn.Consume(
+		s.options.Queue,
+		key,
+		s.exchange,
+		s.headers,
+		s.queueArgs,
+		s.options.AutoAck,
+		s.durableQueue,
+		s.autoDelete,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		_ = ch.Close()
+		return nil, errors.New("subscriber closed")
+	}
+	s.ch = ch
+
+	return deliveries, nil
+}
+
+// deliver hands the deliveries to the handler until the channel closes, it
+// reports whether at least one was delivered.
+func (s *subscriber) deliver(deliveries <-chan amqp.Delivery) bool {
+	delivered := false
+	for {
+		select {
+		case <-s.ctx.Done():
+			return delivered
+		case d, ok := <-deliveries:
+			if !ok {
+				s.Lock()
+				s.ch = nil
+				s.inactiveSince = s.r.options.Clock.Now()
+				s.Unlock()
+				return delivered
+			}
+			delivered = true
 			s.fn(d)
-			s.r.wg.Done()
 		}
 	}
 }
 
+// LastActivity reports the subscriber alive as long as its delivery channel is open.
+func (s *subscriber) LastActivity() time.Time {
+	s.RLock()
+	defer s.RUnlock()
+
+	if s.ch != nil {
+		return s.r.options.Clock.Now()
+	}
+	return s.inactiveSince
+}
+
+// consuming report whether the subscriber has an open delivery channel.
+func (s *subscriber) consuming() bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.ch != nil
+}
+
 func (s *subscriber) IsClosed() bool {
 	s.RLock()
 	defer s.RUnlock()
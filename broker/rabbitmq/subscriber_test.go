@@ -0,0 +1,113 @@
+package rabbitmq
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestSubscriberLifecycle(t *testing.T) {
+	b := NewBroker().(*rabbitBroker)
+	b.conn = newRabbitMQConnection(b.options)
+	b.startGroup()
+
+	before := runtime.NumGoroutine()
+
+	handler := func(context.Context, broker.Event) error { return nil }
+	for i := 0; i < 10; i++ {
+		sub, err := b.Subscribe("test", handler, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, sub.Unsubscribe(true))
+	}
+
+	_, err := b.Subscribe("test", handler, nil)
+	assert.Nil(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		_ = b.Disconnect()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("disconnect did not wait for the subscribers to exit")
+	}
+
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+
+	_, err = b.Subscribe("test", handler, nil)
+	assert.NotNil(t, err)
+}
+
+func TestSubscribeUnroutable(t *testing.T) {
+	handler := func(context.Context, broker.Event) error { return nil }
+
+	b := NewBroker().(*rabbitBroker)
+	b.conn = newRabbitMQConnection(b.options)
+	b.startGroup()
+
+	_, err := b.SubscribeUnroutable(handler, nil)
+	assert.ErrorIs(t, err, ErrNoAlternateExchange)
+	_ = b.Disconnect()
+
+	b = NewBroker(WithAlternateExchange("unroutable", "unroutable.capture")).(*rabbitBroker)
+	b.conn = newRabbitMQConnection(b.options)
+	b.startGroup()
+
+	sub, err := b.SubscribeUnroutable(handler, nil, broker.DisableAutoAck())
+	assert.Nil(t, err)
+	assert.Equal(t, "unroutable.capture", sub.Options().Queue)
+	assert.False(t, sub.Options().AutoAck)
+	assert.Equal(t, "unroutable", sub.(*subscriber).exchange)
+	assert.True(t, sub.(*subscriber).durableQueue)
+
+	_ = b.Disconnect()
+}
+
+func TestSubscriberDeliverReportsDelivery(t *testing.T) {
+	b := NewBroker().(*rabbitBroker)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var handled int
+	s := &subscriber{r: b, ctx: ctx, cancel: cancel, fn: func(amqp.Delivery) { handled++ }}
+
+	// a channel closed at once, e.g. the queue was deleted, delivered nothing.
+	closed := make(chan amqp.Delivery)
+	close(closed)
+	assert.False(t, s.deliver(closed))
+
+	deliveries := make(chan amqp.Delivery, 1)
+	deliveries <- amqp.Delivery{}
+	close(deliveries)
+	assert.True(t, s.deliver(deliveries))
+	assert.Equal(t, 1, handled)
+}
+
+func TestSubscriberBackoff(t *testing.T) {
+	clock := broker.NewFakeClock(time.Now())
+	b := NewBroker(broker.WithClock(clock)).(*rabbitBroker)
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &subscriber{r: b, ctx: ctx, cancel: cancel}
+
+	delay := defaultMinResubscribeDelay
+	done := make(chan bool)
+	go func() { done <- s.backoff(&delay) }()
+
+	clock.BlockUntil(1)
+	clock.Advance(defaultMinResubscribeDelay)
+	assert.True(t, <-done)
+	assert.Equal(t, 2*defaultMinResubscribeDelay, delay)
+
+	delay = time.Hour
+	go func() { done <- s.backoff(&delay) }()
+	cancel()
+	assert.False(t, <-done)
+}
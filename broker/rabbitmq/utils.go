@@ -5,14 +5,16 @@ import (
 
 	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/tx7do/kratos-transport/broker"
 )
 
 var re = regexp.MustCompile("^amqp(s)?://.*")
 
-func rabbitHeaderToMap(h amqp.Table) map[string]string {
-	headers := make(map[string]string)
+func rabbitHeaderToMap(codec broker.HeaderCodec, h amqp.Table) map[string]string {
+	headers := make(map[string]string, len(h))
 	for k, v := range h {
-		headers[k], _ = v.(string)
+		headers[k] = broker.EncodeHeader(codec, v)
 	}
 	return headers
 }
@@ -0,0 +1,73 @@
+package broker
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrRawUnsupported = errors.New("broker does not support raw subscriptions")
+
+// RawEvent is a message delivered as received, neither the binder nor the
+// codec touch the body.
+type RawEvent interface {
+	Topic() string
+
+	Headers() Headers
+	Body() []byte
+
+	// RawMessage return the message of the driver.
+	RawMessage() interface{}
+
+	Ack() error
+}
+
+type RawHandler func(ctx context.Context, evt RawEvent) error
+
+// RawSubscriber is implemented by the brokers able to deliver the exact bytes
+// of the messages, for bridges and archivers.
+type RawSubscriber interface {
+	SubscribeRaw(topic string, handler RawHandler, opts ...SubscribeOption) (Subscriber, error)
+}
+
+// SubscribeRaw subscribe topic with the raw mode of b, ErrRawUnsupported is
+// returned when b does not implement RawSubscriber.
+func SubscribeRaw(b Broker, topic string, handler RawHandler, opts ...SubscribeOption) (Subscriber, error) {
+	rs, ok := b.(RawSubscriber)
+	if !ok {
+		return nil, ErrRawUnsupported
+	}
+	return rs.SubscribeRaw(topic, handler, opts...)
+}
+
+// WithRawBody deliver the body of the messages without binder nor codec.
+func WithRawBody() SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.RawBody = true
+	}
+}
+
+// Handler adapt h to the events of a subscription made with WithRawBody.
+func (h RawHandler) Handler() Handler {
+	return func(ctx context.Context, evt Event) error {
+		return h(ctx, &rawEvent{Event: evt})
+	}
+}
+
+type rawEvent struct {
+	Event
+}
+
+func (e *rawEvent) Headers() Headers {
+	if m := e.Message(); m != nil {
+		return m.Headers
+	}
+	return nil
+}
+
+func (e *rawEvent) Body() []byte {
+	if m := e.Message(); m != nil {
+		b, _ := m.Body.([]byte)
+		return b
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package broker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type rawRecordBroker struct {
+	*recordBroker
+}
+
+func (b *rawRecordBroker) SubscribeRaw(topic string, handler RawHandler, opts ...SubscribeOption) (Subscriber, error) {
+	return b.Subscribe(topic, handler.Handler(), nil, append(opts, WithRawBody())...)
+}
+
+func TestSubscribeRaw(t *testing.T) {
+	_, err := SubscribeRaw(newRecordBroker("test"), "orders", nil)
+	assert.ErrorIs(t, err, ErrRawUnsupported)
+
+	rb := &rawRecordBroker{recordBroker: newRecordBroker("test")}
+
+	var received RawEvent
+	sub, err := SubscribeRaw(rb, "orders", func(_ context.Context, evt RawEvent) error {
+		received = evt
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.True(t, sub.Options().RawBody)
+
+	evt := &testEvent{topic: "orders", message: &Message{
+		Headers: Headers{"content-type": "application/x-custom"},
+		Body:    []byte{0x01, 0x02},
+	}}
+	assert.Nil(t, rb.handlers["orders"](context.Background(), evt))
+
+	assert.Equal(t, "orders", received.Topic())
+	assert.Equal(t, "application/x-custom", received.Headers()["content-type"])
+	assert.Equal(t, []byte{0x01, 0x02}, received.Body())
+}
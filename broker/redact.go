@@ -0,0 +1,103 @@
+package broker
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// RedactedValue replaces masked values.
+const RedactedValue = "***"
+
+// Redactor masks sensitive data before it is emitted to logs or traces.
+type Redactor interface {
+	RedactHeaders(headers Headers) Headers
+
+	// RedactBody returns a masked copy of body, it must not modify body.
+	RedactBody(body Any) Any
+}
+
+type keyRedactor struct {
+	keys map[string]struct{}
+}
+
+// NewKeyRedactor masks headers and body fields whose name matches one of
+// keys, case-insensitively. Bodies are inspected when they are maps or JSON
+// documents, any other body is replaced entirely.
+func NewKeyRedactor(keys ...string) Redactor {
+	r := &keyRedactor{keys: make(map[string]struct{}, len(keys))}
+	for _, k := range keys {
+		r.keys[strings.ToLower(k)] = struct{}{}
+	}
+	return r
+}
+
+func (r *keyRedactor) match(key string) bool {
+	_, ok := r.keys[strings.ToLower(key)]
+	return ok
+}
+
+func (r *keyRedactor) RedactHeaders(headers Headers) Headers {
+	if headers == nil {
+		return nil
+	}
+
+	out := make(Headers, len(headers))
+	for k, v := range headers {
+		if r.match(k) {
+			v = RedactedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func (r *keyRedactor) RedactBody(body Any) Any {
+	switch t := body.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		return r.redactValue(t)
+	case []byte, string:
+		var raw []byte
+		if s, ok := t.(string); ok {
+			raw = []byte(s)
+		} else {
+			raw = t.([]byte)
+		}
+
+		var doc interface{}
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return RedactedValue
+		}
+		out, err := json.Marshal(r.redactValue(doc))
+		if err != nil {
+			return RedactedValue
+		}
+		return string(out)
+	default:
+		return RedactedValue
+	}
+}
+
+func (r *keyRedactor) redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			if r.match(k) {
+				out[k] = RedactedValue
+			} else {
+				out[k] = r.redactValue(val)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = r.redactValue(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
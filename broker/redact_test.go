@@ -0,0 +1,24 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRedactor(t *testing.T) {
+	r := NewKeyRedactor("Authorization", "password")
+
+	headers := Headers{"authorization": "Bearer x", "trace": "abc"}
+	assert.Equal(t, Headers{"authorization": RedactedValue, "trace": "abc"}, r.RedactHeaders(headers))
+	assert.Equal(t, "Bearer x", headers["authorization"])
+
+	assert.JSONEq(t,
+		`{"user":{"name":"bob","password":"***"},"items":[{"Password":"***"}]}`,
+		r.RedactBody([]byte(`{"user":{"name":"bob","password":"secret"},"items":[{"Password":"x"}]}`)).(string),
+	)
+
+	assert.Equal(t, map[string]interface{}{"password": RedactedValue}, r.RedactBody(map[string]interface{}{"password": "secret"}))
+	assert.Equal(t, RedactedValue, r.RedactBody("not json"))
+	assert.Equal(t, RedactedValue, r.RedactBody(struct{}{}))
+}
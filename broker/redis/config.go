@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed redis configuration of a broker, the fields are the
+// ones set one by one with the broker options of the package.
+type Config struct {
+	MaxIdle        int
+	MaxActive      int
+	IdleTimeout    time.Duration
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+}
+
+// DefaultConfig return the configuration of a broker without options.
+func DefaultConfig() Config {
+	return Config{
+		MaxIdle:        DefaultMaxIdle,
+		MaxActive:      DefaultMaxActive,
+		IdleTimeout:    DefaultIdleTimeout,
+		ConnectTimeout: DefaultConnectTimeout,
+		ReadTimeout:    DefaultReadTimeout,
+		WriteTimeout:   DefaultWriteTimeout,
+	}
+}
+
+// WithConfig set the whole configuration at once, c should start from
+// DefaultConfig since every field is applied. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, optionsKey, &commonOptions{
+			maxIdle:        c.MaxIdle,
+			maxActive:      c.MaxActive,
+			idleTimeout:    c.IdleTimeout,
+			connectTimeout: c.ConnectTimeout,
+			readTimeout:    c.ReadTimeout,
+			writeTimeout:   c.WriteTimeout,
+		})
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	if opts.Context == nil {
+		return DefaultConfig()
+	}
+
+	v, ok := opts.Context.Value(optionsKey).(*commonOptions)
+	if !ok {
+		return DefaultConfig()
+	}
+	return Config{
+		MaxIdle:        v.maxIdle,
+		MaxActive:      v.maxActive,
+		IdleTimeout:    v.idleTimeout,
+		ConnectTimeout: v.connectTimeout,
+		ReadTimeout:    v.readTimeout,
+		WriteTimeout:   v.writeTimeout,
+	}
+}
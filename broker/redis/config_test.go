@@ -0,0 +1,31 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultConfig(), ConfigFromOptions(broker.NewOptions()))
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithMaxIdle(8),
+		WithMaxActive(16),
+		WithConnectTimeout(time.Second),
+	))
+	assert.Equal(t, Config{MaxIdle: 8, MaxActive: 16, ConnectTimeout: time.Second}, legacy)
+
+	c := DefaultConfig()
+	c.MaxIdle = 8
+	c.ReadTimeout = time.Minute
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c), WithMaxIdle(4)))
+	assert.Equal(t, 4, overridden.MaxIdle)
+	assert.Equal(t, time.Minute, overridden.ReadTimeout)
+}
@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/archive"
+	"github.com/tx7do/kratos-transport/broker/compression"
+	"github.com/tx7do/kratos-transport/broker/mirror"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+	"github.com/tx7do/kratos-transport/broker/pipeline"
+	"github.com/tx7do/kratos-transport/broker/reliable"
+	"github.com/tx7do/kratos-transport/broker/timer"
+)
+
+// TestHeaderDependentWrappers check the wrappers reading their state back
+// from the headers refuse the driver, whose messages have none.
+func TestHeaderDependentWrappers(t *testing.T) {
+	ctx := context.Background()
+
+	b := compression.NewBroker(NewBroker(), compression.WithCodec("json"))
+	assert.ErrorIs(t, b.Connect(), broker.ErrHeadersUnsupported)
+	assert.ErrorIs(t, b.Publish(ctx, "orders", "hello"), broker.ErrHeadersUnsupported)
+
+	event := mocks.NewEvent("orders", "hello", nil)
+	assert.ErrorIs(t, broker.Republish(ctx, NewBroker(), event, "orders.retry"), broker.ErrHeadersUnsupported)
+
+	sb := NewBroker(broker.WithStandardHeaders())
+	assert.ErrorIs(t, sb.Publish(ctx, "orders", "hello", broker.WithIdempotencyKey("k1")), broker.ErrHeadersUnsupported)
+	deadline, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	assert.ErrorIs(t, sb.Publish(deadline, "orders", "hello"), broker.ErrHeadersUnsupported)
+
+	var retries int
+	rb := reliable.NewBroker(NewBroker(), reliable.WithRetryCallback(func(string, int, error) { retries++ }))
+	assert.ErrorIs(t, rb.Publish(ctx, "orders", "hello", broker.WithHeaders(broker.Headers{"x-tenant": "acme"})), broker.ErrHeadersUnsupported)
+	assert.Zero(t, retries)
+
+	p := pipeline.Source(NewBroker(), "orders", nil, pipeline.WithErrorTopic("orders.errors")).Sink("orders.out")
+	assert.ErrorIs(t, p.Run(), broker.ErrHeadersUnsupported)
+
+	d := timer.NewDurableTimer(NewBroker())
+	_, err := d.After(ctx, "orders.timeout", "hello", time.Minute, timer.WithHeaders(broker.Headers{"x-tenant": "acme"}))
+	assert.ErrorIs(t, err, broker.ErrHeadersUnsupported)
+
+	sink := mirror.TopicSink(NewBroker(), "debug")
+	assert.ErrorIs(t, sink(ctx, &mirror.Message{Topic: "orders", Body: "hello"}), broker.ErrHeadersUnsupported)
+
+	// the archived headers are dropped, the replay reaches the server.
+	var segment bytes.Buffer
+	w := archive.JSONLines.NewWriter(&segment)
+	assert.NoError(t, w.Write(&archive.Record{Topic: "orders", Headers: broker.Headers{"x-tenant": "acme"}, Body: []byte("hello")}))
+	rp := NewBroker(broker.WithAddress("127.0.0.1:1"))
+	assert.NoError(t, rp.Connect())
+	err = archive.Replay(ctx, rp, &segment, archive.JSONLines, archive.CompressionNone)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, broker.ErrHeadersUnsupported)
+
+	// the oversize messages are parked without their headers.
+	var parkErr error
+	so := broker.NewSubscribeOptions(
+		broker.WithMaxReceiveSize(4),
+		broker.WithParkOversize(rp, "orders.oversize"),
+		broker.WithSubscribeErrorHandler(func(_ context.Context, err error, _ broker.Event) { parkErr = err }),
+	)
+	headers := broker.Headers{"x-tenant": "acme"}
+	assert.Equal(t, broker.UnmarshalActionRetry, so.HandleUnmarshalFailure(ctx, "orders", headers, []byte("hello"), broker.ErrPayloadTooLarge))
+	assert.Error(t, parkErr)
+	assert.NotErrorIs(t, parkErr, broker.ErrHeadersUnsupported)
+}
@@ -3,6 +3,8 @@ package redis
 import "github.com/tx7do/kratos-transport/broker"
 
 type publication struct {
+	broker.AckState
+
 	topic   string
 	message *broker.Message
 	err     error
@@ -21,7 +23,7 @@ func (p *publication) RawMessage() interface{} {
 }
 
 func (p *publication) Ack() error {
-	return nil
+	return p.AckState.Ack(nil)
 }
 
 func (p *publication) Error() error {
@@ -0,0 +1,22 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func TestSubscribePath(t *testing.T) {
+	mocks.TestSubscribePath(t, func(opts []broker.Option, topic string, _ broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) {
+		s := &subscriber{
+			b:       NewBroker(opts...).(*redisBroker),
+			topic:   topic,
+			handler: handler,
+			binder:  binder,
+			options: broker.NewSubscribeOptions(sopts...),
+		}
+
+		_ = s.onMessage(topic, body)
+	}, false)
+}
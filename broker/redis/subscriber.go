@@ -3,7 +3,6 @@ package redis
 import (
 	"errors"
 	"sync"
-	"time"
 
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/gomodule/redigo/redis"
@@ -34,7 +33,7 @@ func (s *subscriber) onStart() error {
 func (s *subscriber) onMessage(channel string, data []byte) error {
 	var m broker.Message
 
-	if s.binder != nil {
+	if s.binder != nil && !s.options.RawBody {
 		m.Body = s.binder()
 	} else {
 		m.Body = data
@@ -45,8 +44,17 @@ func (s *subscriber) onMessage(channel string, data []byte) error {
 		message: &m,
 	}
 
-	if p.err = broker.Unmarshal(s.b.options.Codec, data, &m.Body); p.err != nil {
-		//log.Error("[redis]", err)
+	if p.err = s.b.options.Decode(&s.options, nil, data, &m.Body); p.err != nil {
+		log.Errorf("[redis] unmarshal message failed: %v", p.err)
+		s.options.ReportError(s.options.Context, broker.ErrUnmarshal, p.err, &p)
+
+		// redis pub/sub does not redeliver, retry drops the message as well.
+		switch s.options.HandleUnmarshalFailure(s.options.Context, channel, nil, data, p.err) {
+		case broker.UnmarshalActionAck:
+			_ = p.Ack()
+		case broker.UnmarshalActionStop:
+			_ = s.Unsubscribe(true)
+		}
 		return p.err
 	}
 
@@ -55,7 +63,8 @@ func (s *subscriber) onMessage(channel string, data []byte) error {
 	}
 
 	if s.options.AutoAck {
-		if p.err = p.Ack(); p.err != nil {
+		if p.err = broker.AutoAck(&p); p.err != nil {
+			s.options.ReportError(s.options.Context, broker.ErrAck, p.err, &p)
 			return p.err
 		}
 	}
@@ -84,13 +93,13 @@ func (s *subscriber) recv() {
 
 	s.done = make(chan error, 1)
 
-	ticker := time.NewTicker(DefaultHealthCheckPeriod)
+	ticker := s.b.options.Clock.NewTicker(DefaultHealthCheckPeriod)
 	defer ticker.Stop()
 
 	go func() {
 		for {
 			select {
-			case <-ticker.C:
+			case <-ticker.C():
 				if err := s.ping(); err != nil {
 					s.done <- err
 					return
@@ -108,13 +117,17 @@ func (s *subscriber) recv() {
 		switch x := s.conn.Receive().(type) {
 		case error:
 			log.Errorf("[redis] recv error: %s\n", x.Error())
+			if s.options.Context.Err() == nil && !s.IsClosed() {
+				s.options.ReportError(s.options.Context, broker.ErrReceive, x, nil)
+			}
 			s.done <- x
 			return
 
 		case redis.Message:
+			// done holds a single error and is not drained, a failed
+			// message must not block the next ones.
 			if err := s.onMessage(x.Channel, x.Data); err != nil {
-				s.done <- err
-				break
+				log.Errorf("[redis] handle message failed: %v", err)
 			}
 
 		case redis.Subscription:
@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/tx7do/kratos-transport/broker/pipeline"
+)
+
+var _ pipeline.StateStore = (*WindowStateStore)(nil)
+
+// WindowStateStore keeps the open windows of a pipeline in one redis hash,
+// so aggregations survive restarts and can be shared by several instances.
+type WindowStateStore struct {
+	pool *redis.Pool
+	key  string
+}
+
+func NewWindowStateStore(pool *redis.Pool, key string) *WindowStateStore {
+	return &WindowStateStore{
+		pool: pool,
+		key:  key,
+	}
+}
+
+func (s *WindowStateStore) Get(ctx context.Context, field string) ([]byte, bool, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer conn.Close()
+
+	value, err := redis.Bytes(redis.DoContext(conn, ctx, "HGET", s.key, field))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+func (s *WindowStateStore) Put(ctx context.Context, field string, value []byte) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "HSET", s.key, field, value)
+	return err
+}
+
+func (s *WindowStateStore) Delete(ctx context.Context, field string) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "HDEL", s.key, field)
+	return err
+}
+
+func (s *WindowStateStore) Keys(ctx context.Context) ([]string, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return redis.Strings(redis.DoContext(conn, ctx, "HKEYS", s.key))
+}
@@ -0,0 +1,67 @@
+package reliable
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(b *reliableBroker)
+
+// WithClock set the clock of the backoff, broker.SystemClock by default.
+func WithClock(clock broker.Clock) Option {
+	return func(b *reliableBroker) {
+		b.clock = clock
+	}
+}
+
+// WithAttempts set how many times a message is sent at most, default is 3.
+func WithAttempts(n int) Option {
+	return func(b *reliableBroker) {
+		if n < 1 {
+			n = 1
+		}
+		b.attempts = n
+	}
+}
+
+// WithBackoff set the first resend delay and its upper bound, the delay
+// doubles after every attempt. Default is 100ms up to 5s.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(b *reliableBroker) {
+		b.backoff = initial
+		b.maxBackoff = max
+	}
+}
+
+// WithOnNack set what a nacked publish does, ActionRetry by default.
+func WithOnNack(action Action) Option {
+	return func(b *reliableBroker) {
+		b.onNack = action
+	}
+}
+
+// WithOnReturn set what a returned publish does, ActionRetry by default.
+// Resending an unroutable message only helps when its queue is about to be
+// declared.
+func WithOnReturn(action Action) Option {
+	return func(b *reliableBroker) {
+		b.onReturn = action
+	}
+}
+
+// WithRetryable decide which of the failures other than the nacks and the
+// returns are resent, all of them by default. The canceled publishes are
+// never resent.
+func WithRetryable(fn func(err error) bool) Option {
+	return func(b *reliableBroker) {
+		b.retryable = fn
+	}
+}
+
+// WithRetryCallback observe the failed attempts about to be resent.
+func WithRetryCallback(fn func(topic string, attempt int, err error)) Option {
+	return func(b *reliableBroker) {
+		b.onRetry = fn
+	}
+}
@@ -0,0 +1,138 @@
+package reliable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/google/uuid"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// MessageIDHeader carries the id shared by the attempts of a message, the
+// header exactlyonce deduplicates on too.
+const MessageIDHeader = "x-message-id"
+
+var ErrPublishExhausted = errors.New("publish attempts exhausted")
+
+// Action is what a publish does on a nack or a return.
+type Action int
+
+const (
+	// ActionRetry resend the message, with the other failures.
+	ActionRetry Action = iota
+	// ActionFail return the failure right away.
+	ActionFail
+)
+
+const (
+	defaultAttempts   = 3
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+)
+
+type reliableBroker struct {
+	broker.Broker
+
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	onNack     Action
+	onReturn   Action
+	retryable  func(err error) bool
+	onRetry    func(topic string, attempt int, err error)
+	clock      broker.Clock
+
+	// headers is false when b drops the headers, hence the id.
+	headers bool
+}
+
+// NewBroker wraps b to resend the failed publishes, up to a bounded number
+// of attempts with an exponential backoff. The attempts of a message share
+// an id in MessageIDHeader, the consumers deduplicate the resends of the
+// messages which reached the broker but whose outcome was lost.
+//
+// The nacks and the returns are told apart from the other failures by
+// broker.ErrPublishNacked and broker.ErrPublishReturned, reported by the
+// brokers waiting for the confirms, e.g. rabbitmq.WithPublisherConfirms.
+// An asynchronous producer reports no outcome, its publishes are only
+// retried when they cannot be queued.
+//
+// The brokers whose messages have no headers, see broker.CarriesHeaders, get
+// no id: their publishes are still resent, but the resends cannot be told
+// apart from new messages.
+func NewBroker(b broker.Broker, opts ...Option) broker.Broker {
+	rb := &reliableBroker{
+		Broker:     b,
+		attempts:   defaultAttempts,
+		backoff:    defaultBackoff,
+		maxBackoff: defaultMaxBackoff,
+		clock:      broker.SystemClock,
+		headers:    broker.CarriesHeaders(b),
+	}
+
+	for _, o := range opts {
+		o(rb)
+	}
+
+	return rb
+}
+
+func (b *reliableBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	if b.headers && broker.NewPublishOptions(opts...).Headers[MessageIDHeader] == "" {
+		opts = append(opts, broker.WithHeaders(broker.Headers{MessageIDHeader: uuid.New().String()}))
+	}
+
+	backoff := b.backoff
+
+	for attempt := 1; ; attempt++ {
+		err := b.Broker.Publish(ctx, topic, msg, opts...)
+		if err == nil {
+			return nil
+		}
+		if !b.retry(err) {
+			return err
+		}
+		if attempt >= b.attempts {
+			return fmt.Errorf("%w after %d attempts: %w", ErrPublishExhausted, attempt, err)
+		}
+
+		log.Warnf("[reliable] publish [%s] failed, attempt %d: %v", topic, attempt, err)
+		if b.onRetry != nil {
+			b.onRetry(topic, attempt, err)
+		}
+
+		timer := b.clock.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+
+		backoff *= 2
+		if backoff > b.maxBackoff {
+			backoff = b.maxBackoff
+		}
+	}
+}
+
+// retry report whether a failed publish may be resent.
+func (b *reliableBroker) retry(err error) bool {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, broker.ErrHeadersUnsupported):
+		return false
+	case errors.Is(err, broker.ErrPublishNacked):
+		return b.onNack == ActionRetry
+	case errors.Is(err, broker.ErrPublishReturned):
+		return b.onReturn == ActionRetry
+	case b.retryable != nil:
+		return b.retryable(err)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,105 @@
+package reliable
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+// newFakeBroker return a broker failing the publishes to orders with errs in turn.
+func newFakeBroker(t *testing.T, errs ...error) *mocks.FakeBroker {
+	b := mocks.NewFakeBroker()
+	assert.Nil(t, b.Connect())
+	for _, err := range errs {
+		b.ExpectPublish("orders").Return(err)
+	}
+	return b
+}
+
+// ids return the message id of every publish attempt.
+func ids(b *mocks.FakeBroker) []string {
+	var ids []string
+	for _, p := range b.Published() {
+		ids = append(ids, p.Headers[MessageIDHeader])
+	}
+	return ids
+}
+
+func TestNewBroker(t *testing.T) {
+	ctx := context.Background()
+	mb := newFakeBroker(t, errors.New("connection reset"), broker.ErrPublishNacked)
+
+	var retries []int
+	b := NewBroker(mb,
+		WithBackoff(time.Millisecond, time.Millisecond),
+		WithRetryCallback(func(_ string, attempt int, _ error) { retries = append(retries, attempt) }),
+	)
+
+	assert.Nil(t, b.Publish(ctx, "orders", "order"))
+	assert.Equal(t, []int{1, 2}, retries)
+
+	// every attempt carries the same id.
+	sent := ids(mb)
+	assert.Len(t, sent, 3)
+	assert.NotEmpty(t, sent[0])
+	assert.Equal(t, sent[0], sent[1])
+	assert.Equal(t, sent[0], sent[2])
+}
+
+func TestNewBroker_Exhausted(t *testing.T) {
+	ctx := context.Background()
+	mb := newFakeBroker(t, broker.ErrPublishNacked, broker.ErrPublishNacked, broker.ErrPublishNacked)
+
+	b := NewBroker(mb, WithAttempts(2), WithBackoff(time.Millisecond, time.Millisecond))
+
+	err := b.Publish(ctx, "orders", "order")
+	assert.ErrorIs(t, err, ErrPublishExhausted)
+	assert.ErrorIs(t, err, broker.ErrPublishNacked)
+	assert.Len(t, ids(mb), 2)
+}
+
+func TestNewBroker_Fail(t *testing.T) {
+	ctx := context.Background()
+
+	mb := newFakeBroker(t, broker.ErrPublishReturned)
+	b := NewBroker(mb, WithOnReturn(ActionFail))
+	assert.ErrorIs(t, b.Publish(ctx, "orders", "order"), broker.ErrPublishReturned)
+	assert.Len(t, ids(mb), 1)
+
+	permanent := errors.New("message too large")
+	mb = newFakeBroker(t, permanent)
+	b = NewBroker(mb, WithRetryable(func(err error) bool { return !errors.Is(err, permanent) }))
+	assert.ErrorIs(t, b.Publish(ctx, "orders", "order"), permanent)
+	assert.Len(t, ids(mb), 1)
+
+	// the id of the caller is kept.
+	mb = newFakeBroker(t)
+	b = NewBroker(mb)
+	assert.Nil(t, b.Publish(ctx, "orders", "order", broker.WithHeaders(broker.Headers{MessageIDHeader: "order-1"})))
+	assert.Equal(t, []string{"order-1"}, ids(mb))
+}
+
+func TestNewBroker_Headerless(t *testing.T) {
+	ctx := context.Background()
+	hb := mocks.NewHeaderlessBroker()
+	assert.Nil(t, hb.Connect())
+	hb.ExpectPublish("orders").Return(errors.New("connection reset"))
+
+	b := NewBroker(hb, WithBackoff(time.Millisecond, time.Millisecond))
+
+	// resent without an id, the broker would refuse it.
+	assert.Nil(t, b.Publish(ctx, "orders", "order"))
+	assert.Equal(t, []string{"", ""}, ids(hb.FakeBroker))
+
+	// a refused header is not worth a retry.
+	hb.Reset()
+	err := b.Publish(ctx, "orders", "order", broker.WithHeaders(broker.Headers{"x-tenant": "acme"}))
+	assert.ErrorIs(t, err, broker.ErrHeadersUnsupported)
+	assert.Empty(t, hb.Published())
+}
@@ -0,0 +1,71 @@
+package replay
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(g *Guard)
+
+// WithWindow set how old a message may be, default is 5 minutes. The nonces
+// are remembered for as long.
+func WithWindow(d time.Duration) Option {
+	return func(g *Guard) {
+		g.window = d
+	}
+}
+
+// WithSkewTolerance set how far in the future the timestamp of a message may
+// be, for the clock skew between the publishers and the consumers, default
+// is 30s.
+func WithSkewTolerance(d time.Duration) Option {
+	return func(g *Guard) {
+		g.skew = d
+	}
+}
+
+// WithStore set the store of the nonces, default is a MemoryStore.
+func WithStore(store Store) Option {
+	return func(g *Guard) {
+		g.store = store
+	}
+}
+
+// WithRequireNonce reject the messages without NonceHeader, by default only
+// their freshness is checked.
+func WithRequireNonce() Option {
+	return func(g *Guard) {
+		g.requireNonce = true
+	}
+}
+
+// WithTimestampHeader set the header of the publish time in Unix
+// milliseconds, default is broker.PublishTimeHeader.
+func WithTimestampHeader(name string) Option {
+	return func(g *Guard) {
+		g.timestampHeader = name
+	}
+}
+
+// WithNonceHeader set the header of the nonce, default is NonceHeader.
+func WithNonceHeader(name string) Option {
+	return func(g *Guard) {
+		g.nonceHeader = name
+	}
+}
+
+// WithRejectHandler set the function called with the rejected messages
+// before they are acked, default logs them.
+func WithRejectHandler(fn RejectHandler) Option {
+	return func(g *Guard) {
+		g.onReject = fn
+	}
+}
+
+// WithClock set the clock the freshness is measured with.
+func WithClock(clock broker.Clock) Option {
+	return func(g *Guard) {
+		g.clock = clock
+	}
+}
@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/tx7do/kratos-transport/broker/replay"
+)
+
+const defaultPrefix = "replay:"
+
+var _ replay.Store = (*Store)(nil)
+
+type StoreOption func(s *Store)
+
+// WithPrefix set the prefix of all keys, default is "replay:".
+func WithPrefix(prefix string) StoreOption {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// Store remembers the nonces in redis, shared by the instances of a
+// consumer group, as {prefix}{topic}:{nonce} keys expiring with the window.
+type Store struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+func NewStore(pool *redis.Pool, opts ...StoreOption) *Store {
+	s := &Store{
+		pool:   pool,
+		prefix: defaultPrefix,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+func (s *Store) Remember(ctx context.Context, key string, expiry time.Time) (bool, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	ttl := time.Until(expiry).Milliseconds()
+	if ttl < 1 {
+		ttl = 1
+	}
+
+	reply, err := redis.DoContext(conn, ctx, "SET", s.prefix+key, 1, "PX", ttl, "NX")
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+func (s *Store) Forget(ctx context.Context, key string) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "DEL", s.prefix+key)
+	return err
+}
@@ -0,0 +1,33 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/internal/redistest"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(redistest.NewPool(t), WithPrefix("replay-test:"))
+	defer store.Forget(ctx, "commands:n1")
+
+	ok, err := store.Remember(ctx, "commands:n1", time.Now().Add(time.Second))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = store.Remember(ctx, "commands:n1", time.Now().Add(time.Second))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, store.Forget(ctx, "commands:n1"))
+	ok, _ = store.Remember(ctx, "commands:n1", time.Now().Add(50*time.Millisecond))
+	assert.True(t, ok)
+
+	time.Sleep(60 * time.Millisecond)
+	ok, _ = store.Remember(ctx, "commands:n1", time.Now().Add(time.Second))
+	assert.True(t, ok)
+}
@@ -0,0 +1,141 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/google/uuid"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+const NonceHeader = "x-nonce"
+
+var (
+	ErrMissingTimestamp = errors.New("replay: message has no timestamp")
+	ErrMissingNonce     = errors.New("replay: message has no nonce")
+	ErrStale            = errors.New("replay: message outside the freshness window")
+	ErrReplayed         = errors.New("replay: nonce already seen")
+)
+
+// RejectHandler is called with a message rejected by the Guard and the reason.
+type RejectHandler func(ctx context.Context, event broker.Event, err error)
+
+// Stamp stamp the published messages with their publish time and a random
+// NonceHeader, for the Guard of the consumers.
+func Stamp() broker.Option {
+	return func(o *broker.Options) {
+		broker.WithPublishTimestamp()(o)
+		o.PublishInterceptors = append(o.PublishInterceptors, func(_ context.Context, _ string, msg *broker.Message) error {
+			if _, ok := msg.Headers[NonceHeader]; !ok {
+				msg.Headers[NonceHeader] = uuid.NewString()
+			}
+			return nil
+		})
+	}
+}
+
+// Guard protects the command topics against the replayed and the severely
+// delayed messages: it rejects those whose timestamp is outside the
+// freshness window, and those whose nonce was already seen within it. The
+// rejected messages are acked without running the handler, so they are not
+// redelivered.
+type Guard struct {
+	window          time.Duration
+	skew            time.Duration
+	store           Store
+	requireNonce    bool
+	timestampHeader string
+	nonceHeader     string
+	onReject        RejectHandler
+	clock           broker.Clock
+}
+
+func NewGuard(opts ...Option) *Guard {
+	g := &Guard{
+		window:          5 * time.Minute,
+		skew:            30 * time.Second,
+		timestampHeader: broker.PublishTimeHeader,
+		nonceHeader:     NonceHeader,
+		onReject: func(_ context.Context, event broker.Event, err error) {
+			log.Warnf("[replay] reject message of [%s]: %v", event.Topic(), err)
+		},
+		clock: broker.SystemClock,
+	}
+
+	for _, o := range opts {
+		o(g)
+	}
+
+	if g.store == nil {
+		g.store = NewMemoryStore()
+	}
+
+	return g
+}
+
+// Handler wraps handler to run it with the accepted messages only. The
+// nonce of a message is forgotten when handler fails, so its redelivery is
+// accepted.
+func (g *Guard) Handler(handler broker.Handler) broker.Handler {
+	return func(ctx context.Context, event broker.Event) error {
+		key, err := g.Check(ctx, event)
+		switch {
+		case errors.Is(err, ErrMissingTimestamp), errors.Is(err, ErrMissingNonce),
+			errors.Is(err, ErrStale), errors.Is(err, ErrReplayed):
+			if g.onReject != nil {
+				g.onReject(ctx, event, err)
+			}
+			return broker.AutoAck(event)
+		case err != nil:
+			return err
+		}
+
+		if err = handler(ctx, event); err != nil && key != "" {
+			if fErr := g.store.Forget(ctx, key); fErr != nil {
+				log.Errorf("[replay] forget nonce of [%s] failed: %v", event.Topic(), fErr)
+			}
+		}
+		return err
+	}
+}
+
+// Check check the freshness and the nonce of event, and remember the nonce.
+// It return the key of the nonce in the store, empty when it has none.
+func (g *Guard) Check(ctx context.Context, event broker.Event) (string, error) {
+	var headers broker.Headers
+	if event.Message() != nil {
+		headers = event.Message().Headers
+	}
+
+	ms, err := headers.GetInt(g.timestampHeader)
+	if err != nil {
+		return "", ErrMissingTimestamp
+	}
+	published := time.UnixMilli(ms)
+
+	age := g.clock.Since(published)
+	if age > g.window || -age > g.skew {
+		return "", ErrStale
+	}
+
+	nonce := headers[g.nonceHeader]
+	if nonce == "" {
+		if g.requireNonce {
+			return "", ErrMissingNonce
+		}
+		return "", nil
+	}
+
+	key := event.Topic() + ":" + nonce
+	ok, err := g.store.Remember(ctx, key, published.Add(g.window))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrReplayed
+	}
+	return key, nil
+}
@@ -0,0 +1,104 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func newEvent(published time.Time, nonce string) *mocks.Event {
+	headers := broker.Headers{broker.PublishTimeHeader: strconv.FormatInt(published.UnixMilli(), 10)}
+	if nonce != "" {
+		headers[NonceHeader] = nonce
+	}
+	return mocks.NewEvent("commands", "{}", headers)
+}
+
+func TestGuard(t *testing.T) {
+	clock := broker.NewFakeClock(time.Now())
+
+	var rejected []error
+	g := NewGuard(
+		WithClock(clock),
+		WithWindow(time.Minute),
+		WithSkewTolerance(time.Second),
+		WithRejectHandler(func(_ context.Context, _ broker.Event, err error) {
+			rejected = append(rejected, err)
+		}),
+	)
+
+	handled := 0
+	handler := g.Handler(func(context.Context, broker.Event) error {
+		handled++
+		return nil
+	})
+	ctx := context.Background()
+
+	assert.Nil(t, handler(ctx, newEvent(clock.Now(), "n1")))
+	assert.Nil(t, handler(ctx, newEvent(clock.Now().Add(-30*time.Second), "")))
+	assert.Equal(t, 2, handled)
+
+	replayed := newEvent(clock.Now(), "n1")
+	assert.Nil(t, handler(ctx, replayed))
+	assert.True(t, replayed.IsAcked())
+
+	assert.Nil(t, handler(ctx, newEvent(clock.Now().Add(-2*time.Minute), "n2")))
+	assert.Nil(t, handler(ctx, newEvent(clock.Now().Add(time.Minute), "n3")))
+	assert.Nil(t, handler(ctx, mocks.NewEvent("commands", "{}", broker.Headers{})))
+
+	assert.Equal(t, 2, handled)
+	if assert.Len(t, rejected, 4) {
+		assert.ErrorIs(t, rejected[0], ErrReplayed)
+		assert.ErrorIs(t, rejected[1], ErrStale)
+		assert.ErrorIs(t, rejected[2], ErrStale)
+		assert.ErrorIs(t, rejected[3], ErrMissingTimestamp)
+	}
+
+	// the same nonce on another topic is accepted.
+	other := newEvent(clock.Now(), "n1")
+	other.TopicName = "events"
+	assert.Nil(t, handler(ctx, other))
+	assert.Equal(t, 3, handled)
+}
+
+func TestGuard_RequireNonce(t *testing.T) {
+	g := NewGuard(WithRequireNonce())
+
+	_, err := g.Check(context.Background(), newEvent(time.Now(), ""))
+	assert.ErrorIs(t, err, ErrMissingNonce)
+}
+
+func TestGuard_ForgetOnFailure(t *testing.T) {
+	store := NewMemoryStore()
+	g := NewGuard(WithStore(store))
+
+	fail := errors.New("failed")
+	handler := g.Handler(func(context.Context, broker.Event) error { return fail })
+
+	published := time.Now()
+	assert.ErrorIs(t, handler(context.Background(), newEvent(published, "n1")), fail)
+	assert.Equal(t, 0, store.Len())
+
+	// the redelivery is accepted.
+	assert.ErrorIs(t, handler(context.Background(), newEvent(published, "n1")), fail)
+}
+
+func TestStamp(t *testing.T) {
+	o := broker.NewOptionsAndApply(Stamp())
+
+	_, opts, err := o.InterceptPublish(context.Background(), "commands", []byte("{}"), nil)
+	assert.Nil(t, err)
+
+	headers := broker.NewPublishOptions(opts...).Headers
+	assert.NotEmpty(t, headers[NonceHeader])
+
+	_, err = NewGuard().Check(context.Background(), mocks.NewEvent("commands", "{}", headers))
+	assert.Nil(t, err)
+}
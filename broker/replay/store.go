@@ -0,0 +1,70 @@
+package replay
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store remembers the nonces seen within the freshness window.
+type Store interface {
+	// Remember record key until expiry, false when it is recorded already.
+	Remember(ctx context.Context, key string, expiry time.Time) (bool, error)
+	// Forget remove key, so the message is accepted when redelivered.
+	Forget(ctx context.Context, key string) error
+}
+
+const sweepInterval = 1024
+
+// MemoryStore remembers the nonces of a single process.
+type MemoryStore struct {
+	sync.Mutex
+
+	keys    map[string]time.Time
+	inserts int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		keys: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryStore) Remember(_ context.Context, key string, expiry time.Time) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now()
+	if e, ok := s.keys[key]; ok && e.After(now) {
+		return false, nil
+	}
+	s.keys[key] = expiry
+
+	s.inserts++
+	if s.inserts >= sweepInterval {
+		s.inserts = 0
+		for k, e := range s.keys {
+			if !e.After(now) {
+				delete(s.keys, k)
+			}
+		}
+	}
+	return true, nil
+}
+
+func (s *MemoryStore) Forget(_ context.Context, key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.keys, key)
+	return nil
+}
+
+// Len return the number of nonces remembered, including the expired ones
+// not swept yet.
+func (s *MemoryStore) Len() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.keys)
+}
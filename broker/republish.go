@@ -0,0 +1,130 @@
+package broker
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// The headers set by Republish.
+const (
+	// EscalationHeader is the escalation level of the message, 0 when unset.
+	EscalationHeader = "x-escalation-level"
+	// DelayHeader is the delay of the delivery in milliseconds, honored by
+	// the RabbitMQ delayed message exchanges.
+	DelayHeader = "x-delay"
+	// OriginTopicHeader is the topic the message was first published to.
+	OriginTopicHeader = "x-origin-topic"
+	// RepublishCountHeader is the number of times the message was republished.
+	RepublishCountHeader = "x-republish-count"
+)
+
+type RepublishOption func(o *republishOptions)
+
+type republishOptions struct {
+	headers    Headers
+	escalation func(level int) int
+	delay      time.Duration
+	publish    []PublishOption
+}
+
+// WithRepublishHeaders set headers on the republished message, over the original ones.
+func WithRepublishHeaders(headers Headers) RepublishOption {
+	return func(o *republishOptions) {
+		for k, v := range headers {
+			o.headers[k] = v
+		}
+	}
+}
+
+// WithEscalationLevel set the escalation level of the republished message.
+func WithEscalationLevel(level int) RepublishOption {
+	return func(o *republishOptions) {
+		o.escalation = func(int) int { return level }
+	}
+}
+
+// Escalate increment the escalation level of the republished message.
+func Escalate() RepublishOption {
+	return func(o *republishOptions) {
+		o.escalation = func(level int) int { return level + 1 }
+	}
+}
+
+// WithRepublishDelay set DelayHeader on the republished message. The
+// brokers with their own delay take it through WithRepublishOptions.
+func WithRepublishDelay(d time.Duration) RepublishOption {
+	return func(o *republishOptions) {
+		o.delay = d
+	}
+}
+
+// WithRepublishOptions add opts to the publish, e.g. a delay option of the driver.
+func WithRepublishOptions(opts ...PublishOption) RepublishOption {
+	return func(o *republishOptions) {
+		o.publish = append(o.publish, opts...)
+	}
+}
+
+// EscalationLevel return the escalation level of headers, 0 when unset.
+func EscalationLevel(headers Headers) int {
+	level, err := strconv.Atoi(headers[EscalationHeader])
+	if err != nil {
+		return 0
+	}
+	return level
+}
+
+// Republish publish the message of event to topic with b, e.g. to escalate
+// it or to retry it later:
+//
+//	return broker.Republish(ctx, b, event, "orders.escalated", broker.Escalate(), broker.WithRepublishDelay(time.Minute))
+//
+// The body and the headers of the message are kept, the original message
+// is left untouched, and a hop of event.Topic() is appended to its lineage.
+// The event is not acked, its handler returning nil acks it.
+//
+// The escalation level, the republish count and the lineage are headers, b
+// must carry them: the brokers whose messages have none, see CarriesHeaders,
+// are refused with ErrHeadersUnsupported.
+func Republish(ctx context.Context, b Broker, event Event, topic string, opts ...RepublishOption) error {
+	if err := RequireHeaders(b); err != nil {
+		return err
+	}
+
+	var body Any
+	headers := Headers{}
+	if m := event.Message(); m != nil {
+		body = m.Body
+		for k, v := range m.Headers {
+			headers[k] = v
+		}
+	}
+
+	from := event.Topic()
+	if _, ok := headers[OriginTopicHeader]; !ok {
+		headers[OriginTopicHeader] = from
+	}
+	AppendHop(ctx, headers, b.Options().ServiceName, from)
+	count, _ := strconv.Atoi(headers[RepublishCountHeader])
+	headers[RepublishCountHeader] = strconv.Itoa(count + 1)
+
+	o := republishOptions{headers: Headers{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.escalation != nil {
+		headers[EscalationHeader] = strconv.Itoa(o.escalation(EscalationLevel(headers)))
+	}
+	if o.delay > 0 {
+		headers[DelayHeader] = strconv.FormatInt(o.delay.Milliseconds(), 10)
+	} else {
+		delete(headers, DelayHeader)
+	}
+	for k, v := range o.headers {
+		headers[k] = v
+	}
+
+	return b.Publish(ctx, topic, body, append([]PublishOption{WithHeaders(headers)}, o.publish...)...)
+}
@@ -0,0 +1,53 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepublish(t *testing.T) {
+	pb := &parkBroker{recordBroker: *newRecordBroker("escalation")}
+	ctx := context.Background()
+
+	event := &testEvent{topic: "alerts", message: &Message{
+		Headers: Headers{"x-tenant": "acme", DelayHeader: "1000"},
+		Body:    "disk full",
+	}}
+	assert.Nil(t, Republish(ctx, pb, event, "alerts.l1", Escalate(), WithRepublishDelay(time.Minute)))
+	assert.Equal(t, []string{"alerts.l1"}, pb.published)
+
+	first := pb.headers
+	assert.Equal(t, "acme", first["x-tenant"])
+	assert.Equal(t, "alerts", first[OriginTopicHeader])
+	assert.Equal(t, []string{"alerts"}, Lineage(first))
+	assert.Equal(t, "1", first[RepublishCountHeader])
+	assert.Equal(t, 1, EscalationLevel(first))
+	assert.Equal(t, "60000", first[DelayHeader])
+	assert.Equal(t, Headers{"x-tenant": "acme", DelayHeader: "1000"}, event.message.Headers)
+
+	escalated := &testEvent{topic: "alerts.l1", message: &Message{Headers: first, Body: "disk full"}}
+	assert.Nil(t, Republish(ctx, pb, escalated, "alerts.l2", Escalate(), WithRepublishHeaders(Headers{"x-on-call": "sre"})))
+
+	second := pb.headers
+	assert.Equal(t, "alerts", second[OriginTopicHeader])
+	assert.Equal(t, []string{"alerts", "alerts.l1"}, Lineage(second))
+	assert.Equal(t, "2", second[RepublishCountHeader])
+	assert.Equal(t, 2, EscalationLevel(second))
+	assert.Equal(t, "sre", second["x-on-call"])
+	_, delayed := second[DelayHeader]
+	assert.False(t, delayed)
+
+	assert.Nil(t, Republish(ctx, pb, escalated, "alerts.retry", WithEscalationLevel(0)))
+	assert.Equal(t, 0, EscalationLevel(pb.headers))
+}
+
+func TestRepublish_Headerless(t *testing.T) {
+	event := &testEvent{topic: "alerts", message: &Message{Body: "disk full"}}
+
+	// the escalation level would be lost, and the escalation never end.
+	err := Republish(context.Background(), headerlessBroker{}, event, "alerts.l1", Escalate())
+	assert.ErrorIs(t, err, ErrHeadersUnsupported)
+}
@@ -0,0 +1,114 @@
+package aliyun
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	aliyun "github.com/aliyunmq/mq-http-go-sdk"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+const (
+	// maxAckBatch is the most receipt handles a single AckMessage accepts.
+	maxAckBatch = 16
+
+	defaultAckBatchInterval = time.Second
+)
+
+// ackBatcher groups the receipt handles acked on a consumer and acks them
+// with one request per batch, flushed when full or every interval. The
+// failed batches are passed to report, their messages are redelivered
+// after their invisible time.
+type ackBatcher struct {
+	sync.Mutex
+
+	reader  aliyun.MQConsumer
+	size    int
+	handles []string
+	closed  bool
+	report  func(err error)
+}
+
+func newAckBatcher(reader aliyun.MQConsumer, size int, report func(err error)) *ackBatcher {
+	if size > maxAckBatch {
+		size = maxAckBatch
+	}
+	return &ackBatcher{
+		reader:  reader,
+		size:    size,
+		handles: make([]string, 0, size),
+		report:  report,
+	}
+}
+
+// add queue handles, the full batches are acked at once. The handles are
+// acked right away once the batcher is closed.
+func (a *ackBatcher) add(handles ...string) {
+	a.Lock()
+	if a.closed {
+		a.Unlock()
+		a.ack(handles)
+		return
+	}
+
+	var batches [][]string
+	for _, h := range handles {
+		a.handles = append(a.handles, h)
+		if len(a.handles) >= a.size {
+			batches = append(batches, a.handles)
+			a.handles = make([]string, 0, a.size)
+		}
+	}
+	a.Unlock()
+
+	for _, batch := range batches {
+		a.ack(batch)
+	}
+}
+
+// flush ack the pending handles.
+func (a *ackBatcher) flush() {
+	a.Lock()
+	batch := a.handles
+	a.handles = make([]string, 0, a.size)
+	a.Unlock()
+
+	a.ack(batch)
+}
+
+// run flush every interval until ctx is done, then flush and close.
+func (a *ackBatcher) run(ctx context.Context, clock broker.Clock, interval time.Duration) {
+	ticker := clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.Lock()
+			a.closed = true
+			a.Unlock()
+			a.flush()
+			return
+		case <-ticker.C():
+			a.flush()
+		}
+	}
+}
+
+func (a *ackBatcher) ack(handles []string) {
+	for len(handles) > 0 {
+		n := len(handles)
+		if n > maxAckBatch {
+			n = maxAckBatch
+		}
+		if err := a.reader.AckMessage(handles[:n]); err != nil {
+			logAckError(err)
+			if a.report != nil {
+				a.report(err)
+			}
+		}
+		handles = handles[n:]
+	}
+}
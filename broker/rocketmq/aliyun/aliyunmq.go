@@ -2,6 +2,8 @@ package aliyun
 
 import (
 	"context"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +20,11 @@ import (
 	"github.com/tx7do/kratos-transport/tracing"
 )
 
+const (
+	// defaultMaxMessageSize is the body size limit of the HTTP API.
+	defaultMaxMessageSize = 256 * 1024
+)
+
 type aliyunmqBroker struct {
 	sync.RWMutex
 
@@ -41,10 +48,12 @@ type aliyunmqBroker struct {
 
 	producerTracer *tracing.Tracer
 	consumerTracer *tracing.Tracer
+
+	metrics *broker.Metrics
 }
 
 func NewBroker(opts ...broker.Option) broker.Broker {
-	options := broker.NewOptionsAndApply(opts...)
+	options := broker.NewOptionsAndApply(append([]broker.Option{broker.WithMaxMessageSize(defaultMaxMessageSize)}, opts...)...)
 	return &aliyunmqBroker{
 		producers:   make(map[string]aliyun.MQProducer),
 		options:     options,
@@ -73,43 +82,34 @@ func (r *aliyunmqBroker) Options() broker.Options {
 func (r *aliyunmqBroker) Init(opts ...broker.Option) error {
 	r.options.Apply(opts...)
 
-	if v, ok := r.options.Context.Value(rocketmqOption.NameServersKey{}).([]string); ok {
-		r.nameServers = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.NameServerUrlKey{}).(string); ok {
-		r.nameServerUrl = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.AccessKey{}).(string); ok {
-		r.credentials.AccessKey = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.SecretKey{}).(string); ok {
-		r.credentials.AccessSecret = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.SecurityTokenKey{}).(string); ok {
-		r.credentials.SecurityToken = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.CredentialsKey{}).(*rocketmqOption.Credentials); ok {
-		r.credentials = *v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.RetryCountKey{}).(int); ok {
-		r.retryCount = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.NamespaceKey{}).(string); ok {
-		r.namespace = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.InstanceNameKey{}).(string); ok {
-		r.instanceName = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.GroupNameKey{}).(string); ok {
-		r.groupName = v
-	}
+	c := rocketmqOption.ConfigFromOptions(r.options)
+	r.nameServers = c.NameServers
+	r.nameServerUrl = c.NameServerUrl
+	r.credentials = c.Credentials
+	r.retryCount = c.RetryCount
+	r.namespace = c.Namespace
+	r.instanceName = c.InstanceName
+	r.groupName = c.GroupName
 
 	if len(r.options.Tracings) > 0 {
 		r.producerTracer = tracing.NewTracer(trace.SpanKindProducer, "rocketmq-producer", r.options.Tracings...)
 		r.consumerTracer = tracing.NewTracer(trace.SpanKindConsumer, "rocketmq-consumer", r.options.Tracings...)
 	}
 
-	return nil
+	r.metrics = broker.NewMetrics("rocketmq", r.options.MeterProvider)
+
+	return r.Validate()
+}
+
+// Validate check the options set at Init, the http client requires the
+// access keys and panics on an invalid endpoint.
+func (r *aliyunmqBroker) Validate() error {
+	v := broker.NewConfigValidator(r.Name())
+	_, err := url.Parse(r.Address())
+	v.Require(err == nil, "endpoint %q is not a valid url", r.Address())
+	v.Require(r.credentials.AccessKey != "", "access key is required")
+	v.Require(r.credentials.AccessSecret != "", "secret key is required")
+	return v.Err()
 }
 
 func (r *aliyunmqBroker) Connect() error {
@@ -139,6 +139,8 @@ func (r *aliyunmqBroker) Disconnect() error {
 	}
 	r.RUnlock()
 
+	r.subscribers.Clear()
+
 	r.Lock()
 	defer r.Unlock()
 
@@ -149,66 +151,102 @@ func (r *aliyunmqBroker) Disconnect() error {
 }
 
 func (r *aliyunmqBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
-	buf, err := broker.Marshal(r.options.Codec, msg)
+	aMsg, options, err := r.newMessage(ctx, topic, msg, opts)
 	if err != nil {
 		return err
 	}
 
-	return r.publish(ctx, topic, buf, opts...)
+	start := time.Now()
+	err = r.publish(topic, aMsg, options)
+	r.metrics.RecordPublish(ctx, topic, start, err)
+
+	return err
 }
 
-func (r *aliyunmqBroker) publish(ctx context.Context, topic string, msg []byte, opts ...broker.PublishOption) error {
+// newMessage encode msg and run the publish interceptors, it returns the
+// message to publish to topic with the options of the publish.
+func (r *aliyunmqBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) (aliyun.PublishMessageRequest, broker.PublishOptions, error) {
 	options := broker.PublishOptions{
 		Context: ctx,
 	}
+
+	buf, err := broker.Marshal(r.options.Codec, msg)
+	if err != nil {
+		return aliyun.PublishMessageRequest{}, options, err
+	}
+	buf, opts, err = r.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return aliyun.PublishMessageRequest{}, options, err
+	}
+
 	for _, o := range opts {
 		o(&options)
 	}
 
+	c := rocketmqOption.PublishConfigFromOptions(options)
+
+	aMsg := aliyun.PublishMessageRequest{
+		MessageBody:      string(buf),
+		Properties:       c.Properties,
+		StartDeliverTime: int64(c.DelayTimeLevel),
+		MessageTag:       c.Tag,
+		ShardingKey:      c.ShardingKey,
+	}
+
+	if len(options.Headers) > 0 {
+		aMsg.Properties = make(map[string]string, len(c.Properties)+len(options.Headers))
+		for k, v := range c.Properties {
+			aMsg.Properties[k] = v
+		}
+		for k, v := range options.Headers {
+			aMsg.Properties[k] = v
+		}
+	}
+
+	if c.Keys != nil {
+		var sb strings.Builder
+		for _, k := range c.Keys {
+			sb.WriteString(k)
+			sb.WriteString(" ")
+		}
+		aMsg.MessageKey = sb.String()
+	}
+
+	return aMsg, options, nil
+}
+
+func (r *aliyunmqBroker) publish(topic string, aMsg aliyun.PublishMessageRequest, options broker.PublishOptions) error {
 	if r.client == nil {
 		return errors.New("client is nil")
 	}
 
+	c := rocketmqOption.PublishConfigFromOptions(options)
+
+	instanceName := r.instanceName
+	if c.InstanceName != "" {
+		instanceName = c.InstanceName
+	}
+	namespace := r.namespace
+	if c.Namespace != "" {
+		namespace = c.Namespace
+	}
+
+	producerKey := instanceName + "/" + topic
+
 	r.Lock()
-	p, ok := r.producers[topic]
+	p, ok := r.producers[producerKey]
 	if !ok {
-		p = r.client.GetProducer(r.instanceName, topic)
+		p = r.client.GetProducer(instanceName, topic)
 		if p == nil {
 			r.Unlock()
 			return errors.New("create producer failed")
 		}
 
-		r.producers[topic] = p
-	} else {
+		r.producers[producerKey] = p
 	}
 	r.Unlock()
 
-	aMsg := aliyun.PublishMessageRequest{
-		MessageBody: string(msg),
-	}
-
-	if v, ok := options.Context.Value(rocketmqOption.PropertiesKey{}).(map[string]string); ok {
-		aMsg.Properties = v
-	}
-	if v, ok := options.Context.Value(rocketmqOption.DelayTimeLevelKey{}).(int); ok {
-		aMsg.StartDeliverTime = int64(v)
-	}
-	if v, ok := options.Context.Value(rocketmqOption.TagsKey{}).(string); ok {
-		aMsg.MessageTag = v
-	}
-	if v, ok := options.Context.Value(rocketmqOption.KeysKey{}).([]string); ok {
-		var sb strings.Builder
-		for _, k := range v {
-			sb.WriteString(k)
-			sb.WriteString(" ")
-		}
-		aMsg.MessageKey = sb.String()
-	}
-	if v, ok := options.Context.Value(rocketmqOption.ShardingKeyKey{}).(string); ok {
-		aMsg.ShardingKey = v
-	}
-
-	span := r.startProducerSpan(options.Context, topic, &aMsg)
+	span := r.startProducerSpan(options.Context, instanceName, namespace, topic, &aMsg)
 
 	ret, err := p.PublishMessage(aMsg)
 	if err != nil {
@@ -217,7 +255,7 @@ func (r *aliyunmqBroker) publish(ctx context.Context, topic string, msg []byte,
 
 	r.finishProducerSpan(span, ret.MessageId, err)
 
-	return nil
+	return err
 }
 
 func (r *aliyunmqBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
@@ -234,120 +272,292 @@ func (r *aliyunmqBroker) Subscribe(topic string, handler broker.Handler, binder
 		o(&options)
 	}
 
-	mqConsumer := r.client.GetConsumer(r.instanceName, topic, options.Queue, "")
+	v := broker.NewConfigValidator(r.Name())
+	v.Require(options.Queue != "", "group name is required to subscribe topic %s", topic)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	handler = options.TimeoutHandler(handler)
+	handler = r.metrics.Handler(topic, handler)
+
+	c := rocketmqOption.SubscribeConfigFromOptions(options)
+
+	instanceName := r.instanceName
+	if c.InstanceName != "" {
+		instanceName = c.InstanceName
+	}
+	namespace := r.namespace
+	if c.Namespace != "" {
+		namespace = c.Namespace
+	}
+
+	mqConsumer := r.client.GetConsumer(instanceName, topic, options.Queue, c.Tag)
 
 	sub := &Subscriber{
-		options: options,
-		topic:   topic,
-		handler: handler,
-		binder:  binder,
-		reader:  mqConsumer,
-		done:    make(chan struct{}),
+		r:            r,
+		options:      options,
+		config:       c,
+		topic:        topic,
+		instanceName: instanceName,
+		namespace:    namespace,
+		handler:      handler,
+		binder:       binder,
+		reader:       mqConsumer,
+		done:         make(chan struct{}),
+		lastPoll:     time.Now(),
+	}
+	sub.ctx, sub.cancel = context.WithCancel(context.Background())
+
+	if c.AckBatchSize > 1 {
+		interval := c.AckBatchInterval
+		if interval <= 0 {
+			interval = defaultAckBatchInterval
+		}
+		sub.acks = newAckBatcher(mqConsumer, c.AckBatchSize, func(err error) {
+			sub.options.ReportError(sub.ctx, broker.ErrAck, err, nil)
+		})
+		go sub.acks.run(sub.ctx, r.options.Clock, interval)
 	}
 
+	r.subscribers.Add(topic, sub)
+
 	go r.doConsume(sub)
 
 	return sub, nil
 }
 
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (r *aliyunmqBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return r.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
+
 func (r *aliyunmqBroker) doConsume(sub *Subscriber) {
-	for {
-		endChan := make(chan int)
-		respChan := make(chan aliyun.ConsumeMessageResponse)
-		errChan := make(chan error)
+	defer close(sub.done)
+
+	orderly := sub.config.ConsumeOrderly
+
+	for sub.ctx.Err() == nil {
+		// buffered, so an abandoned poll can still deliver its result and exit.
+		respChan := make(chan aliyun.ConsumeMessageResponse, 1)
+		errChan := make(chan error, 1)
+
+		// 长轮询消费消息，网络超时时间默认为35s。
+		// 长轮询表示如果Topic没有消息，则客户端请求会在服务端挂起3s，3s内如果有消息可以消费则立即返回响应。
 		go func() {
-			select {
-			case resp := <-respChan:
-				{
-					var err error
-					var m broker.Message
-					for _, msg := range resp.Messages {
-
-						ctx, span := r.startConsumerSpan(sub.options.Context, &msg)
-
-						p := &Publication{
-							topic:  msg.Message,
-							reader: sub.reader,
-							m:      &m,
-							rm:     []string{msg.ReceiptHandle},
-							ctx:    r.options.Context,
-						}
-
-						m.Headers = msg.Properties
-
-						if sub.binder != nil {
-							m.Body = sub.binder()
-						} else {
-							m.Body = msg.MessageBody
-						}
-
-						if err = broker.Unmarshal(r.options.Codec, []byte(msg.MessageBody), &m.Body); err != nil {
-							p.err = err
-							LogError(err)
-							r.finishConsumerSpan(span, err)
-							continue
-						}
-
-						err = sub.handler(ctx, p)
-						if err != nil {
-							LogErrorf("process message failed: %v", err)
-							r.finishConsumerSpan(span, err)
-							continue
-						}
-
-						if sub.options.AutoAck {
-							if err = p.Ack(); err != nil {
-								// 某些消息的句柄可能超时，会导致消息消费状态确认不成功。
-								if errAckItems, ok := err.(errors.ErrCode).Context()["Detail"].([]aliyun.ErrAckItem); ok {
-									for _, errAckItem := range errAckItems {
-										LogErrorf("ErrorHandle:%s, ErrorCode:%s, ErrorMsg:%s\n",
-											errAckItem.ErrorHandle, errAckItem.ErrorCode, errAckItem.ErrorMsg)
-									}
-								} else {
-									LogError("ack err =", err)
-								}
-								time.Sleep(time.Duration(3) * time.Second)
-							}
-						}
-
-						r.finishConsumerSpan(span, err)
-					}
-
-					endChan <- 1
-				}
-			case err := <-errChan:
-				{
-					// Topic中没有消息可消费。
-					if strings.Contains(err.(errors.ErrCode).Error(), "MessageNotExist") {
-						//LogDebug("No new message, continue!")
-					} else {
-						LogError(err)
-						time.Sleep(time.Duration(3) * time.Second)
-					}
-					endChan <- 1
-				}
-			case <-time.After(35 * time.Second):
-				{
-					//LogDebug("Timeout of consumer message ??")
-					endChan <- 1
+			if orderly {
+				sub.reader.ConsumeMessageOrderly(respChan, errChan, 3, 3)
+			} else {
+				sub.reader.ConsumeMessage(respChan, errChan,
+					3, // 一次最多消费3条（最多可设置为16条）。
+					3, // 长轮询时间3s（最多可设置为30s）。
+				)
+			}
+		}()
+
+		select {
+		case <-sub.ctx.Done():
+			return
+
+		case resp := <-respChan:
+			sub.polled()
+			if orderly {
+				r.consumeOrderly(sub, resp.Messages)
+			} else {
+				r.consume(sub, resp.Messages)
+			}
+
+		case err := <-errChan:
+			sub.polled()
+			// Topic中没有消息可消费。
+			if !strings.Contains(err.Error(), "MessageNotExist") {
+				LogError(err)
+				sub.options.ReportError(sub.ctx, broker.ErrReceive, err, nil)
+				sleep(sub.ctx, r.options.Clock, 3*time.Second)
+			}
+
+		case <-r.options.Clock.After(35 * time.Second):
+			//LogDebug("Timeout of consumer message ??")
+		}
+	}
+}
+
+func (r *aliyunmqBroker) consume(sub *Subscriber, msgs []aliyun.ConsumeMessageEntry) {
+	for i := range msgs {
+		// the rest is redelivered after its invisible time.
+		if sub.ctx.Err() != nil {
+			return
+		}
+
+		p, span, err := r.handleMessage(sub, &msgs[i])
+		if err == nil && sub.options.AutoAck && !broker.IsSettled(p) {
+			if err = broker.AutoAck(p); err != nil {
+				logAckError(err)
+				sub.options.ReportError(sub.ctx, broker.ErrAck, err, p)
+				sleep(sub.ctx, r.options.Clock, 3*time.Second)
+			}
+		}
+		r.finishConsumerSpan(span, err)
+	}
+}
+
+// consumeOrderly handles every sharding key in sequence and stops at the first
+// failure, the server redelivers the failed message at its NextConsumeTime and
+// holds back the rest of the sharding key until then. The handles of a sharding
+// key are acked at once, without the ack batcher, so it is not held back.
+func (r *aliyunmqBroker) consumeOrderly(sub *Subscriber, msgs []aliyun.ConsumeMessageEntry) {
+	var shardingKeys []string
+	shards := make(map[string][]*aliyun.ConsumeMessageEntry)
+	for i := range msgs {
+		key := msgs[i].ShardingKey
+		if _, ok := shards[key]; !ok {
+			shardingKeys = append(shardingKeys, key)
+		}
+		shards[key] = append(shards[key], &msgs[i])
+	}
+
+	var blocked int
+	var nextConsumeTime int64
+	for _, key := range shardingKeys {
+		var handles []string
+		for _, msg := range shards[key] {
+			if sub.ctx.Err() != nil {
+				break
+			}
+
+			p, span, err := r.handleMessage(sub, msg)
+			r.finishConsumerSpan(span, err)
+			if err != nil {
+				LogErrorf("sharding key [%s] blocked until %s", key, time.UnixMilli(msg.NextConsumeTime))
+				blocked++
+				if nextConsumeTime == 0 || msg.NextConsumeTime < nextConsumeTime {
+					nextConsumeTime = msg.NextConsumeTime
 				}
+				break
+			}
+			if sub.options.AutoAck && !broker.IsSettled(p) {
+				handles = append(handles, p.rm...)
+			}
+		}
 
-			case sub.done <- struct{}{}:
-				return
+		if len(handles) > 0 {
+			if err := sub.reader.AckMessage(handles); err != nil {
+				logAckError(err)
+				sub.options.ReportError(sub.ctx, broker.ErrAck, err, nil)
 			}
-		}()
+		}
+	}
 
-		// 长轮询消费消息，网络超时时间默认为35s。
-		// 长轮询表示如果Topic没有消息，则客户端请求会在服务端挂起3s，3s内如果有消息可以消费则立即返回响应。
-		sub.reader.ConsumeMessage(respChan, errChan,
-			3, // 一次最多消费3条（最多可设置为16条）。
-			3, // 长轮询时间3s（最多可设置为30s）。
-		)
-		<-endChan
+	// nothing can be consumed before the earliest redelivery.
+	if blocked > 0 && blocked == len(shardingKeys) {
+		sleep(sub.ctx, r.options.Clock, time.UnixMilli(nextConsumeTime).Sub(r.options.Clock.Now()))
+	}
+}
+
+// handleMessage decodes msg and runs the handler, the span is left open for the caller.
+func (r *aliyunmqBroker) handleMessage(sub *Subscriber, msg *aliyun.ConsumeMessageEntry) (*Publication, trace.Span, error) {
+	ctx, span := r.startConsumerSpan(sub, msg)
+
+	var m broker.Message
+	p := &Publication{
+		topic:  sub.topic,
+		reader: sub.reader,
+		acks:   sub.acks,
+		m:      &m,
+		rm:     []string{msg.ReceiptHandle},
+		ctx:    r.options.Context,
+		entry:  msg,
+	}
+
+	m.Headers = messageHeaders(msg)
+
+	switch {
+	case sub.options.RawBody:
+		m.Body = []byte(msg.MessageBody)
+	case sub.binder != nil:
+		m.Body = sub.binder()
+	default:
+		m.Body = msg.MessageBody
+	}
+
+	if err := r.options.Decode(&sub.options, m.Headers, []byte(msg.MessageBody), &m.Body); err != nil {
+		p.err = err
+		LogError(err)
+		sub.options.ReportError(ctx, broker.ErrUnmarshal, err, p)
+
+		// the message left unacked is redelivered at its NextConsumeTime.
+		switch sub.options.HandleUnmarshalFailure(ctx, sub.topic, m.Headers, []byte(msg.MessageBody), err) {
+		case broker.UnmarshalActionAck:
+			if ackErr := p.Ack(); ackErr != nil {
+				logAckError(ackErr)
+				sub.options.ReportError(ctx, broker.ErrAck, ackErr, p)
+			}
+			return p, span, nil
+		case broker.UnmarshalActionStop:
+			_ = sub.Unsubscribe(true)
+		}
+		return p, span, err
 	}
+
+	if err := sub.handler(ctx, p); err != nil {
+		LogErrorf("process message failed: %v", err)
+		return p, span, err
+	}
+
+	return p, span, nil
+}
+
+// sleep waits for d on clock or until ctx is done.
+func sleep(ctx context.Context, clock broker.Clock, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	t := clock.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-t.C():
+	}
+}
+
+func logAckError(err error) {
+	// 某些消息的句柄可能超时，会导致消息消费状态确认不成功。
+	if errCode, ok := err.(errors.ErrCode); ok {
+		if errAckItems, ok := errCode.Context()["Detail"].([]aliyun.ErrAckItem); ok {
+			for _, errAckItem := range errAckItems {
+				LogErrorf("ErrorHandle:%s, ErrorCode:%s, ErrorMsg:%s\n",
+					errAckItem.ErrorHandle, errAckItem.ErrorCode, errAckItem.ErrorMsg)
+			}
+			return
+		}
+	}
+	LogError("ack err =", err)
+}
+
+func messageHeaders(msg *aliyun.ConsumeMessageEntry) broker.Headers {
+	headers := make(broker.Headers, len(msg.Properties)+7)
+	for k, v := range msg.Properties {
+		headers[k] = v
+	}
+
+	headers[HeaderMessageID] = msg.MessageId
+	headers[HeaderPublishTime] = strconv.FormatInt(msg.PublishTime, 10)
+	headers[HeaderFirstConsumeTime] = strconv.FormatInt(msg.FirstConsumeTime, 10)
+	headers[HeaderNextConsumeTime] = strconv.FormatInt(msg.NextConsumeTime, 10)
+	headers[HeaderConsumedTimes] = strconv.FormatInt(msg.ConsumedTimes, 10)
+	if msg.MessageTag != "" {
+		headers[HeaderMessageTag] = msg.MessageTag
+	}
+	if msg.MessageKey != "" {
+		headers[HeaderMessageKey] = msg.MessageKey
+	}
+
+	return headers
 }
 
-func (r *aliyunmqBroker) startProducerSpan(ctx context.Context, topicName string, msg *aliyun.PublishMessageRequest) trace.Span {
+func (r *aliyunmqBroker) startProducerSpan(ctx context.Context, instanceName, namespace, topicName string, msg *aliyun.PublishMessageRequest) trace.Span {
 	if r.producerTracer == nil {
 		return nil
 	}
@@ -357,9 +567,9 @@ func (r *aliyunmqBroker) startProducerSpan(ctx context.Context, topicName string
 	attrs := []attribute.KeyValue{
 		semConv.MessagingSystemKey.String(rocketmqOption.SPAN_ATTRIBUTE_VALUE_ROCKETMQ_MESSAGING_SYSTEM),
 		semConv.MessagingDestinationKindTopic,
-		semConv.MessagingRocketmqNamespaceKey.String(r.namespace),
+		semConv.MessagingRocketmqNamespaceKey.String(namespace),
 		semConv.MessagingRocketmqClientGroupKey.String(r.groupName),
-		semConv.MessagingRocketmqClientIDKey.String(r.instanceName),
+		semConv.MessagingRocketmqClientIDKey.String(instanceName),
 
 		semConv.MessagingDestinationKey.String(topicName),
 	}
@@ -389,7 +599,8 @@ func (r *aliyunmqBroker) finishProducerSpan(span trace.Span, messageId string, e
 	r.producerTracer.End(context.Background(), span, err, attrs...)
 }
 
-func (r *aliyunmqBroker) startConsumerSpan(ctx context.Context, msg *aliyun.ConsumeMessageEntry) (context.Context, trace.Span) {
+func (r *aliyunmqBroker) startConsumerSpan(sub *Subscriber, msg *aliyun.ConsumeMessageEntry) (context.Context, trace.Span) {
+	ctx := sub.options.Context
 	if r.consumerTracer == nil {
 		return ctx, nil
 	}
@@ -399,6 +610,9 @@ func (r *aliyunmqBroker) startConsumerSpan(ctx context.Context, msg *aliyun.Cons
 	attrs := []attribute.KeyValue{
 		semConv.MessagingSystemKey.String(rocketmqOption.SPAN_ATTRIBUTE_VALUE_ROCKETMQ_MESSAGING_SYSTEM),
 		semConv.MessagingDestinationKindTopic,
+		semConv.MessagingRocketmqNamespaceKey.String(sub.namespace),
+		semConv.MessagingRocketmqClientGroupKey.String(sub.options.Queue),
+		semConv.MessagingRocketmqClientIDKey.String(sub.instanceName),
 		semConv.MessagingDestinationKey.String(msg.Message),
 		semConv.MessagingOperationReceive,
 		semConv.MessagingMessageIDKey.String(msg.MessageId),
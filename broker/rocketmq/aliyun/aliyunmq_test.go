@@ -2,14 +2,17 @@ package aliyun
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
 
+	aliyun "github.com/aliyunmq/mq-http-go-sdk"
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/stretchr/testify/assert"
 
@@ -141,3 +144,189 @@ func Test_Aliyun_Subscribe(t *testing.T) {
 
 	<-interrupt
 }
+
+func TestMessageHeaders(t *testing.T) {
+	msg := aliyun.ConsumeMessageEntry{
+		MessageId:     "id-1",
+		MessageTag:    "created",
+		MessageKey:    "order-1 order-2 ",
+		PublishTime:   1700000000000,
+		ConsumedTimes: 2,
+		Properties:    map[string]string{"trace": "abc"},
+	}
+
+	headers := messageHeaders(&msg)
+	assert.Equal(t, "abc", headers["trace"])
+	assert.Equal(t, "id-1", headers[HeaderMessageID])
+	assert.Equal(t, "created", headers[HeaderMessageTag])
+	assert.Equal(t, "2", headers[HeaderConsumedTimes])
+	assert.Equal(t, "1700000000000", headers[HeaderPublishTime])
+
+	p := &Publication{entry: &msg}
+	assert.Equal(t, []string{"order-1", "order-2"}, p.Keys())
+	assert.Equal(t, int64(1700000000000), p.PublishTime().UnixMilli())
+}
+
+type orderlyConsumer struct {
+	aliyun.MQConsumer
+	acked []string
+}
+
+func (c *orderlyConsumer) AckMessage(receiptHandles []string) error {
+	c.acked = append(c.acked, receiptHandles...)
+	return nil
+}
+
+func TestConsumeOrderly(t *testing.T) {
+	b := NewBroker().(*aliyunmqBroker)
+	reader := &orderlyConsumer{}
+
+	var handled []string
+	sub := &Subscriber{
+		options: broker.SubscribeOptions{Context: context.Background(), AutoAck: true},
+		ctx:     context.Background(),
+		reader:  reader,
+		handler: func(_ context.Context, event broker.Event) error {
+			body := event.Message().Body.(string)
+			handled = append(handled, body)
+			if body == "a2" {
+				return fmt.Errorf("failed")
+			}
+			return nil
+		},
+	}
+
+	msgs := []aliyun.ConsumeMessageEntry{
+		{MessageBody: "a1", ReceiptHandle: "h-a1", ShardingKey: "a"},
+		{MessageBody: "b1", ReceiptHandle: "h-b1", ShardingKey: "b"},
+		{MessageBody: "a2", ReceiptHandle: "h-a2", ShardingKey: "a"},
+		{MessageBody: "a3", ReceiptHandle: "h-a3", ShardingKey: "a"},
+		{MessageBody: "b2", ReceiptHandle: "h-b2", ShardingKey: "b"},
+	}
+
+	b.consumeOrderly(sub, msgs)
+
+	assert.Equal(t, []string{"a1", "a2", "b1", "b2"}, handled)
+	assert.Equal(t, []string{"h-a1", "h-b1", "h-b2"}, reader.acked)
+}
+
+type batchConsumer struct {
+	aliyun.MQConsumer
+	sync.Mutex
+	batches [][]string
+	err     error
+}
+
+func (c *batchConsumer) AckMessage(receiptHandles []string) error {
+	c.Lock()
+	defer c.Unlock()
+	c.batches = append(c.batches, append([]string(nil), receiptHandles...))
+	return c.err
+}
+
+func TestAckBatcher(t *testing.T) {
+	reader := &batchConsumer{}
+	acks := newAckBatcher(reader, 3, nil)
+
+	acks.add("h1", "h2")
+	assert.Empty(t, reader.batches)
+	acks.add("h3", "h4")
+	assert.Equal(t, [][]string{{"h1", "h2", "h3"}}, reader.batches)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		acks.run(ctx, broker.SystemClock, time.Hour)
+		close(done)
+	}()
+	cancel()
+	<-done
+	assert.Equal(t, [][]string{{"h1", "h2", "h3"}, {"h4"}}, reader.batches, "flushed when closed")
+
+	acks.add("h5")
+	assert.Equal(t, []string{"h5"}, reader.batches[2], "acked right away once closed")
+
+	p := &Publication{acks: newAckBatcher(reader, maxAckBatch+10, nil), rm: []string{"h6"}}
+	assert.Nil(t, p.Ack())
+	assert.Len(t, reader.batches, 3, "batched")
+	assert.Equal(t, maxAckBatch, p.acks.size)
+
+	// the failed batches are reported.
+	var reported []error
+	so := broker.NewSubscribeOptions(broker.WithSubscribeErrorHandler(func(_ context.Context, err error, _ broker.Event) {
+		reported = append(reported, err)
+	}))
+	failed := errors.New("handle expired")
+	acks = newAckBatcher(&batchConsumer{err: failed}, 2, func(err error) {
+		so.ReportError(context.Background(), broker.ErrAck, err, nil)
+	})
+	acks.add("h7", "h8")
+	if assert.Len(t, reported, 1) {
+		assert.ErrorIs(t, reported[0], broker.ErrAck)
+		assert.ErrorIs(t, reported[0], failed)
+	}
+}
+
+type blockingConsumer struct {
+	aliyun.MQConsumer
+	polled  chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingConsumer) ConsumeMessage(respChan chan aliyun.ConsumeMessageResponse, _ chan error, _ int32, _ int64) {
+	c.polled <- struct{}{}
+	<-c.release
+	respChan <- aliyun.ConsumeMessageResponse{}
+}
+
+func TestUnsubscribeStopsPolling(t *testing.T) {
+	b := NewBroker().(*aliyunmqBroker)
+	reader := &blockingConsumer{polled: make(chan struct{}, 1), release: make(chan struct{})}
+
+	sub := &Subscriber{
+		r:       b,
+		topic:   testTopic,
+		options: broker.SubscribeOptions{Context: context.Background(), AutoAck: true},
+		reader:  reader,
+		done:    make(chan struct{}),
+	}
+	sub.ctx, sub.cancel = context.WithCancel(context.Background())
+	b.subscribers.Add(testTopic, sub)
+
+	go b.doConsume(sub)
+	<-reader.polled
+
+	assert.Nil(t, sub.Unsubscribe(true))
+
+	select {
+	case <-sub.done:
+	case <-time.After(time.Second):
+		t.Fatal("consumer still polling after unsubscribe")
+	}
+	assert.Nil(t, b.subscribers.Get(testTopic))
+
+	// the abandoned poll must not block forever.
+	close(reader.release)
+}
+
+func TestValidate(t *testing.T) {
+	b := NewBroker(rocketmqOption.WithNameServerDomain(testBroker))
+
+	err := b.Init()
+	assert.ErrorIs(t, err, broker.ErrInvalidConfig)
+
+	var configErr *broker.ConfigError
+	assert.ErrorAs(t, err, &configErr)
+	assert.Len(t, configErr.Problems, 3)
+
+	b = NewBroker(
+		rocketmqOption.WithNameServerDomain("http://"+testBroker),
+		rocketmqOption.WithAccessKey("key"),
+		rocketmqOption.WithSecretKey("secret"),
+	)
+	assert.Nil(t, b.Init())
+	assert.Nil(t, b.Connect())
+
+	_, err = b.Subscribe(testTopic, nil, nil)
+	assert.ErrorIs(t, err, broker.ErrInvalidConfig)
+}
@@ -0,0 +1,13 @@
+package aliyun
+
+// well-known headers filled from the consumed message, user properties with
+// the same name are overwritten.
+const (
+	HeaderMessageID        = "x-rocketmq-message-id"
+	HeaderMessageTag       = "x-rocketmq-message-tag"
+	HeaderMessageKey       = "x-rocketmq-message-key"
+	HeaderPublishTime      = "x-rocketmq-publish-time"
+	HeaderFirstConsumeTime = "x-rocketmq-first-consume-time"
+	HeaderNextConsumeTime  = "x-rocketmq-next-consume-time"
+	HeaderConsumedTimes    = "x-rocketmq-consumed-times"
+)
@@ -3,6 +3,8 @@ package aliyun
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	aliyun "github.com/aliyunmq/mq-http-go-sdk"
 
@@ -10,12 +12,16 @@ import (
 )
 
 type Publication struct {
+	broker.AckState
+
 	topic  string
 	err    error
 	m      *broker.Message
 	ctx    context.Context
 	reader aliyun.MQConsumer
+	acks   *ackBatcher
 	rm     []string
+	entry  *aliyun.ConsumeMessageEntry
 }
 
 func (p *Publication) Topic() string {
@@ -30,12 +36,60 @@ func (p *Publication) RawMessage() interface{} {
 	return p.rm
 }
 
-func (p *Publication) Ack() error {
-	if p.reader == nil {
-		return errors.New("reader is nil")
+// Entry returns the consumed message as received from the server.
+func (p *Publication) Entry() *aliyun.ConsumeMessageEntry {
+	return p.entry
+}
+
+func (p *Publication) MessageID() string {
+	if p.entry == nil {
+		return ""
 	}
-	p.err = p.reader.AckMessage(p.rm)
-	return p.err
+	return p.entry.MessageId
+}
+
+func (p *Publication) Tag() string {
+	if p.entry == nil {
+		return ""
+	}
+	return p.entry.MessageTag
+}
+
+// Keys returns the message keys, multiple keys are separated by spaces.
+func (p *Publication) Keys() []string {
+	if p.entry == nil {
+		return nil
+	}
+	return strings.Fields(p.entry.MessageKey)
+}
+
+func (p *Publication) PublishTime() time.Time {
+	if p.entry == nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(p.entry.PublishTime)
+}
+
+// ConsumedTimes returns how many times the message has been delivered, including this one.
+func (p *Publication) ConsumedTimes() int64 {
+	if p.entry == nil {
+		return 0
+	}
+	return p.entry.ConsumedTimes
+}
+
+func (p *Publication) Ack() error {
+	return p.AckState.Ack(func() error {
+		if p.acks != nil {
+			p.acks.add(p.rm...)
+			return nil
+		}
+		if p.reader == nil {
+			return errors.New("reader is nil")
+		}
+		p.err = p.reader.AckMessage(p.rm)
+		return p.err
+	})
 }
 
 func (p *Publication) Error() error {
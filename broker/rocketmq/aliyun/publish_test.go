@@ -0,0 +1,21 @@
+package aliyun
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func TestPublishPath(t *testing.T) {
+	mocks.TestPublishPath(t, func(opts []broker.Option, topic string, msg broker.Any, popts ...broker.PublishOption) (broker.Headers, []byte, error) {
+		r := NewBroker(opts...).(*aliyunmqBroker)
+
+		aMsg, _, err := r.newMessage(context.Background(), topic, msg, popts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return aMsg.Properties, []byte(aMsg.MessageBody), nil
+	}, true)
+}
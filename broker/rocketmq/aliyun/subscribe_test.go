@@ -0,0 +1,44 @@
+package aliyun
+
+import (
+	"context"
+	"testing"
+
+	aliyun "github.com/aliyunmq/mq-http-go-sdk"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+	rocketmqOption "github.com/tx7do/kratos-transport/broker/rocketmq/option"
+)
+
+// newTestSubscriber return a subscriber consuming reader without a server.
+func newTestSubscriber(opts []broker.Option, topic string, reader aliyun.MQConsumer, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) (*aliyunmqBroker, *Subscriber) {
+	r := NewBroker(opts...).(*aliyunmqBroker)
+	options := broker.NewSubscribeOptions(sopts...)
+
+	sub := &Subscriber{
+		r:       r,
+		topic:   topic,
+		options: options,
+		config:  rocketmqOption.SubscribeConfigFromOptions(options),
+		handler: handler,
+		binder:  binder,
+		reader:  reader,
+		done:    make(chan struct{}),
+	}
+	sub.ctx, sub.cancel = context.WithCancel(context.Background())
+
+	return r, sub
+}
+
+func TestSubscribePath(t *testing.T) {
+	mocks.TestSubscribePath(t, func(opts []broker.Option, topic string, headers broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) {
+		r, sub := newTestSubscriber(opts, topic, &orderlyConsumer{}, handler, binder, sopts...)
+
+		r.consume(sub, []aliyun.ConsumeMessageEntry{{
+			MessageBody:   string(body),
+			ReceiptHandle: "handle",
+			Properties:    headers,
+		}})
+	}, true)
+}
@@ -1,23 +1,34 @@
 package aliyun
 
 import (
+	"context"
 	"sync"
+	"time"
 
 	aliyun "github.com/aliyunmq/mq-http-go-sdk"
 
 	"github.com/tx7do/kratos-transport/broker"
+	rocketmqOption "github.com/tx7do/kratos-transport/broker/rocketmq/option"
 )
 
 type Subscriber struct {
 	sync.RWMutex
-	r       *aliyunmqBroker
-	topic   string
-	options broker.SubscribeOptions
-	handler broker.Handler
-	binder  broker.Binder
-	reader  aliyun.MQConsumer
-	closed  bool
-	done    chan struct{}
+	r            *aliyunmqBroker
+	topic        string
+	instanceName string
+	namespace    string
+	options      broker.SubscribeOptions
+	config       rocketmqOption.SubscribeConfig
+	handler      broker.Handler
+	binder       broker.Binder
+	reader       aliyun.MQConsumer
+	acks         *ackBatcher
+	closed       bool
+	lastPoll     time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
 func (s *Subscriber) Options() broker.SubscribeOptions {
@@ -33,12 +44,25 @@ func (s *Subscriber) Unsubscribe(removeFromManager bool) error {
 	defer s.Unlock()
 
 	s.closed = true
+	s.cancel()
 
-	var err error
+	if s.r != nil && s.r.subscribers != nil && removeFromManager {
+		_ = s.r.subscribers.RemoveOnly(s.topic)
+	}
 
-	if removeFromManager {
+	return nil
+}
 
-	}
+// LastActivity returns when the last poll returned.
+func (s *Subscriber) LastActivity() time.Time {
+	s.RLock()
+	defer s.RUnlock()
 
-	return err
+	return s.lastPoll
+}
+
+func (s *Subscriber) polled() {
+	s.Lock()
+	s.lastPoll = time.Now()
+	s.Unlock()
 }
@@ -0,0 +1,284 @@
+package rocketmqOption
+
+import (
+	"time"
+
+	rmqClient "github.com/apache/rocketmq-clients/golang/v5"
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Config is the typed rocketmq configuration of a broker, shared by the
+// drivers, the fields are the ones set one by one with the broker options
+// of the package.
+type Config struct {
+	NameServers   []string
+	NameServerUrl string
+	Credentials   Credentials
+	RetryCount    int
+	Namespace     string
+	InstanceName  string
+	GroupName     string
+	EnableTrace   bool
+	LoggerLevel   log.Level
+
+	// the fields of the v5 consumer, the zero ones keep the default of the
+	// driver.
+	SubscriptionExpressions map[string]*rmqClient.FilterExpression
+	AwaitDuration           time.Duration
+	MaxMessageNum           int32
+	InvisibleDuration       time.Duration
+	ReceiveInterval         time.Duration
+}
+
+// DefaultConfig return the configuration of a broker without options.
+func DefaultConfig() Config {
+	return Config{
+		RetryCount:  2,
+		LoggerLevel: log.LevelInfo,
+	}
+}
+
+// WithConfig set the whole configuration at once, c should start from
+// DefaultConfig since the retry count, trace and logger level are always
+// applied, the other zero fields are left unset. The credentials are set
+// together as with WithCredentials. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		opts := []broker.Option{
+			WithRetryCount(c.RetryCount),
+			WithLoggerLevel(c.LoggerLevel),
+			broker.OptionContextWithValue(EnableTraceKey{}, c.EnableTrace),
+		}
+		if c.NameServers != nil {
+			opts = append(opts, WithNameServer(c.NameServers))
+		}
+		if c.NameServerUrl != "" {
+			opts = append(opts, WithNameServerDomain(c.NameServerUrl))
+		}
+		if c.Credentials != (Credentials{}) {
+			opts = append(opts, WithCredentials(c.Credentials.AccessKey, c.Credentials.AccessSecret, c.Credentials.SecurityToken))
+		}
+		if c.Namespace != "" {
+			opts = append(opts, WithNamespace(c.Namespace))
+		}
+		if c.InstanceName != "" {
+			opts = append(opts, WithInstanceName(c.InstanceName))
+		}
+		if c.GroupName != "" {
+			opts = append(opts, WithGroupName(c.GroupName))
+		}
+		if c.SubscriptionExpressions != nil {
+			opts = append(opts, WithSubscriptionExpressions(c.SubscriptionExpressions))
+		}
+		if c.AwaitDuration != 0 {
+			opts = append(opts, WithAwaitDuration(c.AwaitDuration))
+		}
+		if c.MaxMessageNum != 0 {
+			opts = append(opts, WithMaxMessageNumKey(c.MaxMessageNum))
+		}
+		if c.InvisibleDuration != 0 {
+			opts = append(opts, WithInvisibleDuration(c.InvisibleDuration))
+		}
+		if c.ReceiveInterval != 0 {
+			opts = append(opts, WithReceiveInterval(c.ReceiveInterval))
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options. WithCredentials overrides the
+// keys set one by one.
+func ConfigFromOptions(opts broker.Options) Config {
+	c := DefaultConfig()
+	if opts.Context == nil {
+		return c
+	}
+
+	c.NameServers, _ = opts.Context.Value(NameServersKey{}).([]string)
+	c.NameServerUrl, _ = opts.Context.Value(NameServerUrlKey{}).(string)
+	c.Credentials.AccessKey, _ = opts.Context.Value(AccessKey{}).(string)
+	c.Credentials.AccessSecret, _ = opts.Context.Value(SecretKey{}).(string)
+	c.Credentials.SecurityToken, _ = opts.Context.Value(SecurityTokenKey{}).(string)
+	if v, ok := opts.Context.Value(CredentialsKey{}).(*Credentials); ok && v != nil {
+		c.Credentials = *v
+	}
+	if v, ok := opts.Context.Value(RetryCountKey{}).(int); ok {
+		c.RetryCount = v
+	}
+	c.Namespace, _ = opts.Context.Value(NamespaceKey{}).(string)
+	c.InstanceName, _ = opts.Context.Value(InstanceNameKey{}).(string)
+	c.GroupName, _ = opts.Context.Value(GroupNameKey{}).(string)
+	c.EnableTrace, _ = opts.Context.Value(EnableTraceKey{}).(bool)
+	if v, ok := opts.Context.Value(LoggerLevelKey{}).(log.Level); ok {
+		c.LoggerLevel = v
+	}
+
+	c.SubscriptionExpressions, _ = opts.Context.Value(SubscriptionExpressionsKey{}).(map[string]*rmqClient.FilterExpression)
+	c.AwaitDuration, _ = opts.Context.Value(AwaitDurationKey{}).(time.Duration)
+	c.MaxMessageNum, _ = opts.Context.Value(MaxMessageNumKey{}).(int32)
+	c.InvisibleDuration, _ = opts.Context.Value(InvisibleDurationKey{}).(time.Duration)
+	c.ReceiveInterval, _ = opts.Context.Value(ReceiveIntervalKey{}).(time.Duration)
+
+	return c
+}
+
+// SubscribeConfig is the typed rocketmq configuration of a subscription, the
+// zero fields are left unset. Not every driver supports every field, see
+// the dedicated options.
+type SubscribeConfig struct {
+	FilterExpression *rmqClient.FilterExpression
+	ConsumerModel    MessageModel
+	Tag              string
+	ConsumeOrderly   bool
+	AckBatchSize     int
+	AckBatchInterval time.Duration
+	InstanceName     string
+	Namespace        string
+}
+
+// WithSubscribeConfig set the whole subscription configuration at once.
+func WithSubscribeConfig(c SubscribeConfig) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		var opts []broker.SubscribeOption
+		if c.FilterExpression != nil {
+			opts = append(opts, WithSubscriptionFilterExpression(c.FilterExpression))
+		}
+		if c.ConsumerModel != "" {
+			opts = append(opts, WithConsumerModel(c.ConsumerModel))
+		}
+		if c.Tag != "" {
+			opts = append(opts, WithSubscribeTag(c.Tag))
+		}
+		if c.ConsumeOrderly {
+			opts = append(opts, WithConsumeOrderly())
+		}
+		if c.AckBatchSize != 0 {
+			opts = append(opts, WithAckBatch(c.AckBatchSize, c.AckBatchInterval))
+		}
+		if c.InstanceName != "" {
+			opts = append(opts, WithSubscribeInstanceName(c.InstanceName))
+		}
+		if c.Namespace != "" {
+			opts = append(opts, WithSubscribeNamespace(c.Namespace))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// SubscribeConfigFromOptions return the subscription configuration set in
+// the context of opts.
+func SubscribeConfigFromOptions(opts broker.SubscribeOptions) SubscribeConfig {
+	var c SubscribeConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.FilterExpression, _ = opts.Context.Value(SubscriptionFilterExpressionKey{}).(*rmqClient.FilterExpression)
+	c.ConsumerModel, _ = opts.Context.Value(ConsumerModelKey{}).(MessageModel)
+	c.Tag, _ = opts.Context.Value(SubscribeTagKey{}).(string)
+	c.ConsumeOrderly, _ = opts.Context.Value(ConsumeOrderlyKey{}).(bool)
+	c.AckBatchSize, _ = opts.Context.Value(AckBatchSizeKey{}).(int)
+	c.AckBatchInterval, _ = opts.Context.Value(AckBatchIntervalKey{}).(time.Duration)
+	c.InstanceName, _ = opts.Context.Value(SubscribeInstanceNameKey{}).(string)
+	c.Namespace, _ = opts.Context.Value(SubscribeNamespaceKey{}).(string)
+
+	return c
+}
+
+// PublishConfig is the typed rocketmq configuration of a publishing, the
+// zero fields are left unset. Not every driver supports every field, see
+// the dedicated options.
+type PublishConfig struct {
+	Compress            bool
+	Batch               bool
+	Properties          map[string]string
+	DelayTimeLevel      int
+	Tag                 string
+	Keys                []string
+	ShardingKey         string
+	DeliveryTimestamp   time.Time
+	MessageGroup        string
+	SendAsync           bool
+	SendWithTransaction bool
+	InstanceName        string
+	Namespace           string
+}
+
+// WithPublishConfig set the whole publishing configuration at once.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		var opts []broker.PublishOption
+		if c.Compress {
+			opts = append(opts, WithCompress(true))
+		}
+		if c.Batch {
+			opts = append(opts, WithBatch(true))
+		}
+		if c.Properties != nil {
+			opts = append(opts, WithProperties(c.Properties))
+		}
+		if c.DelayTimeLevel != 0 {
+			opts = append(opts, WithDelayTimeLevel(c.DelayTimeLevel))
+		}
+		if c.Tag != "" {
+			opts = append(opts, WithTag(c.Tag))
+		}
+		if c.Keys != nil {
+			opts = append(opts, WithKeys(c.Keys))
+		}
+		if c.ShardingKey != "" {
+			opts = append(opts, WithShardingKey(c.ShardingKey))
+		}
+		if !c.DeliveryTimestamp.IsZero() {
+			opts = append(opts, WithDeliveryTimestamp(c.DeliveryTimestamp))
+		}
+		if c.MessageGroup != "" {
+			opts = append(opts, WithMessageGroup(c.MessageGroup))
+		}
+		if c.SendAsync {
+			opts = append(opts, WithSendAsync(true))
+		}
+		if c.SendWithTransaction {
+			opts = append(opts, WithSendWithTransaction(true))
+		}
+		if c.InstanceName != "" {
+			opts = append(opts, WithPublishInstanceName(c.InstanceName))
+		}
+		if c.Namespace != "" {
+			opts = append(opts, WithPublishNamespace(c.Namespace))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	var c PublishConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.Compress, _ = opts.Context.Value(CompressKey{}).(bool)
+	c.Batch, _ = opts.Context.Value(BatchKey{}).(bool)
+	c.Properties, _ = opts.Context.Value(PropertiesKey{}).(map[string]string)
+	c.DelayTimeLevel, _ = opts.Context.Value(DelayTimeLevelKey{}).(int)
+	c.Tag, _ = opts.Context.Value(TagsKey{}).(string)
+	c.Keys, _ = opts.Context.Value(KeysKey{}).([]string)
+	c.ShardingKey, _ = opts.Context.Value(ShardingKeyKey{}).(string)
+	c.DeliveryTimestamp, _ = opts.Context.Value(DeliveryTimestampKey{}).(time.Time)
+	c.MessageGroup, _ = opts.Context.Value(MessageGroupKey{}).(string)
+	c.SendAsync, _ = opts.Context.Value(SendAsyncKey{}).(bool)
+	c.SendWithTransaction, _ = opts.Context.Value(SendWithTransactionKey{}).(bool)
+	c.InstanceName, _ = opts.Context.Value(PublishInstanceNameKey{}).(string)
+	c.Namespace, _ = opts.Context.Value(PublishNamespaceKey{}).(string)
+
+	return c
+}
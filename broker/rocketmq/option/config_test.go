@@ -0,0 +1,80 @@
+package rocketmqOption
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, DefaultConfig(), ConfigFromOptions(broker.NewOptions()))
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithNameServer([]string{"127.0.0.1:9876"}),
+		WithAccessKey("key"),
+		WithSecretKey("secret"),
+		WithRetryCount(3),
+		WithGroupName("group"),
+		WithEnableTrace(),
+		WithLoggerLevel(log.LevelDebug),
+		WithAwaitDuration(time.Second),
+	))
+
+	c := DefaultConfig()
+	c.NameServers = []string{"127.0.0.1:9876"}
+	c.Credentials = Credentials{AccessKey: "key", AccessSecret: "secret"}
+	c.RetryCount = 3
+	c.GroupName = "group"
+	c.EnableTrace = true
+	c.LoggerLevel = log.LevelDebug
+	c.AwaitDuration = time.Second
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c), WithCredentials("other", "", "")))
+	assert.Equal(t, Credentials{AccessKey: "other"}, overridden.Credentials)
+}
+
+func TestSubscribeConfigFromOptions(t *testing.T) {
+	assert.Equal(t, SubscribeConfig{}, SubscribeConfigFromOptions(broker.NewSubscribeOptions()))
+
+	legacy := SubscribeConfigFromOptions(broker.NewSubscribeOptions(
+		WithConsumerModel(MessageModelBroadCasting),
+		WithSubscribeTag("tag"),
+		WithConsumeOrderly(),
+		WithAckBatch(16, time.Second),
+	))
+
+	c := SubscribeConfig{
+		ConsumerModel:    MessageModelBroadCasting,
+		Tag:              "tag",
+		ConsumeOrderly:   true,
+		AckBatchSize:     16,
+		AckBatchInterval: time.Second,
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c))))
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	assert.Equal(t, PublishConfig{}, PublishConfigFromOptions(broker.PublishOptions{}))
+
+	c := PublishConfig{
+		Properties:     map[string]string{"k": "v"},
+		DelayTimeLevel: 3,
+		Tag:            "tag",
+		Keys:           []string{"key"},
+		ShardingKey:    "shard",
+		SendAsync:      true,
+	}
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c), WithTag("other")))
+	assert.Equal(t, "other", overridden.Tag)
+}
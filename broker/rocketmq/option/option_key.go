@@ -37,6 +37,8 @@ type DeliveryTimestampKey struct{}
 type MessageGroupKey struct{}
 type SendAsyncKey struct{}
 type SendWithTransactionKey struct{}
+type PublishInstanceNameKey struct{}
+type PublishNamespaceKey struct{}
 
 ///
 /// SubscribeOption
@@ -44,3 +46,9 @@ type SendWithTransactionKey struct{}
 
 type SubscriptionFilterExpressionKey struct{}
 type ConsumerModelKey struct{}
+type SubscribeTagKey struct{}
+type ConsumeOrderlyKey struct{}
+type AckBatchSizeKey struct{}
+type AckBatchIntervalKey struct{}
+type SubscribeInstanceNameKey struct{}
+type SubscribeNamespaceKey struct{}
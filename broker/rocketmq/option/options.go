@@ -106,6 +106,7 @@ func WithDelayTimeLevel(level int) broker.PublishOption {
 	return broker.PublishContextWithValue(DelayTimeLevelKey{}, level)
 }
 
+// WithTag set the message tag, subscribers can filter on it with WithSubscribeTag
 func WithTag(tags string) broker.PublishOption {
 	return broker.PublishContextWithValue(TagsKey{}, tags)
 }
@@ -134,6 +135,16 @@ func WithSendWithTransaction(enable bool) broker.PublishOption {
 	return broker.PublishContextWithValue(SendWithTransactionKey{}, enable)
 }
 
+// WithPublishInstanceName override the broker instance for this publish
+func WithPublishInstanceName(name string) broker.PublishOption {
+	return broker.PublishContextWithValue(PublishInstanceNameKey{}, name)
+}
+
+// WithPublishNamespace override the broker namespace for this publish
+func WithPublishNamespace(ns string) broker.PublishOption {
+	return broker.PublishContextWithValue(PublishNamespaceKey{}, ns)
+}
+
 ///
 /// SubscribeOption
 ///
@@ -145,3 +156,40 @@ func WithSubscriptionFilterExpression(filterExpression *rmqClient.FilterExpressi
 func WithConsumerModel(model MessageModel) broker.SubscribeOption {
 	return broker.SubscribeContextWithValue(ConsumerModelKey{}, model)
 }
+
+// WithSubscribeTag only consume messages matching the tag expression, e.g. "TagA||TagB"
+func WithSubscribeTag(expression string) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(SubscribeTagKey{}, expression)
+}
+
+// WithConsumeOrderly consume a FIFO topic in order, a failed message blocks
+// its sharding key until it is redelivered. Only supported by the aliyun driver.
+func WithConsumeOrderly() broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(ConsumeOrderlyKey{}, true)
+}
+
+// WithAckBatch ack the receipt handles by batches of up to size, flushed
+// when full or every interval, instead of one request per Ack. Only
+// supported by the aliyun driver, the interval must stay well below the
+// invisible time of the messages.
+//
+// Ack then returns nil once the handle is queued and the event reads as
+// acked: a failed batch is only reported to the error handler of the
+// subscription with broker.ErrAck, see broker.WithSubscribeErrorHandler,
+// and its messages are redelivered. Use it with idempotent handlers.
+func WithAckBatch(size int, interval time.Duration) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		broker.SubscribeContextWithValue(AckBatchSizeKey{}, size)(o)
+		broker.SubscribeContextWithValue(AckBatchIntervalKey{}, interval)(o)
+	}
+}
+
+// WithSubscribeInstanceName override the broker instance for this subscription
+func WithSubscribeInstanceName(name string) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(SubscribeInstanceNameKey{}, name)
+}
+
+// WithSubscribeNamespace override the broker namespace for this subscription
+func WithSubscribeNamespace(ns string) broker.SubscribeOption {
+	return broker.SubscribeContextWithValue(SubscribeNamespaceKey{}, ns)
+}
@@ -9,6 +9,8 @@ import (
 )
 
 type publication struct {
+	broker.AckState
+
 	topic  string
 	err    error
 	m      *broker.Message
@@ -30,7 +32,7 @@ func (p *publication) RawMessage() interface{} {
 }
 
 func (p *publication) Ack() error {
-	return nil
+	return p.AckState.Ack(nil)
 }
 
 func (p *publication) Error() error {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	rocketmqOption "github.com/tx7do/kratos-transport/broker/rocketmq/option"
 	"sync"
+	"time"
 
 	"github.com/apache/rocketmq-client-go/v2"
 	"github.com/apache/rocketmq-client-go/v2/consumer"
@@ -23,6 +24,11 @@ import (
 	"github.com/tx7do/kratos-transport/broker"
 )
 
+const (
+	// defaultMaxMessageSize is the default maxMessageSize of the brokers.
+	defaultMaxMessageSize = 4 * 1024 * 1024
+)
+
 type rocketmqBroker struct {
 	sync.RWMutex
 
@@ -39,6 +45,8 @@ type rocketmqBroker struct {
 
 	enableTrace bool
 
+	mode broker.Mode
+
 	connected bool
 	options   broker.Options
 
@@ -48,11 +56,13 @@ type rocketmqBroker struct {
 	producerTracer *tracing.Tracer
 	consumerTracer *tracing.Tracer
 
+	metrics *broker.Metrics
+
 	logger *logger
 }
 
 func NewBroker(opts ...broker.Option) broker.Broker {
-	options := broker.NewOptionsAndApply(opts...)
+	options := broker.NewOptionsAndApply(append([]broker.Option{broker.WithMaxMessageSize(defaultMaxMessageSize)}, opts...)...)
 
 	return &rocketmqBroker{
 		options:     options,
@@ -87,50 +97,36 @@ func (r *rocketmqBroker) Init(opts ...broker.Option) error {
 
 	rlog.SetLogger(r.logger)
 
-	if v, ok := r.options.Context.Value(rocketmqOption.NameServersKey{}).([]string); ok {
-		r.nameServers = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.NameServerUrlKey{}).(string); ok {
-		r.nameServerUrl = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.AccessKey{}).(string); ok {
-		r.credentials.AccessKey = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.SecretKey{}).(string); ok {
-		r.credentials.AccessSecret = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.SecurityTokenKey{}).(string); ok {
-		r.credentials.SecurityToken = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.CredentialsKey{}).(*rocketmqOption.Credentials); ok {
-		r.credentials = *v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.RetryCountKey{}).(int); ok {
-		r.retryCount = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.NamespaceKey{}).(string); ok {
-		r.namespace = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.InstanceNameKey{}).(string); ok {
-		r.instanceName = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.GroupNameKey{}).(string); ok {
-		r.groupName = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.EnableTraceKey{}).(bool); ok {
-		r.enableTrace = v
-	}
+	c := rocketmqOption.ConfigFromOptions(r.options)
+	r.nameServers = c.NameServers
+	r.nameServerUrl = c.NameServerUrl
+	r.credentials = c.Credentials
+	r.retryCount = c.RetryCount
+	r.namespace = c.Namespace
+	r.instanceName = c.InstanceName
+	r.groupName = c.GroupName
+	r.enableTrace = c.EnableTrace
+	r.logger.level = c.LoggerLevel
 
-	if v, ok := r.options.Context.Value(rocketmqOption.LoggerLevelKey{}).(log.Level); ok {
-		r.logger.level = v
-	}
+	r.mode = broker.ModeFromContext(r.options.Context)
 
 	if len(r.options.Tracings) > 0 {
 		r.producerTracer = tracing.NewTracer(trace.SpanKindProducer, "rocketmq-producer", r.options.Tracings...)
 		r.consumerTracer = tracing.NewTracer(trace.SpanKindConsumer, "rocketmq-consumer", r.options.Tracings...)
 	}
 
-	return nil
+	r.metrics = broker.NewMetrics("rocketmq", r.options.MeterProvider)
+
+	return r.Validate()
+}
+
+// Validate check the options set at Init.
+func (r *rocketmqBroker) Validate() error {
+	v := broker.NewConfigValidator(r.Name())
+	v.Require((r.credentials.AccessKey == "") == (r.credentials.AccessSecret == ""),
+		"access key and secret key must be set together")
+	v.Require(r.retryCount >= 0, "retry count %d must not be negative", r.retryCount)
+	return v.Err()
 }
 
 func (r *rocketmqBroker) Connect() error {
@@ -141,12 +137,20 @@ func (r *rocketmqBroker) Connect() error {
 	}
 	r.RUnlock()
 
-	p, err := r.createProducer()
-	if err != nil {
-		return err
+	if r.mode.CanPublish() == nil {
+		p, err := r.createProducer()
+		if err != nil {
+			return err
+		}
+
+		_ = p.Shutdown()
 	}
 
-	_ = p.Shutdown()
+	if t, ok := broker.VerifyOnStartFromContext(r.options.Context); ok {
+		if err := r.verifyTopology(r.options.Context, t); err != nil {
+			return err
+		}
+	}
 
 	r.Lock()
 	r.connected = true
@@ -265,9 +269,11 @@ func (r *rocketmqBroker) createConsumer(options *broker.SubscribeOptions) (rocke
 	resolver := r.createNsResolver()
 	consumerOptions = append(consumerOptions, consumer.WithNsResolver(resolver))
 
-	if v, ok := options.Context.Value(rocketmqOption.ConsumerModelKey{}).(rocketmqOption.MessageModel); ok {
+	sc := rocketmqOption.SubscribeConfigFromOptions(*options)
+
+	if sc.ConsumerModel != "" {
 		var m consumer.MessageModel
-		switch v {
+		switch sc.ConsumerModel {
 		case rocketmqOption.MessageModelClustering:
 			m = consumer.Clustering
 		case rocketmqOption.MessageModelBroadCasting:
@@ -298,22 +304,70 @@ func (r *rocketmqBroker) createConsumer(options *broker.SubscribeOptions) (rocke
 }
 
 func (r *rocketmqBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
-	buf, err := broker.Marshal(r.options.Codec, msg)
+	if err := r.mode.CanPublish(); err != nil {
+		return err
+	}
+
+	rMsg, options, err := r.newMessage(ctx, topic, msg, opts)
 	if err != nil {
 		return err
 	}
 
-	return r.publish(ctx, topic, buf, opts...)
+	start := time.Now()
+	err = r.publish(topic, rMsg, options)
+	r.metrics.RecordPublish(ctx, topic, start, err)
+
+	return err
 }
 
-func (r *rocketmqBroker) publish(ctx context.Context, topic string, msg []byte, opts ...broker.PublishOption) error {
+// newMessage encode msg and run the publish interceptors, it returns the
+// message to send to topic with the options of the publish.
+func (r *rocketmqBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) (*primitive.Message, broker.PublishOptions, error) {
 	options := broker.PublishOptions{
 		Context: ctx,
 	}
+
+	buf, err := broker.Marshal(r.options.Codec, msg)
+	if err != nil {
+		return nil, options, err
+	}
+	buf, opts, err = r.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return nil, options, err
+	}
+
 	for _, o := range opts {
 		o(&options)
 	}
 
+	rMsg := primitive.NewMessage(topic, buf)
+
+	c := rocketmqOption.PublishConfigFromOptions(options)
+	rMsg.Compress = c.Compress
+	rMsg.Batch = c.Batch
+	if c.Properties != nil {
+		rMsg.WithProperties(c.Properties)
+	}
+	for k, v := range options.Headers {
+		rMsg.WithProperty(k, v)
+	}
+	if c.DelayTimeLevel != 0 {
+		rMsg.WithDelayTimeLevel(c.DelayTimeLevel)
+	}
+	if c.Tag != "" {
+		rMsg.WithTag(c.Tag)
+	}
+	if c.Keys != nil {
+		rMsg.WithKeys(c.Keys)
+	}
+	if c.ShardingKey != "" {
+		rMsg.WithShardingKey(c.ShardingKey)
+	}
+
+	return rMsg, options, nil
+}
+
+func (r *rocketmqBroker) publish(topic string, rMsg *primitive.Message, options broker.PublishOptions) error {
 	var cached bool
 
 	r.Lock()
@@ -332,30 +386,6 @@ func (r *rocketmqBroker) publish(ctx context.Context, topic string, msg []byte,
 	}
 	r.Unlock()
 
-	rMsg := primitive.NewMessage(topic, msg)
-
-	if v, ok := options.Context.Value(rocketmqOption.CompressKey{}).(bool); ok {
-		rMsg.Compress = v
-	}
-	if v, ok := options.Context.Value(rocketmqOption.BatchKey{}).(bool); ok {
-		rMsg.Batch = v
-	}
-	if v, ok := options.Context.Value(rocketmqOption.PropertiesKey{}).(map[string]string); ok {
-		rMsg.WithProperties(v)
-	}
-	if v, ok := options.Context.Value(rocketmqOption.DelayTimeLevelKey{}).(int); ok {
-		rMsg.WithDelayTimeLevel(v)
-	}
-	if v, ok := options.Context.Value(rocketmqOption.TagsKey{}).(string); ok {
-		rMsg.WithTag(v)
-	}
-	if v, ok := options.Context.Value(rocketmqOption.KeysKey{}).([]string); ok {
-		rMsg.WithKeys(v)
-	}
-	if v, ok := options.Context.Value(rocketmqOption.ShardingKeyKey{}).(string); ok {
-		rMsg.WithShardingKey(v)
-	}
-
 	span := r.startProducerSpan(options.Context, rMsg)
 
 	var err error
@@ -398,7 +428,16 @@ func (r *rocketmqBroker) publish(ctx context.Context, topic string, msg []byte,
 	return err
 }
 
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (r *rocketmqBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return r.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
+
 func (r *rocketmqBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	if err := r.mode.CanSubscribe(); err != nil {
+		return nil, err
+	}
+
 	options := broker.SubscribeOptions{
 		Context: context.Background(),
 		AutoAck: true,
@@ -408,6 +447,19 @@ func (r *rocketmqBroker) Subscribe(topic string, handler broker.Handler, binder
 		o(&options)
 	}
 
+	v := broker.NewConfigValidator(r.Name())
+	v.Require(options.Queue != "", "group name is required to subscribe topic %s", topic)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	handler = options.TimeoutHandler(handler)
+	handler = r.metrics.Handler(topic, handler)
+
+	if options.RawBody {
+		binder = nil
+	}
+
 	c, err := r.createConsumer(&options)
 	if err != nil {
 		return nil, err
@@ -420,7 +472,12 @@ func (r *rocketmqBroker) Subscribe(topic string, handler broker.Handler, binder
 		reader:  c,
 	}
 
-	if err = c.Subscribe(topic, consumer.MessageSelector{},
+	var selector consumer.MessageSelector
+	if tag := rocketmqOption.SubscribeConfigFromOptions(options).Tag; tag != "" {
+		selector = consumer.MessageSelector{Type: consumer.TAG, Expression: tag}
+	}
+
+	if err = c.Subscribe(topic, selector,
 		func(ctx context.Context, msgs ...*primitive.MessageExt) (consumer.ConsumeResult, error) {
 			//r.logger.Infof("[rocketmq] subscribe callback: %v \n", msgs)
 
@@ -439,10 +496,20 @@ func (r *rocketmqBroker) Subscribe(topic string, handler broker.Handler, binder
 					m.Body = msg.Body
 				}
 
-				if errSub = broker.Unmarshal(r.options.Codec, msg.Body, &m.Body); errSub != nil {
+				if errSub = r.options.Decode(&sub.options, m.Headers, msg.Body, &m.Body); errSub != nil {
 					p.err = errSub
 					r.logger.Errorf("%s", errSub.Error())
+					sub.options.ReportError(newCtx, broker.ErrUnmarshal, errSub, p)
 					r.finishConsumerSpan(span, errSub)
+
+					switch sub.options.HandleUnmarshalFailure(newCtx, msg.Topic, m.Headers, msg.Body, errSub) {
+					case broker.UnmarshalActionRetry:
+						return consumer.ConsumeRetryLater, nil
+					case broker.UnmarshalActionStop:
+						// unsubscribe outside of the consume callback.
+						go func() { _ = sub.Unsubscribe(true) }()
+						return consumer.ConsumeRetryLater, nil
+					}
 					continue
 				}
 
@@ -453,8 +520,9 @@ func (r *rocketmqBroker) Subscribe(topic string, handler broker.Handler, binder
 				}
 
 				if sub.options.AutoAck {
-					if errSub = p.Ack(); errSub != nil {
+					if errSub = broker.AutoAck(p); errSub != nil {
 						r.logger.Errorf("unable to commit msg: %v", errSub)
+						sub.options.ReportError(newCtx, broker.ErrAck, errSub, p)
 					}
 				}
 
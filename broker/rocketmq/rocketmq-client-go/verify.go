@@ -0,0 +1,36 @@
+package rocketmqClientGo
+
+import (
+	"context"
+
+	"github.com/apache/rocketmq-client-go/v2/admin"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// verifyTopology check the topics of t exist on the name servers. The groups
+// can only be listed per broker address, they are reported as unsupported.
+func (r *rocketmqBroker) verifyTopology(ctx context.Context, t broker.Topology) error {
+	opts := []admin.AdminOption{admin.WithResolver(r.createNsResolver())}
+	if credentials := r.makeCredentials(); credentials.AccessKey != "" && credentials.SecretKey != "" {
+		opts = append(opts, admin.WithCredentials(*credentials))
+	}
+	if r.namespace != "" {
+		opts = append(opts, admin.WithNamespace(r.namespace))
+	}
+
+	adm, err := admin.NewAdmin(opts...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = adm.Close()
+	}()
+
+	return broker.VerifyTopology(ctx, "rocketmq", t, map[string]broker.TopologyChecker{
+		broker.ResourceTopic: func(ctx context.Context, name string) error {
+			_, err := adm.FetchPublishMessageQueues(ctx, name)
+			return err
+		},
+	})
+}
@@ -13,4 +13,7 @@ const (
 	defaultInvisibleDuration = time.Second * 20
 
 	defaultReceiveInterval = time.Second * 3
+
+	// defaultMaxMessageSize is the default maxMessageSize of the brokers.
+	defaultMaxMessageSize = 4 * 1024 * 1024
 )
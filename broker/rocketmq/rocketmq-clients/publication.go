@@ -9,6 +9,8 @@ import (
 )
 
 type publication struct {
+	broker.AckState
+
 	topic string
 	err   error
 	ctx   context.Context
@@ -32,12 +34,14 @@ func (p *publication) RawMessage() interface{} {
 }
 
 func (p *publication) Ack() error {
-	if p.reader == nil {
-		p.err = errors.New("reader is nil")
+	return p.AckState.Ack(func() error {
+		if p.reader == nil {
+			p.err = errors.New("reader is nil")
+			return p.err
+		}
+		p.err = p.reader.Ack(p.ctx, p.rmqMessage)
 		return p.err
-	}
-	p.err = p.reader.Ack(p.ctx, p.rmqMessage)
-	return p.err
+	})
 }
 
 func (p *publication) Error() error {
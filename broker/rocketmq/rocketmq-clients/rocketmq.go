@@ -54,10 +54,12 @@ type rocketmqBroker struct {
 
 	producerTracer *tracing.Tracer
 	consumerTracer *tracing.Tracer
+
+	metrics *broker.Metrics
 }
 
 func NewBroker(opts ...broker.Option) broker.Broker {
-	rocketmqOptions := broker.NewOptionsAndApply(opts...)
+	rocketmqOptions := broker.NewOptionsAndApply(append([]broker.Option{broker.WithMaxMessageSize(defaultMaxMessageSize)}, opts...)...)
 
 	return &rocketmqBroker{
 		options:           rocketmqOptions,
@@ -95,71 +97,41 @@ func (r *rocketmqBroker) Init(opts ...broker.Option) error {
 	// init logger
 	rmqClient.ResetLogger()
 	_ = os.Setenv(rmqClient.ENABLE_CONSOLE_APPENDER, "true")
-	_ = os.Setenv(rmqClient.CLIENT_LOG_LEVEL, "info")
 
-	if v, ok := r.options.Context.Value(rocketmqOption.NameServersKey{}).([]string); ok {
-		r.nameServers = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.NameServerUrlKey{}).(string); ok {
-		r.nameServerUrl = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.AccessKey{}).(string); ok {
-		r.credentials.AccessKey = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.SecretKey{}).(string); ok {
-		r.credentials.AccessSecret = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.SecurityTokenKey{}).(string); ok {
-		r.credentials.SecurityToken = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.CredentialsKey{}).(*rocketmqOption.Credentials); ok {
-		r.credentials = *v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.RetryCountKey{}).(int); ok {
-		r.retryCount = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.NamespaceKey{}).(string); ok {
-		r.namespace = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.InstanceNameKey{}).(string); ok {
-		r.instanceName = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.GroupNameKey{}).(string); ok {
-		r.groupName = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.EnableTraceKey{}).(bool); ok {
-		r.enableTrace = v
-	}
+	c := rocketmqOption.ConfigFromOptions(r.options)
 
-	if v, ok := r.options.Context.Value(rocketmqOption.SubscriptionExpressionsKey{}).(map[string]*rmqClient.FilterExpression); ok {
-		r.subscriptionExpressions = v
+	strLevel := "info"
+	switch c.LoggerLevel {
+	case log.LevelDebug:
+		strLevel = "debug"
+	case log.LevelWarn:
+		strLevel = "warn"
+	case log.LevelError:
+		strLevel = "error"
 	}
-	if v, ok := r.options.Context.Value(rocketmqOption.AwaitDurationKey{}).(time.Duration); ok {
-		r.awaitDuration = v
-	}
-	if v, ok := r.options.Context.Value(rocketmqOption.MaxMessageNumKey{}).(int32); ok {
-		r.maxMessageNum = v
+	_ = os.Setenv(rmqClient.CLIENT_LOG_LEVEL, strLevel)
+
+	r.nameServers = c.NameServers
+	r.nameServerUrl = c.NameServerUrl
+	r.credentials = c.Credentials
+	r.retryCount = c.RetryCount
+	r.namespace = c.Namespace
+	r.instanceName = c.InstanceName
+	r.groupName = c.GroupName
+	r.enableTrace = c.EnableTrace
+
+	r.subscriptionExpressions = c.SubscriptionExpressions
+	if c.AwaitDuration != 0 {
+		r.awaitDuration = c.AwaitDuration
 	}
-	if v, ok := r.options.Context.Value(rocketmqOption.InvisibleDurationKey{}).(time.Duration); ok {
-		r.invisibleDuration = v
+	if c.MaxMessageNum != 0 {
+		r.maxMessageNum = c.MaxMessageNum
 	}
-	if v, ok := r.options.Context.Value(rocketmqOption.ReceiveIntervalKey{}).(time.Duration); ok {
-		r.receiveInterval = v
+	if c.InvisibleDuration != 0 {
+		r.invisibleDuration = c.InvisibleDuration
 	}
-
-	if v, ok := r.options.Context.Value(rocketmqOption.LoggerLevelKey{}).(log.Level); ok {
-		var strLevel string
-		switch v {
-		case log.LevelDebug:
-			strLevel = "debug"
-		case log.LevelWarn:
-			strLevel = "warn"
-		case log.LevelError:
-			strLevel = "error"
-		case log.LevelInfo:
-			strLevel = "info"
-		}
-		_ = os.Setenv(rmqClient.CLIENT_LOG_LEVEL, strLevel)
+	if c.ReceiveInterval != 0 {
+		r.receiveInterval = c.ReceiveInterval
 	}
 
 	if len(r.options.Tracings) > 0 {
@@ -167,7 +139,20 @@ func (r *rocketmqBroker) Init(opts ...broker.Option) error {
 		r.consumerTracer = tracing.NewTracer(trace.SpanKindConsumer, "rocketmq-consumer", r.options.Tracings...)
 	}
 
-	return nil
+	r.metrics = broker.NewMetrics("rocketmq", r.options.MeterProvider)
+
+	return r.Validate()
+}
+
+// Validate check the options set at Init, the v5 client connects to the
+// first name server as its endpoint.
+func (r *rocketmqBroker) Validate() error {
+	v := broker.NewConfigValidator(r.Name())
+	v.Require(len(r.nameServers) > 0, "name server endpoint is required")
+	v.Require((r.credentials.AccessKey == "") == (r.credentials.AccessSecret == ""),
+		"access key and secret key must be set together")
+	v.Require(r.retryCount >= 0, "retry count %d must not be negative", r.retryCount)
+	return v.Err()
 }
 
 func (r *rocketmqBroker) Connect() error {
@@ -216,22 +201,66 @@ func (r *rocketmqBroker) Disconnect() error {
 }
 
 func (r *rocketmqBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
-	buf, err := broker.Marshal(r.options.Codec, msg)
+	rMsg, options, err := r.newMessage(ctx, topic, msg, opts)
 	if err != nil {
 		return err
 	}
 
-	return r.publish(ctx, topic, buf, opts...)
+	start := time.Now()
+	err = r.publish(topic, rMsg, options)
+	r.metrics.RecordPublish(ctx, topic, start, err)
+
+	return err
 }
 
-func (r *rocketmqBroker) publish(ctx context.Context, topic string, msg []byte, opts ...broker.PublishOption) error {
+// newMessage encode msg and run the publish interceptors, it returns the
+// message to send to topic with the options of the publish.
+func (r *rocketmqBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) (*rmqClient.Message, broker.PublishOptions, error) {
 	rocketmqOptions := broker.PublishOptions{
 		Context: ctx,
 	}
+
+	buf, err := broker.Marshal(r.options.Codec, msg)
+	if err != nil {
+		return nil, rocketmqOptions, err
+	}
+	buf, opts, err = r.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return nil, rocketmqOptions, err
+	}
+
 	for _, o := range opts {
 		o(&rocketmqOptions)
 	}
 
+	rMsg := &rmqClient.Message{
+		Topic: topic,
+		Body:  buf,
+	}
+	c := rocketmqOption.PublishConfigFromOptions(rocketmqOptions)
+	for pk, pv := range c.Properties {
+		rMsg.AddProperty(pk, pv)
+	}
+	for pk, pv := range rocketmqOptions.Headers {
+		rMsg.AddProperty(pk, pv)
+	}
+	if c.Tag != "" {
+		rMsg.SetTag(c.Tag)
+	}
+	if c.Keys != nil {
+		rMsg.SetKeys(c.Keys...)
+	}
+	if !c.DeliveryTimestamp.IsZero() {
+		rMsg.SetDelayTimestamp(c.DeliveryTimestamp)
+	}
+	if c.MessageGroup != "" {
+		rMsg.SetMessageGroup(c.MessageGroup)
+	}
+
+	return rMsg, rocketmqOptions, nil
+}
+
+func (r *rocketmqBroker) publish(topic string, rMsg *rmqClient.Message, rocketmqOptions broker.PublishOptions) error {
 	r.Lock()
 	producer, ok := r.producers[topic]
 	if !ok {
@@ -246,42 +275,12 @@ func (r *rocketmqBroker) publish(ctx context.Context, topic string, msg []byte,
 	}
 	r.Unlock()
 
-	rMsg := &rmqClient.Message{
-		Topic: topic,
-		Body:  msg,
-	}
-	if v, ok := rocketmqOptions.Context.Value(rocketmqOption.PropertiesKey{}).(map[string]string); ok {
-		for pk, pv := range v {
-			rMsg.AddProperty(pk, pv)
-		}
-	}
-	if v, ok := rocketmqOptions.Context.Value(rocketmqOption.TagsKey{}).(string); ok {
-		rMsg.SetTag(v)
-	}
-	if v, ok := rocketmqOptions.Context.Value(rocketmqOption.KeysKey{}).([]string); ok {
-		rMsg.SetKeys(v...)
-	}
-	if v, ok := rocketmqOptions.Context.Value(rocketmqOption.DeliveryTimestampKey{}).(time.Time); ok {
-		rMsg.SetDelayTimestamp(v)
-	}
-	if v, ok := rocketmqOptions.Context.Value(rocketmqOption.MessageGroupKey{}).(string); ok {
-		rMsg.SetMessageGroup(v)
-	}
-
-	var sendAsync bool
-	if v, ok := rocketmqOptions.Context.Value(rocketmqOption.SendAsyncKey{}).(bool); ok {
-		sendAsync = v
-	}
-
-	var sendWithTransaction bool
-	if v, ok := rocketmqOptions.Context.Value(rocketmqOption.SendWithTransactionKey{}).(bool); ok {
-		sendWithTransaction = v
-	}
+	c := rocketmqOption.PublishConfigFromOptions(rocketmqOptions)
 
 	var err error
-	if sendWithTransaction {
+	if c.SendWithTransaction {
 		err = r.doSendTransaction(rocketmqOptions.Context, producer, rMsg)
-	} else if sendAsync {
+	} else if c.SendAsync {
 		err = r.doSendAsync(rocketmqOptions.Context, producer, rMsg)
 	} else {
 		err = r.doSend(rocketmqOptions.Context, producer, rMsg)
@@ -345,6 +344,11 @@ func (r *rocketmqBroker) doSendTransaction(ctx context.Context, producer rmqClie
 	return nil
 }
 
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (r *rocketmqBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return r.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
+
 func (r *rocketmqBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
 	rocketmqOptions := &broker.SubscribeOptions{
 		Context: context.Background(),
@@ -355,6 +359,15 @@ func (r *rocketmqBroker) Subscribe(topic string, handler broker.Handler, binder
 		o(rocketmqOptions)
 	}
 
+	v := broker.NewConfigValidator(r.Name())
+	v.Require(r.groupName != "", "consumer group name is required to subscribe topic %s", topic)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	handler = rocketmqOptions.TimeoutHandler(handler)
+	handler = r.metrics.Handler(topic, handler)
+
 	if r.consumer == nil {
 		c, err := r.createConsumer(rocketmqOptions)
 		if err != nil {
@@ -373,9 +386,13 @@ func (r *rocketmqBroker) Subscribe(topic string, handler broker.Handler, binder
 		done:    make(chan error),
 	}
 
+	sc := rocketmqOption.SubscribeConfigFromOptions(*rocketmqOptions)
+
 	var filterExpression *rmqClient.FilterExpression
-	if v, ok := rocketmqOptions.Context.Value(rocketmqOption.SubscriptionFilterExpressionKey{}).(*rmqClient.FilterExpression); ok {
-		filterExpression = v
+	if sc.FilterExpression != nil {
+		filterExpression = sc.FilterExpression
+	} else if sc.Tag != "" {
+		filterExpression = rmqClient.NewFilterExpression(sc.Tag)
 	} else {
 		filterExpression = rmqClient.SUB_ALL
 	}
@@ -492,7 +509,7 @@ func (r *rocketmqBroker) run() {
 			r.finishConsumerSpan(span, nil)
 		}
 
-		time.Sleep(r.receiveInterval)
+		r.options.Clock.Sleep(r.receiveInterval)
 	}
 }
 
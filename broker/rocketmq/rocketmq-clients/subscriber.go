@@ -76,7 +76,7 @@ func (s *subscriber) onMessage(ctx context.Context, msg *rmqClient.MessageView)
 
 	outMessage := broker.Message{}
 
-	if s.binder != nil {
+	if s.binder != nil && !s.options.RawBody {
 		outMessage.Body = s.binder()
 	} else {
 		outMessage.Body = msg.GetBody()
@@ -92,8 +92,16 @@ func (s *subscriber) onMessage(ctx context.Context, msg *rmqClient.MessageView)
 		rmqMessage: msg,
 	}
 
-	if p.err = broker.Unmarshal(s.r.options.Codec, msg.GetBody(), &outMessage.Body); p.err != nil {
-		//log.Error("[redis]", err)
+	if p.err = s.r.options.Decode(&s.options, outMessage.Headers, msg.GetBody(), &outMessage.Body); p.err != nil {
+		s.options.ReportError(ctx, broker.ErrUnmarshal, p.err, &p)
+		switch s.options.HandleUnmarshalFailure(ctx, p.topic, outMessage.Headers, msg.GetBody(), p.err) {
+		case broker.UnmarshalActionAck:
+			if err := p.Ack(); err != nil {
+				s.options.ReportError(ctx, broker.ErrAck, err, &p)
+			}
+		case broker.UnmarshalActionStop:
+			_ = s.Unsubscribe(true)
+		}
 		return p.err
 	}
 
@@ -102,7 +110,8 @@ func (s *subscriber) onMessage(ctx context.Context, msg *rmqClient.MessageView)
 	}
 
 	if s.options.AutoAck {
-		if p.err = p.Ack(); p.err != nil {
+		if p.err = broker.AutoAck(&p); p.err != nil {
+			s.options.ReportError(ctx, broker.ErrAck, p.err, &p)
 			return p.err
 		}
 	}
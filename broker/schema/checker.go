@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Checker compares the schemas a service publishes with the versions of a
+// store. Call Check at startup, before publishing, or from a test to fail CI:
+//
+//	c := schema.NewChecker(schema.NewFileStore("schemas"))
+//	_ = c.AddStruct("orders", Order{})
+//	_ = c.AddDescriptor("payments", (&pb.Payment{}).ProtoReflect().Descriptor())
+//	if err := c.Check(ctx); err != nil {
+//		return err
+//	}
+type Checker struct {
+	store         Store
+	compatibility Compatibility
+
+	schemas []*Schema
+}
+
+func NewChecker(store Store, opts ...Option) *Checker {
+	c := &Checker{
+		store:         store,
+		compatibility: CompatibilityForward,
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
+}
+
+// Add check s with the other schemas.
+func (c *Checker) Add(s *Schema) {
+	c.schemas = append(c.schemas, s)
+}
+
+// AddStruct check the schema of the struct v published to subject.
+func (c *Checker) AddStruct(subject string, v interface{}) error {
+	s, err := FromStruct(subject, v)
+	if err != nil {
+		return err
+	}
+	c.Add(s)
+	return nil
+}
+
+// AddDescriptor check the schema of the protobuf message md published to subject.
+func (c *Checker) AddDescriptor(subject string, md protoreflect.MessageDescriptor) error {
+	s, err := FromDescriptor(subject, md)
+	if err != nil {
+		return err
+	}
+	c.Add(s)
+	return nil
+}
+
+// Check compare every schema with the latest version of its subject, the
+// returned error joins an IncompatibleError per incompatible subject. The
+// subjects without version are compatible.
+func (c *Checker) Check(ctx context.Context) error {
+	var errs []error
+	for _, s := range c.schemas {
+		latest, err := c.store.Latest(ctx, s.Subject)
+		if errors.Is(err, ErrSchemaNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err = Check(latest, s, c.compatibility); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Register check the schemas, then record in the store those which changed
+// since their latest version. Nothing is recorded when a schema is incompatible.
+func (c *Checker) Register(ctx context.Context) error {
+	if err := c.Check(ctx); err != nil {
+		return err
+	}
+
+	for _, s := range c.schemas {
+		latest, err := c.store.Latest(ctx, s.Subject)
+		if err != nil && !errors.Is(err, ErrSchemaNotFound) {
+			return err
+		}
+		if latest != nil && reflect.DeepEqual(latest.Fields, s.Fields) {
+			s.Version = latest.Version
+			continue
+		}
+		if err = c.store.Register(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrIncompatible = errors.New("schema: incompatible change")
+
+// Compatibility is the kind of changes allowed between two versions of a schema.
+type Compatibility int
+
+const (
+	// CompatibilityForward keeps the consumers of the previous version able
+	// to read the new messages: no required field is removed, no type changes.
+	CompatibilityForward Compatibility = iota
+	// CompatibilityBackward keeps the new version able to read the previous
+	// messages: no required field is added, no type changes.
+	CompatibilityBackward
+	// CompatibilityFull is both forward and backward.
+	CompatibilityFull
+	// CompatibilityNone allows every change.
+	CompatibilityNone
+)
+
+func (c Compatibility) String() string {
+	switch c {
+	case CompatibilityForward:
+		return "forward"
+	case CompatibilityBackward:
+		return "backward"
+	case CompatibilityFull:
+		return "full"
+	case CompatibilityNone:
+		return "none"
+	default:
+		return "unknown"
+	}
+}
+
+// IncompatibleError lists the incompatible changes of a subject.
+type IncompatibleError struct {
+	Subject  string
+	Version  int
+	Problems []string
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("schema: %s is incompatible with version %d, %d problem(s): %s",
+		e.Subject, e.Version, len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+func (e *IncompatibleError) Unwrap() error {
+	return ErrIncompatible
+}
+
+// Check compare next with the previous version of the schema, it returns an
+// IncompatibleError listing every change c does not allow.
+func Check(previous, next *Schema, c Compatibility) error {
+	if previous == nil || c == CompatibilityNone {
+		return nil
+	}
+
+	var problems []string
+	compareFields("", previous.Fields, next.Fields, c, &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return &IncompatibleError{Subject: next.Subject, Version: previous.Version, Problems: problems}
+}
+
+func compareFields(prefix string, previous, next []Field, c Compatibility, problems *[]string) {
+	forward := c == CompatibilityForward || c == CompatibilityFull
+	backward := c == CompatibilityBackward || c == CompatibilityFull
+
+	nextByName := make(map[string]Field, len(next))
+	for _, f := range next {
+		nextByName[f.Name] = f
+	}
+	previousByName := make(map[string]Field, len(previous))
+	for _, f := range previous {
+		previousByName[f.Name] = f
+	}
+
+	for _, pf := range previous {
+		path := prefix + pf.Name
+
+		nf, ok := nextByName[pf.Name]
+		if !ok {
+			if forward && pf.Required {
+				*problems = append(*problems, fmt.Sprintf("required field %s removed", path))
+			}
+			continue
+		}
+
+		if pf.Type != nf.Type {
+			*problems = append(*problems, fmt.Sprintf("field %s type changed from %s to %s", path, pf.Type, nf.Type))
+			continue
+		}
+		if pf.Number != nf.Number {
+			*problems = append(*problems, fmt.Sprintf("field %s number changed from %d to %d", path, pf.Number, nf.Number))
+		}
+		if forward && pf.Required && !nf.Required {
+			*problems = append(*problems, fmt.Sprintf("field %s is no longer required", path))
+		}
+		if backward && !pf.Required && nf.Required {
+			*problems = append(*problems, fmt.Sprintf("field %s became required", path))
+		}
+
+		compareFields(path+".", pf.Fields, nf.Fields, c, problems)
+	}
+
+	for _, nf := range next {
+		if _, ok := previousByName[nf.Name]; ok {
+			continue
+		}
+		if backward && nf.Required {
+			*problems = append(*problems, fmt.Sprintf("required field %s added", prefix+nf.Name))
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package schema
+
+type Option func(c *Checker)
+
+// WithCompatibility set the changes allowed, default is CompatibilityForward
+// so the consumers of the previous version keep working.
+func WithCompatibility(compatibility Compatibility) Option {
+	return func(c *Checker) {
+		c.compatibility = compatibility
+	}
+}
@@ -0,0 +1,177 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Schema is the shape of the messages published to a subject, usually a topic.
+type Schema struct {
+	Subject string  `json:"subject"`
+	Version int     `json:"version"`
+	Fields  []Field `json:"fields"`
+}
+
+// Field is a field of a schema, Fields lists the fields of objects and of
+// arrays or maps of objects.
+type Field struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Number   int     `json:"number,omitempty"`
+	Required bool    `json:"required,omitempty"`
+	Fields   []Field `json:"fields,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// FromStruct return the schema of the struct v, or of the struct v points to.
+// Fields are named after their json tag, the ones without omitempty which are
+// not pointers are required.
+func FromStruct(subject string, v interface{}) (*Schema, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema: %s: %T is not a struct", subject, v)
+	}
+
+	return &Schema{Subject: subject, Fields: structFields(t, map[reflect.Type]bool{})}, nil
+}
+
+func structFields(t reflect.Type, seen map[reflect.Type]bool) []Field {
+	// recursive types are described once.
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, structFields(ft, seen)...)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		f := Field{
+			Name:     name,
+			Required: sf.Type.Kind() != reflect.Pointer && !strings.Contains(opts, "omitempty"),
+		}
+		f.Type, f.Fields = goType(sf.Type, seen)
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func goType(t reflect.Type, seen map[reflect.Type]bool) (string, []Field) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return "timestamp", nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", nil
+	case reflect.Float32, reflect.Float64:
+		return "number", nil
+	case reflect.String:
+		return "string", nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes", nil
+		}
+		elem, fields := goType(t.Elem(), seen)
+		return "array<" + elem + ">", fields
+	case reflect.Map:
+		elem, fields := goType(t.Elem(), seen)
+		return "map<" + elem + ">", fields
+	case reflect.Struct:
+		return "object", structFields(t, seen)
+	default:
+		return "any", nil
+	}
+}
+
+// FromDescriptor return the schema of a protobuf message, fields keep their
+// proto name and number.
+func FromDescriptor(subject string, md protoreflect.MessageDescriptor) (*Schema, error) {
+	if md == nil {
+		return nil, errors.New("schema: message descriptor is nil")
+	}
+	return &Schema{Subject: subject, Fields: protoFields(md, map[protoreflect.FullName]bool{})}, nil
+}
+
+func protoFields(md protoreflect.MessageDescriptor, seen map[protoreflect.FullName]bool) []Field {
+	if seen[md.FullName()] {
+		return nil
+	}
+	seen[md.FullName()] = true
+	defer delete(seen, md.FullName())
+
+	var fields []Field
+	fds := md.Fields()
+	for i := 0; i < fds.Len(); i++ {
+		fd := fds.Get(i)
+
+		f := Field{
+			Name:     string(fd.Name()),
+			Number:   int(fd.Number()),
+			Required: fd.Cardinality() == protoreflect.Required,
+		}
+
+		switch {
+		case fd.IsMap():
+			f.Type, f.Fields = protoType(fd.MapValue(), seen)
+			f.Type = "map<" + f.Type + ">"
+		case fd.IsList():
+			f.Type, f.Fields = protoType(fd, seen)
+			f.Type = "array<" + f.Type + ">"
+		default:
+			f.Type, f.Fields = protoType(fd, seen)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func protoType(fd protoreflect.FieldDescriptor, seen map[protoreflect.FullName]bool) (string, []Field) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return string(fd.Message().FullName()), protoFields(fd.Message(), seen)
+	case protoreflect.EnumKind:
+		return "enum", nil
+	default:
+		return fd.Kind().String(), nil
+	}
+}
@@ -0,0 +1,110 @@
+package schema
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type orderV1 struct {
+	ID     string    `json:"id"`
+	Amount int64     `json:"amount"`
+	Note   string    `json:"note,omitempty"`
+	Items  []item    `json:"items"`
+	At     time.Time `json:"at"`
+}
+
+type item struct {
+	SKU string `json:"sku"`
+}
+
+type orderV2 struct {
+	ID     string    `json:"id"`
+	Amount float64   `json:"amount"`
+	Items  []item    `json:"items"`
+	At     time.Time `json:"at"`
+	Coupon *string   `json:"coupon"`
+}
+
+type orderV3 struct {
+	ID       string    `json:"id"`
+	Amount   int64     `json:"amount"`
+	Items    []item    `json:"items"`
+	At       time.Time `json:"at"`
+	Currency string    `json:"currency"`
+}
+
+func TestFromStruct(t *testing.T) {
+	s, err := FromStruct("orders", &orderV1{})
+	assert.Nil(t, err)
+	assert.Equal(t, []Field{
+		{Name: "id", Type: "string", Required: true},
+		{Name: "amount", Type: "integer", Required: true},
+		{Name: "note", Type: "string"},
+		{Name: "items", Type: "array<object>", Required: true, Fields: []Field{{Name: "sku", Type: "string", Required: true}}},
+		{Name: "at", Type: "timestamp", Required: true},
+	}, s.Fields)
+
+	_, err = FromStruct("orders", "order")
+	assert.NotNil(t, err)
+}
+
+func TestFromDescriptor(t *testing.T) {
+	s, err := FromDescriptor("durations", (&durationpb.Duration{}).ProtoReflect().Descriptor())
+	assert.Nil(t, err)
+	assert.Equal(t, []Field{
+		{Name: "seconds", Type: "int64", Number: 1},
+		{Name: "nanos", Type: "int32", Number: 2},
+	}, s.Fields)
+}
+
+func TestCheck(t *testing.T) {
+	v1, _ := FromStruct("orders", orderV1{})
+	v2, _ := FromStruct("orders", orderV2{})
+	v3, _ := FromStruct("orders", orderV3{})
+
+	err := Check(v1, v2, CompatibilityForward)
+	assert.ErrorIs(t, err, ErrIncompatible)
+	assert.Equal(t, []string{"field amount type changed from integer to number"}, err.(*IncompatibleError).Problems)
+
+	// the optional note is dropped and a required currency is added.
+	assert.Nil(t, Check(v1, v3, CompatibilityForward))
+	err = Check(v1, v3, CompatibilityBackward)
+	assert.Equal(t, []string{"required field currency added"}, err.(*IncompatibleError).Problems)
+
+	err = Check(v3, v1, CompatibilityFull)
+	assert.Equal(t, []string{"required field currency removed"}, err.(*IncompatibleError).Problems)
+
+	assert.Nil(t, Check(v1, v2, CompatibilityNone))
+	assert.Nil(t, Check(nil, v2, CompatibilityFull))
+}
+
+func TestChecker(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(t.TempDir())
+
+	c := NewChecker(store)
+	assert.Nil(t, c.AddStruct("orders", orderV1{}))
+	assert.Nil(t, c.AddDescriptor("durations", (&durationpb.Duration{}).ProtoReflect().Descriptor()))
+	assert.Nil(t, c.Register(ctx))
+
+	// unchanged schemas are not recorded again.
+	assert.Nil(t, c.Register(ctx))
+	latest, err := store.Latest(ctx, "orders")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, latest.Version)
+
+	c = NewChecker(store)
+	assert.Nil(t, c.AddStruct("orders", orderV2{}))
+	assert.ErrorIs(t, c.Check(ctx), ErrIncompatible)
+	assert.ErrorIs(t, c.Register(ctx), ErrIncompatible)
+
+	c = NewChecker(store, WithCompatibility(CompatibilityNone))
+	assert.Nil(t, c.AddStruct("orders", orderV2{}))
+	assert.Nil(t, c.Register(ctx))
+	latest, _ = store.Latest(ctx, "orders")
+	assert.Equal(t, 2, latest.Version)
+}
@@ -0,0 +1,119 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var ErrSchemaNotFound = errors.New("schema: not found")
+
+// Store records the versions of the schemas.
+type Store interface {
+	// Latest return the last version of subject, ErrSchemaNotFound when there is none.
+	Latest(ctx context.Context, subject string) (*Schema, error)
+
+	// Register record s as the next version of its subject and set its version.
+	Register(ctx context.Context, s *Schema) error
+}
+
+// MemoryStore keeps the schemas in memory, for tests.
+type MemoryStore struct {
+	sync.RWMutex
+	versions map[string][]*Schema
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{versions: make(map[string][]*Schema)}
+}
+
+func (s *MemoryStore) Latest(_ context.Context, subject string) (*Schema, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	versions := s.versions[subject]
+	if len(versions) == 0 {
+		return nil, ErrSchemaNotFound
+	}
+	return versions[len(versions)-1], nil
+}
+
+func (s *MemoryStore) Register(_ context.Context, schema *Schema) error {
+	s.Lock()
+	defer s.Unlock()
+
+	schema.Version = len(s.versions[schema.Subject]) + 1
+	s.versions[schema.Subject] = append(s.versions[schema.Subject], schema)
+	return nil
+}
+
+// FileStore keeps the versions of every subject in a JSON file of a
+// directory, which can be committed with the code and checked in CI.
+type FileStore struct {
+	sync.Mutex
+	dir string
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (s *FileStore) path(subject string) string {
+	return filepath.Join(s.dir, url.PathEscape(subject)+".json")
+}
+
+func (s *FileStore) read(subject string) ([]*Schema, error) {
+	data, err := os.ReadFile(s.path(subject))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []*Schema
+	if err = json.Unmarshal(data, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (s *FileStore) Latest(_ context.Context, subject string) (*Schema, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	versions, err := s.read(subject)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, ErrSchemaNotFound
+	}
+	return versions[len(versions)-1], nil
+}
+
+func (s *FileStore) Register(_ context.Context, schema *Schema) error {
+	s.Lock()
+	defer s.Unlock()
+
+	versions, err := s.read(schema.Subject)
+	if err != nil {
+		return err
+	}
+
+	schema.Version = len(versions) + 1
+	versions = append(versions, schema)
+
+	data, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(schema.Subject), data, 0o644)
+}
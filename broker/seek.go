@@ -0,0 +1,122 @@
+package broker
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrSeekUnsupported = errors.New("subscriber does not support seek")
+
+type PositionKind int
+
+const (
+	PositionBeginning PositionKind = iota
+	PositionEnd
+	PositionTime
+	PositionOffset
+	PositionMessageID
+)
+
+// Position is where a subscription resumes consuming.
+type Position struct {
+	Kind PositionKind
+
+	// Time of PositionTime, the first message published at or after it.
+	// The positions of the events carry the publish time of their message.
+	Time time.Time
+
+	// Partition and Offset of PositionOffset, a negative Partition sets
+	// every partition to Offset.
+	Partition int
+	Offset    int64
+
+	// MessageID of PositionMessageID, serialized by the broker, e.g. pulsar.
+	MessageID []byte
+}
+
+// Beginning is the oldest message retained.
+func Beginning() Position {
+	return Position{Kind: PositionBeginning}
+}
+
+// End is the next message published.
+func End() Position {
+	return Position{Kind: PositionEnd}
+}
+
+// AtTime is the first message published at or after t.
+func AtTime(t time.Time) Position {
+	return Position{Kind: PositionTime, Time: t}
+}
+
+// AtOffset is offset of partition, every partition when it is negative.
+func AtOffset(partition int, offset int64) Position {
+	return Position{Kind: PositionOffset, Partition: partition, Offset: offset}
+}
+
+// AtMessageID is the message of id, serialized by the broker.
+func AtMessageID(id []byte) Position {
+	return Position{Kind: PositionMessageID, MessageID: id}
+}
+
+func (p Position) String() string {
+	switch p.Kind {
+	case PositionBeginning:
+		return "beginning"
+	case PositionEnd:
+		return "end"
+	case PositionTime:
+		return "time " + p.Time.Format(time.RFC3339Nano)
+	case PositionOffset:
+		return fmt.Sprintf("offset %d of partition %d", p.Offset, p.Partition)
+	case PositionMessageID:
+		return "message id " + hex.EncodeToString(p.MessageID)
+	default:
+		return fmt.Sprintf("position(%d)", int(p.Kind))
+	}
+}
+
+// Seeker is implemented by the subscribers of the brokers retaining the
+// messages, able to consume them again or to skip them.
+type Seeker interface {
+	Seek(ctx context.Context, pos Position) error
+}
+
+// Seek move sub to pos, e.g. to backfill or to skip a poisoned backlog.
+// ErrSeekUnsupported is returned when sub does not implement Seeker.
+func Seek(ctx context.Context, sub Subscriber, pos Position) error {
+	s, ok := sub.(Seeker)
+	if !ok {
+		return ErrSeekUnsupported
+	}
+	return s.Seek(ctx, pos)
+}
+
+// PositionEvent is implemented by the events of the brokers whose
+// subscribers implement Seeker.
+type PositionEvent interface {
+	// Position return where to resume after the event.
+	Position() Position
+}
+
+// EventPosition return the position after event, false when its broker
+// does not track one.
+func EventPosition(event Event) (Position, bool) {
+	pe, ok := event.(PositionEvent)
+	if !ok {
+		return Position{}, false
+	}
+	return pe.Position(), true
+}
+
+// WithStartPosition start a new subscription at pos, e.g.
+// AtTime(time.Now().Add(-time.Hour)) to backfill the last hour, on the
+// brokers whose subscribers implement Seeker. The others ignore it.
+func WithStartPosition(pos Position) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.StartPosition = &pos
+	}
+}
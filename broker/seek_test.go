@@ -0,0 +1,46 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type seekSubscriber struct {
+	Subscriber
+	pos *Position
+}
+
+func (s *seekSubscriber) Seek(_ context.Context, pos Position) error {
+	s.pos = &pos
+	return nil
+}
+
+func TestSeek(t *testing.T) {
+	ctx := context.Background()
+
+	sub := &seekSubscriber{}
+	assert.NoError(t, Seek(ctx, sub, AtOffset(2, 42)))
+	assert.Equal(t, &Position{Kind: PositionOffset, Partition: 2, Offset: 42}, sub.pos)
+
+	assert.ErrorIs(t, Seek(ctx, sub.Subscriber, Beginning()), ErrSeekUnsupported)
+}
+
+func TestPosition_String(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "beginning", Beginning().String())
+	assert.Equal(t, "end", End().String())
+	assert.Equal(t, "time 2024-05-01T12:00:00Z", AtTime(at).String())
+	assert.Equal(t, "offset 42 of partition -1", AtOffset(-1, 42).String())
+	assert.Equal(t, "message id 0801", AtMessageID([]byte{8, 1}).String())
+}
+
+func TestWithStartPosition(t *testing.T) {
+	at := time.Now().Add(-time.Hour)
+	options := NewSubscribeOptions(WithStartPosition(AtTime(at)))
+	assert.Equal(t, &Position{Kind: PositionTime, Time: at}, options.StartPosition)
+	assert.Nil(t, NewSubscribeOptions().StartPosition)
+}
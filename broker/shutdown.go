@@ -0,0 +1,136 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrConsumingStopped = errors.New("broker stopped consuming")
+	ErrBrokerClosed     = errors.New("broker closed")
+)
+
+// GracefulBroker supports a two-phase shutdown: StopConsuming drains the
+// handlers while publishing keeps working, Disconnect then waits for the
+// pending publishes before closing the connection.
+type GracefulBroker interface {
+	Broker
+	Drainer
+
+	StopConsuming(ctx context.Context) error
+}
+
+type gracefulBroker struct {
+	Broker
+
+	sync.Mutex
+
+	subscribers []Subscriber
+	stopped     bool
+	closed      bool
+
+	handlers   HandlerTracker
+	publishing sync.WaitGroup
+}
+
+// NewGracefulBroker wraps b to track in-flight handlers and publishes.
+func NewGracefulBroker(b Broker) GracefulBroker {
+	return &gracefulBroker{Broker: b}
+}
+
+// Shutdown stops consuming when b supports it, then disconnects.
+func Shutdown(ctx context.Context, b Broker) error {
+	var errs []error
+	if gb, ok := b.(GracefulBroker); ok {
+		if err := gb.StopConsuming(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := b.Disconnect(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (b *gracefulBroker) Subscribe(topic string, handler Handler, binder Binder, opts ...SubscribeOption) (Subscriber, error) {
+	b.Lock()
+	stopped := b.stopped
+	b.Unlock()
+	if stopped {
+		return nil, ErrConsumingStopped
+	}
+
+	sub, err := b.Broker.Subscribe(topic, b.handlers.Track(handler), binder, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.stopped {
+		_ = sub.Unsubscribe(true)
+		return nil, ErrConsumingStopped
+	}
+	b.subscribers = append(b.subscribers, sub)
+
+	return sub, nil
+}
+
+func (b *gracefulBroker) Publish(ctx context.Context, topic string, msg Any, opts ...PublishOption) error {
+	b.Lock()
+	if b.closed {
+		b.Unlock()
+		return ErrBrokerClosed
+	}
+	b.publishing.Add(1)
+	b.Unlock()
+	defer b.publishing.Done()
+
+	return b.Broker.Publish(ctx, topic, msg, opts...)
+}
+
+// StopConsuming unsubscribes every subscriber and waits until the running
+// handlers return or ctx is done, messages arriving meanwhile are rejected
+// with ErrConsumingStopped so they are redelivered.
+func (b *gracefulBroker) StopConsuming(ctx context.Context) error {
+	return b.Drain(ctx, nil)
+}
+
+// Drain is StopConsuming reporting the handlers still running to progress.
+func (b *gracefulBroker) Drain(ctx context.Context, progress chan<- DrainProgress) error {
+	b.Lock()
+	b.stopped = true
+	subscribers := b.subscribers
+	b.subscribers = nil
+	b.Unlock()
+	b.handlers.Stop()
+
+	var errs []error
+	for _, sub := range subscribers {
+		if err := sub.Unsubscribe(true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := b.handlers.Drain(ctx, progress); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Disconnect rejects new publishes and waits for the pending ones before
+// disconnecting.
+func (b *gracefulBroker) Disconnect() error {
+	b.Lock()
+	b.stopped = true
+	b.closed = true
+	b.Unlock()
+	b.handlers.Stop()
+
+	b.publishing.Wait()
+
+	return b.Broker.Disconnect()
+}
@@ -0,0 +1,50 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGracefulBroker_StopConsuming(t *testing.T) {
+	rb := newRecordBroker("shared")
+	b := NewGracefulBroker(rb)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	_, err := b.Subscribe("orders",
+		func(context.Context, Event) error {
+			close(started)
+			<-release
+			return nil
+		},
+		nil,
+	)
+	assert.Nil(t, err)
+
+	handled := make(chan error)
+	go func() {
+		handled <- rb.handlers["orders"](context.Background(), nil)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, b.StopConsuming(ctx), context.DeadlineExceeded)
+
+	assert.ErrorIs(t, rb.handlers["orders"](context.Background(), nil), ErrConsumingStopped)
+	_, err = b.Subscribe("orders", nil, nil)
+	assert.ErrorIs(t, err, ErrConsumingStopped)
+
+	close(release)
+	assert.Nil(t, <-handled)
+	assert.Nil(t, b.StopConsuming(context.Background()))
+
+	assert.Nil(t, b.Publish(context.Background(), "audit", "msg"))
+	assert.Equal(t, []string{"audit"}, rb.published)
+
+	assert.Nil(t, Shutdown(context.Background(), b))
+	assert.ErrorIs(t, b.Publish(context.Background(), "audit", "msg"), ErrBrokerClosed)
+}
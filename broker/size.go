@@ -0,0 +1,64 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithMaxMessageSize reject the messages whose encoded body exceeds n bytes
+// with ErrPayloadTooLarge before they reach the broker, zero means no limit.
+// The drivers default to the limit of their broker.
+func WithMaxMessageSize(n int) Option {
+	return func(o *Options) {
+		o.MaxMessageSize = n
+	}
+}
+
+// CheckMessageSize return ErrPayloadTooLarge when buf exceeds MaxMessageSize.
+func (o *Options) CheckMessageSize(topic string, buf []byte) error {
+	if o.MaxMessageSize > 0 && len(buf) > o.MaxMessageSize {
+		return fmt.Errorf("%w: %d bytes published to %s, limit is %d", ErrPayloadTooLarge, len(buf), topic, o.MaxMessageSize)
+	}
+	return nil
+}
+
+// WithMaxReceiveSize fail the decoding of the messages whose body exceeds n
+// bytes, so the handler never sees them. They are acked and dropped, or
+// parked with WithParkOversize.
+func WithMaxReceiveSize(n int) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.MaxMessageSize = n
+	}
+}
+
+// WithParkOversize publish the messages exceeding the receive size to topic
+// with b, then ack them. They are redelivered when the publish fails. A broker
+// whose messages have no headers, see CarriesHeaders, gets the bodies only.
+func WithParkOversize(b Broker, topic string) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.ParkBroker = b
+		o.ParkTopic = topic
+	}
+}
+
+func (o *SubscribeOptions) checkReceiveSize(data []byte) error {
+	if o.MaxMessageSize > 0 && len(data) > o.MaxMessageSize {
+		return fmt.Errorf("%w: %d bytes received, limit is %d", ErrPayloadTooLarge, len(data), o.MaxMessageSize)
+	}
+	return nil
+}
+
+// parkOversize ack the oversize message, once published to the park topic if any.
+func (o *SubscribeOptions) parkOversize(ctx context.Context, headers Headers, body []byte) UnmarshalAction {
+	if o.ParkBroker == nil {
+		return UnmarshalActionAck
+	}
+	if !CarriesHeaders(o.ParkBroker) {
+		headers = nil
+	}
+	if err := o.ParkBroker.Publish(ctx, o.ParkTopic, body, WithHeaders(headers)); err != nil {
+		o.ReportError(ctx, ErrPayloadTooLarge, err, nil)
+		return UnmarshalActionRetry
+	}
+	return UnmarshalActionAck
+}
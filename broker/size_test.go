@@ -0,0 +1,52 @@
+package broker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckMessageSize(t *testing.T) {
+	options := NewOptionsAndApply(WithMaxMessageSize(4))
+	assert.Nil(t, options.CheckMessageSize("orders", []byte("1234")))
+	assert.ErrorIs(t, options.CheckMessageSize("orders", []byte("12345")), ErrPayloadTooLarge)
+
+	_, _, err := options.InterceptPublish(context.Background(), "orders", []byte("12345"), nil)
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+
+	options = NewOptionsAndApply(WithMaxMessageSize(4), WithPublishInterceptors(SetHeader("app", "billing")))
+	_, _, err = options.InterceptPublish(context.Background(), "orders", []byte("12345"), nil)
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+
+	options = NewOptions()
+	assert.Nil(t, options.CheckMessageSize("orders", make([]byte, 1<<20)))
+}
+
+func TestMaxReceiveSize(t *testing.T) {
+	ctx := context.Background()
+	options := NewOptions()
+	body := []byte("12345")
+
+	so := NewSubscribeOptions(WithMaxReceiveSize(4))
+	var out Any
+	err := options.Decode(&so, Headers{}, body, &out)
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+	assert.Equal(t, UnmarshalActionAck, so.HandleUnmarshalFailure(ctx, "orders", nil, body, err))
+
+	park := newRecordBroker("park")
+	so = NewSubscribeOptions(WithMaxReceiveSize(4), WithParkOversize(park, "orders.oversize"), WithUnmarshalFail())
+	err = options.Decode(&so, Headers{}, body, &out)
+	assert.Equal(t, UnmarshalActionAck, so.HandleUnmarshalFailure(ctx, "orders", nil, body, err))
+	assert.Equal(t, []string{"orders.oversize"}, park.published)
+
+	// the headers are dropped, the park broker would refuse them.
+	park = newRecordBroker("park")
+	so = NewSubscribeOptions(WithMaxReceiveSize(4), WithParkOversize(headerlessBroker{Broker: park}, "orders.oversize"))
+	err = options.Decode(&so, Headers{"id": "1"}, body, &out)
+	assert.Equal(t, UnmarshalActionAck, so.HandleUnmarshalFailure(ctx, "orders", Headers{"id": "1"}, body, err))
+	assert.Equal(t, []string{"orders.oversize"}, park.published)
+
+	so = NewSubscribeOptions(WithMaxReceiveSize(5), WithRawBody())
+	assert.Nil(t, options.Decode(&so, Headers{}, body, &out))
+}
@@ -0,0 +1,14 @@
+package sqlutil
+
+import "strconv"
+
+// PlaceholderFormat return the bind parameter of the index-th argument of a
+// query, starting from 1.
+type PlaceholderFormat func(index int) string
+
+var (
+	// Question mysql, sqlite: ?
+	Question PlaceholderFormat = func(_ int) string { return "?" }
+	// Dollar postgres: $1, $2 ...
+	Dollar PlaceholderFormat = func(index int) string { return "$" + strconv.Itoa(index) }
+)
@@ -0,0 +1,171 @@
+package broker
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// The standard headers shared by the services exchanging messages, emitted
+// by StandardHeadersInterceptor and the publish options below, and read by
+// StandardHeadersHandler. The brokers whose messages have no headers, see
+// CarriesHeaders, refuse the publishes carrying them with ErrHeadersUnsupported.
+const (
+	// DeadlineHeader is the time, RFC 3339, after which the message is
+	// not worth handling any more.
+	DeadlineHeader = "x-deadline"
+	// BaggageHeader carries the W3C baggage of the publisher.
+	BaggageHeader = "x-baggage"
+	// IdempotencyKeyHeader identifies the operation the message requests,
+	// for the consumers to perform it once.
+	IdempotencyKeyHeader = "x-idempotency-key"
+	// SchemaHeader names the schema of the body, e.g. "orders.v2".
+	SchemaHeader = "x-schema"
+	// AttemptHeader is the delivery attempt of the message, 1 for the first.
+	AttemptHeader = "x-attempt"
+)
+
+// StandardHeaders are the standard headers of a consumed message.
+type StandardHeaders struct {
+	Deadline       time.Time
+	IdempotencyKey string
+	Schema         string
+	Attempt        int
+}
+
+type standardHeadersKey struct{}
+
+// StandardHeadersFromContext return the standard headers of the message
+// handled with ctx, set by StandardHeadersHandler.
+func StandardHeadersFromContext(ctx context.Context) (StandardHeaders, bool) {
+	if ctx == nil {
+		return StandardHeaders{}, false
+	}
+	h, ok := ctx.Value(standardHeadersKey{}).(StandardHeaders)
+	return h, ok
+}
+
+// IdempotencyKeyFromContext return the idempotency key of the message handled with ctx.
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	h, _ := StandardHeadersFromContext(ctx)
+	return h.IdempotencyKey, h.IdempotencyKey != ""
+}
+
+// SchemaFromContext return the schema of the message handled with ctx.
+func SchemaFromContext(ctx context.Context) (string, bool) {
+	h, _ := StandardHeadersFromContext(ctx)
+	return h.Schema, h.Schema != ""
+}
+
+// AttemptFromContext return the delivery attempt of the message handled
+// with ctx, 1 when it has none.
+func AttemptFromContext(ctx context.Context) int {
+	h, ok := StandardHeadersFromContext(ctx)
+	if !ok || h.Attempt < 1 {
+		return 1
+	}
+	return h.Attempt
+}
+
+// WithIdempotencyKey set the IdempotencyKeyHeader of the message.
+func WithIdempotencyKey(key string) PublishOption {
+	return WithHeaders(Headers{IdempotencyKeyHeader: key})
+}
+
+// WithSchema set the SchemaHeader of the message.
+func WithSchema(schema string) PublishOption {
+	return WithHeaders(Headers{SchemaHeader: schema})
+}
+
+// WithAttempt set the AttemptHeader of the message, e.g. to republish it
+// for a retry with AttemptFromContext(ctx)+1.
+func WithAttempt(attempt int) PublishOption {
+	return WithHeaders(Headers{AttemptHeader: strconv.Itoa(attempt)})
+}
+
+// WithStandardHeaders add StandardHeadersInterceptor to the publish interceptors.
+// Not for the brokers whose messages have no headers: their publishes would
+// fail as soon as the context has a deadline or a baggage.
+func WithStandardHeaders() Option {
+	return WithPublishInterceptors(StandardHeadersInterceptor)
+}
+
+// StandardHeadersInterceptor propagate the deadline and the baggage of the
+// publish context in DeadlineHeader and BaggageHeader, unless the message
+// has them already.
+func StandardHeadersInterceptor(ctx context.Context, _ string, msg *Message) error {
+	if _, ok := msg.Headers[DeadlineHeader]; !ok {
+		if deadline, ok := ctx.Deadline(); ok {
+			msg.Headers[DeadlineHeader] = deadline.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	if _, ok := msg.Headers[BaggageHeader]; !ok {
+		if b := baggage.FromContext(ctx); b.Len() > 0 {
+			msg.Headers[BaggageHeader] = b.String()
+		}
+	}
+	return nil
+}
+
+// StandardHeadersHandler wraps handler to run it with a context carrying
+// the standard headers of the message: canceled at its deadline, with its
+// baggage, and its other headers read by StandardHeadersFromContext. The
+// messages past their deadline are acknowledged without being handled.
+func StandardHeadersHandler(handler Handler) Handler {
+	return func(ctx context.Context, event Event) error {
+		if event == nil || event.Message() == nil {
+			return handler(ctx, event)
+		}
+		headers := event.Message().Headers
+
+		var h StandardHeaders
+		h.IdempotencyKey = headers[IdempotencyKeyHeader]
+		h.Schema = headers[SchemaHeader]
+		if attempt, err := strconv.Atoi(headers[AttemptHeader]); err == nil {
+			h.Attempt = attempt
+		}
+
+		if v, ok := headers[BaggageHeader]; ok {
+			if b, err := baggage.Parse(v); err == nil {
+				ctx = baggage.ContextWithBaggage(ctx, mergeBaggage(baggage.FromContext(ctx), b))
+			} else {
+				log.Warnf("[broker] invalid %s header of [%s]: %v", BaggageHeader, event.Topic(), err)
+			}
+		}
+
+		if v, ok := headers[DeadlineHeader]; ok {
+			deadline, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				log.Warnf("[broker] invalid %s header of [%s]: %v", DeadlineHeader, event.Topic(), err)
+			} else {
+				if !time.Now().Before(deadline) {
+					log.Warnf("[broker] drop message of [%s] past its deadline %s", event.Topic(), v)
+					return AutoAck(event)
+				}
+				h.Deadline = deadline
+
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, deadline)
+				defer cancel()
+			}
+		}
+
+		return handler(context.WithValue(ctx, standardHeadersKey{}, h), event)
+	}
+}
+
+// mergeBaggage add the members of b missing in base.
+func mergeBaggage(base, b baggage.Baggage) baggage.Baggage {
+	for _, m := range b.Members() {
+		if base.Member(m.Key()).Key() != "" {
+			continue
+		}
+		if merged, err := base.SetMember(m); err == nil {
+			base = merged
+		}
+	}
+	return base
+}
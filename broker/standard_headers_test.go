@@ -0,0 +1,102 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+func TestStandardHeadersInterceptor(t *testing.T) {
+	o := NewOptionsAndApply(WithStandardHeaders())
+
+	deadline := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	member, _ := baggage.NewMember("tenant", "acme")
+	b, _ := baggage.New(member)
+	ctx = baggage.ContextWithBaggage(ctx, b)
+
+	_, opts, err := o.InterceptPublish(ctx, "orders", []byte("{}"), []PublishOption{
+		WithIdempotencyKey("order-1"),
+		WithSchema("orders.v2"),
+		WithAttempt(2),
+	})
+	assert.Nil(t, err)
+
+	headers := NewPublishOptions(opts...).Headers
+	assert.Equal(t, Headers{
+		DeadlineHeader:       "2030-01-02T03:04:05Z",
+		BaggageHeader:        "tenant=acme",
+		IdempotencyKeyHeader: "order-1",
+		SchemaHeader:         "orders.v2",
+		AttemptHeader:        "2",
+	}, headers)
+
+	// the headers set by the caller are kept.
+	_, opts, err = o.InterceptPublish(ctx, "orders", []byte("{}"), []PublishOption{
+		WithHeaders(Headers{DeadlineHeader: "2031-01-01T00:00:00Z"}),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "2031-01-01T00:00:00Z", NewPublishOptions(opts...).Headers[DeadlineHeader])
+}
+
+func TestStandardHeadersHandler(t *testing.T) {
+	deadline := time.Now().Add(time.Hour).UTC()
+
+	var handled context.Context
+	handler := StandardHeadersHandler(func(ctx context.Context, _ Event) error {
+		handled = ctx
+		return nil
+	})
+
+	assert.Nil(t, handler(context.Background(), &testEvent{topic: "orders", message: &Message{Headers: Headers{
+		DeadlineHeader:       deadline.Format(time.RFC3339Nano),
+		BaggageHeader:        "tenant=acme",
+		IdempotencyKeyHeader: "order-1",
+		SchemaHeader:         "orders.v2",
+		AttemptHeader:        "3",
+	}}}))
+
+	d, ok := handled.Deadline()
+	assert.True(t, ok)
+	assert.True(t, d.Equal(deadline))
+	assert.Equal(t, "acme", baggage.FromContext(handled).Member("tenant").Value())
+
+	key, ok := IdempotencyKeyFromContext(handled)
+	assert.True(t, ok)
+	assert.Equal(t, "order-1", key)
+	schema, _ := SchemaFromContext(handled)
+	assert.Equal(t, "orders.v2", schema)
+	assert.Equal(t, 3, AttemptFromContext(handled))
+
+	assert.Nil(t, handler(context.Background(), &testEvent{topic: "orders", message: &Message{Headers: Headers{}}}))
+	assert.Equal(t, 1, AttemptFromContext(handled))
+	_, ok = handled.Deadline()
+	assert.False(t, ok)
+}
+
+func TestStandardHeadersHandler_Expired(t *testing.T) {
+	called := false
+	handler := StandardHeadersHandler(func(context.Context, Event) error {
+		called = true
+		return nil
+	})
+
+	event := &ackedEvent{testEvent: testEvent{topic: "orders", message: &Message{Headers: Headers{
+		DeadlineHeader: time.Now().Add(-time.Second).Format(time.RFC3339Nano),
+	}}}}
+	assert.Nil(t, handler(context.Background(), event))
+	assert.False(t, called)
+	assert.True(t, IsAcked(event))
+}
+
+type ackedEvent struct {
+	testEvent
+	AckState
+}
+
+func (e *ackedEvent) Ack() error { return e.AckState.Ack(nil) }
@@ -0,0 +1,122 @@
+package broker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TopicStats is a snapshot of the counters of one topic.
+type TopicStats struct {
+	Published     int64
+	PublishErrors int64
+	Consumed      int64
+	ConsumeErrors int64
+
+	// InFlight is the number of handlers currently running.
+	InFlight int64
+
+	LastPublish time.Time
+	LastConsume time.Time
+}
+
+// StatsBroker counts publishes and consumed messages per topic.
+type StatsBroker interface {
+	Broker
+
+	Stats() map[string]TopicStats
+}
+
+type topicCounters struct {
+	published     atomic.Int64
+	publishErrors atomic.Int64
+	consumed      atomic.Int64
+	consumeErrors atomic.Int64
+	inFlight      atomic.Int64
+	lastPublish   atomic.Int64
+	lastConsume   atomic.Int64
+}
+
+func (c *topicCounters) snapshot() TopicStats {
+	s := TopicStats{
+		Published:     c.published.Load(),
+		PublishErrors: c.publishErrors.Load(),
+		Consumed:      c.consumed.Load(),
+		ConsumeErrors: c.consumeErrors.Load(),
+		InFlight:      c.inFlight.Load(),
+	}
+	if t := c.lastPublish.Load(); t != 0 {
+		s.LastPublish = time.Unix(0, t)
+	}
+	if t := c.lastConsume.Load(); t != 0 {
+		s.LastConsume = time.Unix(0, t)
+	}
+	return s
+}
+
+type statsBroker struct {
+	Broker
+
+	topics sync.Map
+}
+
+// NewStatsBroker wraps b to collect per-topic statistics, the counters are
+// atomic so the overhead stays low enough to leave it on in production.
+func NewStatsBroker(b Broker) StatsBroker {
+	return &statsBroker{Broker: b}
+}
+
+func (b *statsBroker) counters(topic string) *topicCounters {
+	if c, ok := b.topics.Load(topic); ok {
+		return c.(*topicCounters)
+	}
+	c, _ := b.topics.LoadOrStore(topic, &topicCounters{})
+	return c.(*topicCounters)
+}
+
+func (b *statsBroker) Stats() map[string]TopicStats {
+	stats := make(map[string]TopicStats)
+	b.topics.Range(func(topic, c any) bool {
+		stats[topic.(string)] = c.(*topicCounters).snapshot()
+		return true
+	})
+	return stats
+}
+
+func (b *statsBroker) Publish(ctx context.Context, topic string, msg Any, opts ...PublishOption) error {
+	c := b.counters(topic)
+
+	err := b.Broker.Publish(ctx, topic, msg, opts...)
+
+	c.published.Add(1)
+	if err != nil {
+		c.publishErrors.Add(1)
+	}
+	c.lastPublish.Store(time.Now().UnixNano())
+
+	return err
+}
+
+func (b *statsBroker) Subscribe(topic string, handler Handler, binder Binder, opts ...SubscribeOption) (Subscriber, error) {
+	c := b.counters(topic)
+
+	return b.Broker.Subscribe(topic,
+		func(ctx context.Context, event Event) error {
+			c.inFlight.Add(1)
+			defer c.inFlight.Add(-1)
+
+			err := handler(ctx, event)
+
+			c.consumed.Add(1)
+			if err != nil {
+				c.consumeErrors.Add(1)
+			}
+			c.lastConsume.Store(time.Now().UnixNano())
+
+			return err
+		},
+		binder,
+		opts...,
+	)
+}
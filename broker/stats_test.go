@@ -0,0 +1,39 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsBroker(t *testing.T) {
+	rb := newRecordBroker("shared")
+	b := NewStatsBroker(rb)
+
+	assert.Nil(t, b.Publish(context.Background(), "orders", "msg"))
+	assert.Nil(t, b.Publish(context.Background(), "orders", "msg"))
+
+	_, err := b.Subscribe("orders",
+		func(_ context.Context, event Event) error {
+			assert.Equal(t, int64(1), b.Stats()["orders"].InFlight)
+			if event == nil {
+				return errors.New("empty event")
+			}
+			return nil
+		},
+		nil,
+	)
+	assert.Nil(t, err)
+	assert.Error(t, rb.handlers["orders"](context.Background(), nil))
+
+	stats := b.Stats()["orders"]
+	assert.Equal(t, int64(2), stats.Published)
+	assert.Equal(t, int64(0), stats.PublishErrors)
+	assert.Equal(t, int64(1), stats.Consumed)
+	assert.Equal(t, int64(1), stats.ConsumeErrors)
+	assert.Equal(t, int64(0), stats.InFlight)
+	assert.False(t, stats.LastPublish.IsZero())
+	assert.False(t, stats.LastConsume.IsZero())
+}
@@ -0,0 +1,171 @@
+package stomp
+
+import (
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// HeartBeatConfig is the heart-beat negotiated on connect, zero durations
+// disable it in that direction.
+type HeartBeatConfig struct {
+	SendTimeout time.Duration
+	RecvTimeout time.Duration
+}
+
+// Config is the typed stomp configuration of a broker, the fields are the
+// ones set one by one with the broker options of the package.
+type Config struct {
+	ConnectTimeout time.Duration
+	Username       string
+	Password       string
+	ConnectHeaders map[string]string
+	VirtualHost    string
+	// HeartBeat keeps the heart-beat of the client library when nil.
+	HeartBeat         *HeartBeatConfig
+	HeartBeatError    time.Duration
+	MsgSendTimeout    time.Duration
+	RcvReceiptTimeout time.Duration
+}
+
+// WithConfig set the whole configuration at once, the zero fields are left
+// unset. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		var opts []broker.Option
+		if c.ConnectTimeout != 0 {
+			opts = append(opts, WithConnectTimeout(c.ConnectTimeout))
+		}
+		if c.Username != "" || c.Password != "" {
+			opts = append(opts, WithAuth(c.Username, c.Password))
+		}
+		if c.ConnectHeaders != nil {
+			opts = append(opts, WithConnectHeaders(c.ConnectHeaders))
+		}
+		if c.VirtualHost != "" {
+			opts = append(opts, WithVirtualHost(c.VirtualHost))
+		}
+		if c.HeartBeat != nil {
+			opts = append(opts, WithHeartBeat(c.HeartBeat.SendTimeout, c.HeartBeat.RecvTimeout))
+		}
+		if c.HeartBeatError != 0 {
+			opts = append(opts, WithHeartBeatError(c.HeartBeatError))
+		}
+		if c.MsgSendTimeout != 0 {
+			opts = append(opts, WithMsgSendTimeout(c.MsgSendTimeout))
+		}
+		if c.RcvReceiptTimeout != 0 {
+			opts = append(opts, WithRcvReceiptTimeout(c.RcvReceiptTimeout))
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	var c Config
+	if opts.Context == nil {
+		return c
+	}
+
+	c.ConnectTimeout, _ = opts.Context.Value(connectTimeoutKey{}).(time.Duration)
+	if v, ok := opts.Context.Value(authKey{}).(*authRecord); ok {
+		c.Username, c.Password = v.username, v.password
+	}
+	c.ConnectHeaders, _ = opts.Context.Value(connectHeaderKey{}).(map[string]string)
+	c.VirtualHost, _ = opts.Context.Value(vHostKey{}).(string)
+	if v, ok := opts.Context.Value(heartBeatKey{}).(*heartbeatTimeout); ok {
+		c.HeartBeat = &HeartBeatConfig{SendTimeout: v.sendTimeout, RecvTimeout: v.recvTimeout}
+	}
+	c.HeartBeatError, _ = opts.Context.Value(heartBeatErrorKey{}).(time.Duration)
+	c.MsgSendTimeout, _ = opts.Context.Value(msgSendTimeoutKey{}).(time.Duration)
+	c.RcvReceiptTimeout, _ = opts.Context.Value(rcvReceiptTimeoutKey{}).(time.Duration)
+
+	return c
+}
+
+// SubscribeConfig is the typed stomp configuration of a subscription, the
+// zero fields are left unset.
+type SubscribeConfig struct {
+	Durable bool
+	Headers map[string]string
+	// AckOnSuccess acks a message only once handled, see WithAckOnSuccess.
+	AckOnSuccess bool
+}
+
+// WithSubscribeConfig set the whole subscription configuration at once.
+func WithSubscribeConfig(c SubscribeConfig) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		var opts []broker.SubscribeOption
+		if c.Durable {
+			opts = append(opts, WithDurable())
+		}
+		if c.Headers != nil {
+			opts = append(opts, WithSubscribeHeaders(c.Headers))
+		}
+		if c.AckOnSuccess {
+			opts = append(opts, WithAckOnSuccess())
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// SubscribeConfigFromOptions return the subscription configuration set in
+// the context of opts.
+func SubscribeConfigFromOptions(opts broker.SubscribeOptions) SubscribeConfig {
+	var c SubscribeConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.Durable, _ = opts.Context.Value(durableQueueKey{}).(bool)
+	c.Headers, _ = opts.Context.Value(subscribeHeaderKey{}).(map[string]string)
+	c.AckOnSuccess, _ = opts.Context.Value(ackSuccessKey{}).(bool)
+
+	return c
+}
+
+// PublishConfig is the typed stomp configuration of a publishing, the zero
+// fields are left unset.
+type PublishConfig struct {
+	Headers               map[string]string
+	Receipt               bool
+	SuppressContentLength bool
+}
+
+// WithPublishConfig set the whole publishing configuration at once.
+func WithPublishConfig(c PublishConfig) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		var opts []broker.PublishOption
+		if c.Headers != nil {
+			opts = append(opts, WithHeaders(c.Headers))
+		}
+		if c.Receipt {
+			opts = append(opts, WithReceipt(0))
+		}
+		if c.SuppressContentLength {
+			opts = append(opts, WithSuppressContentLength(0))
+		}
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// PublishConfigFromOptions return the publishing configuration set in the
+// context of opts.
+func PublishConfigFromOptions(opts broker.PublishOptions) PublishConfig {
+	var c PublishConfig
+	if opts.Context == nil {
+		return c
+	}
+
+	c.Headers, _ = opts.Context.Value(headerKey{}).(map[string]string)
+	c.Receipt, _ = opts.Context.Value(receiptKey{}).(bool)
+	c.SuppressContentLength, _ = opts.Context.Value(suppressContentLengthKey{}).(bool)
+
+	return c
+}
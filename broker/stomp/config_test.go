@@ -0,0 +1,64 @@
+package stomp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestConfigFromOptions(t *testing.T) {
+	assert.Equal(t, Config{}, ConfigFromOptions(broker.NewOptions()))
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithConnectTimeout(time.Second),
+		WithAuth("user", "password"),
+		WithVirtualHost("/"),
+		WithHeartBeat(time.Second, 2*time.Second),
+	))
+
+	c := Config{
+		ConnectTimeout: time.Second,
+		Username:       "user",
+		Password:       "password",
+		VirtualHost:    "/",
+		HeartBeat:      &HeartBeatConfig{SendTimeout: time.Second, RecvTimeout: 2 * time.Second},
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	// a dedicated option set later overrides the config.
+	overridden := ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c), WithVirtualHost("orders")))
+	assert.Equal(t, "orders", overridden.VirtualHost)
+}
+
+func TestSubscribeConfigFromOptions(t *testing.T) {
+	assert.Equal(t, SubscribeConfig{}, SubscribeConfigFromOptions(broker.NewSubscribeOptions()))
+
+	legacy := SubscribeConfigFromOptions(broker.NewSubscribeOptions(
+		WithDurable(),
+		WithAckOnSuccess(),
+	))
+
+	c := SubscribeConfig{Durable: true, AckOnSuccess: true}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, SubscribeConfigFromOptions(broker.NewSubscribeOptions(WithSubscribeConfig(c))))
+}
+
+func TestPublishConfigFromOptions(t *testing.T) {
+	assert.Equal(t, PublishConfig{}, PublishConfigFromOptions(broker.PublishOptions{}))
+
+	legacy := PublishConfigFromOptions(broker.NewPublishOptions(
+		WithHeaders(map[string]string{"trace": "1"}),
+		WithReceipt(0),
+	))
+
+	c := PublishConfig{
+		Headers: map[string]string{"trace": "1"},
+		Receipt: true,
+	}
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, PublishConfigFromOptions(broker.NewPublishOptions(WithPublishConfig(c))))
+}
@@ -8,6 +8,8 @@ import (
 )
 
 type publication struct {
+	broker.AckState
+
 	msg    *stompV3.Message
 	m      *broker.Message
 	broker *stompBroker
@@ -16,13 +18,15 @@ type publication struct {
 }
 
 func (p *publication) Ack() error {
-	if p.broker == nil {
-		return errors.New("broker is nil")
-	}
-	if p.broker.stompConn == nil {
-		return errors.New("stomp connection is nil")
-	}
-	return p.broker.stompConn.Ack(p.msg)
+	return p.AckState.Ack(func() error {
+		if p.broker == nil {
+			return errors.New("broker is nil")
+		}
+		if p.broker.stompConn == nil {
+			return errors.New("stomp connection is nil")
+		}
+		return p.broker.stompConn.Ack(p.msg)
+	})
 }
 
 func (p *publication) Error() error {
@@ -0,0 +1,36 @@
+package stomp
+
+import (
+	"context"
+	"testing"
+
+	frameV3 "github.com/go-stomp/stomp/v3/frame"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func TestPublishPath(t *testing.T) {
+	mocks.TestPublishPath(t, func(opts []broker.Option, topic string, msg broker.Any, popts ...broker.PublishOption) (broker.Headers, []byte, error) {
+		b := NewBroker(opts...).(*stompBroker)
+
+		buf, stompOpt, _, err := b.newMessage(context.Background(), topic, msg, popts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		f := frameV3.New(frameV3.SEND)
+		for _, o := range stompOpt {
+			if err = o(f); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		headers := broker.Headers{}
+		for i := 0; i < f.Header.Len(); i++ {
+			k, v := f.Header.GetAt(i)
+			headers[k] = v
+		}
+		return headers, buf, nil
+	}, true)
+}
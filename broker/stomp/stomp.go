@@ -35,6 +35,8 @@ type stompBroker struct {
 
 	producerTracer *tracing.Tracer
 	consumerTracer *tracing.Tracer
+
+	metrics *broker.Metrics
 }
 
 func NewBroker(opts ...broker.Option) broker.Broker {
@@ -94,6 +96,8 @@ func (b *stompBroker) Init(opts ...broker.Option) error {
 		b.consumerTracer = tracing.NewTracer(trace.SpanKindConsumer, "stomp-consumer", b.options.Tracings...)
 	}
 
+	b.metrics = broker.NewMetrics("stomp", b.options.MeterProvider)
+
 	return nil
 }
 
@@ -107,14 +111,11 @@ func (b *stompBroker) Connect() error {
 		return fmt.Errorf("expected stomp:// protocol but was %s", uri.Scheme)
 	}
 
-	var connectTimeOut time.Duration
-	if v, ok := b.options.Context.Value(connectTimeoutKey{}).(time.Duration); ok {
-		connectTimeOut = v
-	}
+	c := ConfigFromOptions(b.options)
 
 	var netConn net.Conn
-	if connectTimeOut > 0 {
-		netConn, err = net.DialTimeout("tcp", uri.Host, connectTimeOut)
+	if c.ConnectTimeout > 0 {
+		netConn, err = net.DialTimeout("tcp", uri.Host, c.ConnectTimeout)
 	} else {
 		netConn, err = net.Dial("tcp", uri.Host)
 	}
@@ -127,29 +128,27 @@ func (b *stompBroker) Connect() error {
 		password, _ := uri.User.Password()
 		stompOpts = append(stompOpts, stompV3.ConnOpt.Login(uri.User.Username(), password))
 	}
-	if v, ok := b.options.Context.Value(authKey{}).(*authRecord); ok {
-		stompOpts = append(stompOpts, stompV3.ConnOpt.Login(v.username, v.password))
+	if c.Username != "" || c.Password != "" {
+		stompOpts = append(stompOpts, stompV3.ConnOpt.Login(c.Username, c.Password))
 	}
-	if headers, ok := b.options.Context.Value(connectHeaderKey{}).(map[string]string); ok {
-		for k, v := range headers {
-			stompOpts = append(stompOpts, stompV3.ConnOpt.Header(k, v))
-		}
+	for k, v := range c.ConnectHeaders {
+		stompOpts = append(stompOpts, stompV3.ConnOpt.Header(k, v))
 	}
-	if host, ok := b.options.Context.Value(vHostKey{}).(string); ok {
-		log.Infof("Adding host: %s", host)
-		stompOpts = append(stompOpts, stompV3.ConnOpt.Host(host))
+	if c.VirtualHost != "" {
+		log.Infof("Adding host: %s", c.VirtualHost)
+		stompOpts = append(stompOpts, stompV3.ConnOpt.Host(c.VirtualHost))
 	}
-	if v, ok := b.options.Context.Value(heartBeatKey{}).(*heartbeatTimeout); ok {
-		stompOpts = append(stompOpts, stompV3.ConnOpt.HeartBeat(v.sendTimeout, v.recvTimeout))
+	if c.HeartBeat != nil {
+		stompOpts = append(stompOpts, stompV3.ConnOpt.HeartBeat(c.HeartBeat.SendTimeout, c.HeartBeat.RecvTimeout))
 	}
-	if v, ok := b.options.Context.Value(heartBeatErrorKey{}).(time.Duration); ok {
-		stompOpts = append(stompOpts, stompV3.ConnOpt.HeartBeatError(v))
+	if c.HeartBeatError != 0 {
+		stompOpts = append(stompOpts, stompV3.ConnOpt.HeartBeatError(c.HeartBeatError))
 	}
-	if v, ok := b.options.Context.Value(msgSendTimeoutKey{}).(time.Duration); ok {
-		stompOpts = append(stompOpts, stompV3.ConnOpt.MsgSendTimeout(v))
+	if c.MsgSendTimeout != 0 {
+		stompOpts = append(stompOpts, stompV3.ConnOpt.MsgSendTimeout(c.MsgSendTimeout))
 	}
-	if v, ok := b.options.Context.Value(rcvReceiptTimeoutKey{}).(time.Duration); ok {
-		stompOpts = append(stompOpts, stompV3.ConnOpt.RcvReceiptTimeout(v))
+	if c.RcvReceiptTimeout != 0 {
+		stompOpts = append(stompOpts, stompV3.ConnOpt.RcvReceiptTimeout(c.RcvReceiptTimeout))
 	}
 
 	b.stompConn, err = stompV3.Connect(netConn, stompOpts...)
@@ -174,42 +173,65 @@ func (b *stompBroker) Disconnect() error {
 }
 
 func (b *stompBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
-	buf, err := broker.Marshal(b.options.Codec, msg)
+	buf, stompOpt, options, err := b.newMessage(ctx, topic, msg, opts)
 	if err != nil {
 		return err
 	}
 
-	return b.publish(ctx, topic, buf, opts...)
-}
+	start := time.Now()
+	err = b.publish(topic, buf, stompOpt, options)
+	b.metrics.RecordPublish(ctx, topic, start, err)
 
-func (b *stompBroker) publish(ctx context.Context, topic string, msg []byte, opts ...broker.PublishOption) error {
-	if b.stompConn == nil {
-		return errors.New("not connected")
-	}
+	return err
+}
 
+// newMessage encode msg and run the publish interceptors, it returns the
+// body to send to topic, the options of the SEND frame and the options of
+// the publish.
+func (b *stompBroker) newMessage(ctx context.Context, topic string, msg broker.Any, opts []broker.PublishOption) ([]byte, []func(*frameV3.Frame) error, broker.PublishOptions, error) {
 	options := broker.PublishOptions{
 		Context: ctx,
 	}
+
+	buf, err := broker.Marshal(b.options.Codec, msg)
+	if err != nil {
+		return nil, nil, options, err
+	}
+	buf, opts, err = b.options.InterceptPublish(ctx, topic, buf, opts)
+	if err != nil {
+		return nil, nil, options, err
+	}
+
 	for _, o := range opts {
 		o(&options)
 	}
 
 	stompOpt := make([]func(*frameV3.Frame) error, 0, 0)
 
-	span := b.startProducerSpan(options.Context, topic, &stompOpt)
-
-	if headers, ok := options.Context.Value(headerKey{}).(map[string]string); ok {
-		for k, v := range headers {
-			stompOpt = append(stompOpt, stompV3.SendOpt.Header(k, v))
-		}
+	c := PublishConfigFromOptions(options)
+	for k, v := range c.Headers {
+		stompOpt = append(stompOpt, stompV3.SendOpt.Header(k, v))
 	}
-	if withReceipt, ok := options.Context.Value(receiptKey{}).(bool); ok && withReceipt {
+	for k, v := range options.Headers {
+		stompOpt = append(stompOpt, stompV3.SendOpt.Header(k, v))
+	}
+	if c.Receipt {
 		stompOpt = append(stompOpt, stompV3.SendOpt.Receipt)
 	}
-	if withoutContentLength, ok := options.Context.Value(suppressContentLengthKey{}).(bool); ok && withoutContentLength {
+	if c.SuppressContentLength {
 		stompOpt = append(stompOpt, stompV3.SendOpt.NoContentLength)
 	}
 
+	return buf, stompOpt, options, nil
+}
+
+func (b *stompBroker) publish(topic string, msg []byte, stompOpt []func(*frameV3.Frame) error, options broker.PublishOptions) error {
+	if b.stompConn == nil {
+		return errors.New("not connected")
+	}
+
+	span := b.startProducerSpan(options.Context, topic, &stompOpt)
+
 	err := b.stompConn.Send(topic, "", msg, stompOpt...)
 
 	b.finishProducerSpan(span, err)
@@ -230,22 +252,24 @@ func (b *stompBroker) Subscribe(topic string, handler broker.Handler, binder bro
 		o(&options)
 	}
 
+	handler = options.TimeoutHandler(handler)
+	handler = b.metrics.Handler(topic, handler)
+
 	stompOpt := make([]func(*frameV3.Frame) error, 0, len(opts))
 
-	if durableQueue, ok := options.Context.Value(durableQueueKey{}).(bool); ok && durableQueue {
+	sc := SubscribeConfigFromOptions(options)
+
+	if sc.Durable {
 		stompOpt = append(stompOpt, stompV3.SubscribeOpt.Header("persistent", "true"))
 	}
 
-	if headers, ok := options.Context.Value(subscribeHeaderKey{}).(map[string]string); ok && len(headers) > 0 {
-		for k, v := range headers {
-			stompOpt = append(stompOpt, stompV3.SubscribeOpt.Header(k, v))
-		}
+	for k, v := range sc.Headers {
+		stompOpt = append(stompOpt, stompV3.SubscribeOpt.Header(k, v))
 	}
 
-	var ackSuccess bool
-	if bVal, ok := options.Context.Value(ackSuccessKey{}).(bool); ok && bVal {
+	ackSuccess := sc.AckOnSuccess
+	if ackSuccess {
 		options.AutoAck = false
-		ackSuccess = true
 	}
 
 	var ackMode stompV3.AckMode
@@ -260,56 +284,94 @@ func (b *stompBroker) Subscribe(topic string, handler broker.Handler, binder bro
 		return nil, err
 	}
 
+	subs := &subscriber{
+		b:       b,
+		sub:     sub,
+		topic:   topic,
+		options: options,
+	}
+
+	ack := options.AutoAck || ackSuccess
+
 	go func() {
 		for msg := range sub.C {
-			go func(msg *stompV3.Message) {
-				m := &broker.Message{
-					Headers: stompHeaderToMap(msg.Header),
+			// the subscription ends with a message carrying the error.
+			if msg.Err != nil {
+				log.Errorf("[stomp] receive from [%s] failed: %s", topic, msg.Err)
+				if options.Context.Err() == nil && sub.Active() {
+					options.ReportError(options.Context, broker.ErrReceive, msg.Err, nil)
 				}
+				continue
+			}
 
-				p := &publication{msg: msg, m: m, topic: topic, broker: b}
+			go b.handleMessage(subs, msg, handler, binder, ack)
+		}
+	}()
 
-				ctx, span := b.startConsumerSpan(options.Context, msg)
+	b.subscribers.Add(topic, subs)
 
-				if binder != nil {
-					m.Body = binder()
-				} else {
-					m.Body = msg.Body
-				}
+	return subs, nil
+}
 
-				if err = broker.Unmarshal(b.options.Codec, msg.Body, &m.Body); err != nil {
-					p.err = err
-					log.Error(err)
-					b.finishConsumerSpan(span, p.err)
-					return
-				}
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (b *stompBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Subscribe(topic, handler.Handler(), nil, append(opts, broker.WithRawBody())...)
+}
 
-				if err = handler(ctx, p); p.err != nil {
-					p.err = err
-					b.finishConsumerSpan(span, p.err)
-					return
-				}
+// handleMessage decodes msg and runs the handler, msg is acked afterwards
+// when ack is set and its subscription is not in auto mode.
+func (b *stompBroker) handleMessage(sub *subscriber, msg *stompV3.Message, handler broker.Handler, binder broker.Binder, ack bool) {
+	m := &broker.Message{
+		Headers: stompHeaderToMap(msg.Header),
+	}
 
-				if options.AutoAck || ackSuccess {
-					err = msg.Conn.Ack(msg)
-					p.err = err
-				}
+	p := &publication{msg: msg, m: m, topic: sub.topic, broker: b}
 
-				b.finishConsumerSpan(span, err)
-			}(msg)
+	ctx, span := b.startConsumerSpan(sub.options.Context, msg)
+
+	if binder != nil && !sub.options.RawBody {
+		m.Body = binder()
+	} else {
+		m.Body = msg.Body
+	}
+
+	if p.err = b.options.Decode(&sub.options, m.Headers, msg.Body, &m.Body); p.err != nil {
+		log.Error(p.err)
+		sub.options.ReportError(ctx, broker.ErrUnmarshal, p.err, p)
+		b.finishConsumerSpan(span, p.err)
+
+		// the messages of the auto ack subscriptions are settled already.
+		switch sub.options.HandleUnmarshalFailure(ctx, sub.topic, m.Headers, msg.Body, p.err) {
+		case broker.UnmarshalActionAck:
+			if msg.ShouldAck() {
+				if err := p.Ack(); err != nil {
+					sub.options.ReportError(ctx, broker.ErrAck, err, p)
+				}
+			}
+		case broker.UnmarshalActionRetry:
+			if msg.ShouldAck() {
+				_ = p.Nack(func() error {
+					return b.stompConn.Nack(msg)
+				})
+			}
+		case broker.UnmarshalActionStop:
+			_ = sub.Unsubscribe(true)
 		}
-	}()
+		return
+	}
 
-	subs := &subscriber{
-		b:       b,
-		sub:     sub,
-		topic:   topic,
-		options: options,
+	if p.err = handler(ctx, p); p.err != nil {
+		b.finishConsumerSpan(span, p.err)
+		return
 	}
 
-	b.subscribers.Add(topic, subs)
+	if ack && msg.ShouldAck() {
+		if p.err = broker.AutoAck(p); p.err != nil {
+			sub.options.ReportError(ctx, broker.ErrAck, p.err, p)
+		}
+	}
 
-	return subs, nil
+	b.finishConsumerSpan(span, p.err)
 }
 
 func (b *stompBroker) startProducerSpan(ctx context.Context, topic string, msg *[]func(*frameV3.Frame) error) trace.Span {
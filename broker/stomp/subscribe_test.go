@@ -0,0 +1,25 @@
+package stomp
+
+import (
+	"testing"
+
+	stompV3 "github.com/go-stomp/stomp/v3"
+	frameV3 "github.com/go-stomp/stomp/v3/frame"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+func TestSubscribePath(t *testing.T) {
+	mocks.TestSubscribePath(t, func(opts []broker.Option, topic string, headers broker.Headers, body []byte, handler broker.Handler, binder broker.Binder, sopts ...broker.SubscribeOption) {
+		b := NewBroker(opts...).(*stompBroker)
+		sub := &subscriber{b: b, options: broker.NewSubscribeOptions(sopts...), topic: topic}
+
+		msg := &stompV3.Message{Destination: topic, Header: frameV3.NewHeader(), Body: body}
+		for k, v := range headers {
+			msg.Header.Set(k, v)
+		}
+
+		b.handleMessage(sub, msg, handler, binder, true)
+	}, true)
+}
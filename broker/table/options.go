@@ -0,0 +1,49 @@
+package table
+
+import (
+	"time"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(t *Table)
+
+// WithStore set the store of the entries, default is a MemoryStore.
+func WithStore(store Store) Option {
+	return func(t *Table) {
+		t.store = store
+	}
+}
+
+// WithKeyFunc set how the key of a message is read, default is the KeyHeader
+// header. The messages without key are ignored.
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(t *Table) {
+		t.keyFunc = fn
+	}
+}
+
+// WithCodec set the codec the values are decoded with, default is the codec of the broker.
+func WithCodec(name string) Option {
+	return func(t *Table) {
+		t.codec = encoding.GetCodec(name)
+	}
+}
+
+// WithIdleTimeout set how long without message the table is considered up
+// to date once started, default is 1s. It should exceed the delay of the
+// subscription to deliver the first messages, see WaitReady.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(t *Table) {
+		t.idle = d
+	}
+}
+
+// WithSubscribeOptions add opts to the subscription of the topic.
+func WithSubscribeOptions(opts ...broker.SubscribeOption) Option {
+	return func(t *Table) {
+		t.subscribeOpts = append(t.subscribeOpts, opts...)
+	}
+}
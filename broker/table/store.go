@@ -0,0 +1,74 @@
+package table
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Store keeps the latest value of every key of a table, e.g. in memory or
+// in pebble or badger for the tables outgrowing the memory.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte) error
+	Delete(ctx context.Context, key string) error
+	// Range call fn with the entries in key order until it returns false.
+	Range(ctx context.Context, fn func(key string, value []byte) bool) error
+	Len(ctx context.Context) (int, error)
+}
+
+type MemoryStore struct {
+	sync.RWMutex
+	m map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{m: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.RLock()
+	defer s.RUnlock()
+	v, ok := s.m[key]
+	return v, ok, nil
+}
+
+func (s *MemoryStore) Put(_ context.Context, key string, value []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	s.m[key] = value
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.m, key)
+	return nil
+}
+
+func (s *MemoryStore) Range(_ context.Context, fn func(key string, value []byte) bool) error {
+	s.RLock()
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+	s.RUnlock()
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s.RLock()
+		v, ok := s.m[k]
+		s.RUnlock()
+		if ok && !fn(k, v) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Len(_ context.Context) (int, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return len(s.m), nil
+}
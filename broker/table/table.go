@@ -0,0 +1,263 @@
+package table
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// KeyHeader carries the key of a message by default.
+const KeyHeader = "x-message-key"
+
+var (
+	ErrAlreadyStarted = errors.New("table: already started")
+	ErrNotStarted     = errors.New("table: not started")
+)
+
+// KeyFunc return the key of a message, e.g. the key of the Kafka message
+// held by evt.RawMessage(), empty when it has none.
+type KeyFunc func(evt broker.RawEvent) string
+
+// Change is an update of the value of a key, Value is nil when the key was
+// deleted by a tombstone.
+type Change struct {
+	Key   string
+	Old   []byte
+	Value []byte
+
+	codec encoding.Codec
+}
+
+// Deleted reports whether the key was deleted.
+func (c Change) Deleted() bool {
+	return c.Value == nil
+}
+
+// Decode decode the new value into v.
+func (c Change) Decode(v interface{}) error {
+	return broker.Unmarshal(c.codec, c.Value, v)
+}
+
+// Listener is called with the changes of a table, in order.
+type Listener func(ctx context.Context, c Change)
+
+// Table materializes a compacted topic, holding the latest value of every
+// key, into a key-value view kept up to date by a subscription, like the
+// KTable of Kafka Streams, for the reference data:
+//
+//	t := table.New(b, "currencies")
+//	_ = t.Start(ctx)
+//	_ = t.WaitReady(ctx)
+//	var c Currency
+//	ok, err := t.Get(ctx, "EUR", &c)
+//
+// A message with an empty body is a tombstone deleting its key.
+type Table struct {
+	b     broker.Broker
+	topic string
+	store Store
+	codec encoding.Codec
+
+	keyFunc       KeyFunc
+	idle          time.Duration
+	clock         broker.Clock
+	subscribeOpts []broker.SubscribeOption
+
+	mu        sync.RWMutex
+	listeners []Listener
+	sub       broker.Subscriber
+	ready     chan struct{}
+	activity  chan struct{}
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+func New(b broker.Broker, topic string, opts ...Option) *Table {
+	t := &Table{
+		b:     b,
+		topic: topic,
+		codec: b.Options().Codec,
+		keyFunc: func(evt broker.RawEvent) string {
+			return evt.Headers()[KeyHeader]
+		},
+		idle:  time.Second,
+		clock: broker.ClockOrSystem(b.Options().Clock),
+	}
+
+	for _, o := range opts {
+		o(t)
+	}
+
+	if t.store == nil {
+		t.store = NewMemoryStore()
+	}
+
+	return t
+}
+
+// OnChange call l with every change applied from now on.
+func (t *Table) OnChange(l Listener) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.listeners = append(t.listeners, l)
+}
+
+// Start subscribe to the topic from its beginning.
+func (t *Table) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sub != nil {
+		return ErrAlreadyStarted
+	}
+
+	t.ready = make(chan struct{})
+	t.activity = make(chan struct{}, 1)
+
+	opts := append([]broker.SubscribeOption{broker.WithStartPosition(broker.Beginning())}, t.subscribeOpts...)
+	sub, err := broker.SubscribeRaw(t.b, t.topic, t.apply, opts...)
+	if errors.Is(err, broker.ErrRawUnsupported) {
+		opts = append(opts, broker.WithRawBody())
+		sub, err = t.b.Subscribe(t.topic, broker.RawHandler(t.apply).Handler(), nil, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	t.sub = sub
+
+	ctx, t.cancel = context.WithCancel(ctx)
+	t.done = make(chan struct{})
+	go t.watch(ctx, t.ready, t.activity, t.done)
+
+	return nil
+}
+
+// Stop unsubscribe from the topic, the entries are kept.
+func (t *Table) Stop(_ context.Context) error {
+	t.mu.Lock()
+	sub, cancel, done := t.sub, t.cancel, t.done
+	t.sub, t.cancel, t.done = nil, nil, nil
+	t.mu.Unlock()
+
+	if sub == nil {
+		return nil
+	}
+	cancel()
+	<-done
+
+	return sub.Unsubscribe(true)
+}
+
+// WaitReady wait until the table caught up with the topic, when no message
+// came for the idle timeout. The readiness is a heuristic, the offsets are
+// not compared: a subscription slower than the idle timeout to deliver, e.g.
+// while its consumer group rebalances, is reported caught up too early.
+func (t *Table) WaitReady(ctx context.Context) error {
+	t.mu.RLock()
+	ready := t.ready
+	t.mu.RUnlock()
+
+	if ready == nil {
+		return ErrNotStarted
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ready:
+		return nil
+	}
+}
+
+// Get decode the value of key into v, false when the table has no key.
+func (t *Table) Get(ctx context.Context, key string, v interface{}) (bool, error) {
+	value, ok, err := t.store.Get(ctx, key)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, broker.Unmarshal(t.codec, value, v)
+}
+
+// GetBytes return the encoded value of key.
+func (t *Table) GetBytes(ctx context.Context, key string) ([]byte, bool, error) {
+	return t.store.Get(ctx, key)
+}
+
+// Range call fn with the entries in key order until it returns false.
+func (t *Table) Range(ctx context.Context, fn func(key string, value []byte) bool) error {
+	return t.store.Range(ctx, fn)
+}
+
+// Len return the number of keys.
+func (t *Table) Len(ctx context.Context) (int, error) {
+	return t.store.Len(ctx)
+}
+
+func (t *Table) apply(ctx context.Context, evt broker.RawEvent) error {
+	t.mu.RLock()
+	activity := t.activity
+	listeners := t.listeners
+	t.mu.RUnlock()
+
+	select {
+	case activity <- struct{}{}:
+	default:
+	}
+
+	key := t.keyFunc(evt)
+	if key == "" {
+		log.Warnf("[table] ignore message of [%s] without key", t.topic)
+		return nil
+	}
+
+	old, _, err := t.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	c := Change{Key: key, Old: old, codec: t.codec}
+	if body := evt.Body(); len(body) > 0 {
+		c.Value = append([]byte(nil), body...)
+		err = t.store.Put(ctx, key, c.Value)
+	} else {
+		err = t.store.Delete(ctx, key)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, l := range listeners {
+		l(ctx, c)
+	}
+	return nil
+}
+
+// watch close ready once no message came for the idle timeout.
+func (t *Table) watch(ctx context.Context, ready chan struct{}, activity chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	timer := t.clock.NewTimer(t.idle)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-activity:
+			if !timer.Stop() {
+				<-timer.C()
+			}
+			timer.Reset(t.idle)
+		case <-timer.C():
+			close(ready)
+			return
+		}
+	}
+}
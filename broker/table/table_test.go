@@ -0,0 +1,113 @@
+package table
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+type currency struct {
+	Code string  `json:"code"`
+	Rate float64 `json:"rate"`
+}
+
+func TestTable(t *testing.T) {
+	ctx := context.Background()
+	b := mocks.NewFakeBroker()
+	assert.Nil(t, b.Connect())
+
+	tbl := New(b, "currencies", WithCodec("json"), WithIdleTimeout(20*time.Millisecond))
+	assert.ErrorIs(t, tbl.WaitReady(ctx), ErrNotStarted)
+
+	var changes []Change
+	tbl.OnChange(func(_ context.Context, c Change) {
+		changes = append(changes, c)
+	})
+
+	assert.Nil(t, tbl.Start(ctx))
+	assert.ErrorIs(t, tbl.Start(ctx), ErrAlreadyStarted)
+
+	deliver := func(key string, body string) {
+		_, err := b.Deliver(ctx, "currencies", []byte(body), broker.Headers{KeyHeader: key})
+		assert.Nil(t, err)
+	}
+	deliver("EUR", `{"code":"EUR","rate":1.1}`)
+	deliver("GBP", `{"code":"GBP","rate":1.3}`)
+	deliver("EUR", `{"code":"EUR","rate":1.2}`)
+	deliver("GBP", "")
+	_, _ = b.Deliver(ctx, "currencies", []byte(`{}`), nil)
+
+	assert.Nil(t, tbl.WaitReady(ctx))
+
+	var c currency
+	ok, err := tbl.Get(ctx, "EUR", &c)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, currency{Code: "EUR", Rate: 1.2}, c)
+
+	ok, err = tbl.Get(ctx, "GBP", &c)
+	assert.Nil(t, err)
+	assert.False(t, ok, "deleted by the tombstone")
+
+	n, _ := tbl.Len(ctx)
+	assert.Equal(t, 1, n)
+
+	if assert.Len(t, changes, 4) {
+		assert.Nil(t, changes[0].Old)
+		assert.Equal(t, changes[0].Value, changes[2].Old)
+		assert.Nil(t, changes[2].Decode(&c))
+		assert.Equal(t, 1.2, c.Rate)
+		assert.True(t, changes[3].Deleted())
+	}
+
+	assert.Nil(t, tbl.Stop(ctx))
+	assert.Nil(t, tbl.Stop(ctx))
+}
+
+func TestTable_KeyFunc(t *testing.T) {
+	ctx := context.Background()
+	b := mocks.NewFakeBroker()
+	assert.Nil(t, b.Connect())
+
+	tbl := New(b, "users", WithKeyFunc(func(evt broker.RawEvent) string {
+		return evt.Headers()["user-id"]
+	}))
+	assert.Nil(t, tbl.Start(ctx))
+	defer tbl.Stop(ctx)
+
+	_, _ = b.Deliver(ctx, "users", []byte("alice"), broker.Headers{"user-id": "1"})
+	_, _ = b.Deliver(ctx, "users", []byte("bob"), broker.Headers{"user-id": "2"})
+
+	var keys []string
+	assert.Nil(t, tbl.Range(ctx, func(key string, value []byte) bool {
+		keys = append(keys, key+"="+string(value))
+		return true
+	}))
+	assert.Equal(t, []string{"1=alice", "2=bob"}, keys)
+}
+
+func TestTable_IdleClock(t *testing.T) {
+	ctx := context.Background()
+	clock := broker.NewFakeClock(time.Unix(0, 0))
+	b := mocks.NewFakeBroker(broker.WithClock(clock))
+	assert.Nil(t, b.Connect())
+
+	tbl := New(b, "currencies", WithIdleTimeout(time.Second))
+	assert.Nil(t, tbl.Start(ctx))
+	defer tbl.Stop(ctx)
+
+	clock.BlockUntil(1)
+	clock.Advance(999 * time.Millisecond)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, tbl.WaitReady(waitCtx), context.DeadlineExceeded)
+
+	clock.Advance(time.Millisecond)
+	assert.Nil(t, tbl.WaitReady(ctx))
+}
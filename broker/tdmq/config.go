@@ -0,0 +1,47 @@
+package tdmq
+
+import (
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/pulsar"
+)
+
+// Config is the typed tdmq configuration of a broker, the pulsar
+// configuration is set with pulsar.WithConfig.
+type Config struct {
+	ClusterID string
+	Namespace string
+	RoleToken string
+}
+
+// WithConfig set the whole configuration at once, the empty fields are left
+// unset. Later options override it.
+func WithConfig(c Config) broker.Option {
+	return func(o *broker.Options) {
+		var opts []broker.Option
+		if c.ClusterID != "" {
+			opts = append(opts, WithClusterID(c.ClusterID))
+		}
+		if c.Namespace != "" {
+			opts = append(opts, WithNamespace(c.Namespace))
+		}
+		if c.RoleToken != "" {
+			opts = append(opts, WithRoleToken(c.RoleToken))
+		}
+		o.Apply(opts...)
+	}
+}
+
+// ConfigFromOptions return the configuration set in the context of opts,
+// by WithConfig or the dedicated options.
+func ConfigFromOptions(opts broker.Options) Config {
+	var c Config
+	if opts.Context == nil {
+		return c
+	}
+
+	c.ClusterID, _ = opts.Context.Value(clusterIdKey{}).(string)
+	c.Namespace, _ = opts.Context.Value(namespaceKey{}).(string)
+	c.RoleToken = pulsar.ConfigFromOptions(opts).AuthenticationToken
+
+	return c
+}
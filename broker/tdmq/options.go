@@ -0,0 +1,29 @@
+package tdmq
+
+import (
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/pulsar"
+)
+
+///
+/// Option
+///
+
+type clusterIdKey struct{}
+type namespaceKey struct{}
+
+// WithClusterID set the id of the TDMQ cluster, such as pulsar-xxxxxxxx,
+// which is the tenant of the topics.
+func WithClusterID(id string) broker.Option {
+	return broker.OptionContextWithValue(clusterIdKey{}, id)
+}
+
+// WithNamespace set the namespace of the topics.
+func WithNamespace(namespace string) broker.Option {
+	return broker.OptionContextWithValue(namespaceKey{}, namespace)
+}
+
+// WithRoleToken authenticate with the token of a role authorized on the namespace.
+func WithRoleToken(token string) broker.Option {
+	return pulsar.WithAuthenticationToken(token)
+}
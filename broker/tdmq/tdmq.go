@@ -0,0 +1,68 @@
+package tdmq
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/pulsar"
+)
+
+// tdmqBroker is the pulsar broker with the topics qualified by the cluster
+// and the namespace, the pulsar options apply as well.
+type tdmqBroker struct {
+	broker.Broker
+
+	clusterId string
+	namespace string
+}
+
+func NewBroker(opts ...broker.Option) broker.Broker {
+	b := &tdmqBroker{
+		Broker: pulsar.NewBroker(opts...),
+	}
+	b.configure()
+
+	return b
+}
+
+func (b *tdmqBroker) Name() string {
+	return "tdmq"
+}
+
+func (b *tdmqBroker) Init(opts ...broker.Option) error {
+	if err := b.Broker.Init(opts...); err != nil {
+		return err
+	}
+	b.configure()
+
+	return nil
+}
+
+func (b *tdmqBroker) configure() {
+	c := ConfigFromOptions(b.Broker.Options())
+	b.clusterId = c.ClusterID
+	b.namespace = c.Namespace
+}
+
+func (b *tdmqBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	return b.Broker.Publish(ctx, b.topicName(topic), msg, opts...)
+}
+
+func (b *tdmqBroker) Subscribe(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return b.Broker.Subscribe(b.topicName(topic), handler, binder, opts...)
+}
+
+// SubscribeRaw subscribe topic and deliver the bodies as received.
+func (b *tdmqBroker) SubscribeRaw(topic string, handler broker.RawHandler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	return broker.SubscribeRaw(b.Broker, b.topicName(topic), handler, opts...)
+}
+
+// topicName returns persistent://<cluster id>/<namespace>/<topic>, topics
+// which are already fully qualified are kept.
+func (b *tdmqBroker) topicName(topic string) string {
+	if strings.Contains(topic, "://") || b.clusterId == "" || b.namespace == "" {
+		return topic
+	}
+	return "persistent://" + b.clusterId + "/" + b.namespace + "/" + topic
+}
@@ -0,0 +1,38 @@
+package tdmq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestTopicName(t *testing.T) {
+	b := NewBroker(
+		WithClusterID("pulsar-xxxxxxxx"),
+		WithNamespace("default"),
+	).(*tdmqBroker)
+
+	assert.Equal(t, "tdmq", b.Name())
+	assert.Equal(t, "persistent://pulsar-xxxxxxxx/default/test_topic", b.topicName("test_topic"))
+	assert.Equal(t, "non-persistent://pulsar-xxxxxxxx/other/test_topic", b.topicName("non-persistent://pulsar-xxxxxxxx/other/test_topic"))
+
+	b = NewBroker(broker.WithAddress("127.0.0.1:6650")).(*tdmqBroker)
+	assert.Equal(t, "test_topic", b.topicName("test_topic"))
+}
+
+func TestConfigFromOptions(t *testing.T) {
+	c := Config{ClusterID: "pulsar-xxxxxxxx", Namespace: "default", RoleToken: "token"}
+
+	legacy := ConfigFromOptions(broker.NewOptionsAndApply(
+		WithClusterID("pulsar-xxxxxxxx"),
+		WithNamespace("default"),
+		WithRoleToken("token"),
+	))
+	assert.Equal(t, c, legacy)
+	assert.Equal(t, c, ConfigFromOptions(broker.NewOptionsAndApply(WithConfig(c))))
+
+	b := NewBroker(WithConfig(c)).(*tdmqBroker)
+	assert.Equal(t, "persistent://pulsar-xxxxxxxx/default/test_topic", b.topicName("test_topic"))
+}
@@ -0,0 +1,207 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sync"
+)
+
+var ErrTenantRequired = errors.New("tenant not found in context")
+
+type tenantKey struct{}
+
+// NewTenantContext returns a new Context that carries the tenant id.
+func NewTenantContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant id stored in ctx by NewTenantContext.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// TenantBrokerFactory creates the broker dedicated to a tenant, e.g. with its
+// own credentials, RabbitMQ vhost or RocketMQ instance. The returned broker
+// is initialized and connected by the tenant broker.
+type TenantBrokerFactory func(tenant string) (Broker, error)
+
+type TenantOption func(b *tenantBroker)
+
+// WithTenantResolver set how the tenant is read from the publish or subscribe context.
+func WithTenantResolver(fn func(ctx context.Context) (string, bool)) TenantOption {
+	return func(b *tenantBroker) {
+		b.resolve = fn
+	}
+}
+
+// WithTenantFormat set how tenant names are derived, default is "tenant.name".
+func WithTenantFormat(fn func(tenant, name string) string) TenantOption {
+	return func(b *tenantBroker) {
+		b.format = fn
+	}
+}
+
+// WithTenantRequired reject publishes and subscriptions without a tenant.
+func WithTenantRequired() TenantOption {
+	return func(b *tenantBroker) {
+		b.required = true
+	}
+}
+
+// WithTenantBrokerFactory route every tenant to its own broker.
+func WithTenantBrokerFactory(factory TenantBrokerFactory) TenantOption {
+	return func(b *tenantBroker) {
+		b.factory = factory
+	}
+}
+
+type tenantBroker struct {
+	Broker
+
+	sync.Mutex
+
+	resolve  func(ctx context.Context) (string, bool)
+	format   func(tenant, name string) string
+	required bool
+	factory  TenantBrokerFactory
+	tenants  map[string]Broker
+
+	// shared are the topics, in path.Match syntax, left unprefixed.
+	shared []string
+	// untagged leaves the tenant out of the handler context.
+	untagged bool
+}
+
+// NewTenantBroker isolates tenants sharing b: topics, queues and consumer
+// groups are prefixed with the tenant found in the context, so callers keep
+// using logical names.
+func NewTenantBroker(b Broker, opts ...TenantOption) Broker {
+	tb := newTenantBroker(b)
+
+	for _, o := range opts {
+		o(tb)
+	}
+
+	return tb
+}
+
+func newTenantBroker(b Broker) *tenantBroker {
+	return &tenantBroker{
+		Broker:  b,
+		resolve: TenantFromContext,
+		format: func(tenant, name string) string {
+			return tenant + "." + name
+		},
+		tenants: make(map[string]Broker),
+	}
+}
+
+func (b *tenantBroker) Disconnect() error {
+	b.Lock()
+	tenants := b.tenants
+	b.tenants = make(map[string]Broker)
+	b.Unlock()
+
+	var errs []error
+	for _, tb := range tenants {
+		if err := tb.Disconnect(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := b.Broker.Disconnect(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (b *tenantBroker) Publish(ctx context.Context, topic string, msg Any, opts ...PublishOption) error {
+	tenant, ok := b.resolve(ctx)
+	if !ok {
+		if b.required {
+			return ErrTenantRequired
+		}
+		return b.Broker.Publish(ctx, topic, msg, opts...)
+	}
+
+	target, err := b.brokerOf(tenant)
+	if err != nil {
+		return err
+	}
+
+	return target.Publish(ctx, b.topic(tenant, topic), msg, opts...)
+}
+
+func (b *tenantBroker) Subscribe(topic string, handler Handler, binder Binder, opts ...SubscribeOption) (Subscriber, error) {
+	options := NewSubscribeOptions(opts...)
+
+	tenant, ok := b.resolve(options.Context)
+	if !ok {
+		if b.required {
+			return nil, ErrTenantRequired
+		}
+		return b.Broker.Subscribe(topic, handler, binder, opts...)
+	}
+
+	target, err := b.brokerOf(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	// the queue, or group, is prefixed even for the shared topics.
+	if options.Queue != "" {
+		opts = append(opts, WithQueueName(b.format(tenant, options.Queue)))
+	}
+
+	if !b.untagged {
+		next := handler
+		handler = func(ctx context.Context, event Event) error {
+			return next(NewTenantContext(ctx, tenant), event)
+		}
+	}
+
+	return target.Subscribe(b.topic(tenant, topic), handler, binder, opts...)
+}
+
+func (b *tenantBroker) topic(tenant, topic string) string {
+	for _, pattern := range b.shared {
+		if ok, _ := path.Match(pattern, topic); ok || pattern == topic {
+			return topic
+		}
+	}
+	return b.format(tenant, topic)
+}
+
+func (b *tenantBroker) brokerOf(tenant string) (Broker, error) {
+	if b.factory == nil {
+		return b.Broker, nil
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if tb, ok := b.tenants[tenant]; ok {
+		return tb, nil
+	}
+
+	tb, err := b.factory(tenant)
+	if err != nil {
+		return nil, err
+	}
+	if err = tb.Init(); err != nil {
+		return nil, err
+	}
+	if err = tb.Connect(); err != nil {
+		_ = tb.Disconnect()
+		return nil, err
+	}
+
+	b.tenants[tenant] = tb
+
+	return tb, nil
+}
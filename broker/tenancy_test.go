@@ -0,0 +1,143 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordSubscriber struct {
+	topic   string
+	options SubscribeOptions
+}
+
+func (s *recordSubscriber) Options() SubscribeOptions { return s.options }
+func (s *recordSubscriber) Topic() string             { return s.topic }
+func (s *recordSubscriber) Unsubscribe(bool) error    { return nil }
+
+type recordBroker struct {
+	name       string
+	published  []string
+	subscribed []*recordSubscriber
+	handlers   map[string]Handler
+}
+
+func newRecordBroker(name string) *recordBroker {
+	return &recordBroker{name: name, handlers: map[string]Handler{}}
+}
+
+func (b *recordBroker) Name() string         { return b.name }
+func (b *recordBroker) Options() Options     { return NewOptions() }
+func (b *recordBroker) Address() string      { return "" }
+func (b *recordBroker) Init(...Option) error { return nil }
+func (b *recordBroker) Connect() error       { return nil }
+func (b *recordBroker) Disconnect() error    { return nil }
+
+func (b *recordBroker) Publish(_ context.Context, topic string, _ Any, _ ...PublishOption) error {
+	b.published = append(b.published, topic)
+	return nil
+}
+
+func (b *recordBroker) Subscribe(topic string, handler Handler, _ Binder, opts ...SubscribeOption) (Subscriber, error) {
+	sub := &recordSubscriber{topic: topic, options: NewSubscribeOptions(opts...)}
+	b.subscribed = append(b.subscribed, sub)
+	b.handlers[topic] = handler
+	return sub, nil
+}
+
+func TestTenantBroker_Prefix(t *testing.T) {
+	rb := newRecordBroker("shared")
+	b := NewTenantBroker(rb)
+
+	ctx := NewTenantContext(context.Background(), "acme")
+
+	assert.Nil(t, b.Publish(ctx, "orders", "msg"))
+	assert.Nil(t, b.Publish(context.Background(), "orders", "msg"))
+	assert.Equal(t, []string{"acme.orders", "orders"}, rb.published)
+
+	_, err := b.Subscribe("orders",
+		func(ctx context.Context, _ Event) error {
+			tenant, ok := TenantFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "acme", tenant)
+			return nil
+		},
+		nil,
+		WithSubscribeContext(ctx),
+		WithQueueName("billing"),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "acme.orders", rb.subscribed[0].topic)
+	assert.Equal(t, "acme.billing", rb.subscribed[0].options.Queue)
+
+	assert.Nil(t, rb.handlers["acme.orders"](context.Background(), nil))
+}
+
+func TestTenantBroker_Required(t *testing.T) {
+	b := NewTenantBroker(newRecordBroker("shared"), WithTenantRequired())
+
+	assert.ErrorIs(t, b.Publish(context.Background(), "orders", "msg"), ErrTenantRequired)
+
+	_, err := b.Subscribe("orders", nil, nil)
+	assert.ErrorIs(t, err, ErrTenantRequired)
+}
+
+func TestTenantBroker_Factory(t *testing.T) {
+	shared := newRecordBroker("shared")
+	dedicated := map[string]*recordBroker{}
+
+	b := NewTenantBroker(shared,
+		WithTenantBrokerFactory(func(tenant string) (Broker, error) {
+			dedicated[tenant] = newRecordBroker(tenant)
+			return dedicated[tenant], nil
+		}),
+		WithTenantFormat(func(_, name string) string { return name }),
+	)
+
+	ctx := NewTenantContext(context.Background(), "acme")
+	assert.Nil(t, b.Publish(ctx, "orders", "msg"))
+	assert.Nil(t, b.Publish(ctx, "orders", "msg"))
+
+	assert.Empty(t, shared.published)
+	assert.Equal(t, 1, len(dedicated))
+	assert.Equal(t, []string{"orders", "orders"}, dedicated["acme"].published)
+
+	assert.Nil(t, b.Disconnect())
+}
+
+type failConnectBroker struct {
+	*recordBroker
+	connects    int
+	disconnects int
+}
+
+func (b *failConnectBroker) Connect() error {
+	b.connects++
+	return errors.New("connection refused")
+}
+
+func (b *failConnectBroker) Disconnect() error {
+	b.disconnects++
+	return nil
+}
+
+func TestTenantBroker_FactoryConnectFailure(t *testing.T) {
+	dedicated := &failConnectBroker{recordBroker: newRecordBroker("acme")}
+
+	b := NewTenantBroker(newRecordBroker("shared"),
+		WithTenantBrokerFactory(func(string) (Broker, error) {
+			return dedicated, nil
+		}),
+	)
+
+	ctx := NewTenantContext(context.Background(), "acme")
+	assert.NotNil(t, b.Publish(ctx, "orders", "msg"))
+	assert.Equal(t, 1, dedicated.disconnects)
+
+	// the failed broker is not kept, the next publish connects again.
+	assert.NotNil(t, b.Publish(ctx, "orders", "msg"))
+	assert.Equal(t, 2, dedicated.connects)
+	assert.Empty(t, dedicated.published)
+}
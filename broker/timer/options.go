@@ -0,0 +1,76 @@
+package timer
+
+import (
+	"time"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(d *DurableTimer)
+
+// WithStore set the store of the timers, default is a MemoryStore, which
+// does not survive a restart.
+func WithStore(store Store) Option {
+	return func(d *DurableTimer) {
+		d.store = store
+	}
+}
+
+// WithPollInterval set how often the due timers are claimed, default is 1s.
+func WithPollInterval(interval time.Duration) Option {
+	return func(d *DurableTimer) {
+		d.interval = interval
+	}
+}
+
+// WithBatchSize set the number of timers claimed at once, default is 100.
+func WithBatchSize(n int) Option {
+	return func(d *DurableTimer) {
+		d.batch = n
+	}
+}
+
+// WithLease set how long a claimed timer is hidden from the other
+// schedulers, it fires again when it is not published within it. Default
+// is 30s.
+func WithLease(lease time.Duration) Option {
+	return func(d *DurableTimer) {
+		d.lease = lease
+	}
+}
+
+// WithNativeDelay publish the timers due within max at once, with the
+// delay options fn of the broker, e.g. rocketmq.WithDelayTimeLevel or the
+// broker.DelayHeader of a RabbitMQ delayed exchange. The later ones are
+// stored. The timers delayed by the broker cannot be canceled.
+func WithNativeDelay(max time.Duration, fn func(delay time.Duration) []broker.PublishOption) Option {
+	return func(d *DurableTimer) {
+		d.nativeMax = max
+		d.nativeDelay = fn
+	}
+}
+
+// WithCodec set the codec of the stored bodies, default is the codec of the broker.
+func WithCodec(name string) Option {
+	return func(d *DurableTimer) {
+		d.codec = encoding.GetCodec(name)
+	}
+}
+
+// WithTopicBinder decode the stored bodies of topic into a value of binder
+// before they are published, needed by the codecs other than json, e.g.
+// proto.
+func WithTopicBinder(topic string, binder broker.Binder) Option {
+	return func(d *DurableTimer) {
+		d.binders[topic] = binder
+	}
+}
+
+// WithClock set the clock of the scheduler, broker.SystemClock by default.
+func WithClock(clock broker.Clock) Option {
+	return func(d *DurableTimer) {
+		d.clock = clock
+	}
+}
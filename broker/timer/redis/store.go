@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/tx7do/kratos-transport/broker/timer"
+)
+
+const defaultPrefix = "timer:"
+
+var _ timer.Store = (*Store)(nil)
+
+var claimScript = redis.NewScript(1, `
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+local timers = {}
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], ARGV[3], id)
+	local t = redis.call('GET', ARGV[4] .. id)
+	if t then
+		table.insert(timers, t)
+	else
+		redis.call('ZREM', KEYS[1], id)
+	end
+end
+return timers`)
+
+type StoreOption func(s *Store)
+
+// WithPrefix set the prefix of all keys, default is "timer:".
+func WithPrefix(prefix string) StoreOption {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// Store keeps the timers in redis, shared by the schedulers:
+//
+//	{prefix}due          sorted set of the timer ids by due time
+//	{prefix}timer:{id}   timer as JSON
+type Store struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+func NewStore(pool *redis.Pool, opts ...StoreOption) *Store {
+	s := &Store{
+		pool:   pool,
+		prefix: defaultPrefix,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+func (s *Store) dueKey() string            { return s.prefix + "due" }
+func (s *Store) timerKey(id string) string { return s.prefix + "timer:" + id }
+
+func (s *Store) Add(ctx context.Context, t *timer.Timer) error {
+	buf, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("SET", s.timerKey(t.ID), buf)
+	_ = conn.Send("ZADD", s.dueKey(), t.DueAt.UnixMilli(), t.ID)
+	_, err = redis.DoContext(conn, ctx, "EXEC")
+	return err
+}
+
+func (s *Store) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*timer.Timer, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if limit <= 0 {
+		limit = -1
+	}
+	values, err := redis.ByteSlices(claimScript.DoContext(ctx, conn, s.dueKey(),
+		now.UnixMilli(), limit, now.Add(lease).UnixMilli(), s.prefix+"timer:"))
+	if err != nil {
+		return nil, err
+	}
+
+	timers := make([]*timer.Timer, 0, len(values))
+	for _, v := range values {
+		var t timer.Timer
+		if err = json.Unmarshal(v, &t); err != nil {
+			return nil, err
+		}
+		timers = append(timers, &t)
+	}
+	return timers, nil
+}
+
+func (s *Store) Remove(ctx context.Context, id string) (bool, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("ZREM", s.dueKey(), id)
+	_ = conn.Send("DEL", s.timerKey(id))
+	replies, err := redis.Ints(redis.DoContext(conn, ctx, "EXEC"))
+	if err != nil {
+		return false, err
+	}
+	return replies[0] > 0, nil
+}
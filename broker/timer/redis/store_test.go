@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker/timer"
+	"github.com/tx7do/kratos-transport/internal/redistest"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(redistest.NewPool(t), WithPrefix("timer-test:"))
+	defer store.Remove(ctx, "t1")
+
+	now := time.Now()
+	assert.Nil(t, store.Add(ctx, &timer.Timer{ID: "t1", Topic: "orders.timeout", Body: []byte("1"), DueAt: now}))
+	assert.Nil(t, store.Add(ctx, &timer.Timer{ID: "t2", Topic: "orders.timeout", Body: []byte("2"), DueAt: now.Add(time.Hour)}))
+	defer store.Remove(ctx, "t2")
+
+	timers, err := store.Claim(ctx, now, 10, time.Minute)
+	assert.Nil(t, err)
+	if assert.Len(t, timers, 1) {
+		assert.Equal(t, "t1", timers[0].ID)
+		assert.Equal(t, []byte("1"), timers[0].Body)
+	}
+
+	timers, err = store.Claim(ctx, now, 10, time.Minute)
+	assert.Nil(t, err)
+	assert.Empty(t, timers, "leased")
+
+	ok, err := store.Remove(ctx, "t1")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, _ = store.Remove(ctx, "t1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,83 @@
+package timer
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store persists the timers until they fire.
+type Store interface {
+	Add(ctx context.Context, t *Timer) error
+	// Claim return up to limit timers due at now, and postpone them by lease
+	// so the other schedulers skip them while they are published.
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Timer, error)
+	// Remove delete the timer id, false when there is none.
+	Remove(ctx context.Context, id string) (bool, error)
+}
+
+// MemoryStore keeps the timers of a single process, lost on restart, for tests.
+type MemoryStore struct {
+	sync.Mutex
+
+	timers map[string]*Timer
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		timers: make(map[string]*Timer),
+	}
+}
+
+func (s *MemoryStore) Add(_ context.Context, t *Timer) error {
+	s.Lock()
+	defer s.Unlock()
+
+	c := *t
+	s.timers[t.ID] = &c
+	return nil
+}
+
+func (s *MemoryStore) Claim(_ context.Context, now time.Time, limit int, lease time.Duration) ([]*Timer, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var due []*Timer
+	for _, t := range s.timers {
+		if !t.DueAt.After(now) {
+			due = append(due, t)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].DueAt.Before(due[j].DueAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*Timer, 0, len(due))
+	for _, t := range due {
+		c := *t
+		claimed = append(claimed, &c)
+		t.DueAt = now.Add(lease)
+	}
+	return claimed, nil
+}
+
+func (s *MemoryStore) Remove(_ context.Context, id string) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	_, ok := s.timers[id]
+	delete(s.timers, id)
+	return ok, nil
+}
+
+// Len return the number of timers not fired yet.
+func (s *MemoryStore) Len() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.timers)
+}
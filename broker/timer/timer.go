@@ -0,0 +1,300 @@
+package timer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/google/uuid"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// The headers of the messages published by the timers, left out on the
+// brokers whose messages have no headers, see broker.CarriesHeaders.
+const (
+	IDHeader    = "x-timer-id"
+	DueAtHeader = "x-timer-due-at"
+)
+
+var (
+	ErrAlreadyStarted = errors.New("timer: already started")
+	ErrNotFound       = errors.New("timer: not found")
+	ErrNoBinder       = errors.New("timer: no binder for the topic")
+)
+
+// Timer is a message to publish to a topic at a future time.
+type Timer struct {
+	ID      string         `json:"id"`
+	Topic   string         `json:"topic"`
+	Headers broker.Headers `json:"headers,omitempty"`
+	Body    []byte         `json:"body"`
+	DueAt   time.Time      `json:"due_at"`
+}
+
+type ScheduleOption func(t *Timer)
+
+// WithID set the id of the timer, default is a random one. Scheduling a
+// timer with the id of another replaces it, e.g. to push back the timeout
+// of an order.
+func WithID(id string) ScheduleOption {
+	return func(t *Timer) {
+		t.ID = id
+	}
+}
+
+// WithHeaders add headers to the message of the timer.
+func WithHeaders(headers broker.Headers) ScheduleOption {
+	return func(t *Timer) {
+		for k, v := range headers {
+			t.Headers[k] = v
+		}
+	}
+}
+
+// DurableTimer publishes messages at a future time, for the order timeouts
+// and the SLA escalations:
+//
+//	d := timer.NewDurableTimer(b, timer.WithStore(redis.NewStore(pool)))
+//	_ = d.Start(ctx)
+//	id, err := d.Schedule(ctx, "orders.timeout", &OrderTimeout{ID: order.ID}, order.CreatedAt.Add(30*time.Minute))
+//
+// The timers are kept in the store until they are published, so they
+// survive a restart, and delivered at least once: a timer is published
+// again when its scheduler stops between its publish and its removal. Run
+// as many schedulers as needed on a shared store, a timer is claimed by one
+// at a time.
+//
+// The timers with headers are refused at Schedule by the brokers whose
+// messages have none, rather than failing to fire.
+type DurableTimer struct {
+	b       broker.Broker
+	store   Store
+	codec   encoding.Codec
+	carries bool
+
+	interval    time.Duration
+	batch       int
+	lease       time.Duration
+	nativeMax   time.Duration
+	nativeDelay func(delay time.Duration) []broker.PublishOption
+	binders     map[string]broker.Binder
+	clock       broker.Clock
+
+	sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewDurableTimer(b broker.Broker, opts ...Option) *DurableTimer {
+	d := &DurableTimer{
+		b:        b,
+		codec:    b.Options().Codec,
+		carries:  broker.CarriesHeaders(b),
+		interval: time.Second,
+		batch:    100,
+		lease:    30 * time.Second,
+		binders:  make(map[string]broker.Binder),
+		clock:    broker.SystemClock,
+	}
+
+	for _, o := range opts {
+		o(d)
+	}
+
+	if d.store == nil {
+		d.store = NewMemoryStore()
+	}
+
+	return d
+}
+
+// Schedule publish msg to topic at at, and return the id of the timer.
+func (d *DurableTimer) Schedule(ctx context.Context, topic string, msg broker.Any, at time.Time, opts ...ScheduleOption) (string, error) {
+	t := &Timer{
+		ID:      uuid.NewString(),
+		Topic:   topic,
+		Headers: broker.Headers{},
+		DueAt:   at,
+	}
+	for _, o := range opts {
+		o(t)
+	}
+	if !d.carries {
+		if err := broker.RejectHeaders(topic, t.Headers); err != nil {
+			return "", err
+		}
+	}
+
+	if delay := at.Sub(d.clock.Now()); d.nativeDelay != nil && delay <= d.nativeMax {
+		if delay < 0 {
+			delay = 0
+		}
+		pubOpts := append([]broker.PublishOption{broker.WithHeaders(d.headers(t))}, d.nativeDelay(delay)...)
+		if err := d.b.Publish(ctx, topic, msg, pubOpts...); err != nil {
+			return "", err
+		}
+		return t.ID, nil
+	}
+
+	if _, ok := d.binders[topic]; !ok && d.codec != nil && d.codec.Name() != "json" {
+		return "", fmt.Errorf("%w [%s] with codec %s", ErrNoBinder, topic, d.codec.Name())
+	}
+	body, err := broker.Marshal(d.codec, msg)
+	if err != nil {
+		return "", err
+	}
+	t.Body = body
+
+	if err = d.store.Add(ctx, t); err != nil {
+		return "", err
+	}
+	return t.ID, nil
+}
+
+// After publish msg to topic after delay, and return the id of the timer.
+func (d *DurableTimer) After(ctx context.Context, topic string, msg broker.Any, delay time.Duration, opts ...ScheduleOption) (string, error) {
+	return d.Schedule(ctx, topic, msg, d.clock.Now().Add(delay), opts...)
+}
+
+// Cancel remove the timer id before it fires, ErrNotFound is returned when
+// it fired already, or was delayed by the broker.
+func (d *DurableTimer) Cancel(ctx context.Context, id string) error {
+	ok, err := d.store.Remove(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Start publish the due timers every poll interval until Stop.
+func (d *DurableTimer) Start(ctx context.Context) error {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.done != nil {
+		return ErrAlreadyStarted
+	}
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.done = make(chan struct{})
+
+	go d.run(ctx, d.done)
+
+	return nil
+}
+
+// Stop stop publishing the due timers, they are kept in the store.
+func (d *DurableTimer) Stop(_ context.Context) error {
+	d.Lock()
+	cancel, done := d.cancel, d.done
+	d.cancel, d.done = nil, nil
+	d.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	<-done
+
+	return nil
+}
+
+func (d *DurableTimer) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := d.clock.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			for {
+				n, err := d.Poll(ctx)
+				if err != nil && ctx.Err() == nil {
+					log.Errorf("[timer] poll failed: %v", err)
+				}
+				if err != nil || n < d.batch {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Poll publish a batch of due timers and return how many were claimed,
+// called by the scheduler started with Start.
+func (d *DurableTimer) Poll(ctx context.Context) (int, error) {
+	timers, err := d.store.Claim(ctx, d.clock.Now(), d.batch, d.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, t := range timers {
+		if err = d.fire(ctx, t); err != nil {
+			log.Errorf("[timer] fire [%s] to [%s] failed: %v", t.ID, t.Topic, err)
+		}
+	}
+	return len(timers), nil
+}
+
+// fire publish t and remove it. A failed publish is retried once its lease
+// expires, a body which cannot be decoded is dropped.
+func (d *DurableTimer) fire(ctx context.Context, t *Timer) error {
+	msg, err := d.decode(t)
+	if err != nil {
+		if _, rmErr := d.store.Remove(ctx, t.ID); rmErr != nil {
+			return rmErr
+		}
+		return fmt.Errorf("drop timer: %w", err)
+	}
+
+	if err = d.b.Publish(ctx, t.Topic, msg, broker.WithHeaders(d.headers(t))); err != nil {
+		return err
+	}
+	_, err = d.store.Remove(ctx, t.ID)
+	return err
+}
+
+// decode return the message of the stored body of t.
+func (d *DurableTimer) decode(t *Timer) (broker.Any, error) {
+	if binder, ok := d.binders[t.Topic]; ok {
+		v := binder()
+		if err := broker.Unmarshal(d.codec, t.Body, v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+
+	switch {
+	case d.codec == nil:
+		return t.Body, nil
+	case d.codec.Name() == "json":
+		return json.RawMessage(t.Body), nil
+	default:
+		return nil, fmt.Errorf("%w [%s] with codec %s", ErrNoBinder, t.Topic, d.codec.Name())
+	}
+}
+
+func (d *DurableTimer) headers(t *Timer) broker.Headers {
+	if !d.carries {
+		return nil
+	}
+	headers := make(broker.Headers, len(t.Headers)+2)
+	for k, v := range t.Headers {
+		headers[k] = v
+	}
+	headers[IDHeader] = t.ID
+	headers[DueAtHeader] = strconv.FormatInt(t.DueAt.UnixMilli(), 10)
+	return headers
+}
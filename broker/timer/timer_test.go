@@ -0,0 +1,160 @@
+package timer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/mocks"
+)
+
+type orderTimeout struct {
+	OrderID string `json:"order_id"`
+}
+
+func newFakeBroker(t *testing.T, opts ...broker.Option) *mocks.FakeBroker {
+	b := mocks.NewFakeBroker(opts...)
+	assert.Nil(t, b.Connect())
+	return b
+}
+
+func TestDurableTimer(t *testing.T) {
+	ctx := context.Background()
+	b := newFakeBroker(t, broker.WithCodec("json"))
+	clock := broker.NewFakeClock(time.Now())
+	store := NewMemoryStore()
+	d := NewDurableTimer(b, WithStore(store), WithClock(clock))
+
+	id, err := d.After(ctx, "orders.timeout", &orderTimeout{OrderID: "1"}, time.Minute,
+		WithHeaders(broker.Headers{"x-tenant": "acme"}))
+	assert.Nil(t, err)
+	canceled, err := d.After(ctx, "orders.timeout", &orderTimeout{OrderID: "2"}, time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, d.Cancel(ctx, canceled))
+	assert.ErrorIs(t, d.Cancel(ctx, canceled), ErrNotFound)
+
+	n, err := d.Poll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	clock.Advance(time.Minute)
+	n, err = d.Poll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 0, store.Len())
+
+	published := b.Published()
+	if assert.Len(t, published, 1) {
+		assert.Equal(t, "orders.timeout", published[0].Topic)
+		assert.JSONEq(t, `{"order_id":"1"}`, string(published[0].Body.(json.RawMessage)))
+		assert.Equal(t, id, published[0].Headers[IDHeader])
+		assert.Equal(t, "acme", published[0].Headers["x-tenant"])
+	}
+}
+
+func TestDurableTimer_Headerless(t *testing.T) {
+	ctx := context.Background()
+	b := mocks.NewHeaderlessBroker(broker.WithCodec("json"))
+	assert.Nil(t, b.Connect())
+	clock := broker.NewFakeClock(time.Now())
+	store := NewMemoryStore()
+	d := NewDurableTimer(b, WithStore(store), WithClock(clock))
+
+	// refused now rather than failing to fire.
+	_, err := d.After(ctx, "orders.timeout", &orderTimeout{OrderID: "1"}, time.Minute,
+		WithHeaders(broker.Headers{"x-tenant": "acme"}))
+	assert.ErrorIs(t, err, broker.ErrHeadersUnsupported)
+	assert.Equal(t, 0, store.Len())
+
+	_, err = d.After(ctx, "orders.timeout", &orderTimeout{OrderID: "2"}, time.Minute)
+	assert.Nil(t, err)
+
+	clock.Advance(time.Minute)
+	n, err := d.Poll(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 0, store.Len())
+
+	published := b.Published()
+	if assert.Len(t, published, 1) {
+		assert.Empty(t, published[0].Headers)
+	}
+}
+
+func TestDurableTimer_Retry(t *testing.T) {
+	ctx := context.Background()
+	b := newFakeBroker(t)
+	b.ExpectPublish("sla.escalate").Return(errors.New("unavailable"))
+	clock := broker.NewFakeClock(time.Now())
+	store := NewMemoryStore()
+	d := NewDurableTimer(b, WithStore(store), WithClock(clock), WithLease(10*time.Second))
+
+	_, err := d.Schedule(ctx, "sla.escalate", []byte("ticket-1"), clock.Now())
+	assert.Nil(t, err)
+
+	n, _ := d.Poll(ctx)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, store.Len(), "kept after the failed publish")
+
+	n, _ = d.Poll(ctx)
+	assert.Equal(t, 0, n, "leased")
+
+	clock.Advance(10 * time.Second)
+	n, _ = d.Poll(ctx)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 0, store.Len())
+	assert.Len(t, b.Published(), 2)
+	assert.Equal(t, []byte("ticket-1"), b.Published()[1].Body)
+}
+
+func TestDurableTimer_NativeDelay(t *testing.T) {
+	ctx := context.Background()
+	b := newFakeBroker(t)
+	store := NewMemoryStore()
+	d := NewDurableTimer(b, WithStore(store), WithNativeDelay(time.Hour, func(delay time.Duration) []broker.PublishOption {
+		return []broker.PublishOption{broker.WithHeaders(broker.Headers{broker.DelayHeader: delay.String()})}
+	}))
+
+	_, err := d.After(ctx, "orders.timeout", "1", 30*time.Minute)
+	assert.Nil(t, err)
+	_, err = d.After(ctx, "orders.timeout", "2", 2*time.Hour)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, store.Len())
+	if assert.Len(t, b.Published(), 1) {
+		assert.NotEmpty(t, b.Published()[0].Headers[broker.DelayHeader])
+	}
+}
+
+func TestDurableTimer_Binder(t *testing.T) {
+	ctx := context.Background()
+	b := newFakeBroker(t, broker.WithCodec("proto"))
+
+	_, err := NewDurableTimer(b).After(ctx, "orders.timeout", "1", time.Minute)
+	assert.ErrorIs(t, err, ErrNoBinder)
+
+	d := NewDurableTimer(b, WithCodec("json"), WithTopicBinder("orders.timeout", func() broker.Any {
+		return &orderTimeout{}
+	}))
+	_, err = d.Schedule(ctx, "orders.timeout", &orderTimeout{OrderID: "1"}, time.Now())
+	assert.Nil(t, err)
+	_, err = d.Poll(ctx)
+	assert.Nil(t, err)
+	if assert.Len(t, b.Published(), 1) {
+		assert.Equal(t, &orderTimeout{OrderID: "1"}, b.Published()[0].Body)
+	}
+}
+
+func TestDurableTimer_StartStop(t *testing.T) {
+	d := NewDurableTimer(newFakeBroker(t))
+
+	assert.Nil(t, d.Start(context.Background()))
+	assert.ErrorIs(t, d.Start(context.Background()), ErrAlreadyStarted)
+	assert.Nil(t, d.Stop(context.Background()))
+	assert.Nil(t, d.Stop(context.Background()))
+}
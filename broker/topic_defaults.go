@@ -0,0 +1,41 @@
+package broker
+
+import (
+	"path"
+)
+
+// TopicDefaults are the publish options applied to the topics matching Pattern.
+type TopicDefaults struct {
+	// Pattern is a topic in path.Match syntax, e.g. "orders.*".
+	Pattern string
+	Options []PublishOption
+}
+
+// WithTopicDefaults register options applied to every message published to
+// the topics matching pattern, e.g. the delivery mode, content type, ttl,
+// priority or tag of the driver. The options of the call take precedence,
+// and the defaults of several matching patterns apply in registration order.
+// Every driver applies them in InterceptPublish, the header defaults are
+// refused by the drivers without headers, see HeaderCarrier.
+func WithTopicDefaults(pattern string, opts ...PublishOption) Option {
+	return func(o *Options) {
+		o.TopicDefaults = append(o.TopicDefaults, TopicDefaults{Pattern: pattern, Options: opts})
+	}
+}
+
+// TopicPublishOptions return the default options of topic followed by opts.
+func (o *Options) TopicPublishOptions(topic string, opts []PublishOption) []PublishOption {
+	var defaults []PublishOption
+	for _, td := range o.TopicDefaults {
+		if td.Pattern != topic {
+			if ok, _ := path.Match(td.Pattern, topic); !ok {
+				continue
+			}
+		}
+		defaults = append(defaults, td.Options...)
+	}
+	if len(defaults) == 0 {
+		return opts
+	}
+	return append(defaults, opts...)
+}
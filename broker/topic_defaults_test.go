@@ -0,0 +1,44 @@
+package broker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type priorityKey struct{}
+
+func withPriority(p int) PublishOption {
+	return PublishContextWithValue(priorityKey{}, p)
+}
+
+func TestTopicDefaults(t *testing.T) {
+	options := NewOptionsAndApply(
+		WithTopicDefaults("orders.*", withPriority(1), WithHeaders(Headers{"ttl": "60s", "tag": "orders"})),
+		WithTopicDefaults("orders.vip", withPriority(9)),
+	)
+
+	priority := func(topic string, opts ...PublishOption) (int, Headers) {
+		_, opts, err := options.InterceptPublish(context.Background(), topic, []byte("msg"), opts)
+		assert.Nil(t, err)
+		po := NewPublishOptions(opts...)
+		p, _ := po.Context.Value(priorityKey{}).(int)
+		return p, po.Headers
+	}
+
+	p, headers := priority("orders.created")
+	assert.Equal(t, 1, p)
+	assert.Equal(t, Headers{"ttl": "60s", "tag": "orders"}, headers)
+
+	// later registrations, then the call, take precedence.
+	p, _ = priority("orders.vip")
+	assert.Equal(t, 9, p)
+	p, headers = priority("orders.vip", withPriority(5), WithHeaders(Headers{"ttl": "5s"}))
+	assert.Equal(t, 5, p)
+	assert.Equal(t, Headers{"ttl": "5s", "tag": "orders"}, headers)
+
+	p, headers = priority("payments.created")
+	assert.Equal(t, 0, p)
+	assert.Nil(t, headers)
+}
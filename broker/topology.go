@@ -0,0 +1,105 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type fanInOriginKey struct{}
+
+// FanInOriginFromContext returns the topic a fan-in handler was subscribed to for the current message.
+func FanInOriginFromContext(ctx context.Context) (string, bool) {
+	origin, ok := ctx.Value(fanInOriginKey{}).(string)
+	return origin, ok
+}
+
+type fanInSubscriber struct {
+	topics  []string
+	options SubscribeOptions
+	subs    []Subscriber
+}
+
+func (s *fanInSubscriber) Options() SubscribeOptions {
+	return s.options
+}
+
+func (s *fanInSubscriber) Topic() string {
+	return strings.Join(s.topics, ",")
+}
+
+func (s *fanInSubscriber) Unsubscribe(removeFromManager bool) error {
+	var errs []error
+	for _, sub := range s.subs {
+		if err := sub.Unsubscribe(removeFromManager); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FanIn subscribe to every topic with the same handler, the subscribed topic
+// is available to the handler through FanInOriginFromContext.
+func FanIn(b Broker, topics []string, handler Handler, binder Binder, opts ...SubscribeOption) (Subscriber, error) {
+	fanIn := &fanInSubscriber{
+		topics:  topics,
+		options: NewSubscribeOptions(opts...),
+	}
+
+	for _, topic := range topics {
+		origin := topic
+		sub, err := b.Subscribe(topic,
+			func(ctx context.Context, event Event) error {
+				return handler(context.WithValue(ctx, fanInOriginKey{}, origin), event)
+			},
+			binder,
+			opts...,
+		)
+		if err != nil {
+			_ = fanIn.Unsubscribe(true)
+			return nil, fmt.Errorf("fan-in subscribe topic[%s] failed: %w", topic, err)
+		}
+		fanIn.subs = append(fanIn.subs, sub)
+	}
+
+	return fanIn, nil
+}
+
+// FanOutTarget is one destination of FanOut.
+type FanOutTarget struct {
+	Topic string
+
+	// Transform converts the message for this target, nil sends it unchanged,
+	// returning a nil message skips the target.
+	Transform func(ctx context.Context, msg Any) (Any, error)
+
+	Options []PublishOption
+}
+
+// FanOut publish msg to every target, a failing target does not stop the
+// others and all errors are returned together.
+func FanOut(ctx context.Context, b Broker, msg Any, targets []FanOutTarget, opts ...PublishOption) error {
+	var errs []error
+
+	for _, target := range targets {
+		out := msg
+		if target.Transform != nil {
+			var err error
+			if out, err = target.Transform(ctx, msg); err != nil {
+				errs = append(errs, fmt.Errorf("fan-out transform topic[%s] failed: %w", target.Topic, err))
+				continue
+			}
+			if out == nil {
+				continue
+			}
+		}
+
+		publishOpts := append(append([]PublishOption{}, opts...), target.Options...)
+		if err := b.Publish(ctx, target.Topic, out, publishOpts...); err != nil {
+			errs = append(errs, fmt.Errorf("fan-out publish topic[%s] failed: %w", target.Topic, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,148 @@
+package broker
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrSubscriptionStopped = errors.New("subscription stopped on unmarshal failure")
+
+// UnmarshalPolicy decides what a subscription does with a message whose body
+// cannot be decoded.
+type UnmarshalPolicy int
+
+const (
+	// UnmarshalDefault keeps the behavior of the broker.
+	UnmarshalDefault UnmarshalPolicy = iota
+	// UnmarshalSkip ack and drop the message.
+	UnmarshalSkip
+	// UnmarshalDeadLetter publish the undecoded body to a topic, then ack.
+	UnmarshalDeadLetter
+	// UnmarshalFallback pass the undecoded body to a fallback handler.
+	UnmarshalFallback
+	// UnmarshalFail stop the subscription.
+	UnmarshalFail
+)
+
+func (p UnmarshalPolicy) String() string {
+	switch p {
+	case UnmarshalDefault:
+		return "default"
+	case UnmarshalSkip:
+		return "skip"
+	case UnmarshalDeadLetter:
+		return "dead-letter"
+	case UnmarshalFallback:
+		return "fallback"
+	case UnmarshalFail:
+		return "fail"
+	default:
+		return "unknown"
+	}
+}
+
+// RawFallbackHandler receives the undecoded body of a message.
+type RawFallbackHandler func(ctx context.Context, topic string, headers Headers, body []byte) error
+
+// UnmarshalFailure is the unmarshal failure policy of a subscription.
+type UnmarshalFailure struct {
+	Policy UnmarshalPolicy
+
+	DeadLetter      Broker
+	DeadLetterTopic string
+
+	Fallback RawFallbackHandler
+}
+
+// UnmarshalAction is what the broker does with a message that failed to decode.
+type UnmarshalAction int
+
+const (
+	// UnmarshalActionDefault apply the behavior of the broker.
+	UnmarshalActionDefault UnmarshalAction = iota
+	// UnmarshalActionAck ack the message, it is handled.
+	UnmarshalActionAck
+	// UnmarshalActionRetry redeliver the message.
+	UnmarshalActionRetry
+	// UnmarshalActionStop stop the subscription.
+	UnmarshalActionStop
+)
+
+// WithUnmarshalSkip ack and drop the messages which cannot be decoded.
+func WithUnmarshalSkip() SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.UnmarshalFailure = UnmarshalFailure{Policy: UnmarshalSkip}
+	}
+}
+
+// WithUnmarshalDeadLetter publish the undecoded body to topic with b, which
+// should have no codec so the body is kept as is. The message is redelivered
+// when the publish fails.
+func WithUnmarshalDeadLetter(b Broker, topic string) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.UnmarshalFailure = UnmarshalFailure{Policy: UnmarshalDeadLetter, DeadLetter: b, DeadLetterTopic: topic}
+	}
+}
+
+// WithUnmarshalFallback pass the undecoded body to fn, the message is acked
+// when fn succeeds and redelivered otherwise.
+func WithUnmarshalFallback(fn RawFallbackHandler) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.UnmarshalFailure = UnmarshalFailure{Policy: UnmarshalFallback, Fallback: fn}
+	}
+}
+
+// WithUnmarshalFail stop the subscription on the first message which cannot
+// be decoded, the message is not acked.
+func WithUnmarshalFail() SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.UnmarshalFailure = UnmarshalFailure{Policy: UnmarshalFail}
+	}
+}
+
+// HandleUnmarshalFailure apply the unmarshal failure policy to a message and
+// return what the broker must do with it. The failure is reported to the
+// error handler of the subscription. The messages exceeding the receive size
+// are parked instead.
+func (o *SubscribeOptions) HandleUnmarshalFailure(ctx context.Context, topic string, headers Headers, body []byte, err error) UnmarshalAction {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if errors.Is(err, ErrPayloadTooLarge) {
+		return o.parkOversize(ctx, headers, body)
+	}
+
+	f := o.UnmarshalFailure
+	switch f.Policy {
+	case UnmarshalSkip:
+		return UnmarshalActionAck
+
+	case UnmarshalDeadLetter:
+		if f.DeadLetter == nil {
+			return UnmarshalActionRetry
+		}
+		if pubErr := f.DeadLetter.Publish(ctx, f.DeadLetterTopic, body); pubErr != nil {
+			o.ReportError(ctx, ErrUnmarshal, pubErr, nil)
+			return UnmarshalActionRetry
+		}
+		return UnmarshalActionAck
+
+	case UnmarshalFallback:
+		if f.Fallback == nil {
+			return UnmarshalActionRetry
+		}
+		if fbErr := f.Fallback(ctx, topic, headers, body); fbErr != nil {
+			o.ReportError(ctx, ErrUnmarshal, fbErr, nil)
+			return UnmarshalActionRetry
+		}
+		return UnmarshalActionAck
+
+	case UnmarshalFail:
+		o.ReportError(ctx, ErrSubscriptionStopped, err, nil)
+		return UnmarshalActionStop
+
+	default:
+		return UnmarshalActionDefault
+	}
+}
@@ -0,0 +1,46 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleUnmarshalFailure(t *testing.T) {
+	ctx := context.Background()
+	body := []byte("{broken")
+	cause := errors.New("invalid character")
+
+	options := NewSubscribeOptions()
+	assert.Equal(t, UnmarshalActionDefault, options.HandleUnmarshalFailure(ctx, "orders", nil, body, cause))
+
+	options = NewSubscribeOptions(WithUnmarshalSkip())
+	assert.Equal(t, UnmarshalActionAck, options.HandleUnmarshalFailure(ctx, "orders", nil, body, cause))
+
+	dlq := newRecordBroker("dlq")
+	options = NewSubscribeOptions(WithUnmarshalDeadLetter(dlq, "orders.dlq"))
+	assert.Equal(t, UnmarshalActionAck, options.HandleUnmarshalFailure(ctx, "orders", nil, body, cause))
+	assert.Equal(t, []string{"orders.dlq"}, dlq.published)
+
+	var fallbackBody []byte
+	options = NewSubscribeOptions(WithUnmarshalFallback(func(_ context.Context, topic string, _ Headers, body []byte) error {
+		fallbackBody = body
+		if topic == "payments" {
+			return errors.New("fallback failed")
+		}
+		return nil
+	}))
+	assert.Equal(t, UnmarshalActionAck, options.HandleUnmarshalFailure(ctx, "orders", nil, body, cause))
+	assert.Equal(t, body, fallbackBody)
+	assert.Equal(t, UnmarshalActionRetry, options.HandleUnmarshalFailure(ctx, "payments", nil, body, cause))
+
+	var reported error
+	options = NewSubscribeOptions(WithUnmarshalFail(), WithSubscribeErrorHandler(func(_ context.Context, err error, _ Event) {
+		reported = err
+	}))
+	assert.Equal(t, UnmarshalActionStop, options.HandleUnmarshalFailure(ctx, "orders", nil, body, cause))
+	assert.ErrorIs(t, reported, ErrSubscriptionStopped)
+	assert.ErrorIs(t, reported, cause)
+}
@@ -0,0 +1,55 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidConfig = errors.New("invalid broker configuration")
+
+// Validator is implemented by the brokers checking their options at Init.
+type Validator interface {
+	Validate() error
+}
+
+// ConfigError lists every problem found in the options of a broker.
+type ConfigError struct {
+	Broker   string
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s invalid configuration, %d problem(s): %s",
+		e.Broker, len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+func (e *ConfigError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// ConfigValidator collects the problems of a configuration, so they are all
+// reported at once instead of failing on the first one.
+type ConfigValidator struct {
+	broker   string
+	problems []string
+}
+
+func NewConfigValidator(name string) *ConfigValidator {
+	return &ConfigValidator{broker: name}
+}
+
+// Require record the problem when ok is false.
+func (v *ConfigValidator) Require(ok bool, format string, args ...interface{}) {
+	if !ok {
+		v.problems = append(v.problems, fmt.Sprintf(format, args...))
+	}
+}
+
+// Err return a ConfigError with the recorded problems, nil if there is none.
+func (v *ConfigValidator) Err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ConfigError{Broker: v.broker, Problems: v.problems}
+}
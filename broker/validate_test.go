@@ -0,0 +1,24 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValidator(t *testing.T) {
+	v := NewConfigValidator("test")
+	v.Require(true, "never reported")
+	assert.Nil(t, v.Err())
+
+	v.Require(false, "access key is required")
+	v.Require(false, "prefetch count %d must not be negative", -1)
+
+	err := v.Err()
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	var configErr *ConfigError
+	assert.ErrorAs(t, err, &configErr)
+	assert.Equal(t, []string{"access key is required", "prefetch count -1 must not be negative"}, configErr.Problems)
+	assert.Equal(t, "test invalid configuration, 2 problem(s): access key is required; prefetch count -1 must not be negative", err.Error())
+}
@@ -0,0 +1,144 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+var ErrInvalidMessage = errors.New("invalid message")
+
+// InvalidReasonHeader carries the validation error of a parked message.
+const InvalidReasonHeader = "x-invalid-reason"
+
+// MessageValidator validate the decoded body of a message. The bodies
+// implementing Validator are validated by default, e.g. the messages
+// generated by protoc-gen-validate. protovalidate or go-playground/validator
+// plug in as:
+//
+//	broker.WithMessageValidator(func(_ context.Context, body any) error {
+//		if m, ok := body.(proto.Message); ok {
+//			return protovalidate.Validate(m)
+//		}
+//		return nil
+//	})
+//
+//	broker.WithMessageValidator(func(ctx context.Context, body any) error {
+//		return validate.StructCtx(ctx, body)
+//	})
+type MessageValidator func(ctx context.Context, body any) error
+
+// InvalidMessagePolicy decides what ValidationHandler does with a message
+// failing the validation.
+type InvalidMessagePolicy int
+
+const (
+	// InvalidReject return the validation error so the message is not acked,
+	// or park it when a park topic is set.
+	InvalidReject InvalidMessagePolicy = iota
+	// InvalidAck log and ack the message.
+	InvalidAck
+)
+
+func (p InvalidMessagePolicy) String() string {
+	switch p {
+	case InvalidReject:
+		return "reject"
+	case InvalidAck:
+		return "ack"
+	default:
+		return "unknown"
+	}
+}
+
+type ValidationOption func(v *messageValidation)
+
+// WithMessageValidator validate the bodies with fn instead of their Validate method.
+func WithMessageValidator(fn MessageValidator) ValidationOption {
+	return func(v *messageValidation) {
+		v.validate = fn
+	}
+}
+
+// WithInvalidPolicy set the policy of the invalid messages, default is InvalidReject.
+func WithInvalidPolicy(p InvalidMessagePolicy) ValidationOption {
+	return func(v *messageValidation) {
+		v.policy = p
+	}
+}
+
+// WithInvalidPark reject the invalid messages by publishing them to topic
+// with b, with the error in InvalidReasonHeader, then acking them. They are
+// redelivered when the publish fails.
+func WithInvalidPark(b Broker, topic string) ValidationOption {
+	return func(v *messageValidation) {
+		v.policy = InvalidReject
+		v.park = b
+		v.parkTopic = topic
+	}
+}
+
+type messageValidation struct {
+	validate  MessageValidator
+	policy    InvalidMessagePolicy
+	park      Broker
+	parkTopic string
+}
+
+// ValidationHandler wraps handler to validate the decoded body of the
+// messages before it runs, so the services consuming an event share its
+// input validation. The invalid messages never reach handler, they are
+// handled by the policy set with WithInvalidPolicy or WithInvalidPark.
+func ValidationHandler(handler Handler, opts ...ValidationOption) Handler {
+	v := &messageValidation{validate: validateBody}
+	for _, o := range opts {
+		o(v)
+	}
+
+	return func(ctx context.Context, event Event) error {
+		if event == nil || event.Message() == nil || event.Message().Body == nil {
+			return handler(ctx, event)
+		}
+
+		err := v.validate(ctx, event.Message().Body)
+		if err == nil {
+			return handler(ctx, event)
+		}
+		return v.invalid(ctx, event, err)
+	}
+}
+
+func (v *messageValidation) invalid(ctx context.Context, event Event, err error) error {
+	switch {
+	case v.policy == InvalidAck:
+		log.Warnf("[broker] ack invalid message of [%s]: %v", event.Topic(), err)
+		return AutoAck(event)
+
+	case v.park != nil:
+		headers := Headers{}
+		for k, val := range event.Message().Headers {
+			headers[k] = val
+		}
+		headers[InvalidReasonHeader] = err.Error()
+		AppendHop(ctx, headers, v.park.Options().ServiceName, event.Topic())
+
+		if pubErr := v.park.Publish(ctx, v.parkTopic, event.Message().Body, WithHeaders(headers)); pubErr != nil {
+			return fmt.Errorf("%w of [%s], park failed: %w", ErrInvalidMessage, event.Topic(), pubErr)
+		}
+		log.Warnf("[broker] park invalid message of [%s] to [%s]: %v", event.Topic(), v.parkTopic, err)
+		return AutoAck(event)
+
+	default:
+		return fmt.Errorf("%w of [%s]: %w", ErrInvalidMessage, event.Topic(), err)
+	}
+}
+
+// validateBody call the Validate method of body, if any.
+func validateBody(_ context.Context, body any) error {
+	if v, ok := body.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type parkBroker struct {
+	recordBroker
+	headers Headers
+	err     error
+}
+
+func (b *parkBroker) Publish(ctx context.Context, topic string, msg Any, opts ...PublishOption) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.headers = NewPublishOptions(opts...).Headers
+	return b.recordBroker.Publish(ctx, topic, msg, opts...)
+}
+
+func TestValidationHandler(t *testing.T) {
+	handled := 0
+	handler := func(context.Context, Event) error {
+		handled++
+		return nil
+	}
+	event := func(order *testOrder) Event {
+		return &testEvent{topic: "orders", message: &Message{Headers: Headers{"x-tenant": "acme"}, Body: order}}
+	}
+	ctx := context.Background()
+
+	reject := ValidationHandler(handler)
+	assert.Nil(t, reject(ctx, event(&testOrder{ID: "1"})))
+	assert.Equal(t, 1, handled)
+	assert.ErrorIs(t, reject(ctx, event(&testOrder{})), ErrInvalidMessage)
+	assert.Equal(t, 1, handled)
+
+	ack := ValidationHandler(handler, WithInvalidPolicy(InvalidAck))
+	assert.Nil(t, ack(ctx, event(&testOrder{})))
+	assert.Equal(t, 1, handled)
+
+	custom := ValidationHandler(handler, WithMessageValidator(func(_ context.Context, body any) error {
+		if body.(*testOrder).ID != "2" {
+			return errors.New("unknown order")
+		}
+		return nil
+	}))
+	assert.ErrorIs(t, custom(ctx, event(&testOrder{ID: "1"})), ErrInvalidMessage)
+	assert.Nil(t, custom(ctx, event(&testOrder{ID: "2"})))
+	assert.Equal(t, 2, handled)
+}
+
+func TestValidationHandler_Park(t *testing.T) {
+	pb := &parkBroker{recordBroker: *newRecordBroker("park")}
+	handler := ValidationHandler(func(context.Context, Event) error { return nil }, WithInvalidPark(pb, "orders.invalid"))
+
+	event := &testEvent{topic: "orders", message: &Message{Headers: Headers{"x-tenant": "acme"}, Body: &testOrder{}}}
+	assert.Nil(t, handler(context.Background(), event))
+	assert.Equal(t, []string{"orders.invalid"}, pb.published)
+	assert.Equal(t, "acme", pb.headers["x-tenant"])
+	assert.Equal(t, "missing id", pb.headers[InvalidReasonHeader])
+	assert.Empty(t, event.message.Headers[InvalidReasonHeader])
+
+	pb.err = errors.New("unavailable")
+	assert.ErrorIs(t, handler(context.Background(), event), ErrInvalidMessage)
+}
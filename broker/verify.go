@@ -0,0 +1,113 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Resource kinds checked by a topology verification.
+const (
+	ResourceExchange = "exchange"
+	ResourceQueue    = "queue"
+	ResourceTopic    = "topic"
+	ResourceGroup    = "group"
+)
+
+var (
+	ErrTopologyMissing     = errors.New("broker topology missing")
+	ErrResourceUnsupported = errors.New("resource kind not supported by the broker")
+)
+
+// Topology lists the resources a service needs to exist.
+type Topology struct {
+	Exchanges []string
+	Queues    []string
+	Topics    []string
+	Groups    []string
+}
+
+func (t Topology) resources() map[string][]string {
+	return map[string][]string{
+		ResourceExchange: t.Exchanges,
+		ResourceQueue:    t.Queues,
+		ResourceTopic:    t.Topics,
+		ResourceGroup:    t.Groups,
+	}
+}
+
+// MissingResource is a resource a verification could not find or check.
+type MissingResource struct {
+	Kind string
+	Name string
+	Err  error
+}
+
+// TopologyError lists every resource missing when a broker connects.
+type TopologyError struct {
+	Broker  string
+	Missing []MissingResource
+}
+
+func (e *TopologyError) Error() string {
+	items := make([]string, 0, len(e.Missing))
+	for _, m := range e.Missing {
+		item := fmt.Sprintf("%s[%s]", m.Kind, m.Name)
+		if m.Err != nil {
+			item += ": " + m.Err.Error()
+		}
+		items = append(items, item)
+	}
+	return fmt.Sprintf("%s topology verification failed, %d missing: %s",
+		e.Broker, len(e.Missing), strings.Join(items, "; "))
+}
+
+func (e *TopologyError) Unwrap() error {
+	return ErrTopologyMissing
+}
+
+// TopologyChecker reports an error when the resource name does not exist.
+type TopologyChecker func(ctx context.Context, name string) error
+
+type verifyOnStartKey struct{}
+
+// WithVerifyOnStart check on Connect that the resources of t exist, without
+// declaring them, and fail with a TopologyError listing all missing ones.
+func WithVerifyOnStart(t Topology) Option {
+	return OptionContextWithValue(verifyOnStartKey{}, t)
+}
+
+// VerifyOnStartFromContext return the topology set by WithVerifyOnStart.
+func VerifyOnStartFromContext(ctx context.Context) (Topology, bool) {
+	if ctx == nil {
+		return Topology{}, false
+	}
+	t, ok := ctx.Value(verifyOnStartKey{}).(Topology)
+	return t, ok
+}
+
+// VerifyTopology check every resource of t with the checker of its kind, the
+// resources of kinds without checker are reported as unsupported.
+func VerifyTopology(ctx context.Context, name string, t Topology, checkers map[string]TopologyChecker) error {
+	topologyErr := &TopologyError{Broker: name}
+
+	resources := t.resources()
+	for _, kind := range []string{ResourceExchange, ResourceQueue, ResourceTopic, ResourceGroup} {
+		for _, resource := range resources[kind] {
+			check, ok := checkers[kind]
+			if !ok {
+				topologyErr.Missing = append(topologyErr.Missing, MissingResource{Kind: kind, Name: resource, Err: ErrResourceUnsupported})
+				continue
+			}
+			if err := check(ctx, resource); err != nil {
+				topologyErr.Missing = append(topologyErr.Missing, MissingResource{Kind: kind, Name: resource, Err: err})
+			}
+		}
+	}
+
+	if len(topologyErr.Missing) > 0 {
+		return topologyErr
+	}
+	return nil
+}
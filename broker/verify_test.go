@@ -0,0 +1,54 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyTopology(t *testing.T) {
+	existing := map[string]bool{"orders": true}
+	check := func(_ context.Context, name string) error {
+		if !existing[name] {
+			return errors.New("not found")
+		}
+		return nil
+	}
+
+	topology := Topology{
+		Topics:    []string{"orders", "payments"},
+		Exchanges: []string{"events"},
+	}
+
+	err := VerifyTopology(context.Background(), "kafka", topology, map[string]TopologyChecker{
+		ResourceTopic: check,
+	})
+	assert.ErrorIs(t, err, ErrTopologyMissing)
+
+	var topologyErr *TopologyError
+	assert.True(t, errors.As(err, &topologyErr))
+	assert.Equal(t, []MissingResource{
+		{Kind: ResourceExchange, Name: "events", Err: ErrResourceUnsupported},
+		{Kind: ResourceTopic, Name: "payments", Err: errors.New("not found")},
+	}, topologyErr.Missing)
+	assert.Equal(t,
+		"kafka topology verification failed, 2 missing: exchange[events]: resource kind not supported by the broker; topic[payments]: not found",
+		err.Error())
+
+	assert.Nil(t, VerifyTopology(context.Background(), "kafka", Topology{Topics: []string{"orders"}}, map[string]TopologyChecker{
+		ResourceTopic: check,
+	}))
+}
+
+func TestWithVerifyOnStart(t *testing.T) {
+	opts := NewOptionsAndApply(WithVerifyOnStart(Topology{Queues: []string{"orders"}}))
+
+	topology, ok := VerifyOnStartFromContext(opts.Context)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"orders"}, topology.Queues)
+
+	_, ok = VerifyOnStartFromContext(context.Background())
+	assert.False(t, ok)
+}
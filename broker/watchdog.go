@@ -0,0 +1,271 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWatchdogInterval = 30 * time.Second
+	defaultStallTimeout     = 5 * time.Minute
+)
+
+// ActivityReporter is implemented by subscribers able to tell when they were
+// last known alive: last poll, or now while their delivery channel is open.
+type ActivityReporter interface {
+	LastActivity() time.Time
+}
+
+// StallHandler is called when a stalled subscriber is recreated, err is the
+// resubscribe error if it failed.
+type StallHandler func(topic string, idle time.Duration, err error)
+
+type WatchdogOption func(w *Watchdog)
+
+// WithWatchdogInterval set how often subscribers are checked.
+func WithWatchdogInterval(interval time.Duration) WatchdogOption {
+	return func(w *Watchdog) {
+		w.interval = interval
+	}
+}
+
+// WithStallTimeout set how long a subscriber may stay inactive before it is recreated.
+func WithStallTimeout(timeout time.Duration) WatchdogOption {
+	return func(w *Watchdog) {
+		w.stallTimeout = timeout
+	}
+}
+
+// WithStallHandler set the hook called for every recreated subscriber, e.g. for alerting.
+func WithStallHandler(handler StallHandler) WatchdogOption {
+	return func(w *Watchdog) {
+		w.onStall = handler
+	}
+}
+
+// WithWatchdogClock set the clock of the checks, SystemClock by default.
+func WithWatchdogClock(clock Clock) WatchdogOption {
+	return func(w *Watchdog) {
+		w.clock = clock
+	}
+}
+
+// Watchdog recreates subscribers that stopped receiving messages and, when
+// they implement ActivityReporter, stopped polling. Without ActivityReporter
+// only received messages count, so the stall timeout must exceed the longest
+// expected quiet period of the topic.
+type Watchdog struct {
+	Broker
+
+	sync.Mutex
+
+	interval     time.Duration
+	stallTimeout time.Duration
+	onStall      StallHandler
+	clock        Clock
+
+	subscribers map[*watchedSubscriber]struct{}
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+func NewWatchdog(b Broker, opts ...WatchdogOption) *Watchdog {
+	w := &Watchdog{
+		Broker:       b,
+		interval:     defaultWatchdogInterval,
+		stallTimeout: defaultStallTimeout,
+		clock:        SystemClock,
+		subscribers:  make(map[*watchedSubscriber]struct{}),
+	}
+
+	for _, o := range opts {
+		o(w)
+	}
+
+	return w
+}
+
+func (w *Watchdog) Subscribe(topic string, handler Handler, binder Binder, opts ...SubscribeOption) (Subscriber, error) {
+	ws := &watchedSubscriber{
+		w:       w,
+		topic:   topic,
+		handler: handler,
+		binder:  binder,
+		opts:    opts,
+	}
+
+	if err := ws.subscribe(); err != nil {
+		return nil, err
+	}
+
+	w.Lock()
+	w.subscribers[ws] = struct{}{}
+	if w.cancel == nil {
+		var ctx context.Context
+		ctx, w.cancel = context.WithCancel(context.Background())
+		w.done = make(chan struct{})
+		go w.run(ctx, w.done)
+	}
+	w.Unlock()
+
+	return ws, nil
+}
+
+func (w *Watchdog) Disconnect() error {
+	w.Lock()
+	cancel, done := w.cancel, w.done
+	w.cancel, w.done = nil, nil
+	w.subscribers = make(map[*watchedSubscriber]struct{})
+	w.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	return w.Broker.Disconnect()
+}
+
+// Check recreates the subscribers stalled at now.
+func (w *Watchdog) Check(now time.Time) {
+	w.Lock()
+	subscribers := make([]*watchedSubscriber, 0, len(w.subscribers))
+	for ws := range w.subscribers {
+		subscribers = append(subscribers, ws)
+	}
+	w.Unlock()
+
+	for _, ws := range subscribers {
+		idle := now.Sub(ws.lastActivity())
+		if idle < w.stallTimeout {
+			continue
+		}
+
+		err := ws.resubscribe()
+		if w.onStall != nil {
+			w.onStall(ws.topic, idle, err)
+		}
+	}
+}
+
+func (w *Watchdog) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := w.clock.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C():
+			w.Check(now)
+		}
+	}
+}
+
+func (w *Watchdog) remove(ws *watchedSubscriber) {
+	w.Lock()
+	defer w.Unlock()
+
+	delete(w.subscribers, ws)
+}
+
+type watchedSubscriber struct {
+	sync.Mutex
+
+	w       *Watchdog
+	topic   string
+	handler Handler
+	binder  Binder
+	opts    []SubscribeOption
+
+	sub         Subscriber
+	lastMessage time.Time
+	closed      bool
+}
+
+func (s *watchedSubscriber) Options() SubscribeOptions {
+	return NewSubscribeOptions(s.opts...)
+}
+
+func (s *watchedSubscriber) Topic() string {
+	return s.topic
+}
+
+func (s *watchedSubscriber) Unsubscribe(removeFromManager bool) error {
+	s.w.remove(s)
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.closed = true
+	if s.sub == nil {
+		return nil
+	}
+	return s.sub.Unsubscribe(removeFromManager)
+}
+
+func (s *watchedSubscriber) subscribe() error {
+	sub, err := s.w.Broker.Subscribe(s.topic,
+		func(ctx context.Context, event Event) error {
+			s.Lock()
+			s.lastMessage = s.w.clock.Now()
+			s.Unlock()
+
+			return s.handler(ctx, event)
+		},
+		s.binder,
+		s.opts...,
+	)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	// unsubscribed while recreating.
+	if s.closed {
+		return sub.Unsubscribe(true)
+	}
+	s.sub = sub
+	s.lastMessage = s.w.clock.Now()
+
+	return nil
+}
+
+func (s *watchedSubscriber) resubscribe() error {
+	s.Lock()
+	old := s.sub
+	s.sub = nil
+	s.Unlock()
+
+	var errs []error
+	if old != nil {
+		if err := old.Unsubscribe(true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := s.subscribe(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *watchedSubscriber) lastActivity() time.Time {
+	s.Lock()
+	defer s.Unlock()
+
+	last := s.lastMessage
+	if reporter, ok := s.sub.(ActivityReporter); ok {
+		if t := reporter.LastActivity(); t.After(last) {
+			last = t
+		}
+	}
+
+	return last
+}
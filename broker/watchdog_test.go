@@ -0,0 +1,45 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchdog_Resubscribe(t *testing.T) {
+	rb := newRecordBroker("shared")
+
+	var stalled []string
+	w := NewWatchdog(rb,
+		WithStallTimeout(time.Minute),
+		WithWatchdogInterval(time.Hour),
+		WithStallHandler(func(topic string, _ time.Duration, err error) {
+			assert.Nil(t, err)
+			stalled = append(stalled, topic)
+		}),
+	)
+
+	sub, err := w.Subscribe("orders", func(context.Context, Event) error { return nil }, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(rb.subscribed))
+
+	w.Check(time.Now())
+	assert.Empty(t, stalled)
+
+	w.Check(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, []string{"orders"}, stalled)
+	assert.Equal(t, 2, len(rb.subscribed))
+
+	// a message resets the idle time.
+	assert.Nil(t, rb.handlers["orders"](context.Background(), nil))
+	w.Check(time.Now().Add(30 * time.Second))
+	assert.Equal(t, 1, len(stalled))
+
+	assert.Nil(t, sub.Unsubscribe(true))
+	w.Check(time.Now().Add(time.Hour))
+	assert.Equal(t, 1, len(stalled))
+
+	assert.Nil(t, w.Disconnect())
+}
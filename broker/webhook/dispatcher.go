@@ -0,0 +1,385 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/google/uuid"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// MessageIDHeader is the message header reused as the delivery id.
+const MessageIDHeader = "x-message-id"
+
+const (
+	defaultMaxRetries = 5
+	defaultBackoff    = time.Second
+	defaultMaxBackoff = time.Minute
+	defaultTimeout    = 10 * time.Second
+)
+
+var (
+	ErrAlreadyRunning    = errors.New("webhook: dispatcher already running")
+	ErrEndpointExists    = errors.New("webhook: endpoint already registered")
+	ErrInvalidEndpoint   = errors.New("webhook: endpoint needs a name, an url and topics")
+	ErrUnsupportedBody   = errors.New("webhook: message body can not be encoded")
+	ErrDeliveryRejected  = errors.New("webhook: delivery rejected by endpoint")
+	ErrDeliveryExhausted = errors.New("webhook: delivery retries exhausted")
+)
+
+// Endpoint is a webhook receiver and the topics delivered to it.
+type Endpoint struct {
+	Name   string
+	URL    string
+	Topics []string
+
+	// Secret signs deliveries into SignatureHeader when set.
+	Secret []byte
+	// Headers are added to every request.
+	Headers map[string]string
+
+	// RateLimit is the maximum number of requests per second, 0 is unlimited.
+	RateLimit float64
+	// Burst is the number of requests allowed above RateLimit at once.
+	Burst int
+}
+
+// Failure describes a delivery given up after its retries.
+type Failure struct {
+	Endpoint string
+	Topic    string
+	ID       string
+	Attempts int
+	Body     []byte
+	Err      error
+}
+
+type endpoint struct {
+	Endpoint
+	limiter *limiter
+}
+
+// Dispatcher subscribes to the topics of its endpoints and delivers every
+// message to them as a signed HTTP POST. Failed requests are retried with
+// exponential backoff; a 4xx other than 408 and 429 is not retried.
+type Dispatcher struct {
+	sync.RWMutex
+
+	b broker.Broker
+
+	client        *http.Client
+	maxRetries    int
+	backoff       time.Duration
+	maxBackoff    time.Duration
+	failureTopic  string
+	onFailure     func(ctx context.Context, f *Failure)
+	subscribeOpts []broker.SubscribeOption
+	clock         broker.Clock
+
+	endpoints map[string]*endpoint
+	subs      map[string]broker.Subscriber
+	running   bool
+}
+
+func NewDispatcher(b broker.Broker, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		b:          b,
+		client:     &http.Client{Timeout: defaultTimeout},
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+		maxBackoff: defaultMaxBackoff,
+		clock:      broker.SystemClock,
+		endpoints:  make(map[string]*endpoint),
+		subs:       make(map[string]broker.Subscriber),
+	}
+
+	for _, o := range opts {
+		o(d)
+	}
+
+	return d
+}
+
+// Register add an endpoint, its topics are subscribed at once when running.
+func (d *Dispatcher) Register(ep Endpoint) error {
+	if ep.Name == "" || ep.URL == "" || len(ep.Topics) == 0 {
+		return ErrInvalidEndpoint
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	if _, ok := d.endpoints[ep.Name]; ok {
+		return ErrEndpointExists
+	}
+	d.endpoints[ep.Name] = &endpoint{
+		Endpoint: ep,
+		limiter:  newLimiter(d.clock, ep.RateLimit, ep.Burst),
+	}
+
+	if d.running {
+		return d.subscribe(ep.Topics...)
+	}
+	return nil
+}
+
+// Unregister remove an endpoint, topics without endpoints are unsubscribed.
+func (d *Dispatcher) Unregister(name string) error {
+	d.Lock()
+	defer d.Unlock()
+
+	ep, ok := d.endpoints[name]
+	if !ok {
+		return nil
+	}
+	delete(d.endpoints, name)
+
+	var err error
+	for _, topic := range ep.Topics {
+		if len(d.endpointsOf(topic)) > 0 {
+			continue
+		}
+		if sub, ok := d.subs[topic]; ok {
+			delete(d.subs, topic)
+			if e := sub.Unsubscribe(true); e != nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// Start subscribe to the topics of all registered endpoints.
+func (d *Dispatcher) Start() error {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.running {
+		return ErrAlreadyRunning
+	}
+
+	for _, ep := range d.endpoints {
+		if err := d.subscribe(ep.Topics...); err != nil {
+			d.unsubscribeAll()
+			return err
+		}
+	}
+	d.running = true
+
+	return nil
+}
+
+// Stop unsubscribe from all topics.
+func (d *Dispatcher) Stop() error {
+	d.Lock()
+	defer d.Unlock()
+
+	if !d.running {
+		return nil
+	}
+	d.running = false
+
+	return d.unsubscribeAll()
+}
+
+func (d *Dispatcher) subscribe(topics ...string) error {
+	for _, topic := range topics {
+		if _, ok := d.subs[topic]; ok {
+			continue
+		}
+		sub, err := d.b.Subscribe(topic, d.handle, nil, d.subscribeOpts...)
+		if err != nil {
+			return err
+		}
+		d.subs[topic] = sub
+	}
+	return nil
+}
+
+func (d *Dispatcher) unsubscribeAll() error {
+	var err error
+	for topic, sub := range d.subs {
+		if e := sub.Unsubscribe(true); e != nil {
+			err = e
+		}
+		delete(d.subs, topic)
+	}
+	return err
+}
+
+func (d *Dispatcher) endpointsOf(topic string) []*endpoint {
+	var eps []*endpoint
+	for _, ep := range d.endpoints {
+		for _, t := range ep.Topics {
+			if t == topic {
+				eps = append(eps, ep)
+				break
+			}
+		}
+	}
+	return eps
+}
+
+// handle deliver a message to every endpoint of its topic in parallel. Failed
+// deliveries are reported instead of returned, so the message is not
+// redelivered to the endpoints that already received it.
+func (d *Dispatcher) handle(ctx context.Context, event broker.Event) error {
+	body, err := payload(event.Message())
+	if err != nil {
+		return err
+	}
+
+	var id string
+	if m := event.Message(); m != nil {
+		id = m.GetHeader(MessageIDHeader)
+	}
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	d.RLock()
+	eps := d.endpointsOf(event.Topic())
+	d.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, ep := range eps {
+		wg.Add(1)
+		go func(ep *endpoint) {
+			defer wg.Done()
+
+			attempts, err := d.deliver(ctx, ep, event.Topic(), id, body)
+			if err != nil {
+				d.fail(ctx, &Failure{
+					Endpoint: ep.Name,
+					Topic:    event.Topic(),
+					ID:       id,
+					Attempts: attempts,
+					Body:     body,
+					Err:      err,
+				})
+			}
+		}(ep)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, ep *endpoint, topic, id string, body []byte) (int, error) {
+	backoff := d.backoff
+
+	for attempt := 1; ; attempt++ {
+		if err := ep.limiter.Wait(ctx); err != nil {
+			return attempt - 1, err
+		}
+
+		retry, err := d.post(ctx, ep, topic, id, body)
+		if err == nil {
+			return attempt, nil
+		}
+		if !retry {
+			return attempt, err
+		}
+		if attempt > d.maxRetries {
+			return attempt, fmt.Errorf("%w: %v", ErrDeliveryExhausted, err)
+		}
+
+		log.Warnf("[webhook] deliver [%s] to [%s] failed, attempt %d: %v", topic, ep.Name, attempt, err)
+
+		timer := d.clock.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, ctx.Err()
+		case <-timer.C():
+		}
+
+		backoff *= 2
+		if backoff > d.maxBackoff {
+			backoff = d.maxBackoff
+		}
+	}
+}
+
+// post send one request and report whether a failure may be retried.
+func (d *Dispatcher) post(ctx context.Context, ep *endpoint, topic, id string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(d.clock.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range ep.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set(IDHeader, id)
+	req.Header.Set(TopicHeader, topic)
+	req.Header.Set(TimestampHeader, timestamp)
+	if len(ep.Secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(ep.Secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook: endpoint responded %s", resp.Status)
+	default:
+		return false, fmt.Errorf("%w: %s", ErrDeliveryRejected, resp.Status)
+	}
+}
+
+func (d *Dispatcher) fail(ctx context.Context, f *Failure) {
+	log.Errorf("[webhook] deliver [%s] to [%s] failed after %d attempts: %v", f.Topic, f.Endpoint, f.Attempts, f.Err)
+
+	if d.onFailure != nil {
+		d.onFailure(ctx, f)
+	}
+
+	if d.failureTopic != "" {
+		if err := d.b.Publish(ctx, d.failureTopic, f.Body); err != nil {
+			log.Errorf("[webhook] publish failure of [%s] to [%s] failed: %v", f.Topic, d.failureTopic, err)
+		}
+	}
+}
+
+func payload(msg *broker.Message) ([]byte, error) {
+	if msg == nil {
+		return nil, nil
+	}
+	switch body := msg.Body.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return body, nil
+	case json.RawMessage:
+		return body, nil
+	case string:
+		return []byte(body), nil
+	default:
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedBody, err)
+		}
+		return buf, nil
+	}
+}
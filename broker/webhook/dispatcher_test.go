@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type testSubscriber struct {
+	broker.Subscriber
+	b     *testBroker
+	topic string
+}
+
+func (s *testSubscriber) Unsubscribe(bool) error {
+	s.b.Lock()
+	defer s.b.Unlock()
+	delete(s.b.handlers, s.topic)
+	return nil
+}
+
+type testBroker struct {
+	broker.Broker
+	sync.Mutex
+
+	handlers  map[string]broker.Handler
+	published map[string][]broker.Any
+}
+
+func newTestBroker() *testBroker {
+	return &testBroker{
+		handlers:  map[string]broker.Handler{},
+		published: map[string][]broker.Any{},
+	}
+}
+
+func (b *testBroker) Publish(_ context.Context, topic string, msg broker.Any, _ ...broker.PublishOption) error {
+	b.Lock()
+	defer b.Unlock()
+	b.published[topic] = append(b.published[topic], msg)
+	return nil
+}
+
+func (b *testBroker) Subscribe(topic string, handler broker.Handler, _ broker.Binder, _ ...broker.SubscribeOption) (broker.Subscriber, error) {
+	b.Lock()
+	defer b.Unlock()
+	b.handlers[topic] = handler
+	return &testSubscriber{b: b, topic: topic}, nil
+}
+
+func (b *testBroker) deliver(t *testing.T, topic string, msg *broker.Message) {
+	b.Lock()
+	h, ok := b.handlers[topic]
+	b.Unlock()
+	assert.True(t, ok)
+	assert.Nil(t, h(context.Background(), &testEvent{topic: topic, msg: msg}))
+}
+
+type testEvent struct {
+	broker.Event
+	topic string
+	msg   *broker.Message
+}
+
+func (e *testEvent) Topic() string            { return e.topic }
+func (e *testEvent) Message() *broker.Message { return e.msg }
+
+func TestDispatcher_SignedDelivery(t *testing.T) {
+	secret := []byte("secret")
+
+	var verified atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verified.Store(Verify(secret, r.Header.Get(TimestampHeader), body, r.Header.Get(SignatureHeader)) &&
+			r.Header.Get(IDHeader) == "id-1" &&
+			r.Header.Get(TopicHeader) == "orders")
+	}))
+	defer srv.Close()
+
+	b := newTestBroker()
+	d := NewDispatcher(b)
+	assert.Nil(t, d.Register(Endpoint{Name: "shop", URL: srv.URL, Topics: []string{"orders"}, Secret: secret}))
+	assert.Nil(t, d.Start())
+
+	b.deliver(t, "orders", &broker.Message{
+		Headers: broker.Headers{MessageIDHeader: "id-1"},
+		Body:    []byte(`{"id":1}`),
+	})
+	assert.True(t, verified.Load())
+
+	assert.Nil(t, d.Unregister("shop"))
+	assert.Equal(t, 0, len(b.handlers))
+	assert.Nil(t, d.Stop())
+}
+
+func TestDispatcher_Retry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	var failures []*Failure
+	b := newTestBroker()
+	d := NewDispatcher(b,
+		WithBackoff(time.Millisecond, 2*time.Millisecond),
+		WithFailureHandler(func(_ context.Context, f *Failure) { failures = append(failures, f) }),
+	)
+	assert.Nil(t, d.Register(Endpoint{Name: "shop", URL: srv.URL, Topics: []string{"orders"}}))
+	assert.Nil(t, d.Start())
+
+	b.deliver(t, "orders", &broker.Message{Body: "hello"})
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Equal(t, 0, len(failures))
+}
+
+func TestDispatcher_Failure(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	var failures []*Failure
+	b := newTestBroker()
+	d := NewDispatcher(b,
+		WithFailureTopic("orders.failed"),
+		WithFailureHandler(func(_ context.Context, f *Failure) { failures = append(failures, f) }),
+	)
+	assert.Nil(t, d.Register(Endpoint{Name: "shop", URL: srv.URL, Topics: []string{"orders"}}))
+	assert.Nil(t, d.Start())
+
+	b.deliver(t, "orders", &broker.Message{Body: map[string]int{"id": 1}})
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, 1, len(failures))
+	assert.ErrorIs(t, failures[0].Err, ErrDeliveryRejected)
+	assert.Equal(t, []broker.Any{[]byte(`{"id":1}`)}, b.published["orders.failed"])
+}
+
+func TestDispatcher_InvalidEndpoint(t *testing.T) {
+	d := NewDispatcher(newTestBroker())
+	assert.ErrorIs(t, d.Register(Endpoint{Name: "shop"}), ErrInvalidEndpoint)
+	assert.Nil(t, d.Register(Endpoint{Name: "shop", URL: "http://localhost", Topics: []string{"orders"}}))
+	assert.ErrorIs(t, d.Register(Endpoint{Name: "shop", URL: "http://localhost", Topics: []string{"orders"}}), ErrEndpointExists)
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(broker.SystemClock, 100, 1)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, l.Wait(context.Background()))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+
+	l = newLimiter(broker.SystemClock, 0.001, 1)
+	assert.Nil(t, l.Wait(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NotNil(t, l.Wait(ctx))
+
+	assert.Nil(t, (*limiter)(nil).Wait(context.Background()))
+}
@@ -0,0 +1,276 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+const (
+	defaultMaxBodySize = 1 << 20
+	defaultTolerance   = 5 * time.Minute
+)
+
+var (
+	ErrMissingSignature = errors.New("webhook: missing signature")
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	ErrExpiredTimestamp = errors.New("webhook: timestamp outside tolerance")
+	ErrInvalidAPIKey    = errors.New("webhook: invalid api key")
+)
+
+// Verifier authenticates an incoming webhook request.
+type Verifier interface {
+	Verify(r *http.Request, body []byte) error
+}
+
+type VerifierFunc func(r *http.Request, body []byte) error
+
+func (f VerifierFunc) Verify(r *http.Request, body []byte) error {
+	return f(r, body)
+}
+
+// SignatureVerifier verifies requests signed by a Dispatcher endpoint with
+// secret, rejecting timestamps older or newer than tolerance.
+func SignatureVerifier(secret []byte, tolerance time.Duration) Verifier {
+	return VerifierFunc(func(r *http.Request, body []byte) error {
+		signature := r.Header.Get(SignatureHeader)
+		if signature == "" {
+			return ErrMissingSignature
+		}
+		timestamp := r.Header.Get(TimestampHeader)
+		if err := checkTimestamp(timestamp, tolerance); err != nil {
+			return err
+		}
+		if !Verify(secret, timestamp, body, signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	})
+}
+
+// HMACVerifier verifies a hex HMAC-SHA256 of the body in header, with or
+// without the "sha256=" prefix, e.g. GitHub's X-Hub-Signature-256.
+func HMACVerifier(header string, secret []byte) Verifier {
+	return VerifierFunc(func(r *http.Request, body []byte) error {
+		signature := strings.TrimPrefix(r.Header.Get(header), signaturePrefix)
+		if signature == "" {
+			return ErrMissingSignature
+		}
+		expected, err := hex.DecodeString(signature)
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		if !hmac.Equal(mac.Sum(nil), expected) {
+			return ErrInvalidSignature
+		}
+		return nil
+	})
+}
+
+// StripeVerifier verifies the Stripe-Signature header of Stripe webhooks.
+func StripeVerifier(secret []byte, tolerance time.Duration) Verifier {
+	return VerifierFunc(func(r *http.Request, body []byte) error {
+		header := r.Header.Get("Stripe-Signature")
+		if header == "" {
+			return ErrMissingSignature
+		}
+
+		var timestamp string
+		var signatures []string
+		for _, part := range strings.Split(header, ",") {
+			k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if !ok {
+				continue
+			}
+			switch k {
+			case "t":
+				timestamp = v
+			case "v1":
+				signatures = append(signatures, v)
+			}
+		}
+		if len(signatures) == 0 {
+			return ErrMissingSignature
+		}
+		if err := checkTimestamp(timestamp, tolerance); err != nil {
+			return err
+		}
+
+		expected := strings.TrimPrefix(Sign(secret, timestamp, body), signaturePrefix)
+		for _, s := range signatures {
+			if hmac.Equal([]byte(expected), []byte(s)) {
+				return nil
+			}
+		}
+		return ErrInvalidSignature
+	})
+}
+
+// APIKeyVerifier accepts requests whose header carries one of keys.
+func APIKeyVerifier(header string, keys ...string) Verifier {
+	return VerifierFunc(func(r *http.Request, _ []byte) error {
+		got := r.Header.Get(header)
+		if got == "" {
+			return ErrInvalidAPIKey
+		}
+		for _, k := range keys {
+			if subtle.ConstantTimeCompare([]byte(got), []byte(k)) == 1 {
+				return nil
+			}
+		}
+		return ErrInvalidAPIKey
+	})
+}
+
+func checkTimestamp(timestamp string, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		tolerance = defaultTolerance
+	}
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrExpiredTimestamp
+	}
+	diff := time.Since(time.Unix(sec, 0))
+	if diff > tolerance || diff < -tolerance {
+		return ErrExpiredTimestamp
+	}
+	return nil
+}
+
+// MapFunc transforms a verified request into the published message, a nil
+// message acknowledges the request without publishing.
+type MapFunc func(r *http.Request, body []byte) (broker.Any, error)
+
+type IngressOption func(i *Ingress)
+
+// WithVerifier add a verifier, a request has to pass all of them.
+func WithVerifier(v Verifier) IngressOption {
+	return func(i *Ingress) {
+		i.verifiers = append(i.verifiers, v)
+	}
+}
+
+// WithMapFunc set the transformation of requests, default publishes the raw body.
+func WithMapFunc(fn MapFunc) IngressOption {
+	return func(i *Ingress) {
+		i.mapFunc = fn
+	}
+}
+
+// WithTopicFunc choose the topic per request, e.g. from X-GitHub-Event. An
+// empty result falls back to the configured topic.
+func WithTopicFunc(fn func(r *http.Request) string) IngressOption {
+	return func(i *Ingress) {
+		i.topicFunc = fn
+	}
+}
+
+// WithMaxBodySize set the largest accepted body, default is 1MB.
+func WithMaxBodySize(n int64) IngressOption {
+	return func(i *Ingress) {
+		i.maxBodySize = n
+	}
+}
+
+// WithPublishOptions pass options to every publish.
+func WithPublishOptions(opts ...broker.PublishOption) IngressOption {
+	return func(i *Ingress) {
+		i.publishOpts = append(i.publishOpts, opts...)
+	}
+}
+
+// Ingress is an http.Handler that verifies incoming webhooks and publishes
+// them to a topic, the inverse of Dispatcher:
+//
+//	mux.Handle("/webhooks/github", webhook.NewIngress(b, "github.events",
+//		webhook.WithVerifier(webhook.HMACVerifier("X-Hub-Signature-256", secret)),
+//	))
+type Ingress struct {
+	b     broker.Broker
+	topic string
+
+	verifiers   []Verifier
+	mapFunc     MapFunc
+	topicFunc   func(r *http.Request) string
+	maxBodySize int64
+	publishOpts []broker.PublishOption
+}
+
+func NewIngress(b broker.Broker, topic string, opts ...IngressOption) *Ingress {
+	i := &Ingress{
+		b:           b,
+		topic:       topic,
+		maxBodySize: defaultMaxBodySize,
+	}
+
+	for _, o := range opts {
+		o(i)
+	}
+
+	return i
+}
+
+func (i *Ingress) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, i.maxBodySize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	for _, v := range i.verifiers {
+		if err = v.Verify(r, body); err != nil {
+			log.Warnf("[webhook] reject request from %s: %v", r.RemoteAddr, err)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var msg broker.Any = body
+	if i.mapFunc != nil {
+		if msg, err = i.mapFunc(r, body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if msg != nil {
+		topic := i.topic
+		if i.topicFunc != nil {
+			if t := i.topicFunc(r); t != "" {
+				topic = t
+			}
+		}
+
+		if err = i.b.Publish(r.Context(), topic, msg, i.publishOpts...); err != nil {
+			log.Errorf("[webhook] publish to [%s] failed: %v", topic, err)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
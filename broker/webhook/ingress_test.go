@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func newWebhookRequest(body string, headers map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	return r
+}
+
+func serve(h http.Handler, r *http.Request) int {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestIngress_Signature(t *testing.T) {
+	secret := []byte("secret")
+	b := newTestBroker()
+	h := NewIngress(b, "events", WithVerifier(SignatureVerifier(secret, time.Minute)))
+
+	body := `{"id":1}`
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	assert.Equal(t, http.StatusAccepted, serve(h, newWebhookRequest(body, map[string]string{
+		TimestampHeader: ts,
+		SignatureHeader: Sign(secret, ts, []byte(body)),
+	})))
+	assert.Equal(t, []broker.Any{[]byte(body)}, b.published["events"])
+
+	assert.Equal(t, http.StatusUnauthorized, serve(h, newWebhookRequest(body, map[string]string{
+		TimestampHeader: ts,
+		SignatureHeader: Sign([]byte("other"), ts, []byte(body)),
+	})))
+
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	assert.Equal(t, http.StatusUnauthorized, serve(h, newWebhookRequest(body, map[string]string{
+		TimestampHeader: old,
+		SignatureHeader: Sign(secret, old, []byte(body)),
+	})))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(h, httptest.NewRequest(http.MethodGet, "/webhook", nil)))
+	assert.Equal(t, 1, len(b.published["events"]))
+}
+
+func TestIngress_GitHub(t *testing.T) {
+	secret := []byte("secret")
+	body := `{"action":"opened"}`
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	b := newTestBroker()
+	h := NewIngress(b, "github",
+		WithVerifier(HMACVerifier("X-Hub-Signature-256", secret)),
+		WithVerifier(APIKeyVerifier("X-Api-Key", "key-1", "key-2")),
+		WithTopicFunc(func(r *http.Request) string {
+			if e := r.Header.Get("X-GitHub-Event"); e != "" {
+				return "github." + e
+			}
+			return ""
+		}),
+	)
+
+	assert.Equal(t, http.StatusAccepted, serve(h, newWebhookRequest(body, map[string]string{
+		"X-Hub-Signature-256": signature,
+		"X-Api-Key":           "key-2",
+		"X-GitHub-Event":      "pull_request",
+	})))
+	assert.Equal(t, 1, len(b.published["github.pull_request"]))
+
+	assert.Equal(t, http.StatusUnauthorized, serve(h, newWebhookRequest(body, map[string]string{
+		"X-Hub-Signature-256": signature,
+		"X-Api-Key":           "key-3",
+	})))
+}
+
+func TestIngress_Stripe(t *testing.T) {
+	secret := []byte("whsec")
+	body := `{"type":"charge.succeeded"}`
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	v1 := strings.TrimPrefix(Sign(secret, ts, []byte(body)), "sha256=")
+
+	b := newTestBroker()
+	h := NewIngress(b, "stripe", WithVerifier(StripeVerifier(secret, 0)))
+
+	assert.Equal(t, http.StatusAccepted, serve(h, newWebhookRequest(body, map[string]string{
+		"Stripe-Signature": "t=" + ts + ",v1=deadbeef,v1=" + v1,
+	})))
+	assert.Equal(t, http.StatusUnauthorized, serve(h, newWebhookRequest(body, map[string]string{
+		"Stripe-Signature": "t=" + ts + ",v1=deadbeef",
+	})))
+}
+
+func TestIngress_MapFunc(t *testing.T) {
+	b := newTestBroker()
+	h := NewIngress(b, "events",
+		WithMaxBodySize(16),
+		WithMapFunc(func(_ *http.Request, body []byte) (broker.Any, error) {
+			switch string(body) {
+			case "skip":
+				return nil, nil
+			case "bad":
+				return nil, errors.New("bad payload")
+			}
+			return strings.ToUpper(string(body)), nil
+		}),
+	)
+
+	assert.Equal(t, http.StatusAccepted, serve(h, newWebhookRequest("hello", nil)))
+	assert.Equal(t, http.StatusAccepted, serve(h, newWebhookRequest("skip", nil)))
+	assert.Equal(t, http.StatusBadRequest, serve(h, newWebhookRequest("bad", nil)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(h, newWebhookRequest(strings.Repeat("x", 32), nil)))
+	assert.Equal(t, []broker.Any{"HELLO"}, b.published["events"])
+}
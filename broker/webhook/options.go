@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type Option func(d *Dispatcher)
+
+// WithHTTPClient set the client sending deliveries, default times out after 10s.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithMaxRetries set how many times a failed delivery is retried, default is 5.
+func WithMaxRetries(n int) Option {
+	return func(d *Dispatcher) {
+		if n < 0 {
+			n = 0
+		}
+		d.maxRetries = n
+	}
+}
+
+// WithBackoff set the first retry delay and its upper bound, the delay doubles after every attempt.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.backoff = initial
+		d.maxBackoff = max
+	}
+}
+
+// WithFailureTopic publish the body of deliveries given up to topic.
+func WithFailureTopic(topic string) Option {
+	return func(d *Dispatcher) {
+		d.failureTopic = topic
+	}
+}
+
+// WithFailureHandler observe deliveries given up.
+func WithFailureHandler(h func(ctx context.Context, f *Failure)) Option {
+	return func(d *Dispatcher) {
+		d.onFailure = h
+	}
+}
+
+// WithSubscribeOptions pass options to the topic subscriptions.
+func WithSubscribeOptions(opts ...broker.SubscribeOption) Option {
+	return func(d *Dispatcher) {
+		d.subscribeOpts = append(d.subscribeOpts, opts...)
+	}
+}
+
+// WithClock set the clock of the retry backoff and the rate limits, broker.SystemClock by default.
+func WithClock(clock broker.Clock) Option {
+	return func(d *Dispatcher) {
+		d.clock = clock
+	}
+}
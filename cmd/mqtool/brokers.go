@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/kafka"
+	"github.com/tx7do/kratos-transport/broker/rabbitmq"
+	rocketmqOption "github.com/tx7do/kratos-transport/broker/rocketmq/option"
+	rocketmq "github.com/tx7do/kratos-transport/broker/rocketmq/rocketmq-client-go"
+)
+
+type config struct {
+	broker   string
+	addrs    []string
+	exchange string
+	group    string
+}
+
+// newBroker create and connect the broker of c.
+func newBroker(c config) (broker.Broker, error) {
+	var b broker.Broker
+	switch c.broker {
+	case "rabbitmq":
+		opts := []broker.Option{broker.WithAddress(c.addrs...)}
+		if c.exchange != "" {
+			opts = append(opts, rabbitmq.WithExchangeName(c.exchange))
+		}
+		b = rabbitmq.NewBroker(opts...)
+	case "kafka":
+		b = kafka.NewBroker(broker.WithAddress(c.addrs...))
+	case "rocketmq":
+		b = rocketmq.NewBroker(rocketmqOption.WithNameServer(c.addrs))
+	default:
+		return nil, fmt.Errorf("unknown broker %q, want rabbitmq, kafka or rocketmq", c.broker)
+	}
+
+	if err := b.Init(); err != nil {
+		return nil, err
+	}
+	if err := b.Connect(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// tailOptions return the subscribe options reading a topic without
+// disturbing the consumers of the services.
+func tailOptions(c config) []broker.SubscribeOption {
+	group := c.group
+	if group == "" {
+		group = "mqtool-" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+	}
+
+	opts := []broker.SubscribeOption{broker.WithQueueName(group)}
+	if c.broker == "rabbitmq" {
+		opts = append(opts, rabbitmq.WithAutoDeleteQueue())
+	}
+	return opts
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/broker/kafka"
+	"github.com/tx7do/kratos-transport/broker/rabbitmq"
+	rocketmqOption "github.com/tx7do/kratos-transport/broker/rocketmq/option"
+)
+
+// headerFlags collect the repeated -H key=value flags.
+type headerFlags []string
+
+func (h *headerFlags) String() string { return strings.Join(*h, ",") }
+
+func (h *headerFlags) Set(v string) error {
+	if !strings.Contains(v, "=") {
+		return fmt.Errorf("header %q is not key=value", v)
+	}
+	*h = append(*h, v)
+	return nil
+}
+
+// loadHeaders merge the JSON object of file, if any, with the key=value pairs.
+func loadHeaders(file string, pairs []string) (broker.Headers, error) {
+	headers := broker.Headers{}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(data, &headers); err != nil {
+			return nil, fmt.Errorf("headers file %s: %w", file, err)
+		}
+	}
+	for _, pair := range pairs {
+		k, v, _ := strings.Cut(pair, "=")
+		headers[k] = v
+	}
+	return headers, nil
+}
+
+// headerOption pass the headers with the publish option of the broker.
+func headerOption(name string, headers broker.Headers) broker.PublishOption {
+	switch name {
+	case "rabbitmq":
+		h := make(map[string]interface{}, len(headers))
+		for k, v := range headers {
+			h[k] = v
+		}
+		return rabbitmq.WithPublishHeaders(h)
+	case "kafka":
+		h := make(map[string]interface{}, len(headers))
+		for k, v := range headers {
+			h[k] = v
+		}
+		return kafka.WithHeaders(h)
+	default:
+		return rocketmqOption.WithProperties(headers)
+	}
+}
+
+func runPublish(ctx context.Context, c config, args []string) error {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	topic := fs.String("topic", "", "topic or routing key to publish to")
+	body := fs.String("body", "", "message body")
+	bodyFile := fs.String("body-file", "", "read the message body from a file, - for stdin")
+	headersFile := fs.String("headers-file", "", "JSON object of the message headers")
+	var pairs headerFlags
+	fs.Var(&pairs, "H", "message header key=value, repeatable")
+	_ = fs.Parse(args)
+
+	if *topic == "" {
+		return errors.New("publish: -topic is required")
+	}
+
+	payload := []byte(*body)
+	if *bodyFile != "" {
+		var err error
+		if *bodyFile == "-" {
+			payload, err = io.ReadAll(os.Stdin)
+		} else {
+			payload, err = os.ReadFile(*bodyFile)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	headers, err := loadHeaders(*headersFile, pairs)
+	if err != nil {
+		return err
+	}
+
+	b, err := newBroker(c)
+	if err != nil {
+		return err
+	}
+	defer b.Disconnect()
+
+	var opts []broker.PublishOption
+	if len(headers) > 0 {
+		opts = append(opts, headerOption(c.broker, headers))
+	}
+	if err = b.Publish(ctx, *topic, payload, opts...); err != nil {
+		return err
+	}
+
+	fmt.Printf("published %d bytes to %s\n", len(payload), *topic)
+	return nil
+}
+
+func runTail(ctx context.Context, c config, args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	topic := fs.String("topic", "", "topic or routing key to tail")
+	count := fs.Int("n", 0, "stop after n messages, 0 tails until interrupted")
+	asJSON := fs.Bool("json", false, "print one JSON object per message")
+	_ = fs.Parse(args)
+
+	if *topic == "" {
+		return errors.New("tail: -topic is required")
+	}
+
+	b, err := newBroker(c)
+	if err != nil {
+		return err
+	}
+	defer b.Disconnect()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	received := make(chan broker.RawEvent)
+	_, err = broker.SubscribeRaw(b, *topic, func(ctx context.Context, evt broker.RawEvent) error {
+		select {
+		case received <- evt:
+		case <-ctx.Done():
+		}
+		return nil
+	}, tailOptions(c)...)
+	if err != nil {
+		return err
+	}
+
+	for n := 0; *count == 0 || n < *count; n++ {
+		select {
+		case evt := <-received:
+			printEvent(os.Stdout, evt, *asJSON)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+type printedEvent struct {
+	Time    time.Time      `json:"time"`
+	Topic   string         `json:"topic"`
+	Headers broker.Headers `json:"headers,omitempty"`
+	Body    string         `json:"body"`
+	Binary  bool           `json:"binary,omitempty"`
+}
+
+// printEvent write evt to w, the bodies which are not UTF-8 are quoted.
+func printEvent(w io.Writer, evt broker.RawEvent, asJSON bool) {
+	body := evt.Body()
+	e := printedEvent{Time: time.Now(), Topic: evt.Topic(), Headers: evt.Headers()}
+	if utf8.Valid(body) {
+		e.Body = string(body)
+	} else {
+		e.Body, e.Binary = fmt.Sprintf("%q", body), true
+	}
+
+	if asJSON {
+		_ = json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "%s %s %d bytes\n", e.Time.Format(time.RFC3339), e.Topic, len(body))
+	for k, v := range e.Headers {
+		_, _ = fmt.Fprintf(w, "  %s: %s\n", k, v)
+	}
+	_, _ = fmt.Fprintf(w, "%s\n\n", e.Body)
+}
+
+func runDepth(ctx context.Context, c config, args []string) error {
+	fs := flag.NewFlagSet("depth", flag.ExitOnError)
+	queue := fs.String("queue", "", "queue, or topic for kafka")
+	_ = fs.Parse(args)
+
+	if *queue == "" {
+		return errors.New("depth: -queue is required")
+	}
+
+	b, err := newBroker(c)
+	if err != nil {
+		return err
+	}
+	defer b.Disconnect()
+
+	depth, err := broker.QueueDepth(ctx, b, *queue)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: %d messages\n", *queue, depth)
+	return nil
+}
+
+func runPurge(ctx context.Context, c config, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	queue := fs.String("queue", "", "queue to purge")
+	yes := fs.Bool("yes", false, "confirm the purge, the messages cannot be recovered")
+	_ = fs.Parse(args)
+
+	if *queue == "" {
+		return errors.New("purge: -queue is required")
+	}
+	if !*yes {
+		return fmt.Errorf("purge: add -yes to drop the messages of %s", *queue)
+	}
+
+	b, err := newBroker(c)
+	if err != nil {
+		return err
+	}
+	defer b.Disconnect()
+
+	n, err := broker.PurgeQueue(ctx, b, *queue)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: %d messages purged\n", *queue, n)
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestLoadHeaders(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "headers.json")
+	assert.Nil(t, os.WriteFile(file, []byte(`{"tenant":"acme","app-id":"billing"}`), 0o644))
+
+	headers, err := loadHeaders(file, []string{"tenant=globex", "trace=a=b"})
+	assert.Nil(t, err)
+	assert.Equal(t, broker.Headers{"tenant": "globex", "app-id": "billing", "trace": "a=b"}, headers)
+
+	var pairs headerFlags
+	assert.NotNil(t, pairs.Set("tenant"))
+}
+
+type rawEvent struct {
+	body []byte
+}
+
+func (e *rawEvent) Topic() string           { return "orders" }
+func (e *rawEvent) Headers() broker.Headers { return broker.Headers{"tenant": "acme"} }
+func (e *rawEvent) Body() []byte            { return e.body }
+func (e *rawEvent) RawMessage() interface{} { return nil }
+func (e *rawEvent) Ack() error              { return nil }
+
+func TestPrintEvent(t *testing.T) {
+	var buf bytes.Buffer
+	printEvent(&buf, &rawEvent{body: []byte{0xff, 0x01}}, true)
+
+	var e printedEvent
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal(t, "orders", e.Topic)
+	assert.Equal(t, `"\xff\x01"`, e.Body)
+	assert.True(t, e.Binary)
+
+	buf.Reset()
+	printEvent(&buf, &rawEvent{body: []byte(`{"id":"1"}`)}, false)
+	assert.Contains(t, buf.String(), "orders 10 bytes\n  tenant: acme\n{\"id\":\"1\"}\n")
+}
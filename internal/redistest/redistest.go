@@ -0,0 +1,32 @@
+// Package redistest provides the redis pool of the tests run against a
+// local redis server.
+package redistest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const Addr = "127.0.0.1:6379"
+
+// NewPool return a pool of the local redis server, the test is skipped when
+// it is not available.
+func NewPool(t testing.TB) *redis.Pool {
+	t.Helper()
+
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", Addr, redis.DialConnectTimeout(time.Second))
+		},
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("PING"); err != nil {
+		_ = pool.Close()
+		t.Skipf("redis not available at %s: %v", Addr, err)
+	}
+	return pool
+}
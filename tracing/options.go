@@ -2,6 +2,7 @@ package tracing
 
 import (
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -12,6 +13,7 @@ type options struct {
 	kind           trace.SpanKind
 	tracerName     string
 	spanName       string
+	redactedKeys   map[attribute.Key]struct{}
 }
 
 type Option func(*options)
@@ -45,3 +47,15 @@ func WithGlobalPropagator() Option {
 		opts.propagator = otel.GetTextMapPropagator()
 	}
 }
+
+// WithRedactedAttributes mask the value of the span attributes with these keys.
+func WithRedactedAttributes(keys ...string) Option {
+	return func(opts *options) {
+		if opts.redactedKeys == nil {
+			opts.redactedKeys = make(map[attribute.Key]struct{}, len(keys))
+		}
+		for _, k := range keys {
+			opts.redactedKeys[attribute.Key(k)] = struct{}{}
+		}
+	}
+}
@@ -52,7 +52,7 @@ func (t *Tracer) Start(ctx context.Context, carrier propagation.TextMapCarrier,
 	}
 
 	opts := []trace.SpanStartOption{
-		trace.WithAttributes(attrs...),
+		trace.WithAttributes(t.redact(attrs)...),
 		trace.WithSpanKind(t.opt.kind),
 	}
 
@@ -65,6 +65,44 @@ func (t *Tracer) Start(ctx context.Context, carrier propagation.TextMapCarrier,
 	return ctx, span
 }
 
+// Extract return ctx with the span context propagated by carrier.
+func (t *Tracer) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return t.opt.propagator.Extract(ctx, carrier)
+}
+
+// StartBatch start a span processing the messages of carriers together,
+// linked to the span of each of them rather than the child of one, as the
+// OpenTelemetry messaging conventions do for batches.
+func (t *Tracer) StartBatch(ctx context.Context, carriers []propagation.TextMapCarrier, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.Int("messaging.batch.message_count", len(carriers)))
+
+	opts := []trace.SpanStartOption{
+		trace.WithAttributes(t.redact(attrs)...),
+		trace.WithSpanKind(t.opt.kind),
+		trace.WithLinks(t.Links(carriers...)...),
+	}
+
+	return t.tracer.Start(ctx, t.opt.spanName, opts...)
+}
+
+// Links return a link to each distinct valid span context propagated by carriers.
+func (t *Tracer) Links(carriers ...propagation.TextMapCarrier) []trace.Link {
+	links := make([]trace.Link, 0, len(carriers))
+	seen := make(map[trace.SpanID]struct{}, len(carriers))
+	for _, carrier := range carriers {
+		sc := trace.SpanContextFromContext(t.opt.propagator.Extract(context.Background(), carrier))
+		if !sc.IsValid() {
+			continue
+		}
+		if _, ok := seen[sc.SpanID()]; ok {
+			continue
+		}
+		seen[sc.SpanID()] = struct{}{}
+		links = append(links, trace.Link{SpanContext: sc})
+	}
+	return links
+}
+
 func (t *Tracer) End(ctx context.Context, span trace.Span, err error, attrs ...attribute.KeyValue) {
 	if span == nil {
 		return
@@ -73,7 +111,7 @@ func (t *Tracer) End(ctx context.Context, span trace.Span, err error, attrs ...a
 		return
 	}
 
-	span.SetAttributes(attrs...)
+	span.SetAttributes(t.redact(attrs)...)
 
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
@@ -81,3 +119,18 @@ func (t *Tracer) End(ctx context.Context, span trace.Span, err error, attrs ...a
 
 	span.End()
 }
+
+func (t *Tracer) redact(attrs []attribute.KeyValue) []attribute.KeyValue {
+	if len(t.opt.redactedKeys) == 0 {
+		return attrs
+	}
+
+	out := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		if _, ok := t.opt.redactedKeys[attr.Key]; ok {
+			attr = attr.Key.String("***")
+		}
+		out[i] = attr
+	}
+	return out
+}
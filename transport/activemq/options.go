@@ -6,6 +6,8 @@ import (
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/go-kratos/kratos/v2/middleware"
+
 	"github.com/tx7do/kratos-transport/broker"
 )
 
@@ -69,3 +71,10 @@ func WithPropagator(propagators propagation.TextMapPropagator) ServerOption {
 		s.brokerOpts = append(s.brokerOpts, broker.WithPropagator(propagators))
 	}
 }
+
+// WithMiddleware 订阅者处理函数的kratos中间件，与HTTP、gRPC服务共用，如recovery、ratelimit、metrics、validate
+func WithMiddleware(m ...middleware.Middleware) ServerOption {
+	return func(s *Server) {
+		s.middlewares = append(s.middlewares, m...)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"sync"
 
+	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/transport"
 
 	"github.com/tx7do/kratos-transport/broker"
@@ -16,6 +17,7 @@ import (
 var (
 	_ transport.Server     = (*Server)(nil)
 	_ transport.Endpointer = (*Server)(nil)
+	_ broker.Drainer       = (*Server)(nil)
 )
 
 type SubscriberMap map[string]broker.Subscriber
@@ -33,6 +35,8 @@ type Server struct {
 
 	subscribers    SubscriberMap
 	subscriberOpts SubscribeOptionMap
+	handlers       broker.HandlerTracker
+	middlewares    []middleware.Middleware
 
 	sync.RWMutex
 	started bool
@@ -117,6 +121,22 @@ func (s *Server) Stop(_ context.Context) error {
 	return s.Disconnect()
 }
 
+// Drain stop fetching and wait until the running handlers return or ctx is
+// done, for a preStop hook, e.g. kratos.BeforeStop(broker.DrainHook(srv, timeout)).
+func (s *Server) Drain(ctx context.Context, progress chan<- broker.DrainProgress) error {
+	s.Lock()
+	subscribers := s.subscribers
+	s.subscribers = SubscriberMap{}
+	s.Unlock()
+
+	s.handlers.Stop()
+	for _, sub := range subscribers {
+		_ = sub.Unsubscribe(true)
+	}
+
+	return s.handlers.Drain(ctx, progress)
+}
+
 func (s *Server) Endpoint() (*url.URL, error) {
 	if s.err != nil {
 		return nil, s.err
@@ -170,7 +190,7 @@ func RegisterSubscriber[T any](srv *Server, ctx context.Context, topic string, h
 }
 
 func (s *Server) doRegisterSubscriber(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) error {
-	sub, err := s.Subscribe(topic, handler, binder, opts...)
+	sub, err := s.Subscribe(topic, s.handlers.Track(broker.MiddlewareHandler(KindActiveMQ, topic, handler, s.middlewares...)), binder, opts...)
 	if err != nil {
 		return err
 	}
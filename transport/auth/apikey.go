@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+)
+
+// APIKey accept the peers presenting one of keys in the header, or query
+// parameter, name. keys maps a key to the subject of its owner.
+func APIKey(name string, keys map[string]string) Authenticator {
+	return AuthenticatorFunc(func(_ context.Context, r *Request) (*Identity, error) {
+		key := r.Value(name)
+		if key == "" {
+			return nil, ErrMissingCredentials
+		}
+
+		for k, subject := range keys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				return &Identity{Subject: subject, Method: "apikey"}, nil
+			}
+		}
+		return nil, ErrInvalidCredentials
+	})
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrUnauthenticated    = errors.New("auth: unauthenticated")
+	ErrMissingCredentials = errors.New("auth: missing credentials")
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+)
+
+// Identity is the authenticated peer of a connection.
+type Identity struct {
+	// Subject identifies the peer, the user id of a token, the name of an
+	// api key or the common name of a client certificate.
+	Subject string
+	// Method is the authenticator that accepted the peer, e.g. jwt, apikey, mtls.
+	Method string
+	Claims map[string]interface{}
+}
+
+// Request carries the credentials presented by a peer when it connects.
+// Transports fill in what they have: an http handshake has headers and a
+// query, a raw tcp connection only the address and the tls state.
+type Request struct {
+	Header     http.Header
+	Query      url.Values
+	RemoteAddr string
+	TLS        *tls.ConnectionState
+}
+
+// NewHTTPRequest return the credentials of an http handshake.
+func NewHTTPRequest(r *http.Request) *Request {
+	return &Request{
+		Header:     r.Header,
+		Query:      r.URL.Query(),
+		RemoteAddr: r.RemoteAddr,
+		TLS:        r.TLS,
+	}
+}
+
+// Authenticator verifies the credentials of a peer.
+type Authenticator interface {
+	Authenticate(ctx context.Context, r *Request) (*Identity, error)
+}
+
+type AuthenticatorFunc func(ctx context.Context, r *Request) (*Identity, error)
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, r *Request) (*Identity, error) {
+	return f(ctx, r)
+}
+
+// Any accept a peer accepted by one of authenticators, tried in order.
+func Any(authenticators ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, r *Request) (*Identity, error) {
+		err := ErrUnauthenticated
+		for _, a := range authenticators {
+			id, e := a.Authenticate(ctx, r)
+			if e == nil {
+				return id, nil
+			}
+			// keep the most telling error
+			if err == ErrUnauthenticated || errors.Is(err, ErrMissingCredentials) {
+				err = e
+			}
+		}
+		return nil, err
+	})
+}
+
+// Value return the first value of key in the header or else the query.
+func (r *Request) Value(key string) string {
+	if r.Header != nil {
+		if v := r.Header.Get(key); v != "" {
+			return v
+		}
+	}
+	if r.Query != nil {
+		return r.Query.Get(key)
+	}
+	return ""
+}
+
+// BearerToken return the token of the Authorization header, or of the
+// access_token query parameter for clients that can not set headers, as
+// browser websockets.
+func (r *Request) BearerToken() string {
+	if r.Header != nil {
+		h := r.Header.Get("Authorization")
+		if len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+			return strings.TrimSpace(h[7:])
+		}
+	}
+	if r.Query != nil {
+		return r.Query.Get("access_token")
+	}
+	return ""
+}
+
+type identityKey struct{}
+
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signJWT(secret []byte, claims map[string]interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	body, _ := json.Marshal(claims)
+	payload := base64.RawURLEncoding.EncodeToString(body)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func bearerRequest(token string) *Request {
+	return &Request{Header: http.Header{"Authorization": []string{"Bearer " + token}}}
+}
+
+func TestJWT(t *testing.T) {
+	ctx := context.Background()
+	secret := []byte("secret")
+	a := JWT(secret)
+
+	id, err := a.Authenticate(ctx, bearerRequest(signJWT(secret, map[string]interface{}{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})))
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", id.Subject)
+	assert.Equal(t, "jwt", id.Method)
+
+	// from the query, as browsers can not set headers on websockets
+	token := signJWT(secret, map[string]interface{}{"sub": "bob"})
+	id, err = a.Authenticate(ctx, &Request{Query: url.Values{"access_token": []string{token}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", id.Subject)
+
+	_, err = a.Authenticate(ctx, bearerRequest(signJWT(secret, map[string]interface{}{
+		"sub": "alice",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	})))
+	assert.ErrorIs(t, err, ErrTokenExpired)
+
+	_, err = a.Authenticate(ctx, bearerRequest(signJWT([]byte("other"), map[string]interface{}{"sub": "alice"})))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = a.Authenticate(ctx, &Request{})
+	assert.ErrorIs(t, err, ErrMissingCredentials)
+}
+
+func TestAPIKey(t *testing.T) {
+	ctx := context.Background()
+	a := APIKey("X-Api-Key", map[string]string{"k1": "device-1"})
+
+	id, err := a.Authenticate(ctx, &Request{Header: http.Header{"X-Api-Key": []string{"k1"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "device-1", id.Subject)
+
+	_, err = a.Authenticate(ctx, &Request{Query: url.Values{"X-Api-Key": []string{"k2"}}})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestMTLS(t *testing.T) {
+	ctx := context.Background()
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "device-1"}, SerialNumber: big.NewInt(7)}
+
+	id, err := MTLS().Authenticate(ctx, &Request{TLS: &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, "device-1", id.Subject)
+
+	_, err = MTLS().Authenticate(ctx, &Request{TLS: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = MTLS().Authenticate(ctx, &Request{})
+	assert.ErrorIs(t, err, ErrMissingCredentials)
+}
+
+func TestAny(t *testing.T) {
+	ctx := context.Background()
+	a := Any(MTLS(), APIKey("X-Api-Key", map[string]string{"k1": "device-1"}))
+
+	id, err := a.Authenticate(ctx, &Request{Header: http.Header{"X-Api-Key": []string{"k1"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "apikey", id.Method)
+
+	_, err = a.Authenticate(ctx, &Request{Header: http.Header{"X-Api-Key": []string{"k2"}}})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	id, ok := FromContext(NewContext(ctx, &Identity{Subject: "alice"}))
+	assert.True(t, ok)
+	assert.Equal(t, "alice", id.Subject)
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTokenExpired     = errors.New("auth: token expired")
+	ErrTokenNotValidYet = errors.New("auth: token not valid yet")
+)
+
+// Bearer accept the peers whose bearer token is accepted by verify.
+func Bearer(verify func(ctx context.Context, token string) (*Identity, error)) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, r *Request) (*Identity, error) {
+		token := r.BearerToken()
+		if token == "" {
+			return nil, ErrMissingCredentials
+		}
+		return verify(ctx, token)
+	})
+}
+
+// JWT accept the peers with a bearer JWT signed by secret with HS256, HS384
+// or HS512. The exp and nbf claims are checked, the sub claim is the subject.
+func JWT(secret []byte) Authenticator {
+	return Bearer(func(_ context.Context, token string) (*Identity, error) {
+		claims, err := verifyJWT(secret, token, time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		sub, _ := claims["sub"].(string)
+		return &Identity{Subject: sub, Method: "jwt", Claims: claims}, nil
+	})
+}
+
+func verifyJWT(secret []byte, token string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCredentials
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var h func() hash.Hash
+	switch header.Alg {
+	case "HS256":
+		h = sha256.New
+	case "HS384":
+		h = sha512.New384
+	case "HS512":
+		h = sha512.New
+	default:
+		return nil, ErrInvalidCredentials
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	mac := hmac.New(h, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, ErrInvalidCredentials
+	}
+
+	var claims map[string]interface{}
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if exp, ok := claims["exp"].(float64); ok && now.Unix() >= int64(exp) {
+		return nil, ErrTokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Unix() < int64(nbf) {
+		return nil, ErrTokenNotValidYet
+	}
+
+	return claims, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	buf, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, v)
+}
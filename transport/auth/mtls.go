@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"context"
+)
+
+// MTLS accept the peers presenting a client certificate verified by the tls
+// config of the server, which must require and verify client certificates.
+// The common name of the certificate is the subject.
+func MTLS() Authenticator {
+	return AuthenticatorFunc(func(_ context.Context, r *Request) (*Identity, error) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return nil, ErrMissingCredentials
+		}
+		if len(r.TLS.VerifiedChains) == 0 {
+			return nil, ErrInvalidCredentials
+		}
+
+		cert := r.TLS.PeerCertificates[0]
+		return &Identity{
+			Subject: cert.Subject.CommonName,
+			Method:  "mtls",
+			Claims: map[string]interface{}{
+				"dns_names": cert.DNSNames,
+				"serial":    cert.SerialNumber.String(),
+			},
+		}, nil
+	})
+}
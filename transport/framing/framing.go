@@ -0,0 +1,29 @@
+package framing
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidFrame  = errors.New("framing: invalid frame")
+	ErrFrameTooLarge = errors.New("framing: frame too large")
+)
+
+// Frame is a message of a wire protocol: its type, which selects the
+// handler, and its body, which the payload codec of the server decodes.
+type Frame struct {
+	Type uint32
+	Body []byte
+}
+
+// Codec implements a wire protocol, so a server can speak a custom game or
+// device protocol without being forked.
+type Codec interface {
+	// Marshal encode a frame into the bytes written to the connection.
+	Marshal(f *Frame) ([]byte, error)
+	// Unmarshal decode one complete frame as cut by FrameSplit.
+	Unmarshal(data []byte) (*Frame, error)
+	// FrameSplit is a bufio.SplitFunc cutting the frames out of a stream.
+	// Message oriented transports, as websocket, do not call it.
+	FrameSplit(data []byte, atEOF bool) (advance int, token []byte, err error)
+}
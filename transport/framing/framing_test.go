@@ -0,0 +1,82 @@
+package framing
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func split(t *testing.T, c Codec, stream []byte) []*Frame {
+	scanner := bufio.NewScanner(bytes.NewReader(stream))
+	scanner.Split(c.FrameSplit)
+
+	var frames []*Frame
+	for scanner.Scan() {
+		f, err := c.Unmarshal(scanner.Bytes())
+		assert.Nil(t, err)
+		// the scanner reuses its buffer
+		f.Body = append([]byte(nil), f.Body...)
+		frames = append(frames, f)
+	}
+	assert.Nil(t, scanner.Err())
+	return frames
+}
+
+func TestLengthPrefix(t *testing.T) {
+	c := LengthPrefix(1024)
+
+	var stream []byte
+	for _, f := range []*Frame{{Type: 1, Body: []byte("hello")}, {Type: 300, Body: nil}, {Type: 2, Body: make([]byte, 200)}} {
+		buf, err := c.Marshal(f)
+		assert.Nil(t, err)
+		stream = append(stream, buf...)
+	}
+
+	frames := split(t, c, stream)
+	assert.Equal(t, 3, len(frames))
+	assert.Equal(t, uint32(1), frames[0].Type)
+	assert.Equal(t, "hello", string(frames[0].Body))
+	assert.Equal(t, uint32(300), frames[1].Type)
+	assert.Empty(t, frames[1].Body)
+	assert.Equal(t, 200, len(frames[2].Body))
+
+	// a partial frame waits for more data, and fails at the end of the stream
+	advance, token, err := c.FrameSplit(stream[:3], false)
+	assert.Equal(t, 0, advance)
+	assert.Nil(t, token)
+	assert.Nil(t, err)
+	_, _, err = c.FrameSplit(stream[:3], true)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	_, err = c.Marshal(&Frame{Type: 1, Body: make([]byte, 2048)})
+	assert.ErrorIs(t, err, ErrFrameTooLarge)
+	_, _, err = c.FrameSplit([]byte{0xff, 0x7f}, false)
+	assert.ErrorIs(t, err, ErrFrameTooLarge)
+
+	_, err = c.Unmarshal([]byte{5, 1})
+	assert.ErrorIs(t, err, ErrInvalidFrame)
+}
+
+func TestJSONLines(t *testing.T) {
+	c := JSONLines()
+
+	buf, err := c.Marshal(&Frame{Type: 1, Body: []byte(`{"text":"hi"}`)})
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"type\":1,\"body\":{\"text\":\"hi\"}}\n", string(buf))
+
+	stream := append(buf, []byte("\n\r\n{\"type\":2,\"body\":[1,2]}\r\n{\"type\":3}")...)
+	frames := split(t, c, stream)
+	assert.Equal(t, 3, len(frames))
+	assert.Equal(t, `{"text":"hi"}`, string(frames[0].Body))
+	assert.Equal(t, uint32(2), frames[1].Type)
+	assert.Equal(t, `[1,2]`, string(frames[1].Body))
+	assert.Equal(t, uint32(3), frames[2].Type)
+
+	_, err = c.Marshal(&Frame{Type: 1, Body: []byte("not json")})
+	assert.ErrorIs(t, err, ErrInvalidFrame)
+	_, err = c.Unmarshal([]byte("not json"))
+	assert.ErrorIs(t, err, ErrInvalidFrame)
+}
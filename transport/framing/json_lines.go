@@ -0,0 +1,58 @@
+package framing
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+)
+
+// JSONLines frame each message as one line of json, with the body, which
+// must be json, embedded as is.
+//
+//	{"type":1,"body":{"text":"hi"}}\n
+func JSONLines() Codec {
+	return jsonLines{}
+}
+
+type jsonLines struct{}
+
+type jsonLine struct {
+	Type uint32          `json:"type"`
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+func (jsonLines) Marshal(f *Frame) ([]byte, error) {
+	if len(f.Body) > 0 && !json.Valid(f.Body) {
+		return nil, ErrInvalidFrame
+	}
+
+	buf, err := json.Marshal(&jsonLine{Type: f.Type, Body: f.Body})
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '\n'), nil
+}
+
+func (jsonLines) Unmarshal(data []byte) (*Frame, error) {
+	var line jsonLine
+	if err := json.Unmarshal(bytes.TrimSpace(data), &line); err != nil {
+		return nil, ErrInvalidFrame
+	}
+	return &Frame{Type: line.Type, Body: line.Body}, nil
+}
+
+func (jsonLines) FrameSplit(data []byte, atEOF bool) (int, []byte, error) {
+	// skip the blank lines here, a scanner at the end of the stream stops
+	// at the first call returning no token
+	skipped := 0
+	for {
+		advance, token, err := bufio.ScanLines(data[skipped:], atEOF)
+		if err != nil || advance == 0 {
+			return skipped, nil, err
+		}
+		if len(bytes.TrimSpace(token)) > 0 {
+			return skipped + advance, token, nil
+		}
+		skipped += advance
+	}
+}
@@ -0,0 +1,79 @@
+package framing
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// LengthPrefix frame each message with the varint length prefix of
+// delimited protobuf streams. The frame holds the varint type followed by
+// the body, which is usually a protobuf message.
+//
+//	| uvarint(len) | uvarint(type) | body |
+func LengthPrefix(maxFrameSize int) Codec {
+	return &lengthPrefix{maxFrameSize: maxFrameSize}
+}
+
+type lengthPrefix struct {
+	maxFrameSize int
+}
+
+func (c *lengthPrefix) Marshal(f *Frame) ([]byte, error) {
+	var head [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(head[:], uint64(f.Type))
+
+	size := n + len(f.Body)
+	if c.maxFrameSize > 0 && size > c.maxFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+
+	buf := make([]byte, 0, binary.MaxVarintLen64+size)
+	buf = binary.AppendUvarint(buf, uint64(size))
+	buf = append(buf, head[:n]...)
+	buf = append(buf, f.Body...)
+	return buf, nil
+}
+
+func (c *lengthPrefix) Unmarshal(data []byte) (*Frame, error) {
+	size, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) != size {
+		return nil, ErrInvalidFrame
+	}
+	data = data[n:]
+
+	typ, n := binary.Uvarint(data)
+	if n <= 0 || typ > uint64(^uint32(0)) {
+		return nil, ErrInvalidFrame
+	}
+
+	return &Frame{Type: uint32(typ), Body: data[n:]}, nil
+}
+
+func (c *lengthPrefix) FrameSplit(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	size, n := binary.Uvarint(data)
+	switch {
+	case n < 0:
+		return 0, nil, ErrInvalidFrame
+	case n == 0:
+		if atEOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, nil
+	}
+	if c.maxFrameSize > 0 && size > uint64(c.maxFrameSize) {
+		return 0, nil, ErrFrameTooLarge
+	}
+
+	end := n + int(size)
+	if len(data) < end {
+		if atEOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, nil
+	}
+	return end, data[:end], nil
+}
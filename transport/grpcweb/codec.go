@@ -0,0 +1,15 @@
+package grpcweb
+
+import (
+	"github.com/go-kratos/kratos/v2/encoding"
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+
+	grpcEncoding "google.golang.org/grpc/encoding"
+)
+
+func init() {
+	// Connect JSON requests reach the gRPC server as application/grpc+json.
+	if grpcEncoding.GetCodec("json") == nil {
+		grpcEncoding.RegisterCodec(encoding.GetCodec("json"))
+	}
+}
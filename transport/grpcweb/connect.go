@@ -0,0 +1,244 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeConnectProto = "application/proto"
+	contentTypeConnectJSON  = "application/json"
+)
+
+var connectCodes = map[codes.Code]string{
+	codes.Canceled:           "canceled",
+	codes.Unknown:            "unknown",
+	codes.InvalidArgument:    "invalid_argument",
+	codes.DeadlineExceeded:   "deadline_exceeded",
+	codes.NotFound:           "not_found",
+	codes.AlreadyExists:      "already_exists",
+	codes.PermissionDenied:   "permission_denied",
+	codes.ResourceExhausted:  "resource_exhausted",
+	codes.FailedPrecondition: "failed_precondition",
+	codes.Aborted:            "aborted",
+	codes.OutOfRange:         "out_of_range",
+	codes.Unimplemented:      "unimplemented",
+	codes.Internal:           "internal",
+	codes.Unavailable:        "unavailable",
+	codes.DataLoss:           "data_loss",
+	codes.Unauthenticated:    "unauthenticated",
+}
+
+var connectHTTPStatus = map[codes.Code]int{
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+type connectError struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message,omitempty"`
+	Details []connectErrorDetail `json:"details,omitempty"`
+}
+
+type connectErrorDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// serveConnect serves a Connect unary request, the message is framed as a
+// single gRPC message and the response unframed again.
+func (h *Handler) serveConnect(w http.ResponseWriter, r *http.Request) {
+	if enc := r.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		writeConnectError(w, codes.Unimplemented, "unsupported content encoding: "+enc, nil)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeConnectError(w, codes.InvalidArgument, err.Error(), nil)
+		return
+	}
+
+	frame := make([]byte, 5, 5+len(body))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(body)))
+	frame = append(frame, body...)
+
+	contentType := mediaType(r)
+
+	req := toGrpcRequest(r)
+	req.Body = io.NopCloser(bytes.NewReader(frame))
+	req.ContentLength = int64(len(frame))
+	req.Header.Set("Content-Type", contentTypeGrpc+"+"+strings.TrimPrefix(contentType, "application/"))
+	if ms := req.Header.Get("Connect-Timeout-Ms"); ms != "" {
+		req.Header.Set("Grpc-Timeout", ms+"m")
+	}
+	req.Header.Del("Connect-Timeout-Ms")
+	req.Header.Del("Connect-Protocol-Version")
+
+	rw := newConnectResponseWriter()
+	h.server.ServeHTTP(rw, req)
+	rw.writeTo(w, contentType)
+}
+
+// connectResponseWriter buffers the gRPC response of a unary call.
+type connectResponseWriter struct {
+	header      http.Header
+	body        bytes.Buffer
+	code        int
+	sentHeaders map[string]struct{}
+}
+
+func newConnectResponseWriter() *connectResponseWriter {
+	return &connectResponseWriter{header: make(http.Header)}
+}
+
+func (w *connectResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *connectResponseWriter) WriteHeader(code int) {
+	if w.code != 0 {
+		return
+	}
+	w.code = code
+
+	w.sentHeaders = make(map[string]struct{}, len(w.header))
+	for k := range w.header {
+		w.sentHeaders[k] = struct{}{}
+	}
+}
+
+func (w *connectResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+func (w *connectResponseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (w *connectResponseWriter) writeTo(dst http.ResponseWriter, contentType string) {
+	if w.code != 0 && w.code != http.StatusOK {
+		// rejected by the gRPC server before the call started.
+		writeConnectError(dst, httpStatusToCode(w.code), strings.TrimSpace(w.body.String()), nil)
+		return
+	}
+
+	header := dst.Header()
+	for k, vv := range w.header {
+		trailer := strings.HasPrefix(k, http.TrailerPrefix)
+		k = strings.TrimPrefix(k, http.TrailerPrefix)
+		if k == "Trailer" || k == "Content-Type" || strings.HasPrefix(k, "Grpc-") {
+			continue
+		}
+		if _, sent := w.sentHeaders[k]; !sent || trailer {
+			k = "Trailer-" + k
+		}
+		header[k] = vv
+	}
+
+	code := codes.Unknown
+	if v := w.header.Get("Grpc-Status"); v != "" {
+		if c, err := strconv.ParseUint(v, 10, 32); err == nil {
+			code = codes.Code(c)
+		}
+	}
+	if code != codes.OK {
+		message, _ := url.PathUnescape(w.header.Get("Grpc-Message"))
+		writeConnectError(dst, code, message, decodeDetails(w.header.Get("Grpc-Status-Details-Bin")))
+		return
+	}
+
+	buf := w.body.Bytes()
+	if len(buf) < 5 || int(binary.BigEndian.Uint32(buf[1:5])) != len(buf)-5 {
+		writeConnectError(dst, codes.Internal, "invalid response message", nil)
+		return
+	}
+	if buf[0] != 0 {
+		writeConnectError(dst, codes.Internal, "compressed response message is not supported", nil)
+		return
+	}
+
+	header.Set("Content-Type", contentType)
+	dst.WriteHeader(http.StatusOK)
+	_, _ = dst.Write(buf[5:])
+}
+
+func writeConnectError(w http.ResponseWriter, code codes.Code, message string, details []connectErrorDetail) {
+	name, ok := connectCodes[code]
+	if !ok {
+		code, name = codes.Unknown, connectCodes[codes.Unknown]
+	}
+
+	buf, _ := json.Marshal(&connectError{Code: name, Message: message, Details: details})
+
+	w.Header().Set("Content-Type", contentTypeConnectJSON)
+	w.WriteHeader(connectHTTPStatus[code])
+	_, _ = w.Write(buf)
+}
+
+func decodeDetails(v string) []connectErrorDetail {
+	if v == "" {
+		return nil
+	}
+
+	buf, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(v, "="))
+	if err != nil {
+		return nil
+	}
+	var st status.Status
+	if err = proto.Unmarshal(buf, &st); err != nil {
+		return nil
+	}
+
+	details := make([]connectErrorDetail, 0, len(st.Details))
+	for _, d := range st.Details {
+		details = append(details, connectErrorDetail{
+			Type:  strings.TrimPrefix(d.GetTypeUrl(), "type.googleapis.com/"),
+			Value: base64.RawStdEncoding.EncodeToString(d.GetValue()),
+		})
+	}
+	return details
+}
+
+func httpStatusToCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.Internal
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.Unimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
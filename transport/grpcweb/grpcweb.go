@@ -0,0 +1,163 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	contentTypeGrpc        = "application/grpc"
+	contentTypeGrpcWeb     = "application/grpc-web"
+	contentTypeGrpcWebText = "application/grpc-web-text"
+
+	// trailerFlag marks the gRPC-Web frame carrying the trailers.
+	trailerFlag byte = 0x80
+)
+
+func (h *Handler) serveGrpcWeb(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, contentTypeGrpcWebText)
+
+	req := toGrpcRequest(r)
+	req.Header.Set("Content-Type", contentTypeGrpc+strings.TrimPrefix(strings.TrimPrefix(contentType, contentTypeGrpcWebText), contentTypeGrpcWeb))
+	if text {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{base64.NewDecoder(base64.StdEncoding, r.Body), r.Body}
+	}
+
+	rw := newWebResponseWriter(w, contentType, text)
+	h.server.ServeHTTP(rw, req)
+	rw.finish()
+}
+
+// toGrpcRequest clones r into a request the gRPC server accepts through
+// ServeHTTP, which only serves HTTP/2.
+func toGrpcRequest(r *http.Request) *http.Request {
+	req := r.Clone(r.Context())
+	req.Proto = "HTTP/2"
+	req.ProtoMajor = 2
+	req.ProtoMinor = 0
+	req.Header.Del("Content-Length")
+	return req
+}
+
+// webResponseWriter turns a gRPC response into a gRPC-Web one, trailers are
+// sent as the last frame of the body.
+type webResponseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	contentType string
+	text        bool
+
+	wroteHeader bool
+	sentHeaders map[string]struct{}
+}
+
+func newWebResponseWriter(w http.ResponseWriter, contentType string, text bool) *webResponseWriter {
+	return &webResponseWriter{
+		w:           w,
+		header:      make(http.Header),
+		contentType: contentType,
+		text:        text,
+	}
+}
+
+func (w *webResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *webResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	w.sentHeaders = make(map[string]struct{}, len(w.header))
+	header := w.w.Header()
+	for k, vv := range w.header {
+		w.sentHeaders[k] = struct{}{}
+		if k == "Trailer" || strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
+		header[k] = vv
+	}
+	if code == http.StatusOK {
+		header.Set("Content-Type", w.contentType)
+	}
+
+	w.w.WriteHeader(code)
+}
+
+func (w *webResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+
+	if w.text {
+		if _, err := w.w.Write([]byte(base64.StdEncoding.EncodeToString(b))); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	return w.w.Write(b)
+}
+
+func (w *webResponseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes the trailer frame: the headers set after the response
+// header was written.
+func (w *webResponseWriter) finish() {
+	w.WriteHeader(http.StatusOK)
+
+	trailer := make(http.Header)
+	for k, vv := range w.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			trailer[strings.TrimPrefix(k, http.TrailerPrefix)] = vv
+			continue
+		}
+		if _, ok := w.sentHeaders[k]; !ok {
+			trailer[k] = vv
+		}
+	}
+	if len(trailer) == 0 {
+		return
+	}
+
+	_, _ = w.Write(encodeTrailer(trailer))
+	w.Flush()
+}
+
+func encodeTrailer(trailer http.Header) []byte {
+	keys := make([]string, 0, len(trailer))
+	for k := range trailer {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var body bytes.Buffer
+	for _, k := range keys {
+		for _, v := range trailer[k] {
+			body.WriteString(strings.ToLower(k))
+			body.WriteString(": ")
+			body.WriteString(v)
+			body.WriteString("\r\n")
+		}
+	}
+
+	frame := make([]byte, 5, 5+body.Len())
+	frame[0] = trailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(body.Len()))
+	return append(frame, body.Bytes()...)
+}
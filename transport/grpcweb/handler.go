@@ -0,0 +1,152 @@
+package grpcweb
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	kHttp "github.com/go-kratos/kratos/v2/transport/http"
+
+	"google.golang.org/grpc"
+)
+
+var (
+	defaultAllowedHeaders = []string{
+		"Content-Type",
+		"Authorization",
+		"X-Grpc-Web",
+		"X-User-Agent",
+		"Grpc-Timeout",
+		"Connect-Protocol-Version",
+		"Connect-Timeout-Ms",
+	}
+	defaultExposedHeaders = []string{
+		"Grpc-Status",
+		"Grpc-Message",
+		"Grpc-Status-Details-Bin",
+	}
+)
+
+// Server is the gRPC server requests are translated for, both *grpc.Server
+// and the kratos grpc transport server satisfy it.
+type Server interface {
+	http.Handler
+	GetServiceInfo() map[string]grpc.ServiceInfo
+}
+
+// Handler serves gRPC-Web and Connect unary requests by translating them to
+// gRPC, so browsers can call gRPC services without a proxy.
+type Handler struct {
+	server Server
+
+	originFunc     func(origin string) bool
+	allowedHeaders []string
+	exposedHeaders []string
+	disableConnect bool
+}
+
+func NewHandler(server Server, opts ...Option) *Handler {
+	h := &Handler{
+		server:         server,
+		allowedHeaders: append([]string(nil), defaultAllowedHeaders...),
+		exposedHeaders: append([]string(nil), defaultExposedHeaders...),
+	}
+
+	for _, o := range opts {
+		o(h)
+	}
+
+	return h
+}
+
+// Register mounts a Handler on srv for every service registered on server.
+func Register(srv *kHttp.Server, server Server, opts ...Option) *Handler {
+	h := NewHandler(server, opts...)
+	for name := range server.GetServiceInfo() {
+		srv.HandlePrefix("/"+name+"/", h)
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		h.servePreflight(w, r)
+		return
+	}
+
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if !h.allowOrigin(origin, r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(h.exposedHeaders, ", "))
+	}
+
+	switch {
+	case IsGrpcWebRequest(r):
+		h.serveGrpcWeb(w, r)
+	case !h.disableConnect && IsConnectRequest(r):
+		h.serveConnect(w, r)
+	default:
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+	}
+}
+
+// allowOrigin accepts the same-origin requests only, unless an origin func
+// is set.
+func (h *Handler) allowOrigin(origin string, r *http.Request) bool {
+	if h.originFunc != nil {
+		return h.originFunc(origin)
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func (h *Handler) servePreflight(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !h.allowOrigin(origin, r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Add("Vary", "Origin")
+	header.Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	header.Set("Access-Control-Allow-Headers", strings.Join(h.allowedHeaders, ", "))
+	header.Set("Access-Control-Max-Age", "600")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// IsGrpcWebRequest reports whether r uses the gRPC-Web protocol.
+func IsGrpcWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(mediaType(r), contentTypeGrpcWeb)
+}
+
+// IsConnectRequest reports whether r is a Connect unary request.
+func IsConnectRequest(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	switch mediaType(r) {
+	case contentTypeConnectProto, contentTypeConnectJSON:
+		return true
+	default:
+		return false
+	}
+}
+
+func mediaType(r *http.Request) string {
+	t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return t
+}
@@ -0,0 +1,194 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestHandler(opts ...Option) *Handler {
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	return NewHandler(srv, opts...)
+}
+
+func frame(flag byte, payload []byte) []byte {
+	buf := make([]byte, 5, 5+len(payload))
+	buf[0] = flag
+	binary.BigEndian.PutUint32(buf[1:], uint32(len(payload)))
+	return append(buf, payload...)
+}
+
+func readFrames(t *testing.T, body []byte) (messages [][]byte, trailer string) {
+	for len(body) > 0 {
+		assert.GreaterOrEqual(t, len(body), 5)
+		n := binary.BigEndian.Uint32(body[1:5])
+		payload := body[5 : 5+n]
+		if body[0]&trailerFlag != 0 {
+			trailer = string(payload)
+		} else {
+			messages = append(messages, payload)
+		}
+		body = body[5+n:]
+	}
+	return
+}
+
+func TestGrpcWeb(t *testing.T) {
+	h := newTestHandler(WithAllowAllOrigins())
+
+	req, _ := proto.Marshal(&healthpb.HealthCheckRequest{})
+	r := httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Check", bytes.NewReader(frame(0, req)))
+	r.Header.Set("Content-Type", "application/grpc-web+proto")
+	r.Header.Set("Origin", "http://localhost:3000")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/grpc-web+proto", w.Header().Get("Content-Type"))
+	assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Grpc-Status"))
+
+	messages, trailer := readFrames(t, w.Body.Bytes())
+	assert.Len(t, messages, 1)
+	var rsp healthpb.HealthCheckResponse
+	assert.NoError(t, proto.Unmarshal(messages[0], &rsp))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, rsp.Status)
+	assert.Contains(t, trailer, "grpc-status: 0\r\n")
+}
+
+func TestGrpcWebText(t *testing.T) {
+	h := newTestHandler()
+
+	req, _ := proto.Marshal(&healthpb.HealthCheckRequest{Service: "unknown"})
+	body := base64.StdEncoding.EncodeToString(frame(0, req))
+	r := httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Check", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/grpc-web-text")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/grpc-web-text", w.Header().Get("Content-Type"))
+
+	decoded, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, w.Body))
+	assert.NoError(t, err)
+	messages, trailer := readFrames(t, decoded)
+	assert.Empty(t, messages)
+	assert.Contains(t, trailer, "grpc-status: 5\r\n")
+	assert.Contains(t, trailer, "grpc-message: unknown service\r\n")
+}
+
+func TestConnect(t *testing.T) {
+	h := newTestHandler()
+
+	req, _ := proto.Marshal(&healthpb.HealthCheckRequest{})
+	r := httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Check", bytes.NewReader(req))
+	r.Header.Set("Content-Type", "application/proto")
+	r.Header.Set("Connect-Protocol-Version", "1")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/proto", w.Header().Get("Content-Type"))
+	var rsp healthpb.HealthCheckResponse
+	assert.NoError(t, proto.Unmarshal(w.Body.Bytes(), &rsp))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, rsp.Status)
+
+	r = httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Check", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"SERVING"}`, w.Body.String())
+}
+
+func TestConnectError(t *testing.T) {
+	h := newTestHandler()
+
+	r := httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Check", strings.NewReader(`{"service":"unknown"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	var rsp connectError
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rsp))
+	assert.Equal(t, connectError{Code: "not_found", Message: "unknown service"}, rsp)
+
+	r = httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Unknown", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestPreflight(t *testing.T) {
+	h := newTestHandler(WithOriginFunc(func(origin string) bool {
+		return origin == "http://localhost:3000"
+	}))
+
+	r := httptest.NewRequest(http.MethodOptions, "/grpc.health.v1.Health/Check", nil)
+	r.Header.Set("Origin", "http://localhost:3000")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "X-Grpc-Web")
+
+	r.Header.Set("Origin", "http://evil.example")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestSameOrigin(t *testing.T) {
+	h := newTestHandler()
+
+	for origin, code := range map[string]int{
+		"http://example.com":    http.StatusOK,
+		"http://localhost:3000": http.StatusForbidden,
+		"null":                  http.StatusForbidden,
+	} {
+		req, _ := proto.Marshal(&healthpb.HealthCheckRequest{})
+		r := httptest.NewRequest(http.MethodPost, "http://example.com/grpc.health.v1.Health/Check", bytes.NewReader(frame(0, req)))
+		r.Header.Set("Content-Type", "application/grpc-web+proto")
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, code, w.Code, origin)
+	}
+}
+
+func TestUnsupportedContentType(t *testing.T) {
+	h := newTestHandler(WithoutConnect())
+
+	r := httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Check", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
@@ -0,0 +1,37 @@
+package grpcweb
+
+type Option func(*Handler)
+
+// WithOriginFunc decides which cross-origin requests are allowed, only the
+// same-origin requests are allowed by default.
+func WithOriginFunc(f func(origin string) bool) Option {
+	return func(h *Handler) {
+		h.originFunc = f
+	}
+}
+
+// WithAllowAllOrigins allows the requests of every origin.
+func WithAllowAllOrigins() Option {
+	return WithOriginFunc(func(string) bool { return true })
+}
+
+// WithAllowedHeaders extends the request headers accepted on CORS preflight.
+func WithAllowedHeaders(headers ...string) Option {
+	return func(h *Handler) {
+		h.allowedHeaders = append(h.allowedHeaders, headers...)
+	}
+}
+
+// WithExposedHeaders extends the response headers readable by browser clients.
+func WithExposedHeaders(headers ...string) Option {
+	return func(h *Handler) {
+		h.exposedHeaders = append(h.exposedHeaders, headers...)
+	}
+}
+
+// WithoutConnect only serves gRPC-Web requests.
+func WithoutConnect() Option {
+	return func(h *Handler) {
+		h.disableConnect = true
+	}
+}
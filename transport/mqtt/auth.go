@@ -0,0 +1,35 @@
+package mqtt
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/auth"
+)
+
+// authHandler authenticate the messages before handler. The server is a
+// client of the mqtt broker, which authenticates the connections itself, so
+// the credentials are taken from the headers of each message.
+func (s *Server) authHandler(handler broker.Handler) broker.Handler {
+	if s.authenticator == nil {
+		return handler
+	}
+
+	return func(ctx context.Context, event broker.Event) error {
+		req := &auth.Request{Header: http.Header{}}
+		if m := event.Message(); m != nil {
+			for k, v := range m.Headers {
+				req.Header.Set(k, v)
+			}
+		}
+
+		id, err := s.authenticator.Authenticate(ctx, req)
+		if err != nil {
+			LogErrorf("authenticate message of [%s] failed: %s", event.Topic(), err)
+			return err
+		}
+
+		return handler(auth.NewContext(ctx, id), event)
+	}
+}
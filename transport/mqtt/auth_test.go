@@ -0,0 +1,37 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/auth"
+)
+
+type testEvent struct {
+	broker.Event
+	msg *broker.Message
+}
+
+func (e *testEvent) Topic() string            { return "test" }
+func (e *testEvent) Message() *broker.Message { return e.msg }
+
+func TestAuthHandler(t *testing.T) {
+	s := &Server{authenticator: auth.APIKey("X-Api-Key", map[string]string{"k1": "device-1"})}
+
+	var subject string
+	h := s.authHandler(func(ctx context.Context, _ broker.Event) error {
+		id, _ := auth.FromContext(ctx)
+		subject = id.Subject
+		return nil
+	})
+
+	err := h(context.Background(), &testEvent{msg: &broker.Message{Headers: broker.Headers{"x-api-key": "k1"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "device-1", subject)
+
+	err = h(context.Background(), &testEvent{msg: &broker.Message{}})
+	assert.ErrorIs(t, err, auth.ErrMissingCredentials)
+}
@@ -3,8 +3,11 @@ package mqtt
 import (
 	"crypto/tls"
 
+	"github.com/go-kratos/kratos/v2/middleware"
+
 	"github.com/tx7do/kratos-transport/broker"
 	"github.com/tx7do/kratos-transport/broker/mqtt"
+	"github.com/tx7do/kratos-transport/transport/auth"
 )
 
 type ServerOption func(o *Server)
@@ -31,6 +34,15 @@ func WithTLSConfig(c *tls.Config) ServerOption {
 	}
 }
 
+// WithAuthenticator authenticate the received messages by their headers, the
+// identity is carried by the context of the handler. Rejected messages are
+// not handled.
+func WithAuthenticator(a auth.Authenticator) ServerOption {
+	return func(s *Server) {
+		s.authenticator = a
+	}
+}
+
 // WithEnableKeepAlive enable keep alive
 func WithEnableKeepAlive(enable bool) ServerOption {
 	return func(s *Server) {
@@ -61,3 +73,10 @@ func WithCodec(c string) ServerOption {
 		s.brokerOpts = append(s.brokerOpts, broker.WithCodec(c))
 	}
 }
+
+// WithMiddleware 订阅者处理函数的kratos中间件，与HTTP、gRPC服务共用，如recovery、ratelimit、metrics、validate
+func WithMiddleware(m ...middleware.Middleware) ServerOption {
+	return func(s *Server) {
+		s.middlewares = append(s.middlewares, m...)
+	}
+}
@@ -6,16 +6,19 @@ import (
 	"net/url"
 	"sync"
 
+	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/transport"
 
 	"github.com/tx7do/kratos-transport/broker"
 	"github.com/tx7do/kratos-transport/broker/mqtt"
+	"github.com/tx7do/kratos-transport/transport/auth"
 	"github.com/tx7do/kratos-transport/utils"
 )
 
 var (
 	_ transport.Server     = (*Server)(nil)
 	_ transport.Endpointer = (*Server)(nil)
+	_ broker.Drainer       = (*Server)(nil)
 )
 
 type SubscriberMap map[string]broker.Subscriber
@@ -33,6 +36,8 @@ type Server struct {
 
 	subscribers    SubscriberMap
 	subscriberOpts SubscribeOptionMap
+	handlers       broker.HandlerTracker
+	middlewares    []middleware.Middleware
 
 	sync.RWMutex
 	started bool
@@ -42,6 +47,8 @@ type Server struct {
 
 	keepAlive       *utils.KeepAliveService
 	enableKeepAlive bool
+
+	authenticator auth.Authenticator
 }
 
 func NewServer(opts ...ServerOption) *Server {
@@ -125,6 +132,22 @@ func (s *Server) Stop(_ context.Context) error {
 	return s.Disconnect()
 }
 
+// Drain stop fetching and wait until the running handlers return or ctx is
+// done, for a preStop hook, e.g. kratos.BeforeStop(broker.DrainHook(srv, timeout)).
+func (s *Server) Drain(ctx context.Context, progress chan<- broker.DrainProgress) error {
+	s.Lock()
+	subscribers := s.subscribers
+	s.subscribers = SubscriberMap{}
+	s.Unlock()
+
+	s.handlers.Stop()
+	for _, sub := range subscribers {
+		_ = sub.Unsubscribe(true)
+	}
+
+	return s.handlers.Drain(ctx, progress)
+}
+
 func (s *Server) RegisterSubscriber(ctx context.Context, topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) error {
 	s.Lock()
 	defer s.Unlock()
@@ -162,7 +185,7 @@ func RegisterSubscriber[T any](srv *Server, ctx context.Context, topic string, h
 }
 
 func (s *Server) doRegisterSubscriber(topic string, handler broker.Handler, binder broker.Binder, opts ...broker.SubscribeOption) error {
-	sub, err := s.Subscribe(topic, handler, binder, opts...)
+	sub, err := s.Subscribe(topic, s.handlers.Track(broker.MiddlewareHandler(KindMQTT, topic, s.authHandler(handler), s.middlewares...)), binder, opts...)
 	if err != nil {
 		return err
 	}
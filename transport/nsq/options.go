@@ -3,6 +3,8 @@ package nsq
 import (
 	"crypto/tls"
 
+	"github.com/go-kratos/kratos/v2/middleware"
+
 	"github.com/tx7do/kratos-transport/broker"
 	"github.com/tx7do/kratos-transport/broker/nsq"
 )
@@ -55,3 +57,10 @@ func WithConsumerOptions(opts []string) ServerOption {
 		s.brokerOpts = append(s.brokerOpts, nsq.WithConsumerOptions(opts))
 	}
 }
+
+// WithMiddleware 订阅者处理函数的kratos中间件，与HTTP、gRPC服务共用，如recovery、ratelimit、metrics、validate
+func WithMiddleware(m ...middleware.Middleware) ServerOption {
+	return func(s *Server) {
+		s.middlewares = append(s.middlewares, m...)
+	}
+}
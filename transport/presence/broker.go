@@ -0,0 +1,103 @@
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+var ErrInvalidRelayMessage = errors.New("presence: invalid relay message")
+
+// relayMessage is the payload published to the control topic.
+type relayMessage struct {
+	Node     string    `json:"node,omitempty"`
+	Envelope *Envelope `json:"envelope"`
+}
+
+// BrokerRelay moves envelopes between nodes over one control topic of a
+// broker. Every node must receive every message of the topic, so the
+// subscribe options must not put the nodes in one queue group.
+type BrokerRelay struct {
+	b             broker.Broker
+	topic         string
+	subscribeOpts []broker.SubscribeOption
+}
+
+func NewBrokerRelay(b broker.Broker, topic string, opts ...broker.SubscribeOption) *BrokerRelay {
+	return &BrokerRelay{
+		b:             b,
+		topic:         topic,
+		subscribeOpts: opts,
+	}
+}
+
+func (r *BrokerRelay) Publish(ctx context.Context, node string, env *Envelope) error {
+	buf, err := json.Marshal(&relayMessage{Node: node, Envelope: env})
+	if err != nil {
+		return err
+	}
+	return r.b.Publish(ctx, r.topic, buf)
+}
+
+func (r *BrokerRelay) Subscribe(node string, handler func(ctx context.Context, env *Envelope)) (func() error, error) {
+	sub, err := r.b.Subscribe(r.topic,
+		func(ctx context.Context, event broker.Event) error {
+			msg, err := decodeRelayMessage(event.Message())
+			if err != nil {
+				return err
+			}
+			if msg.Node != "" && msg.Node != node {
+				return nil
+			}
+			handler(ctx, msg.Envelope)
+			return nil
+		},
+		nil,
+		r.subscribeOpts...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return sub.Unsubscribe(true)
+	}, nil
+}
+
+func decodeRelayMessage(m *broker.Message) (*relayMessage, error) {
+	if m == nil {
+		return nil, ErrInvalidRelayMessage
+	}
+
+	var buf []byte
+	switch body := m.Body.(type) {
+	case []byte:
+		buf = body
+	case string:
+		buf = []byte(body)
+	case json.RawMessage:
+		buf = body
+	default:
+		return nil, ErrInvalidRelayMessage
+	}
+
+	// a broker with a json codec publishes the bytes as a base64 string
+	if len(buf) > 0 && buf[0] == '"' {
+		var raw []byte
+		if err := json.Unmarshal(buf, &raw); err != nil {
+			return nil, err
+		}
+		buf = raw
+	}
+
+	var msg relayMessage
+	if err := json.Unmarshal(buf, &msg); err != nil {
+		return nil, err
+	}
+	if msg.Envelope == nil {
+		return nil, ErrInvalidRelayMessage
+	}
+	return &msg, nil
+}
@@ -0,0 +1,128 @@
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type testSubscriber struct {
+	broker.Subscriber
+	b  *testBroker
+	id int
+}
+
+func (s *testSubscriber) Unsubscribe(bool) error {
+	s.b.Lock()
+	defer s.b.Unlock()
+	delete(s.b.handlers, s.id)
+	return nil
+}
+
+// testBroker delivers every message to every subscriber, and twice when
+// redeliver is set.
+type testBroker struct {
+	broker.Broker
+	sync.Mutex
+
+	handlers  map[int]broker.Handler
+	next      int
+	redeliver bool
+}
+
+func (b *testBroker) Publish(ctx context.Context, topic string, msg broker.Any, _ ...broker.PublishOption) error {
+	b.Lock()
+	var handlers []broker.Handler
+	for _, h := range b.handlers {
+		handlers = append(handlers, h)
+	}
+	times := 1
+	if b.redeliver {
+		times = 2
+	}
+	b.Unlock()
+
+	for i := 0; i < times; i++ {
+		for _, h := range handlers {
+			if err := h(ctx, &testEvent{topic: topic, msg: &broker.Message{Body: msg}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *testBroker) Subscribe(_ string, handler broker.Handler, _ broker.Binder, _ ...broker.SubscribeOption) (broker.Subscriber, error) {
+	b.Lock()
+	defer b.Unlock()
+	b.next++
+	b.handlers[b.next] = handler
+	return &testSubscriber{b: b, id: b.next}, nil
+}
+
+type testEvent struct {
+	broker.Event
+	topic string
+	msg   *broker.Message
+}
+
+func (e *testEvent) Topic() string            { return e.topic }
+func (e *testEvent) Message() *broker.Message { return e.msg }
+
+func TestBrokerRelay_Fanout(t *testing.T) {
+	ctx := context.Background()
+	box := &inbox{msgs: map[string][]string{}}
+	b := &testBroker{handlers: map[int]broker.Handler{}, redeliver: true}
+
+	m1 := NewManager(WithNode("n1"), WithFanout(true), WithRelay(NewBrokerRelay(b, "presence")))
+	m2 := NewManager(WithNode("n2"), WithFanout(true), WithRelay(NewBrokerRelay(b, "presence")))
+	assert.Nil(t, m1.Start())
+	assert.Nil(t, m2.Start())
+
+	assert.Nil(t, m1.Register(ctx, &Session{ID: "s1"}, box.sender("s1")))
+	assert.Nil(t, m2.Register(ctx, &Session{ID: "s2"}, box.sender("s2")))
+	assert.Nil(t, m2.Register(ctx, &Session{ID: "s3"}, box.sender("s3")))
+	assert.Nil(t, m1.Join(ctx, "s1", "lobby"))
+	assert.Nil(t, m2.Join(ctx, "s2", "lobby"))
+	assert.Nil(t, m2.Bind(ctx, "s3", "alice"))
+
+	assert.Nil(t, m1.SendToRoom(ctx, "lobby", &Message{Data: []byte("room")}))
+	assert.Nil(t, m1.SendToUser(ctx, "alice", &Message{Data: []byte("dm")}))
+	assert.Nil(t, m1.SendToSession(ctx, "s2", &Message{Data: []byte("direct")}))
+	assert.Nil(t, m2.Broadcast(ctx, &Message{Data: []byte("all")}))
+
+	assert.Equal(t, []string{"room", "all"}, box.msgs["s1"])
+	assert.Equal(t, []string{"room", "direct", "all"}, box.msgs["s2"])
+	assert.Equal(t, []string{"dm", "all"}, box.msgs["s3"])
+
+	assert.Nil(t, m1.Stop(ctx))
+	assert.Nil(t, m2.Stop(ctx))
+	assert.Empty(t, b.handlers)
+}
+
+func TestDecodeRelayMessage(t *testing.T) {
+	buf, err := json.Marshal(&relayMessage{Node: "n1", Envelope: &Envelope{Room: "lobby", Message: &Message{Data: []byte("hi")}}})
+	assert.Nil(t, err)
+
+	// the body of a broker with a json codec
+	quoted, err := json.Marshal(buf)
+	assert.Nil(t, err)
+
+	for _, body := range []broker.Any{buf, string(buf), quoted} {
+		msg, err := decodeRelayMessage(&broker.Message{Body: body})
+		assert.Nil(t, err)
+		assert.Equal(t, "n1", msg.Node)
+		assert.Equal(t, "lobby", msg.Envelope.Room)
+		assert.Equal(t, "hi", string(msg.Envelope.Message.Data))
+	}
+
+	_, err = decodeRelayMessage(&broker.Message{Body: 1})
+	assert.ErrorIs(t, err, ErrInvalidRelayMessage)
+	_, err = decodeRelayMessage(&broker.Message{Body: []byte(`{"node":"n1"}`)})
+	assert.ErrorIs(t, err, ErrInvalidRelayMessage)
+}
@@ -0,0 +1,406 @@
+package presence
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/google/uuid"
+)
+
+type Option func(m *Manager)
+
+// WithNode set the id of this server instance, default is a random uuid.
+func WithNode(node string) Option {
+	return func(m *Manager) {
+		m.node = node
+	}
+}
+
+// WithStore set the session store, default is a MemoryStore.
+func WithStore(store Store) Option {
+	return func(m *Manager) {
+		m.store = store
+	}
+}
+
+// WithRelay deliver messages to sessions connected to other nodes.
+func WithRelay(relay Relay) Option {
+	return func(m *Manager) {
+		m.relay = relay
+	}
+}
+
+// WithFanout send the messages to rooms and users to every node, which
+// resolves them against its own sessions. Use it when the store of each node
+// only knows its own sessions, as MemoryStore does; the queries then only
+// cover the sessions of this node.
+func WithFanout(enable bool) Option {
+	return func(m *Manager) {
+		m.fanout = enable
+	}
+}
+
+// WithDedupWindow set how long the ids of received envelopes are remembered
+// to drop redelivered ones, default is 1 minute.
+func WithDedupWindow(window time.Duration) Option {
+	return func(m *Manager) {
+		m.dedupWindow = window
+	}
+}
+
+// Manager tracks the sessions of the realtime servers of this node in a
+// Store shared by all nodes, and delivers messages to sessions wherever they
+// are connected: directly when local, through the Relay otherwise.
+type Manager struct {
+	mtx sync.RWMutex
+
+	node   string
+	store  Store
+	relay  Relay
+	fanout bool
+
+	local map[string]Sender
+	stop  func() error
+
+	dedupMtx    sync.Mutex
+	dedupWindow time.Duration
+	seen        map[string]time.Time
+	pruned      time.Time
+}
+
+func NewManager(opts ...Option) *Manager {
+	m := &Manager{
+		node:        uuid.New().String(),
+		local:       make(map[string]Sender),
+		dedupWindow: time.Minute,
+		seen:        make(map[string]time.Time),
+	}
+
+	for _, o := range opts {
+		o(m)
+	}
+
+	if m.store == nil {
+		m.store = NewMemoryStore()
+	}
+
+	return m
+}
+
+func (m *Manager) Node() string {
+	return m.node
+}
+
+func (m *Manager) Store() Store {
+	return m.store
+}
+
+// Start receive the messages relayed to this node.
+func (m *Manager) Start() error {
+	if m.relay == nil {
+		return nil
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.stop != nil {
+		return nil
+	}
+
+	stop, err := m.relay.Subscribe(m.node, m.onEnvelope)
+	if err != nil {
+		return err
+	}
+	m.stop = stop
+
+	return nil
+}
+
+// Stop unsubscribe from the relay and remove the local sessions from the store.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mtx.Lock()
+	stop := m.stop
+	m.stop = nil
+	ids := make([]string, 0, len(m.local))
+	for id := range m.local {
+		ids = append(ids, id)
+	}
+	m.local = make(map[string]Sender)
+	m.mtx.Unlock()
+
+	var err error
+	if stop != nil {
+		err = stop()
+	}
+	for _, id := range ids {
+		if e := m.store.Remove(ctx, id); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Register add a session connected to this node.
+func (m *Manager) Register(ctx context.Context, s *Session, sender Sender) error {
+	s.Node = m.node
+	if s.ConnectedAt.IsZero() {
+		s.ConnectedAt = time.Now()
+	}
+
+	m.mtx.Lock()
+	m.local[s.ID] = sender
+	m.mtx.Unlock()
+
+	return m.store.Add(ctx, s)
+}
+
+// Unregister remove a session connected to this node.
+func (m *Manager) Unregister(ctx context.Context, id string) error {
+	m.mtx.Lock()
+	delete(m.local, id)
+	m.mtx.Unlock()
+
+	return m.store.Remove(ctx, id)
+}
+
+func (m *Manager) Get(ctx context.Context, id string) (*Session, error) {
+	return m.store.Get(ctx, id)
+}
+
+// Bind associate a session with a user.
+func (m *Manager) Bind(ctx context.Context, id, userID string) error {
+	return m.store.Bind(ctx, id, userID)
+}
+
+func (m *Manager) Join(ctx context.Context, id, room string) error {
+	return m.store.Join(ctx, id, room)
+}
+
+func (m *Manager) Leave(ctx context.Context, id, room string) error {
+	return m.store.Leave(ctx, id, room)
+}
+
+func (m *Manager) Rooms(ctx context.Context, id string) ([]string, error) {
+	return m.store.Rooms(ctx, id)
+}
+
+func (m *Manager) RoomSessions(ctx context.Context, room string) ([]*Session, error) {
+	return m.store.RoomSessions(ctx, room)
+}
+
+func (m *Manager) UserSessions(ctx context.Context, userID string) ([]*Session, error) {
+	return m.store.UserSessions(ctx, userID)
+}
+
+// IsOnline report whether a user has a session on any node.
+func (m *Manager) IsOnline(ctx context.Context, userID string) (bool, error) {
+	sessions, err := m.store.UserSessions(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return len(sessions) > 0, nil
+}
+
+// LocalCount return the number of sessions connected to this node.
+func (m *Manager) LocalCount() int {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return len(m.local)
+}
+
+func (m *Manager) SendToSession(ctx context.Context, id string, msg *Message) error {
+	s, err := m.store.Get(ctx, id)
+	if err == nil {
+		return m.send(ctx, []*Session{s}, msg)
+	}
+	if !m.fanout || err != ErrSessionNotFound {
+		return err
+	}
+	return m.publish(ctx, "", &Envelope{Sessions: []string{id}, Message: msg})
+}
+
+func (m *Manager) SendToUser(ctx context.Context, userID string, msg *Message) error {
+	if m.fanout {
+		return m.sendFanout(ctx, &Envelope{User: userID, Message: msg})
+	}
+
+	sessions, err := m.store.UserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return m.send(ctx, sessions, msg)
+}
+
+func (m *Manager) SendToRoom(ctx context.Context, room string, msg *Message) error {
+	if m.fanout {
+		return m.sendFanout(ctx, &Envelope{Room: room, Message: msg})
+	}
+
+	sessions, err := m.store.RoomSessions(ctx, room)
+	if err != nil {
+		return err
+	}
+	return m.send(ctx, sessions, msg)
+}
+
+// Broadcast send msg to every session of every node.
+func (m *Manager) Broadcast(ctx context.Context, msg *Message) error {
+	m.deliverAll(ctx, msg)
+	return m.publish(ctx, "", &Envelope{Message: msg})
+}
+
+func (m *Manager) send(ctx context.Context, sessions []*Session, msg *Message) error {
+	remote := make(map[string][]string)
+	var local []string
+	for _, s := range sessions {
+		if s.Node == m.node {
+			local = append(local, s.ID)
+		} else {
+			remote[s.Node] = append(remote[s.Node], s.ID)
+		}
+	}
+
+	m.deliver(ctx, local, msg)
+
+	if len(remote) == 0 {
+		return nil
+	}
+	if m.relay == nil {
+		log.Warnf("[presence] no relay configured, drop message to %d remote nodes", len(remote))
+		return nil
+	}
+
+	var err error
+	for node, ids := range remote {
+		if e := m.publish(ctx, node, &Envelope{Sessions: ids, Message: msg}); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// sendFanout deliver env to the sessions of this node and publish it to the
+// other nodes, which do the same.
+func (m *Manager) sendFanout(ctx context.Context, env *Envelope) error {
+	if err := m.deliverEnvelope(ctx, env); err != nil {
+		return err
+	}
+	return m.publish(ctx, "", env)
+}
+
+// publish tag env with this node and a unique id, and hand it to the relay.
+func (m *Manager) publish(ctx context.Context, node string, env *Envelope) error {
+	if m.relay == nil {
+		return nil
+	}
+
+	env.ID = uuid.New().String()
+	env.Origin = m.node
+	return m.relay.Publish(ctx, node, env)
+}
+
+func (m *Manager) deliver(ctx context.Context, ids []string, msg *Message) {
+	for _, id := range ids {
+		m.mtx.RLock()
+		sender, ok := m.local[id]
+		m.mtx.RUnlock()
+		if !ok {
+			continue
+		}
+		if err := sender(ctx, msg); err != nil {
+			log.Errorf("[presence] send to session [%s] failed: %v", id, err)
+		}
+	}
+}
+
+func (m *Manager) deliverAll(ctx context.Context, msg *Message) {
+	m.mtx.RLock()
+	senders := make(map[string]Sender, len(m.local))
+	for id, sender := range m.local {
+		senders[id] = sender
+	}
+	m.mtx.RUnlock()
+
+	for id, sender := range senders {
+		if err := sender(ctx, msg); err != nil {
+			log.Errorf("[presence] send to session [%s] failed: %v", id, err)
+		}
+	}
+}
+
+func (m *Manager) deliverEnvelope(ctx context.Context, env *Envelope) error {
+	var (
+		sessions []*Session
+		err      error
+	)
+	switch {
+	case env.Room != "":
+		sessions, err = m.store.RoomSessions(ctx, env.Room)
+	case env.User != "":
+		sessions, err = m.store.UserSessions(ctx, env.User)
+	case len(env.Sessions) > 0:
+		m.deliver(ctx, env.Sessions, env.Message)
+		return nil
+	default:
+		m.deliverAll(ctx, env.Message)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		if s.Node == m.node {
+			ids = append(ids, s.ID)
+		}
+	}
+	m.deliver(ctx, ids, env.Message)
+	return nil
+}
+
+func (m *Manager) onEnvelope(ctx context.Context, env *Envelope) {
+	if env == nil || env.Message == nil {
+		return
+	}
+	// the origin already delivered to its own sessions
+	if env.Origin == m.node && len(env.Sessions) == 0 {
+		return
+	}
+	if m.duplicate(env.ID) {
+		return
+	}
+
+	if err := m.deliverEnvelope(ctx, env); err != nil {
+		log.Errorf("[presence] deliver envelope [%s] from [%s] failed: %v", env.ID, env.Origin, err)
+	}
+}
+
+// duplicate report whether an envelope with id was received within the dedup window.
+func (m *Manager) duplicate(id string) bool {
+	if id == "" || m.dedupWindow <= 0 {
+		return false
+	}
+
+	m.dedupMtx.Lock()
+	defer m.dedupMtx.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.pruned) > m.dedupWindow {
+		for k, t := range m.seen {
+			if now.Sub(t) > m.dedupWindow {
+				delete(m.seen, k)
+			}
+		}
+		m.pruned = now
+	}
+
+	if t, ok := m.seen[id]; ok && now.Sub(t) <= m.dedupWindow {
+		return true
+	}
+	m.seen[id] = now
+	return false
+}
@@ -0,0 +1,117 @@
+package presence
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryRelay connects the managers of one process.
+type memoryRelay struct {
+	mtx      sync.Mutex
+	handlers map[string]func(ctx context.Context, env *Envelope)
+}
+
+func (r *memoryRelay) Publish(ctx context.Context, node string, env *Envelope) error {
+	r.mtx.Lock()
+	var targets []func(ctx context.Context, env *Envelope)
+	for n, h := range r.handlers {
+		if node == "" || n == node {
+			targets = append(targets, h)
+		}
+	}
+	r.mtx.Unlock()
+
+	for _, h := range targets {
+		h(ctx, env)
+	}
+	return nil
+}
+
+func (r *memoryRelay) Subscribe(node string, handler func(ctx context.Context, env *Envelope)) (func() error, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.handlers[node] = handler
+	return func() error {
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+		delete(r.handlers, node)
+		return nil
+	}, nil
+}
+
+type inbox struct {
+	mtx  sync.Mutex
+	msgs map[string][]string
+}
+
+func (i *inbox) sender(id string) Sender {
+	return func(_ context.Context, msg *Message) error {
+		i.mtx.Lock()
+		defer i.mtx.Unlock()
+		i.msgs[id] = append(i.msgs[id], string(msg.Data))
+		return nil
+	}
+}
+
+func TestManager_Rooms(t *testing.T) {
+	ctx := context.Background()
+	box := &inbox{msgs: map[string][]string{}}
+
+	m := NewManager(WithNode("n1"))
+	assert.Nil(t, m.Register(ctx, &Session{ID: "s1"}, box.sender("s1")))
+	assert.Nil(t, m.Register(ctx, &Session{ID: "s2"}, box.sender("s2")))
+
+	assert.Nil(t, m.Bind(ctx, "s1", "alice"))
+	assert.Nil(t, m.Join(ctx, "s1", "lobby"))
+	assert.Nil(t, m.Join(ctx, "s2", "lobby"))
+	assert.ErrorIs(t, m.Join(ctx, "s3", "lobby"), ErrSessionNotFound)
+
+	online, err := m.IsOnline(ctx, "alice")
+	assert.Nil(t, err)
+	assert.True(t, online)
+
+	assert.Nil(t, m.SendToRoom(ctx, "lobby", &Message{Data: []byte("hi")}))
+	assert.Nil(t, m.SendToUser(ctx, "alice", &Message{Data: []byte("dm")}))
+	assert.Equal(t, []string{"hi", "dm"}, box.msgs["s1"])
+	assert.Equal(t, []string{"hi"}, box.msgs["s2"])
+
+	assert.Nil(t, m.Unregister(ctx, "s1"))
+	online, _ = m.IsOnline(ctx, "alice")
+	assert.False(t, online)
+	sessions, _ := m.RoomSessions(ctx, "lobby")
+	assert.Equal(t, 1, len(sessions))
+	rooms, _ := m.Rooms(ctx, "s1")
+	assert.Empty(t, rooms)
+}
+
+func TestManager_Relay(t *testing.T) {
+	ctx := context.Background()
+	box := &inbox{msgs: map[string][]string{}}
+
+	store := NewMemoryStore()
+	relay := &memoryRelay{handlers: map[string]func(ctx context.Context, env *Envelope){}}
+
+	m1 := NewManager(WithNode("n1"), WithStore(store), WithRelay(relay))
+	m2 := NewManager(WithNode("n2"), WithStore(store), WithRelay(relay))
+	assert.Nil(t, m1.Start())
+	assert.Nil(t, m2.Start())
+
+	assert.Nil(t, m1.Register(ctx, &Session{ID: "s1"}, box.sender("s1")))
+	assert.Nil(t, m2.Register(ctx, &Session{ID: "s2"}, box.sender("s2")))
+	assert.Nil(t, m1.Join(ctx, "s1", "lobby"))
+	assert.Nil(t, m2.Join(ctx, "s2", "lobby"))
+
+	assert.Nil(t, m1.SendToRoom(ctx, "lobby", &Message{Data: []byte("room")}))
+	assert.Nil(t, m2.Broadcast(ctx, &Message{Data: []byte("all")}))
+
+	assert.Equal(t, []string{"room", "all"}, box.msgs["s1"])
+	assert.Equal(t, []string{"room", "all"}, box.msgs["s2"])
+
+	assert.Nil(t, m2.Stop(ctx))
+	_, err := store.Get(ctx, "s2")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.Equal(t, 1, len(relay.handlers))
+}
@@ -0,0 +1,169 @@
+package presence
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore keeps the sessions of a single node in memory.
+type MemoryStore struct {
+	mtx sync.RWMutex
+
+	sessions map[string]*Session
+	rooms    map[string]map[string]struct{}
+	joined   map[string]map[string]struct{}
+	users    map[string]map[string]struct{}
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions: make(map[string]*Session),
+		rooms:    make(map[string]map[string]struct{}),
+		joined:   make(map[string]map[string]struct{}),
+		users:    make(map[string]map[string]struct{}),
+	}
+}
+
+func (m *MemoryStore) Add(_ context.Context, s *Session) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	c := *s
+	m.sessions[s.ID] = &c
+	if s.UserID != "" {
+		addMember(m.users, s.UserID, s.ID)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Remove(_ context.Context, id string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil
+	}
+	delete(m.sessions, id)
+
+	if s.UserID != "" {
+		removeMember(m.users, s.UserID, id)
+	}
+	for room := range m.joined[id] {
+		removeMember(m.rooms, room, id)
+	}
+	delete(m.joined, id)
+
+	return nil
+}
+
+func (m *MemoryStore) Get(_ context.Context, id string) (*Session, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	c := *s
+	return &c, nil
+}
+
+func (m *MemoryStore) Bind(_ context.Context, id, userID string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if s.UserID != "" {
+		removeMember(m.users, s.UserID, id)
+	}
+	s.UserID = userID
+	if userID != "" {
+		addMember(m.users, userID, id)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Join(_ context.Context, id, room string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.sessions[id]; !ok {
+		return ErrSessionNotFound
+	}
+	addMember(m.rooms, room, id)
+	addMember(m.joined, id, room)
+	return nil
+}
+
+func (m *MemoryStore) Leave(_ context.Context, id, room string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	removeMember(m.rooms, room, id)
+	removeMember(m.joined, id, room)
+	return nil
+}
+
+func (m *MemoryStore) Rooms(_ context.Context, id string) ([]string, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return members(m.joined[id]), nil
+}
+
+func (m *MemoryStore) RoomSessions(_ context.Context, room string) ([]*Session, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.sessionsOf(m.rooms[room]), nil
+}
+
+func (m *MemoryStore) UserSessions(_ context.Context, userID string) ([]*Session, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.sessionsOf(m.users[userID]), nil
+}
+
+func (m *MemoryStore) sessionsOf(ids map[string]struct{}) []*Session {
+	sessions := make([]*Session, 0, len(ids))
+	for id := range ids {
+		if s, ok := m.sessions[id]; ok {
+			c := *s
+			sessions = append(sessions, &c)
+		}
+	}
+	return sessions
+}
+
+func addMember(sets map[string]map[string]struct{}, key, member string) {
+	set, ok := sets[key]
+	if !ok {
+		set = make(map[string]struct{})
+		sets[key] = set
+	}
+	set[member] = struct{}{}
+}
+
+func removeMember(sets map[string]map[string]struct{}, key, member string) {
+	set, ok := sets[key]
+	if !ok {
+		return
+	}
+	delete(set, member)
+	if len(set) == 0 {
+		delete(sets, key)
+	}
+}
+
+func members(set map[string]struct{}) []string {
+	list := make([]string, 0, len(set))
+	for k := range set {
+		list = append(list, k)
+	}
+	return list
+}
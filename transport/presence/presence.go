@@ -0,0 +1,69 @@
+package presence
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrSessionNotFound = errors.New("presence: session not found")
+	ErrNotLocalSession = errors.New("presence: session is not connected to this node")
+)
+
+// Session is a client connection of a realtime server.
+type Session struct {
+	ID          string            `json:"id"`
+	Node        string            `json:"node"`
+	UserID      string            `json:"user_id,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	ConnectedAt time.Time         `json:"connected_at"`
+}
+
+// Message is delivered to sessions. Event names the message for transports
+// that have one (sse, socket.io), Data is sent as is.
+type Message struct {
+	Event string `json:"event,omitempty"`
+	Data  []byte `json:"data"`
+}
+
+// Sender writes a message to a session connected to this node.
+type Sender func(ctx context.Context, msg *Message) error
+
+// Store keeps the sessions, user bindings and rooms of all nodes.
+type Store interface {
+	Add(ctx context.Context, s *Session) error
+	Remove(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (*Session, error)
+
+	Bind(ctx context.Context, id, userID string) error
+
+	Join(ctx context.Context, id, room string) error
+	Leave(ctx context.Context, id, room string) error
+	Rooms(ctx context.Context, id string) ([]string, error)
+
+	RoomSessions(ctx context.Context, room string) ([]*Session, error)
+	UserSessions(ctx context.Context, userID string) ([]*Session, error)
+}
+
+// Envelope carries a message to the sessions of another node. An envelope
+// with a room or a user is resolved by the receiving node against its own
+// sessions, one with sessions is delivered to those of them connected to the
+// node, any other is a broadcast to every session of the node.
+type Envelope struct {
+	ID       string   `json:"id,omitempty"`
+	Origin   string   `json:"origin"`
+	Room     string   `json:"room,omitempty"`
+	User     string   `json:"user,omitempty"`
+	Sessions []string `json:"sessions,omitempty"`
+	Message  *Message `json:"message"`
+}
+
+// Relay moves envelopes between nodes.
+type Relay interface {
+	// Publish send env to node, or to every node when node is empty.
+	Publish(ctx context.Context, node string, env *Envelope) error
+	// Subscribe receive the envelopes sent to node and to every node until
+	// the returned function is called.
+	Subscribe(node string, handler func(ctx context.Context, env *Envelope)) (func() error, error)
+}
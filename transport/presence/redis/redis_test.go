@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/internal/redistest"
+	"github.com/tx7do/kratos-transport/transport/presence"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(redistest.NewPool(t), WithPrefix("presence-test:"))
+
+	assert.Nil(t, store.Add(ctx, &presence.Session{ID: "s1", Node: "n1"}))
+	defer store.Remove(ctx, "s1")
+
+	assert.Nil(t, store.Bind(ctx, "s1", "alice"))
+	assert.Nil(t, store.Join(ctx, "s1", "lobby"))
+
+	sessions, err := store.RoomSessions(ctx, "lobby")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(sessions))
+	assert.Equal(t, "alice", sessions[0].UserID)
+
+	sessions, err = store.UserSessions(ctx, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(sessions))
+
+	assert.Nil(t, store.Remove(ctx, "s1"))
+	sessions, _ = store.RoomSessions(ctx, "lobby")
+	assert.Empty(t, sessions)
+	_, err = store.Get(ctx, "s1")
+	assert.ErrorIs(t, err, presence.ErrSessionNotFound)
+}
+
+func TestRelay(t *testing.T) {
+	relay := NewRelay(redistest.NewPool(t), WithPrefix("presence-test:"))
+
+	received := make(chan *presence.Envelope, 1)
+	stop, err := relay.Subscribe("n1", func(_ context.Context, env *presence.Envelope) {
+		received <- env
+	})
+	assert.Nil(t, err)
+	defer stop()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, relay.Publish(context.Background(), "n1", &presence.Envelope{
+		Origin:   "n2",
+		Sessions: []string{"s1"},
+		Message:  &presence.Message{Data: []byte("hello")},
+	}))
+
+	select {
+	case env := <-received:
+		assert.Equal(t, "hello", string(env.Message.Data))
+	case <-time.After(5 * time.Second):
+		t.Fatal("envelope not received")
+	}
+}
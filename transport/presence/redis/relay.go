@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/tx7do/kratos-transport/transport/presence"
+)
+
+var _ presence.Relay = (*Relay)(nil)
+
+// Relay moves envelopes between nodes over redis pub/sub, every node listens
+// on {prefix}node:{node} and {prefix}broadcast.
+type Relay struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+func NewRelay(pool *redis.Pool, opts ...StoreOption) *Relay {
+	s := NewStore(pool, opts...)
+	return &Relay{
+		pool:   pool,
+		prefix: s.prefix,
+	}
+}
+
+func (r *Relay) channel(node string) string {
+	if node == "" {
+		return r.prefix + "broadcast"
+	}
+	return r.prefix + "node:" + node
+}
+
+func (r *Relay) Publish(ctx context.Context, node string, env *presence.Envelope) error {
+	buf, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "PUBLISH", r.channel(node), buf)
+	return err
+}
+
+func (r *Relay) Subscribe(node string, handler func(ctx context.Context, env *presence.Envelope)) (func() error, error) {
+	psc := redis.PubSubConn{Conn: r.pool.Get()}
+	if err := psc.Subscribe(r.channel(node), r.channel("")); err != nil {
+		_ = psc.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			switch v := psc.Receive().(type) {
+			case redis.Message:
+				var env presence.Envelope
+				if err := json.Unmarshal(v.Data, &env); err != nil {
+					log.Errorf("[presence] decode envelope failed: %v", err)
+					continue
+				}
+				handler(context.Background(), &env)
+			case error:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() error {
+		var err error
+		once.Do(func() {
+			_ = psc.Unsubscribe()
+			err = psc.Close()
+			<-done
+		})
+		return err
+	}, nil
+}
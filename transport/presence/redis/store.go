@@ -0,0 +1,253 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/tx7do/kratos-transport/transport/presence"
+)
+
+const defaultPrefix = "presence:"
+
+var _ presence.Store = (*Store)(nil)
+
+type StoreOption func(s *Store)
+
+// WithPrefix set the prefix of all keys, default is "presence:".
+func WithPrefix(prefix string) StoreOption {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// Store keeps the sessions of all nodes in redis:
+//
+//	{prefix}session:{id}  session as json
+//	{prefix}joined:{id}   set of the rooms of a session
+//	{prefix}room:{room}   set of the sessions in a room
+//	{prefix}user:{user}   set of the sessions of a user
+type Store struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+func NewStore(pool *redis.Pool, opts ...StoreOption) *Store {
+	s := &Store{
+		pool:   pool,
+		prefix: defaultPrefix,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+func (s *Store) sessionKey(id string) string { return s.prefix + "session:" + id }
+func (s *Store) joinedKey(id string) string  { return s.prefix + "joined:" + id }
+func (s *Store) roomKey(room string) string  { return s.prefix + "room:" + room }
+func (s *Store) userKey(user string) string  { return s.prefix + "user:" + user }
+
+func (s *Store) Add(ctx context.Context, session *presence.Session) error {
+	buf, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("SET", s.sessionKey(session.ID), buf)
+	if session.UserID != "" {
+		_ = conn.Send("SADD", s.userKey(session.UserID), session.ID)
+	}
+	_, err = redis.DoContext(conn, ctx, "EXEC")
+	return err
+}
+
+func (s *Store) Remove(ctx context.Context, id string) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	session, err := s.get(ctx, conn, id)
+	if errors.Is(err, presence.ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rooms, err := redis.Strings(redis.DoContext(conn, ctx, "SMEMBERS", s.joinedKey(id)))
+	if err != nil {
+		return err
+	}
+
+	_ = conn.Send("MULTI")
+	for _, room := range rooms {
+		_ = conn.Send("SREM", s.roomKey(room), id)
+	}
+	if session.UserID != "" {
+		_ = conn.Send("SREM", s.userKey(session.UserID), id)
+	}
+	_ = conn.Send("DEL", s.sessionKey(id), s.joinedKey(id))
+	_, err = redis.DoContext(conn, ctx, "EXEC")
+	return err
+}
+
+func (s *Store) Get(ctx context.Context, id string) (*presence.Session, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return s.get(ctx, conn, id)
+}
+
+func (s *Store) get(ctx context.Context, conn redis.Conn, id string) (*presence.Session, error) {
+	buf, err := redis.Bytes(redis.DoContext(conn, ctx, "GET", s.sessionKey(id)))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, presence.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session presence.Session
+	if err = json.Unmarshal(buf, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *Store) Bind(ctx context.Context, id, userID string) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	session, err := s.get(ctx, conn, id)
+	if err != nil {
+		return err
+	}
+
+	previous := session.UserID
+	session.UserID = userID
+
+	buf, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	_ = conn.Send("MULTI")
+	if previous != "" {
+		_ = conn.Send("SREM", s.userKey(previous), id)
+	}
+	if userID != "" {
+		_ = conn.Send("SADD", s.userKey(userID), id)
+	}
+	_ = conn.Send("SET", s.sessionKey(id), buf)
+	_, err = redis.DoContext(conn, ctx, "EXEC")
+	return err
+}
+
+func (s *Store) Join(ctx context.Context, id, room string) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	exists, err := redis.Bool(redis.DoContext(conn, ctx, "EXISTS", s.sessionKey(id)))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return presence.ErrSessionNotFound
+	}
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("SADD", s.roomKey(room), id)
+	_ = conn.Send("SADD", s.joinedKey(id), room)
+	_, err = redis.DoContext(conn, ctx, "EXEC")
+	return err
+}
+
+func (s *Store) Leave(ctx context.Context, id, room string) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_ = conn.Send("MULTI")
+	_ = conn.Send("SREM", s.roomKey(room), id)
+	_ = conn.Send("SREM", s.joinedKey(id), room)
+	_, err = redis.DoContext(conn, ctx, "EXEC")
+	return err
+}
+
+func (s *Store) Rooms(ctx context.Context, id string) ([]string, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return redis.Strings(redis.DoContext(conn, ctx, "SMEMBERS", s.joinedKey(id)))
+}
+
+func (s *Store) RoomSessions(ctx context.Context, room string) ([]*presence.Session, error) {
+	return s.sessionsOf(ctx, s.roomKey(room))
+}
+
+func (s *Store) UserSessions(ctx context.Context, userID string) ([]*presence.Session, error) {
+	return s.sessionsOf(ctx, s.userKey(userID))
+}
+
+func (s *Store) sessionsOf(ctx context.Context, setKey string) ([]*presence.Session, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ids, err := redis.Strings(redis.DoContext(conn, ctx, "SMEMBERS", setKey))
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, s.sessionKey(id))
+	}
+	values, err := redis.ByteSlices(redis.DoContext(conn, ctx, "MGET", args...))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*presence.Session, 0, len(values))
+	for _, buf := range values {
+		if buf == nil {
+			continue
+		}
+		var session presence.Session
+		if err = json.Unmarshal(buf, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+	return sessions, nil
+}
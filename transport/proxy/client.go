@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// client is a websocket subscribed to a topic, its messages are written by
+// writePump so that a slow client never blocks the broker.
+type client struct {
+	conn *ws.Conn
+
+	queue chan []byte
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newClient(conn *ws.Conn, bufferSize int) *client {
+	return &client{
+		conn:  conn,
+		queue: make(chan []byte, bufferSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// send queue data, false when the queue is full.
+func (c *client) send(data []byte) bool {
+	select {
+	case <-c.done:
+		return true
+	case c.queue <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *client) writePump(timeout time.Duration) {
+	for {
+		select {
+		case <-c.done:
+			return
+		case data := <-c.queue:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(timeout))
+			if err := c.conn.WriteMessage(ws.TextMessage, data); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+func (c *client) close() {
+	c.once.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
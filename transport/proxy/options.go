@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/auth"
+)
+
+const (
+	DefaultMaxBodySize  = 1 << 20
+	DefaultHeaderPrefix = "X-Message-"
+	DefaultSendBuffer   = 256
+)
+
+type ServerOption func(o *Server)
+
+func WithNetwork(network string) ServerOption {
+	return func(s *Server) {
+		s.network = network
+	}
+}
+
+func WithAddress(addr string) ServerOption {
+	return func(s *Server) {
+		s.address = addr
+	}
+}
+
+// WithPath set the prefix of the topic routes, default is "/topics".
+func WithPath(path string) ServerOption {
+	return func(s *Server) {
+		s.path = path
+	}
+}
+
+func WithTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.timeout = timeout
+	}
+}
+
+func WithTLSConfig(c *tls.Config) ServerOption {
+	return func(s *Server) {
+		s.tlsConf = c
+	}
+}
+
+func WithListener(lis net.Listener) ServerOption {
+	return func(s *Server) {
+		s.lis = lis
+	}
+}
+
+// WithTopics set the topics exposed by the proxy, in path.Match syntax,
+// e.g. "orders.*". No topic is exposed by default.
+func WithTopics(topics ...string) ServerOption {
+	return func(s *Server) {
+		s.topics = append(s.topics, topics...)
+	}
+}
+
+// WithAuthenticator authenticate every request and websocket handshake.
+func WithAuthenticator(a auth.Authenticator) ServerOption {
+	return func(s *Server) {
+		s.authenticator = a
+	}
+}
+
+// WithAuthorizer check publishes and subscriptions, default allows all
+// the exposed topics. id is nil without authenticator.
+func WithAuthorizer(fn Authorizer) ServerOption {
+	return func(s *Server) {
+		s.authorize = fn
+	}
+}
+
+// WithSubscribeOptions pass options to the broker subscriptions.
+func WithSubscribeOptions(opts ...broker.SubscribeOption) ServerOption {
+	return func(s *Server) {
+		s.subscribeOpts = append(s.subscribeOpts, opts...)
+	}
+}
+
+// WithMaxBodySize set the size limit of the published bodies, default is 1MiB.
+func WithMaxBodySize(size int64) ServerOption {
+	return func(s *Server) {
+		s.maxBodySize = size
+	}
+}
+
+// WithHeaderPrefix set the prefix of the request headers published as
+// message headers, default is "X-Message-".
+func WithHeaderPrefix(prefix string) ServerOption {
+	return func(s *Server) {
+		s.headerPrefix = prefix
+	}
+}
+
+// WithSendBuffer set the messages queued for a slow websocket client before
+// new ones are dropped, default is 256.
+func WithSendBuffer(size int) ServerOption {
+	return func(s *Server) {
+		s.sendBuffer = size
+	}
+}
+
+// WithCheckOrigin set the origin check of the websocket handshakes.
+func WithCheckOrigin(fn func(r *http.Request) bool) ServerOption {
+	return func(s *Server) {
+		s.upgrader.CheckOrigin = fn
+	}
+}
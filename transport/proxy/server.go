@@ -0,0 +1,424 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/gorilla/mux"
+	ws "github.com/gorilla/websocket"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/auth"
+)
+
+const (
+	KindProxy transport.Kind = "proxy"
+)
+
+var (
+	_ transport.Server     = (*Server)(nil)
+	_ transport.Endpointer = (*Server)(nil)
+)
+
+type Action string
+
+const (
+	ActionPublish   Action = "publish"
+	ActionSubscribe Action = "subscribe"
+)
+
+// Authorizer reports whether the client may publish to or subscribe to topic.
+type Authorizer func(id *auth.Identity, action Action, topic string) bool
+
+// Message is the json body of the websocket frames, Topic is only set on
+// the pushed messages.
+type Message struct {
+	Topic   string            `json:"topic,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// Server exposes the topics of a broker to the clients without broker
+// libraries: POST {path}/{topic} publishes the request body, a websocket
+// on GET {path}/{topic} receives the messages of topic and publishes the
+// frames it sends.
+type Server struct {
+	*http.Server
+
+	lis     net.Listener
+	tlsConf *tls.Config
+
+	network string
+	address string
+	path    string
+
+	timeout time.Duration
+
+	err error
+
+	router   *mux.Router
+	upgrader ws.Upgrader
+
+	b             broker.Broker
+	topics        []string
+	authenticator auth.Authenticator
+	authorize     Authorizer
+	subscribeOpts []broker.SubscribeOption
+
+	maxBodySize  int64
+	headerPrefix string
+	sendBuffer   int
+
+	mtx     sync.Mutex
+	subs    map[string]*topicSubscription
+	clients map[*client]struct{}
+}
+
+func NewServer(b broker.Broker, opts ...ServerOption) *Server {
+	srv := &Server{
+		network: "tcp",
+		address: ":0",
+		timeout: 1 * time.Second,
+		path:    "/topics",
+		router:  mux.NewRouter(),
+
+		b: b,
+
+		maxBodySize:  DefaultMaxBodySize,
+		headerPrefix: DefaultHeaderPrefix,
+		sendBuffer:   DefaultSendBuffer,
+
+		subs:    make(map[string]*topicSubscription),
+		clients: make(map[*client]struct{}),
+	}
+
+	srv.init(opts...)
+
+	srv.err = srv.listen()
+
+	return srv
+}
+
+func (s *Server) Name() string {
+	return string(KindProxy)
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.BaseContext = func(net.Listener) context.Context {
+		return ctx
+	}
+	LogInfof("server listening on: %s", s.lis.Addr().String())
+
+	var err error
+	if s.tlsConf != nil {
+		err = s.ServeTLS(s.lis, "", "")
+	} else {
+		err = s.Serve(s.lis)
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	LogInfo("server stopping")
+
+	err := s.Shutdown(ctx)
+
+	// the hijacked websocket connections are not closed by Shutdown.
+	s.mtx.Lock()
+	clients := make([]*client, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mtx.Unlock()
+	for _, c := range clients {
+		c.close()
+	}
+
+	return err
+}
+
+func (s *Server) Endpoint() (*url.URL, error) {
+	addr := s.address
+
+	prefix := "http://"
+	if s.tlsConf != nil {
+		prefix = "https://"
+	}
+	addr = prefix + addr
+
+	var endpoint *url.URL
+	endpoint, s.err = url.Parse(addr)
+	return endpoint, nil
+}
+
+func (s *Server) init(opts ...ServerOption) {
+	for _, o := range opts {
+		o(s)
+	}
+
+	s.path = strings.TrimSuffix(s.path, "/")
+	s.router.HandleFunc(s.path+"/{topic:.+}", s.handlePublish).Methods(http.MethodPost)
+	s.router.HandleFunc(s.path+"/{topic:.+}", s.handleSubscribe).Methods(http.MethodGet)
+
+	s.Server = &http.Server{
+		Handler:   s.router,
+		TLSConfig: s.tlsConf,
+	}
+}
+
+func (s *Server) listen() error {
+	if s.lis == nil {
+		lis, err := net.Listen(s.network, s.address)
+		if err != nil {
+			return err
+		}
+		s.lis = lis
+	}
+
+	return nil
+}
+
+// exposed reports whether topic matches one of the configured topics.
+func (s *Server) exposed(topic string) bool {
+	for _, pattern := range s.topics {
+		if ok, _ := path.Match(pattern, topic); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// admit authenticate the request and check it may do action on its topic,
+// the failures are written to w.
+func (s *Server) admit(w http.ResponseWriter, r *http.Request, action Action) (string, *auth.Identity, bool) {
+	topic := mux.Vars(r)["topic"]
+	if !s.exposed(topic) {
+		http.Error(w, "topic not found", http.StatusNotFound)
+		return "", nil, false
+	}
+
+	var id *auth.Identity
+	if s.authenticator != nil {
+		var err error
+		id, err = s.authenticator.Authenticate(r.Context(), auth.NewHTTPRequest(r))
+		if err != nil {
+			LogErrorf("authenticate [%s] failed: %s", r.RemoteAddr, err)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return "", nil, false
+		}
+	}
+
+	if s.authorize != nil && !s.authorize(id, action, topic) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return "", nil, false
+	}
+
+	return topic, id, true
+}
+
+// messageHeaders return the request headers carrying the prefix, without it.
+func (s *Server) messageHeaders(h http.Header) broker.Headers {
+	headers := broker.Headers{}
+	for k, v := range h {
+		if len(v) == 0 || len(k) <= len(s.headerPrefix) || !strings.EqualFold(k[:len(s.headerPrefix)], s.headerPrefix) {
+			continue
+		}
+		headers[strings.ToLower(k[len(s.headerPrefix):])] = v[0]
+	}
+	return headers
+}
+
+func (s *Server) publish(ctx context.Context, id *auth.Identity, topic string, headers broker.Headers, body []byte) error {
+	if id != nil {
+		ctx = auth.NewContext(ctx, id)
+	}
+	return s.b.Publish(ctx, topic, body, broker.WithHeaders(headers))
+}
+
+func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
+	topic, id, ok := s.admit(w, r, ActionPublish)
+	if !ok {
+		return
+	}
+
+	body, err := readBody(w, r, s.maxBodySize)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	if err = s.publish(r.Context(), id, topic, s.messageHeaders(r.Header), body); err != nil {
+		LogErrorf("publish to [%s] failed: %s", topic, err)
+		http.Error(w, "publish failed", http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	topic, id, ok := s.admit(w, r, ActionSubscribe)
+	if !ok {
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		LogErrorf("upgrade [%s] failed: %s", r.RemoteAddr, err)
+		return
+	}
+	if s.maxBodySize > 0 {
+		conn.SetReadLimit(s.maxBodySize)
+	}
+
+	c := newClient(conn, s.sendBuffer)
+	if err = s.join(topic, c); err != nil {
+		LogErrorf("subscribe to [%s] failed: %s", topic, err)
+		_ = conn.WriteControl(ws.CloseMessage,
+			ws.FormatCloseMessage(ws.CloseInternalServerErr, "subscribe failed"),
+			time.Now().Add(s.timeout))
+		c.close()
+		return
+	}
+
+	go c.writePump(s.timeout)
+	s.readPump(c, id, topic)
+
+	s.leave(topic, c)
+	c.close()
+}
+
+// readPump publish the frames of c to topic until the connection closes.
+func (s *Server) readPump(c *client, id *auth.Identity, topic string) {
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg Message
+		if err = json.Unmarshal(data, &msg); err != nil {
+			LogErrorf("decode frame of [%s] failed: %s", c.conn.RemoteAddr(), err)
+			continue
+		}
+		if err = s.publish(context.Background(), id, topic, msg.Headers, []byte(msg.Body)); err != nil {
+			LogErrorf("publish to [%s] failed: %s", topic, err)
+		}
+	}
+}
+
+// topicSubscription is the broker subscription shared by the clients of a topic.
+type topicSubscription struct {
+	sub     broker.Subscriber
+	clients map[*client]struct{}
+}
+
+func (s *Server) join(topic string, c *client) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	ts, ok := s.subs[topic]
+	if !ok {
+		sub, err := s.subscribe(topic)
+		if err != nil {
+			return err
+		}
+		ts = &topicSubscription{sub: sub, clients: make(map[*client]struct{})}
+		s.subs[topic] = ts
+	}
+
+	ts.clients[c] = struct{}{}
+	s.clients[c] = struct{}{}
+
+	return nil
+}
+
+func (s *Server) leave(topic string, c *client) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	delete(s.clients, c)
+
+	ts, ok := s.subs[topic]
+	if !ok {
+		return
+	}
+	delete(ts.clients, c)
+	if len(ts.clients) > 0 {
+		return
+	}
+
+	delete(s.subs, topic)
+	if err := ts.sub.Unsubscribe(true); err != nil {
+		LogErrorf("unsubscribe [%s] failed: %s", topic, err)
+	}
+}
+
+func (s *Server) subscribe(topic string) (broker.Subscriber, error) {
+	handler := func(_ context.Context, evt broker.RawEvent) error {
+		s.push(topic, evt)
+		return nil
+	}
+
+	sub, err := broker.SubscribeRaw(s.b, topic, handler, s.subscribeOpts...)
+	if errors.Is(err, broker.ErrRawUnsupported) {
+		opts := append(append([]broker.SubscribeOption{}, s.subscribeOpts...), broker.WithRawBody())
+		return s.b.Subscribe(topic, broker.RawHandler(handler).Handler(), nil, opts...)
+	}
+	return sub, err
+}
+
+func (s *Server) push(topic string, evt broker.RawEvent) {
+	data, err := json.Marshal(&Message{
+		Topic:   evt.Topic(),
+		Headers: evt.Headers(),
+		Body:    string(evt.Body()),
+	})
+	if err != nil {
+		LogErrorf("encode message of [%s] failed: %s", topic, err)
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	ts, ok := s.subs[topic]
+	if !ok {
+		return
+	}
+	for c := range ts.clients {
+		if !c.send(data) {
+			LogWarnf("client [%s] is too slow, message of [%s] dropped", c.conn.RemoteAddr(), topic)
+		}
+	}
+}
+
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	if limit > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	return io.ReadAll(r.Body)
+}
@@ -0,0 +1,180 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/auth"
+)
+
+type testBroker struct {
+	mtx       sync.Mutex
+	handlers  map[string]broker.Handler
+	published []*broker.Message
+}
+
+func (b *testBroker) Name() string                { return "test" }
+func (b *testBroker) Options() broker.Options     { return broker.Options{} }
+func (b *testBroker) Address() string             { return "" }
+func (b *testBroker) Init(...broker.Option) error { return nil }
+func (b *testBroker) Connect() error              { return nil }
+func (b *testBroker) Disconnect() error           { return nil }
+
+func (b *testBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	options := broker.NewPublishOptions(opts...)
+	m := &broker.Message{Headers: options.Headers, Body: msg}
+
+	b.mtx.Lock()
+	b.published = append(b.published, m)
+	h := b.handlers[topic]
+	b.mtx.Unlock()
+	if h == nil {
+		return nil
+	}
+	return h(ctx, &testEvent{topic: topic, msg: m})
+}
+
+func (b *testBroker) Subscribe(topic string, handler broker.Handler, _ broker.Binder, _ ...broker.SubscribeOption) (broker.Subscriber, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.handlers[topic] = handler
+	return &testSubscriber{b: b, topic: topic}, nil
+}
+
+func (b *testBroker) subscribed(topic string) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	_, ok := b.handlers[topic]
+	return ok
+}
+
+type testSubscriber struct {
+	b     *testBroker
+	topic string
+}
+
+func (s *testSubscriber) Options() broker.SubscribeOptions { return broker.SubscribeOptions{} }
+func (s *testSubscriber) Topic() string                    { return s.topic }
+func (s *testSubscriber) Unsubscribe(bool) error {
+	s.b.mtx.Lock()
+	defer s.b.mtx.Unlock()
+	delete(s.b.handlers, s.topic)
+	return nil
+}
+
+type testEvent struct {
+	topic string
+	msg   *broker.Message
+}
+
+func (e *testEvent) Topic() string            { return e.topic }
+func (e *testEvent) Message() *broker.Message { return e.msg }
+func (e *testEvent) RawMessage() interface{}  { return nil }
+func (e *testEvent) Ack() error               { return nil }
+func (e *testEvent) Error() error             { return nil }
+
+func newTestServer(t *testing.T, b broker.Broker, opts ...ServerOption) (*Server, *httptest.Server) {
+	srv := NewServer(b, append([]ServerOption{WithAddress("127.0.0.1:0")}, opts...)...)
+	assert.NoError(t, srv.err)
+	hs := httptest.NewServer(srv.Handler)
+	t.Cleanup(func() {
+		hs.Close()
+		_ = srv.Stop(context.Background())
+	})
+	return srv, hs
+}
+
+func TestServer_Publish(t *testing.T) {
+	b := &testBroker{handlers: map[string]broker.Handler{}}
+	_, hs := newTestServer(t, b,
+		WithTopics("orders.*"),
+		WithAuthenticator(auth.APIKey("X-Api-Key", map[string]string{"secret": "alice"})),
+		WithAuthorizer(func(id *auth.Identity, action Action, topic string) bool {
+			return id.Subject == "alice" && topic != "orders.audit"
+		}),
+		WithMaxBodySize(16),
+	)
+
+	post := func(topic, key, body string) int {
+		req, err := http.NewRequest(http.MethodPost, hs.URL+"/topics/"+topic, strings.NewReader(body))
+		assert.NoError(t, err)
+		if key != "" {
+			req.Header.Set("X-Api-Key", key)
+		}
+		req.Header.Set("X-Message-Trace-Id", "t1")
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusAccepted, post("orders.created", "secret", "hello"))
+	assert.Equal(t, http.StatusNotFound, post("payments.created", "secret", "hello"))
+	assert.Equal(t, http.StatusUnauthorized, post("orders.created", "", "hello"))
+	assert.Equal(t, http.StatusUnauthorized, post("orders.created", "wrong", "hello"))
+	assert.Equal(t, http.StatusForbidden, post("orders.audit", "secret", "hello"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("orders.created", "secret", strings.Repeat("x", 17)))
+
+	assert.Len(t, b.published, 1)
+	assert.Equal(t, []byte("hello"), b.published[0].Body)
+	assert.Equal(t, broker.Headers{"trace-id": "t1"}, b.published[0].Headers)
+}
+
+func TestServer_WebSocket(t *testing.T) {
+	b := &testBroker{handlers: map[string]broker.Handler{}}
+	_, hs := newTestServer(t, b, WithTopics("chat.*"))
+
+	url := "ws" + strings.TrimPrefix(hs.URL, "http") + "/topics/chat.room1"
+	conn, _, err := ws.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	assert.True(t, b.subscribed("chat.room1"))
+
+	other, _, err := ws.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+
+	read := func(c *ws.Conn) *Message {
+		_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, data, err := c.ReadMessage()
+		assert.NoError(t, err)
+		var msg Message
+		assert.NoError(t, json.Unmarshal(data, &msg))
+		return &msg
+	}
+
+	assert.NoError(t, b.Publish(context.Background(), "chat.room1", []byte("hello"),
+		broker.WithHeaders(broker.Headers{"from": "broker"})))
+	for _, c := range []*ws.Conn{conn, other} {
+		msg := read(c)
+		assert.Equal(t, "chat.room1", msg.Topic)
+		assert.Equal(t, "hello", msg.Body)
+		assert.Equal(t, "broker", msg.Headers["from"])
+	}
+
+	frame, err := json.Marshal(&Message{Headers: map[string]string{"from": "browser"}, Body: "hi"})
+	assert.NoError(t, err)
+	assert.NoError(t, conn.WriteMessage(ws.TextMessage, frame))
+	msg := read(other)
+	assert.Equal(t, "hi", msg.Body)
+	assert.Equal(t, "browser", msg.Headers["from"])
+
+	_ = conn.Close()
+	assert.True(t, b.subscribed("chat.room1"))
+	_ = other.Close()
+	assert.Eventually(t, func() bool {
+		return !b.subscribed("chat.room1")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, resp, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(hs.URL, "http")+"/topics/orders", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,114 @@
+package signalr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// BridgeMessage is the envelope relayed through the broker, so a broadcast
+// reaches the clients connected to every server instance.
+type BridgeMessage struct {
+	Method string        `json:"method"`
+	Group  string        `json:"group,omitempty"`
+	Args   []interface{} `json:"args,omitempty"`
+}
+
+// Broadcast invokes method on all clients.
+func (s *Server) Broadcast(ctx context.Context, method string, args ...interface{}) error {
+	return s.send(ctx, &BridgeMessage{Method: method, Args: args})
+}
+
+// BroadcastToGroup invokes method on the clients of group.
+func (s *Server) BroadcastToGroup(ctx context.Context, group, method string, args ...interface{}) error {
+	return s.send(ctx, &BridgeMessage{Method: method, Group: group, Args: args})
+}
+
+func (s *Server) send(ctx context.Context, msg *BridgeMessage) error {
+	if s.broker == nil {
+		s.deliver(msg)
+		return nil
+	}
+
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return s.broker.Publish(ctx, s.bridgeTopic, buf)
+}
+
+func (s *Server) deliver(msg *BridgeMessage) {
+	if s.Server == nil {
+		return
+	}
+
+	clients := s.HubClients()
+	if msg.Group != "" {
+		clients.Group(msg.Group).Send(msg.Method, msg.Args...)
+	} else {
+		clients.All().Send(msg.Method, msg.Args...)
+	}
+}
+
+func (s *Server) subscribeBridge() error {
+	if s.broker == nil || s.bridgeSub != nil {
+		return nil
+	}
+
+	sub, err := s.broker.Subscribe(s.bridgeTopic,
+		func(_ context.Context, event broker.Event) error {
+			msg, err := decodeBridgeMessage(event.Message().Body)
+			if err != nil {
+				log.Errorf("[signalr] decode bridge message failed: %v", err)
+				return err
+			}
+			s.deliver(msg)
+			return nil
+		},
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	s.bridgeSub = sub
+
+	return nil
+}
+
+func (s *Server) unsubscribeBridge() error {
+	if s.bridgeSub == nil {
+		return nil
+	}
+	err := s.bridgeSub.Unsubscribe(true)
+	s.bridgeSub = nil
+	return err
+}
+
+func decodeBridgeMessage(body broker.Any) (*BridgeMessage, error) {
+	var buf []byte
+	switch t := body.(type) {
+	case *BridgeMessage:
+		return t, nil
+	case []byte:
+		buf = t
+	case string:
+		buf = []byte(t)
+	default:
+		// decoded by the broker codec, e.g. into a map.
+		var err error
+		if buf, err = json.Marshal(t); err != nil {
+			return nil, fmt.Errorf("unsupported bridge message %T: %w", body, err)
+		}
+	}
+
+	var msg BridgeMessage
+	if err := json.Unmarshal(buf, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
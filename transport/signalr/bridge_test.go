@@ -0,0 +1,25 @@
+package signalr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeBridgeMessage(t *testing.T) {
+	want := &BridgeMessage{Method: "receive", Group: "group", Args: []interface{}{"hello"}}
+
+	for _, body := range []interface{}{
+		want,
+		[]byte(`{"method":"receive","group":"group","args":["hello"]}`),
+		`{"method":"receive","group":"group","args":["hello"]}`,
+		map[string]interface{}{"method": "receive", "group": "group", "args": []interface{}{"hello"}},
+	} {
+		msg, err := decodeBridgeMessage(body)
+		assert.NoError(t, err)
+		assert.Equal(t, want, msg)
+	}
+
+	_, err := decodeBridgeMessage([]byte("not json"))
+	assert.Error(t, err)
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/go-kratos/kratos/v2/encoding"
+
+	"github.com/tx7do/kratos-transport/broker"
 )
 
 type ServerOption func(o *Server)
@@ -64,4 +66,13 @@ func WithHub(hub signalr.HubInterface) ServerOption {
 	}
 }
 
+// WithBroker relay Broadcast and BroadcastToGroup through topic, so the
+// clients of every server instance receive them.
+func WithBroker(b broker.Broker, topic string) ServerOption {
+	return func(s *Server) {
+		s.broker = b
+		s.bridgeTopic = topic
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
@@ -15,6 +15,8 @@ import (
 	"github.com/go-kratos/kratos/v2/transport"
 
 	"github.com/philippseith/signalr"
+
+	"github.com/tx7do/kratos-transport/broker"
 )
 
 var (
@@ -43,6 +45,10 @@ type Server struct {
 
 	hub signalr.HubInterface
 
+	broker      broker.Broker
+	bridgeTopic string
+	bridgeSub   broker.Subscriber
+
 	router *http.ServeMux
 }
 
@@ -73,6 +79,10 @@ func (s *Server) Start(ctx context.Context) error {
 		return s.err
 	}
 
+	if err := s.subscribeBridge(); err != nil {
+		return err
+	}
+
 	log.Infof("[signalr] server listening on: %s", s.lis.Addr().String())
 
 	//handler := handlers.CORS(
@@ -100,6 +110,9 @@ func (s *Server) Start(ctx context.Context) error {
 
 func (s *Server) Stop(ctx context.Context) error {
 	log.Info("[signalr] server stopping")
+	if err := s.unsubscribeBridge(); err != nil {
+		log.Errorf("[signalr] unsubscribe bridge failed: %v", err)
+	}
 	return s.lis.Close()
 }
 
@@ -5,6 +5,8 @@ import (
 
 	"github.com/go-kratos/kratos/v2/encoding"
 	socketIo "github.com/googollee/go-socket.io"
+
+	"github.com/tx7do/kratos-transport/transport/presence"
 )
 
 type ServerOption func(o *Server)
@@ -41,13 +43,13 @@ func WithPath(path string) ServerOption {
 
 func WithConnectHandler(namespace string, f func(socketIo.Conn) error) ServerOption {
 	return func(s *Server) {
-		s.Server.OnConnect(namespace, f)
+		s.RegisterConnectHandler(namespace, f)
 	}
 }
 
 func WithDisconnectHandler(namespace string, f func(socketIo.Conn, string)) ServerOption {
 	return func(s *Server) {
-		s.Server.OnDisconnect(namespace, f)
+		s.RegisterDisconnectHandler(namespace, f)
 	}
 }
 
@@ -63,4 +65,12 @@ func WithEventHandler(namespace, event string, f interface{}) ServerOption {
 	}
 }
 
+// WithPresence register the connections in m, so they can be joined to rooms,
+// bound to users and reached from other instances.
+func WithPresence(m *presence.Manager) ServerOption {
+	return func(s *Server) {
+		s.presence = m
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,108 @@
+package socketio
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/log"
+	socketIo "github.com/googollee/go-socket.io"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/presence"
+)
+
+// DefaultPresenceEvent is emitted for presence messages without an event name.
+const DefaultPresenceEvent = "message"
+
+var ErrPresenceDisabled = errors.New("presence manager not configured")
+
+func (s *Server) Presence() *presence.Manager {
+	return s.presence
+}
+
+// SessionID return the presence session id of conn. The connection ids of
+// socket.io are only unique within a process, so the id is prefixed with the
+// node of the presence manager.
+func (s *Server) SessionID(conn socketIo.Conn) string {
+	id := conn.ID()
+	if s.presence != nil {
+		id = s.presence.Node() + ":" + id
+	}
+	if nsp := normalizeNamespace(conn.Namespace()); nsp != "/" {
+		id += nsp
+	}
+	return id
+}
+
+func (s *Server) registerPresence(conn socketIo.Conn) {
+	if s.presence == nil {
+		return
+	}
+
+	id := s.SessionID(conn)
+	err := s.presence.Register(context.Background(), &presence.Session{ID: id},
+		func(_ context.Context, msg *presence.Message) error {
+			event := msg.Event
+			if event == "" {
+				event = DefaultPresenceEvent
+			}
+			conn.Emit(event, string(msg.Data))
+			return nil
+		},
+	)
+	if err != nil {
+		log.Errorf("[socket.io] register session [%s] to presence failed: %s", id, err)
+	}
+}
+
+func (s *Server) unregisterPresence(conn socketIo.Conn) {
+	if s.presence == nil {
+		return
+	}
+
+	id := s.SessionID(conn)
+	if err := s.presence.Unregister(context.Background(), id); err != nil {
+		log.Errorf("[socket.io] unregister session [%s] from presence failed: %s", id, err)
+	}
+}
+
+func (s *Server) presenceMessage(event string, data interface{}) (*presence.Message, error) {
+	if s.presence == nil {
+		return nil, ErrPresenceDisabled
+	}
+
+	buf, err := broker.Marshal(s.codec, data)
+	if err != nil {
+		return nil, err
+	}
+	return &presence.Message{Event: event, Data: buf}, nil
+}
+
+// SendToRoom emit an event to the connections in room on every instance.
+func (s *Server) SendToRoom(ctx context.Context, room, event string, data interface{}) error {
+	msg, err := s.presenceMessage(event, data)
+	if err != nil {
+		return err
+	}
+	return s.presence.SendToRoom(ctx, room, msg)
+}
+
+// SendToUser emit an event to the connections bound to userID on every instance.
+func (s *Server) SendToUser(ctx context.Context, userID, event string, data interface{}) error {
+	msg, err := s.presenceMessage(event, data)
+	if err != nil {
+		return err
+	}
+	return s.presence.SendToUser(ctx, userID, msg)
+}
+
+func normalizeNamespace(namespace string) string {
+	if namespace == "" {
+		return "/"
+	}
+	if !strings.HasPrefix(namespace, "/") {
+		return "/" + namespace
+	}
+	return namespace
+}
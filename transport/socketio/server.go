@@ -21,6 +21,8 @@ import (
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+
+	"github.com/tx7do/kratos-transport/transport/presence"
 )
 
 var (
@@ -42,6 +44,10 @@ type Server struct {
 	codec encoding.Codec
 
 	router *mux.Router
+
+	presence      *presence.Manager
+	connectNsp    map[string]struct{}
+	disconnectNsp map[string]struct{}
 }
 
 func NewServer(opts ...ServerOption) *Server {
@@ -50,6 +56,9 @@ func NewServer(opts ...ServerOption) *Server {
 		address: ":0",
 		router:  mux.NewRouter(),
 		path:    "/socket.io/",
+
+		connectNsp:    make(map[string]struct{}),
+		disconnectNsp: make(map[string]struct{}),
 	}
 
 	srv.init(opts...)
@@ -117,11 +126,24 @@ func (s *Server) Endpoint() (*url.URL, error) {
 }
 
 func (s *Server) RegisterConnectHandler(namespace string, f func(socketIo.Conn) error) {
-	s.Server.OnConnect(namespace, f)
+	s.connectNsp[normalizeNamespace(namespace)] = struct{}{}
+	s.Server.OnConnect(namespace, func(conn socketIo.Conn) error {
+		s.registerPresence(conn)
+		if f == nil {
+			return nil
+		}
+		return f(conn)
+	})
 }
 
 func (s *Server) RegisterDisconnectHandler(namespace string, f func(socketIo.Conn, string)) {
-	s.Server.OnDisconnect(namespace, f)
+	s.disconnectNsp[normalizeNamespace(namespace)] = struct{}{}
+	s.Server.OnDisconnect(namespace, func(conn socketIo.Conn, reason string) {
+		s.unregisterPresence(conn)
+		if f != nil {
+			f(conn, reason)
+		}
+	})
 }
 
 func (s *Server) RegisterErrorHandler(namespace string, f func(socketIo.Conn, error)) {
@@ -153,6 +175,15 @@ func (s *Server) init(opts ...ServerOption) {
 		o(s)
 	}
 
+	if s.presence != nil {
+		if _, ok := s.connectNsp["/"]; !ok {
+			s.RegisterConnectHandler("/", nil)
+		}
+		if _, ok := s.disconnectNsp["/"]; !ok {
+			s.RegisterDisconnectHandler("/", nil)
+		}
+	}
+
 	s.router.Use(mux.CORSMethodMiddleware(s.router))
 
 	s.router.Handle(s.path, server)
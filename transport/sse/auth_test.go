@@ -0,0 +1,49 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/transport/auth"
+)
+
+func TestAuthenticator(t *testing.T) {
+	subjects := make(chan string, 1)
+
+	s := NewServer(
+		WithAuthenticator(auth.APIKey("X-Api-Key", map[string]string{"k1": "alice"})),
+		WithAuthStatusCode(http.StatusForbidden),
+		WithSubscriberFunction(func(_ StreamID, sub *Subscriber) {
+			id, _ := auth.FromContext(sub.Context())
+			subjects <- id.Subject
+		}, nil),
+	)
+	defer s.Stop(nil)
+	s.CreateStream("test")
+
+	hs := httptest.NewServer(http.HandlerFunc(s.ServeHTTP))
+	defer hs.Close()
+
+	resp, err := http.Get(hs.URL + "?stream=test")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, hs.URL+"?stream=test", nil)
+	req.Header.Set("X-Api-Key", "k1")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	select {
+	case subject := <-subjects:
+		assert.Equal(t, "alice", subject)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "subscriber not registered")
+	}
+}
@@ -0,0 +1,60 @@
+package sse
+
+import (
+	"context"
+	"time"
+)
+
+const drainCheckInterval = 50 * time.Millisecond
+
+// DefaultGoAwayEvent is sent to every subscriber when the server starts
+// draining, clients should reconnect to another instance on it.
+var DefaultGoAwayEvent = &Event{
+	Event: []byte("goaway"),
+	Data:  []byte("server shutting down"),
+}
+
+func (s *Server) isDraining() bool {
+	select {
+	case <-s.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Server) subscriberCount() int {
+	var count int
+	s.streamMgr.Range(func(stream *Stream) {
+		count += stream.getSubscriberCount()
+	})
+	return count
+}
+
+// drain refuse new subscribers, send the go away event to the current ones
+// and wait for them to leave, for the drain timeout at most.
+func (s *Server) drain(ctx context.Context) {
+	s.drainOnce.Do(func() {
+		close(s.draining)
+	})
+
+	if s.drainTimeout <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(s.drainTimeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+
+	for s.subscriberCount() > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			LogWarnf("drain timeout, close %d subscribers", s.subscriberCount())
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrain(t *testing.T) {
+	s := NewServer(WithDrainTimeout(5 * time.Second))
+	s.CreateStream("test")
+
+	hs := httptest.NewServer(http.HandlerFunc(s.ServeHTTP))
+	defer hs.Close()
+
+	resp, err := http.Get(hs.URL + "?stream=test")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	stopped := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		stopped <- s.Stop(context.Background())
+	}()
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		if strings.HasPrefix(line, "event: goaway") {
+			break
+		}
+	}
+
+	refused, err := http.Get(hs.URL + "?stream=test")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, refused.StatusCode)
+	_ = refused.Body.Close()
+
+	_ = resp.Body.Close()
+
+	select {
+	case err = <-stopped:
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	case <-time.After(10 * time.Second):
+		t.Fatal("server not stopped")
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	s := NewServer(WithDrainTimeout(100*time.Millisecond), WithGoAwayEvent(nil))
+	s.CreateStream("test")
+
+	hs := httptest.NewServer(http.HandlerFunc(s.ServeHTTP))
+	defer hs.Close()
+
+	resp, err := http.Get(hs.URL + "?stream=test")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	start := time.Now()
+	assert.NoError(t, s.Stop(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, 0, s.streamMgr.Count())
+}
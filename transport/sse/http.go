@@ -2,10 +2,13 @@ package sse
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/tx7do/kratos-transport/transport/auth"
 )
 
 func (s *Server) prepareHeaderForSSE(w http.ResponseWriter) {
@@ -28,6 +31,22 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.isDraining() {
+		writeError(w, "Server is shutting down!", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx := context.Background()
+	if s.authenticator != nil {
+		id, err := s.authenticator.Authenticate(r.Context(), auth.NewHTTPRequest(r))
+		if err != nil {
+			LogErrorf("authenticate [%s] failed: %s", r.RemoteAddr, err)
+			writeError(w, http.StatusText(s.authStatusCode), s.authStatusCode)
+			return
+		}
+		ctx = auth.NewContext(ctx, id)
+	}
+
 	s.prepareHeaderForSSE(w)
 
 	streamID := r.URL.Query().Get("stream")
@@ -56,7 +75,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	sub := stream.addSubscriber(eventId, r.URL)
+	sub := stream.addSubscriberContext(ctx, eventId, r.URL)
 
 	go func() {
 		<-r.Context().Done()
@@ -64,53 +83,78 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		sub.close()
 
 		if s.autoStream && !s.autoReplay && stream.getSubscriberCount() == 0 {
-			s.streamMgr.RemoveWithID(StreamID(streamID))
+			s.RemoveStream(StreamID(streamID))
 		}
 	}()
 
 	w.WriteHeader(http.StatusOK)
 	flusher.Flush()
 
-	for ev := range sub.connection {
-		if len(ev.Data) == 0 && len(ev.Comment) == 0 {
-			break
-		}
+	drain := s.draining
+	for {
+		select {
+		case ev, ok := <-sub.connection:
+			if !ok || (len(ev.Data) == 0 && len(ev.Comment) == 0) {
+				select {
+				case <-drain:
+					s.writeGoAway(w, flusher)
+				default:
+				}
+				return
+			}
+
+			if s.eventTTL != 0 && time.Now().After(ev.timestamp.Add(s.eventTTL)) {
+				continue
+			}
+
+			s.writeEvent(w, ev)
+			flusher.Flush()
 
-		if s.eventTTL != 0 && time.Now().After(ev.timestamp.Add(s.eventTTL)) {
-			continue
+		case <-drain:
+			drain = nil
+			s.writeGoAway(w, flusher)
 		}
+	}
+}
 
-		if len(ev.Data) > 0 {
-			_, _ = writeData(w, FieldId, ev.ID)
+// writeGoAway tell a subscriber the server is draining.
+func (s *Server) writeGoAway(w http.ResponseWriter, flusher http.Flusher) {
+	if s.goAwayEvent == nil {
+		return
+	}
+	s.writeEvent(w, s.goAwayEvent)
+	flusher.Flush()
+}
 
-			if s.splitData {
-				sd := bytes.Split(ev.Data, []byte("\n"))
-				for i := range sd {
-					_, _ = writeData(w, FieldData, sd[i])
-				}
-			} else {
-				if bytes.HasPrefix(ev.Data, []byte(":")) {
-					_, _ = fmt.Fprintf(w, "%s\n", ev.Data)
-				} else {
-					_, _ = writeData(w, FieldData, ev.Data)
-				}
-			}
+func (s *Server) writeEvent(w http.ResponseWriter, ev *Event) {
+	if len(ev.Data) > 0 {
+		_, _ = writeData(w, FieldId, ev.ID)
 
-			if len(ev.Event) > 0 {
-				_, _ = writeData(w, FieldEvent, ev.Event)
+		if s.splitData {
+			sd := bytes.Split(ev.Data, []byte("\n"))
+			for i := range sd {
+				_, _ = writeData(w, FieldData, sd[i])
 			}
-
-			if len(ev.Retry) > 0 {
-				_, _ = writeData(w, FieldRetry, ev.Retry)
+		} else {
+			if bytes.HasPrefix(ev.Data, []byte(":")) {
+				_, _ = fmt.Fprintf(w, "%s\n", ev.Data)
+			} else {
+				_, _ = writeData(w, FieldData, ev.Data)
 			}
 		}
 
-		if len(ev.Comment) > 0 {
-			_, _ = writeData(w, "", ev.Comment)
+		if len(ev.Event) > 0 {
+			_, _ = writeData(w, FieldEvent, ev.Event)
 		}
 
-		_, _ = fmt.Fprint(w, "\n")
+		if len(ev.Retry) > 0 {
+			_, _ = writeData(w, FieldRetry, ev.Retry)
+		}
+	}
 
-		flusher.Flush()
+	if len(ev.Comment) > 0 {
+		_, _ = writeData(w, "", ev.Comment)
 	}
+
+	_, _ = fmt.Fprint(w, "\n")
 }
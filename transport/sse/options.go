@@ -6,6 +6,10 @@ import (
 	"time"
 
 	"github.com/go-kratos/kratos/v2/encoding"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/auth"
+	"github.com/tx7do/kratos-transport/transport/presence"
 )
 
 const DefaultBufferSize = 1024
@@ -103,6 +107,60 @@ func WithEventTTL(timeout time.Duration) ServerOption {
 	}
 }
 
+// WithPresence register the streams in m, so they can be joined to rooms,
+// bound to users and reached from other instances.
+func WithPresence(m *presence.Manager) ServerOption {
+	return func(s *Server) {
+		s.presence = m
+	}
+}
+
+// WithCluster relay the messages sent to rooms, users and all streams to the
+// other instances of the server over topic of b. Each instance resolves them
+// against its own streams, the server starts and stops the presence manager.
+func WithCluster(b broker.Broker, topic string, opts ...presence.Option) ServerOption {
+	return func(s *Server) {
+		opts = append([]presence.Option{
+			presence.WithFanout(true),
+			presence.WithRelay(presence.NewBrokerRelay(b, topic)),
+		}, opts...)
+		s.presence = presence.NewManager(opts...)
+		s.ownPresence = true
+	}
+}
+
+// WithAuthenticator authenticate the clients before they subscribe, the
+// identity is carried by the context of the subscriber.
+func WithAuthenticator(a auth.Authenticator) ServerOption {
+	return func(s *Server) {
+		s.authenticator = a
+	}
+}
+
+// WithAuthStatusCode set the http status of the responses to the clients
+// failing the authentication, default is 401.
+func WithAuthStatusCode(code int) ServerOption {
+	return func(s *Server) {
+		s.authStatusCode = code
+	}
+}
+
+// WithDrainTimeout wait for the subscribers to leave for timeout at most when
+// the server stops, after sending them the go away event.
+func WithDrainTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.drainTimeout = timeout
+	}
+}
+
+// WithGoAwayEvent set the event sent to the subscribers when the server stops,
+// default DefaultGoAwayEvent, nil sends none.
+func WithGoAwayEvent(event *Event) ServerOption {
+	return func(s *Server) {
+		s.goAwayEvent = event
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 type ClientOption func(o *Client)
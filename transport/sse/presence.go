@@ -0,0 +1,84 @@
+package sse
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/presence"
+)
+
+var ErrPresenceDisabled = errors.New("presence manager not configured")
+
+func (s *Server) Presence() *presence.Manager {
+	return s.presence
+}
+
+func (s *Server) registerPresence(streamId StreamID) {
+	if s.presence == nil {
+		return
+	}
+
+	err := s.presence.Register(context.Background(), &presence.Session{ID: string(streamId)},
+		func(ctx context.Context, msg *presence.Message) error {
+			event := &Event{Data: msg.Data}
+			if msg.Event != "" {
+				event.Event = []byte(msg.Event)
+			}
+			s.Publish(ctx, streamId, event)
+			return nil
+		},
+	)
+	if err != nil {
+		LogErrorf("register stream [%s] to presence failed: %s", streamId, err)
+	}
+}
+
+func (s *Server) unregisterPresence(streamId StreamID) {
+	if s.presence == nil {
+		return
+	}
+
+	if err := s.presence.Unregister(context.Background(), string(streamId)); err != nil {
+		LogErrorf("unregister stream [%s] from presence failed: %s", streamId, err)
+	}
+}
+
+func (s *Server) presenceMessage(event string, data MessagePayload) (*presence.Message, error) {
+	if s.presence == nil {
+		return nil, ErrPresenceDisabled
+	}
+
+	buf, err := broker.Marshal(s.codec, data)
+	if err != nil {
+		return nil, err
+	}
+	return &presence.Message{Event: event, Data: buf}, nil
+}
+
+// SendToRoom publish an event to the streams in room on every instance.
+func (s *Server) SendToRoom(ctx context.Context, room, event string, data MessagePayload) error {
+	msg, err := s.presenceMessage(event, data)
+	if err != nil {
+		return err
+	}
+	return s.presence.SendToRoom(ctx, room, msg)
+}
+
+// SendToUser publish an event to the streams bound to userID on every instance.
+func (s *Server) SendToUser(ctx context.Context, userID, event string, data MessagePayload) error {
+	msg, err := s.presenceMessage(event, data)
+	if err != nil {
+		return err
+	}
+	return s.presence.SendToUser(ctx, userID, msg)
+}
+
+// SendToAll publish an event to every stream on every instance.
+func (s *Server) SendToAll(ctx context.Context, event string, data MessagePayload) error {
+	msg, err := s.presenceMessage(event, data)
+	if err != nil {
+		return err
+	}
+	return s.presence.Broadcast(ctx, msg)
+}
@@ -0,0 +1,37 @@
+package sse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/transport/presence"
+)
+
+func TestPresence(t *testing.T) {
+	ctx := context.Background()
+	m := presence.NewManager()
+
+	s := NewServer(WithAddress(":0"), WithCodec("json"), WithPresence(m))
+	defer s.Stop(ctx)
+
+	s.CreateStream("alice-feed")
+	assert.Equal(t, 1, m.LocalCount())
+	assert.NoError(t, m.Bind(ctx, "alice-feed", "alice"))
+
+	sub := s.streamMgr.Get("alice-feed").addSubscriber(0, nil)
+
+	assert.NoError(t, s.SendToUser(ctx, "alice", "notice", map[string]string{"text": "hi"}))
+
+	event, err := waitEvent(sub.connection, 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "notice", string(event.Event))
+	assert.Equal(t, `{"text":"hi"}`, string(event.Data))
+
+	s.RemoveStream("alice-feed")
+	assert.Equal(t, 0, m.LocalCount())
+
+	assert.ErrorIs(t, NewServer(WithAddress(":0")).SendToRoom(ctx, "lobby", "notice", "hi"), ErrPresenceDisabled)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/encoding"
@@ -14,6 +15,8 @@ import (
 	"github.com/go-kratos/kratos/v2/transport"
 	"github.com/gorilla/mux"
 	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/auth"
+	"github.com/tx7do/kratos-transport/transport/presence"
 )
 
 type Any interface{}
@@ -56,6 +59,17 @@ type Server struct {
 	unsubscribeFunc SubscriberFunction
 
 	streamMgr *StreamManager
+
+	presence    *presence.Manager
+	ownPresence bool
+
+	authenticator  auth.Authenticator
+	authStatusCode int
+
+	drainTimeout time.Duration
+	goAwayEvent  *Event
+	draining     chan struct{}
+	drainOnce    sync.Once
 }
 
 func NewServer(opts ...ServerOption) *Server {
@@ -75,6 +89,11 @@ func NewServer(opts ...ServerOption) *Server {
 		headers:    map[string]string{},
 
 		streamMgr: NewStreamManager(),
+
+		authStatusCode: http.StatusUnauthorized,
+
+		goAwayEvent: DefaultGoAwayEvent,
+		draining:    make(chan struct{}),
 	}
 
 	srv.init(opts...)
@@ -97,6 +116,12 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 	LogInfof("server listening on: %s", s.lis.Addr().String())
 
+	if s.ownPresence {
+		if err := s.presence.Start(); err != nil {
+			return err
+		}
+	}
+
 	s.HandleServeHTTP(s.path)
 
 	var err error
@@ -113,9 +138,20 @@ func (s *Server) Start(ctx context.Context) error {
 }
 
 func (s *Server) Stop(ctx context.Context) error {
+	LogInfo("server stopping")
+
+	s.drain(ctx)
+
+	s.streamMgr.Range(func(stream *Stream) {
+		s.unregisterPresence(stream.StreamID())
+	})
 	s.streamMgr.Clean()
+	if s.ownPresence {
+		if err := s.presence.Stop(ctx); err != nil {
+			LogErrorf("stop presence failed: %s", err)
+		}
+	}
 
-	LogInfo("server stopping")
 	return s.Shutdown(ctx)
 }
 
@@ -244,10 +280,16 @@ func (s *Server) CreateStream(streamId StreamID) *Stream {
 	stream = s.createStream(streamId)
 
 	s.streamMgr.Add(stream)
+	s.registerPresence(streamId)
 
 	return stream
 }
 
+func (s *Server) RemoveStream(streamId StreamID) {
+	s.streamMgr.RemoveWithID(streamId)
+	s.unregisterPresence(streamId)
+}
+
 func (s *Server) process(event *Event) *Event {
 	if s.encodeBase64 {
 		event.encodeBase64()
@@ -1,6 +1,7 @@
 package sse
 
 import (
+	"context"
 	"net/url"
 	"sync"
 	"sync/atomic"
@@ -103,8 +104,13 @@ func (s *Stream) getSubIndex(sub *Subscriber) int {
 }
 
 func (s *Stream) addSubscriber(eventId int, url *url.URL) *Subscriber {
+	return s.addSubscriberContext(context.Background(), eventId, url)
+}
+
+func (s *Stream) addSubscriberContext(ctx context.Context, eventId int, url *url.URL) *Subscriber {
 	atomic.AddInt32(&s.subscriberCount, 1)
 	sub := &Subscriber{
+		ctx:        ctx,
 		eventId:    eventId,
 		quit:       s.deregister,
 		connection: make(chan *Event, 64),
@@ -1,8 +1,12 @@
 package sse
 
-import "net/url"
+import (
+	"context"
+	"net/url"
+)
 
 type Subscriber struct {
+	ctx        context.Context
 	quit       chan *Subscriber
 	connection chan *Event
 	removed    chan struct{}
@@ -10,6 +14,11 @@ type Subscriber struct {
 	URL        *url.URL
 }
 
+// Context carry the identity of the authenticated client, see auth.FromContext.
+func (s *Subscriber) Context() context.Context {
+	return s.ctx
+}
+
 func (s *Subscriber) close() {
 	s.quit <- s
 	if s.removed != nil {
@@ -0,0 +1,59 @@
+package tcp
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/tx7do/kratos-transport/transport/auth"
+)
+
+// Identity return the identity of an authenticated session.
+func (s *Server) Identity(sessionId SessionID) (*auth.Identity, bool) {
+	session, ok := s.sessions[sessionId]
+	if !ok {
+		return nil, false
+	}
+	return auth.FromContext(session.Context())
+}
+
+// authenticate return the context of the session of conn, or close it.
+// Raw tcp has no handshake, so the peer is known by its address and, with
+// WithTLSConfig, its client certificate.
+func (s *Server) authenticate(conn net.Conn) (context.Context, bool) {
+	ctx := context.Background()
+	if s.authenticator == nil {
+		return ctx, true
+	}
+
+	req := &auth.Request{RemoteAddr: conn.RemoteAddr().String()}
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		_ = conn.SetDeadline(time.Now().Add(s.timeout))
+		err := tlsConn.Handshake()
+		_ = conn.SetDeadline(time.Time{})
+		if err != nil {
+			LogErrorf("tls handshake with [%s] failed: %s", req.RemoteAddr, err)
+			_ = conn.Close()
+			return nil, false
+		}
+
+		state := tlsConn.ConnectionState()
+		req.TLS = &state
+	}
+
+	id, err := s.authenticator.Authenticate(ctx, req)
+	if err != nil {
+		LogErrorf("authenticate [%s] failed: %s", req.RemoteAddr, err)
+
+		if len(s.authRejectData) > 0 {
+			_ = conn.SetWriteDeadline(time.Now().Add(s.timeout))
+			_, _ = conn.Write(s.authRejectData)
+		}
+		_ = conn.Close()
+		return nil, false
+	}
+
+	return auth.NewContext(ctx, id), true
+}
@@ -0,0 +1,63 @@
+package tcp
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/transport/auth"
+)
+
+func TestAuthenticator(t *testing.T) {
+	ctx := context.Background()
+
+	var allow atomic.Bool
+	connected := make(chan SessionID, 1)
+
+	srv := NewServer(
+		WithAddress("127.0.0.1:0"),
+		WithAuthenticator(auth.AuthenticatorFunc(func(_ context.Context, r *auth.Request) (*auth.Identity, error) {
+			if !allow.Load() {
+				return nil, auth.ErrInvalidCredentials
+			}
+			return &auth.Identity{Subject: r.RemoteAddr}, nil
+		})),
+		WithAuthRejectData([]byte("unauthorized")),
+		WithConnectHandler(func(id SessionID, register bool) {
+			if register {
+				connected <- id
+			}
+		}),
+	)
+	assert.NoError(t, srv.Start(ctx))
+	lis := srv.lis
+	defer lis.Close()
+	addr := lis.Addr().String()
+
+	conn, err := net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	data, err := io.ReadAll(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, "unauthorized", string(data))
+	_ = conn.Close()
+
+	allow.Store(true)
+	conn, err = net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	select {
+	case id := <-connected:
+		identity, ok := auth.FromContext(srv.sessions[id].Context())
+		assert.True(t, ok)
+		assert.Equal(t, conn.LocalAddr().String(), identity.Subject)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "session not registered")
+	}
+}
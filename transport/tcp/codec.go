@@ -0,0 +1,43 @@
+package tcp
+
+import (
+	"github.com/go-kratos/kratos/v2/encoding"
+
+	"github.com/tx7do/kratos-transport/transport/framing"
+)
+
+// RegisterMessageCodec encode the payloads of messageType with the codec
+// named codec instead of the codec of the server.
+func (s *Server) RegisterMessageCodec(messageType MessageType, codec string) {
+	s.messageCodecs[messageType] = encoding.GetCodec(codec)
+}
+
+func (s *Server) codecOf(messageType MessageType) encoding.Codec {
+	if c, ok := s.messageCodecs[messageType]; ok {
+		return c
+	}
+	return s.codec
+}
+
+func (s *Server) encodeFrame(msg *Message) ([]byte, error) {
+	if s.frameCodec == nil {
+		return msg.Marshal()
+	}
+	return s.frameCodec.Marshal(&framing.Frame{Type: uint32(msg.Type), Body: msg.Body})
+}
+
+func (s *Server) decodeFrame(buf []byte) (*Message, error) {
+	if s.frameCodec == nil {
+		var msg Message
+		if err := msg.Unmarshal(buf); err != nil {
+			return nil, err
+		}
+		return &msg, nil
+	}
+
+	f, err := s.frameCodec.Unmarshal(buf)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Type: MessageType(f.Type), Body: f.Body}, nil
+}
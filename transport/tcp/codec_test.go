@@ -0,0 +1,70 @@
+package tcp
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/transport/framing"
+)
+
+func TestFrameCodec(t *testing.T) {
+	received := make(chan *ChatMessage, 2)
+
+	srv := NewServer(
+		WithAddress("127.0.0.1:0"),
+		WithCodec("json"),
+		WithFrameCodec(framing.JSONLines()),
+	)
+	RegisterServerMessageHandler(srv, MessageTypeChat, func(_ SessionID, msg *ChatMessage) error {
+		received <- msg
+		return nil
+	})
+	assert.NoError(t, srv.Start(context.Background()))
+	lis := srv.lis
+	defer lis.Close()
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// two messages in one write, the second split across writes
+	_, err = conn.Write([]byte("{\"type\":1,\"body\":{\"message\":\"a\"}}\n{\"type\":1,"))
+	assert.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	_, err = conn.Write([]byte("\"body\":{\"message\":\"b\"}}\n"))
+	assert.NoError(t, err)
+
+	for _, text := range []string{"a", "b"} {
+		select {
+		case msg := <-received:
+			assert.Equal(t, text, msg.Message)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "message not received")
+		}
+	}
+
+	buf, err := srv.marshalMessage(MessageTypeChat, &ChatMessage{Message: "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, byte('\n'), buf[len(buf)-1])
+
+	var reply struct {
+		Type MessageType  `json:"type"`
+		Body *ChatMessage `json:"body"`
+	}
+	assert.NoError(t, json.Unmarshal(buf, &reply))
+	assert.Equal(t, MessageType(MessageTypeChat), reply.Type)
+	assert.Equal(t, "c", reply.Body.Message)
+}
+
+func TestRegisterMessageCodec(t *testing.T) {
+	srv := NewServer(WithCodec("json"))
+	srv.RegisterMessageCodec(2, "proto")
+
+	assert.Equal(t, "json", srv.codecOf(1).Name())
+	assert.Equal(t, "proto", srv.codecOf(2).Name())
+}
@@ -0,0 +1,52 @@
+package tcp
+
+import (
+	"time"
+)
+
+// OverflowPolicy decides what happens to a session going over a limit.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock wait: stop reading the client until the rate allows, or
+	// wait for room in the send buffer.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop drop the message.
+	OverflowDrop
+	// OverflowDisconnect close the session.
+	OverflowDisconnect
+)
+
+// kick end the read of the session, which then closes it.
+func (c *Session) kick() {
+	conn := c.conn
+	if conn == nil {
+		return
+	}
+	_ = conn.SetReadDeadline(time.Now())
+}
+
+// allowMessage apply the message rate limit of the session, and report
+// whether the message is to be handled.
+func (c *Session) allowMessage() bool {
+	if c.limiter == nil {
+		return true
+	}
+
+	switch c.server.rateLimitPolicy {
+	case OverflowDrop:
+		if !c.limiter.Allow() {
+			LogWarnf("[tcp] session [%s] over the message rate, drop message", c.id)
+			return false
+		}
+	case OverflowDisconnect:
+		if !c.limiter.Allow() {
+			LogWarnf("[tcp] session [%s] over the message rate, disconnect", c.id)
+			c.kick()
+			return false
+		}
+	default:
+		_ = c.limiter.Wait(c.ctx)
+	}
+	return true
+}
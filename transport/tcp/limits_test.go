@@ -0,0 +1,78 @@
+package tcp
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func startTestServer(t *testing.T, opts ...ServerOption) (string, func()) {
+	srv := NewServer(append([]ServerOption{WithAddress("127.0.0.1:0")}, opts...)...)
+	assert.NoError(t, srv.Start(context.Background()))
+
+	lis := srv.lis
+	return lis.Addr().String(), func() { _ = lis.Close() }
+}
+
+// waitClosed report whether the server closed conn.
+func waitClosed(conn net.Conn) bool {
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err := io.ReadAll(conn)
+	return err == nil
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	addr, stop := startTestServer(t, WithMaxMessageSize(16))
+	defer stop()
+
+	conn, err := net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write(make([]byte, 64))
+	assert.NoError(t, err)
+	assert.True(t, waitClosed(conn))
+}
+
+func TestMessageRateLimit(t *testing.T) {
+	handled := make(chan struct{}, 10)
+
+	addr, stop := startTestServer(t,
+		WithMessageRateLimit(1, 1, OverflowDisconnect),
+		WithRawDataHandler(func(SessionID, []byte) error {
+			handled <- struct{}{}
+			return nil
+		}),
+	)
+	defer stop()
+
+	conn, err := net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	for i := 0; i < 3; i++ {
+		_, _ = conn.Write([]byte("ping"))
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, waitClosed(conn))
+	assert.Equal(t, 1, len(handled))
+}
+
+func TestSlowConsumerPolicy(t *testing.T) {
+	srv := NewServer(WithSlowConsumerPolicy(OverflowDrop))
+	c := &Session{id: "s1", server: srv, send: make(chan []byte, 1)}
+
+	c.SendMessage([]byte("1"))
+	c.SendMessage([]byte("2"))
+	assert.Equal(t, 1, len(c.send))
+	assert.Equal(t, "1", string(<-c.send))
+
+	srv.slowConsumerPolicy = OverflowDisconnect
+	c.SendMessage([]byte("1"))
+	c.SendMessage([]byte("2"))
+	assert.Equal(t, 1, len(c.send))
+}
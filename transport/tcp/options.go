@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/go-kratos/kratos/v2/encoding"
+
+	"github.com/tx7do/kratos-transport/transport/auth"
+	"github.com/tx7do/kratos-transport/transport/framing"
 )
 
 type ServerOption func(o *Server)
@@ -57,6 +60,65 @@ func WithRecvBufferSize(size int) ServerOption {
 	}
 }
 
+// WithAuthenticator authenticate the clients when they connect, the identity
+// is carried by the context of the session.
+func WithAuthenticator(a auth.Authenticator) ServerOption {
+	return func(s *Server) {
+		s.authenticator = a
+	}
+}
+
+// WithAuthRejectData set the data written to the clients failing the
+// authentication before the connection is closed.
+func WithAuthRejectData(data []byte) ServerOption {
+	return func(s *Server) {
+		s.authRejectData = data
+	}
+}
+
+// WithFrameCodec speak the wire protocol of c: it cuts the messages out of
+// the stream of each session and encodes their frames. Without it each read
+// is a message of a 4 bytes type followed by the body.
+func WithFrameCodec(c framing.Codec) ServerOption {
+	return func(s *Server) {
+		s.frameCodec = c
+	}
+}
+
+// WithMaxMessageSize close the sessions sending a message over size bytes,
+// or more than size bytes at once without a frame codec.
+func WithMaxMessageSize(size int) ServerOption {
+	return func(s *Server) {
+		s.maxMessageSize = size
+	}
+}
+
+// WithMessageRateLimit limit the messages read from each client to rate per
+// second with bursts of burst, policy decides what happens to the messages
+// over the limit.
+func WithMessageRateLimit(rate float64, burst int, policy OverflowPolicy) ServerOption {
+	return func(s *Server) {
+		s.messageRate = rate
+		s.messageBurst = burst
+		s.rateLimitPolicy = policy
+	}
+}
+
+// WithSlowConsumerPolicy decide what happens to the messages sent to a
+// session whose send buffer is full, default is OverflowBlock.
+func WithSlowConsumerPolicy(policy OverflowPolicy) ServerOption {
+	return func(s *Server) {
+		s.slowConsumerPolicy = policy
+	}
+}
+
+// WithWriteTimeout close the sessions whose writes do not complete in timeout.
+func WithWriteTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.writeTimeout = timeout
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 type ClientOption func(o *Client)
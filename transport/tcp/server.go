@@ -13,6 +13,8 @@ import (
 	"github.com/go-kratos/kratos/v2/encoding"
 	"github.com/go-kratos/kratos/v2/transport"
 	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/auth"
+	"github.com/tx7do/kratos-transport/transport/framing"
 )
 
 type Binder func() Any
@@ -53,6 +55,19 @@ type Server struct {
 	sessions   SessionMap
 	register   chan *Session
 	unregister chan *Session
+
+	authenticator  auth.Authenticator
+	authRejectData []byte
+
+	frameCodec    framing.Codec
+	messageCodecs map[MessageType]encoding.Codec
+
+	maxMessageSize     int
+	messageRate        float64
+	messageBurst       int
+	rateLimitPolicy    OverflowPolicy
+	slowConsumerPolicy OverflowPolicy
+	writeTimeout       time.Duration
 }
 
 func NewServer(opts ...ServerOption) *Server {
@@ -62,6 +77,7 @@ func NewServer(opts ...ServerOption) *Server {
 		timeout: 1 * time.Second,
 
 		messageHandlers: make(MessageHandlerMap),
+		messageCodecs:   make(map[MessageType]encoding.Codec),
 
 		sessions: SessionMap{},
 
@@ -208,12 +224,12 @@ func (s *Server) marshalMessage(messageType MessageType, message MessagePayload)
 	var err error
 	var msg Message
 	msg.Type = messageType
-	msg.Body, err = broker.Marshal(s.codec, message)
+	msg.Body, err = broker.Marshal(s.codecOf(messageType), message)
 	if err != nil {
 		return nil, err
 	}
 
-	buff, err := msg.Marshal()
+	buff, err := s.encodeFrame(&msg)
 	if err != nil {
 		return nil, err
 	}
@@ -230,8 +246,8 @@ func (s *Server) messageHandler(sessionId SessionID, buf []byte) error {
 		return nil
 	}
 
-	var msg Message
-	if err := msg.Unmarshal(buf); err != nil {
+	msg, err := s.decodeFrame(buf)
+	if err != nil {
 		LogErrorf("decode message exception: %s", err)
 		return err
 	}
@@ -250,7 +266,7 @@ func (s *Server) messageHandler(sessionId SessionID, buf []byte) error {
 		payload = msg.Body
 	}
 
-	if err := broker.Unmarshal(s.codec, msg.Body, &payload); err != nil {
+	if err := broker.Unmarshal(s.codecOf(msg.Type), msg.Body, &payload); err != nil {
 		LogErrorf("unmarshal message exception: %s", err)
 		return err
 	}
@@ -269,6 +285,9 @@ func (s *Server) listen() error {
 		if err != nil {
 			return err
 		}
+		if s.tlsConf != nil {
+			lis = tls.NewListener(lis, s.tlsConf)
+		}
 		s.lis = lis
 	}
 
@@ -294,15 +313,28 @@ func (s *Server) doAccept() {
 
 		conn, err := s.lis.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
 			LogError("accept exception:", err)
 			continue
 		}
 
-		session := NewSession(conn, s)
-		session.server.register <- session
+		go s.handleConn(conn)
+	}
+}
 
-		session.Listen()
+func (s *Server) handleConn(conn net.Conn) {
+	ctx, ok := s.authenticate(conn)
+	if !ok {
+		return
 	}
+
+	session := NewSession(conn, s)
+	session.ctx = ctx
+	session.server.register <- session
+
+	session.Listen()
 }
 
 func (s *Server) addSession(c *Session) {
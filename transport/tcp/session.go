@@ -1,9 +1,15 @@
 package tcp
 
 import (
+	"bufio"
+	"context"
 	"net"
+	"time"
 
 	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+
+	"github.com/tx7do/kratos-transport/utils"
 )
 
 var channelBufSize = 256
@@ -13,9 +19,12 @@ type SessionID string
 
 type Session struct {
 	id     SessionID
+	ctx    context.Context
 	conn   net.Conn
 	send   chan []byte
 	server *Server
+
+	limiter *rate.Limiter
 }
 
 type SessionMap map[SessionID]*Session
@@ -29,9 +38,12 @@ func NewSession(conn net.Conn, server *Server) *Session {
 
 	c := &Session{
 		id:     SessionID(u1.String()),
+		ctx:    context.Background(),
 		conn:   conn,
 		send:   make(chan []byte, channelBufSize),
 		server: server,
+
+		limiter: utils.NewRateLimiter(server.messageRate, server.messageBurst),
 	}
 
 	return c
@@ -45,9 +57,28 @@ func (c *Session) SessionID() SessionID {
 	return c.id
 }
 
+// Context carry the identity of the authenticated client, see auth.FromContext.
+func (c *Session) Context() context.Context {
+	return c.ctx
+}
+
 func (c *Session) SendMessage(message []byte) {
-	select {
-	case c.send <- message:
+	switch c.server.slowConsumerPolicy {
+	case OverflowDrop:
+		select {
+		case c.send <- message:
+		default:
+			LogWarnf("[tcp] session [%s] send buffer full, drop message", c.id)
+		}
+	case OverflowDisconnect:
+		select {
+		case c.send <- message:
+		default:
+			LogWarnf("[tcp] session [%s] send buffer full, disconnect", c.id)
+			c.kick()
+		}
+	default:
+		c.send <- message
 	}
 }
 
@@ -81,6 +112,9 @@ func (c *Session) writePump() {
 			if c.conn == nil {
 				return
 			}
+			if c.server.writeTimeout > 0 {
+				_ = c.conn.SetWriteDeadline(time.Now().Add(c.server.writeTimeout))
+			}
 			var err error
 			if _, err = c.conn.Write(msg); err != nil {
 				LogError("[tcp] write message error: ", err)
@@ -93,7 +127,17 @@ func (c *Session) writePump() {
 func (c *Session) readPump() {
 	defer c.Close()
 
-	buf := make([]byte, recvBufferSize)
+	if c.server.frameCodec != nil {
+		c.readFrames()
+		return
+	}
+
+	size := recvBufferSize
+	if limit := c.server.maxMessageSize; limit > 0 && limit+1 > size {
+		// one more byte to tell an oversized read
+		size = limit + 1
+	}
+	buf := make([]byte, size)
 	var err error
 	var readLen int
 
@@ -107,8 +151,47 @@ func (c *Session) readPump() {
 			return
 		}
 
+		if limit := c.server.maxMessageSize; limit > 0 && readLen > limit {
+			LogWarnf("[tcp] session [%s] message over %d bytes, disconnect", c.id, limit)
+			return
+		}
+
+		if !c.allowMessage() {
+			continue
+		}
+
 		if err = c.server.messageHandler(c.SessionID(), buf[:readLen]); err != nil {
 			LogErrorf("[tcp] process message error: %v", err)
 		}
 	}
 }
+
+// readFrames read the messages cut by the frame codec of the server.
+func (c *Session) readFrames() {
+	conn := c.conn
+	if conn == nil {
+		return
+	}
+
+	size := recvBufferSize
+	if c.server.maxMessageSize > 0 {
+		size = c.server.maxMessageSize
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, min(size, 4096)), size)
+	scanner.Split(c.server.frameCodec.FrameSplit)
+
+	for scanner.Scan() {
+		if !c.allowMessage() {
+			continue
+		}
+		if err := c.server.messageHandler(c.SessionID(), scanner.Bytes()); err != nil {
+			LogErrorf("[tcp] process message error: %v", err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		LogErrorf("[tcp] read message error: %v", err)
+	}
+}
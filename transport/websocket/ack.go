@@ -0,0 +1,394 @@
+package websocket
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	ws "github.com/gorilla/websocket"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Message types reserved by the acknowledged delivery.
+const (
+	AckDeliverMessageType MessageType = 0xFFFF0011
+	AckMessageType        MessageType = 0xFFFF0012
+	AckResumeMessageType  MessageType = 0xFFFF0013
+)
+
+const (
+	defaultAckRetryInterval = 5 * time.Second
+	defaultResumeTimeout    = time.Minute
+)
+
+var (
+	ErrAckBufferFull   = errors.New("ack: session buffer full")
+	ErrResumeNotFound  = errors.New("ack: resume token not found")
+	errAckStreamClosed = errors.New("ack: stream closed")
+)
+
+// AckDelivery is the body of a message delivered at least once, the client
+// acknowledges it with the sequence.
+type AckDelivery struct {
+	Seq  uint64      `json:"seq" xml:"seq"`
+	Type MessageType `json:"type" xml:"type"`
+	Body string      `json:"body" xml:"body"`
+}
+
+// AckRequest acknowledge every message up to Seq.
+type AckRequest struct {
+	Seq uint64 `json:"seq" xml:"seq"`
+}
+
+// AckResume is sent by the server with the resume token of a new session,
+// and by a reconnecting client with the token and the last sequence it
+// received. The server answers with the token of the resumed stream.
+type AckResume struct {
+	Token string `json:"token" xml:"token"`
+	Seq   uint64 `json:"seq" xml:"seq"`
+}
+
+type ackPending struct {
+	seq         uint64
+	messageType MessageType
+	body        string
+	sentAt      time.Time
+}
+
+// ackStream holds the unacknowledged messages of a session, it outlives the
+// session for the resume timeout.
+type ackStream struct {
+	mtx sync.Mutex
+
+	token   string
+	session *Session
+	seq     uint64
+	acked   uint64
+	pending []*ackPending
+
+	detachedAt time.Time
+
+	space  chan struct{}
+	closed chan struct{}
+}
+
+type ackTracker struct {
+	mtx sync.Mutex
+
+	bufferSize    int
+	retryInterval time.Duration
+	policy        OverflowPolicy
+	resumeTimeout time.Duration
+
+	streams  map[SessionID]*ackStream
+	detached map[string]*ackStream
+
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+func newAckTracker(bufferSize int, retryInterval time.Duration, policy OverflowPolicy) *ackTracker {
+	if retryInterval <= 0 {
+		retryInterval = defaultAckRetryInterval
+	}
+	return &ackTracker{
+		bufferSize:    bufferSize,
+		retryInterval: retryInterval,
+		policy:        policy,
+		resumeTimeout: defaultResumeTimeout,
+		streams:       make(map[SessionID]*ackStream),
+		detached:      make(map[string]*ackStream),
+		quit:          make(chan struct{}),
+	}
+}
+
+func newAckStream(c *Session) *ackStream {
+	return &ackStream{
+		token:   uuid.New().String(),
+		session: c,
+		space:   make(chan struct{}, 1),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (s *Server) registerAckHandlers() {
+	RegisterServerMessageHandler(s, AckMessageType, func(sessionId SessionID, req *AckRequest) error {
+		s.acks.ack(sessionId, req.Seq)
+		return nil
+	})
+	RegisterServerMessageHandler(s, AckResumeMessageType, func(sessionId SessionID, req *AckResume) error {
+		return s.acks.resume(s, sessionId, req)
+	})
+}
+
+// attach start the stream of a new session and send it its resume token.
+func (t *ackTracker) attach(s *Server, c *Session) {
+	st := newAckStream(c)
+
+	t.mtx.Lock()
+	t.streams[c.id] = st
+	t.mtx.Unlock()
+
+	s.sendAckResume(c, st.token, 0)
+}
+
+// detach keep the stream of a closed session for the resume timeout.
+func (t *ackTracker) detach(c *Session) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	st, ok := t.streams[c.id]
+	if !ok {
+		return
+	}
+	delete(t.streams, c.id)
+
+	st.mtx.Lock()
+	st.session = nil
+	st.detachedAt = time.Now()
+	st.mtx.Unlock()
+
+	t.detached[st.token] = st
+}
+
+func (t *ackTracker) stream(sessionId SessionID) *ackStream {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.streams[sessionId]
+}
+
+// send number a message and deliver it, it stays buffered until acknowledged.
+func (t *ackTracker) send(s *Server, c *Session, messageType MessageType, message MessagePayload) error {
+	st := t.stream(c.id)
+	if st == nil {
+		return ErrResumeNotFound
+	}
+
+	body, err := broker.Marshal(s.codecOf(messageType), message)
+	if err != nil {
+		return err
+	}
+
+	p := &ackPending{messageType: messageType, body: string(body)}
+	if err = t.enqueue(c.id, st, p); err != nil {
+		return err
+	}
+
+	return s.deliverAck(c, p)
+}
+
+func (t *ackTracker) enqueue(sessionId SessionID, st *ackStream, p *ackPending) error {
+	for {
+		st.mtx.Lock()
+		if t.bufferSize <= 0 || len(st.pending) < t.bufferSize {
+			st.seq++
+			p.seq = st.seq
+			p.sentAt = time.Now()
+			st.pending = append(st.pending, p)
+			st.mtx.Unlock()
+			return nil
+		}
+		session := st.session
+		st.mtx.Unlock()
+
+		switch t.policy {
+		case OverflowDrop:
+			LogWarnf("session [%s] ack buffer full, drop message", sessionId)
+			return ErrAckBufferFull
+		case OverflowDisconnect:
+			LogWarnf("session [%s] ack buffer full, disconnect", sessionId)
+			if session != nil {
+				session.kick(ws.CloseTryAgainLater, "ack buffer full")
+			}
+			return ErrAckBufferFull
+		default:
+			select {
+			case <-st.space:
+			case <-st.closed:
+				return errAckStreamClosed
+			}
+		}
+	}
+}
+
+// ack drop the messages of a session up to seq.
+func (t *ackTracker) ack(sessionId SessionID, seq uint64) {
+	if st := t.stream(sessionId); st != nil {
+		st.ack(seq)
+	}
+}
+
+func (st *ackStream) ack(seq uint64) {
+	st.mtx.Lock()
+	defer st.mtx.Unlock()
+
+	if seq <= st.acked {
+		return
+	}
+	st.acked = seq
+
+	i := 0
+	for i < len(st.pending) && st.pending[i].seq <= seq {
+		i++
+	}
+	st.pending = st.pending[i:]
+
+	select {
+	case st.space <- struct{}{}:
+	default:
+	}
+}
+
+func (st *ackStream) close() {
+	select {
+	case <-st.closed:
+	default:
+		close(st.closed)
+	}
+}
+
+// resume move the stream of token onto the session, renumbering the messages
+// sent to the session meanwhile, and resend all unacknowledged messages.
+func (t *ackTracker) resume(s *Server, sessionId SessionID, req *AckResume) error {
+	t.mtx.Lock()
+	cur, ok := t.streams[sessionId]
+	if !ok {
+		t.mtx.Unlock()
+		return ErrResumeNotFound
+	}
+	old, ok := t.detached[req.Token]
+	if !ok {
+		t.mtx.Unlock()
+		s.sendAckResume(cur.session, cur.token, 0)
+		return ErrResumeNotFound
+	}
+	delete(t.detached, req.Token)
+	t.streams[sessionId] = old
+	t.mtx.Unlock()
+
+	old.ack(req.Seq)
+
+	cur.mtx.Lock()
+	moved := cur.pending
+	cur.pending = nil
+	session := cur.session
+	cur.mtx.Unlock()
+	cur.close()
+
+	old.mtx.Lock()
+	old.session = session
+	old.detachedAt = time.Time{}
+	for _, p := range moved {
+		old.seq++
+		p.seq = old.seq
+		old.pending = append(old.pending, p)
+	}
+	acked := old.acked
+	pending := append([]*ackPending(nil), old.pending...)
+	old.mtx.Unlock()
+
+	s.sendAckResume(session, old.token, acked)
+
+	for _, p := range pending {
+		_ = s.deliverAck(session, p)
+	}
+
+	return nil
+}
+
+// run resend the messages not acknowledged in the retry interval, and
+// expire the streams not resumed in the resume timeout.
+func (t *ackTracker) run(s *Server) {
+	ticker := time.NewTicker(t.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.quit:
+			return
+		case <-ticker.C:
+			t.retry(s)
+			t.expire()
+		}
+	}
+}
+
+func (t *ackTracker) stop() {
+	t.quitOnce.Do(func() {
+		close(t.quit)
+	})
+}
+
+func (t *ackTracker) retry(s *Server) {
+	t.mtx.Lock()
+	streams := make([]*ackStream, 0, len(t.streams))
+	for _, st := range t.streams {
+		streams = append(streams, st)
+	}
+	t.mtx.Unlock()
+
+	now := time.Now()
+	for _, st := range streams {
+		var due []*ackPending
+
+		st.mtx.Lock()
+		session := st.session
+		for _, p := range st.pending {
+			if now.Sub(p.sentAt) >= t.retryInterval {
+				p.sentAt = now
+				due = append(due, p)
+			}
+		}
+		st.mtx.Unlock()
+
+		for _, p := range due {
+			_ = s.deliverAck(session, p)
+		}
+	}
+}
+
+func (t *ackTracker) expire() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for token, st := range t.detached {
+		if time.Since(st.detachedAt) > t.resumeTimeout {
+			delete(t.detached, token)
+			st.close()
+		}
+	}
+}
+
+func (s *Server) deliverAck(c *Session, p *ackPending) error {
+	if c == nil {
+		return nil
+	}
+
+	buf, err := s.marshalMessage(AckDeliverMessageType, &AckDelivery{
+		Seq:  p.seq,
+		Type: p.messageType,
+		Body: p.body,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.SendMessage(buf)
+	return nil
+}
+
+func (s *Server) sendAckResume(c *Session, token string, seq uint64) {
+	if c == nil {
+		return
+	}
+
+	buf, err := s.marshalMessage(AckResumeMessageType, &AckResume{Token: token, Seq: seq})
+	if err != nil {
+		LogError("marshal message exception:", err)
+		return
+	}
+
+	c.SendMessage(buf)
+}
@@ -0,0 +1,140 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func readAckMessage(t *testing.T, conn *ws.Conn, messageType MessageType, v any) {
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, buf, err := conn.ReadMessage()
+	assert.NoError(t, err)
+
+	var msg TextMessage
+	assert.NoError(t, msg.Unmarshal(buf))
+	assert.Equal(t, messageType, msg.Type)
+	assert.NoError(t, json.Unmarshal([]byte(msg.Body), v))
+}
+
+func writeAckMessage(t *testing.T, conn *ws.Conn, messageType MessageType, v any) {
+	body, _ := json.Marshal(v)
+	buf, _ := json.Marshal(&TextMessage{Type: messageType, Body: string(body)})
+	assert.NoError(t, conn.WriteMessage(ws.TextMessage, buf))
+}
+
+func newAckTestServer(t *testing.T, path string, opts ...ServerOption) (*Server, string, func()) {
+	srv := NewServer(append([]ServerOption{
+		WithPath(path),
+		WithCodec("json"),
+		WithPayloadType(PayloadTypeText),
+	}, opts...)...)
+	go srv.run()
+
+	hs := httptest.NewServer(http.HandlerFunc(srv.wsHandler))
+	return srv, "ws" + strings.TrimPrefix(hs.URL, "http"), func() {
+		srv.acks.stop()
+		hs.Close()
+	}
+}
+
+func onlySession(t *testing.T, srv *Server) SessionID {
+	assert.Eventually(t, func() bool { return srv.SessionCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+	return srv.sessions()[0].SessionID()
+}
+
+func TestAckDelivery(t *testing.T) {
+	srv, url, closeFn := newAckTestServer(t, "/ack-delivery-test", WithAckDelivery(8, 50*time.Millisecond, OverflowDrop))
+	defer closeFn()
+
+	conn, _, err := ws.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	var resume AckResume
+	readAckMessage(t, conn, AckResumeMessageType, &resume)
+	assert.NotEmpty(t, resume.Token)
+
+	srv.SendMessage(onlySession(t, srv), MessageTypeChat, &ChatMessage{Message: "hi"})
+
+	var delivery AckDelivery
+	readAckMessage(t, conn, AckDeliverMessageType, &delivery)
+	assert.Equal(t, uint64(1), delivery.Seq)
+	assert.Equal(t, MessageType(MessageTypeChat), delivery.Type)
+
+	var chat ChatMessage
+	assert.NoError(t, json.Unmarshal([]byte(delivery.Body), &chat))
+	assert.Equal(t, "hi", chat.Message)
+
+	// not acknowledged yet, so it is resent
+	readAckMessage(t, conn, AckDeliverMessageType, &delivery)
+	assert.Equal(t, uint64(1), delivery.Seq)
+
+	writeAckMessage(t, conn, AckMessageType, &AckRequest{Seq: 1})
+	assert.Eventually(t, func() bool {
+		st := srv.acks.stream(onlySession(t, srv))
+		st.mtx.Lock()
+		defer st.mtx.Unlock()
+		return len(st.pending) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestAckResume(t *testing.T) {
+	srv, url, closeFn := newAckTestServer(t, "/ack-resume-test", WithAckDelivery(8, time.Minute, OverflowDrop))
+	defer closeFn()
+
+	conn, _, err := ws.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+
+	var resume AckResume
+	readAckMessage(t, conn, AckResumeMessageType, &resume)
+	token := resume.Token
+
+	sessionId := onlySession(t, srv)
+	srv.SendMessage(sessionId, MessageTypeChat, &ChatMessage{Message: "1"})
+	srv.SendMessage(sessionId, MessageTypeChat, &ChatMessage{Message: "2"})
+
+	var delivery AckDelivery
+	readAckMessage(t, conn, AckDeliverMessageType, &delivery)
+	readAckMessage(t, conn, AckDeliverMessageType, &delivery)
+	_ = conn.Close()
+	assert.Eventually(t, func() bool { return srv.SessionCount() == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	conn, _, err = ws.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	readAckMessage(t, conn, AckResumeMessageType, &resume)
+	assert.NotEqual(t, token, resume.Token)
+
+	writeAckMessage(t, conn, AckResumeMessageType, &AckResume{Token: token, Seq: 1})
+
+	readAckMessage(t, conn, AckResumeMessageType, &resume)
+	assert.Equal(t, token, resume.Token)
+	assert.Equal(t, uint64(1), resume.Seq)
+
+	readAckMessage(t, conn, AckDeliverMessageType, &delivery)
+	assert.Equal(t, uint64(2), delivery.Seq)
+
+	var chat ChatMessage
+	assert.NoError(t, json.Unmarshal([]byte(delivery.Body), &chat))
+	assert.Equal(t, "2", chat.Message)
+}
+
+func TestAckBufferFull(t *testing.T) {
+	tracker := newAckTracker(1, time.Minute, OverflowDrop)
+	st := newAckStream(nil)
+
+	assert.NoError(t, tracker.enqueue("s1", st, &ackPending{}))
+	assert.Equal(t, ErrAckBufferFull, tracker.enqueue("s1", st, &ackPending{}))
+
+	st.ack(1)
+	assert.NoError(t, tracker.enqueue("s1", st, &ackPending{}))
+	assert.Equal(t, uint64(2), st.pending[0].seq)
+}
@@ -0,0 +1,53 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+
+	"github.com/tx7do/kratos-transport/transport/auth"
+)
+
+// Identity return the identity of an authenticated session.
+func (s *Server) Identity(sessionId SessionID) (*auth.Identity, bool) {
+	session, ok := s.sessionMgr.Get(sessionId)
+	if !ok {
+		return nil, false
+	}
+	return auth.FromContext(session.Context())
+}
+
+// authenticate return the context of the session of req, or reject it.
+func (s *Server) authenticate(res http.ResponseWriter, req *http.Request) (context.Context, bool) {
+	ctx := context.Background()
+	if s.authenticator == nil {
+		return ctx, true
+	}
+
+	id, err := s.authenticator.Authenticate(req.Context(), auth.NewHTTPRequest(req))
+	if err == nil {
+		return auth.NewContext(ctx, id), true
+	}
+
+	LogErrorf("authenticate [%s] failed: %s", req.RemoteAddr, err)
+
+	if s.authCloseCode == 0 {
+		http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return nil, false
+	}
+
+	conn, err := s.upgrader.Upgrade(res, req, nil)
+	if err != nil {
+		LogError("upgrade exception:", err)
+		return nil, false
+	}
+	_ = conn.WriteControl(ws.CloseMessage,
+		ws.FormatCloseMessage(s.authCloseCode, "unauthorized"),
+		time.Now().Add(s.timeout),
+	)
+	_ = conn.Close()
+
+	return nil, false
+}
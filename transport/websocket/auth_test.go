@@ -0,0 +1,70 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/transport/auth"
+	"github.com/tx7do/kratos-transport/transport/presence"
+)
+
+func TestAuthenticator(t *testing.T) {
+	m := presence.NewManager()
+
+	srv := NewServer(
+		WithPath("/auth-test"),
+		WithPresence(m),
+		WithAuthenticator(auth.APIKey("X-Api-Key", map[string]string{"k1": "alice"})),
+		WithAuthCloseCode(4401),
+	)
+	go srv.run()
+
+	hs := httptest.NewServer(http.HandlerFunc(srv.wsHandler))
+	defer hs.Close()
+	url := "ws" + strings.TrimPrefix(hs.URL, "http")
+
+	conn, _, err := ws.DefaultDialer.Dial(url, http.Header{"X-Api-Key": []string{"k2"}})
+	assert.NoError(t, err)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, ws.IsCloseError(err, 4401))
+	_ = conn.Close()
+
+	conn, _, err = ws.DefaultDialer.Dial(url+"?X-Api-Key=k1", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	assert.Eventually(t, func() bool { return srv.SessionCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	var id SessionID
+	srv.sessionMgr.Range(func(s *Session) { id = s.SessionID() })
+	identity, ok := srv.Identity(id)
+	assert.True(t, ok)
+	assert.Equal(t, "alice", identity.Subject)
+
+	online, err := m.IsOnline(context.Background(), "alice")
+	assert.NoError(t, err)
+	assert.True(t, online)
+}
+
+func TestAuthenticator_HTTPStatus(t *testing.T) {
+	srv := NewServer(
+		WithPath("/auth-status-test"),
+		WithAuthenticator(auth.JWT([]byte("secret"))),
+		WithAuthCloseCode(0),
+	)
+
+	hs := httptest.NewServer(http.HandlerFunc(srv.wsHandler))
+	defer hs.Close()
+
+	_, resp, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(hs.URL, "http"), nil)
+	assert.ErrorIs(t, err, ws.ErrBadHandshake)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
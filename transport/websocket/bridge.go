@@ -0,0 +1,281 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// Message types reserved by the broker bridge.
+const (
+	BridgeSubscribeMessageType   MessageType = 0xFFFF0001
+	BridgeUnsubscribeMessageType MessageType = 0xFFFF0002
+	BridgePublishMessageType     MessageType = 0xFFFF0003
+	BridgePushMessageType        MessageType = 0xFFFF0004
+)
+
+type BridgeAction string
+
+const (
+	BridgeActionPublish   BridgeAction = "publish"
+	BridgeActionSubscribe BridgeAction = "subscribe"
+)
+
+var ErrBridgeForbidden = errors.New("bridge: action not allowed")
+
+// BridgeAuthorizer reports whether a session may publish to or subscribe to
+// a topic. For subscriptions topic is the filter sent by the client.
+type BridgeAuthorizer func(sessionId SessionID, action BridgeAction, topic string) bool
+
+// BridgeRequest is the body of the subscribe and unsubscribe messages, Topic
+// is a filter in path.Match syntax, e.g. "orders.*".
+type BridgeRequest struct {
+	Topic string `json:"topic" xml:"topic"`
+}
+
+// BridgeMessage is the body of the publish and push messages. Topic and
+// Headers are only set on pushed messages.
+type BridgeMessage struct {
+	Topic   string            `json:"topic,omitempty" xml:"topic,omitempty"`
+	Headers map[string]string `json:"headers,omitempty" xml:"headers,omitempty"`
+	Body    string            `json:"body" xml:"body"`
+}
+
+type BridgeOption func(b *bridge)
+
+// WithBridgeTopics set the broker topics pushed to subscribed sessions.
+func WithBridgeTopics(topics ...string) BridgeOption {
+	return func(b *bridge) {
+		b.topics = append(b.topics, topics...)
+	}
+}
+
+// WithBridgeSharedTopic publish the messages of all sessions to topic.
+func WithBridgeSharedTopic(topic string) BridgeOption {
+	return func(b *bridge) {
+		b.sharedTopic = topic
+	}
+}
+
+// WithBridgeSessionTopic publish the messages of every session to prefix
+// followed by its session id, it takes precedence over the shared topic.
+func WithBridgeSessionTopic(prefix string) BridgeOption {
+	return func(b *bridge) {
+		b.sessionTopicPrefix = prefix
+	}
+}
+
+// WithBridgeAuthorizer check publishes and subscriptions, default allows all.
+func WithBridgeAuthorizer(fn BridgeAuthorizer) BridgeOption {
+	return func(b *bridge) {
+		b.authorize = fn
+	}
+}
+
+// WithBridgeSubscribeOptions pass options to the broker subscriptions.
+func WithBridgeSubscribeOptions(opts ...broker.SubscribeOption) BridgeOption {
+	return func(b *bridge) {
+		b.subscribeOpts = append(b.subscribeOpts, opts...)
+	}
+}
+
+// bridge publishes the messages of sessions to broker topics and pushes the
+// messages of broker topics to the sessions whose filters match them.
+type bridge struct {
+	mtx sync.RWMutex
+
+	b broker.Broker
+
+	topics             []string
+	sharedTopic        string
+	sessionTopicPrefix string
+	authorize          BridgeAuthorizer
+	subscribeOpts      []broker.SubscribeOption
+
+	subs    []broker.Subscriber
+	filters map[SessionID]map[string]struct{}
+}
+
+func newBridge(b broker.Broker, opts ...BridgeOption) *bridge {
+	br := &bridge{
+		b:       b,
+		filters: make(map[SessionID]map[string]struct{}),
+	}
+
+	for _, o := range opts {
+		o(br)
+	}
+
+	return br
+}
+
+func (s *Server) registerBridgeHandlers() {
+	RegisterServerMessageHandler(s, BridgeSubscribeMessageType, func(sessionId SessionID, req *BridgeRequest) error {
+		return s.bridge.subscribe(sessionId, req.Topic)
+	})
+	RegisterServerMessageHandler(s, BridgeUnsubscribeMessageType, func(sessionId SessionID, req *BridgeRequest) error {
+		s.bridge.unsubscribe(sessionId, req.Topic)
+		return nil
+	})
+	RegisterServerMessageHandler(s, BridgePublishMessageType, func(sessionId SessionID, msg *BridgeMessage) error {
+		return s.bridge.publish(sessionId, msg)
+	})
+}
+
+func (b *bridge) start(s *Server) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if len(b.subs) > 0 {
+		return nil
+	}
+
+	for _, topic := range b.topics {
+		sub, err := b.b.Subscribe(topic,
+			func(_ context.Context, event broker.Event) error {
+				return b.push(s, event)
+			},
+			nil,
+			b.subscribeOpts...,
+		)
+		if err != nil {
+			b.unsubscribeAll()
+			return err
+		}
+		b.subs = append(b.subs, sub)
+	}
+
+	return nil
+}
+
+func (b *bridge) stop() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.unsubscribeAll()
+}
+
+func (b *bridge) unsubscribeAll() {
+	for _, sub := range b.subs {
+		if err := sub.Unsubscribe(true); err != nil {
+			LogErrorf("bridge unsubscribe [%s] failed: %s", sub.Topic(), err)
+		}
+	}
+	b.subs = nil
+}
+
+func (b *bridge) allowed(sessionId SessionID, action BridgeAction, topic string) bool {
+	return b.authorize == nil || b.authorize(sessionId, action, topic)
+}
+
+func (b *bridge) subscribe(sessionId SessionID, filter string) error {
+	if filter == "" {
+		return errors.New("bridge: missing topic")
+	}
+	if _, err := path.Match(filter, ""); err != nil {
+		return fmt.Errorf("bridge: invalid topic filter [%s]: %w", filter, err)
+	}
+	if !b.allowed(sessionId, BridgeActionSubscribe, filter) {
+		return ErrBridgeForbidden
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	filters, ok := b.filters[sessionId]
+	if !ok {
+		filters = make(map[string]struct{})
+		b.filters[sessionId] = filters
+	}
+	filters[filter] = struct{}{}
+
+	return nil
+}
+
+func (b *bridge) unsubscribe(sessionId SessionID, filter string) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if filters, ok := b.filters[sessionId]; ok {
+		delete(filters, filter)
+		if len(filters) == 0 {
+			delete(b.filters, sessionId)
+		}
+	}
+}
+
+func (b *bridge) removeSession(sessionId SessionID) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	delete(b.filters, sessionId)
+}
+
+func (b *bridge) publishTopic(sessionId SessionID) string {
+	if b.sessionTopicPrefix != "" {
+		return b.sessionTopicPrefix + string(sessionId)
+	}
+	return b.sharedTopic
+}
+
+func (b *bridge) publish(sessionId SessionID, msg *BridgeMessage) error {
+	topic := b.publishTopic(sessionId)
+	if topic == "" {
+		return errors.New("bridge: no publish topic configured")
+	}
+	if !b.allowed(sessionId, BridgeActionPublish, topic) {
+		return ErrBridgeForbidden
+	}
+
+	return b.b.Publish(context.Background(), topic, []byte(msg.Body))
+}
+
+// push send a broker message to every session with a matching filter.
+func (b *bridge) push(s *Server, event broker.Event) error {
+	msg := event.Message()
+	if msg == nil {
+		return nil
+	}
+
+	body, err := stompBody(s.codec, msg.Body)
+	if err != nil {
+		return err
+	}
+
+	b.mtx.RLock()
+	var targets []SessionID
+	for sessionId, filters := range b.filters {
+		for filter := range filters {
+			if ok, _ := path.Match(filter, event.Topic()); ok {
+				targets = append(targets, sessionId)
+				break
+			}
+		}
+	}
+	b.mtx.RUnlock()
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	buf, err := s.marshalMessage(BridgePushMessageType, &BridgeMessage{
+		Topic:   event.Topic(),
+		Headers: msg.Headers,
+		Body:    string(body),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sessionId := range targets {
+		if session, ok := s.sessionMgr.Get(sessionId); ok {
+			session.SendMessage(buf)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+func TestBrokerBridge(t *testing.T) {
+	b := &stompTestBroker{handlers: map[string]broker.Handler{}}
+
+	srv := NewServer(
+		WithCodec("json"),
+		WithPath("/bridge-test"),
+		WithBrokerBridge(b,
+			WithBridgeTopics("chat.room1", "chat.room2"),
+			WithBridgeSharedTopic("chat.room1"),
+			WithBridgeAuthorizer(func(_ SessionID, action BridgeAction, topic string) bool {
+				return action != BridgeActionSubscribe || topic != "chat.room2"
+			}),
+		),
+	)
+	go srv.run()
+	assert.NoError(t, srv.bridge.start(srv))
+	defer srv.bridge.stop()
+
+	hs := httptest.NewServer(http.HandlerFunc(srv.wsHandler))
+	defer hs.Close()
+
+	conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(hs.URL, "http"), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	send := func(messageType MessageType, v interface{}) {
+		body, err := json.Marshal(v)
+		assert.NoError(t, err)
+		msg := BinaryMessage{Type: messageType, Body: body}
+		buf, err := msg.Marshal()
+		assert.NoError(t, err)
+		assert.NoError(t, conn.WriteMessage(ws.BinaryMessage, buf))
+	}
+	read := func() *BridgeMessage {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, data, err := conn.ReadMessage()
+		assert.NoError(t, err)
+
+		var msg BinaryMessage
+		assert.NoError(t, msg.Unmarshal(data))
+		assert.Equal(t, BridgePushMessageType, msg.Type)
+
+		var bm BridgeMessage
+		assert.NoError(t, json.Unmarshal(msg.Body, &bm))
+		return &bm
+	}
+
+	send(BridgeSubscribeMessageType, &BridgeRequest{Topic: "chat.room2"})
+	send(BridgeSubscribeMessageType, &BridgeRequest{Topic: "chat.room*"})
+	send(BridgePublishMessageType, &BridgeMessage{Body: "hello"})
+
+	bm := read()
+	assert.Equal(t, "chat.room1", bm.Topic)
+	assert.Equal(t, "hello", bm.Body)
+	assert.Equal(t, "text/plain", bm.Headers["content-type"])
+
+	assert.NoError(t, b.Publish(context.Background(), "chat.room2", []byte("world")))
+	bm = read()
+	assert.Equal(t, "chat.room2", bm.Topic)
+	assert.Equal(t, "world", bm.Body)
+
+	send(BridgeUnsubscribeMessageType, &BridgeRequest{Topic: "chat.room*"})
+	assert.Eventually(t, func() bool {
+		srv.bridge.mtx.RLock()
+		defer srv.bridge.mtx.RUnlock()
+		return len(srv.bridge.filters) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestBrokerBridge_PublishTopic(t *testing.T) {
+	br := newBridge(nil, WithBridgeSharedTopic("chat"))
+	assert.Equal(t, "chat", br.publishTopic("s1"))
+
+	br = newBridge(nil, WithBridgeSharedTopic("chat"), WithBridgeSessionTopic("chat.session."))
+	assert.Equal(t, "chat.session.s1", br.publishTopic("s1"))
+
+	br = newBridge(nil, WithBridgeAuthorizer(func(SessionID, BridgeAction, string) bool { return false }))
+	assert.ErrorIs(t, br.subscribe("s1", "chat"), ErrBridgeForbidden)
+	assert.NotNil(t, br.subscribe("s1", "chat["))
+}
@@ -0,0 +1,62 @@
+package websocket
+
+import (
+	"errors"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/framing"
+)
+
+// RegisterMessageCodec encode the payloads of messageType with the codec
+// named codec instead of the codec of the server.
+func (s *Server) RegisterMessageCodec(messageType MessageType, codec string) {
+	s.messageCodecs[messageType] = encoding.GetCodec(codec)
+}
+
+func (s *Server) codecOf(messageType MessageType) encoding.Codec {
+	if c, ok := s.messageCodecs[messageType]; ok {
+		return c
+	}
+	return s.codec
+}
+
+// encodeFrame encode a message into a frame of the frame codec, every
+// websocket message carries exactly one frame.
+func (s *Server) encodeFrame(messageType MessageType, message MessagePayload) ([]byte, error) {
+	body, err := broker.Marshal(s.codecOf(messageType), message)
+	if err != nil {
+		return nil, err
+	}
+	return s.frameCodec.Marshal(&framing.Frame{Type: uint32(messageType), Body: body})
+}
+
+func (s *Server) decodeFrame(buf []byte) (*HandlerData, MessagePayload, error) {
+	f, err := s.frameCodec.Unmarshal(buf)
+	if err != nil {
+		LogErrorf("decode message exception: %s", err)
+		return nil, nil, err
+	}
+
+	messageType := MessageType(f.Type)
+	handler, ok := s.messageHandlers[messageType]
+	if !ok {
+		LogError("message handler not found:", messageType)
+		return nil, nil, errors.New("message handler not found")
+	}
+
+	var payload MessagePayload
+	if handler.Binder != nil {
+		payload = handler.Binder()
+	} else {
+		payload = f.Body
+	}
+
+	if err = broker.Unmarshal(s.codecOf(messageType), f.Body, &payload); err != nil {
+		LogErrorf("unmarshal message exception: %s", err)
+		return nil, nil, err
+	}
+
+	return handler, payload, nil
+}
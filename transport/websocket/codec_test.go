@@ -0,0 +1,59 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+	_ "github.com/go-kratos/kratos/v2/encoding/proto"
+	ws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/transport/framing"
+)
+
+func TestFrameCodec(t *testing.T) {
+	srv := NewServer(
+		WithPath("/frame-codec-test"),
+		WithCodec("json"),
+		WithPayloadType(PayloadTypeText),
+		WithFrameCodec(framing.JSONLines()),
+	)
+
+	received := make(chan *ChatMessage, 1)
+	RegisterServerMessageHandler(srv, MessageTypeChat, func(sessionId SessionID, msg *ChatMessage) error {
+		received <- msg
+		srv.SendMessage(sessionId, MessageTypeChat, msg)
+		return nil
+	})
+
+	conn, closeFn := dialTestServer(t, srv)
+	defer closeFn()
+
+	assert.NoError(t, conn.WriteMessage(ws.TextMessage, []byte(`{"type":1,"body":{"sender":"a","message":"hi"}}`+"\n")))
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "hi", msg.Message)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	mt, buf, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, ws.TextMessage, mt)
+
+	f, err := framing.JSONLines().Unmarshal(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(MessageTypeChat), f.Type)
+	assert.JSONEq(t, `{"type":0,"sender":"a","message":"hi"}`, string(f.Body))
+}
+
+func TestRegisterMessageCodec(t *testing.T) {
+	srv := NewServer(WithPath("/message-codec-test"), WithCodec("json"))
+	srv.RegisterMessageCodec(2, "proto")
+
+	assert.Equal(t, "json", srv.codecOf(MessageTypeChat).Name())
+	assert.Equal(t, "proto", srv.codecOf(2).Name())
+}
@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+const drainCheckInterval = 50 * time.Millisecond
+
+func (s *Server) isDraining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
+
+func (s *Server) sessions() []*Session {
+	var sessions []*Session
+	s.sessionMgr.Range(func(c *Session) {
+		sessions = append(sessions, c)
+	})
+	return sessions
+}
+
+// goAway send a going away close frame, the client is expected to answer it
+// and close the connection, which ends the pumps of the session.
+func (c *Session) goAway() {
+	conn := c.Conn()
+	if conn == nil {
+		return
+	}
+	_ = conn.WriteControl(ws.CloseMessage,
+		ws.FormatCloseMessage(ws.CloseGoingAway, "server shutting down"),
+		time.Now().Add(c.server.timeout))
+}
+
+// drain refuse new sessions, ask the current ones to go away and wait for
+// them to leave for the drain timeout at most, then close the rest.
+func (s *Server) drain(ctx context.Context) {
+	if !atomic.CompareAndSwapInt32(&s.draining, 0, 1) {
+		return
+	}
+
+	for _, c := range s.sessions() {
+		c.goAway()
+	}
+
+	if s.drainTimeout > 0 {
+		timer := time.NewTimer(s.drainTimeout)
+		defer timer.Stop()
+		ticker := time.NewTicker(drainCheckInterval)
+		defer ticker.Stop()
+
+	wait:
+		for s.SessionCount() > 0 {
+			select {
+			case <-ctx.Done():
+				break wait
+			case <-timer.C:
+				LogWarnf("drain timeout, close %d sessions", s.SessionCount())
+				break wait
+			case <-ticker.C:
+			}
+		}
+	}
+
+	for _, c := range s.sessions() {
+		if conn := c.Conn(); conn != nil {
+			_ = conn.SetReadDeadline(time.Now())
+		}
+	}
+}
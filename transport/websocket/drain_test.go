@@ -0,0 +1,82 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrain(t *testing.T) {
+	srv := NewServer(WithPath("/drain-test"), WithDrainTimeout(5*time.Second))
+	go srv.run()
+
+	hs := httptest.NewServer(http.HandlerFunc(srv.wsHandler))
+	defer hs.Close()
+	url := "ws" + strings.TrimPrefix(hs.URL, "http")
+
+	conn, _, err := ws.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.Eventually(t, func() bool { return srv.SessionCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	stopped := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		stopped <- srv.Stop(context.Background())
+	}()
+
+	// the client answers the close frame while reading
+	assert.Equal(t, ws.CloseGoingAway, readCloseCode(conn))
+
+	select {
+	case err = <-stopped:
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	case <-time.After(10 * time.Second):
+		t.Fatal("server not stopped")
+	}
+
+	_, resp, err := ws.DefaultDialer.Dial(url, nil)
+	assert.Error(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	srv := NewServer(WithPath("/drain-timeout-test"), WithDrainTimeout(100*time.Millisecond))
+
+	// the client does not read, so it never answers the close frame
+	_, closeFn := dialTestServer(t, srv)
+	defer closeFn()
+	assert.Eventually(t, func() bool { return srv.SessionCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	start := time.Now()
+	assert.NoError(t, srv.Stop(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	assert.Eventually(t, func() bool { return srv.SessionCount() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestGoAwayWhileClosing(t *testing.T) {
+	c, closeFn := acceptTestSession(t, NewServer(WithPath("/go-away-closing-test")))
+	defer closeFn()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			c.goAway()
+		}
+		close(done)
+	}()
+
+	c.closeConnect()
+	<-done
+	assert.Nil(t, c.Conn())
+}
@@ -6,10 +6,12 @@ toolchain go1.22.1
 
 require (
 	github.com/go-kratos/kratos/v2 v2.7.3
+	github.com/go-stomp/stomp/v3 v3.1.0
 	github.com/google/uuid v1.6.0
 	github.com/gorilla/websocket v1.5.1
 	github.com/stretchr/testify v1.9.0
 	github.com/tx7do/kratos-transport v1.1.5
+	golang.org/x/time v0.5.0
 )
 
 require (
@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"sync/atomic"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// LivenessHandler is called with alive true for every pong of a session, and
+// once with alive false when the session is found dead, before it is closed.
+type LivenessHandler func(sessionId SessionID, alive bool)
+
+// heartbeatEnabled report whether the sessions are to be watched.
+func (s *Server) heartbeatEnabled() bool {
+	return s.pingInterval > 0 || s.idleTimeout > 0
+}
+
+// pongWait is how long a session may go without a pong.
+func (s *Server) pongWait() time.Duration {
+	if s.pongTimeout > 0 {
+		return s.pongTimeout
+	}
+	return 2 * s.pingInterval
+}
+
+// heartbeatPeriod is how often the sessions are pinged and checked.
+func (s *Server) heartbeatPeriod() time.Duration {
+	if s.pingInterval > 0 {
+		return s.pingInterval
+	}
+	return s.idleTimeout / 2
+}
+
+func (c *Session) touch() {
+	atomic.StoreInt64(&c.lastActive, time.Now().UnixNano())
+}
+
+func (c *Session) handlePong(string) error {
+	atomic.StoreInt64(&c.lastPong, time.Now().UnixNano())
+	if c.server.livenessHandler != nil {
+		c.server.livenessHandler(c.id, true)
+	}
+	return nil
+}
+
+func since(unixNano *int64) time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(unixNano)))
+}
+
+// heartbeat ping the client every interval, and close the session when it
+// has not answered in time or has been idle for too long.
+func (c *Session) heartbeat() {
+	ticker := time.NewTicker(c.server.heartbeatPeriod())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+
+		case <-ticker.C:
+			if c.server.idleTimeout > 0 && since(&c.lastActive) > c.server.idleTimeout {
+				LogWarnf("session [%s] idle for %s, disconnect", c.id, c.server.idleTimeout)
+				c.die(ws.CloseGoingAway, "idle timeout")
+				return
+			}
+
+			if c.server.pingInterval <= 0 {
+				break
+			}
+
+			if since(&c.lastPong) > c.server.pongWait() {
+				LogWarnf("session [%s] missed its pong, disconnect", c.id)
+				c.die(ws.CloseGoingAway, "pong timeout")
+				return
+			}
+
+			conn := c.Conn()
+			if conn == nil {
+				return
+			}
+			if err := conn.WriteControl(ws.PingMessage, nil, time.Now().Add(c.server.timeout)); err != nil {
+				LogErrorf("write ping message error: %v", err)
+				c.die(ws.CloseGoingAway, "ping failed")
+				return
+			}
+		}
+	}
+}
+
+// die report a dead session and close it, its pumps then clean it up.
+func (c *Session) die(code int, reason string) {
+	if c.server.livenessHandler != nil {
+		c.server.livenessHandler(c.id, false)
+	}
+	c.kick(code, reason)
+}
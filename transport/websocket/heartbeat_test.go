@@ -0,0 +1,129 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPingPong(t *testing.T) {
+	alive := make(chan bool, 16)
+	srv := NewServer(
+		WithPath("/ping-pong-test"),
+		WithPingInterval(20*time.Millisecond),
+		WithLivenessHandler(func(_ SessionID, ok bool) {
+			select {
+			case alive <- ok:
+			default:
+			}
+		}),
+	)
+
+	conn, closeFn := dialTestServer(t, srv)
+	defer closeFn()
+
+	// the default ping handler of the client answers while reading
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case ok := <-alive:
+		assert.True(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no pong received")
+	}
+	assert.Equal(t, 1, srv.SessionCount())
+}
+
+func TestPongTimeout(t *testing.T) {
+	dead := make(chan SessionID, 1)
+	srv := NewServer(
+		WithPath("/pong-timeout-test"),
+		WithPingInterval(20*time.Millisecond),
+		WithPongTimeout(50*time.Millisecond),
+		WithLivenessHandler(func(sessionId SessionID, ok bool) {
+			if !ok {
+				dead <- sessionId
+			}
+		}),
+	)
+
+	// the client does not read, so it never answers the pings
+	_, closeFn := dialTestServer(t, srv)
+	defer closeFn()
+
+	select {
+	case <-dead:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dead session not detected")
+	}
+
+	assert.Eventually(t, func() bool { return srv.SessionCount() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestIdleTimeout(t *testing.T) {
+	conn, closeFn := dialTestServer(t, NewServer(
+		WithPath("/idle-timeout-test"),
+		WithIdleTimeout(50*time.Millisecond),
+	))
+	defer closeFn()
+
+	assert.Equal(t, ws.CloseGoingAway, readCloseCode(conn))
+}
+
+// acceptTestSession return the server side session of a client connection,
+// its pumps are not started.
+func acceptTestSession(t *testing.T, srv *Server) (*Session, func()) {
+	accepted := make(chan *ws.Conn, 1)
+	hs := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		conn, err := srv.upgrader.Upgrade(res, req, nil)
+		assert.NoError(t, err)
+		accepted <- conn
+	}))
+
+	client, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(hs.URL, "http"), nil)
+	assert.NoError(t, err)
+
+	return NewSession(<-accepted, srv), func() {
+		_ = client.Close()
+		hs.Close()
+	}
+}
+
+func TestHeartbeatWhileClosing(t *testing.T) {
+	srv := NewServer(
+		WithPath("/heartbeat-closing-test"),
+		WithPingInterval(time.Millisecond),
+		WithPongTimeout(time.Minute),
+	)
+	c, closeFn := acceptTestSession(t, srv)
+	defer closeFn()
+
+	now := time.Now().UnixNano()
+	c.lastActive, c.lastPong = now, now
+
+	stopped := make(chan struct{})
+	go func() {
+		c.heartbeat()
+		close(stopped)
+	}()
+
+	// the connection goes while the heartbeat pings it.
+	time.Sleep(10 * time.Millisecond)
+	c.closeConnect()
+	assert.Nil(t, c.Conn())
+
+	time.Sleep(10 * time.Millisecond)
+	close(c.done)
+	<-stopped
+}
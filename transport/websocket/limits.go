@@ -0,0 +1,55 @@
+package websocket
+
+import (
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// OverflowPolicy decides what happens to a session going over a limit.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock wait: stop reading the client until the rate allows, or
+	// wait for room in the send buffer.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop drop the message.
+	OverflowDrop
+	// OverflowDisconnect close the session.
+	OverflowDisconnect
+)
+
+// kick close the session with code, the pumps then end and clean it up.
+func (c *Session) kick(code int, reason string) {
+	conn := c.Conn()
+	if conn == nil {
+		return
+	}
+	_ = conn.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(code, reason), time.Now().Add(c.server.timeout))
+	_ = conn.SetReadDeadline(time.Now())
+}
+
+// allowMessage apply the message rate limit of the session, and report
+// whether the message is to be handled.
+func (c *Session) allowMessage() bool {
+	if c.limiter == nil {
+		return true
+	}
+
+	switch c.server.rateLimitPolicy {
+	case OverflowDrop:
+		if !c.limiter.Allow() {
+			LogWarnf("session [%s] over the message rate, drop message", c.id)
+			return false
+		}
+	case OverflowDisconnect:
+		if !c.limiter.Allow() {
+			LogWarnf("session [%s] over the message rate, disconnect", c.id)
+			c.kick(ws.ClosePolicyViolation, "rate limit exceeded")
+			return false
+		}
+	default:
+		_ = c.limiter.Wait(c.ctx)
+	}
+	return true
+}
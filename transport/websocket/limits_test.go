@@ -0,0 +1,116 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func dialTestServer(t *testing.T, srv *Server) (*ws.Conn, func()) {
+	go srv.run()
+
+	hs := httptest.NewServer(http.HandlerFunc(srv.wsHandler))
+	conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(hs.URL, "http"), nil)
+	assert.NoError(t, err)
+
+	return conn, func() {
+		_ = conn.Close()
+		hs.Close()
+	}
+}
+
+func readCloseCode(conn *ws.Conn) int {
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if ce, ok := err.(*ws.CloseError); ok {
+				return ce.Code
+			}
+			return 0
+		}
+	}
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	conn, closeFn := dialTestServer(t, NewServer(WithPath("/max-size-test"), WithMaxMessageSize(16)))
+	defer closeFn()
+
+	assert.NoError(t, conn.WriteMessage(ws.BinaryMessage, make([]byte, 64)))
+	assert.Equal(t, ws.CloseMessageTooBig, readCloseCode(conn))
+}
+
+func TestMessageRateLimit(t *testing.T) {
+	conn, closeFn := dialTestServer(t, NewServer(
+		WithPath("/rate-limit-test"),
+		WithMessageRateLimit(1, 1, OverflowDisconnect),
+	))
+	defer closeFn()
+
+	for i := 0; i < 3; i++ {
+		_ = conn.WriteMessage(ws.BinaryMessage, []byte{1, 0, 0, 0})
+	}
+	assert.Equal(t, ws.ClosePolicyViolation, readCloseCode(conn))
+}
+
+func TestSlowConsumerPolicy(t *testing.T) {
+	srv := NewServer(WithPath("/slow-consumer-test"), WithSlowConsumerPolicy(OverflowDrop))
+	c := &Session{id: "s1", server: srv, send: make(chan []byte, 1)}
+
+	c.SendMessage([]byte("1"))
+	c.SendMessage([]byte("2"))
+	assert.Equal(t, 1, len(c.send))
+	assert.Equal(t, "1", string(<-c.send))
+
+	srv.slowConsumerPolicy = OverflowDisconnect
+	c.SendMessage([]byte("1"))
+	c.SendMessage([]byte("2"))
+	assert.Equal(t, 1, len(c.send))
+}
+
+func TestSlowConsumerPolicy_BlockUntilClosed(t *testing.T) {
+	srv := NewServer(WithPath("/slow-consumer-block-test"))
+	c := &Session{id: "s1", server: srv, send: make(chan []byte, 1), done: make(chan struct{})}
+
+	c.SendMessage([]byte("1"))
+
+	sent := make(chan struct{})
+	go func() {
+		c.SendMessage([]byte("2"))
+		close(sent)
+	}()
+
+	select {
+	case <-sent:
+		t.Fatal("send buffer full, expected to block")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(c.done)
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("blocked after the session closed")
+	}
+}
+
+func TestKickWhileClosing(t *testing.T) {
+	c, closeFn := acceptTestSession(t, NewServer(WithPath("/kick-closing-test")))
+	defer closeFn()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			c.kick(ws.ClosePolicyViolation, "rate limit exceeded")
+		}
+		close(done)
+	}()
+
+	c.closeConnect()
+	<-done
+	assert.Nil(t, c.Conn())
+}
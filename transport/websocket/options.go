@@ -6,6 +6,11 @@ import (
 	"time"
 
 	"github.com/go-kratos/kratos/v2/encoding"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/auth"
+	"github.com/tx7do/kratos-transport/transport/framing"
+	"github.com/tx7do/kratos-transport/transport/presence"
 )
 
 type PayloadType uint8
@@ -65,6 +70,15 @@ func WithCodec(c string) ServerOption {
 	}
 }
 
+// WithFrameCodec encode every message into a frame of c instead of the
+// message format of the payload type, which still picks the websocket
+// message type: use PayloadTypeText with framing.JSONLines.
+func WithFrameCodec(c framing.Codec) ServerOption {
+	return func(s *Server) {
+		s.frameCodec = c
+	}
+}
+
 func WithChannelBufferSize(size int) ServerOption {
 	return func(_ *Server) {
 		channelBufSize = size
@@ -77,6 +91,157 @@ func WithPayloadType(payloadType PayloadType) ServerOption {
 	}
 }
 
+// WithStompBroker accept STOMP over WebSocket clients, their destinations are
+// bridged to the topics of b.
+func WithStompBroker(b broker.Broker) ServerOption {
+	return func(s *Server) {
+		s.stompBroker = b
+		s.upgrader.Subprotocols = append(s.upgrader.Subprotocols, stompSubprotocols...)
+	}
+}
+
+// WithBrokerBridge publish the bridge messages of clients to topics of b and
+// push the bridged topics of b to the sessions subscribed to them.
+func WithBrokerBridge(b broker.Broker, opts ...BridgeOption) ServerOption {
+	return func(s *Server) {
+		s.bridge = newBridge(b, opts...)
+	}
+}
+
+// WithPresence register the sessions in m, so they can be joined to rooms,
+// bound to users and reached from other instances. m may be shared by the
+// realtime servers of the application, which starts and stops it.
+func WithPresence(m *presence.Manager) ServerOption {
+	return func(s *Server) {
+		s.presence = m
+	}
+}
+
+// WithCluster relay the messages sent to rooms, users and all sessions to the
+// other instances of the server over topic of b. Each instance resolves them
+// against its own sessions, the server starts and stops the presence manager.
+func WithCluster(b broker.Broker, topic string, opts ...presence.Option) ServerOption {
+	return func(s *Server) {
+		opts = append([]presence.Option{
+			presence.WithFanout(true),
+			presence.WithRelay(presence.NewBrokerRelay(b, topic)),
+		}, opts...)
+		s.presence = presence.NewManager(opts...)
+		s.ownPresence = true
+	}
+}
+
+// WithAuthenticator authenticate the clients at handshake, the identity is
+// carried by the context of the session.
+func WithAuthenticator(a auth.Authenticator) ServerOption {
+	return func(s *Server) {
+		s.authenticator = a
+	}
+}
+
+// WithAuthCloseCode set the close code sent to the clients failing the
+// authentication, default is 1008 (policy violation). With 0 the handshake is
+// refused with http status 401 instead.
+func WithAuthCloseCode(code int) ServerOption {
+	return func(s *Server) {
+		s.authCloseCode = code
+	}
+}
+
+// WithMaxMessageSize set the max size of the messages read from a client,
+// the session is closed with 1009 (message too big) when it is exceeded.
+func WithMaxMessageSize(size int64) ServerOption {
+	return func(s *Server) {
+		s.maxMessageSize = size
+	}
+}
+
+// WithMessageRateLimit limit the messages read from each client to rate per
+// second with bursts of burst, policy decides what happens to the messages
+// over the limit; OverflowDisconnect closes the session with 1008 (policy
+// violation).
+func WithMessageRateLimit(rate float64, burst int, policy OverflowPolicy) ServerOption {
+	return func(s *Server) {
+		s.messageRate = rate
+		s.messageBurst = burst
+		s.rateLimitPolicy = policy
+	}
+}
+
+// WithSlowConsumerPolicy decide what happens to the messages sent to a
+// session whose send buffer is full, default is OverflowBlock.
+// OverflowDisconnect closes the session with 1013 (try again later).
+func WithSlowConsumerPolicy(policy OverflowPolicy) ServerOption {
+	return func(s *Server) {
+		s.slowConsumerPolicy = policy
+	}
+}
+
+// WithWriteTimeout close the sessions whose writes do not complete in timeout.
+func WithWriteTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.writeTimeout = timeout
+	}
+}
+
+// WithPingInterval ping every client at interval, the sessions missing their
+// pong for the pong timeout are closed with 1001 (going away).
+func WithPingInterval(interval time.Duration) ServerOption {
+	return func(s *Server) {
+		s.pingInterval = interval
+	}
+}
+
+// WithPongTimeout set how long a client may go without a pong, default twice
+// the ping interval.
+func WithPongTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.pongTimeout = timeout
+	}
+}
+
+// WithIdleTimeout close the sessions sending no message for timeout with 1001
+// (going away), pongs do not count.
+func WithIdleTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.idleTimeout = timeout
+	}
+}
+
+// WithLivenessHandler watch the pongs of the clients and the sessions found
+// dead by the heartbeat.
+func WithLivenessHandler(h LivenessHandler) ServerOption {
+	return func(s *Server) {
+		s.livenessHandler = h
+	}
+}
+
+// WithDrainTimeout wait for the clients to leave for timeout at most when the
+// server stops, after sending them a 1001 (going away) close frame.
+func WithDrainTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.drainTimeout = timeout
+	}
+}
+
+// WithAckDelivery deliver the messages of SendMessage and Broadcast at least
+// once: they are numbered, kept until the client acknowledges them and resent
+// every retryInterval. Each session buffers bufferSize messages at most,
+// policy decides what happens to the messages over it.
+func WithAckDelivery(bufferSize int, retryInterval time.Duration, policy OverflowPolicy) ServerOption {
+	return func(s *Server) {
+		s.acks = newAckTracker(bufferSize, retryInterval, policy)
+	}
+}
+
+// WithResumeTimeout set how long the unacknowledged messages of a closed
+// session are kept for a reconnecting client, default 1 minute.
+func WithResumeTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.resumeTimeout = timeout
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 type ClientOption func(o *Client)
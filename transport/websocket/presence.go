@@ -0,0 +1,85 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tx7do/kratos-transport/transport/auth"
+	"github.com/tx7do/kratos-transport/transport/presence"
+)
+
+var ErrPresenceDisabled = errors.New("presence manager not configured")
+
+func (s *Server) Presence() *presence.Manager {
+	return s.presence
+}
+
+func (s *Server) registerPresence(session *Session) {
+	if s.presence == nil {
+		return
+	}
+
+	ps := &presence.Session{ID: string(session.SessionID())}
+	if id, ok := auth.FromContext(session.Context()); ok {
+		ps.UserID = id.Subject
+	}
+
+	err := s.presence.Register(context.Background(), ps,
+		func(_ context.Context, msg *presence.Message) error {
+			session.SendMessage(msg.Data)
+			return nil
+		},
+	)
+	if err != nil {
+		LogErrorf("register session [%s] to presence failed: %s", session.SessionID(), err)
+	}
+}
+
+func (s *Server) unregisterPresence(session *Session) {
+	if s.presence == nil {
+		return
+	}
+
+	if err := s.presence.Unregister(context.Background(), string(session.SessionID())); err != nil {
+		LogErrorf("unregister session [%s] from presence failed: %s", session.SessionID(), err)
+	}
+}
+
+func (s *Server) presenceMessage(messageType MessageType, message MessagePayload) (*presence.Message, error) {
+	if s.presence == nil {
+		return nil, ErrPresenceDisabled
+	}
+
+	buf, err := s.marshalMessage(messageType, message)
+	if err != nil {
+		return nil, err
+	}
+	return &presence.Message{Data: buf}, nil
+}
+
+// SendToRoom send a message to the sessions in room on every instance.
+func (s *Server) SendToRoom(ctx context.Context, room string, messageType MessageType, message MessagePayload) error {
+	msg, err := s.presenceMessage(messageType, message)
+	if err != nil {
+		return err
+	}
+	return s.presence.SendToRoom(ctx, room, msg)
+}
+
+// SendToUser send a message to the sessions bound to userID on every instance.
+func (s *Server) SendToUser(ctx context.Context, userID string, messageType MessageType, message MessagePayload) error {
+	msg, err := s.presenceMessage(messageType, message)
+	if err != nil {
+		return err
+	}
+	return s.presence.SendToUser(ctx, userID, msg)
+}
+
+// SendToAll send a message to every session on every instance.
+func (s *Server) SendToAll(ctx context.Context, messageType MessageType, message MessagePayload) error {
+	msg, err := s.presenceMessage(messageType, message)
+	if err != nil {
+		return err
+	}
+	return s.presence.Broadcast(ctx, msg)
+}
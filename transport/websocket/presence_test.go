@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/presence"
+)
+
+func TestPresence(t *testing.T) {
+	m := presence.NewManager()
+
+	srv := NewServer(WithCodec("json"), WithPath("/presence-test"), WithPresence(m))
+	go srv.run()
+
+	hs := httptest.NewServer(http.HandlerFunc(srv.wsHandler))
+	defer hs.Close()
+
+	conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(hs.URL, "http"), nil)
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return m.LocalCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	var id string
+	srv.sessionMgr.Range(func(s *Session) { id = string(s.SessionID()) })
+	assert.NoError(t, m.Join(context.Background(), id, "lobby"))
+
+	assert.NoError(t, srv.SendToRoom(context.Background(), "lobby", 1, map[string]string{"text": "hi"}))
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	assert.NoError(t, err)
+
+	var msg BinaryMessage
+	assert.NoError(t, msg.Unmarshal(data))
+	assert.Equal(t, MessageType(1), msg.Type)
+
+	var body map[string]string
+	assert.NoError(t, json.Unmarshal(msg.Body, &body))
+	assert.Equal(t, "hi", body["text"])
+
+	_ = conn.Close()
+	assert.Eventually(t, func() bool { return m.LocalCount() == 0 }, 5*time.Second, 10*time.Millisecond)
+
+	assert.ErrorIs(t, NewServer(WithPath("/presence-test-disabled")).SendToUser(context.Background(), "alice", 1, "hi"), ErrPresenceDisabled)
+}
+
+func TestCluster(t *testing.T) {
+	ctx := context.Background()
+	b := &stompTestBroker{handlers: map[string]broker.Handler{}}
+
+	srv := NewServer(WithCodec("json"), WithPath("/cluster-test"), WithCluster(b, "cluster", presence.WithNode("n1")))
+	go srv.run()
+	assert.NoError(t, srv.presence.Start())
+	defer srv.presence.Stop(ctx)
+
+	hs := httptest.NewServer(http.HandlerFunc(srv.wsHandler))
+	defer hs.Close()
+
+	conn, _, err := ws.DefaultDialer.Dial("ws"+strings.TrimPrefix(hs.URL, "http"), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	assert.Eventually(t, func() bool { return srv.presence.LocalCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	var id string
+	srv.sessionMgr.Range(func(s *Session) { id = string(s.SessionID()) })
+	assert.NoError(t, srv.presence.Join(ctx, id, "lobby"))
+
+	read := func() (string, error) {
+		_ = conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return "", err
+		}
+		var msg BinaryMessage
+		assert.NoError(t, msg.Unmarshal(data))
+		var text string
+		assert.NoError(t, json.Unmarshal(msg.Body, &text))
+		return text, nil
+	}
+
+	// another instance sends to the room, the broker delivers it twice
+	buf, err := srv.marshalMessage(1, "remote")
+	assert.NoError(t, err)
+	remote := presence.NewBrokerRelay(b, "cluster")
+	env := &presence.Envelope{ID: "e1", Origin: "n2", Room: "lobby", Message: &presence.Message{Data: buf}}
+	assert.NoError(t, remote.Publish(ctx, "", env))
+	assert.NoError(t, remote.Publish(ctx, "", env))
+
+	text, err := read()
+	assert.NoError(t, err)
+	assert.Equal(t, "remote", text)
+
+	// the local send is not delivered again when it comes back from the broker
+	assert.NoError(t, srv.SendToRoom(ctx, "lobby", 1, "local"))
+	text, err = read()
+	assert.NoError(t, err)
+	assert.Equal(t, "local", text)
+
+	_, err = read()
+	assert.Error(t, err)
+}
@@ -17,6 +17,9 @@ import (
 	ws "github.com/gorilla/websocket"
 
 	"github.com/tx7do/kratos-transport/broker"
+	"github.com/tx7do/kratos-transport/transport/auth"
+	"github.com/tx7do/kratos-transport/transport/framing"
+	"github.com/tx7do/kratos-transport/transport/presence"
 )
 
 type Binder func() Any
@@ -61,6 +64,36 @@ type Server struct {
 	unregister chan *Session
 
 	payloadType PayloadType
+
+	stompBroker broker.Broker
+	bridge      *bridge
+
+	presence    *presence.Manager
+	ownPresence bool
+
+	authenticator auth.Authenticator
+	authCloseCode int
+
+	frameCodec    framing.Codec
+	messageCodecs map[MessageType]encoding.Codec
+
+	maxMessageSize     int64
+	messageRate        float64
+	messageBurst       int
+	rateLimitPolicy    OverflowPolicy
+	slowConsumerPolicy OverflowPolicy
+	writeTimeout       time.Duration
+
+	pingInterval    time.Duration
+	pongTimeout     time.Duration
+	idleTimeout     time.Duration
+	livenessHandler LivenessHandler
+
+	drainTimeout time.Duration
+	draining     int32
+
+	acks          *ackTracker
+	resumeTimeout time.Duration
 }
 
 func NewServer(opts ...ServerOption) *Server {
@@ -72,6 +105,7 @@ func NewServer(opts ...ServerOption) *Server {
 		path:        "/",
 
 		messageHandlers: make(MessageHandlerMap),
+		messageCodecs:   make(map[MessageType]encoding.Codec),
 
 		sessionMgr: NewSessionManager(),
 		upgrader: &ws.Upgrader{
@@ -84,6 +118,8 @@ func NewServer(opts ...ServerOption) *Server {
 		unregister: make(chan *Session),
 
 		payloadType: PayloadTypeBinary,
+
+		authCloseCode: ws.ClosePolicyViolation,
 	}
 
 	srv.init(opts...)
@@ -106,6 +142,17 @@ func (s *Server) init(opts ...ServerOption) {
 		TLSConfig: s.tlsConf,
 	}
 
+	if s.bridge != nil {
+		s.registerBridgeHandlers()
+	}
+
+	if s.acks != nil {
+		if s.resumeTimeout > 0 {
+			s.acks.resumeTimeout = s.resumeTimeout
+		}
+		s.registerAckHandlers()
+	}
+
 	http.HandleFunc(s.path, s.wsHandler)
 }
 
@@ -149,11 +196,15 @@ func (s *Server) marshalMessage(messageType MessageType, message MessagePayload)
 	var err error
 	var buff []byte
 
+	if s.frameCodec != nil {
+		return s.encodeFrame(messageType, message)
+	}
+
 	switch s.payloadType {
 	case PayloadTypeBinary:
 		var msg BinaryMessage
 		msg.Type = messageType
-		msg.Body, err = broker.Marshal(s.codec, message)
+		msg.Body, err = broker.Marshal(s.codecOf(messageType), message)
 		if err != nil {
 			return nil, err
 		}
@@ -167,7 +218,7 @@ func (s *Server) marshalMessage(messageType MessageType, message MessagePayload)
 		var buf []byte
 		var msg TextMessage
 		msg.Type = messageType
-		buf, err = broker.Marshal(s.codec, message)
+		buf, err = broker.Marshal(s.codecOf(messageType), message)
 		msg.Body = string(buf)
 		if err != nil {
 			return nil, err
@@ -191,6 +242,24 @@ func (s *Server) SendMessage(sessionId SessionID, messageType MessageType, messa
 		return
 	}
 
+	if s.acks != nil && c.stomp == nil {
+		if err := s.acks.send(s, c, messageType, message); err != nil {
+			LogError("send message exception:", err)
+		}
+		return
+	}
+
+	if s.frameCodec != nil {
+		buf, err := s.marshalMessage(messageType, message)
+		if err != nil {
+			LogError("marshal message exception:", err)
+			return
+		}
+
+		c.SendMessage(buf)
+		return
+	}
+
 	switch s.payloadType {
 	case PayloadTypeBinary:
 		buf, err := s.marshalMessage(messageType, message)
@@ -203,7 +272,7 @@ func (s *Server) SendMessage(sessionId SessionID, messageType MessageType, messa
 		break
 
 	case PayloadTypeText:
-		buf, err := s.codec.Marshal(message)
+		buf, err := s.codecOf(messageType).Marshal(message)
 		if err != nil {
 			LogError("marshal message exception:", err)
 			return
@@ -222,6 +291,19 @@ func (s *Server) Broadcast(messageType MessageType, message MessagePayload) {
 		return
 	}
 
+	if s.acks != nil {
+		for _, c := range s.sessions() {
+			if c.stomp != nil {
+				c.SendMessage(buf)
+				continue
+			}
+			if err = s.acks.send(s, c, messageType, message); err != nil {
+				LogError("send message exception:", err)
+			}
+		}
+		return
+	}
+
 	s.sessionMgr.Range(func(session *Session) {
 		session.SendMessage(buf)
 	})
@@ -231,6 +313,10 @@ func (s *Server) unmarshalMessage(buf []byte) (*HandlerData, MessagePayload, err
 	var handler *HandlerData
 	var payload MessagePayload
 
+	if s.frameCodec != nil {
+		return s.decodeFrame(buf)
+	}
+
 	switch s.payloadType {
 	case PayloadTypeBinary:
 		var msg BinaryMessage
@@ -252,7 +338,7 @@ func (s *Server) unmarshalMessage(buf []byte) (*HandlerData, MessagePayload, err
 			payload = msg.Body
 		}
 
-		if err := broker.Unmarshal(s.codec, msg.Body, &payload); err != nil {
+		if err := broker.Unmarshal(s.codecOf(msg.Type), msg.Body, &payload); err != nil {
 			LogErrorf("unmarshal message exception: %s", err)
 			return nil, nil, err
 		}
@@ -278,7 +364,7 @@ func (s *Server) unmarshalMessage(buf []byte) (*HandlerData, MessagePayload, err
 			payload = msg.Body
 		}
 
-		if err := broker.Unmarshal(s.codec, []byte(msg.Body), &payload); err != nil {
+		if err := broker.Unmarshal(s.codecOf(msg.Type), []byte(msg.Body), &payload); err != nil {
 			LogErrorf("unmarshal message exception: %s", err)
 			return nil, nil, err
 		}
@@ -308,6 +394,16 @@ func (s *Server) messageHandler(sessionId SessionID, buf []byte) error {
 }
 
 func (s *Server) wsHandler(res http.ResponseWriter, req *http.Request) {
+	if s.isDraining() {
+		http.Error(res, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, ok := s.authenticate(res, req)
+	if !ok {
+		return
+	}
+
 	conn, err := s.upgrader.Upgrade(res, req, nil)
 	if err != nil {
 		LogError("upgrade exception:", err)
@@ -315,6 +411,13 @@ func (s *Server) wsHandler(res http.ResponseWriter, req *http.Request) {
 	}
 
 	session := NewSession(conn, s)
+	session.ctx = ctx
+	if version, ok := stompVersions[conn.Subprotocol()]; ok && s.stompBroker != nil {
+		session.stomp = newStompSession(version)
+	}
+	if s.acks != nil && session.stomp == nil {
+		s.acks.attach(s, session)
+	}
 	session.server.register <- session
 
 	session.Listen()
@@ -354,12 +457,21 @@ func (s *Server) Endpoint() (*url.URL, error) {
 }
 
 func (s *Server) run() {
+	if s.acks != nil {
+		go s.acks.run(s)
+	}
+
 	for {
 		select {
 		case client := <-s.register:
 			s.sessionMgr.Add(client)
+			s.registerPresence(client)
 		case client := <-s.unregister:
 			s.sessionMgr.Remove(client)
+			s.unregisterPresence(client)
+			if s.acks != nil {
+				s.acks.detach(client)
+			}
 		}
 	}
 }
@@ -375,6 +487,18 @@ func (s *Server) Start(ctx context.Context) error {
 
 	go s.run()
 
+	if s.bridge != nil {
+		if err := s.bridge.start(s); err != nil {
+			return err
+		}
+	}
+
+	if s.ownPresence {
+		if err := s.presence.Start(); err != nil {
+			return err
+		}
+	}
+
 	var err error
 	if s.tlsConf != nil {
 		err = s.ServeTLS(s.lis, "", "")
@@ -389,5 +513,22 @@ func (s *Server) Start(ctx context.Context) error {
 
 func (s *Server) Stop(ctx context.Context) error {
 	LogInfo("server stopping")
-	return s.Shutdown(ctx)
+
+	// stop accepting first, the upgraded connections are left to drain
+	err := s.Shutdown(ctx)
+
+	s.drain(ctx)
+
+	if s.bridge != nil {
+		s.bridge.stop()
+	}
+	if s.acks != nil {
+		s.acks.stop()
+	}
+	if s.ownPresence {
+		if err := s.presence.Stop(ctx); err != nil {
+			LogErrorf("stop presence failed: %s", err)
+		}
+	}
+	return err
 }
@@ -1,8 +1,16 @@
 package websocket
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/google/uuid"
 	ws "github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+
+	"github.com/tx7do/kratos-transport/utils"
 )
 
 var channelBufSize = 256
@@ -11,9 +19,23 @@ type SessionID string
 
 type Session struct {
 	id     SessionID
-	conn   *ws.Conn
+	ctx    context.Context
 	send   chan []byte
 	server *Server
+
+	// conn is swapped to nil on close, while the pumps, the heartbeat and
+	// the drain may still be reading it.
+	conn atomic.Pointer[ws.Conn]
+
+	limiter *rate.Limiter
+
+	lastActive int64
+	lastPong   int64
+
+	closeOnce sync.Once
+	done      chan struct{}
+
+	stomp *stompSession
 }
 
 func NewSession(conn *ws.Conn, server *Server) *Session {
@@ -25,74 +47,130 @@ func NewSession(conn *ws.Conn, server *Server) *Session {
 
 	c := &Session{
 		id:     SessionID(u1.String()),
-		conn:   conn,
+		ctx:    context.Background(),
 		send:   make(chan []byte, channelBufSize),
 		server: server,
+
+		limiter: utils.NewRateLimiter(server.messageRate, server.messageBurst),
+
+		done: make(chan struct{}),
 	}
+	c.conn.Store(conn)
 
 	return c
 }
 
 func (c *Session) Conn() *ws.Conn {
-	return c.conn
+	return c.conn.Load()
 }
 
 func (c *Session) SessionID() SessionID {
 	return c.id
 }
 
+// Context carry the identity of the authenticated client, see auth.FromContext.
+func (c *Session) Context() context.Context {
+	return c.ctx
+}
+
 func (c *Session) SendMessage(message []byte) {
-	select {
-	case c.send <- message:
+	switch c.server.slowConsumerPolicy {
+	case OverflowDrop:
+		select {
+		case c.send <- message:
+		default:
+			LogWarnf("session [%s] send buffer full, drop message", c.id)
+		}
+	case OverflowDisconnect:
+		select {
+		case c.send <- message:
+		default:
+			LogWarnf("session [%s] send buffer full, disconnect", c.id)
+			c.kick(ws.CloseTryAgainLater, "slow consumer")
+		}
+	default:
+		// block until the writer catches up or the session is closed.
+		select {
+		case c.send <- message:
+		case <-c.done:
+		}
 	}
 }
 
 func (c *Session) Close() {
-	c.server.unregister <- c
-	c.closeConnect()
+	c.closeOnce.Do(func() {
+		close(c.done)
+		if c.stomp != nil {
+			c.stomp.close()
+		}
+		if c.server.bridge != nil {
+			c.server.bridge.removeSession(c.id)
+		}
+		c.server.unregister <- c
+		c.closeConnect()
+	})
 }
 
 func (c *Session) Listen() {
+	conn := c.Conn()
+	if conn == nil {
+		return
+	}
+
+	if c.server.maxMessageSize > 0 {
+		conn.SetReadLimit(c.server.maxMessageSize)
+	}
+
+	if c.server.heartbeatEnabled() {
+		now := time.Now().UnixNano()
+		c.lastActive, c.lastPong = now, now
+		conn.SetPongHandler(c.handlePong)
+		go c.heartbeat()
+	}
+
 	go c.writePump()
 	go c.readPump()
 }
 
 func (c *Session) closeConnect() {
 	//LogInfo(c.SessionID(), " connection closed")
-	if c.conn != nil {
-		if err := c.conn.Close(); err != nil {
+	if conn := c.conn.Swap(nil); conn != nil {
+		if err := conn.Close(); err != nil {
 			LogErrorf("disconnect error: %s", err.Error())
 		}
-		c.conn = nil
 	}
 }
 
 func (c *Session) sendPingMessage(message string) error {
-	if c.conn == nil {
+	conn := c.Conn()
+	if conn == nil {
 		return nil
 	}
-	return c.conn.WriteMessage(ws.PingMessage, []byte(message))
+	return conn.WriteMessage(ws.PingMessage, []byte(message))
 }
 
 func (c *Session) sendPongMessage(message string) error {
-	if c.conn == nil {
+	conn := c.Conn()
+	if conn == nil {
 		return nil
 	}
-	return c.conn.WriteMessage(ws.PongMessage, []byte(message))
+	return conn.WriteMessage(ws.PongMessage, []byte(message))
 }
 
 func (c *Session) sendTextMessage(message string) error {
-	if c.conn == nil {
+	conn := c.Conn()
+	if conn == nil {
 		return nil
 	}
-	return c.conn.WriteMessage(ws.TextMessage, []byte(message))
+	return conn.WriteMessage(ws.TextMessage, []byte(message))
 }
 
 func (c *Session) sendBinaryMessage(message []byte) error {
-	if c.conn == nil {
+	conn := c.Conn()
+	if conn == nil {
 		return nil
 	}
-	return c.conn.WriteMessage(ws.BinaryMessage, message)
+	return conn.WriteMessage(ws.BinaryMessage, message)
 }
 
 func (c *Session) writePump() {
@@ -100,8 +178,27 @@ func (c *Session) writePump() {
 
 	for {
 		select {
+		case <-c.done:
+			return
+
 		case msg := <-c.send:
+			// nil is queued by closeAfterSend.
+			if msg == nil {
+				return
+			}
+
 			var err error
+			if conn := c.Conn(); c.server.writeTimeout > 0 && conn != nil {
+				_ = conn.SetWriteDeadline(time.Now().Add(c.server.writeTimeout))
+			}
+			if c.stomp != nil {
+				if err = c.sendTextMessage(string(msg)); err != nil {
+					LogError("write stomp message error: ", err)
+					return
+				}
+				break
+			}
+
 			switch c.server.payloadType {
 			case PayloadTypeBinary:
 				if err = c.sendBinaryMessage(msg); err != nil {
@@ -125,12 +222,13 @@ func (c *Session) writePump() {
 func (c *Session) readPump() {
 	defer c.Close()
 
-	for {
-		if c.conn == nil {
-			break
-		}
+	conn := c.Conn()
+	if conn == nil {
+		return
+	}
 
-		messageType, data, err := c.conn.ReadMessage()
+	for {
+		messageType, data, err := conn.ReadMessage()
 		if err != nil {
 			if ws.IsUnexpectedCloseError(err, ws.CloseNormalClosure, ws.CloseGoingAway, ws.CloseAbnormalClosure) {
 				LogErrorf("read message error: %v", err)
@@ -142,12 +240,16 @@ func (c *Session) readPump() {
 		case ws.CloseMessage:
 			return
 
-		case ws.BinaryMessage:
-			_ = c.server.messageHandler(c.SessionID(), data)
-			break
-
-		case ws.TextMessage:
-			_ = c.server.messageHandler(c.SessionID(), data)
+		case ws.BinaryMessage, ws.TextMessage:
+			c.touch()
+			if !c.allowMessage() {
+				break
+			}
+			if c.stomp != nil {
+				_ = c.server.stompHandler(c, data)
+			} else {
+				_ = c.server.messageHandler(c.SessionID(), data)
+			}
 			break
 
 		case ws.PingMessage:
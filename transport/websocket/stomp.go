@@ -0,0 +1,282 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/go-kratos/kratos/v2/encoding"
+	"github.com/go-stomp/stomp/v3/frame"
+	"github.com/google/uuid"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+// stompVersions maps the STOMP over WebSocket subprotocols to the STOMP
+// version they speak.
+var stompVersions = map[string]string{
+	"v12.stomp": "1.2",
+	"v11.stomp": "1.1",
+	"v10.stomp": "1.0",
+}
+
+var stompSubprotocols = []string{"v12.stomp", "v11.stomp", "v10.stomp"}
+
+// stompFrameHeaders are the headers of a SEND frame about the frame itself,
+// the others are published as message headers.
+var stompFrameHeaders = map[string]bool{
+	frame.Destination:   true,
+	frame.ContentLength: true,
+	frame.Receipt:       true,
+	frame.Transaction:   true,
+}
+
+// stompSession bridges the STOMP destinations of one session to broker topics.
+type stompSession struct {
+	mtx           sync.Mutex
+	version       string
+	connected     bool
+	closed        bool
+	subscriptions map[string]broker.Subscriber
+}
+
+func newStompSession(version string) *stompSession {
+	return &stompSession{
+		version:       version,
+		subscriptions: make(map[string]broker.Subscriber),
+	}
+}
+
+func (s *stompSession) close() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	for id, sub := range s.subscriptions {
+		if err := sub.Unsubscribe(true); err != nil {
+			LogErrorf("stomp unsubscribe [%s] failed: %s", id, err)
+		}
+	}
+	s.subscriptions = nil
+}
+
+func (s *Server) stompHandler(session *Session, buf []byte) error {
+	reader := frame.NewReader(bytes.NewReader(buf))
+	for {
+		f, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			session.sendStompError(err.Error(), nil)
+			session.closeAfterSend()
+			return err
+		}
+		if f == nil {
+			// heart-beat
+			continue
+		}
+
+		if err = s.handleStompFrame(session, f); err != nil {
+			LogErrorf("stomp %s frame failed: %s", f.Command, err)
+			session.sendStompError(err.Error(), f)
+			session.closeAfterSend()
+			return err
+		}
+
+		if receipt := f.Header.Get(frame.Receipt); receipt != "" {
+			session.sendStompFrame(frame.New(frame.RECEIPT, frame.ReceiptId, receipt))
+		}
+	}
+}
+
+func (s *Server) handleStompFrame(session *Session, f *frame.Frame) error {
+	st := session.stomp
+
+	if f.Command == frame.CONNECT || f.Command == frame.STOMP {
+		st.mtx.Lock()
+		st.connected = true
+		st.mtx.Unlock()
+
+		session.sendStompFrame(frame.New(frame.CONNECTED,
+			frame.Version, st.version,
+			frame.Session, string(session.SessionID()),
+			frame.HeartBeat, "0,0",
+			frame.Server, "kratos-transport",
+		))
+		return nil
+	}
+
+	st.mtx.Lock()
+	connected := st.connected
+	st.mtx.Unlock()
+	if !connected {
+		return errors.New("not connected")
+	}
+
+	switch f.Command {
+	case frame.SEND:
+		destination := f.Header.Get(frame.Destination)
+		if destination == "" {
+			return errors.New("missing destination header")
+		}
+		return s.stompBroker.Publish(context.Background(), destination, f.Body, broker.WithHeaders(stompHeaders(f)))
+
+	case frame.SUBSCRIBE:
+		// the messages are acked by the bridge once written to the session.
+		if ack := f.Header.Get(frame.Ack); ack != "" && ack != "auto" {
+			return fmt.Errorf("ack mode [%s] not supported", ack)
+		}
+		return s.stompSubscribe(session, f.Header.Get(frame.Id), f.Header.Get(frame.Destination))
+
+	case frame.UNSUBSCRIBE:
+		id := f.Header.Get(frame.Id)
+
+		st.mtx.Lock()
+		sub, ok := st.subscriptions[id]
+		delete(st.subscriptions, id)
+		st.mtx.Unlock()
+
+		if !ok {
+			return fmt.Errorf("subscription [%s] not found", id)
+		}
+		return sub.Unsubscribe(true)
+
+	case frame.ACK, frame.NACK:
+		return errors.New("ack mode not supported, subscribe with ack:auto")
+
+	case frame.DISCONNECT:
+		// the client closes the connection once it received the receipt.
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported command: %s", f.Command)
+	}
+}
+
+func (s *Server) stompSubscribe(session *Session, id, destination string) error {
+	if id == "" {
+		return errors.New("missing id header")
+	}
+	if destination == "" {
+		return errors.New("missing destination header")
+	}
+
+	st := session.stomp
+
+	st.mtx.Lock()
+	_, exists := st.subscriptions[id]
+	st.mtx.Unlock()
+	if exists {
+		return fmt.Errorf("subscription [%s] already exists", id)
+	}
+
+	sub, err := s.stompBroker.Subscribe(destination,
+		func(_ context.Context, event broker.Event) error {
+			msg := event.Message()
+
+			body, err := stompBody(s.codec, msg.Body)
+			if err != nil {
+				return err
+			}
+
+			f := frame.New(frame.MESSAGE)
+			for k, v := range msg.Headers {
+				f.Header.Set(k, v)
+			}
+			f.Header.Set(frame.Destination, event.Topic())
+			f.Header.Set(frame.Subscription, id)
+			f.Header.Set(frame.MessageId, uuid.NewString())
+			f.Body = body
+
+			session.sendStompFrame(f)
+			return nil
+		},
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	st.mtx.Lock()
+	defer st.mtx.Unlock()
+	if st.closed {
+		return sub.Unsubscribe(true)
+	}
+	st.subscriptions[id] = sub
+
+	return nil
+}
+
+// stompHeaders return the message headers of a SEND frame, the first value
+// of a repeated header wins as in STOMP 1.2.
+func stompHeaders(f *frame.Frame) broker.Headers {
+	headers := broker.Headers{}
+	for i := 0; i < f.Header.Len(); i++ {
+		k, v := f.Header.GetAt(i)
+		if stompFrameHeaders[k] {
+			continue
+		}
+		if _, ok := headers[k]; !ok {
+			headers[k] = v
+		}
+	}
+	return headers
+}
+
+func stompBody(codec encoding.Codec, body broker.Any) ([]byte, error) {
+	switch t := body.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return t, nil
+	case string:
+		return []byte(t), nil
+	default:
+		return broker.Marshal(codec, body)
+	}
+}
+
+func (c *Session) sendStompFrame(f *frame.Frame) {
+	c.stomp.mtx.Lock()
+	closed := c.stomp.closed
+	c.stomp.mtx.Unlock()
+	if closed {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := frame.NewWriter(&buf).Write(f); err != nil {
+		LogError("write stomp frame error: ", err)
+		return
+	}
+	c.SendMessage(buf.Bytes())
+}
+
+// closeAfterSend close the session once the frames queued, e.g. an ERROR
+// frame, are written, as STOMP requires after an ERROR frame.
+func (c *Session) closeAfterSend() {
+	select {
+	case c.send <- nil:
+	case <-c.done:
+	default:
+		c.Close()
+	}
+}
+
+func (c *Session) sendStompError(message string, f *frame.Frame) {
+	e := frame.New(frame.ERROR, frame.Message, message)
+	if f != nil {
+		if receipt := f.Header.Get(frame.Receipt); receipt != "" {
+			e.Header.Set(frame.ReceiptId, receipt)
+		}
+	}
+	c.sendStompFrame(e)
+}
@@ -0,0 +1,149 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-stomp/stomp/v3/frame"
+	ws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tx7do/kratos-transport/broker"
+)
+
+type stompTestBroker struct {
+	mtx      sync.Mutex
+	handlers map[string]broker.Handler
+}
+
+func (b *stompTestBroker) Name() string                { return "test" }
+func (b *stompTestBroker) Options() broker.Options     { return broker.Options{} }
+func (b *stompTestBroker) Address() string             { return "" }
+func (b *stompTestBroker) Init(...broker.Option) error { return nil }
+func (b *stompTestBroker) Connect() error              { return nil }
+func (b *stompTestBroker) Disconnect() error           { return nil }
+
+func (b *stompTestBroker) Publish(ctx context.Context, topic string, msg broker.Any, opts ...broker.PublishOption) error {
+	b.mtx.Lock()
+	h := b.handlers[topic]
+	b.mtx.Unlock()
+	if h == nil {
+		return nil
+	}
+	headers := broker.Headers{"content-type": "text/plain"}
+	for k, v := range broker.NewPublishOptions(opts...).Headers {
+		headers[k] = v
+	}
+	return h(ctx, &stompTestEvent{topic: topic, msg: &broker.Message{Headers: headers, Body: msg}})
+}
+
+func (b *stompTestBroker) Subscribe(topic string, handler broker.Handler, _ broker.Binder, _ ...broker.SubscribeOption) (broker.Subscriber, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.handlers[topic] = handler
+	return &stompTestSubscriber{b: b, topic: topic}, nil
+}
+
+type stompTestSubscriber struct {
+	b     *stompTestBroker
+	topic string
+}
+
+func (s *stompTestSubscriber) Options() broker.SubscribeOptions { return broker.SubscribeOptions{} }
+func (s *stompTestSubscriber) Topic() string                    { return s.topic }
+func (s *stompTestSubscriber) Unsubscribe(bool) error {
+	s.b.mtx.Lock()
+	defer s.b.mtx.Unlock()
+	delete(s.b.handlers, s.topic)
+	return nil
+}
+
+type stompTestEvent struct {
+	topic string
+	msg   *broker.Message
+}
+
+func (e *stompTestEvent) Topic() string            { return e.topic }
+func (e *stompTestEvent) Message() *broker.Message { return e.msg }
+func (e *stompTestEvent) RawMessage() interface{}  { return nil }
+func (e *stompTestEvent) Ack() error               { return nil }
+func (e *stompTestEvent) Error() error             { return nil }
+
+func TestStompBridge(t *testing.T) {
+	b := &stompTestBroker{handlers: map[string]broker.Handler{}}
+
+	srv := NewServer(WithStompBroker(b), WithPath("/stomp-test"))
+	go srv.run()
+
+	hs := httptest.NewServer(http.HandlerFunc(srv.wsHandler))
+	defer hs.Close()
+
+	dialer := ws.Dialer{Subprotocols: []string{"v12.stomp"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(hs.URL, "http"), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "v12.stomp", conn.Subprotocol())
+
+	send := func(f *frame.Frame) {
+		var buf bytes.Buffer
+		assert.NoError(t, frame.NewWriter(&buf).Write(f))
+		assert.NoError(t, conn.WriteMessage(ws.TextMessage, buf.Bytes()))
+	}
+	read := func() *frame.Frame {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, data, err := conn.ReadMessage()
+		assert.NoError(t, err)
+		f, err := frame.NewReader(bytes.NewReader(data)).Read()
+		assert.NoError(t, err)
+		return f
+	}
+
+	send(frame.New(frame.CONNECT, frame.AcceptVersion, "1.2", frame.Host, "localhost"))
+	f := read()
+	assert.Equal(t, frame.CONNECTED, f.Command)
+	assert.Equal(t, "1.2", f.Header.Get(frame.Version))
+
+	send(frame.New(frame.SUBSCRIBE, frame.Id, "sub-0", frame.Destination, "chat", frame.Receipt, "r-1"))
+	f = read()
+	assert.Equal(t, frame.RECEIPT, f.Command)
+	assert.Equal(t, "r-1", f.Header.Get(frame.ReceiptId))
+
+	msg := frame.New(frame.SEND, frame.Destination, "chat", "x-tenant", "acme")
+	msg.Body = []byte("hello")
+	send(msg)
+	f = read()
+	assert.Equal(t, frame.MESSAGE, f.Command)
+	assert.Equal(t, "chat", f.Header.Get(frame.Destination))
+	assert.Equal(t, "sub-0", f.Header.Get(frame.Subscription))
+	assert.Equal(t, "text/plain", f.Header.Get(frame.ContentType))
+	assert.Equal(t, "acme", f.Header.Get("x-tenant"))
+	assert.Equal(t, "hello", string(f.Body))
+
+	send(frame.New(frame.UNSUBSCRIBE, frame.Id, "sub-0", frame.Receipt, "r-2"))
+	f = read()
+	assert.Equal(t, frame.RECEIPT, f.Command)
+
+	b.mtx.Lock()
+	assert.Empty(t, b.handlers)
+	b.mtx.Unlock()
+
+	// the client-ack modes are rejected, the session is closed after the ERROR frame.
+	send(frame.New(frame.SUBSCRIBE, frame.Id, "sub-1", frame.Destination, "chat", frame.Ack, "client"))
+	f = read()
+	assert.Equal(t, frame.ERROR, f.Command)
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.Error(t, err)
+	var netErr interface{ Timeout() bool }
+	if errors.As(err, &netErr) {
+		assert.False(t, netErr.Timeout(), "connection closed")
+	}
+}
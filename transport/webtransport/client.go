@@ -29,7 +29,8 @@ type ClientHandlerData struct {
 type ClientMessageHandlerMap map[MessageType]ClientHandlerData
 
 type Client struct {
-	transport *http3.RoundTripper
+	quicConf     *quic.Config
+	roundTripper *http3.SingleDestinationRoundTripper
 
 	ctx       context.Context
 	ctxCancel context.CancelFunc
@@ -48,7 +49,6 @@ type Client struct {
 
 func NewClient(opts ...ClientOption) *Client {
 	cli := &Client{
-		transport:       &http3.RoundTripper{},
 		codec:           encoding.GetCodec("json"),
 		messageHandlers: make(ClientMessageHandlerMap),
 	}
@@ -67,6 +67,7 @@ func (c *Client) init(opts ...ClientOption) {
 	if timeout == 0 {
 		timeout = 5 * time.Second
 	}
+	c.timeout = timeout
 	c.sessions = newSessionManager(timeout)
 
 	if c.tlsConf == nil {
@@ -75,43 +76,17 @@ func (c *Client) init(opts ...ClientOption) {
 			InsecureSkipVerify: true,
 		}
 	}
-	c.transport.TLSClientConfig = c.tlsConf
-
-	c.transport.EnableDatagrams = true
-
-	if c.transport.AdditionalSettings == nil {
-		c.transport.AdditionalSettings = make(map[uint64]uint64)
+	if len(c.tlsConf.NextProtos) == 0 {
+		c.tlsConf = c.tlsConf.Clone()
+		c.tlsConf.NextProtos = []string{http3.NextProtoH3}
 	}
 
-	c.transport.StreamHijacker = func(ft http3.FrameType, conn quic.Connection, str quic.Stream, e error) (hijacked bool, err error) {
-		if isWebTransportError(e) {
-			return true, nil
-		}
-		if ft != webTransportFrameType {
-			return false, nil
-		}
-		id, err := quicvarint.Read(quicvarint.NewReader(str))
-		if err != nil {
-			if isWebTransportError(err) {
-				return true, nil
-			}
-			return false, err
-		}
-		c.sessions.AddStream(conn, str, SessionID(id))
-		return true, nil
-	}
-	c.transport.UniStreamHijacker = func(st http3.StreamType, conn quic.Connection, str quic.ReceiveStream, err error) (hijacked bool) {
-		if st != webTransportUniStreamType && !isWebTransportError(err) {
-			return false
-		}
-		c.sessions.AddUniStream(conn, str)
-		return true
+	if c.quicConf == nil {
+		c.quicConf = &quic.Config{}
 	}
-	if c.transport.QuicConfig == nil {
-		c.transport.QuicConfig = &quic.Config{}
-	}
-	if c.transport.QuicConfig.MaxIncomingStreams == 0 {
-		c.transport.QuicConfig.MaxIncomingStreams = 100
+	c.quicConf.EnableDatagrams = true
+	if c.quicConf.MaxIncomingStreams == 0 {
+		c.quicConf.MaxIncomingStreams = 100
 	}
 }
 
@@ -121,11 +96,66 @@ func (c *Client) Connect() error {
 		return err
 	}
 
-	rsp, err := c.transport.RoundTripOpt(req,
-		http3.RoundTripOpt{
-			DontCloseRequestStream: true,
+	ctx, cancel := context.WithTimeout(c.ctx, c.timeout)
+	defer cancel()
+
+	conn, err := quic.DialAddrEarly(ctx, req.URL.Host, c.tlsConf, c.quicConf)
+	if err != nil {
+		return err
+	}
+
+	c.roundTripper = &http3.SingleDestinationRoundTripper{
+		Connection:      conn,
+		EnableDatagrams: true,
+		AdditionalSettings: map[uint64]uint64{
+			settingsEnableWebtransport: 1,
 		},
-	)
+		StreamHijacker: func(ft http3.FrameType, connID quic.ConnectionTracingID, str quic.Stream, e error) (hijacked bool, err error) {
+			if isWebTransportError(e) {
+				return true, nil
+			}
+			if ft != webTransportFrameType {
+				return false, nil
+			}
+			id, err := quicvarint.Read(quicvarint.NewReader(str))
+			if err != nil {
+				if isWebTransportError(err) {
+					return true, nil
+				}
+				return false, err
+			}
+			c.sessions.AddStream(connID, str, SessionID(id))
+			return true, nil
+		},
+		UniStreamHijacker: func(st http3.StreamType, connID quic.ConnectionTracingID, str quic.ReceiveStream, err error) (hijacked bool) {
+			if st != webTransportUniStreamType && !isWebTransportError(err) {
+				return false
+			}
+			c.sessions.AddUniStream(connID, str)
+			return true
+		},
+	}
+	hconn := c.roundTripper.Start()
+
+	select {
+	case <-hconn.ReceivedSettings():
+	case <-ctx.Done():
+		_ = conn.CloseWithError(0, "")
+		return fmt.Errorf("error waiting for HTTP/3 settings: %w", ctx.Err())
+	}
+	if settings := hconn.Settings(); !settings.EnableDatagrams {
+		_ = conn.CloseWithError(0, "")
+		return errors.New("server didn't enable HTTP/3 datagram support")
+	}
+
+	requestStream, err := c.roundTripper.OpenRequestStream(ctx)
+	if err != nil {
+		return err
+	}
+	if err = requestStream.SendRequestHeader(req); err != nil {
+		return err
+	}
+	rsp, err := requestStream.ReadResponse()
 	if err != nil {
 		return err
 	}
@@ -133,16 +163,16 @@ func (c *Client) Connect() error {
 		return fmt.Errorf("received status %d", rsp.StatusCode)
 	}
 
-	stream := rsp.Body.(http3.HTTPStreamer).HTTPStream()
 	session := c.sessions.AddSession(
-		rsp.Body.(http3.Hijacker).StreamCreator(),
-		SessionID(stream.StreamID()),
-		stream,
+		hconn,
+		SessionID(requestStream.StreamID()),
+		requestStream,
 	)
 
 	c.session = session
 
 	go c.doAcceptStream(session)
+	go c.doAcceptUniStream(session)
 
 	log.Infof("[webtransport] client connected to: %s", c.url)
 
@@ -151,7 +181,23 @@ func (c *Client) Connect() error {
 
 func (c *Client) Disconnect() error {
 	log.Info("[webtransport] client stopping")
-	return nil
+
+	var err error
+	if c.session != nil {
+		err = c.session.Close()
+		c.session = nil
+	}
+	if c.ctxCancel != nil {
+		c.ctxCancel()
+	}
+	if c.sessions != nil {
+		c.sessions.Close()
+	}
+	if c.roundTripper != nil {
+		_ = c.roundTripper.Connection.CloseWithError(0, "")
+		c.roundTripper = nil
+	}
+	return err
 }
 
 func (c *Client) RegisterMessageHandler(messageType MessageType, handler ClientMessageHandler, binder Binder) {
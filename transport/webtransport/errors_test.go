@@ -5,6 +5,7 @@ import (
 	"math"
 	"testing"
 
+	"github.com/quic-go/quic-go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,6 +26,14 @@ type ConnectHandler func(SessionID, bool)
 
 type MessageHandler func(SessionID, MessagePayload) error
 
+// StreamHandler takes over a bidirectional stream opened by the client,
+// bypassing the message handlers.
+type StreamHandler func(*Session, Stream)
+
+// UniStreamHandler takes over a unidirectional stream opened by the client,
+// bypassing the message handlers.
+type UniStreamHandler func(*Session, ReceiveStream)
+
 type HandlerData struct {
 	Handler MessageHandler
 	Binder  Binder
@@ -53,11 +61,16 @@ type Server struct {
 	ctxCancel context.CancelFunc
 	refCount  sync.WaitGroup
 
-	messageHandlers MessageHandlerMap
-	connectHandler  ConnectHandler
-	codec           encoding.Codec
+	messageHandlers  MessageHandlerMap
+	connectHandler   ConnectHandler
+	streamHandler    StreamHandler
+	uniStreamHandler UniStreamHandler
+	codec            encoding.Codec
 
 	sessions *sessionManager
+
+	sessionMtx     sync.RWMutex
+	activeSessions map[SessionID]*Session
 }
 
 func NewServer(opts ...ServerOption) *Server {
@@ -66,11 +79,13 @@ func NewServer(opts ...ServerOption) *Server {
 		ctx:       ctx,
 		ctxCancel: ctxCancel,
 		mux:       http.NewServeMux(),
+		path:      "/",
 		upgrader: &Upgrader{
 			CheckOrigin: func(r *http.Request) bool { return true },
 		},
 		messageHandlers: make(MessageHandlerMap),
 		codec:           encoding.GetCodec("json"),
+		activeSessions:  make(map[SessionID]*Session),
 	}
 	srv.init(opts...)
 	return srv
@@ -81,7 +96,7 @@ func (s *Server) init(opts ...ServerOption) {
 
 	s.Server = &http3.Server{
 		Addr: ":443",
-		QuicConfig: &quic.Config{
+		QUICConfig: &quic.Config{
 			MaxIdleTimeout:  idleTimeout,
 			KeepAlivePeriod: idleTimeout / 2,
 		},
@@ -96,18 +111,17 @@ func (s *Server) init(opts ...ServerOption) {
 	}
 	s.Server.TLSConfig = s.tlsConf
 
-	timeout := s.timeout
-	if timeout == 0 {
-		timeout = 5 * time.Second
+	if s.timeout == 0 {
+		s.timeout = 5 * time.Second
 	}
-	s.sessions = newSessionManager(timeout)
+	s.sessions = newSessionManager(s.timeout)
 
 	if s.Server.AdditionalSettings == nil {
 		s.Server.AdditionalSettings = make(map[uint64]uint64)
 	}
 	s.Server.AdditionalSettings[settingsEnableWebtransport] = 1
 	s.Server.EnableDatagrams = true
-	s.Server.StreamHijacker = func(ft http3.FrameType, qConn quic.Connection, qStream quic.Stream, err error) (bool /* hijacked */, error) {
+	s.Server.StreamHijacker = func(ft http3.FrameType, connID quic.ConnectionTracingID, qStream quic.Stream, err error) (bool /* hijacked */, error) {
 		if isWebTransportError(err) {
 			return true, nil
 		}
@@ -121,14 +135,14 @@ func (s *Server) init(opts ...ServerOption) {
 			}
 			return false, err
 		}
-		s.sessions.AddStream(qConn, qStream, SessionID(id))
+		s.sessions.AddStream(connID, qStream, SessionID(id))
 		return true, nil
 	}
-	s.Server.UniStreamHijacker = func(st http3.StreamType, qConn quic.Connection, qStream quic.ReceiveStream, err error) (hijacked bool) {
+	s.Server.UniStreamHijacker = func(st http3.StreamType, connID quic.ConnectionTracingID, qStream quic.ReceiveStream, err error) (hijacked bool) {
 		if st != webTransportUniStreamType && !isWebTransportError(err) {
 			return false
 		}
-		s.sessions.AddUniStream(qConn, qStream)
+		s.sessions.AddUniStream(connID, qStream)
 		return true
 	}
 
@@ -161,6 +175,7 @@ func (s *Server) Stop(ctx context.Context) error {
 	if s.ctxCancel != nil {
 		s.ctxCancel()
 	}
+	s.closeSessions()
 	if s.sessions != nil {
 		s.sessions.Close()
 	}
@@ -179,10 +194,98 @@ func (s *Server) RegisterMessageHandler(messageType MessageType, handler Message
 	}
 }
 
+func RegisterServerMessageHandler[T any](srv *Server, messageType MessageType, handler func(SessionID, *T) error) {
+	srv.RegisterMessageHandler(messageType,
+		func(sessionId SessionID, payload MessagePayload) error {
+			switch t := payload.(type) {
+			case *T:
+				return handler(sessionId, t)
+			default:
+				log.Error("[webtransport] invalid payload struct type:", t)
+				return errors.New("invalid payload struct type")
+			}
+		},
+		func() Any {
+			var t T
+			return &t
+		},
+	)
+}
+
 func (s *Server) DeregisterMessageHandler(messageType MessageType) {
 	delete(s.messageHandlers, messageType)
 }
 
+func (s *Server) SessionCount() int {
+	s.sessionMtx.RLock()
+	defer s.sessionMtx.RUnlock()
+	return len(s.activeSessions)
+}
+
+func (s *Server) Session(sessionId SessionID) (*Session, bool) {
+	s.sessionMtx.RLock()
+	defer s.sessionMtx.RUnlock()
+	session, ok := s.activeSessions[sessionId]
+	return session, ok
+}
+
+func (s *Server) SendMessage(sessionId SessionID, messageType MessageType, message MessagePayload) {
+	session, ok := s.Session(sessionId)
+	if !ok {
+		log.Error("[webtransport] session not found:", sessionId)
+		return
+	}
+
+	buf, err := s.marshalMessage(messageType, message)
+	if err != nil {
+		log.Error("[webtransport] marshal message exception:", err)
+		return
+	}
+
+	if err = s.sendData(session, buf); err != nil {
+		log.Errorf("[webtransport] send message to [%d] failed: %s", sessionId, err)
+	}
+}
+
+func (s *Server) Broadcast(messageType MessageType, message MessagePayload) {
+	buf, err := s.marshalMessage(messageType, message)
+	if err != nil {
+		log.Error("[webtransport] marshal message exception:", err)
+		return
+	}
+
+	s.sessionMtx.RLock()
+	sessions := make([]*Session, 0, len(s.activeSessions))
+	for _, session := range s.activeSessions {
+		sessions = append(sessions, session)
+	}
+	s.sessionMtx.RUnlock()
+
+	for _, session := range sessions {
+		if err = s.sendData(session, buf); err != nil {
+			log.Errorf("[webtransport] broadcast to [%d] failed: %s", session.SessionID(), err)
+		}
+	}
+}
+
+// sendData writes buf on a new unidirectional stream, one stream per message.
+func (s *Server) sendData(session *Session, buf []byte) error {
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+	defer cancel()
+
+	stream, err := session.OpenUniStreamSync(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err = stream.Write(buf); err != nil {
+		stream.CancelWrite(0)
+		return err
+	}
+
+	return stream.Close()
+}
+
 func (s *Server) marshalMessage(messageType MessageType, message MessagePayload) ([]byte, error) {
 	var err error
 	var msg Message
@@ -252,16 +355,47 @@ func (s *Server) addHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	session := s.sessions.AddSession(
-		hijacker.StreamCreator(),
+		hijacker.Connection(),
 		sID,
 		r.Body.(http3.HTTPStreamer).HTTPStream(),
 	)
 
+	s.addSession(session)
+
+	go s.doAcceptStream(session)
+	go s.doAcceptUniStream(session)
+}
+
+func (s *Server) addSession(session *Session) {
+	s.sessionMtx.Lock()
+	s.activeSessions[session.SessionID()] = session
+	s.sessionMtx.Unlock()
+
 	if s.connectHandler != nil {
 		s.connectHandler(session.SessionID(), true)
 	}
+}
+
+func (s *Server) removeSession(session *Session) {
+	s.sessionMtx.Lock()
+	_, ok := s.activeSessions[session.SessionID()]
+	delete(s.activeSessions, session.SessionID())
+	s.sessionMtx.Unlock()
+
+	if ok && s.connectHandler != nil {
+		s.connectHandler(session.SessionID(), false)
+	}
+}
 
-	go s.doAcceptStream(session)
+func (s *Server) closeSessions() {
+	s.sessionMtx.Lock()
+	sessions := s.activeSessions
+	s.activeSessions = make(map[SessionID]*Session)
+	s.sessionMtx.Unlock()
+
+	for _, session := range sessions {
+		_ = session.Close()
+	}
 }
 
 func (s *Server) doAcceptStream(session *Session) {
@@ -269,17 +403,10 @@ func (s *Server) doAcceptStream(session *Session) {
 		acceptStream, err := session.AcceptStream(s.ctx)
 		if err != nil {
 			log.Debug("[webtransport] accept stream failed: ", err.Error())
-			if s.connectHandler != nil {
-				s.connectHandler(session.SessionID(), false)
-			}
+			s.removeSession(session)
 			break
 		}
-		data, err := io.ReadAll(acceptStream)
-		if err != nil {
-			log.Error("[webtransport] read data failed: ", err.Error())
-		}
-		//log.Debug("receive data: ", string(data))
-		_ = s.messageHandler(session.SessionID(), data)
+		go s.handleStream(session, acceptStream)
 	}
 }
 
@@ -288,16 +415,40 @@ func (s *Server) doAcceptUniStream(session *Session) {
 		acceptStream, err := session.AcceptUniStream(s.ctx)
 		if err != nil {
 			log.Debug("[webtransport] accept uni stream failed: ", err.Error())
-			if s.connectHandler != nil {
-				s.connectHandler(session.SessionID(), false)
-			}
+			s.removeSession(session)
 			break
 		}
-		data, err := io.ReadAll(acceptStream)
-		if err != nil {
-			log.Error("[webtransport] read uni data failed: ", err.Error())
-		}
-		//log.Debug("receive data: ", string(data))
-		_ = s.messageHandler(session.SessionID(), data)
+		go s.handleUniStream(session, acceptStream)
+	}
+}
+
+func (s *Server) handleStream(session *Session, stream Stream) {
+	if s.streamHandler != nil {
+		s.streamHandler(session, stream)
+		return
+	}
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		log.Error("[webtransport] read data failed: ", err.Error())
+		return
+	}
+	_ = stream.Close()
+	//log.Debug("receive data: ", string(data))
+	_ = s.messageHandler(session.SessionID(), data)
+}
+
+func (s *Server) handleUniStream(session *Session, stream ReceiveStream) {
+	if s.uniStreamHandler != nil {
+		s.uniStreamHandler(session, stream)
+		return
+	}
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		log.Error("[webtransport] read uni data failed: ", err.Error())
+		return
 	}
+	//log.Debug("receive data: ", string(data))
+	_ = s.messageHandler(session.SessionID(), data)
 }
@@ -9,7 +9,12 @@ import (
 	"syscall"
 	"testing"
 
+	"github.com/golang/mock/gomock"
+	"github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/require"
+
 	api "github.com/tx7do/kratos-transport/testing/api/manual"
+	"github.com/tx7do/kratos-transport/transport/webtransport/mock"
 )
 
 var testServer *Server
@@ -69,3 +74,43 @@ func TestServer(t *testing.T) {
 
 	<-interrupt
 }
+
+func TestServerBroadcast(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var connected []SessionID
+	srv := NewServer(WithConnectHandle(func(id SessionID, register bool) {
+		if register {
+			connected = append(connected, id)
+		}
+	}))
+
+	mockConn := mock.NewMockConnection(ctrl)
+	sess := newSession(42, mockConn, newMockRequestStream(ctrl))
+	srv.addSession(sess)
+	require.Equal(t, 1, srv.SessionCount())
+	require.Equal(t, []SessionID{42}, connected)
+
+	var written [][]byte
+	str := mock.NewMockStream(ctrl)
+	str.EXPECT().StreamID().Return(quic.StreamID(3)).AnyTimes()
+	str.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+		written = append(written, append([]byte(nil), b...))
+		return len(b), nil
+	}).Times(2)
+	str.EXPECT().Close()
+	mockConn.EXPECT().OpenUniStreamSync(gomock.Any()).Return(str, nil)
+
+	srv.Broadcast(api.MessageTypeChat, &api.ChatMessage{Message: "hello"})
+	require.Len(t, written, 2)
+	require.Equal(t, sess.uniStreamHdr, written[0])
+
+	var msg Message
+	require.NoError(t, msg.Unmarshal(written[1]))
+	require.Equal(t, MessageType(api.MessageTypeChat), msg.Type)
+	require.JSONEq(t, `{"type":0,"sender":"","message":"hello"}`, string(msg.Body))
+
+	srv.closeSessions()
+	require.Equal(t, 0, srv.SessionCount())
+}
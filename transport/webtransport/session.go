@@ -1,7 +1,6 @@
 package webtransport
 
 import (
-	"bytes"
 	"context"
 	"math/rand"
 	"net"
@@ -55,7 +54,7 @@ func (q *acceptQueue[T]) Chan() <-chan struct{} { return q.c }
 
 type Session struct {
 	sessionID     SessionID
-	qConn         http3.StreamCreator
+	qConn         http3.Connection
 	requestStream quic.Stream
 
 	streamHdr    []byte
@@ -72,7 +71,7 @@ type Session struct {
 	streams streamsMap
 }
 
-func newSession(sessionID SessionID, qConn http3.StreamCreator, requestStr quic.Stream) *Session {
+func newSession(sessionID SessionID, qConn http3.Connection, requestStr quic.Stream) *Session {
 	ctx, ctxCancel := context.WithCancel(context.Background())
 	c := &Session{
 		sessionID:       sessionID,
@@ -86,16 +85,14 @@ func newSession(sessionID SessionID, qConn http3.StreamCreator, requestStr quic.
 	}
 
 	// precompute the headers for unidirectional streams
-	buf := bytes.NewBuffer(make([]byte, 0, 2+quicvarint.Len(uint64(c.sessionID))))
-	quicvarint.Write(buf, webTransportUniStreamType)
-	quicvarint.Write(buf, uint64(c.sessionID))
-	c.uniStreamHdr = buf.Bytes()
+	c.uniStreamHdr = make([]byte, 0, 2+quicvarint.Len(uint64(c.sessionID)))
+	c.uniStreamHdr = quicvarint.Append(c.uniStreamHdr, webTransportUniStreamType)
+	c.uniStreamHdr = quicvarint.Append(c.uniStreamHdr, uint64(c.sessionID))
 
 	// precompute the headers for bidirectional streams
-	buf = bytes.NewBuffer(make([]byte, 0, 2+quicvarint.Len(uint64(c.sessionID))))
-	quicvarint.Write(buf, webTransportFrameType)
-	quicvarint.Write(buf, uint64(c.sessionID))
-	c.streamHdr = buf.Bytes()
+	c.streamHdr = make([]byte, 0, 2+quicvarint.Len(uint64(c.sessionID)))
+	c.streamHdr = quicvarint.Append(c.streamHdr, webTransportFrameType)
+	c.streamHdr = quicvarint.Append(c.streamHdr, uint64(c.sessionID))
 
 	go func() {
 		defer ctxCancel()
@@ -18,7 +18,7 @@ type session struct {
 }
 
 type sessionMap map[SessionID]*session
-type connectMap map[http3.StreamCreator]sessionMap
+type connectMap map[quic.ConnectionTracingID]sessionMap
 
 type sessionManager struct {
 	refCount  sync.WaitGroup
@@ -44,8 +44,8 @@ func newSessionManager(timeout time.Duration) *sessionManager {
 // If the WebTransport session has not yet been established,
 // it starts a new go routine and waits for establishment of the session.
 // If that takes longer than timeout, the qStream is reset.
-func (m *sessionManager) AddStream(qConn http3.StreamCreator, qStream quic.Stream, id SessionID) {
-	sess, isExisting := m.getOrCreateSession(qConn, id)
+func (m *sessionManager) AddStream(connID quic.ConnectionTracingID, qStream quic.Stream, id SessionID) {
+	sess, isExisting := m.getOrCreateSession(connID, id)
 	if isExisting {
 		sess.conn.addIncomingStream(qStream)
 		return
@@ -63,19 +63,19 @@ func (m *sessionManager) AddStream(qConn http3.StreamCreator, qStream quic.Strea
 		// Once no more streams are waiting for this session to be established,
 		// and this session is still outstanding, delete it from the map.
 		if sess.counter == 0 && sess.conn == nil {
-			m.maybeDelete(qConn, id)
+			m.maybeDelete(connID, id)
 		}
 	}()
 }
 
-func (m *sessionManager) maybeDelete(qConn http3.StreamCreator, id SessionID) {
-	sessions, ok := m.connections[qConn]
+func (m *sessionManager) maybeDelete(connID quic.ConnectionTracingID, id SessionID) {
+	sessions, ok := m.connections[connID]
 	if !ok { // should never happen
 		return
 	}
 	delete(sessions, id)
 	if len(sessions) == 0 {
-		delete(m.connections, qConn)
+		delete(m.connections, connID)
 	}
 }
 
@@ -83,14 +83,14 @@ func (m *sessionManager) maybeDelete(qConn http3.StreamCreator, id SessionID) {
 // If the WebTransport session has not yet been established,
 // it starts a new go routine and waits for establishment of the session.
 // If that takes longer than timeout, the qStream is reset.
-func (m *sessionManager) AddUniStream(qConn http3.StreamCreator, qStream quic.ReceiveStream) {
+func (m *sessionManager) AddUniStream(connID quic.ConnectionTracingID, qStream quic.ReceiveStream) {
 	idv, err := quicvarint.Read(quicvarint.NewReader(qStream))
 	if err != nil {
 		qStream.CancelRead(1337)
 	}
 	id := SessionID(idv)
 
-	sess, isExisting := m.getOrCreateSession(qConn, id)
+	sess, isExisting := m.getOrCreateSession(connID, id)
 	if isExisting {
 		sess.conn.addIncomingUniStream(qStream)
 		return
@@ -108,19 +108,19 @@ func (m *sessionManager) AddUniStream(qConn http3.StreamCreator, qStream quic.Re
 		// Once no more streams are waiting for this session to be established,
 		// and this session is still outstanding, delete it from the map.
 		if sess.counter == 0 && sess.conn == nil {
-			m.maybeDelete(qConn, id)
+			m.maybeDelete(connID, id)
 		}
 	}()
 }
 
-func (m *sessionManager) getOrCreateSession(qConn http3.StreamCreator, id SessionID) (sess *session, existed bool) {
+func (m *sessionManager) getOrCreateSession(connID quic.ConnectionTracingID, id SessionID) (sess *session, existed bool) {
 	m.mx.Lock()
 	defer m.mx.Unlock()
 
-	sessions, ok := m.connections[qConn]
+	sessions, ok := m.connections[connID]
 	if !ok {
 		sessions = make(sessionMap)
-		m.connections[qConn] = sessions
+		m.connections[connID] = sessions
 	}
 
 	sess, ok = sessions[id]
@@ -167,16 +167,17 @@ func (m *sessionManager) handleUniStream(qStream quic.ReceiveStream, sess *sessi
 }
 
 // AddSession add a new WebTransport session.
-func (m *sessionManager) AddSession(qConn http3.StreamCreator, id SessionID, requestStream quic.Stream) *Session {
+func (m *sessionManager) AddSession(qConn http3.Connection, id SessionID, requestStream quic.Stream) *Session {
 	conn := newSession(id, qConn, requestStream)
+	connID := qConn.Context().Value(quic.ConnectionTracingKey).(quic.ConnectionTracingID)
 
 	m.mx.Lock()
 	defer m.mx.Unlock()
 
-	sessions, ok := m.connections[qConn]
+	sessions, ok := m.connections[connID]
 	if !ok {
 		sessions = make(sessionMap)
-		m.connections[qConn] = sessions
+		m.connections[connID] = sessions
 	}
 	if sess, ok := sessions[id]; ok {
 		// We might already have an entry of this session.
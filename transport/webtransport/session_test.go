@@ -5,12 +5,13 @@ import (
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/quic-go/quic-go"
 	"github.com/stretchr/testify/require"
 
 	"github.com/tx7do/kratos-transport/transport/webtransport/mock"
 )
 
-//go:generate mockgen -package mock -destination mock/stream_creator_mock.go github.com/quic-go/quic-go/http3 StreamCreator
+//go:generate mockgen -package mock -destination mock/connection_mock.go github.com/quic-go/quic-go/http3 Connection
 ////go:generate sh -c "mockgen -package webtransport -destination mock_stream_test.go github.com/quic-go/quic-go Stream && cat mock_stream_test.go | sed s@protocol\\.StreamID@quic.StreamID@g | sed s@qerr\\.StreamErrorCode@quic.StreamErrorCode@g > tmp.go && mv tmp.go mock_stream_test.go && goimports -w mock_stream_test.go"
 
 type mockRequestStream struct {
@@ -40,7 +41,7 @@ func TestCloseStreamsOnClose(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockSess := mock.NewMockStreamCreator(ctrl)
+	mockSess := mock.NewMockConnection(ctrl)
 	sess := newSession(42, mockSess, newMockRequestStream(ctrl))
 
 	str := mock.NewMockStream(ctrl)
@@ -64,7 +65,7 @@ func TestAddStreamAfterSessionClose(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	sess := newSession(42, mock.NewMockStreamCreator(ctrl), newMockRequestStream(ctrl))
+	sess := newSession(42, mock.NewMockConnection(ctrl), newMockRequestStream(ctrl))
 	require.NoError(t, sess.Close())
 
 	str := mock.NewMockStream(ctrl)
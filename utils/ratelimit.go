@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"golang.org/x/time/rate"
+)
+
+// NewRateLimiter return a token bucket refilled at r tokens per second, nil
+// when r is not positive, meaning unlimited.
+func NewRateLimiter(r float64, burst int) *rate.Limiter {
+	if r <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(r), burst)
+}
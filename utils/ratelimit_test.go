@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	assert.Nil(t, NewRateLimiter(0, 1))
+	assert.Equal(t, 1, NewRateLimiter(1, 0).Burst())
+
+	l := NewRateLimiter(1000, 2)
+	assert.True(t, l.Allow())
+	assert.True(t, l.Allow())
+	assert.False(t, l.Allow())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, l.Wait(ctx))
+
+	slow := NewRateLimiter(0.01, 1)
+	assert.True(t, slow.Allow())
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.NotNil(t, slow.Wait(ctx))
+}